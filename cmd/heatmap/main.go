@@ -0,0 +1,329 @@
+// Command heatmap draws where players go and die on a level, for finding
+// the stretches that need balancing. It plays replays back to trace each
+// run's path and mark its deaths, and shades the level segments of death
+// events from telemetry exports, all over the level's tiles in a PNG.
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/andersfylling/rayman-slides/internal/debugdraw"
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/render"
+	"github.com/andersfylling/rayman-slides/internal/telemetry"
+)
+
+func main() {
+	mapPath := flag.String("map", "", "level the replays and events are from (JSON, default the demo level)")
+	out := flag.String("o", "heatmap.png", "PNG to write")
+	scale := flag.Int("scale", 8, "pixels per tile")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: heatmap [-map level.json] [-o heatmap.png] replay.json|telemetry.json ...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 || *scale < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(*mapPath, *out, *scale, flag.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "heatmap: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(mapPath, out string, scale int, inputs []string) error {
+	level := game.NewDemoLevel(80, 45)
+	if mapPath != "" {
+		var err error
+		if level, err = game.ReadLevelFile(os.DirFS(filepath.Dir(mapPath)), filepath.Base(mapPath)); err != nil {
+			return err
+		}
+	}
+	hash, err := game.LevelHash(level)
+	if err != nil {
+		return err
+	}
+
+	h := newHeat(level, hex.EncodeToString(hash[:]), scale)
+	for _, path := range inputs {
+		if err := h.read(path); err != nil {
+			return err
+		}
+	}
+	if h.otherLevels > 0 {
+		fmt.Fprintf(os.Stderr, "heatmap: skipped %d telemetry events from other levels\n", h.otherLevels)
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, h.draw()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// heat is what the inputs add up to on one level
+type heat struct {
+	level *game.Level
+	key   string // Level hash, hex, as telemetry events name it
+	scale int
+
+	w, h        int      // Image size, in pixels
+	paths       []int    // Replays that passed each pixel
+	seen        []int    // Last replay to pass each pixel, so a replay counts once
+	replays     int      // Replays played
+	deaths      [][2]int // Replay deaths, in pixels
+	segments    map[int]int
+	telemetry   int // Telemetry deaths on this level
+	otherLevels int // Telemetry events skipped for naming another level
+}
+
+func newHeat(level *game.Level, key string, scale int) *heat {
+	w, h := level.TileMap.Width*scale, level.TileMap.Height*scale
+	return &heat{
+		level: level, key: key, scale: scale,
+		w: w, h: h,
+		paths:    make([]int, w*h),
+		seen:     make([]int, w*h),
+		segments: make(map[int]int),
+	}
+}
+
+// read adds a file of replays, telemetry batches (one per line, as POSTed)
+// or event arrays (as the game queues them)
+func (h *heat) read(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if err := h.add(raw); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+}
+
+// add adds one JSON value: a replay, a telemetry batch or an event array
+func (h *heat) add(raw json.RawMessage) error {
+	if raw = bytes.TrimSpace(raw); len(raw) > 0 && raw[0] == '[' {
+		var events []telemetry.Event
+		if err := json.Unmarshal(raw, &events); err != nil {
+			return err
+		}
+		h.addEvents(events)
+		return nil
+	}
+	var probe struct{ Runs, Events json.RawMessage }
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return err
+	}
+	switch {
+	case probe.Runs != nil:
+		replay, err := game.ReadReplay(bytes.NewReader(raw))
+		if err != nil {
+			return err
+		}
+		h.addReplay(replay)
+	case probe.Events != nil:
+		var batch telemetry.Batch
+		if err := json.Unmarshal(raw, &batch); err != nil {
+			return err
+		}
+		h.addEvents(batch.Events)
+	default:
+		return errors.New("not a replay or telemetry export")
+	}
+	return nil
+}
+
+// addEvents counts the deaths of this level per segment
+func (h *heat) addEvents(events []telemetry.Event) {
+	for _, e := range events {
+		if e.Level != h.key {
+			h.otherLevels++
+			continue
+		}
+		if e.Kind == telemetry.KindDeath {
+			h.segments[e.Segment]++
+			h.telemetry++
+		}
+	}
+}
+
+// addReplay plays a replay back, tracing the player's path and marking
+// where they died
+func (h *heat) addReplay(replay *game.Replay) {
+	if replay.Level != "" && h.level.Name != "" && replay.Level != h.level.Name {
+		fmt.Fprintf(os.Stderr, "heatmap: warning: a replay of %q played on %q\n", replay.Level, h.level.Name)
+	}
+	h.replays++
+	ghost := game.NewGhost(h.level, replay)
+	ghost.Subscribe(func(e game.Event) {
+		if e.Type == game.EventDeath && e.Player != 0 {
+			px, py := h.pixel(e.X, e.Y)
+			h.deaths = append(h.deaths, [2]int{px, py})
+		}
+	})
+
+	prevX, prevY, alive := 0, 0, false
+	for !ghost.Done() {
+		ghost.Step()
+		x, y, ok := ghost.Position()
+		if !ok {
+			alive = false
+			continue
+		}
+		px, py := h.pixel(x, y)
+		if !alive {
+			prevX, prevY = px, py
+		}
+		h.trace(prevX, prevY, px, py)
+		prevX, prevY, alive = px, py, true
+	}
+}
+
+// pixel returns the pixel at the middle of a player's body at (x, y)
+func (h *heat) pixel(x, y float64) (int, int) {
+	return int(math.Floor(x * float64(h.scale))), int(math.Floor((y + 0.45) * float64(h.scale)))
+}
+
+// trace marks the pixels on the line from (x0, y0) to (x1, y1), three
+// pixels wide, as passed by the current replay
+func (h *heat) trace(x0, y0, x1, y1 int) {
+	steps := max(abs(x1-x0), abs(y1-y0), 1)
+	for i := 0; i <= steps; i++ {
+		x := x0 + (x1-x0)*i/steps
+		y := y0 + (y1-y0)*i/steps
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				h.pass(x+dx, y+dy)
+			}
+		}
+	}
+}
+
+func (h *heat) pass(x, y int) {
+	if x < 0 || x >= h.w || y < 0 || y >= h.h {
+		return
+	}
+	i := y*h.w + x
+	if h.seen[i] != h.replays {
+		h.seen[i] = h.replays
+		h.paths[i]++
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// Heatmap colors
+var (
+	emptyColor  = color.RGBA{24, 24, 32, 255}
+	deathColor  = color.RGBA{255, 255, 255, 255}
+	labelColor  = color.RGBA{230, 230, 230, 255}
+	labelBg     = color.RGBA{0, 0, 0, 255}
+	pathColors  = []color.RGBA{{40, 80, 255, 255}, {40, 220, 120, 255}, {255, 230, 40, 255}, {255, 60, 30, 255}}
+	segmentTint = color.RGBA{255, 0, 0, 255}
+)
+
+// draw renders the level's tiles, dimmed, then the telemetry segments, the
+// paths from cool (few replays) to hot (all of them), the deaths and a
+// legend
+func (h *heat) draw() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, h.w, h.h))
+	tiles := h.level.TileMap
+	for ty := range tiles.Height {
+		for tx := range tiles.Width {
+			c := emptyColor
+			if rgb, ok := render.TileFlagColor(tiles.Get(tx, ty)); ok {
+				c = blend(c, color.RGBA{uint8(rgb >> 16), uint8(rgb >> 8), uint8(rgb), 255}, 0.4)
+			}
+			for y := ty * h.scale; y < (ty+1)*h.scale; y++ {
+				for x := tx * h.scale; x < (tx+1)*h.scale; x++ {
+					img.SetRGBA(x, y, c)
+				}
+			}
+		}
+	}
+
+	most := 0
+	for _, n := range h.segments {
+		most = max(most, n)
+	}
+	for seg, n := range h.segments {
+		x0 := max(seg*telemetry.SegmentWidth*h.scale, 0)
+		x1 := min((seg+1)*telemetry.SegmentWidth*h.scale, h.w)
+		if x0 >= x1 {
+			continue
+		}
+		alpha := 0.1 + 0.35*float64(n)/float64(most)
+		for y := range h.h {
+			for x := x0; x < x1; x++ {
+				img.SetRGBA(x, y, blend(img.RGBAAt(x, y), segmentTint, alpha))
+			}
+		}
+		debugdraw.Label(img, x0+4, h.h-debugdraw.CharHeight-4, strconv.Itoa(n), labelColor, labelBg)
+	}
+
+	for i, n := range h.paths {
+		if n > 0 {
+			x, y := i%h.w, i/h.w
+			img.SetRGBA(x, y, blend(img.RGBAAt(x, y), ramp(float64(n)/float64(h.replays)), 0.75))
+		}
+	}
+
+	for _, d := range h.deaths {
+		debugdraw.Cross(img, d[0], d[1], color.Black, h.scale/2+1)
+		debugdraw.Cross(img, d[0], d[1], deathColor, h.scale/2)
+	}
+
+	legend := fmt.Sprintf("%d REPLAYS  %d DEATHS", h.replays, len(h.deaths))
+	if h.telemetry > 0 {
+		legend += fmt.Sprintf("  TELEMETRY: %d DEATHS", h.telemetry)
+	}
+	debugdraw.Label(img, 4, 4, legend, labelColor, labelBg)
+	return img
+}
+
+// ramp returns the path color for the share t of replays passing a pixel
+func ramp(t float64) color.RGBA {
+	t = min(max(t, 0), 1) * float64(len(pathColors)-1)
+	i := min(int(t), len(pathColors)-2)
+	return blend(pathColors[i], pathColors[i+1], t-float64(i))
+}
+
+// blend mixes c over dst by alpha, from 0 to 1
+func blend(dst, c color.RGBA, alpha float64) color.RGBA {
+	mix := func(a, b uint8) uint8 { return uint8(float64(a)*(1-alpha) + float64(b)*alpha + 0.5) }
+	return color.RGBA{mix(dst.R, c.R), mix(dst.G, c.G), mix(dst.B, c.B), 255}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditEntry is one action the bot performed against GitHub or git
+type AuditEntry struct {
+	Time   time.Time `json:"time"`
+	Repo   string    `json:"repo"`
+	Action string    `json:"action"`
+	Target string    `json:"target"`
+	Detail string    `json:"detail,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// AuditLog is an append-only JSON-lines log of performed actions
+type AuditLog struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// OpenAuditLog opens (or creates) the audit log for appending
+func OpenAuditLog(path string) (*AuditLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditLog{f: f}, nil
+}
+
+// Record appends an entry. Each entry is a single write so lines never interleave.
+func (l *AuditLog) Record(e AuditEntry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.f.Write(data)
+	return err
+}
+
+// Close closes the underlying file
+func (l *AuditLog) Close() error {
+	return l.f.Close()
+}
+
+// ReportAction is a single action the bot would have taken in dry-run mode
+type ReportAction struct {
+	Action string
+	Target string
+	Detail string
+}
+
+// reportSection groups actions for one issue, PR or housekeeping step
+type reportSection struct {
+	Title   string
+	Actions []ReportAction
+}
+
+// DryRunReport collects every action a dry run would take and renders it as markdown
+type DryRunReport struct {
+	mu       sync.Mutex
+	repo     string
+	started  time.Time
+	sections []*reportSection
+}
+
+// NewDryRunReport creates an empty report for a repository
+func NewDryRunReport(repo string) *DryRunReport {
+	return &DryRunReport{repo: repo, started: time.Now()}
+}
+
+// Reset clears the report for a new poll cycle
+func (r *DryRunReport) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.started = time.Now()
+	r.sections = nil
+}
+
+// Begin starts a new section; subsequent actions are grouped under it
+func (r *DryRunReport) Begin(title string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sections = append(r.sections, &reportSection{Title: title})
+}
+
+// Add records an action in the current section
+func (r *DryRunReport) Add(action, target, detail string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.sections) == 0 {
+		r.sections = append(r.sections, &reportSection{Title: "Housekeeping"})
+	}
+	s := r.sections[len(r.sections)-1]
+	s.Actions = append(s.Actions, ReportAction{Action: action, Target: target, Detail: detail})
+}
+
+// Markdown renders the report
+func (r *DryRunReport) Markdown() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Issue Bot Dry Run: %s\n\n", r.repo)
+	fmt.Fprintf(&sb, "Generated %s. No changes were made.\n\n", r.started.Format(time.RFC3339))
+
+	if len(r.sections) == 0 {
+		sb.WriteString("_Nothing to do._\n")
+		return sb.String()
+	}
+
+	for _, s := range r.sections {
+		fmt.Fprintf(&sb, "## %s\n\n", s.Title)
+		if len(s.Actions) == 0 {
+			sb.WriteString("_No actions._\n\n")
+			continue
+		}
+		for i, a := range s.Actions {
+			fmt.Fprintf(&sb, "%d. **%s** `%s`\n", i+1, a.Action, a.Target)
+			if a.Detail == "" {
+				continue
+			}
+			if strings.Contains(a.Detail, "\n") {
+				// Multi-line details (prompts, comment bodies) go in a collapsible block
+				sb.WriteString("\n   <details><summary>Details</summary>\n\n   ```\n")
+				for _, line := range strings.Split(a.Detail, "\n") {
+					sb.WriteString("   " + line + "\n")
+				}
+				sb.WriteString("   ```\n\n   </details>\n")
+			} else {
+				fmt.Fprintf(&sb, "   - %s\n", a.Detail)
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// WriteFile writes the rendered report to path
+func (r *DryRunReport) WriteFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(r.Markdown()), 0o644)
+}
+
+// perform runs a side-effecting action, or records it in the dry-run report.
+// Performed actions are appended to the audit log with their outcome.
+func (b *Bot) perform(action, target, detail string, fn func() error) error {
+	if b.cfg.DryRun {
+		if b.report != nil {
+			b.report.Add(action, target, detail)
+		}
+		return nil
+	}
+
+	err := fn()
+
+	if b.audit != nil {
+		entry := AuditEntry{
+			Repo:   b.cfg.RepoName,
+			Action: action,
+			Target: target,
+			Detail: detail,
+		}
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		if aerr := b.audit.Record(entry); aerr != nil {
+			b.logger.Printf("Warning: failed to write audit log: %v", aerr)
+		}
+	}
+	return err
+}
+
+// planned records an informational step in the dry-run report
+func (b *Bot) planned(action, target, detail string) {
+	if b.report != nil {
+		b.report.Add(action, target, detail)
+	}
+}
+
+// planIssue records what processIssue would do. Only read-only calls are made,
+// so steps that depend on Claude's answers are listed as conditional.
+func (b *Bot) planIssue(issue *Issue) {
+	target := fmt.Sprintf("issue #%d", issue.Number)
+	b.report.Begin(fmt.Sprintf("Issue #%d: %s", issue.Number, issue.Title))
+	b.addLabel("issue", issue.Number, b.cfg.Labels.InProgress)
+
+	workflow := b.workflowFor(issue.Labels)
+	if workflow != WorkflowBug && workflow != WorkflowFeature {
+		b.planned("skip", target, "no label mapped to a workflow")
+		b.removeLabel("issue", issue.Number, b.cfg.Labels.InProgress)
+		return
+	}
+	b.planned("workflow", target, string(workflow))
+
+	context := b.fetchIssueContext(issue.Number)
+	if workflow == WorkflowFeature {
+		b.planned("claude", target, alignmentPrompt(issue, context))
+		b.planned("if conflicts", target, fmt.Sprintf("comment conflicts, label '%s'", b.cfg.Labels.WaitingUser))
+	}
+	b.planned("claude", target, analysisPrompt(issue, context, workflow == WorkflowBug))
+	b.planned("if more info needed", target, fmt.Sprintf("comment questions, label '%s'", b.cfg.Labels.WaitingUser))
+
+	branch := fmt.Sprintf("issue-%d-tests", issue.Number)
+	b.planned("claude", branch, "create test cases on branch "+branch+" (prompt depends on analysis)")
+	b.planned("push", branch, "git push -u origin "+branch)
+	b.planned("create-pr", branch, fmt.Sprintf("Test cases for #%d: %s (label '%s')", issue.Number, issue.Title, b.cfg.Labels.TestPR))
+	b.planned("comment", target, "link to the test PR")
+	b.removeLabel("issue", issue.Number, b.cfg.Labels.InProgress)
+	b.addLabel("issue", issue.Number, b.cfg.Labels.WaitingUser)
+}
+
+// planPR records what processPR would do
+func (b *Bot) planPR(pr *PR) {
+	target := fmt.Sprintf("pr #%d", pr.Number)
+	b.report.Begin(fmt.Sprintf("PR #%d: %s", pr.Number, pr.Title))
+	b.addLabel("pr", pr.Number, b.cfg.Labels.InProgress)
+	b.checkoutPRBranch(pr)
+	b.planned("claude", target, implementPrompt(pr))
+	b.pushChanges(pr.HeadRef)
+	b.planned("comment", target, "implementation summary and commit SHA")
+	b.removeLabel("pr", pr.Number, b.cfg.Labels.InProgress)
+	b.checkoutMain()
+}
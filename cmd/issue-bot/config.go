@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Workflow names an issue processing pipeline
+type Workflow string
+
+const (
+	// WorkflowBug reproduces the issue with failing tests
+	WorkflowBug Workflow = "bug"
+	// WorkflowFeature checks doc/ADR alignment, then specifies behavior as tests
+	WorkflowFeature Workflow = "feature"
+	// WorkflowSkip ignores issues carrying the label
+	WorkflowSkip Workflow = "skip"
+)
+
+// LabelConfig holds the label names the bot reads and writes
+type LabelConfig struct {
+	Accepted    string `yaml:"accepted"`
+	InProgress  string `yaml:"in_progress"`
+	WaitingUser string `yaml:"waiting_user"`
+	TestPR      string `yaml:"test_pr"`
+	Failed      string `yaml:"failed"`
+}
+
+// DefaultLabels returns the built-in label names
+func DefaultLabels() LabelConfig {
+	return LabelConfig{
+		Accepted:    LabelAccepted,
+		InProgress:  LabelInProgress,
+		WaitingUser: LabelWaitingUser,
+		TestPR:      LabelBotTestPR,
+		Failed:      LabelBotFailed,
+	}
+}
+
+// merge fills empty fields from fallback
+func (l LabelConfig) merge(fallback LabelConfig) LabelConfig {
+	if l.Accepted == "" {
+		l.Accepted = fallback.Accepted
+	}
+	if l.InProgress == "" {
+		l.InProgress = fallback.InProgress
+	}
+	if l.WaitingUser == "" {
+		l.WaitingUser = fallback.WaitingUser
+	}
+	if l.TestPR == "" {
+		l.TestPR = fallback.TestPR
+	}
+	if l.Failed == "" {
+		l.Failed = fallback.Failed
+	}
+	return l
+}
+
+// ClaudeConfig controls how the claude CLI is invoked
+type ClaudeConfig struct {
+	Model        string        `yaml:"model"`         // Passed as --model when set
+	Timeout      time.Duration `yaml:"timeout"`       // e.g. "5m"
+	AllowedTools string        `yaml:"allowed_tools"` // Passed as --allowedTools
+}
+
+// AutoAcceptConfig decides whose issues are accepted without a maintainer label
+type AutoAcceptConfig struct {
+	Owner   *bool    `yaml:"owner"`   // Auto-accept the repository owner (default true)
+	Authors []string `yaml:"authors"` // Additional trusted authors
+}
+
+// JanitorConfig controls periodic cleanup of stale bot state.
+// A zero threshold disables that cleanup step.
+type JanitorConfig struct {
+	Interval   time.Duration `yaml:"interval"`    // How often the janitor runs
+	PingAfter  time.Duration `yaml:"ping_after"`  // Ping waiting-for-user issues idle this long
+	CloseAfter time.Duration `yaml:"close_after"` // Close bot PRs and delete bot branches idle this long
+	StuckAfter time.Duration `yaml:"stuck_after"` // Clear bot-in-progress labels older than this
+}
+
+// RepoConfig is the per-repository section of the config file.
+// Empty fields inherit the top-level defaults.
+type RepoConfig struct {
+	Name       string              `yaml:"name"` // owner/repo, informational
+	Dir        string              `yaml:"dir"`  // Local clone (relative to the config file)
+	StatePath  string              `yaml:"state"`
+	Labels     LabelConfig         `yaml:"labels"`
+	AutoAccept *AutoAcceptConfig   `yaml:"auto_accept"`
+	Workflows  map[string]Workflow `yaml:"workflows"` // Issue label -> workflow
+	Reviewers  []string            `yaml:"reviewers"` // Review authors trusted besides writers
+}
+
+// FileConfig is the YAML configuration file layout
+type FileConfig struct {
+	PollInterval time.Duration       `yaml:"poll_interval"`
+	Claude       ClaudeConfig        `yaml:"claude"`
+	Labels       LabelConfig         `yaml:"labels"`
+	AutoAccept   AutoAcceptConfig    `yaml:"auto_accept"`
+	Workflows    map[string]Workflow `yaml:"workflows"`
+	Reviewers    []string            `yaml:"reviewers"` // Review authors trusted besides writers
+	Janitor      JanitorConfig       `yaml:"janitor"`
+	MetricsAddr  string              `yaml:"metrics_addr"` // Dashboard and /metrics listen address
+	Repos        []RepoConfig        `yaml:"repos"`
+}
+
+// DefaultFileConfig returns the configuration used when no file exists
+func DefaultFileConfig() FileConfig {
+	owner := true
+	return FileConfig{
+		PollInterval: 15 * time.Second,
+		Claude: ClaudeConfig{
+			Timeout:      300 * time.Second,
+			AllowedTools: "Bash,Read,Write,Edit,Glob,Grep",
+		},
+		Labels:     DefaultLabels(),
+		AutoAccept: AutoAcceptConfig{Owner: &owner},
+		Workflows: map[string]Workflow{
+			"bug":         WorkflowBug,
+			"enhancement": WorkflowFeature,
+		},
+		Janitor: JanitorConfig{
+			Interval:   6 * time.Hour,
+			PingAfter:  7 * 24 * time.Hour,
+			CloseAfter: 30 * 24 * time.Hour,
+			StuckAfter: 2 * time.Hour,
+		},
+	}
+}
+
+// LoadFileConfig reads a YAML config, layering it over the defaults
+func LoadFileConfig(path string) (FileConfig, error) {
+	cfg := DefaultFileConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+
+	var fc FileConfig
+	if err := yaml.Unmarshal(data, &fc); err != nil {
+		return cfg, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	if fc.PollInterval > 0 {
+		cfg.PollInterval = fc.PollInterval
+	}
+	if fc.Claude.Model != "" {
+		cfg.Claude.Model = fc.Claude.Model
+	}
+	if fc.Claude.Timeout > 0 {
+		cfg.Claude.Timeout = fc.Claude.Timeout
+	}
+	if fc.Claude.AllowedTools != "" {
+		cfg.Claude.AllowedTools = fc.Claude.AllowedTools
+	}
+	cfg.Labels = fc.Labels.merge(cfg.Labels)
+	if fc.AutoAccept.Owner != nil {
+		cfg.AutoAccept.Owner = fc.AutoAccept.Owner
+	}
+	cfg.AutoAccept.Authors = fc.AutoAccept.Authors
+	if len(fc.Workflows) > 0 {
+		cfg.Workflows = fc.Workflows
+	}
+	cfg.Reviewers = fc.Reviewers
+	if fc.Janitor.Interval > 0 {
+		cfg.Janitor.Interval = fc.Janitor.Interval
+	}
+	if fc.Janitor.PingAfter != 0 {
+		cfg.Janitor.PingAfter = fc.Janitor.PingAfter
+	}
+	if fc.Janitor.CloseAfter != 0 {
+		cfg.Janitor.CloseAfter = fc.Janitor.CloseAfter
+	}
+	if fc.Janitor.StuckAfter != 0 {
+		cfg.Janitor.StuckAfter = fc.Janitor.StuckAfter
+	}
+	if fc.MetricsAddr != "" {
+		cfg.MetricsAddr = fc.MetricsAddr
+	}
+	cfg.Repos = fc.Repos
+
+	// Resolve repo directories relative to the config file
+	base := filepath.Dir(path)
+	for i := range cfg.Repos {
+		if cfg.Repos[i].Dir != "" && !filepath.IsAbs(cfg.Repos[i].Dir) {
+			cfg.Repos[i].Dir = filepath.Join(base, cfg.Repos[i].Dir)
+		}
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// Validate checks workflow names and required repo fields
+func (fc FileConfig) Validate() error {
+	check := func(where string, workflows map[string]Workflow) error {
+		for label, wf := range workflows {
+			switch wf {
+			case WorkflowBug, WorkflowFeature, WorkflowSkip:
+			default:
+				return fmt.Errorf("%s: label %q maps to unknown workflow %q", where, label, wf)
+			}
+		}
+		return nil
+	}
+
+	if err := check("workflows", fc.Workflows); err != nil {
+		return err
+	}
+	for i, r := range fc.Repos {
+		if r.Dir == "" {
+			return fmt.Errorf("repos[%d]: dir is required", i)
+		}
+		if err := check(fmt.Sprintf("repos[%d].workflows", i), r.Workflows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RepoConfigs returns the effective per-repo settings. Without a repos
+// section the bot serves the repository it was started in.
+func (fc FileConfig) RepoConfigs(defaultDir string) []RepoConfig {
+	repos := fc.Repos
+	if len(repos) == 0 {
+		repos = []RepoConfig{{Dir: defaultDir}}
+	}
+
+	result := make([]RepoConfig, 0, len(repos))
+	for _, r := range repos {
+		r.Labels = r.Labels.merge(fc.Labels)
+		if r.AutoAccept == nil {
+			aa := fc.AutoAccept
+			r.AutoAccept = &aa
+		} else if r.AutoAccept.Owner == nil {
+			r.AutoAccept.Owner = fc.AutoAccept.Owner
+		}
+		if len(r.Workflows) == 0 {
+			r.Workflows = fc.Workflows
+		}
+		if len(r.Reviewers) == 0 {
+			r.Reviewers = fc.Reviewers
+		}
+		if r.StatePath == "" {
+			r.StatePath = filepath.Join(".issue-bot", "state.json")
+		}
+		if !filepath.IsAbs(r.StatePath) {
+			r.StatePath = filepath.Join(r.Dir, r.StatePath)
+		}
+		result = append(result, r)
+	}
+	return result
+}
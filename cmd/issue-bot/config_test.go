@@ -0,0 +1,144 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestValidate tests that unknown workflows and repos without a directory
+// are reported with where they are.
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     FileConfig
+		wantErr string // Empty for valid
+	}{
+		{name: "defaults", cfg: DefaultFileConfig()},
+		{
+			name: "all workflows",
+			cfg: FileConfig{
+				Workflows: map[string]Workflow{"bug": WorkflowBug, "enhancement": WorkflowFeature, "wontfix": WorkflowSkip},
+				Repos:     []RepoConfig{{Dir: "a"}, {Dir: "b", Workflows: map[string]Workflow{"bug": WorkflowSkip}}},
+			},
+		},
+		{
+			name:    "unknown workflow",
+			cfg:     FileConfig{Workflows: map[string]Workflow{"bug": "fix"}},
+			wantErr: `workflows: label "bug" maps to unknown workflow "fix"`,
+		},
+		{
+			name:    "repo without dir",
+			cfg:     FileConfig{Repos: []RepoConfig{{Dir: "a"}, {Name: "b"}}},
+			wantErr: "repos[1]: dir is required",
+		},
+		{
+			name:    "unknown repo workflow",
+			cfg:     FileConfig{Repos: []RepoConfig{{Dir: "a", Workflows: map[string]Workflow{"docs": "write"}}}},
+			wantErr: `repos[0].workflows: label "docs" maps to unknown workflow "write"`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.Validate()
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Errorf("Validate = %v, want nil", err)
+			case tc.wantErr != "" && (err == nil || err.Error() != tc.wantErr):
+				t.Errorf("Validate = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// TestLoadFileConfig tests that a config file is layered over the defaults
+// and repos inherit what they don't set.
+func TestLoadFileConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".issue-bot.yaml")
+	data := strings.Join([]string{
+		"poll_interval: 1m",
+		"labels:",
+		"  accepted: ready",
+		"auto_accept:",
+		"  owner: false",
+		"  authors: [alice]",
+		"reviewers: [bob]",
+		"janitor:",
+		"  ping_after: -1s",
+		"repos:",
+		"  - dir: game",
+		"  - dir: /srv/site",
+		"    state: /var/lib/bot.json",
+		"    labels:",
+		"      in_progress: working",
+		"    auto_accept:",
+		"      authors: [carol]",
+		"    workflows:",
+		"      docs: feature",
+		"    reviewers: [dave]",
+	}, "\n")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFileConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaults := DefaultFileConfig()
+	if cfg.PollInterval != time.Minute || cfg.Claude.Timeout != defaults.Claude.Timeout {
+		t.Errorf("Poll interval %v and Claude timeout %v, want 1m and the default", cfg.PollInterval, cfg.Claude.Timeout)
+	}
+	if cfg.Janitor.PingAfter != -time.Second || cfg.Janitor.Interval != defaults.Janitor.Interval {
+		t.Errorf("Janitor = %+v, want ping_after -1s over the defaults", cfg.Janitor)
+	}
+
+	repos := cfg.RepoConfigs("unused")
+	if len(repos) != 2 {
+		t.Fatalf("%d repos, want 2", len(repos))
+	}
+	game, site := repos[0], repos[1]
+	if game.Dir != filepath.Join(dir, "game") || site.Dir != "/srv/site" {
+		t.Errorf("Dirs %q and %q, want game next to the file and /srv/site", game.Dir, site.Dir)
+	}
+	if game.StatePath != filepath.Join(dir, "game", ".issue-bot", "state.json") || site.StatePath != "/var/lib/bot.json" {
+		t.Errorf("State paths %q and %q", game.StatePath, site.StatePath)
+	}
+
+	wantGame := DefaultLabels()
+	wantGame.Accepted = "ready"
+	wantSite := wantGame
+	wantSite.InProgress = "working"
+	if game.Labels != wantGame || site.Labels != wantSite {
+		t.Errorf("Labels %+v and %+v, want %+v and %+v", game.Labels, site.Labels, wantGame, wantSite)
+	}
+
+	if *game.AutoAccept.Owner || !slices.Equal(game.AutoAccept.Authors, []string{"alice"}) {
+		t.Errorf("Game auto-accept = %v %v, want the file's", *game.AutoAccept.Owner, game.AutoAccept.Authors)
+	}
+	if *site.AutoAccept.Owner || !slices.Equal(site.AutoAccept.Authors, []string{"carol"}) {
+		t.Errorf("Site auto-accept = %v %v, want its own authors and the file's owner setting", *site.AutoAccept.Owner, site.AutoAccept.Authors)
+	}
+	if len(game.Workflows) != 2 || game.Workflows["enhancement"] != WorkflowFeature {
+		t.Errorf("Game workflows = %v, want the defaults", game.Workflows)
+	}
+	if len(site.Workflows) != 1 || site.Workflows["docs"] != WorkflowFeature {
+		t.Errorf("Site workflows = %v, want its own", site.Workflows)
+	}
+	if !slices.Equal(game.Reviewers, []string{"bob"}) || !slices.Equal(site.Reviewers, []string{"dave"}) {
+		t.Errorf("Reviewers %v and %v, want bob and dave", game.Reviewers, site.Reviewers)
+	}
+}
+
+// TestRepoConfigsDefault tests that without repos the bot serves the
+// directory it started in.
+func TestRepoConfigsDefault(t *testing.T) {
+	repos := DefaultFileConfig().RepoConfigs("/src/game")
+	if len(repos) != 1 || repos[0].Dir != "/src/game" || repos[0].Labels != DefaultLabels() || !*repos[0].AutoAccept.Owner {
+		t.Errorf("RepoConfigs = %+v, want /src/game with the defaults", repos)
+	}
+}
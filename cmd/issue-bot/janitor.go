@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pingMarker identifies the janitor's reminder so an issue is pinged only once
+const pingMarker = "**Still Waiting**"
+
+// listLimit caps how many open issues or PRs the janitor lists. gh returns
+// 30 without --limit; a list that reaches the cap may be cut short, so it is
+// treated as a failure rather than as everything that is open.
+const listLimit = 1000
+
+// botBranchPattern matches branches created for test PRs
+var botBranchPattern = regexp.MustCompile(`^issue-\d+-tests$`)
+
+// labeledItem is an open issue or PR as listed by the janitor
+type labeledItem struct {
+	Number    int       `json:"number"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	HeadRef   string    `json:"headRefName"`
+	Comments  []Comment `json:"comments"`
+}
+
+// runJanitor cleans up state that the normal workflow never revisits
+func (b *Bot) runJanitor() {
+	b.logger.Println("Running janitor...")
+	if b.report != nil {
+		b.report.Begin("Janitor")
+	}
+
+	b.clearStuckInProgress()
+	b.pingStaleWaitingIssues()
+	b.closeAbandonedPRs()
+	b.deleteOrphanedBranches()
+}
+
+// clearStuckInProgress removes bot-in-progress labels that outlived the threshold
+// and are not backed by a persisted task
+func (b *Bot) clearStuckInProgress() {
+	if b.cfg.Janitor.StuckAfter <= 0 {
+		return
+	}
+	for _, kind := range []string{"issue", "pr"} {
+		items, err := b.listItems(kind, b.cfg.Labels.InProgress, "number,updatedAt")
+		if err != nil {
+			b.logger.Printf("Janitor: listing in-progress %ss failed: %v", kind, err)
+			continue
+		}
+		for _, item := range items {
+			if b.state.Has(kind, item.Number) || time.Since(item.UpdatedAt) < b.cfg.Janitor.StuckAfter {
+				continue
+			}
+			b.logger.Printf("Janitor: clearing stuck '%s' from %s #%d", b.cfg.Labels.InProgress, kind, item.Number)
+			b.removeLabel(kind, item.Number, b.cfg.Labels.InProgress)
+		}
+	}
+}
+
+// pingStaleWaitingIssues reminds the author once when an issue waited too long
+func (b *Bot) pingStaleWaitingIssues() {
+	if b.cfg.Janitor.PingAfter <= 0 {
+		return
+	}
+	items, err := b.listItems("issue", b.cfg.Labels.WaitingUser, "number,updatedAt,comments")
+	if err != nil {
+		b.logger.Printf("Janitor: listing waiting issues failed: %v", err)
+		return
+	}
+	for _, item := range items {
+		if time.Since(item.UpdatedAt) < b.cfg.Janitor.PingAfter {
+			continue
+		}
+		if n := len(item.Comments); n > 0 && strings.Contains(item.Comments[n-1].Body, pingMarker) {
+			continue
+		}
+		b.logger.Printf("Janitor: pinging stale issue #%d", item.Number)
+		b.commentOnIssue(item.Number, fmt.Sprintf(`🤖 %s
+
+This issue has been waiting for a reply for %s. Please respond to the questions above, or close the issue if it is no longer relevant.`,
+			pingMarker, formatDays(time.Since(item.UpdatedAt))))
+	}
+}
+
+// closeAbandonedPRs closes bot test PRs without activity and deletes their branches
+func (b *Bot) closeAbandonedPRs() {
+	if b.cfg.Janitor.CloseAfter <= 0 {
+		return
+	}
+	items, err := b.listItems("pr", b.cfg.Labels.TestPR, "number,updatedAt,headRefName")
+	if err != nil {
+		b.logger.Printf("Janitor: listing test PRs failed: %v", err)
+		return
+	}
+	for _, item := range items {
+		if b.state.Has("pr", item.Number) || time.Since(item.UpdatedAt) < b.cfg.Janitor.CloseAfter {
+			continue
+		}
+		b.logger.Printf("Janitor: closing abandoned PR #%d", item.Number)
+		comment := fmt.Sprintf("🤖 **Closing Abandoned PR**\n\nNo activity for %s. Reopen the linked issue or push to the branch to start over.",
+			formatDays(time.Since(item.UpdatedAt)))
+		b.perform("close-pr", fmt.Sprintf("pr #%d", item.Number), "delete branch "+item.HeadRef, func() error {
+			cmd := exec.Command("gh", "pr", "close", strconv.Itoa(item.Number),
+				"--comment", comment,
+				"--delete-branch")
+			cmd.Dir = b.cfg.ProjectDir
+			return cmd.Run()
+		})
+	}
+}
+
+// deleteOrphanedBranches removes bot branches that no open PR points at
+func (b *Bot) deleteOrphanedBranches() {
+	if b.cfg.Janitor.CloseAfter <= 0 {
+		return
+	}
+
+	// Without the full list of open PRs every bot branch would look orphaned
+	items, err := b.listItems("pr", "", "number,headRefName")
+	if err != nil {
+		b.logger.Printf("Janitor: listing open PRs failed, not deleting branches: %v", err)
+		return
+	}
+
+	fetch := exec.Command("git", "fetch", "--prune", "origin")
+	fetch.Dir = b.cfg.ProjectDir
+	if err := fetch.Run(); err != nil {
+		b.logger.Printf("Janitor: git fetch failed: %v", err)
+		return
+	}
+
+	open := make(map[string]bool)
+	for _, item := range items {
+		open[item.HeadRef] = true
+	}
+	for _, t := range b.state.Tasks() {
+		open[t.Branch] = true
+	}
+
+	for _, ref := range b.branchRefs("refs/remotes/origin") {
+		branch := strings.TrimPrefix(ref.name, "origin/")
+		if open[branch] || !botBranchPattern.MatchString(branch) || time.Since(ref.updated) < b.cfg.Janitor.CloseAfter {
+			continue
+		}
+		b.logger.Printf("Janitor: deleting orphaned remote branch %s", branch)
+		b.perform("delete-branch", branch, "remote", func() error {
+			cmd := exec.Command("git", "push", "origin", "--delete", branch)
+			cmd.Dir = b.cfg.ProjectDir
+			return cmd.Run()
+		})
+	}
+
+	for _, ref := range b.branchRefs("refs/heads") {
+		if open[ref.name] || !botBranchPattern.MatchString(ref.name) || time.Since(ref.updated) < b.cfg.Janitor.CloseAfter {
+			continue
+		}
+		b.logger.Printf("Janitor: deleting orphaned local branch %s", ref.name)
+		b.deleteLocalBranch(ref.name)
+	}
+}
+
+// listItems lists open issues or PRs, optionally filtered by label. It fails
+// rather than return a partial list.
+func (b *Bot) listItems(kind, label, fields string) ([]labeledItem, error) {
+	args := []string{kind, "list", "--state", "open", "--limit", strconv.Itoa(listLimit), "--json", fields}
+	if label != "" {
+		args = append(args, "--label", label)
+	}
+	cmd := exec.Command("gh", args...)
+	cmd.Dir = b.cfg.ProjectDir
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("gh %s list: %w", kind, err)
+	}
+
+	var items []labeledItem
+	if err := json.Unmarshal(output, &items); err != nil {
+		return nil, fmt.Errorf("gh %s list: %w", kind, err)
+	}
+	if len(items) >= listLimit {
+		return nil, fmt.Errorf("%d or more open %ss, the list may be incomplete", listLimit, kind)
+	}
+	return items, nil
+}
+
+type branchRef struct {
+	name    string
+	updated time.Time
+}
+
+// branchRefs lists branches under a ref prefix with their last commit time
+func (b *Bot) branchRefs(prefix string) []branchRef {
+	cmd := exec.Command("git", "for-each-ref",
+		"--format=%(refname:short) %(committerdate:unix)", prefix)
+	cmd.Dir = b.cfg.ProjectDir
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	var refs []branchRef
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		name, ts, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		unix, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			continue
+		}
+		refs = append(refs, branchRef{name: name, updated: time.Unix(unix, 0)})
+	}
+	return refs
+}
+
+func formatDays(d time.Duration) string {
+	days := int(d.Hours() / 24)
+	if days == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
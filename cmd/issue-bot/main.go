@@ -4,6 +4,7 @@
 //  1. Monitor accepted issues → analyze and create failing tests
 //  2. Create PR with test cases → link to issue
 //  3. Monitor accepted PRs → implement fix until tests pass
+//  4. Monitor review threads on bot PRs → amend the branch and reply
 //
 // Usage:
 //
@@ -13,15 +14,25 @@
 //
 //	-poll      Poll interval in seconds (default: 15)
 //	-timeout   Claude timeout in seconds (default: 300)
-//	-dry-run   Print actions without executing
+//	-dry-run   Write a report of planned actions without executing
 //	-once      Run once then exit (don't loop)
+//	-state     Task state file (default: .issue-bot/state.json)
+//	-config    YAML config file (default: .issue-bot.yaml, optional)
+//	-report    Dry-run markdown report (default: .issue-bot/dry-run-report.md)
+//	-audit     Append-only audit log (default: .issue-bot/audit.log)
+//	-metrics   Address for the status dashboard and /metrics (default: off)
+//
+// Flags override values from the config file. See docs/issue-bot-workflow.md
+// for the config file format, including serving multiple repositories.
 package main
 
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
 	"os/exec"
@@ -33,7 +44,7 @@ import (
 	"time"
 )
 
-// Labels used by the bot
+// Default labels used by the bot (overridable via the config file)
 const (
 	LabelAccepted    = "accepted"
 	LabelInProgress  = "bot-in-progress"
@@ -42,35 +53,46 @@ const (
 	LabelBotFailed   = "bot-failed"
 )
 
-// Config holds bot configuration
+// Config holds bot configuration for a single repository
 type Config struct {
 	PollInterval  time.Duration
 	ClaudeTimeout time.Duration
+	ClaudeModel   string
+	AllowedTools  string
 	DryRun        bool
 	Once          bool
-	OwnerUsername string
+	RepoName      string
+	AutoAccept    []string // Authors whose issues are accepted automatically
+	BotLogin      string   // GitHub login the bot comments as
+	Reviewers     []string // Review authors trusted besides those with write access
 	ProjectDir    string
+	StatePath     string
+	ReportPath    string // Dry-run markdown report
+	AuditPath     string // Append-only JSON-lines log of performed actions
+	Labels        LabelConfig
+	Workflows     map[string]Workflow // Issue label -> workflow
+	Janitor       JanitorConfig
 }
 
 // Issue represents a GitHub issue
 type Issue struct {
-	Number int      `json:"number"`
-	Title  string   `json:"title"`
-	Body   string   `json:"body"`
-	Labels []Label  `json:"labels"`
-	Author Author   `json:"author"`
-	State  string   `json:"state"`
+	Number int     `json:"number"`
+	Title  string  `json:"title"`
+	Body   string  `json:"body"`
+	Labels []Label `json:"labels"`
+	Author Author  `json:"author"`
+	State  string  `json:"state"`
 }
 
 // PR represents a GitHub pull request
 type PR struct {
-	Number    int      `json:"number"`
-	Title     string   `json:"title"`
-	Body      string   `json:"body"`
-	Labels    []Label  `json:"labels"`
-	State     string   `json:"state"`
-	HeadRef   string   `json:"headRefName"`
-	Mergeable string   `json:"mergeable"`
+	Number    int     `json:"number"`
+	Title     string  `json:"title"`
+	Body      string  `json:"body"`
+	Labels    []Label `json:"labels"`
+	State     string  `json:"state"`
+	HeadRef   string  `json:"headRefName"`
+	Mergeable string  `json:"mergeable"`
 }
 
 // Label represents a GitHub label
@@ -92,45 +114,101 @@ type Comment struct {
 
 // Bot is the main issue bot
 type Bot struct {
-	cfg    Config
-	logger *log.Logger
+	cfg     Config
+	logger  *log.Logger
+	state   *StateStore
+	audit   *AuditLog     // Performed actions (nil in dry-run)
+	report  *DryRunReport // Planned actions (dry-run only)
+	metrics *Metrics      // Shared across repos (nil without -metrics)
+
+	lastJanitor time.Time
 }
 
 func main() {
 	pollInterval := flag.Int("poll", 15, "Poll interval in seconds")
 	claudeTimeout := flag.Int("timeout", 300, "Claude timeout in seconds")
-	dryRun := flag.Bool("dry-run", false, "Print actions without executing")
+	dryRun := flag.Bool("dry-run", false, "Write a report of planned actions without executing")
 	once := flag.Bool("once", false, "Run once then exit")
+	statePath := flag.String("state", filepath.Join(".issue-bot", "state.json"), "Task state file (relative to project root)")
+	configPath := flag.String("config", ".issue-bot.yaml", "YAML config file (relative to project root)")
+	reportPath := flag.String("report", filepath.Join(".issue-bot", "dry-run-report.md"), "Dry-run markdown report (relative to repo)")
+	auditPath := flag.String("audit", filepath.Join(".issue-bot", "audit.log"), "Audit log of performed actions (relative to repo)")
+	metricsAddr := flag.String("metrics", "", "Serve dashboard and Prometheus metrics on this address (e.g. :9090)")
 	flag.Parse()
 
+	setFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+
 	// Find project directory (where .git is)
 	projectDir, err := findProjectRoot()
 	if err != nil {
 		log.Fatalf("Failed to find project root: %v", err)
 	}
 
-	// Get repo owner from git remote
-	owner := getRepoOwner()
+	path := *configPath
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(projectDir, path)
+	}
+	fileCfg, err := LoadFileConfig(path)
+	if err != nil {
+		// A missing default config is fine; an explicitly requested one is not
+		if !errors.Is(err, fs.ErrNotExist) || setFlags["config"] {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		fileCfg = DefaultFileConfig()
+	}
 
-	cfg := Config{
-		PollInterval:  time.Duration(*pollInterval) * time.Second,
-		ClaudeTimeout: time.Duration(*claudeTimeout) * time.Second,
-		DryRun:        *dryRun,
-		Once:          *once,
-		OwnerUsername: owner,
-		ProjectDir:    projectDir,
+	// Command-line flags take precedence over the config file
+	if setFlags["poll"] {
+		fileCfg.PollInterval = time.Duration(*pollInterval) * time.Second
+	}
+	if setFlags["timeout"] {
+		fileCfg.Claude.Timeout = time.Duration(*claudeTimeout) * time.Second
+	}
+	if setFlags["metrics"] {
+		fileCfg.MetricsAddr = *metricsAddr
 	}
 
-	bot := &Bot{
-		cfg:    cfg,
-		logger: log.New(os.Stdout, "", log.LstdFlags),
+	var metrics *Metrics
+	if fileCfg.MetricsAddr != "" {
+		metrics = NewMetrics()
+		metrics.ServeMetrics(fileCfg.MetricsAddr)
 	}
 
-	if err := bot.checkDependencies(); err != nil {
+	repos := fileCfg.RepoConfigs(projectDir)
+	if setFlags["state"] {
+		if len(repos) > 1 {
+			log.Fatalf("-state cannot be used with multiple repos; set 'state' per repo in the config")
+		}
+		repos[0].StatePath = *statePath
+		if !filepath.IsAbs(repos[0].StatePath) {
+			repos[0].StatePath = filepath.Join(repos[0].Dir, repos[0].StatePath)
+		}
+	}
+
+	var bots []*Bot
+	for _, repo := range repos {
+		bot, err := newBot(fileCfg, repo, *dryRun, *once, len(repos) > 1)
+		if err == nil {
+			err = bot.openOutputs(*reportPath, *auditPath)
+		}
+		if err != nil {
+			log.Fatalf("Failed to set up %s: %v", repo.Dir, err)
+		}
+		bot.metrics = metrics
+		bots = append(bots, bot)
+	}
+
+	if err := bots[0].checkDependencies(); err != nil {
 		log.Fatalf("Dependency check failed: %v", err)
 	}
 
-	bot.ensureLabels()
+	for _, bot := range bots {
+		bot.ensureLabels()
+
+		// Resume or roll back anything a previous run left half-finished
+		bot.recoverTasks()
+	}
 
 	// Handle graceful shutdown
 	sigCh := make(chan os.Signal, 1)
@@ -138,78 +216,200 @@ func main() {
 
 	go func() {
 		<-sigCh
-		bot.logger.Println("Shutting down...")
+		log.Println("Shutting down...")
 		os.Exit(0)
 	}()
 
-	bot.logger.Printf("Issue Bot starting (poll=%s, timeout=%s, dry-run=%v)",
-		cfg.PollInterval, cfg.ClaudeTimeout, cfg.DryRun)
+	log.Printf("Issue Bot starting (repos=%d, poll=%s, timeout=%s, dry-run=%v)",
+		len(bots), fileCfg.PollInterval, fileCfg.Claude.Timeout, *dryRun)
 
-	bot.run()
+	runBots(bots, fileCfg.PollInterval, *once)
 }
 
-func (b *Bot) run() {
-	for {
-		b.logger.Println("--- Poll cycle starting ---")
+// newBot builds the bot for one repository from the effective config
+func newBot(fileCfg FileConfig, repo RepoConfig, dryRun, once, prefixLogs bool) (*Bot, error) {
+	name := repo.Name
+	if name == "" {
+		name = filepath.Base(repo.Dir)
+	}
 
-		// Pull latest changes
-		b.logger.Println("Pulling latest changes...")
-		b.gitPull()
+	cfg := Config{
+		PollInterval:  fileCfg.PollInterval,
+		ClaudeTimeout: fileCfg.Claude.Timeout,
+		ClaudeModel:   fileCfg.Claude.Model,
+		AllowedTools:  fileCfg.Claude.AllowedTools,
+		DryRun:        dryRun,
+		Once:          once,
+		RepoName:      name,
+		ProjectDir:    repo.Dir,
+		StatePath:     repo.StatePath,
+		Labels:        repo.Labels,
+		Workflows:     repo.Workflows,
+		Reviewers:     repo.Reviewers,
+		Janitor:       fileCfg.Janitor,
+	}
+
+	// Review threads are waiting on the bot unless it wrote the last comment
+	cfg.BotLogin = getLogin(repo.Dir)
+	if cfg.BotLogin == "" {
+		return nil, fmt.Errorf("could not determine the GitHub login of the bot (gh api user)")
+	}
 
-		// Auto-accept owner issues
-		b.autoAcceptOwnerIssues()
+	// Get repo owner from git remote
+	if repo.AutoAccept.Owner == nil || *repo.AutoAccept.Owner {
+		if owner := getRepoOwner(repo.Dir); owner != "" {
+			cfg.AutoAccept = append(cfg.AutoAccept, owner)
+		}
+	}
+	cfg.AutoAccept = append(cfg.AutoAccept, repo.AutoAccept.Authors...)
 
-		// Check waiting issues for new feedback
-		b.checkWaitingIssuesForFeedback()
+	state, err := LoadStateStore(cfg.StatePath)
+	if err != nil {
+		return nil, fmt.Errorf("load state: %w", err)
+	}
 
-		// Process accepted issues (Phase 1: Test creation)
-		b.logger.Println("Checking for accepted issues...")
-		if issue := b.getNextAcceptedIssue(); issue != nil {
-			b.processIssue(issue)
-		} else {
-			b.logger.Println("No accepted issues to process")
-		}
+	prefix := ""
+	if prefixLogs {
+		prefix = "[" + name + "] "
+	}
 
-		// Process accepted PRs (Phase 2: Implementation)
-		b.logger.Println("Checking for accepted PRs...")
-		if pr := b.getNextAcceptedPR(); pr != nil {
-			b.processPR(pr)
-		} else {
-			b.logger.Println("No accepted PRs to process")
+	return &Bot{
+		cfg:    cfg,
+		logger: log.New(os.Stdout, prefix, log.LstdFlags),
+		state:  state,
+	}, nil
+}
+
+// openOutputs sets up the dry-run report or the audit log, depending on mode
+func (b *Bot) openOutputs(reportPath, auditPath string) error {
+	if !filepath.IsAbs(reportPath) {
+		reportPath = filepath.Join(b.cfg.ProjectDir, reportPath)
+	}
+	if !filepath.IsAbs(auditPath) {
+		auditPath = filepath.Join(b.cfg.ProjectDir, auditPath)
+	}
+	b.cfg.ReportPath = reportPath
+	b.cfg.AuditPath = auditPath
+
+	if b.cfg.DryRun {
+		b.report = NewDryRunReport(b.cfg.RepoName)
+		return nil
+	}
+
+	audit, err := OpenAuditLog(auditPath)
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	b.audit = audit
+	return nil
+}
+
+// runBots runs poll cycles for every repository until stopped
+func runBots(bots []*Bot, pollInterval time.Duration, once bool) {
+	for {
+		for _, b := range bots {
+			b.pollCycle()
 		}
 
-		if b.cfg.Once {
-			b.logger.Println("Single run complete, exiting")
+		if once {
+			log.Println("Single run complete, exiting")
 			return
 		}
 
-		b.logger.Printf("Sleeping for %s...", b.cfg.PollInterval)
-		time.Sleep(b.cfg.PollInterval)
+		log.Printf("Sleeping for %s...", pollInterval)
+		time.Sleep(pollInterval)
 	}
 }
 
+// pollCycle performs one pass over the repository's issues and PRs
+func (b *Bot) pollCycle() {
+	b.logger.Println("--- Poll cycle starting ---")
+
+	if b.report != nil {
+		b.report.Reset()
+		defer b.writeReport()
+	}
+
+	// Pull latest changes
+	b.logger.Println("Pulling latest changes...")
+	b.gitPull()
+
+	// Auto-accept trusted authors' issues
+	b.autoAcceptIssues()
+
+	// Check waiting issues for new feedback
+	b.checkWaitingIssuesForFeedback()
+
+	// Periodic cleanup of stale labels, issues and branches
+	if time.Since(b.lastJanitor) >= b.cfg.Janitor.Interval {
+		b.runJanitor()
+		b.lastJanitor = time.Now()
+	}
+
+	// Process accepted issues (Phase 1: Test creation)
+	b.logger.Println("Checking for accepted issues...")
+	if issue := b.getNextAcceptedIssue(); issue != nil {
+		b.processIssue(issue)
+	} else {
+		b.logger.Println("No accepted issues to process")
+	}
+
+	// Process accepted PRs (Phase 2: Implementation)
+	b.logger.Println("Checking for accepted PRs...")
+	if pr := b.getNextAcceptedPR(); pr != nil {
+		b.processPR(pr)
+	} else {
+		b.logger.Println("No accepted PRs to process")
+	}
+
+	// Address review comments on bot PRs (Phase 3: Review follow-up)
+	b.logger.Println("Checking for unresolved review comments...")
+	if pr, threads := b.getNextReviewedPR(); pr != nil {
+		b.processReview(pr, threads)
+	} else {
+		b.logger.Println("No review comments to address")
+	}
+
+	b.updateQueueMetrics()
+}
+
+// writeReport saves the dry-run report for the cycle that just finished
+func (b *Bot) writeReport() {
+	if err := b.report.WriteFile(b.cfg.ReportPath); err != nil {
+		b.logger.Printf("Failed to write dry-run report: %v", err)
+		return
+	}
+	b.logger.Printf("[DRY RUN] Report written to %s", b.cfg.ReportPath)
+}
+
 // processIssue handles an accepted issue - analyzes it and creates test cases
 func (b *Bot) processIssue(issue *Issue) {
 	b.logger.Printf("Processing issue #%d: %s", issue.Number, issue.Title)
 
 	if b.cfg.DryRun {
 		b.logger.Printf("[DRY RUN] Would process issue #%d", issue.Number)
+		b.planIssue(issue)
 		return
 	}
 
+	task := b.beginTask("issue", issue.Number)
+	defer b.finishTask(task)
+
 	// Add in-progress label
-	b.addLabel("issue", issue.Number, LabelInProgress)
+	b.addLabel("issue", issue.Number, b.cfg.Labels.InProgress)
 
 	// Fetch full issue context with comments
 	context := b.fetchIssueContext(issue.Number)
 
-	// Check if issue type is bug or feature
-	isBug := b.hasLabel(issue.Labels, "bug")
-	isFeature := b.hasLabel(issue.Labels, "enhancement")
+	// Pick the workflow from the issue's labels
+	workflow := b.workflowFor(issue.Labels)
+	isBug := workflow == WorkflowBug
+	isFeature := workflow == WorkflowFeature
 
 	if !isBug && !isFeature {
-		b.logger.Printf("Issue #%d has neither 'bug' nor 'enhancement' label, skipping", issue.Number)
-		b.removeLabel("issue", issue.Number, LabelInProgress)
+		b.logger.Printf("Issue #%d has no label mapped to a workflow, skipping", issue.Number)
+		b.removeLabel("issue", issue.Number, b.cfg.Labels.InProgress)
+		b.recordOutcome("issue", issue.Number, OutcomeSkipped, task, "")
 		return
 	}
 
@@ -223,17 +423,20 @@ func (b *Bot) processIssue(issue *Issue) {
 %s
 
 Please clarify how this feature should align with the project direction, or update the documentation/ADRs first.`, conflicts))
-			b.removeLabel("issue", issue.Number, LabelInProgress)
-			b.addLabel("issue", issue.Number, LabelWaitingUser)
+			b.removeLabel("issue", issue.Number, b.cfg.Labels.InProgress)
+			b.addLabel("issue", issue.Number, b.cfg.Labels.WaitingUser)
+			b.recordOutcome("issue", issue.Number, OutcomeWaiting, task, "")
 			return
 		}
 	}
 
 	// Phase 1b: Check if we have enough info to reproduce
+	b.setPhase(task, PhaseAnalyzing)
 	analysis := b.analyzeIssue(issue, context, isBug)
 	if analysis == nil {
-		b.removeLabel("issue", issue.Number, LabelInProgress)
-		b.addLabel("issue", issue.Number, LabelBotFailed)
+		b.removeLabel("issue", issue.Number, b.cfg.Labels.InProgress)
+		b.addLabel("issue", issue.Number, b.cfg.Labels.Failed)
+		b.recordOutcome("issue", issue.Number, OutcomeFailed, task, "analysis failed")
 		return
 	}
 
@@ -243,29 +446,57 @@ Please clarify how this feature should align with the project direction, or upda
 %s
 
 Please provide the requested information so I can create accurate test cases.`, analysis.Questions))
-		b.removeLabel("issue", issue.Number, LabelInProgress)
-		b.addLabel("issue", issue.Number, LabelWaitingUser)
+		b.removeLabel("issue", issue.Number, b.cfg.Labels.InProgress)
+		b.addLabel("issue", issue.Number, b.cfg.Labels.WaitingUser)
+		b.recordOutcome("issue", issue.Number, OutcomeWaiting, task, "")
 		return
 	}
 
 	// Phase 1c: Create test cases
+	b.updateTask(task, func(t *Task) {
+		t.Phase = PhaseCreatingTests
+		t.Branch = fmt.Sprintf("issue-%d-tests", issue.Number)
+	})
 	testResult := b.createTestCases(issue, analysis)
 	if testResult == nil {
-		b.removeLabel("issue", issue.Number, LabelInProgress)
-		b.addLabel("issue", issue.Number, LabelBotFailed)
+		b.removeLabel("issue", issue.Number, b.cfg.Labels.InProgress)
+		b.addLabel("issue", issue.Number, b.cfg.Labels.Failed)
+		b.recordOutcome("issue", issue.Number, OutcomeFailed, task, "test creation failed")
 		return
 	}
 
 	// Phase 1d: Create PR with tests
+	b.updateTask(task, func(t *Task) {
+		t.Phase = PhaseCreatingPR
+		if testResult.Branch != "" {
+			t.Branch = testResult.Branch
+		}
+	})
 	prNumber := b.createTestPR(issue, testResult)
 	if prNumber == 0 {
-		b.removeLabel("issue", issue.Number, LabelInProgress)
-		b.addLabel("issue", issue.Number, LabelBotFailed)
+		b.removeLabel("issue", issue.Number, b.cfg.Labels.InProgress)
+		b.addLabel("issue", issue.Number, b.cfg.Labels.Failed)
+		b.recordOutcome("issue", issue.Number, OutcomeFailed, task, "PR creation failed")
 		return
 	}
+	b.updateTask(task, func(t *Task) {
+		t.Phase = PhasePRCreated
+		t.PRNumber = prNumber
+	})
+	b.recordOutcome("issue", issue.Number, OutcomePRCreated, task, "")
+	if accepted, ok := b.acceptedAt(issue.Number); ok {
+		b.metrics.RecordPRCreated(b.cfg.RepoName, time.Since(accepted))
+	} else {
+		b.metrics.RecordPRCreated(b.cfg.RepoName, time.Since(task.StartedAt))
+	}
 
-	// Link PR to issue and mark waiting
-	b.commentOnIssue(issue.Number, fmt.Sprintf(`🤖 **Test Cases Created**
+	b.linkTestPR(issue.Number, prNumber)
+	b.logger.Printf("Issue #%d: Created test PR #%d", issue.Number, prNumber)
+}
+
+// linkTestPR comments the test PR on the issue and hands it back to the user
+func (b *Bot) linkTestPR(issueNumber, prNumber int) {
+	b.commentOnIssue(issueNumber, fmt.Sprintf(`🤖 **Test Cases Created**
 
 I've created PR #%d with test cases that reproduce this issue.
 
@@ -276,10 +507,8 @@ I've created PR #%d with test cases that reproduce this issue.
 
 The focus now moves to the PR. I'll wait for your approval there.`, prNumber))
 
-	b.removeLabel("issue", issue.Number, LabelInProgress)
-	b.addLabel("issue", issue.Number, LabelWaitingUser)
-
-	b.logger.Printf("Issue #%d: Created test PR #%d", issue.Number, prNumber)
+	b.removeLabel("issue", issueNumber, b.cfg.Labels.InProgress)
+	b.addLabel("issue", issueNumber, b.cfg.Labels.WaitingUser)
 }
 
 // processPR handles an accepted PR - implements the fix
@@ -288,21 +517,31 @@ func (b *Bot) processPR(pr *PR) {
 
 	if b.cfg.DryRun {
 		b.logger.Printf("[DRY RUN] Would process PR #%d", pr.Number)
+		b.planPR(pr)
 		return
 	}
 
+	task := b.beginTask("pr", pr.Number)
+	defer b.finishTask(task)
+
 	// Add in-progress label
-	b.addLabel("pr", pr.Number, LabelInProgress)
+	b.addLabel("pr", pr.Number, b.cfg.Labels.InProgress)
 
 	// Check out the PR branch
+	b.updateTask(task, func(t *Task) {
+		t.Phase = PhaseCheckout
+		t.Branch = pr.HeadRef
+	})
 	if err := b.checkoutPRBranch(pr); err != nil {
 		b.logger.Printf("Failed to checkout PR branch: %v", err)
-		b.removeLabel("pr", pr.Number, LabelInProgress)
-		b.addLabel("pr", pr.Number, LabelBotFailed)
+		b.removeLabel("pr", pr.Number, b.cfg.Labels.InProgress)
+		b.addLabel("pr", pr.Number, b.cfg.Labels.Failed)
+		b.recordOutcome("pr", pr.Number, OutcomeFailed, task, "checkout: "+err.Error())
 		return
 	}
 
 	// Implement the fix
+	b.setPhase(task, PhaseImplementing)
 	result := b.implementFix(pr)
 	if result == nil || !result.Success {
 		errMsg := "Unknown error"
@@ -314,17 +553,20 @@ func (b *Bot) processPR(pr *PR) {
 ❌ %s
 
 Manual intervention may be required.`, errMsg))
-		b.removeLabel("pr", pr.Number, LabelInProgress)
-		b.addLabel("pr", pr.Number, LabelBotFailed)
+		b.removeLabel("pr", pr.Number, b.cfg.Labels.InProgress)
+		b.addLabel("pr", pr.Number, b.cfg.Labels.Failed)
+		b.recordOutcome("pr", pr.Number, OutcomeFailed, task, errMsg)
 		b.checkoutMain()
 		return
 	}
 
 	// Push the fix
+	b.setPhase(task, PhasePushing)
 	if err := b.pushChanges(pr.HeadRef); err != nil {
 		b.logger.Printf("Failed to push changes: %v", err)
-		b.removeLabel("pr", pr.Number, LabelInProgress)
-		b.addLabel("pr", pr.Number, LabelBotFailed)
+		b.removeLabel("pr", pr.Number, b.cfg.Labels.InProgress)
+		b.addLabel("pr", pr.Number, b.cfg.Labels.Failed)
+		b.recordOutcome("pr", pr.Number, OutcomeFailed, task, "push: "+err.Error())
 		b.checkoutMain()
 		return
 	}
@@ -337,19 +579,20 @@ Manual intervention may be required.`, errMsg))
 
 All tests should now pass. Please review and merge when ready.`, result.Summary, result.CommitSHA))
 
-	b.removeLabel("pr", pr.Number, LabelInProgress)
+	b.removeLabel("pr", pr.Number, b.cfg.Labels.InProgress)
 	b.checkoutMain()
+	b.recordOutcome("pr", pr.Number, OutcomeImplemented, task, "")
 
 	b.logger.Printf("PR #%d: Implementation complete", pr.Number)
 }
 
 // IssueAnalysis holds the result of analyzing an issue
 type IssueAnalysis struct {
-	NeedsMoreInfo   bool
-	Questions       string
-	RootCause       string
-	RelevantFiles   []string
-	TestStrategy    string
+	NeedsMoreInfo    bool
+	Questions        string
+	RootCause        string
+	RelevantFiles    []string
+	TestStrategy     string
 	ExpectedBehavior string
 }
 
@@ -370,12 +613,48 @@ type ImplementResult struct {
 
 // analyzeIssue uses Claude to analyze the issue and determine what's needed
 func (b *Bot) analyzeIssue(issue *Issue, context string, isBug bool) *IssueAnalysis {
+	output, err := b.runClaude(analysisPrompt(issue, context, isBug))
+	if err != nil {
+		b.logger.Printf("Claude analysis failed: %v", err)
+		return nil
+	}
+
+	section := extractSection(output, "---ANALYSIS_RESULT---", "---END_ANALYSIS---")
+	if section == "" {
+		b.logger.Printf("Could not extract analysis from Claude output")
+		b.commentOnIssue(issue.Number, "🤖 **Analysis Failed**\n\nCould not parse Claude's analysis output. Manual intervention required.")
+		return nil
+	}
+
+	analysis := &IssueAnalysis{
+		NeedsMoreInfo:    extractField(section, "NEEDS_MORE_INFO") == "YES",
+		Questions:        extractField(section, "QUESTIONS"),
+		RootCause:        extractField(section, "ROOT_CAUSE"),
+		TestStrategy:     extractField(section, "TEST_STRATEGY"),
+		ExpectedBehavior: extractField(section, "EXPECTED_BEHAVIOR"),
+	}
+
+	filesStr := extractField(section, "RELEVANT_FILES")
+	if filesStr != "" && filesStr != "N/A" {
+		for _, f := range strings.Split(filesStr, ",") {
+			f = strings.TrimSpace(f)
+			if f != "" {
+				analysis.RelevantFiles = append(analysis.RelevantFiles, f)
+			}
+		}
+	}
+
+	return analysis
+}
+
+// analysisPrompt builds the prompt for analyzeIssue
+func analysisPrompt(issue *Issue, context string, isBug bool) string {
 	issueType := "feature request"
 	if isBug {
 		issueType = "bug report"
 	}
 
-	prompt := fmt.Sprintf(`You are analyzing GitHub issue #%d: %s
+	return fmt.Sprintf(`You are analyzing GitHub issue #%d: %s
 
 This is a %s.
 
@@ -409,44 +688,30 @@ RELEVANT_FILES: <comma-separated list of VERIFIED file paths>
 TEST_STRATEGY: <How to test this - what test file to create/modify, what to test>
 EXPECTED_BEHAVIOR: <What should happen when the fix is complete>
 ---END_ANALYSIS---`, issue.Number, issue.Title, issueType, context)
+}
 
-	output, err := b.runClaude(prompt)
+// checkDocAlignment checks if a feature aligns with project documentation
+func (b *Bot) checkDocAlignment(issue *Issue, context string) string {
+	output, err := b.runClaude(alignmentPrompt(issue, context))
 	if err != nil {
-		b.logger.Printf("Claude analysis failed: %v", err)
-		return nil
+		return "" // Assume no conflicts on error
 	}
 
-	section := extractSection(output, "---ANALYSIS_RESULT---", "---END_ANALYSIS---")
+	section := extractSection(output, "---ALIGNMENT_CHECK---", "---END_ALIGNMENT_CHECK---")
 	if section == "" {
-		b.logger.Printf("Could not extract analysis from Claude output")
-		b.commentOnIssue(issue.Number, "🤖 **Analysis Failed**\n\nCould not parse Claude's analysis output. Manual intervention required.")
-		return nil
-	}
-
-	analysis := &IssueAnalysis{
-		NeedsMoreInfo:    extractField(section, "NEEDS_MORE_INFO") == "YES",
-		Questions:        extractField(section, "QUESTIONS"),
-		RootCause:        extractField(section, "ROOT_CAUSE"),
-		TestStrategy:     extractField(section, "TEST_STRATEGY"),
-		ExpectedBehavior: extractField(section, "EXPECTED_BEHAVIOR"),
+		return ""
 	}
 
-	filesStr := extractField(section, "RELEVANT_FILES")
-	if filesStr != "" && filesStr != "N/A" {
-		for _, f := range strings.Split(filesStr, ",") {
-			f = strings.TrimSpace(f)
-			if f != "" {
-				analysis.RelevantFiles = append(analysis.RelevantFiles, f)
-			}
-		}
+	if extractField(section, "HAS_CONFLICTS") == "YES" {
+		return extractField(section, "CONFLICTS")
 	}
 
-	return analysis
+	return ""
 }
 
-// checkDocAlignment checks if a feature aligns with project documentation
-func (b *Bot) checkDocAlignment(issue *Issue, context string) string {
-	prompt := fmt.Sprintf(`You are checking if GitHub issue #%d conflicts with project documentation.
+// alignmentPrompt builds the prompt for checkDocAlignment
+func alignmentPrompt(issue *Issue, context string) string {
+	return fmt.Sprintf(`You are checking if GitHub issue #%d conflicts with project documentation.
 
 ## Issue
 
@@ -470,27 +735,45 @@ Check if implementing this feature would:
 HAS_CONFLICTS: <YES or NO>
 CONFLICTS: <If YES: describe conflicts. If NO: N/A>
 ---END_ALIGNMENT_CHECK---`, issue.Number, context)
+}
 
-	output, err := b.runClaude(prompt)
+// createTestCases uses Claude to create test cases for the issue
+func (b *Bot) createTestCases(issue *Issue, analysis *IssueAnalysis) *TestResult {
+	output, err := b.runClaude(testPrompt(issue, analysis))
 	if err != nil {
-		return "" // Assume no conflicts on error
+		b.logger.Printf("Claude test creation failed: %v", err)
+		b.commentOnIssue(issue.Number, "🤖 **Test Creation Failed**\n\nClaude encountered an error while creating tests.")
+		return nil
 	}
 
-	section := extractSection(output, "---ALIGNMENT_CHECK---", "---END_ALIGNMENT_CHECK---")
+	section := extractSection(output, "---TEST_RESULT---", "---END_TEST_RESULT---")
 	if section == "" {
-		return ""
+		b.logger.Printf("Could not extract test result from Claude output")
+		b.commentOnIssue(issue.Number, "🤖 **Test Creation Failed**\n\nCould not parse test creation output.")
+		return nil
 	}
 
-	if extractField(section, "HAS_CONFLICTS") == "YES" {
-		return extractField(section, "CONFLICTS")
+	result := &TestResult{
+		Branch:  extractField(section, "BRANCH"),
+		Summary: extractField(section, "SUMMARY"),
 	}
 
-	return ""
+	filesStr := extractField(section, "TEST_FILES")
+	if filesStr != "" {
+		for _, f := range strings.Split(filesStr, ",") {
+			f = strings.TrimSpace(f)
+			if f != "" {
+				result.TestFiles = append(result.TestFiles, f)
+			}
+		}
+	}
+
+	return result
 }
 
-// createTestCases uses Claude to create test cases for the issue
-func (b *Bot) createTestCases(issue *Issue, analysis *IssueAnalysis) *TestResult {
-	prompt := fmt.Sprintf(`You are creating test cases for GitHub issue #%d: %s
+// testPrompt builds the prompt for createTestCases
+func testPrompt(issue *Issue, analysis *IssueAnalysis) string {
+	return fmt.Sprintf(`You are creating test cases for GitHub issue #%d: %s
 
 ## Analysis
 
@@ -529,45 +812,17 @@ SUMMARY: <1-2 sentence summary of what the tests cover>
 		analysis.TestStrategy,
 		analysis.ExpectedBehavior,
 		issue.Number)
-
-	output, err := b.runClaude(prompt)
-	if err != nil {
-		b.logger.Printf("Claude test creation failed: %v", err)
-		b.commentOnIssue(issue.Number, "🤖 **Test Creation Failed**\n\nClaude encountered an error while creating tests.")
-		return nil
-	}
-
-	section := extractSection(output, "---TEST_RESULT---", "---END_TEST_RESULT---")
-	if section == "" {
-		b.logger.Printf("Could not extract test result from Claude output")
-		b.commentOnIssue(issue.Number, "🤖 **Test Creation Failed**\n\nCould not parse test creation output.")
-		return nil
-	}
-
-	result := &TestResult{
-		Branch:  extractField(section, "BRANCH"),
-		Summary: extractField(section, "SUMMARY"),
-	}
-
-	filesStr := extractField(section, "TEST_FILES")
-	if filesStr != "" {
-		for _, f := range strings.Split(filesStr, ",") {
-			f = strings.TrimSpace(f)
-			if f != "" {
-				result.TestFiles = append(result.TestFiles, f)
-			}
-		}
-	}
-
-	return result
 }
 
 // createTestPR creates a PR with the test cases
 func (b *Bot) createTestPR(issue *Issue, testResult *TestResult) int {
 	// Push the branch
-	cmd := exec.Command("git", "push", "-u", "origin", testResult.Branch)
-	cmd.Dir = b.cfg.ProjectDir
-	if err := cmd.Run(); err != nil {
+	err := b.perform("push", testResult.Branch, "git push -u origin "+testResult.Branch, func() error {
+		cmd := exec.Command("git", "push", "-u", "origin", testResult.Branch)
+		cmd.Dir = b.cfg.ProjectDir
+		return cmd.Run()
+	})
+	if err != nil {
 		b.logger.Printf("Failed to push test branch: %v", err)
 		return 0
 	}
@@ -598,14 +853,19 @@ Refs #%d
 		strings.Join(testResult.TestFiles, "\n- "),
 		issue.Number)
 
-	cmd = exec.Command("gh", "pr", "create",
-		"--title", title,
-		"--body", body,
-		"--head", testResult.Branch,
-		"--label", LabelBotTestPR)
-	cmd.Dir = b.cfg.ProjectDir
+	var output []byte
+	err = b.perform("create-pr", testResult.Branch, title, func() error {
+		cmd := exec.Command("gh", "pr", "create",
+			"--title", title,
+			"--body", body,
+			"--head", testResult.Branch,
+			"--label", b.cfg.Labels.TestPR)
+		cmd.Dir = b.cfg.ProjectDir
 
-	output, err := cmd.Output()
+		var err error
+		output, err = cmd.Output()
+		return err
+	})
 	if err != nil {
 		b.logger.Printf("Failed to create PR: %v", err)
 		return 0
@@ -625,6 +885,26 @@ Refs #%d
 
 // implementFix uses Claude to implement the fix
 func (b *Bot) implementFix(pr *PR) *ImplementResult {
+	output, err := b.runClaude(implementPrompt(pr))
+	if err != nil {
+		return &ImplementResult{Success: false, Error: err.Error()}
+	}
+
+	section := extractSection(output, "---IMPLEMENTATION_RESULT---", "---END_IMPLEMENTATION---")
+	if section == "" {
+		return &ImplementResult{Success: false, Error: "Could not parse implementation output"}
+	}
+
+	return &ImplementResult{
+		Success:   extractField(section, "SUCCESS") == "YES",
+		CommitSHA: extractField(section, "COMMIT_SHA"),
+		Summary:   extractField(section, "SUMMARY"),
+		Error:     extractField(section, "ERROR"),
+	}
+}
+
+// implementPrompt builds the prompt for implementFix
+func implementPrompt(pr *PR) string {
 	// Extract issue number from PR body (Refs #N)
 	issueNum := 0
 	re := regexp.MustCompile(`Refs #(\d+)`)
@@ -632,7 +912,7 @@ func (b *Bot) implementFix(pr *PR) *ImplementResult {
 		fmt.Sscanf(matches[1], "%d", &issueNum)
 	}
 
-	prompt := fmt.Sprintf(`You are implementing a fix for PR #%d: %s
+	return fmt.Sprintf(`You are implementing a fix for PR #%d: %s
 
 ## PR Description
 
@@ -663,23 +943,6 @@ COMMIT_SHA: <commit SHA if successful, N/A if not>
 SUMMARY: <1-2 sentence summary of the fix>
 ERROR: <error description if failed, N/A if successful>
 ---END_IMPLEMENTATION---`, pr.Number, pr.Title, pr.Body, issueNum)
-
-	output, err := b.runClaude(prompt)
-	if err != nil {
-		return &ImplementResult{Success: false, Error: err.Error()}
-	}
-
-	section := extractSection(output, "---IMPLEMENTATION_RESULT---", "---END_IMPLEMENTATION---")
-	if section == "" {
-		return &ImplementResult{Success: false, Error: "Could not parse implementation output"}
-	}
-
-	return &ImplementResult{
-		Success:   extractField(section, "SUCCESS") == "YES",
-		CommitSHA: extractField(section, "COMMIT_SHA"),
-		Summary:   extractField(section, "SUMMARY"),
-		Error:     extractField(section, "ERROR"),
-	}
 }
 
 // GitHub API helpers
@@ -687,10 +950,10 @@ ERROR: <error description if failed, N/A if successful>
 func (b *Bot) getNextAcceptedIssue() *Issue {
 	cmd := exec.Command("gh", "issue", "list",
 		"--state", "open",
-		"--label", LabelAccepted,
+		"--label", b.cfg.Labels.Accepted,
 		"--json", "number,title,body,labels,author,state",
 		"--jq", fmt.Sprintf(`.[] | select(.labels | map(.name) | (index("%s") | not) and (index("%s") | not) and (index("%s") | not))`,
-			LabelInProgress, LabelBotFailed, LabelWaitingUser))
+			b.cfg.Labels.InProgress, b.cfg.Labels.Failed, b.cfg.Labels.WaitingUser))
 	cmd.Dir = b.cfg.ProjectDir
 
 	output, err := cmd.Output()
@@ -706,8 +969,8 @@ func (b *Bot) getNextAcceptedIssue() *Issue {
 		if err := decoder.Decode(&issue); err != nil {
 			break
 		}
-		// Check it has bug or enhancement label
-		if b.hasLabel(issue.Labels, "bug") || b.hasLabel(issue.Labels, "enhancement") {
+		// Check it has a label mapped to a workflow
+		if wf := b.workflowFor(issue.Labels); wf == WorkflowBug || wf == WorkflowFeature {
 			issues = append(issues, issue)
 		}
 	}
@@ -721,11 +984,11 @@ func (b *Bot) getNextAcceptedIssue() *Issue {
 func (b *Bot) getNextAcceptedPR() *PR {
 	cmd := exec.Command("gh", "pr", "list",
 		"--state", "open",
-		"--label", LabelAccepted,
-		"--label", LabelBotTestPR,
+		"--label", b.cfg.Labels.Accepted,
+		"--label", b.cfg.Labels.TestPR,
 		"--json", "number,title,body,labels,state,headRefName,mergeable",
 		"--jq", fmt.Sprintf(`.[] | select(.labels | map(.name) | (index("%s") | not) and (index("%s") | not))`,
-			LabelInProgress, LabelBotFailed))
+			b.cfg.Labels.InProgress, b.cfg.Labels.Failed))
 	cmd.Dir = b.cfg.ProjectDir
 
 	output, err := cmd.Output()
@@ -777,32 +1040,31 @@ func (b *Bot) fetchIssueContext(number int) string {
 	return sb.String()
 }
 
-func (b *Bot) autoAcceptOwnerIssues() {
-	if b.cfg.OwnerUsername == "" {
-		return
-	}
-
-	cmd := exec.Command("gh", "issue", "list",
-		"--state", "open",
-		"--author", b.cfg.OwnerUsername,
-		"--json", "number,labels",
-		"--jq", fmt.Sprintf(`.[] | select(.labels | map(.name) | index("%s") | not) | .number`, LabelAccepted))
-	cmd.Dir = b.cfg.ProjectDir
-
-	output, err := cmd.Output()
-	if err != nil {
-		return
-	}
+// autoAcceptIssues labels open issues from trusted authors as accepted
+func (b *Bot) autoAcceptIssues() {
+	for _, author := range b.cfg.AutoAccept {
+		cmd := exec.Command("gh", "issue", "list",
+			"--state", "open",
+			"--author", author,
+			"--json", "number,labels",
+			"--jq", fmt.Sprintf(`.[] | select(.labels | map(.name) | index("%s") | not) | .number`, b.cfg.Labels.Accepted))
+		cmd.Dir = b.cfg.ProjectDir
 
-	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
-		if line == "" {
+		output, err := cmd.Output()
+		if err != nil {
 			continue
 		}
-		var num int
-		fmt.Sscanf(line, "%d", &num)
-		if num > 0 {
-			b.logger.Printf("Auto-accepting owner issue #%d", num)
-			b.addLabel("issue", num, LabelAccepted)
+
+		for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+			if line == "" {
+				continue
+			}
+			var num int
+			fmt.Sscanf(line, "%d", &num)
+			if num > 0 {
+				b.logger.Printf("Auto-accepting issue #%d from %s", num, author)
+				b.addLabel("issue", num, b.cfg.Labels.Accepted)
+			}
 		}
 	}
 }
@@ -810,7 +1072,7 @@ func (b *Bot) autoAcceptOwnerIssues() {
 func (b *Bot) checkWaitingIssuesForFeedback() {
 	cmd := exec.Command("gh", "issue", "list",
 		"--state", "open",
-		"--label", LabelWaitingUser,
+		"--label", b.cfg.Labels.WaitingUser,
 		"--json", "number")
 	cmd.Dir = b.cfg.ProjectDir
 
@@ -832,40 +1094,56 @@ func (b *Bot) checkWaitingIssuesForFeedback() {
 		result, _ := cmd.Output()
 		if strings.TrimSpace(string(result)) == "true" {
 			b.logger.Printf("Issue #%d: User feedback detected, removing waiting label", issue.Number)
-			b.removeLabel("issue", issue.Number, LabelWaitingUser)
+			b.removeLabel("issue", issue.Number, b.cfg.Labels.WaitingUser)
 		}
 	}
 }
 
 func (b *Bot) addLabel(itemType string, number int, label string) {
-	if b.cfg.DryRun {
-		b.logger.Printf("[DRY RUN] Would add label '%s' to %s #%d", label, itemType, number)
-		return
-	}
-
-	var cmd *exec.Cmd
-	if itemType == "pr" {
-		cmd = exec.Command("gh", "pr", "edit", fmt.Sprintf("%d", number), "--add-label", label)
-	} else {
-		cmd = exec.Command("gh", "issue", "edit", fmt.Sprintf("%d", number), "--add-label", label)
-	}
-	cmd.Dir = b.cfg.ProjectDir
-	cmd.Run()
+	target := fmt.Sprintf("%s #%d", itemType, number)
+	b.perform("add-label", target, label, func() error {
+		var cmd *exec.Cmd
+		if itemType == "pr" {
+			cmd = exec.Command("gh", "pr", "edit", fmt.Sprintf("%d", number), "--add-label", label)
+		} else {
+			cmd = exec.Command("gh", "issue", "edit", fmt.Sprintf("%d", number), "--add-label", label)
+		}
+		cmd.Dir = b.cfg.ProjectDir
+		return cmd.Run()
+	})
 }
 
 func (b *Bot) removeLabel(itemType string, number int, label string) {
-	if b.cfg.DryRun {
-		return
-	}
+	target := fmt.Sprintf("%s #%d", itemType, number)
+	b.perform("remove-label", target, label, func() error {
+		var cmd *exec.Cmd
+		if itemType == "pr" {
+			cmd = exec.Command("gh", "pr", "edit", fmt.Sprintf("%d", number), "--remove-label", label)
+		} else {
+			cmd = exec.Command("gh", "issue", "edit", fmt.Sprintf("%d", number), "--remove-label", label)
+		}
+		cmd.Dir = b.cfg.ProjectDir
+		return cmd.Run()
+	})
+}
 
-	var cmd *exec.Cmd
-	if itemType == "pr" {
-		cmd = exec.Command("gh", "pr", "edit", fmt.Sprintf("%d", number), "--remove-label", label)
-	} else {
-		cmd = exec.Command("gh", "issue", "edit", fmt.Sprintf("%d", number), "--remove-label", label)
+// workflowFor returns the workflow for an issue's labels. A label mapped to
+// "skip" wins over everything else; otherwise the first mapped label decides.
+func (b *Bot) workflowFor(labels []Label) Workflow {
+	var result Workflow
+	for _, l := range labels {
+		wf, ok := b.cfg.Workflows[l.Name]
+		if !ok {
+			continue
+		}
+		if wf == WorkflowSkip {
+			return WorkflowSkip
+		}
+		if result == "" {
+			result = wf
+		}
 	}
-	cmd.Dir = b.cfg.ProjectDir
-	cmd.Run()
+	return result
 }
 
 func (b *Bot) hasLabel(labels []Label, name string) bool {
@@ -878,57 +1156,76 @@ func (b *Bot) hasLabel(labels []Label, name string) bool {
 }
 
 func (b *Bot) commentOnIssue(number int, body string) {
-	if b.cfg.DryRun {
-		b.logger.Printf("[DRY RUN] Would comment on issue #%d", number)
-		return
-	}
-
-	cmd := exec.Command("gh", "issue", "comment", fmt.Sprintf("%d", number), "--body", body)
-	cmd.Dir = b.cfg.ProjectDir
-	cmd.Run()
+	b.perform("comment", fmt.Sprintf("issue #%d", number), body, func() error {
+		cmd := exec.Command("gh", "issue", "comment", fmt.Sprintf("%d", number), "--body", body)
+		cmd.Dir = b.cfg.ProjectDir
+		return cmd.Run()
+	})
 }
 
 func (b *Bot) commentOnPR(number int, body string) {
-	if b.cfg.DryRun {
-		b.logger.Printf("[DRY RUN] Would comment on PR #%d", number)
-		return
-	}
-
-	cmd := exec.Command("gh", "pr", "comment", fmt.Sprintf("%d", number), "--body", body)
-	cmd.Dir = b.cfg.ProjectDir
-	cmd.Run()
+	b.perform("comment", fmt.Sprintf("pr #%d", number), body, func() error {
+		cmd := exec.Command("gh", "pr", "comment", fmt.Sprintf("%d", number), "--body", body)
+		cmd.Dir = b.cfg.ProjectDir
+		return cmd.Run()
+	})
 }
 
 // Git helpers
 
 func (b *Bot) gitPull() {
-	cmd := exec.Command("git", "pull", "--rebase", "origin", "main")
-	cmd.Dir = b.cfg.ProjectDir
-	cmd.Run()
+	b.perform("pull", "main", "git pull --rebase origin main", func() error {
+		cmd := exec.Command("git", "pull", "--rebase", "origin", "main")
+		cmd.Dir = b.cfg.ProjectDir
+		return cmd.Run()
+	})
 }
 
 func (b *Bot) checkoutPRBranch(pr *PR) error {
-	cmd := exec.Command("git", "fetch", "origin", pr.HeadRef)
-	cmd.Dir = b.cfg.ProjectDir
-	if err := cmd.Run(); err != nil {
-		return err
-	}
+	return b.perform("checkout", pr.HeadRef, "git fetch origin "+pr.HeadRef+" && git checkout "+pr.HeadRef, func() error {
+		cmd := exec.Command("git", "fetch", "origin", pr.HeadRef)
+		cmd.Dir = b.cfg.ProjectDir
+		if err := cmd.Run(); err != nil {
+			return err
+		}
 
-	cmd = exec.Command("git", "checkout", pr.HeadRef)
-	cmd.Dir = b.cfg.ProjectDir
-	return cmd.Run()
+		cmd = exec.Command("git", "checkout", pr.HeadRef)
+		cmd.Dir = b.cfg.ProjectDir
+		return cmd.Run()
+	})
 }
 
 func (b *Bot) checkoutMain() {
-	cmd := exec.Command("git", "checkout", "main")
-	cmd.Dir = b.cfg.ProjectDir
-	cmd.Run()
+	b.perform("checkout", "main", "", func() error {
+		cmd := exec.Command("git", "checkout", "main")
+		cmd.Dir = b.cfg.ProjectDir
+		return cmd.Run()
+	})
+}
+
+// forceCheckoutMain switches to main, discarding uncommitted changes
+func (b *Bot) forceCheckoutMain() {
+	b.perform("checkout", "main", "forced, discarding local changes", func() error {
+		cmd := exec.Command("git", "checkout", "-f", "main")
+		cmd.Dir = b.cfg.ProjectDir
+		return cmd.Run()
+	})
+}
+
+func (b *Bot) deleteLocalBranch(branch string) {
+	b.perform("delete-branch", branch, "local only", func() error {
+		cmd := exec.Command("git", "branch", "-D", branch)
+		cmd.Dir = b.cfg.ProjectDir
+		return cmd.Run()
+	})
 }
 
 func (b *Bot) pushChanges(branch string) error {
-	cmd := exec.Command("git", "push", "origin", branch)
-	cmd.Dir = b.cfg.ProjectDir
-	return cmd.Run()
+	return b.perform("push", branch, "git push origin "+branch, func() error {
+		cmd := exec.Command("git", "push", "origin", branch)
+		cmd.Dir = b.cfg.ProjectDir
+		return cmd.Run()
+	})
 }
 
 // Claude integration
@@ -936,11 +1233,21 @@ func (b *Bot) pushChanges(branch string) error {
 func (b *Bot) runClaude(prompt string) (string, error) {
 	ctx := fmt.Sprintf("timeout %ds", int(b.cfg.ClaudeTimeout.Seconds()))
 
-	cmd := exec.Command("sh", "-c", fmt.Sprintf("%s claude -p %q --allowedTools 'Bash,Read,Write,Edit,Glob,Grep'",
-		ctx, prompt))
-	cmd.Dir = b.cfg.ProjectDir
+	args := fmt.Sprintf("--allowedTools %q", b.cfg.AllowedTools)
+	if b.cfg.ClaudeModel != "" {
+		args += fmt.Sprintf(" --model %q", b.cfg.ClaudeModel)
+	}
 
-	output, err := cmd.Output()
+	var output []byte
+	err := b.perform("claude", b.cfg.RepoName, firstLine(prompt), func() error {
+		cmd := exec.Command("sh", "-c", fmt.Sprintf("%s claude -p %q %s",
+			ctx, prompt, args))
+		cmd.Dir = b.cfg.ProjectDir
+
+		var err error
+		output, err = cmd.Output()
+		return err
+	})
 	if err != nil {
 		return "", fmt.Errorf("claude failed: %w", err)
 	}
@@ -972,11 +1279,11 @@ func (b *Bot) ensureLabels() {
 	}
 
 	labels := map[string]string{
-		LabelAccepted:    "0052CC",
-		LabelInProgress:  "FFA500",
-		LabelWaitingUser: "0E8A16",
-		LabelBotTestPR:   "6F42C1",
-		LabelBotFailed:   "FF0000",
+		b.cfg.Labels.Accepted:    "0052CC",
+		b.cfg.Labels.InProgress:  "FFA500",
+		b.cfg.Labels.WaitingUser: "0E8A16",
+		b.cfg.Labels.TestPR:      "6F42C1",
+		b.cfg.Labels.Failed:      "FF0000",
 	}
 
 	for name, color := range labels {
@@ -1005,8 +1312,9 @@ func findProjectRoot() (string, error) {
 	}
 }
 
-func getRepoOwner() string {
+func getRepoOwner(dir string) string {
 	cmd := exec.Command("gh", "repo", "view", "--json", "owner", "--jq", ".owner.login")
+	cmd.Dir = dir
 	output, err := cmd.Output()
 	if err != nil {
 		return ""
@@ -1014,6 +1322,23 @@ func getRepoOwner() string {
 	return strings.TrimSpace(string(output))
 }
 
+func getLogin(dir string) string {
+	cmd := exec.Command("gh", "api", "user", "--jq", ".login")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
 func extractSection(output, startMarker, endMarker string) string {
 	start := strings.Index(output, startMarker)
 	if start == -1 {
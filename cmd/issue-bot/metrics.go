@@ -0,0 +1,354 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Task outcomes recorded in metrics
+const (
+	OutcomePRCreated   = "pr-created"  // Issue: test PR opened
+	OutcomeWaiting     = "waiting"     // Issue: handed back to the user
+	OutcomeSkipped     = "skipped"     // Issue: no workflow
+	OutcomeImplemented = "implemented" // PR: fix pushed
+	OutcomeAddressed   = "addressed"   // PR: review follow-up pushed
+	OutcomeFailed      = "failed"
+)
+
+// TaskError is the most recent failure of a task
+type TaskError struct {
+	Time  time.Time `json:"time"`
+	Phase string    `json:"phase,omitempty"`
+	Error string    `json:"error"`
+}
+
+// repoMetrics holds counters for one repository
+type repoMetrics struct {
+	outcomes     map[string]int // "kind/outcome" -> count
+	acceptedToPR time.Duration  // Sum over all created test PRs
+	prsCreated   int
+	queue        map[string]int // "issues", "prs", "waiting"
+	lastCycle    time.Time
+	lastErrors   map[string]TaskError // Task key -> last error
+}
+
+// Metrics collects bot statistics across repositories. All methods are
+// safe on a nil receiver so the bot runs unchanged without -metrics.
+type Metrics struct {
+	mu      sync.Mutex
+	started time.Time
+	repos   map[string]*repoMetrics
+}
+
+// NewMetrics creates an empty collector
+func NewMetrics() *Metrics {
+	return &Metrics{started: time.Now(), repos: make(map[string]*repoMetrics)}
+}
+
+func (m *Metrics) repo(name string) *repoMetrics {
+	r, ok := m.repos[name]
+	if !ok {
+		r = &repoMetrics{
+			outcomes:   make(map[string]int),
+			queue:      make(map[string]int),
+			lastErrors: make(map[string]TaskError),
+		}
+		m.repos[name] = r
+	}
+	return r
+}
+
+// RecordOutcome counts a finished task; failures also update the last error
+func (m *Metrics) RecordOutcome(repo, kind string, number int, outcome, phase, errMsg string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r := m.repo(repo)
+	r.outcomes[kind+"/"+outcome]++
+	if outcome == OutcomeFailed {
+		r.lastErrors[fmt.Sprintf("%s#%d", kind, number)] = TaskError{
+			Time:  time.Now(),
+			Phase: phase,
+			Error: errMsg,
+		}
+	}
+}
+
+// RecordPRCreated tracks the time from acceptance to the test PR
+func (m *Metrics) RecordPRCreated(repo string, sinceAccepted time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r := m.repo(repo)
+	r.acceptedToPR += sinceAccepted
+	r.prsCreated++
+}
+
+// SetQueue records the current backlog sizes for a repository
+func (m *Metrics) SetQueue(repo string, issues, prs, waiting int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r := m.repo(repo)
+	r.queue["issues"] = issues
+	r.queue["prs"] = prs
+	r.queue["waiting"] = waiting
+	r.lastCycle = time.Now()
+}
+
+// RepoStatus is the JSON view of one repository
+type RepoStatus struct {
+	Repo             string               `json:"repo"`
+	Outcomes         map[string]int       `json:"outcomes"`
+	SuccessRate      float64              `json:"success_rate"` // Non-failed share of finished tasks
+	MeanAcceptedToPR string               `json:"mean_accepted_to_pr,omitempty"`
+	Queue            map[string]int       `json:"queue"`
+	LastCycle        time.Time            `json:"last_cycle"`
+	LastErrors       map[string]TaskError `json:"last_errors"`
+}
+
+// Status is the JSON document served at /status
+type Status struct {
+	Started time.Time    `json:"started"`
+	Uptime  string       `json:"uptime"`
+	Repos   []RepoStatus `json:"repos"`
+}
+
+// Status returns a snapshot of all metrics
+func (m *Metrics) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := Status{
+		Started: m.started,
+		Uptime:  time.Since(m.started).Round(time.Second).String(),
+	}
+	for _, name := range m.repoNames() {
+		r := m.repos[name]
+		rs := RepoStatus{
+			Repo:       name,
+			Outcomes:   make(map[string]int, len(r.outcomes)),
+			Queue:      make(map[string]int, len(r.queue)),
+			LastCycle:  r.lastCycle,
+			LastErrors: make(map[string]TaskError, len(r.lastErrors)),
+		}
+		total, failed := 0, 0
+		for k, v := range r.outcomes {
+			rs.Outcomes[k] = v
+			total += v
+			if strings.HasSuffix(k, "/"+OutcomeFailed) {
+				failed += v
+			}
+		}
+		if total > 0 {
+			rs.SuccessRate = float64(total-failed) / float64(total)
+		}
+		if r.prsCreated > 0 {
+			rs.MeanAcceptedToPR = (r.acceptedToPR / time.Duration(r.prsCreated)).Round(time.Second).String()
+		}
+		for k, v := range r.queue {
+			rs.Queue[k] = v
+		}
+		for k, v := range r.lastErrors {
+			rs.LastErrors[k] = v
+		}
+		s.Repos = append(s.Repos, rs)
+	}
+	return s
+}
+
+func (m *Metrics) repoNames() []string {
+	names := make([]string, 0, len(m.repos))
+	for name := range m.repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WritePrometheus writes all metrics in the Prometheus text exposition format
+func (m *Metrics) WritePrometheus(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP issuebot_tasks_total Finished tasks by kind and outcome.")
+	fmt.Fprintln(w, "# TYPE issuebot_tasks_total counter")
+	for _, name := range m.repoNames() {
+		r := m.repos[name]
+		keys := make([]string, 0, len(r.outcomes))
+		for k := range r.outcomes {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			kind, outcome, _ := strings.Cut(k, "/")
+			fmt.Fprintf(w, "issuebot_tasks_total{repo=%q,kind=%q,outcome=%q} %d\n", name, kind, outcome, r.outcomes[k])
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP issuebot_accepted_to_pr_seconds Time from the accepted label to the test PR.")
+	fmt.Fprintln(w, "# TYPE issuebot_accepted_to_pr_seconds summary")
+	for _, name := range m.repoNames() {
+		r := m.repos[name]
+		fmt.Fprintf(w, "issuebot_accepted_to_pr_seconds_sum{repo=%q} %g\n", name, r.acceptedToPR.Seconds())
+		fmt.Fprintf(w, "issuebot_accepted_to_pr_seconds_count{repo=%q} %d\n", name, r.prsCreated)
+	}
+
+	fmt.Fprintln(w, "# HELP issuebot_queue Items waiting for the bot or the user.")
+	fmt.Fprintln(w, "# TYPE issuebot_queue gauge")
+	for _, name := range m.repoNames() {
+		r := m.repos[name]
+		for _, q := range []string{"issues", "prs", "waiting"} {
+			fmt.Fprintf(w, "issuebot_queue{repo=%q,queue=%q} %d\n", name, q, r.queue[q])
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP issuebot_last_cycle_timestamp_seconds Unix time of the last poll cycle.")
+	fmt.Fprintln(w, "# TYPE issuebot_last_cycle_timestamp_seconds gauge")
+	for _, name := range m.repoNames() {
+		fmt.Fprintf(w, "issuebot_last_cycle_timestamp_seconds{repo=%q} %d\n", name, m.repos[name].lastCycle.Unix())
+	}
+
+	fmt.Fprintln(w, "# HELP issuebot_last_error_timestamp_seconds Unix time of the last failure per task.")
+	fmt.Fprintln(w, "# TYPE issuebot_last_error_timestamp_seconds gauge")
+	for _, name := range m.repoNames() {
+		r := m.repos[name]
+		for task, e := range r.lastErrors {
+			fmt.Fprintf(w, "issuebot_last_error_timestamp_seconds{repo=%q,task=%q} %d\n", name, task, e.Time.Unix())
+		}
+	}
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"pct": func(f float64) string { return fmt.Sprintf("%.0f%%", f*100) },
+}).Parse(`<!DOCTYPE html>
+<html><head><title>Issue Bot</title><meta http-equiv="refresh" content="30">
+<style>body{font-family:sans-serif;margin:2em}table{border-collapse:collapse;margin-bottom:1em}td,th{border:1px solid #ccc;padding:4px 8px;text-align:left}</style>
+</head><body>
+<h1>Issue Bot</h1>
+<p>Up {{.Uptime}} since {{.Started.Format "2006-01-02 15:04:05"}}</p>
+{{range .Repos}}
+<h2>{{.Repo}}</h2>
+<p>Last cycle {{.LastCycle.Format "15:04:05"}} &middot; success rate {{pct .SuccessRate}}{{if .MeanAcceptedToPR}} &middot; mean accepted&rarr;PR {{.MeanAcceptedToPR}}{{end}}</p>
+<table><tr><th>Queue</th><th>Count</th></tr>
+{{range $k, $v := .Queue}}<tr><td>{{$k}}</td><td>{{$v}}</td></tr>{{end}}
+</table>
+<table><tr><th>Outcome</th><th>Count</th></tr>
+{{range $k, $v := .Outcomes}}<tr><td>{{$k}}</td><td>{{$v}}</td></tr>{{end}}
+</table>
+{{if .LastErrors}}<table><tr><th>Task</th><th>When</th><th>Phase</th><th>Last error</th></tr>
+{{range $k, $v := .LastErrors}}<tr><td>{{$k}}</td><td>{{$v.Time.Format "2006-01-02 15:04"}}</td><td>{{$v.Phase}}</td><td>{{$v.Error}}</td></tr>{{end}}
+</table>{{end}}
+{{end}}
+</body></html>
+`))
+
+// ServeMetrics starts the dashboard and metrics HTTP server in the background
+func (m *Metrics) ServeMetrics(addr string) {
+	handler := m.Handler()
+	go func() {
+		log.Printf("Metrics listening on http://%s", addr)
+		if err := http.ListenAndServe(addr, handler); err != nil {
+			log.Printf("Metrics server stopped: %v", err)
+		}
+	}()
+}
+
+// Handler serves the dashboard at /, the JSON status at /status and the
+// Prometheus metrics at /metrics
+func (m *Metrics) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		m.WritePrometheus(w)
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(m.Status())
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		dashboardTemplate.Execute(w, m.Status())
+	})
+	return mux
+}
+
+// recordOutcome records a finished task in the metrics
+func (b *Bot) recordOutcome(kind string, number int, outcome string, task *Task, errMsg string) {
+	phase := ""
+	if task != nil {
+		phase = string(task.Phase)
+	}
+	b.metrics.RecordOutcome(b.cfg.RepoName, kind, number, outcome, phase, errMsg)
+}
+
+// updateQueueMetrics counts items the bot has yet to pick up
+func (b *Bot) updateQueueMetrics() {
+	if b.metrics == nil {
+		return
+	}
+
+	pending := func(kind string, labels ...string) int {
+		args := []string{kind, "list", "--state", "open", "--json", "number"}
+		for _, l := range labels {
+			args = append(args, "--label", l)
+		}
+		args = append(args, "--jq", "length")
+		cmd := exec.Command("gh", args...)
+		cmd.Dir = b.cfg.ProjectDir
+
+		output, err := cmd.Output()
+		if err != nil {
+			return 0
+		}
+		var n int
+		fmt.Sscanf(strings.TrimSpace(string(output)), "%d", &n)
+		return n
+	}
+
+	b.metrics.SetQueue(b.cfg.RepoName,
+		pending("issue", b.cfg.Labels.Accepted),
+		pending("pr", b.cfg.Labels.Accepted, b.cfg.Labels.TestPR),
+		pending("issue", b.cfg.Labels.WaitingUser))
+}
+
+// acceptedAt returns when the accepted label was last added to an issue
+func (b *Bot) acceptedAt(number int) (time.Time, bool) {
+	cmd := exec.Command("gh", "api", fmt.Sprintf("repos/{owner}/{repo}/issues/%d/events", number),
+		"--jq", fmt.Sprintf(`[.[] | select(.event == "labeled" and .label.name == "%s")] | last | .created_at // empty`, b.cfg.Labels.Accepted))
+	cmd.Dir = b.cfg.ProjectDir
+
+	output, err := cmd.Output()
+	if err != nil {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(string(output)))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMetricsHandler tests what the dashboard, status and metrics endpoints
+// serve for recorded outcomes, PRs and queues.
+func TestMetricsHandler(t *testing.T) {
+	m := NewMetrics()
+	m.RecordOutcome("game", "issue", 7, OutcomePRCreated, "", "")
+	m.RecordOutcome("game", "issue", 8, OutcomePRCreated, "", "")
+	m.RecordOutcome("game", "pr", 12, OutcomeImplemented, "", "")
+	m.RecordOutcome("game", "pr", 13, OutcomeFailed, "pushing", "push rejected")
+	m.RecordPRCreated("game", 2*time.Minute)
+	m.RecordPRCreated("game", 4*time.Minute)
+	m.SetQueue("game", 3, 1, 2)
+	m.SetQueue("site", 0, 0, 0)
+
+	srv := httptest.NewServer(m.Handler())
+	defer srv.Close()
+	get := func(path string) (*http.Response, string) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, string(body)
+	}
+
+	tests := []struct {
+		path        string
+		status      int
+		contentType string
+		contains    []string
+	}{
+		{"/metrics", http.StatusOK, "text/plain", []string{
+			`issuebot_tasks_total{repo="game",kind="issue",outcome="pr-created"} 2`,
+			`issuebot_tasks_total{repo="game",kind="pr",outcome="failed"} 1`,
+			`issuebot_accepted_to_pr_seconds_sum{repo="game"} 360`,
+			`issuebot_accepted_to_pr_seconds_count{repo="game"} 2`,
+			`issuebot_queue{repo="game",queue="issues"} 3`,
+			`issuebot_queue{repo="site",queue="waiting"} 0`,
+			`issuebot_last_error_timestamp_seconds{repo="game",task="pr#13"}`,
+		}},
+		{"/", http.StatusOK, "text/html", []string{"<h2>game</h2>", "<h2>site</h2>", "75%", "mean accepted&rarr;PR 3m0s", "push rejected"}},
+		{"/status", http.StatusOK, "application/json", []string{`"repo": "game"`}},
+		{"/other", http.StatusNotFound, "", nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			resp, body := get(tc.path)
+			if resp.StatusCode != tc.status {
+				t.Fatalf("Status = %d, want %d", resp.StatusCode, tc.status)
+			}
+			if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, tc.contentType) {
+				t.Errorf("Content-Type = %q, want %s", ct, tc.contentType)
+			}
+			for _, s := range tc.contains {
+				if !strings.Contains(body, s) {
+					t.Errorf("Body lacks %q:\n%s", s, body)
+				}
+			}
+		})
+	}
+
+	_, body := get("/status")
+	var status Status
+	if err := json.Unmarshal([]byte(body), &status); err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Repos) != 2 {
+		t.Fatalf("Status has %d repos, want 2", len(status.Repos))
+	}
+	game := status.Repos[0]
+	if game.Repo != "game" || game.SuccessRate != 0.75 || game.MeanAcceptedToPR != "3m0s" || game.Queue["prs"] != 1 {
+		t.Errorf("Status of game = %+v", game)
+	}
+	if e := game.LastErrors["pr#13"]; e.Phase != "pushing" || e.Error != "push rejected" {
+		t.Errorf("Last error of pr#13 = %+v", e)
+	}
+}
+
+// TestMetricsNil tests that a bot without -metrics can record into a nil
+// collector.
+func TestMetricsNil(t *testing.T) {
+	var m *Metrics
+	m.RecordOutcome("game", "issue", 1, OutcomeFailed, "", "boom")
+	m.RecordPRCreated("game", time.Minute)
+	m.SetQueue("game", 1, 2, 3)
+}
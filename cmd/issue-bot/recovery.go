@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// beginTask persists the start of work on an issue or PR.
+// Persistence failures are logged but never block processing.
+func (b *Bot) beginTask(kind string, number int) *Task {
+	task, err := b.state.Begin(kind, number)
+	if err != nil {
+		b.logger.Printf("Warning: failed to persist task %s #%d: %v", kind, number, err)
+	}
+	return task
+}
+
+// setPhase persists progress of a task
+func (b *Bot) setPhase(task *Task, phase TaskPhase) {
+	if err := b.state.SetPhase(task, phase); err != nil {
+		b.logger.Printf("Warning: failed to persist phase %q for %s: %v", phase, task.Key(), err)
+	}
+}
+
+// updateTask persists arbitrary task changes
+func (b *Bot) updateTask(task *Task, fn func(t *Task)) {
+	if err := b.state.Update(task, fn); err != nil {
+		b.logger.Printf("Warning: failed to persist %s: %v", task.Key(), err)
+	}
+}
+
+// finishTask removes a task from the store once it reached a stable label state
+func (b *Bot) finishTask(task *Task) {
+	if err := b.state.Finish(task); err != nil {
+		b.logger.Printf("Warning: failed to clear task %s: %v", task.Key(), err)
+	}
+}
+
+// recoverTasks reconciles work that was interrupted by a crash or restart.
+// Tasks that got far enough to have a PR are resumed; everything else is
+// rolled back so the next poll cycle picks the item up again from scratch.
+func (b *Bot) recoverTasks() {
+	tasks := b.state.Tasks()
+	if len(tasks) == 0 {
+		b.logger.Println("No interrupted tasks to recover")
+	}
+
+	for i := range tasks {
+		task := &tasks[i]
+		b.logger.Printf("Recovering %s #%d interrupted in phase %q", task.Kind, task.Number, task.Phase)
+
+		switch task.Kind {
+		case "issue":
+			b.recoverIssueTask(task)
+		case "pr":
+			b.recoverPRTask(task)
+		default:
+			b.logger.Printf("Unknown task kind %q, dropping", task.Kind)
+		}
+
+		b.finishTask(task)
+	}
+
+	b.clearStaleInProgressLabels()
+}
+
+// recoverIssueTask resumes or rolls back an interrupted issue task
+func (b *Bot) recoverIssueTask(task *Task) {
+	prNumber := task.PRNumber
+	if prNumber == 0 && task.Branch != "" &&
+		(task.Phase == PhaseCreatingPR || task.Phase == PhasePRCreated) {
+		prNumber = b.findPRForBranch(task.Branch)
+	}
+
+	if prNumber != 0 {
+		b.logger.Printf("Issue #%d: test PR #%d already exists, resuming", task.Number, prNumber)
+		b.linkTestPR(task.Number, prNumber)
+		return
+	}
+
+	b.logger.Printf("Issue #%d: rolling back to accepted", task.Number)
+	if !b.cfg.DryRun {
+		b.checkoutMain()
+		if task.Branch != "" {
+			b.deleteLocalBranch(task.Branch)
+		}
+	}
+	b.removeLabel("issue", task.Number, b.cfg.Labels.InProgress)
+}
+
+// recoverPRTask rolls back an interrupted PR implementation.
+// Unpushed local work is discarded; the PR will be retried next cycle.
+func (b *Bot) recoverPRTask(task *Task) {
+	b.logger.Printf("PR #%d: rolling back to accepted", task.Number)
+	if !b.cfg.DryRun {
+		b.forceCheckoutMain()
+	}
+	b.removeLabel("pr", task.Number, b.cfg.Labels.InProgress)
+}
+
+// clearStaleInProgressLabels removes bot-in-progress labels not backed by a
+// persisted task. The bot works on one item at a time, so at startup any
+// such label was left behind by a crash.
+func (b *Bot) clearStaleInProgressLabels() {
+	for _, kind := range []string{"issue", "pr"} {
+		for _, number := range b.listLabeled(kind, b.cfg.Labels.InProgress) {
+			if b.state.Has(kind, number) {
+				continue
+			}
+			b.logger.Printf("Clearing stale '%s' label from %s #%d", b.cfg.Labels.InProgress, kind, number)
+			b.removeLabel(kind, number, b.cfg.Labels.InProgress)
+		}
+	}
+}
+
+// listLabeled returns the numbers of open issues or PRs carrying a label
+func (b *Bot) listLabeled(kind, label string) []int {
+	cmd := exec.Command("gh", kind, "list",
+		"--state", "open",
+		"--label", label,
+		"--json", "number")
+	cmd.Dir = b.cfg.ProjectDir
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	var items []struct{ Number int }
+	if err := json.Unmarshal(output, &items); err != nil {
+		return nil
+	}
+
+	numbers := make([]int, 0, len(items))
+	for _, item := range items {
+		numbers = append(numbers, item.Number)
+	}
+	return numbers
+}
+
+// findPRForBranch returns the open PR number for a head branch, or 0
+func (b *Bot) findPRForBranch(branch string) int {
+	cmd := exec.Command("gh", "pr", "list",
+		"--state", "open",
+		"--head", branch,
+		"--json", "number",
+		"--jq", ".[0].number // empty")
+	cmd.Dir = b.cfg.ProjectDir
+
+	output, err := cmd.Output()
+	if err != nil {
+		return 0
+	}
+
+	var num int
+	fmt.Sscanf(strings.TrimSpace(string(output)), "%d", &num)
+	return num
+}
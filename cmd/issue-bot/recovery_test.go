@@ -0,0 +1,102 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// fakeGH puts a gh on PATH that answers the read-only queries recovery
+// makes: the open PR for bot/issue-7 is #42, and issue #3 carries a stale
+// in-progress label
+func fakeGH(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	script := `#!/bin/sh
+case "$*" in
+*"--head bot/issue-7"*) echo 42 ;;
+"issue list"*) echo '[{"number": 3}]' ;;
+*) echo '[]' ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, "gh"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// TestRecoverTasks tests that interrupted tasks with a PR are resumed, the
+// rest rolled back, stale labels cleared and the store emptied. The bot runs
+// dry, so its actions are read from the report.
+func TestRecoverTasks(t *testing.T) {
+	fakeGH(t)
+	state, err := LoadStateStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &Bot{
+		cfg:    Config{DryRun: true, Labels: DefaultLabels(), ProjectDir: t.TempDir()},
+		logger: log.New(io.Discard, "", 0),
+		state:  state,
+		report: NewDryRunReport("test"),
+	}
+
+	tasks := []struct {
+		kind   string
+		number int
+		update func(t *Task)
+	}{
+		{"issue", 7, func(t *Task) { t.Phase, t.Branch = PhaseCreatingPR, "bot/issue-7" }},    // PR found by branch
+		{"issue", 8, func(t *Task) { t.Phase, t.PRNumber = PhasePRCreated, 50 }},              // PR recorded
+		{"issue", 9, func(t *Task) { t.Phase, t.Branch = PhaseCreatingTests, "bot/issue-9" }}, // No PR yet
+		{"pr", 12, func(t *Task) { t.Phase = PhaseImplementing }},
+		{"commit", 1, func(t *Task) {}}, // Unknown kind
+	}
+	for _, tc := range tasks {
+		task, err := state.Begin(tc.kind, tc.number)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := state.Update(task, tc.update); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b.recoverTasks()
+
+	var got []ReportAction
+	for _, s := range b.report.sections {
+		for _, a := range s.Actions {
+			a.Detail = "" // Comment bodies
+			got = append(got, a)
+		}
+	}
+	labels := DefaultLabels()
+	want := []ReportAction{
+		{Action: "comment", Target: "issue #7"},
+		{Action: "remove-label", Target: "issue #7"},
+		{Action: "add-label", Target: "issue #7"},
+		{Action: "comment", Target: "issue #8"},
+		{Action: "remove-label", Target: "issue #8"},
+		{Action: "add-label", Target: "issue #8"},
+		{Action: "remove-label", Target: "issue #9"},
+		{Action: "remove-label", Target: "pr #12"},
+		{Action: "remove-label", Target: "issue #3"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Actions = %v, want %v", got, want)
+	}
+	for _, s := range b.report.sections {
+		for _, a := range s.Actions {
+			if a.Action == "add-label" && a.Detail != labels.WaitingUser || a.Action == "remove-label" && a.Detail != labels.InProgress {
+				t.Errorf("%s on %s uses label %q", a.Action, a.Target, a.Detail)
+			}
+		}
+	}
+	if n := len(state.Tasks()); n != 0 {
+		t.Errorf("%d tasks left after recovery, want none", n)
+	}
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ReviewComment is a single comment in a review thread
+type ReviewComment struct {
+	Author string
+	Body   string
+}
+
+// ReviewThread is an unresolved review conversation on a PR
+type ReviewThread struct {
+	ID       string // GraphQL node ID, used to reply
+	Path     string
+	Line     int
+	Comments []ReviewComment
+}
+
+// ReviewReply is Claude's verdict for one thread
+type ReviewReply struct {
+	Thread    int // 1-based index into the threads passed to Claude
+	Addressed bool
+	Reply     string
+}
+
+// ReviewResult holds the result of a review follow-up run
+type ReviewResult struct {
+	Success   bool
+	CommitSHA string
+	Replies   []ReviewReply
+	Error     string
+}
+
+const reviewThreadsQuery = `query($owner: String!, $name: String!, $number: Int!) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) {
+      reviewThreads(first: 50) {
+        nodes {
+          id
+          isResolved
+          isOutdated
+          path
+          line
+          comments(first: 50) {
+            nodes { author { login } body }
+          }
+        }
+      }
+    }
+  }
+}`
+
+const replyThreadMutation = `mutation($thread: ID!, $body: String!) {
+  addPullRequestReviewThreadReply(input: {pullRequestReviewThreadId: $thread, body: $body}) {
+    comment { id }
+  }
+}`
+
+// getNextReviewedPR returns the first bot PR with review threads waiting on the bot
+func (b *Bot) getNextReviewedPR() (*PR, []ReviewThread) {
+	cmd := exec.Command("gh", "pr", "list",
+		"--state", "open",
+		"--label", b.cfg.Labels.TestPR,
+		"--json", "number,title,body,labels,state,headRefName,mergeable",
+		"--jq", fmt.Sprintf(`.[] | select(.labels | map(.name) | (index("%s") | not) and (index("%s") | not))`,
+			b.cfg.Labels.InProgress, b.cfg.Labels.Failed))
+	cmd.Dir = b.cfg.ProjectDir
+
+	output, err := cmd.Output()
+	if err != nil || len(output) == 0 {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	for decoder.More() {
+		var pr PR
+		if err := decoder.Decode(&pr); err != nil {
+			break
+		}
+		if threads := b.fetchPendingThreads(pr.Number); len(threads) > 0 {
+			return &pr, threads
+		}
+	}
+	return nil, nil
+}
+
+// fetchPendingThreads returns unresolved threads waiting on the bot. Only
+// comments from trusted reviewers (see canReview) and the bot itself are
+// kept, since they end up in Claude's prompt; a thread is waiting when the
+// last of those is a reviewer's.
+func (b *Bot) fetchPendingThreads(number int) []ReviewThread {
+	cmd := exec.Command("gh", "api", "graphql",
+		"-F", "owner={owner}",
+		"-F", "name={repo}",
+		"-F", fmt.Sprintf("number=%d", number),
+		"-f", "query="+reviewThreadsQuery)
+	cmd.Dir = b.cfg.ProjectDir
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	var resp struct {
+		Data struct {
+			Repository struct {
+				PullRequest struct {
+					ReviewThreads struct {
+						Nodes []struct {
+							ID         string `json:"id"`
+							IsResolved bool   `json:"isResolved"`
+							IsOutdated bool   `json:"isOutdated"`
+							Path       string `json:"path"`
+							Line       int    `json:"line"`
+							Comments   struct {
+								Nodes []struct {
+									Author Author `json:"author"`
+									Body   string `json:"body"`
+								} `json:"nodes"`
+							} `json:"comments"`
+						} `json:"nodes"`
+					} `json:"reviewThreads"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(output, &resp); err != nil {
+		return nil
+	}
+
+	trusted := make(map[string]bool)
+	var threads []ReviewThread
+	for _, n := range resp.Data.Repository.PullRequest.ReviewThreads.Nodes {
+		if n.IsResolved || n.IsOutdated {
+			continue
+		}
+		t := ReviewThread{ID: n.ID, Path: n.Path, Line: n.Line}
+		for _, c := range n.Comments.Nodes {
+			login := c.Author.Login
+			if _, ok := trusted[login]; !ok {
+				trusted[login] = login == b.cfg.BotLogin || b.canReview(login)
+			}
+			if trusted[login] {
+				t.Comments = append(t.Comments, ReviewComment{Author: login, Body: c.Body})
+			}
+		}
+		// Without a reviewer's comment after the bot's reply, the thread is
+		// waiting on the reviewer
+		if len(t.Comments) == 0 || t.Comments[len(t.Comments)-1].Author == b.cfg.BotLogin {
+			continue
+		}
+		threads = append(threads, t)
+	}
+	return threads
+}
+
+// canReview reports whether a review comment's author may direct the bot: a
+// configured reviewer, or a collaborator with write access to the repository
+func (b *Bot) canReview(login string) bool {
+	if login == "" {
+		return false
+	}
+	if slices.Contains(b.cfg.Reviewers, login) {
+		return true
+	}
+	cmd := exec.Command("gh", "api",
+		"repos/{owner}/{repo}/collaborators/"+url.PathEscape(login)+"/permission",
+		"--jq", ".permission")
+	cmd.Dir = b.cfg.ProjectDir
+	output, err := cmd.Output()
+	if err != nil {
+		return false // Not a collaborator
+	}
+	switch strings.TrimSpace(string(output)) {
+	case "admin", "maintain", "write":
+		return true
+	}
+	return false
+}
+
+// processReview amends a bot PR to address unresolved review threads
+func (b *Bot) processReview(pr *PR, threads []ReviewThread) {
+	b.logger.Printf("Addressing %d review thread(s) on PR #%d", len(threads), pr.Number)
+
+	if b.cfg.DryRun {
+		b.logger.Printf("[DRY RUN] Would address review comments on PR #%d", pr.Number)
+		b.planReview(pr, threads)
+		return
+	}
+
+	task := b.beginTask("pr", pr.Number)
+	defer b.finishTask(task)
+
+	b.addLabel("pr", pr.Number, b.cfg.Labels.InProgress)
+
+	b.updateTask(task, func(t *Task) {
+		t.Phase = PhaseCheckout
+		t.Branch = pr.HeadRef
+	})
+	if err := b.checkoutPRBranch(pr); err != nil {
+		b.logger.Printf("Failed to checkout PR branch: %v", err)
+		b.removeLabel("pr", pr.Number, b.cfg.Labels.InProgress)
+		b.addLabel("pr", pr.Number, b.cfg.Labels.Failed)
+		b.recordOutcome("review", pr.Number, OutcomeFailed, task, "checkout: "+err.Error())
+		return
+	}
+
+	b.setPhase(task, PhaseReviewing)
+	result := b.addressReview(pr, threads)
+	if !result.Success {
+		b.commentOnPR(pr.Number, fmt.Sprintf(`🤖 **Review Follow-up Failed**
+
+❌ %s
+
+Manual intervention may be required.`, result.Error))
+		b.removeLabel("pr", pr.Number, b.cfg.Labels.InProgress)
+		b.addLabel("pr", pr.Number, b.cfg.Labels.Failed)
+		b.recordOutcome("review", pr.Number, OutcomeFailed, task, result.Error)
+		b.checkoutMain()
+		return
+	}
+
+	b.setPhase(task, PhasePushing)
+	if result.CommitSHA != "" {
+		if err := b.pushChanges(pr.HeadRef); err != nil {
+			b.logger.Printf("Failed to push review changes: %v", err)
+			b.removeLabel("pr", pr.Number, b.cfg.Labels.InProgress)
+			b.addLabel("pr", pr.Number, b.cfg.Labels.Failed)
+			b.recordOutcome("review", pr.Number, OutcomeFailed, task, "push: "+err.Error())
+			b.checkoutMain()
+			return
+		}
+	}
+
+	// Reply only after the push, so replies never point at unpublished commits
+	for _, r := range result.Replies {
+		if r.Thread < 1 || r.Thread > len(threads) {
+			continue
+		}
+		b.replyToThread(threads[r.Thread-1], reviewReplyBody(r, result.CommitSHA))
+	}
+
+	b.removeLabel("pr", pr.Number, b.cfg.Labels.InProgress)
+	b.checkoutMain()
+	b.recordOutcome("review", pr.Number, OutcomeAddressed, task, "")
+
+	b.logger.Printf("PR #%d: Review follow-up complete", pr.Number)
+}
+
+// reviewReplyBody formats the reply posted on a review thread
+func reviewReplyBody(r ReviewReply, commitSHA string) string {
+	if r.Addressed && commitSHA != "" {
+		return fmt.Sprintf("🤖 Addressed in %s\n\n%s", commitSHA, r.Reply)
+	}
+	if r.Addressed {
+		return "🤖 Addressed\n\n" + r.Reply
+	}
+	return "🤖 Not changed\n\n" + r.Reply
+}
+
+// addressReview runs Claude over the review threads on the checked-out PR branch
+func (b *Bot) addressReview(pr *PR, threads []ReviewThread) *ReviewResult {
+	output, err := b.runClaude(reviewPrompt(pr, threads))
+	if err != nil {
+		return &ReviewResult{Success: false, Error: err.Error()}
+	}
+
+	section := extractSection(output, "---REVIEW_RESULT---", "---END_REVIEW---")
+	if section == "" {
+		return &ReviewResult{Success: false, Error: "Could not parse review output"}
+	}
+
+	result := &ReviewResult{
+		Success:   extractField(section, "SUCCESS") == "YES",
+		CommitSHA: extractField(section, "COMMIT_SHA"),
+		Error:     extractField(section, "ERROR"),
+	}
+	if result.CommitSHA == "N/A" {
+		result.CommitSHA = ""
+	}
+	result.Replies = parseReviewReplies(section)
+	return result
+}
+
+// parseReviewReplies reads lines of the form "THREAD <n>: ADDRESSED|DECLINED | <reply>"
+func parseReviewReplies(section string) []ReviewReply {
+	var replies []ReviewReply
+	for _, line := range strings.Split(section, "\n") {
+		line = strings.TrimSpace(line)
+		rest, ok := strings.CutPrefix(line, "THREAD ")
+		if !ok {
+			continue
+		}
+		num, rest, ok := strings.Cut(rest, ":")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(num))
+		if err != nil {
+			continue
+		}
+		verdict, reply, _ := strings.Cut(rest, "|")
+		replies = append(replies, ReviewReply{
+			Thread:    n,
+			Addressed: strings.TrimSpace(verdict) == "ADDRESSED",
+			Reply:     strings.TrimSpace(reply),
+		})
+	}
+	return replies
+}
+
+// reviewPrompt builds the prompt for addressReview
+func reviewPrompt(pr *PR, threads []ReviewThread) string {
+	var sb strings.Builder
+	for i, t := range threads {
+		fmt.Fprintf(&sb, "### Thread %d: %s", i+1, t.Path)
+		if t.Line > 0 {
+			fmt.Fprintf(&sb, ":%d", t.Line)
+		}
+		sb.WriteString("\n\n")
+		for _, c := range t.Comments {
+			fmt.Fprintf(&sb, "**%s**: %s\n\n", c.Author, c.Body)
+		}
+	}
+
+	return fmt.Sprintf(`You are addressing review comments on PR #%d: %s
+
+## PR Description
+
+%s
+
+## Unresolved Review Threads
+
+%s
+## Your Task
+
+1. You are on branch %s
+2. For each thread, make the requested change if it is reasonable
+3. If you disagree with a comment, leave the code as is and explain why
+4. Run the tests to ensure nothing broke
+5. Commit all changes in a single commit (skip the commit if nothing changed)
+
+## Output Format
+
+After addressing the threads, output:
+
+---REVIEW_RESULT---
+SUCCESS: YES or NO
+COMMIT_SHA: <the commit SHA, or N/A if nothing changed>
+ERROR: <if failed, explain why>
+THREAD 1: ADDRESSED | <short reply to the reviewer>
+THREAD 2: DECLINED | <why the code was left unchanged>
+---END_REVIEW---`, pr.Number, pr.Title, pr.Body, sb.String(), pr.HeadRef)
+}
+
+// replyToThread posts a reply on a review thread
+func (b *Bot) replyToThread(thread ReviewThread, body string) {
+	target := thread.Path
+	if thread.Line > 0 {
+		target = fmt.Sprintf("%s:%d", thread.Path, thread.Line)
+	}
+	b.perform("reply", target, body, func() error {
+		cmd := exec.Command("gh", "api", "graphql",
+			"-f", "thread="+thread.ID,
+			"-f", "body="+body,
+			"-f", "query="+replyThreadMutation)
+		cmd.Dir = b.cfg.ProjectDir
+		return cmd.Run()
+	})
+}
+
+// planReview records what processReview would do
+func (b *Bot) planReview(pr *PR, threads []ReviewThread) {
+	target := fmt.Sprintf("pr #%d", pr.Number)
+	b.report.Begin(fmt.Sprintf("Review on PR #%d: %s", pr.Number, pr.Title))
+	b.addLabel("pr", pr.Number, b.cfg.Labels.InProgress)
+	b.checkoutPRBranch(pr)
+	b.planned("claude", target, reviewPrompt(pr, threads))
+	b.pushChanges(pr.HeadRef)
+	for _, t := range threads {
+		b.planned("reply", t.Path, "reply depends on the outcome of the review run")
+	}
+	b.removeLabel("pr", pr.Number, b.cfg.Labels.InProgress)
+	b.checkoutMain()
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TaskPhase is the step a task had reached when its state was last saved
+type TaskPhase string
+
+const (
+	PhaseStarted       TaskPhase = "started"
+	PhaseAnalyzing     TaskPhase = "analyzing"
+	PhaseCreatingTests TaskPhase = "creating-tests"
+	PhaseCreatingPR    TaskPhase = "creating-pr"
+	PhasePRCreated     TaskPhase = "pr-created"
+	PhaseCheckout      TaskPhase = "checkout"
+	PhaseImplementing  TaskPhase = "implementing"
+	PhasePushing       TaskPhase = "pushing"
+	PhaseReviewing     TaskPhase = "reviewing" // Addressing review comments on a PR
+)
+
+// Task is a unit of in-flight bot work on an issue or PR
+type Task struct {
+	Kind      string    `json:"kind"` // "issue" or "pr"
+	Number    int       `json:"number"`
+	Phase     TaskPhase `json:"phase"`
+	Branch    string    `json:"branch,omitempty"`
+	PRNumber  int       `json:"pr_number,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Key returns the unique key for the task in the store
+func (t *Task) Key() string {
+	return fmt.Sprintf("%s#%d", t.Kind, t.Number)
+}
+
+// StateStore persists in-flight tasks to a JSON file so work survives restarts.
+// Every mutation is written to disk immediately (write to temp file + rename).
+type StateStore struct {
+	path  string
+	mu    sync.Mutex
+	tasks map[string]*Task
+}
+
+// stateFile is the on-disk layout of the store
+type stateFile struct {
+	Version int     `json:"version"`
+	Tasks   []*Task `json:"tasks"`
+}
+
+const stateFileVersion = 1
+
+// LoadStateStore opens the store at path, creating an empty one if missing
+func LoadStateStore(path string) (*StateStore, error) {
+	s := &StateStore{
+		path:  path,
+		tasks: make(map[string]*Task),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f stateFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if f.Version > stateFileVersion {
+		return nil, fmt.Errorf("state file %s has unsupported version %d", path, f.Version)
+	}
+	for _, t := range f.Tasks {
+		s.tasks[t.Key()] = t
+	}
+	return s, nil
+}
+
+// Begin records the start of a task and returns it
+func (s *StateStore) Begin(kind string, number int) (*Task, error) {
+	now := time.Now()
+	t := &Task{
+		Kind:      kind,
+		Number:    number,
+		Phase:     PhaseStarted,
+		StartedAt: now,
+		UpdatedAt: now,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks[t.Key()] = t
+	return t, s.saveLocked()
+}
+
+// Update applies fn to a task and persists the result
+func (s *StateStore) Update(t *Task, fn func(t *Task)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(t)
+	t.UpdatedAt = time.Now()
+	s.tasks[t.Key()] = t
+	return s.saveLocked()
+}
+
+// SetPhase is a shorthand for updating only the phase
+func (s *StateStore) SetPhase(t *Task, phase TaskPhase) error {
+	return s.Update(t, func(t *Task) { t.Phase = phase })
+}
+
+// Finish removes a completed (or abandoned) task from the store
+func (s *StateStore) Finish(t *Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tasks, t.Key())
+	return s.saveLocked()
+}
+
+// Tasks returns a snapshot of all persisted tasks, oldest first
+func (s *StateStore) Tasks() []Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]Task, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		result = append(result, *t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StartedAt.Before(result[j].StartedAt)
+	})
+	return result
+}
+
+// Has reports whether a task for the given item is persisted
+func (s *StateStore) Has(kind string, number int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.tasks[fmt.Sprintf("%s#%d", kind, number)]
+	return ok
+}
+
+func (s *StateStore) saveLocked() error {
+	f := stateFile{Version: stateFileVersion}
+	for _, t := range s.tasks {
+		f.Tasks = append(f.Tasks, t)
+	}
+	sort.Slice(f.Tasks, func(i, j int) bool {
+		return f.Tasks[i].Key() < f.Tasks[j].Key()
+	})
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestStateStoreRoundTrip tests that tasks survive a reload from disk with
+// their progress, and that finished ones are gone.
+func TestStateStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "state.json")
+	store, err := LoadStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(store.Tasks()); n != 0 {
+		t.Fatalf("New store has %d tasks, want none", n)
+	}
+
+	issue, err := store.Begin("issue", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr, err := store.Begin("pr", 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done, err := store.Begin("issue", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Update(issue, func(t *Task) {
+		t.Phase = PhasePRCreated
+		t.Branch = "bot/issue-7"
+		t.PRNumber = 42
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetPhase(pr, PhasePushing); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Finish(done); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := LoadStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := reloaded.Tasks()
+	want := store.Tasks()
+	if len(got) != 2 || len(want) != 2 {
+		t.Fatalf("Reloaded %d tasks, want %d of 2", len(got), len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Key() != w.Key() || g.Phase != w.Phase || g.Branch != w.Branch || g.PRNumber != w.PRNumber ||
+			!g.StartedAt.Equal(w.StartedAt) || !g.UpdatedAt.Equal(w.UpdatedAt) {
+			t.Errorf("Reloaded task %d = %+v, want %+v", i, g, w)
+		}
+	}
+	if !reloaded.Has("issue", 7) || !reloaded.Has("pr", 12) || reloaded.Has("issue", 3) {
+		t.Error("Has disagrees with the saved tasks")
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("Temporary file left behind: %v", err)
+	}
+}
+
+// TestLoadStateStoreErrors tests that unreadable and newer state files are
+// refused rather than silently emptied.
+func TestLoadStateStoreErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"not json", "{"},
+		{"newer version", `{"version": 2, "tasks": []}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "state.json")
+			if err := os.WriteFile(path, []byte(tc.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadStateStore(path); err == nil {
+				t.Error("LoadStateStore succeeded, want an error")
+			}
+		})
+	}
+}
@@ -2,23 +2,64 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
+	"time"
+
+	"github.com/andersfylling/rayman-slides/internal/lobby"
 )
 
 // Version is set at build time
 var Version = "dev"
 
 func main() {
+	port := flag.Int("port", 8080, "HTTP port to listen on")
+	ttl := flag.Duration("ttl", 4*time.Hour, "lifetime of a room until its host's first heartbeat")
+	probeTimeout := flag.Duration("probe-timeout", 2*time.Second, "how long lookups wait for a host to accept TCP (0 = don't check)")
+	cfg := lobby.DefaultServiceConfig()
+	flag.IntVar(&cfg.CreatePerMinute, "create-rate", cfg.CreatePerMinute, "rooms one IP may create per minute (0 = unlimited)")
+	flag.IntVar(&cfg.LookupPerMinute, "lookup-rate", cfg.LookupPerMinute, "lookups one IP may make per minute (0 = unlimited)")
+	flag.IntVar(&cfg.FreeFailures, "free-failures", cfg.FreeFailures, "failed lookups per IP before exponential backoff")
+	flag.DurationVar(&cfg.MaxBackoff, "max-backoff", cfg.MaxBackoff, "longest an IP is blocked for failed lookups")
+	flag.StringVar(&cfg.LatestVersion, "latest-version", "", "latest release, for clients' update notice (e.g. 1.4.0)")
+	flag.StringVar(&cfg.ReleaseURL, "release-url", "", "where clients can download -latest-version")
+	flag.StringVar(&cfg.AllowOrigin, "allow-origin", "", "let pages from this origin call the service, e.g. the browser client's (https://play.example.com, or * for any)")
+	flag.BoolVar(&cfg.TrustProxy, "trust-proxy", false, "take client IPs from X-Forwarded-For (only behind a proxy that sets it)")
+	flag.Parse()
+
 	fmt.Printf("Room Lookup Service v%s\n", Version)
-	fmt.Println("Starting HTTP server...")
 
-	// TODO: Parse flags (--port, --ttl)
-	// TODO: Initialize room store (in-memory or Redis)
-	// TODO: Start HTTP server
-	// POST /rooms - create room, returns code
-	// GET /rooms/:code - lookup room, returns host IP:port
-	// DELETE /rooms/:code - remove room
+	store := lobby.NewRoomStore(*ttl)
+	if *probeTimeout > 0 {
+		store.Probe = lobby.DialProbe(*probeTimeout)
+	}
+
+	// Access log as JSON lines on stdout
+	cfg.Logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	service := lobby.NewService(store, cfg)
+
+	go func() {
+		for range time.Tick(lobby.HeartbeatInterval) {
+			store.Cleanup()
+			service.Cleanup()
+		}
+	}()
 
-	os.Exit(0)
+	addr := fmt.Sprintf(":%d", *port)
+	fmt.Printf("Listening on %s\n", addr)
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           service,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      10 * time.Second,
+		MaxHeaderBytes:    8 << 10,
+	}
+	if err := srv.ListenAndServe(); err != nil {
+		fmt.Fprintf(os.Stderr, "lookup: %v\n", err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,42 @@
+// Command netsim soak-tests client prediction: it runs a server and several
+// clients in one process over simulated links with latency, jitter,
+// reordering and loss, and reports whether every client converged.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/andersfylling/rayman-slides/internal/netsim"
+)
+
+func main() {
+	cfg := netsim.DefaultConfig()
+	flag.IntVar(&cfg.Clients, "clients", cfg.Clients, "number of clients")
+	flag.IntVar(&cfg.Ticks, "ticks", cfg.Ticks, "ticks of random play")
+	flag.IntVar(&cfg.Settle, "settle", cfg.Settle, "idle ticks after play before comparing")
+	flag.IntVar(&cfg.Up.Latency, "latency", cfg.Up.Latency, "one-way latency in ticks")
+	flag.IntVar(&cfg.Up.Jitter, "jitter", cfg.Up.Jitter, "extra random delay, up to this many ticks")
+	flag.Float64Var(&cfg.Up.Loss, "loss", cfg.Up.Loss, "share of messages dropped")
+	flag.Float64Var(&cfg.Up.Reorder, "reorder", cfg.Up.Reorder, "share of messages delayed past later ones")
+	flag.IntVar(&cfg.Lead, "lead", 0, "ticks clients run ahead of the server (0 = from latency)")
+	flag.IntVar(&cfg.Redundancy, "redundancy", cfg.Redundancy, "input frames repeated per message")
+	flag.Float64Var(&cfg.Tolerance, "tolerance", cfg.Tolerance, "largest allowed position difference at the end")
+	flag.Uint64Var(&cfg.Seed, "seed", cfg.Seed, "random seed")
+	flag.Parse()
+	cfg.Down = cfg.Up
+
+	report := netsim.Run(cfg)
+
+	fmt.Printf("server tick %d\n", report.ServerTick)
+	fmt.Printf("%-8s %9s %6s %10s %9s %10s\n", "player", "rollbacks", "stale", "input loss", "max error", "divergence")
+	for _, c := range report.Clients {
+		fmt.Printf("%-8d %9d %6d %9.1f%% %9.2f %10.4f\n", c.PlayerID, c.Rollbacks, c.StaleDrops, 100*c.InputLoss, c.MaxError, c.Divergence)
+	}
+	if !report.Converged(cfg.Tolerance) {
+		fmt.Println("DIVERGED")
+		os.Exit(1)
+	}
+	fmt.Println("converged")
+}
@@ -0,0 +1,206 @@
+//go:build gio
+
+package main
+
+import (
+	"crypto/tls"
+	"sync"
+	"time"
+
+	"gioui.org/io/key"
+
+	"github.com/andersfylling/rayman-slides/internal/client"
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/i18n"
+	"github.com/andersfylling/rayman-slides/internal/lobby"
+	"github.com/andersfylling/rayman-slides/internal/network"
+	"github.com/andersfylling/rayman-slides/internal/render"
+)
+
+// pingTimeout bounds how long the browser waits for one host
+const pingTimeout = 2 * time.Second
+
+// pingSamples is how many pings estimate each host's round trip
+const pingSamples = 3
+
+// browser is the "Browse games" screen: it lists the lookup service's
+// public rooms with a free slot, optionally in one region, pings each host
+// and sorts the list by round trip. Listing, pinging and joining run in the
+// background and redraw the window when done.
+type browser struct {
+	lookup     *lobby.LookupClient
+	region     string
+	tls        *tls.Config   // Connect to hosts over TLS; nil for plain TCP
+	name       string        // Player name in the handshake
+	tr         *i18n.Catalog // Status messages
+	invalidate func()
+
+	mu    sync.Mutex
+	rooms map[string]lobby.Room // By code
+	view  render.Browser
+	gen   int // Bumped by refresh so stale pings are dropped
+}
+
+func newBrowser(lookupURL, region string, secure bool, name string, tr *i18n.Catalog, invalidate func()) *browser {
+	b := &browser{
+		lookup:     lobby.NewLookupClient(lookupURL),
+		region:     region,
+		name:       name,
+		invalidate: invalidate,
+		tr:         tr,
+		view:       render.Browser{Playable: lobby.PlayablePing, Lang: tr},
+	}
+	if secure {
+		b.tls = &tls.Config{}
+	}
+	go b.refresh()
+	return b
+}
+
+// joinInvite joins the room an invite names, with its room key for a
+// private room
+func (b *browser) joinInvite(invite string) {
+	code, key, err := lobby.ParseInvite(invite)
+	b.mu.Lock()
+	if err != nil {
+		b.view.Status = b.tr.T("browser.join_failed", invite, err)
+		b.mu.Unlock()
+		b.invalidate()
+		return
+	}
+	b.view.Status = b.tr.T("browser.joining", code)
+	b.mu.Unlock()
+	go b.join(lobby.Room{Code: code, Name: code}, key)
+}
+
+// View returns a copy of the screen to draw
+func (b *browser) View() *render.Browser {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	view := b.view
+	view.Rooms = append([]render.BrowserRoom(nil), b.view.Rooms...)
+	return &view
+}
+
+// HandleKey applies one key event and reports whether the browser closed
+func (b *browser) HandleKey(ke key.Event) bool {
+	if ke.State != key.Press {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch ke.Name {
+	case key.NameEscape:
+		return true
+	case key.NameUpArrow, "W":
+		b.view.Selected = max(b.view.Selected-1, 0)
+	case key.NameDownArrow, "S":
+		b.view.Selected = max(min(b.view.Selected+1, len(b.view.Rooms)-1), 0)
+	case "R":
+		go b.refresh()
+	case key.NameReturn, key.NameEnter:
+		if b.view.Selected < len(b.view.Rooms) {
+			room := b.rooms[b.view.Rooms[b.view.Selected].Code]
+			b.view.Status = b.tr.T("browser.joining", room.Name)
+			go b.join(room, "")
+		}
+	}
+	return false
+}
+
+// refresh reloads the room list and pings every host concurrently
+func (b *browser) refresh() {
+	b.mu.Lock()
+	b.gen++
+	gen := b.gen
+	b.view.Status = b.tr.T("browser.loading")
+	b.mu.Unlock()
+	b.invalidate()
+
+	list, err := b.lookup.List(lobby.ListFilter{NotFull: true, Region: b.region})
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if gen != b.gen {
+		return
+	}
+	if err != nil {
+		b.view.Status = b.tr.T("browser.load_failed", err)
+		b.invalidate()
+		return
+	}
+	b.rooms = make(map[string]lobby.Room, len(list.Rooms))
+	b.view.Rooms = make([]render.BrowserRoom, len(list.Rooms))
+	b.view.Selected = min(b.view.Selected, max(len(list.Rooms)-1, 0))
+	b.view.Status = b.tr.T("browser.games", list.Total)
+	for i, room := range list.Rooms {
+		b.rooms[room.Code] = room
+		b.view.Rooms[i] = render.BrowserRoom{
+			Code:       room.Code,
+			Name:       room.Name,
+			Players:    room.Players,
+			MaxPlayers: room.MaxPlayers,
+			Region:     room.Region,
+		}
+		go b.ping(gen, room.Code, room.Host)
+	}
+	b.invalidate()
+}
+
+// ping measures one room and moves it into place by round trip
+func (b *browser) ping(gen int, code, host string) {
+	rtt, err := lobby.EstimateRTT(host, pingSamples, pingTimeout)
+	if err != nil {
+		rtt = -1
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if gen != b.gen {
+		return
+	}
+	for i := range b.view.Rooms {
+		if b.view.Rooms[i].Code == code {
+			b.view.Rooms[i].Ping = rtt
+		}
+	}
+	b.view.SortByPing()
+	b.invalidate()
+}
+
+// join looks the room up again, which has the service check the host is
+// still reachable, and runs the handshakes with the host; key is the room
+// key from an invite, empty for an open room
+func (b *browser) join(room lobby.Room, key string) {
+	status, err := b.dial(room.Code, key)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err != nil {
+		status = b.tr.T("browser.join_failed", room.Name, err)
+	}
+	b.view.Status = status
+	b.invalidate()
+}
+
+// dial joins the room's host, fetching its level into the asset cache, and
+// returns the status to show. The client can't play on a remote server yet,
+// so it leaves again once accepted.
+func (b *browser) dial(code, key string) (string, error) {
+	found, err := b.lookup.Lookup(code)
+	if err != nil {
+		return "", err
+	}
+	h, err := client.NewHandshake(b.name, Version, nil, game.DefaultPhysics())
+	if err != nil {
+		return "", err
+	}
+	var cache *network.AssetCache
+	if dir, err := network.DefaultAssetCacheDir(); err == nil {
+		cache, _ = network.NewAssetCache(dir) // Without one the level isn't fetched
+	}
+	remote, err := client.Dial(found.Host, b.tls, lobby.KeyPSK(key), cache, h)
+	if err != nil {
+		return "", err
+	}
+	remote.Close()
+	return b.tr.T("browser.remote", found.Name, remote.Reply.PlayerID), nil
+}
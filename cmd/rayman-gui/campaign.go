@@ -0,0 +1,103 @@
+//go:build gio
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gioui.org/io/key"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/i18n"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+	"github.com/andersfylling/rayman-slides/internal/render"
+)
+
+// campaignPlay is a campaign being played: its world map, the level picked
+// from it, and the stars earned, kept in the save profile
+type campaignPlay struct {
+	campaign *game.Campaign
+	save     *saveProfile
+	tr       *i18n.Catalog
+	current  int // Level being played
+	view     render.WorldMap
+}
+
+// newCampaignPlay loads a campaign file, with the next level to play
+// picked and selected on the map
+func newCampaignPlay(path string, save *saveProfile, tr *i18n.Catalog) (*campaignPlay, error) {
+	c, err := game.ReadCampaign(os.DirFS(filepath.Dir(path)), filepath.Base(path))
+	if err != nil {
+		return nil, err
+	}
+	next := c.Next(save.campaign(c.Name))
+	return &campaignPlay{
+		campaign: c,
+		save:     save,
+		tr:       tr,
+		current:  next,
+		view:     render.WorldMap{Campaign: c, Progress: save.campaign(c.Name), Selected: next, Lang: tr},
+	}, nil
+}
+
+// level loads the level being played
+func (cp *campaignPlay) level() (*game.Level, error) {
+	return cp.campaign.LoadLevel(cp.current)
+}
+
+// View returns the world map to draw
+func (cp *campaignPlay) View() *render.WorldMap {
+	view := cp.view
+	return &view
+}
+
+// open selects the next level to play, for showing the map again
+func (cp *campaignPlay) open() {
+	cp.view.Selected = cp.campaign.Next(cp.view.Progress)
+	cp.view.Status = ""
+}
+
+// HandleKey applies one key event to the open map. It returns the level
+// picked with Enter, loaded, and reports whether the map closed: after a
+// pick, or on Esc.
+func (cp *campaignPlay) HandleKey(ke key.Event) (picked *game.Level, closed bool) {
+	if ke.State != key.Press {
+		return nil, false
+	}
+	switch ke.Name {
+	case key.NameEscape:
+		return nil, true
+	case key.NameUpArrow, "W":
+		cp.view.Selected = max(cp.view.Selected-1, 0)
+	case key.NameDownArrow, "S":
+		cp.view.Selected = min(cp.view.Selected+1, len(cp.campaign.Levels)-1)
+	case key.NameReturn, key.NameEnter:
+		i := cp.view.Selected
+		if !cp.campaign.Unlocked(i, cp.view.Progress) {
+			return nil, false
+		}
+		level, err := cp.campaign.LoadLevel(i)
+		if err != nil {
+			cp.view.Status = cp.tr.T("worldmap.load_failed", cp.campaign.Levels[i].Title, err)
+			return nil, false
+		}
+		cp.current = i
+		return level, true
+	}
+	return nil, false
+}
+
+// finish records the stars a run that reached the exit earned on the
+// current level, saving the profile if any are new, and returns them
+func (cp *campaignPlay) finish(ps protocol.PlayerStats) game.Stars {
+	l := &cp.campaign.Levels[cp.current]
+	stars := l.Earned(ps)
+	if cp.view.Progress.Record(l.ID, stars) {
+		if err := cp.save.save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not save profile: %v\n", err)
+		}
+	}
+	return stars
+}
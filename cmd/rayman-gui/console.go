@@ -0,0 +1,130 @@
+//go:build gio
+
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"gioui.org/io/key"
+
+	"github.com/andersfylling/rayman-slides/internal/client"
+	"github.com/andersfylling/rayman-slides/internal/i18n"
+	"github.com/andersfylling/rayman-slides/internal/input"
+	"github.com/andersfylling/rayman-slides/internal/render"
+)
+
+// consoleScrollback is how many lines of output the console keeps
+const consoleScrollback = 200
+
+// devConsole is the developer console, opened with the tilde key in dev
+// mode. Text comes from key.EditEvents, so it follows the keyboard layout;
+// Enter runs the line with client.Console, Up and Down recall earlier
+// lines, and Esc or the tilde key close it.
+type devConsole struct {
+	console  *client.Console
+	tr       *i18n.Catalog
+	output   []string
+	input    string
+	history  []string
+	recalled int // Index in history of the line shown; len(history) for a new line
+}
+
+func newDevConsole(cl *client.Client, tr *i18n.Catalog) *devConsole {
+	return &devConsole{console: client.NewConsole(cl), tr: tr}
+}
+
+// Register adds a command that needs more than the client, such as the
+// free camera
+func (d *devConsole) Register(name string, cmd client.ConsoleCommand) {
+	d.console.Register(name, cmd)
+}
+
+// View returns the console to draw
+func (d *devConsole) View() *render.Console {
+	return &render.Console{Output: d.output, Input: d.input, Lang: d.tr}
+}
+
+// Type adds typed text to the input line. The tilde key's own characters
+// are dropped: the key opens and closes the console.
+func (d *devConsole) Type(text string) {
+	d.input += strings.Map(func(r rune) rune {
+		if r == '`' || r == '~' || r < ' ' {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// HandleKey applies one key event and reports whether the console closed
+func (d *devConsole) HandleKey(ke key.Event) (closed bool) {
+	if ke.State != key.Press {
+		return false
+	}
+	switch ke.Name {
+	case key.NameEscape, "`", "~":
+		return true
+	case key.NameReturn, key.NameEnter:
+		d.run()
+	case key.NameDeleteBackward:
+		_, size := utf8.DecodeLastRuneInString(d.input)
+		d.input = d.input[:len(d.input)-size]
+	case key.NameUpArrow:
+		if d.recalled > 0 {
+			d.recalled--
+			d.input = d.history[d.recalled]
+		}
+	case key.NameDownArrow:
+		d.recalled = min(d.recalled+1, len(d.history))
+		d.input = ""
+		if d.recalled < len(d.history) {
+			d.input = d.history[d.recalled]
+		}
+	}
+	return false
+}
+
+// run executes the input line and prints it with its output
+func (d *devConsole) run() {
+	line := strings.TrimSpace(d.input)
+	d.input = ""
+	if line == "" {
+		return
+	}
+	d.history = append(d.history, line)
+	d.recalled = len(d.history)
+	d.print("> " + line)
+	out, err := d.console.Exec(line)
+	switch {
+	case err != nil:
+		d.print(err.Error())
+	case out != "":
+		d.print(strings.Split(out, "\n")...)
+	}
+}
+
+// freeCamSteer returns the direction the held movement keys steer the free
+// camera in
+func freeCamSteer(cl *client.Client) (dx, dy float64) {
+	if cl.IsPressed(input.KeyLeft) {
+		dx--
+	}
+	if cl.IsPressed(input.KeyRight) {
+		dx++
+	}
+	if cl.IsPressed(input.KeyJump) {
+		dy--
+	}
+	if cl.IsPressed(input.KeyCrouch) {
+		dy++
+	}
+	return dx, dy
+}
+
+// print adds lines of output, dropping the oldest past consoleScrollback
+func (d *devConsole) print(lines ...string) {
+	d.output = append(d.output, lines...)
+	if n := len(d.output) - consoleScrollback; n > 0 {
+		d.output = d.output[n:]
+	}
+}
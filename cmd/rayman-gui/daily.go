@@ -0,0 +1,119 @@
+//go:build gio
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/i18n"
+	"github.com/andersfylling/rayman-slides/internal/lobby"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+	"github.com/andersfylling/rayman-slides/internal/render"
+)
+
+// challenge is the daily challenge: every run is recorded, and every
+// finish uploaded with its replay hash to the lookup service and answered
+// with today's top times. Uploads run in the background and redraw the
+// window when done.
+type challenge struct {
+	lookup     *lobby.LookupClient
+	date       string // UTC date the level was generated for
+	level      string // Level hash, hex
+	name       string // Leaderboard name
+	tr         *i18n.Catalog
+	invalidate func()
+	run        *game.Replay // Current attempt
+
+	mu   sync.Mutex
+	view *render.Leaderboard // Since the last finish, nil before it
+}
+
+// newChallenge sets up the daily challenge for level, generated for
+// now's date
+func newChallenge(lookupURL, name string, level *game.Level, now time.Time, tr *i18n.Catalog, invalidate func()) (*challenge, error) {
+	hash, err := levelKey(level)
+	if err != nil {
+		return nil, err
+	}
+	return &challenge{
+		lookup:     lobby.NewLookupClient(lookupURL),
+		date:       game.DailyDate(now),
+		level:      hash,
+		name:       name,
+		tr:         tr,
+		invalidate: invalidate,
+		run:        &game.Replay{Level: level.Name, Player: name},
+	}, nil
+}
+
+// record adds the intents of the tick about to run
+func (c *challenge) record(intents protocol.Intent) {
+	c.run.Record(intents)
+}
+
+// finish uploads the run ending at the exit, with its rank, and fetches
+// the leaderboard
+func (c *challenge) finish(orbs int, rank game.RunRank) {
+	c.run.FinishTicks = uint64(c.run.Len())
+	req := lobby.ScoreRequest{Mode: lobby.ModeDaily, Date: c.date, Level: c.level, Name: c.name, Ticks: int(c.run.FinishTicks), Orbs: orbs,
+		Grade: rank.Grade, Points: rank.Score}
+	c.mu.Lock()
+	c.view = &render.Leaderboard{
+		Title:     c.tr.T("leaderboard.title", c.date),
+		Grades:    true,
+		Highlight: c.name,
+		Status:    c.tr.T("leaderboard.submitting"),
+		Footer:    c.tr.T("results.footer"),
+		Lang:      c.tr,
+	}
+	view := c.view
+	c.mu.Unlock()
+	go c.submit(view, req, c.run)
+}
+
+// submit runs in the background; view is dropped if a restart replaced it
+func (c *challenge) submit(view *render.Leaderboard, req lobby.ScoreRequest, run *game.Replay) {
+	var list *lobby.ScoreList
+	var err error
+	if req.Replay, err = replayKey(run); err == nil {
+		if _, err = c.lookup.SubmitScore(req); err == nil {
+			list, err = c.lookup.Scores(lobby.ScoreQuery{Date: c.date, Level: c.level, Limit: leaderboardTop, Name: c.name})
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.view != view {
+		return
+	}
+	if err != nil {
+		view.Status = c.tr.T("leaderboard.offline", err)
+	} else {
+		view.Status = ""
+		view.Rank, view.Total = list.Rank, list.Total
+		view.Entries = leaderboardEntries(list)
+	}
+	c.invalidate()
+}
+
+// View returns a copy of the leaderboard to draw, nil before a finish
+func (c *challenge) View() *render.Leaderboard {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.view == nil {
+		return nil
+	}
+	view := *c.view
+	view.Entries = append([]render.LeaderboardEntry(nil), c.view.Entries...)
+	return &view
+}
+
+// restart hides the leaderboard and starts recording a new attempt
+func (c *challenge) restart() {
+	c.run = &game.Replay{Level: c.run.Level, Player: c.name}
+	c.mu.Lock()
+	c.view = nil
+	c.mu.Unlock()
+}
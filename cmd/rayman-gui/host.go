@@ -0,0 +1,96 @@
+//go:build gio
+
+package main
+
+import (
+	"gioui.org/io/key"
+
+	"github.com/andersfylling/rayman-slides/internal/client"
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/i18n"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+	"github.com/andersfylling/rayman-slides/internal/render"
+)
+
+// hostMenu is the hosting player's menu, opened with H from the pause
+// menu. Every change goes to the server as a protocol.HostCommand, which
+// checks it; the menu shows the settings the server last sent back and the
+// reason for any refusal.
+type hostMenu struct {
+	cl       *client.Client
+	tr       *i18n.Catalog
+	selected int    // Among the players other than the host
+	status   string // Why the last command was refused
+}
+
+func newHostMenu(cl *client.Client, tr *i18n.Catalog) *hostMenu {
+	return &hostMenu{cl: cl, tr: tr}
+}
+
+// Menu returns the menu to draw
+func (h *hostMenu) Menu() *render.Menu {
+	m := render.HostMenu(h.tr, h.cl.Settings(), h.cl.Server().Roster(), h.selected)
+	if h.status != "" {
+		m.Notes = append(m.Notes, "", h.status)
+	}
+	return m
+}
+
+// HandleKey applies one key event and reports whether the menu closed and
+// whether the level restarted, which closes it too
+func (h *hostMenu) HandleKey(ke key.Event) (closed, restarted bool) {
+	if ke.State != key.Press {
+		return false, false
+	}
+	settings := h.cl.Settings()
+	switch ke.Name {
+	case key.NameEscape:
+		return true, false
+	case key.NameUpArrow, "W":
+		h.selected = max(h.selected-1, 0)
+	case key.NameDownArrow, "S":
+		h.selected = max(min(h.selected+1, len(h.kickable())-1), 0)
+	case "D":
+		next := game.Difficulty(settings.Difficulty).Next()
+		h.send(protocol.HostCommand{Action: protocol.HostDifficulty, Value: uint8(next)})
+	case "F":
+		var on uint8
+		if !settings.FriendlyFire {
+			on = 1
+		}
+		h.send(protocol.HostCommand{Action: protocol.HostFriendlyFire, Value: on})
+	case "K":
+		if players := h.kickable(); h.selected < len(players) {
+			h.send(protocol.HostCommand{Action: protocol.HostKick, Target: players[h.selected]})
+			h.selected = max(min(h.selected, len(players)-2), 0)
+		}
+	case "R":
+		if h.send(protocol.HostCommand{Action: protocol.HostRestart}) {
+			return true, true
+		}
+	}
+	return false, false
+}
+
+// send sends a command and reports whether the server applied it
+func (h *hostMenu) send(cmd protocol.HostCommand) bool {
+	result := h.cl.HostCommand(cmd)
+	h.status = ""
+	if !result.OK {
+		h.status = h.tr.T("host.refused", result.Reason)
+	}
+	return result.OK
+}
+
+// kickable returns the IDs of the players other than the host, in the
+// order the menu lists them
+func (h *hostMenu) kickable() []int {
+	host := h.cl.Settings().Host
+	var ids []int
+	for _, p := range h.cl.Server().Roster() {
+		if p.PlayerID != host {
+			ids = append(ids, p.PlayerID)
+		}
+	}
+	return ids
+}
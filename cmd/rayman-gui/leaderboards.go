@@ -0,0 +1,215 @@
+//go:build gio
+
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"gioui.org/io/key"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/i18n"
+	"github.com/andersfylling/rayman-slides/internal/lobby"
+	"github.com/andersfylling/rayman-slides/internal/render"
+)
+
+// leaderboardTop is how many runs a leaderboard lists
+const leaderboardTop = 10
+
+// boardLevel is one leaderboard the browser can show
+type boardLevel struct {
+	name  string // Shown in the title
+	mode  string // lobby.ModeTimeTrial or lobby.ModeDaily
+	date  string // Daily challenge only
+	level string // Level hash, hex
+}
+
+// boards is the leaderboard browser, opened from the pause menu: the best
+// runs on the built-in levels and today's daily challenge, by time, orbs
+// or rank. Boards load in the background and redraw the window when done.
+type boards struct {
+	lookup     *lobby.LookupClient
+	name       string // Local player, marked in the list
+	tr         *i18n.Catalog
+	invalidate func()
+	levels     []boardLevel
+
+	mu       sync.Mutex
+	selected int // Index into levels
+	sort     string
+	view     render.Leaderboard
+	gen      int // Bumped by refresh so stale answers are dropped
+}
+
+// newBoards opens the browser on current's board, if it is one of the
+// built-in levels
+func newBoards(lookupURL, name string, current *game.Level, tr *i18n.Catalog, invalidate func()) (*boards, error) {
+	b := &boards{
+		lookup:     lobby.NewLookupClient(lookupURL),
+		name:       name,
+		tr:         tr,
+		invalidate: invalidate,
+		sort:       lobby.SortTime,
+	}
+	now := time.Now()
+	for _, l := range []struct {
+		level *game.Level
+		mode  string
+		date  string
+	}{
+		{game.NewDemoLevel(80, 45), lobby.ModeTimeTrial, ""},
+		{game.NewTutorialLevel(), lobby.ModeTimeTrial, ""},
+		{game.NewDailyLevel(now), lobby.ModeDaily, game.DailyDate(now)},
+	} {
+		hash, err := levelKey(l.level)
+		if err != nil {
+			return nil, err
+		}
+		b.levels = append(b.levels, boardLevel{name: l.level.Name, mode: l.mode, date: l.date, level: hash})
+	}
+	if hash, err := levelKey(current); err == nil {
+		for i, l := range b.levels {
+			if l.level == hash {
+				b.selected = i
+			}
+		}
+	}
+	go b.refresh()
+	return b, nil
+}
+
+// View returns a copy of the screen to draw
+func (b *boards) View() *render.Leaderboard {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	view := b.view
+	view.Entries = append([]render.LeaderboardEntry(nil), b.view.Entries...)
+	return &view
+}
+
+// HandleKey applies one key event and reports whether the browser closed
+func (b *boards) HandleKey(ke key.Event) bool {
+	if ke.State != key.Press {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch ke.Name {
+	case key.NameEscape:
+		return true
+	case key.NameLeftArrow, "A":
+		b.selected = (b.selected + len(b.levels) - 1) % len(b.levels)
+		go b.refresh()
+	case key.NameRightArrow, "D":
+		b.selected = (b.selected + 1) % len(b.levels)
+		go b.refresh()
+	case "O":
+		switch b.sort {
+		case lobby.SortTime:
+			b.sort = lobby.SortOrbs
+		case lobby.SortOrbs:
+			b.sort = lobby.SortScore
+		default:
+			b.sort = lobby.SortTime
+		}
+		go b.refresh()
+	case "R":
+		go b.refresh()
+	}
+	return false
+}
+
+// refresh loads the selected board
+func (b *boards) refresh() {
+	b.mu.Lock()
+	b.gen++
+	gen := b.gen
+	l, sort := b.levels[b.selected], b.sort
+	b.view = render.Leaderboard{
+		Title:     b.tr.T("leaderboard.browser_"+sort, l.name),
+		Orbs:      sort == lobby.SortOrbs,
+		Grades:    sort == lobby.SortScore,
+		Highlight: b.name,
+		Status:    b.tr.T("browser.loading"),
+		Footer:    b.tr.T("leaderboard.footer"),
+		Lang:      b.tr,
+	}
+	b.mu.Unlock()
+	b.invalidate()
+
+	list, err := b.lookup.Scores(lobby.ScoreQuery{Mode: l.mode, Date: l.date, Level: l.level, Sort: sort, Limit: leaderboardTop, Name: b.name})
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if gen != b.gen {
+		return
+	}
+	if err != nil {
+		b.view.Status = b.tr.T("leaderboard.offline", err)
+	} else {
+		b.view.Status = ""
+		b.view.Rank, b.view.Total = list.Rank, list.Total
+		b.view.Entries = leaderboardEntries(list)
+	}
+	b.invalidate()
+}
+
+// submitTimeTrial uploads a new personal best in time trial with its
+// replay's hash and rank, warning on failure; it runs in the background
+func submitTimeTrial(lookupURL, name string, level *game.Level, best *game.Replay, orbs int, rank game.RunRank) {
+	err := func() error {
+		hash, err := levelKey(level)
+		if err != nil {
+			return err
+		}
+		replay, err := replayKey(best)
+		if err != nil {
+			return err
+		}
+		_, err = lobby.NewLookupClient(lookupURL).SubmitScore(lobby.ScoreRequest{
+			Mode:   lobby.ModeTimeTrial,
+			Level:  hash,
+			Name:   name,
+			Ticks:  int(best.FinishTicks),
+			Orbs:   orbs,
+			Replay: replay,
+			Grade:  rank.Grade,
+			Points: rank.Score,
+		})
+		return err
+	}()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not submit time: %v\n", err)
+	}
+}
+
+// leaderboardEntries converts the service's scores for drawing
+func leaderboardEntries(list *lobby.ScoreList) []render.LeaderboardEntry {
+	entries := make([]render.LeaderboardEntry, len(list.Scores))
+	for i, sc := range list.Scores {
+		entries[i] = render.LeaderboardEntry{Rank: i + 1, Name: sc.Name, Ticks: uint64(sc.Ticks), Orbs: sc.Orbs, Grade: sc.Grade, Points: sc.Points}
+	}
+	return entries
+}
+
+// levelKey names a level on the leaderboards: its hash, in hex
+func levelKey(level *game.Level) (string, error) {
+	hash, err := game.LevelHash(level)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// replayKey is a run's replay hash, in hex
+func replayKey(r *game.Replay) (string, error) {
+	hash, err := game.ReplayHash(r)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash[:]), nil
+}
@@ -4,9 +4,15 @@
 package main
 
 import (
+	"cmp"
 	"embed"
+	"flag"
 	"fmt"
+	"net/http"
+	_ "net/http/pprof" // Profiling handlers for -pprof
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"gioui.org/app"
@@ -17,28 +23,203 @@ import (
 	"gioui.org/op/clip"
 	"gioui.org/unit"
 
+	"github.com/andersfylling/rayman-slides/internal/allocs"
+	"github.com/andersfylling/rayman-slides/internal/client"
+	"github.com/andersfylling/rayman-slides/internal/crash"
 	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/i18n"
 	"github.com/andersfylling/rayman-slides/internal/input"
+	"github.com/andersfylling/rayman-slides/internal/lobby"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
 	"github.com/andersfylling/rayman-slides/internal/render"
+	"github.com/andersfylling/rayman-slides/internal/server"
 )
 
 //go:embed assets
 var assetsFS embed.FS
 
+// Version is set at build time
+var Version = "dev"
+
 type keyboardTag struct{}
 
+// maxCatchUpTicks bounds the ticks run in one frame. After the window was
+// suspended for seconds, replaying all of it at once would make that frame
+// slower still (spiral of death); the excess time is dropped instead.
+const maxCatchUpTicks = 5
+
+// behindWarning is how long the debug overlay reports dropped ticks
+const behindWarning = 3 * time.Second
+
+// combatLogLines is how many combat log lines the debug overlay shows, and
+// how far PgUp/PgDn scroll
+const combatLogLines = 8
+
 func main() {
+	timeTrialMode := flag.Bool("timetrial", false, "race a ghost of your best time to the exit")
+	replayDir := flag.String("replays", defaultReplayDir(), "directory for time-trial best runs")
+	uncapped := flag.Bool("uncapped", false, "redraw as fast as possible instead of once per tick (benchmarking)")
+	browse := flag.String("browse", "", "open the server browser on this lookup service URL first")
+	region := flag.String("region", "", "only browse games with this region tag (e.g. eu)")
+	joinInvite := flag.String("join", "", "join a room by its invite (code, then key for private rooms) on the -browse lookup service")
+	useTLS := flag.Bool("tls", false, "connect to game servers over TLS")
+	physicsPath := flag.String("physics", "", "player physics tunables file (JSON, see assets/physics.json)")
+	dev := flag.Bool("dev", false, "development mode: the tilde key opens the developer console and the -physics file reloads when it changes")
+	pprofAddr := flag.String("pprof", "", "serve /debug/pprof profiling on this address (e.g. localhost:6060)")
+	training := flag.Bool("training", false, "practice room with target dummies and charge timing; reloads -physics on change")
+	tutorial := flag.Bool("tutorial", false, "play the tutorial level, with hints the first time through")
+	campaignPath := flag.String("campaign", "", "play a campaign file (e.g. assets/campaign.json): pick levels on its world map; stars are saved to the profile")
+	dailyMode := flag.Bool("daily", false, "play today's daily challenge, the same generated level for everyone (UTC date)")
+	scoresURL := flag.String("scores", "", "lookup service URL for leaderboards: time trial bests and daily challenge times are uploaded, B in the pause menu browses them")
+	telemetryURL := flag.String("telemetry", "", "endpoint for anonymous gameplay data (deaths, charge and finish times per level), sent only after opting in with T in the pause menu")
+	nameFlag := flag.String("name", "", "your name on the leaderboards (default: the profile's, then Player)")
+	reducedMotion := flag.Bool("reduced-motion", false, "no camera shake or slow motion, at most 3 hit flashes a second, fewer particles (default: the profile's)")
+	narrate := flag.Bool("narrate", false, "describe your surroundings as text on stdout, for screen readers")
+	streamer := flag.Bool("streamer", false, "hide room codes and IP addresses on screen, for streaming (default: the profile's)")
+	profileName := flag.String("profile", "default", "save profile, remembering which tutorial hints were shown")
+	aimFlag := flag.String("aim-assist", "off", "aim fists at nearby enemies: off, low or high (never in time trial)")
+	colorsFlag := flag.String("colors", "", "color vision mode: normal, deuteranopia, protanopia or tritanopia (default: the profile's)")
+	crashDir := flag.String("crash-dir", crash.DefaultDir(), "write a crash report here if the game crashes")
+	langFlag := flag.String("lang", "", "UI language: "+strings.Join(i18n.Languages(), ", ")+" (default: the profile's, then $LANG)")
+	flag.CommandLine.Parse(flagArgs())
+
+	// Everything printed from here on is kept for a crash report. A browser
+	// has no pipes; its output goes to the JavaScript console.
+	reporter := &crash.Reporter{App: "rayman-gui", Version: Version, Renderer: "gio", Dir: *crashDir}
+	if !inBrowser {
+		if err := reporter.CaptureOutput(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: crash reports won't include output: %v\n", err)
+		}
+	}
+
+	aim, err := game.ParseAimAssist(*aimFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		reporter.Exit(2)
+	}
+	if *training && (*timeTrialMode || *tutorial) {
+		fmt.Fprintln(os.Stderr, "Error: -training can't be combined with -timetrial or -tutorial")
+		reporter.Exit(2)
+	}
+	if *dailyMode && (*training || *tutorial || *physicsPath != "") {
+		fmt.Fprintln(os.Stderr, "Error: -daily can't be combined with -training, -tutorial or -physics")
+		reporter.Exit(2)
+	}
+	if *joinInvite != "" && *browse == "" {
+		fmt.Fprintln(os.Stderr, "Error: -join needs -browse to look the room up")
+		reporter.Exit(2)
+	}
+	if *campaignPath != "" && (*training || *tutorial || *dailyMode || *timeTrialMode) {
+		fmt.Fprintln(os.Stderr, "Error: -campaign can't be combined with -training, -tutorial, -daily or -timetrial")
+		reporter.Exit(2)
+	}
+	profile, err := loadProfile(defaultProfileDir(), *profileName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		reporter.Exit(1)
+	}
+	lang := cmp.Or(*langFlag, profile.Lang, i18n.FromLocale(os.Getenv("LANG")), i18n.DefaultLang)
+	tr, err := i18n.Load(lang)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		reporter.Exit(2)
+	}
+	colors, err := render.ParseColorMode(cmp.Or(*colorsFlag, profile.Colors, render.ColorNormal.String()))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		reporter.Exit(2)
+	}
+	name := cmp.Or(*nameFlag, profile.Name, "Player")
+	motion := render.FullMotion()
+	switch {
+	case *reducedMotion:
+		motion = render.ReducedMotion()
+	case profile.Motion != nil:
+		motion = *profile.Motion
+	}
+
+	if *pprofAddr != "" {
+		go func() {
+			if err := http.ListenAndServe(*pprofAddr, nil); err != nil {
+				fmt.Fprintf(os.Stderr, "pprof: %v\n", err)
+			}
+		}()
+	}
+
+	var tele *gameplayTelemetry
+	if *telemetryURL != "" {
+		tele = newTelemetry(*telemetryURL, profile.Telemetry)
+	}
+
+	opts := options{
+		lookupURL:    *browse,
+		region:       *region,
+		invite:       *joinInvite,
+		tls:          *useTLS,
+		physicsPath:  *physicsPath,
+		scoresURL:    *scoresURL,
+		campaignPath: *campaignPath,
+		name:         name,
+		aim:          aim,
+		colors:       colors,
+		motion:       motion,
+		training:     *training,
+		tutorial:     *tutorial,
+		daily:        *dailyMode,
+		narrate:      *narrate,
+		streamer:     *streamer || profile.Streamer,
+		dev:          *dev,
+		uncapped:     *uncapped,
+		tele:         tele,
+	}
+	if *timeTrialMode {
+		opts.replays = *replayDir
+	}
+
 	go func() {
-		if err := run(); err != nil {
+		defer reporter.Recover()
+		if err := run(opts, profile, tr, reporter); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			reporter.Exit(1)
 		}
-		os.Exit(0)
+		reporter.Exit(0)
 	}()
 	app.Main()
 }
 
-func run() error {
+// options are the game's settings, read once from the command line and
+// the profile in main
+type options struct {
+	replays      string // Time trial best runs directory; empty outside time trial
+	lookupURL    string // Lookup service whose server browser opens first
+	region       string // Only browse games with this region tag
+	invite       string // Room to join on the lookup service once the browser opens
+	tls          bool   // Connect to game servers over TLS
+	physicsPath  string // Movement tuning file, reloaded on change in dev mode and training
+	scoresURL    string // Lookup service for leaderboards and daily challenge times
+	campaignPath string // Campaign file whose levels are picked on its world map
+	name         string // Player name on the leaderboards
+
+	aim    game.AimAssist   // Applies outside time trial and the daily challenge
+	colors render.ColorMode // Color vision mode; the pause menu changes it
+	motion render.Motion    // Limits on shake, flashes and particles
+
+	training bool // Practice room with target dummies
+	tutorial bool // Tutorial level, with hints shown once per profile
+	daily    bool // Today's daily challenge
+	narrate  bool // Describe the player's surroundings on stdout
+	streamer bool // Keep room codes and IP addresses off the screen
+	dev      bool // Developer console on the tilde key, outside compared runs
+	uncapped bool // Redraw as fast as possible instead of once per tick
+
+	tele *gameplayTelemetry // Anonymous telemetry, sent once opted in; may be nil
+}
+
+// run plays the level picked by opts until the window closes. UI text is in
+// tr's language, stars, hints and settings changed in the pause menu are
+// saved to the profile, and inputs and the world checksum go to reporter
+// for crash reports.
+func run(opts options, save *saveProfile, tr *i18n.Catalog, reporter *crash.Reporter) error {
 	window := new(app.Window)
 	window.Option(
 		app.Title("Rayman Slides"),
@@ -47,25 +228,373 @@ func run() error {
 
 	inputSystem := input.NewGioInput()
 	renderer := render.NewGioRenderer()
+	renderer.SetColorMode(opts.colors)
 
 	// Load sprite atlas
 	if err := renderer.LoadSprites(assetsFS); err != nil {
 		fmt.Printf("Warning: Could not load sprites: %v\n", err)
 	}
 
-	world := game.NewWorld()
-	tileMap := game.DemoLevelForViewport(80, 45)
-	world.SetTileMap(tileMap)
-	world.SpawnPlayer(1, "Player", 5, 10)
-	world.SpawnEnemy("slime", 15, 10)
-	world.SpawnEnemy("slime", 28, 14)
+	// Single-player runs the same client/server split as multiplayer: an
+	// embedded server owns the authoritative world and the client predicts
+	// ahead of it, so the local game exercises the networked code path
+	level := game.NewDemoLevel(80, 45)
+	var practice *trainingHUD
+	switch {
+	case opts.training:
+		level = game.NewTrainingLevel()
+		practice = &trainingHUD{}
+		opts.dev = true // Tune physics against the dummies
+	case opts.tutorial:
+		level = game.NewTutorialLevel()
+	case opts.daily:
+		level = game.NewDailyLevel(time.Now())
+		opts.aim = game.AimAssistOff // Times are compared on the leaderboard
+	}
+	var campaign *campaignPlay // Campaign being played, nil outside one
+	mapOpen := false           // Its world map is showing
+	if opts.campaignPath != "" {
+		var err error
+		if campaign, err = newCampaignPlay(opts.campaignPath, save, tr); err != nil {
+			return err
+		}
+		if level, err = campaign.level(); err != nil {
+			return err
+		}
+		mapOpen = true
+	}
+	cl, err := client.NewEmbedded(1, opts.name, level)
+	if err != nil {
+		return err
+	}
+	cl.Scripts().OnError = func(err error) { fmt.Fprintf(os.Stderr, "Warning: %v\n", err) }
+	cl.Scripts().Print = func(script, msg string) { fmt.Printf("[%s] %s\n", script, msg) }
+	world := cl.World()
+	authoritative := cl.Server().World()
 
-	tiles := game.RenderTileMap(tileMap)
-	renderer.SetTileMap(tiles)
+	// Other physics and the developer console's cheats would skew the
+	// telemetry, so those runs send none
+	defer opts.tele.close()
+	telemetryLevel := func(l *game.Level) *game.Level {
+		if opts.dev || opts.physicsPath != "" {
+			return nil
+		}
+		return l
+	}
+	opts.tele.setLevel(telemetryLevel(level))
+	timeControl := cl.TimeControl()
+	reporter.Checksum = func() (uint64, uint32) {
+		state := authoritative.Snapshot()
+		return state.Tick, state.Checksum
+	}
+	renderer.SetLocalPlayer(1)
+	cl.SetChatCallback(func(msg protocol.Chat) {
+		line := msg.Text
+		if p, ok := rosterPlayer(cl, msg.PlayerID); ok {
+			line = p.Name + ": " + line
+		}
+		if opts.streamer {
+			line = lobby.Redact(line)
+		}
+		fmt.Println(line)
+	})
+
+	// Both worlds must run the same profile, or prediction diverges
+	setPhysics := func(p game.PhysicsProfile) {
+		cl.Server().SetPhysics(p)
+		world.Physics = p
+	}
+	var physics *physicsWatcher // Reloads the physics file in dev mode
+	if opts.physicsPath != "" {
+		pw, profile, err := newPhysicsWatcher(opts.physicsPath)
+		if err != nil {
+			return err
+		}
+		setPhysics(profile)
+		if opts.dev {
+			physics = pw
+		}
+	}
 
-	// For single player, we don't need the full client/server setup
-	// Just track key state and apply directly to world
-	keyState := input.NewKeyState()
+	var trial *timeTrial
+	if opts.replays != "" {
+		var err error
+		if trial, err = newTimeTrial(opts.replays, level, opts.name); err != nil {
+			return err
+		}
+		renderer.SetGhost(trial.ghost)
+		opts.aim = game.AimAssistOff // Runs are compared with each other
+	}
+	// Like physics, both worlds must agree or predicted fists miss
+	cl.Server().SetAimAssist(1, opts.aim)
+	world.SetAimAssist(1, opts.aim)
+	renderer.SetTileMap(game.RenderTileMap(world.TileMap))
+
+	cameraCtl := render.NewCameraController(render.DefaultCameraConfig())
+	cameraCtl.SetBounds(float64(world.TileMap.Width), float64(world.TileMap.Height))
+	cameraCtl.SetZones(level.CameraZones)
+	var camera render.Camera
+	particles := render.NewParticles()
+	renderer.SetParticles(particles)
+	feedback := render.NewHitFeedback()
+	renderer.SetFeedback(feedback)
+	warp := render.NewTimeWarp()
+	renderer.SetTimeWarp(warp)
+	var warpBuf []game.Renderable // Reused for the time warp each tick
+	opts.motion.Apply(cameraCtl, feedback, particles, warp)
+	renderer.SetAmbience(level.Ambience)
+	weather := render.NewWeatherParticles(level.Ambience)
+	weather.Density = opts.motion.Particles
+	renderer.SetWeather(weather)
+	chargeMeter := render.NewChargeMeter()
+	renderer.SetChargeMeter(chargeMeter)
+	comboMeter := render.NewComboMeter()
+	comboMeter.Lang = tr
+	renderer.SetComboMeter(comboMeter)
+	combatLog := render.NewCombatLog(200)
+	hintBox := func(level *game.Level) *render.HintBox {
+		levelHints := slices.Clone(level.Hints)
+		for i := range levelHints {
+			levelHints[i].Text = tr.Or("hint."+levelHints[i].ID, levelHints[i].Text)
+		}
+		hints := render.NewHintBox(levelHints, save.SeenHints)
+		hints.OnSeen = func(id string) {
+			if err := save.seeHint(id); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not save profile: %v\n", err)
+			}
+		}
+		return hints
+	}
+	hints := hintBox(level)
+	renderer.SetHints(hints)
+	var narrator *render.Narrator // Screen reader descriptions, when enabled
+	if opts.narrate {
+		narrator = render.NewNarrator(tr)
+	}
+	drawnTiles := world.TilesVersion()    // TilesVersion of the drawn tile map
+	teleports := world.PlayerTeleports(1) // Cut the camera when it changes
+
+	showDebug := false
+	allocTracker := cl.Server().Allocs()  // Measures while the debug overlay is shown
+	var allocCounters []allocs.Counter    // Reused for the debug overlay
+	var netGraph *render.NetGraph         // Shown when non-nil
+	var lastNet server.NetStats           // Client traffic at the last net graph sample
+	var hitboxes *render.CollisionOverlay // Shown when non-nil
+	var debugBoxes []game.DebugBox        // Reused for hitboxes each frame
+	showHitboxes := func(on bool) {
+		hitboxes = nil
+		if on {
+			hitboxes = &render.CollisionOverlay{}
+		}
+		renderer.SetCollisionOverlay(hitboxes)
+	}
+	var timings []game.SystemTiming
+	droppedTicks := 0        // Ticks skipped by the catch-up clamp
+	var lastBehind time.Time // When ticks were last dropped
+
+	var scores *challenge // Daily challenge leaderboard, with a scores URL
+	if opts.daily && opts.scoresURL != "" {
+		var err error
+		if scores, err = newChallenge(opts.scoresURL, opts.name, level, time.Now(), tr, window.Invalidate); err != nil {
+			return err
+		}
+	}
+
+	// Single-player ends when the player reaches the exit or dies; the
+	// results screen, or the daily challenge's leaderboard, stays up until
+	// a restart. Reaching the exit shows the run's stats and rank instead
+	// of the scoreboard.
+	var results *render.Scoreboard
+	var finished *render.LevelResults
+	authoritative.Subscribe(func(e game.Event) {
+		combatLog.Add(e)
+
+		// Effects for every entity: hit feedback, and any player's ground
+		// pound shakes the screen and kicks up dust
+		switch e.Type {
+		case game.EventDamage:
+			feedback.Hit(e.Entity, e.X, e.Y, e.Amount)
+			warp.Hitstop(render.HitstopTicks(e.Charge))
+		case game.EventDeath:
+			feedback.Death(e.Entity, e.X, e.Y, e.Kind)
+			if e.Player == 0 && e.Attacker != 0 && authoritative.Enemies() == 0 {
+				// The last enemy standing stands in for a boss
+				warp.SlowMotion(render.SlowMotionTicks)
+			}
+		case game.EventPound:
+			cameraCtl.Shake(0.3, 12)
+			particles.Burst(e.X, e.Y, 16)
+		case game.EventBreak:
+			particles.Burst(e.X+0.5, e.Y+0.5, 8)
+		case game.EventKey:
+			particles.Burst(e.X, e.Y, 8)
+		case game.EventTeleport:
+			particles.Burst(e.X, e.Y, 12)
+		}
+		if e.Player != 1 {
+			return
+		}
+		switch e.Type {
+		case game.EventFinish:
+			results = &render.Scoreboard{Title: tr.T("results.complete"), Lang: tr}
+			var stats protocol.PlayerStats
+			for _, ps := range authoritative.Stats() {
+				if ps.PlayerID == 1 {
+					stats = ps
+				}
+			}
+			rank := level.Rank.Rank(stats)
+			opts.tele.finish(stats.FinishTicks)
+			finished = &render.LevelResults{Title: results.Title, Stats: stats, Goals: level.Rank, Rank: rank, Lang: tr}
+			bestRank, err := save.finishLevel(level.Name, stats, rank)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not save profile: %v\n", err)
+			}
+			if bestRank {
+				finished.Notes = append(finished.Notes, tr.T("results.new_best_rank"))
+			}
+			if trial != nil {
+				best, err := trial.finish()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: could not save replay: %v\n", err)
+				}
+				if best {
+					finished.Title = tr.T("results.best")
+					if opts.scoresURL != "" {
+						go submitTimeTrial(opts.scoresURL, opts.name, level, trial.best, stats.Orbs, rank)
+					}
+				}
+			}
+			if scores != nil {
+				scores.finish(stats.Orbs, rank)
+			}
+			if campaign != nil {
+				finished.Notes = append(finished.Notes, tr.T("results.stars", render.StarMarks(campaign.finish(stats))))
+			}
+		case game.EventDeath:
+			results = &render.Scoreboard{Title: tr.T("results.game_over"), Lang: tr}
+			opts.tele.death(e.X)
+		}
+	})
+
+	var games *browser      // Server browser while open
+	var board *boards       // Leaderboard browser while open
+	var hosting *hostMenu   // Host menu while open
+	var console *devConsole // Developer console, in dev mode
+	consoleOpen := false    // It is showing
+	if opts.dev && trial == nil && !opts.daily {
+		console = newDevConsole(cl, tr)
+	}
+	var freeCam *render.FreeCam // Debug camera detached from the player
+	if console != nil {
+		console.Register("freecam", client.ConsoleCommand{
+			Usage: "[on|off|clamp]",
+			Help:  "fly the camera with the movement keys; clamp bounds it like the player's",
+			Cheat: true,
+			Run: func(args []string) (string, error) {
+				arg := "on"
+				if freeCam != nil {
+					arg = "off"
+				}
+				if len(args) > 0 {
+					arg = strings.ToLower(args[0])
+				}
+				switch arg {
+				case "on", "clamp":
+					if freeCam == nil {
+						freeCam = &render.FreeCam{X: camera.X, Y: camera.Y}
+					}
+					if arg == "clamp" {
+						freeCam.Clamp = !freeCam.Clamp
+					}
+				case "off":
+					if freeCam != nil {
+						freeCam = nil
+						cameraCtl.Reset() // Back on the player at once
+					}
+				default:
+					return "", fmt.Errorf("freecam: want on, off or clamp, not %q", args[0])
+				}
+				cl.SetDetached(freeCam != nil)
+				if freeCam == nil {
+					return "free camera off", nil
+				}
+				return fmt.Sprintf("free camera at %.1f, %.1f, clamp %t", freeCam.X, freeCam.Y, freeCam.Clamp), nil
+			},
+		})
+		console.Register("hitboxes", client.ConsoleCommand{
+			Usage: "[on|off]",
+			Help:  "toggle the collision overlay, as F9 does",
+			Run: func(args []string) (string, error) {
+				on := hitboxes == nil
+				if len(args) > 0 {
+					switch strings.ToLower(args[0]) {
+					case "on":
+						on = true
+					case "off":
+						on = false
+					default:
+						return "", fmt.Errorf("hitboxes: want on or off, not %q", args[0])
+					}
+				}
+				showHitboxes(on)
+				if on {
+					return "collision overlay on", nil
+				}
+				return "collision overlay off", nil
+			},
+		})
+	}
+
+	// resetView clears what the screen carries over from before a restart
+	resetView := func() {
+		cameraCtl.Reset()
+		particles.Clear()
+		feedback.Clear()
+		warp.Clear()
+		comboMeter.Update(0, 0)
+		hints.Clear()
+		if narrator != nil {
+			narrator.Clear()
+		}
+		results, finished = nil, nil
+		if trial != nil {
+			trial.restart()
+			renderer.SetGhost(trial.ghost)
+		}
+		if scores != nil {
+			scores.restart()
+		}
+	}
+	// playLevel switches to a level picked on the campaign's world map,
+	// with everything drawn from the old level replaced
+	playLevel := func(l *game.Level) {
+		level = l
+		cl.LoadLevel(level)
+		opts.tele.setLevel(telemetryLevel(level))
+		renderer.SetTileMap(game.RenderTileMap(world.TileMap))
+		drawnTiles = world.TilesVersion()
+		teleports = world.PlayerTeleports(1)
+		cameraCtl.SetBounds(float64(world.TileMap.Width), float64(world.TileMap.Height))
+		cameraCtl.SetZones(level.CameraZones)
+		renderer.SetAmbience(level.Ambience)
+		weather = render.NewWeatherParticles(level.Ambience)
+		weather.Density = opts.motion.Particles
+		renderer.SetWeather(weather)
+		hints = hintBox(level)
+		renderer.SetHints(hints)
+		resetView()
+	}
+	if opts.lookupURL != "" {
+		games = newBrowser(opts.lookupURL, opts.region, opts.tls, opts.name, tr, window.Invalidate)
+		if opts.invite != "" {
+			games.joinInvite(opts.invite)
+		}
+	}
+	var update *updateCheck // Against whichever lookup service is configured
+	if service := cmp.Or(opts.scoresURL, opts.lookupURL); service != "" {
+		update = checkForUpdate(service, Version, tr, window.Invalidate)
+	}
 
 	var ops op.Ops
 	var tag keyboardTag
@@ -77,6 +606,13 @@ func run() error {
 	lastUpdate := time.Now()
 	tickDuration := time.Second / 60
 
+	// Nothing on screen changes between ticks, so frames are only needed
+	// while ticks run. In the menu, on the results screen or with time
+	// paused, only input (which wakes the window itself) redraws.
+	simulating := func() bool {
+		return games == nil && !mapOpen && !cl.Paused() && results == nil && !timeControl.Idle()
+	}
+
 	for {
 		e := window.Event()
 
@@ -117,96 +653,481 @@ func run() error {
 				if !ok {
 					break
 				}
-				if fe, ok := ev.(key.FocusEvent); ok {
-					hasFocus = fe.Focus
+				switch ev := ev.(type) {
+				case key.FocusEvent:
+					hasFocus = ev.Focus
+				case key.EditEvent:
+					if consoleOpen {
+						console.Type(ev.Text)
+					}
 				}
 			}
 
-			// Process key events
+			// Process key events. Tab is a focus-move system key and is
+			// only delivered to a filter that names it.
 			for {
-				ev, ok := gtx.Event(key.Filter{Focus: &tag, Name: ""})
+				ev, ok := gtx.Event(key.Filter{Focus: &tag, Name: ""}, key.Filter{Focus: &tag, Name: key.NameTab})
 				if !ok {
 					break
 				}
-				if ke, ok := ev.(key.Event); ok {
+				ke, ok := ev.(key.Event)
+				switch {
+				case !ok:
+				case consoleOpen:
+					if ke.State == key.Release {
+						inputSystem.HandleKeyEvent(ke) // Let go of keys held when it opened
+					} else if console.HandleKey(ke) {
+						consoleOpen = false
+					}
+				case games != nil:
+					if games.HandleKey(ke) {
+						games = nil
+						renderer.SetBrowser(nil)
+					}
+				case board != nil:
+					if board.HandleKey(ke) {
+						board = nil
+					}
+				case hosting != nil:
+					closed, restarted := hosting.HandleKey(ke)
+					if closed {
+						hosting = nil
+					}
+					if restarted {
+						resetView()
+						cl.TogglePause()
+					}
+				case mapOpen:
+					picked, closed := campaign.HandleKey(ke)
+					if picked != nil {
+						playLevel(picked)
+						if cl.Paused() {
+							cl.TogglePause()
+						}
+					}
+					if closed {
+						mapOpen = false
+					}
+				default:
 					inputSystem.HandleKeyEvent(ke)
 				}
 			}
 
-			// Fixed timestep game updates
+			// Fixed timestep game updates. Time spent idle is not owed:
+			// a frame woken by input runs one tick's worth to handle it.
 			now := time.Now()
+			if !simulating() && now.Sub(lastUpdate) > tickDuration {
+				lastUpdate = now.Add(-tickDuration)
+			}
+			ticksRun := 0
 			for now.Sub(lastUpdate) >= tickDuration {
+				if ticksRun == maxCatchUpTicks {
+					droppedTicks += int(now.Sub(lastUpdate) / tickDuration)
+					lastBehind = now
+					lastUpdate = now
+					break
+				}
+				ticksRun++
+
 				// Process input events
-				events := inputSystem.Poll()
+				events := cl.ReadInput(inputSystem)
 				for _, ev := range events {
-					switch ev.Type {
-					case input.KeyDown:
-						keyState.SetPressed(ev.Key, true)
-					case input.KeyUp:
-						keyState.SetPressed(ev.Key, false)
+					if ev.Type != input.KeyDown {
+						continue
+					}
+					handleDebugKey(timeControl, ev.Key)
+					if ev.Key == input.KeyDebugOverlay {
+						showDebug = !showDebug
+						allocTracker.Reset()
+						allocTracker.SetEnabled(showDebug)
+					}
+					if showDebug && ev.Key == input.KeyLogOlder {
+						combatLog.Scroll(combatLogLines)
+					}
+					if showDebug && ev.Key == input.KeyLogNewer {
+						combatLog.Scroll(-combatLogLines)
+					}
+					if ev.Key == input.KeyConsole && console != nil {
+						consoleOpen = true
+					}
+					if ev.Key == input.KeyNetGraph {
+						if netGraph == nil {
+							netGraph = render.NewNetGraph(60)
+							lastNet = cl.NetStats()
+						} else {
+							netGraph = nil
+						}
+						renderer.SetNetGraph(netGraph)
+					}
+					if ev.Key == input.KeyHitboxes {
+						showHitboxes(hitboxes == nil)
+					}
+					if ev.Key == input.KeyPause && results == nil {
+						cl.TogglePause()
+					}
+					if ev.Key == input.KeyColorMode && cl.Paused() {
+						opts.colors = opts.colors.Next()
+						renderer.SetColorMode(opts.colors)
+						save.Colors = opts.colors.String()
+						if err := save.save(); err != nil {
+							fmt.Fprintf(os.Stderr, "Warning: could not save profile: %v\n", err)
+						}
+					}
+					if ev.Key == input.KeyTelemetry && cl.Paused() && opts.tele != nil {
+						save.Telemetry = opts.tele.toggle()
+						if err := save.save(); err != nil {
+							fmt.Fprintf(os.Stderr, "Warning: could not save profile: %v\n", err)
+						}
+					}
+					if ev.Key == input.KeyLeaderboards && cl.Paused() && opts.scoresURL != "" {
+						var err error
+						if board, err = newBoards(opts.scoresURL, opts.name, level, tr, window.Invalidate); err != nil {
+							fmt.Fprintf(os.Stderr, "Warning: could not open leaderboards: %v\n", err)
+						}
+					}
+					if (ev.Key == input.KeyVoteYes || ev.Key == input.KeyVoteNo) && cl.Vote().Result == protocol.VoteOpen && cl.Vote().Kind != protocol.VoteNone {
+						cl.CastVote(ev.Key == input.KeyVoteYes)
+					}
+					if ev.Key == input.KeyHostMenu && cl.Paused() && cl.IsHost() {
+						hosting = newHostMenu(cl, tr)
+					}
+					if ev.Key == input.KeyRestart && (results != nil || cl.Paused()) {
+						cl.Restart()
+						if results == nil {
+							cl.TogglePause()
+						}
+						resetView()
+					}
+					if ev.Key == input.KeyWorldMap && campaign != nil && (results != nil || cl.Paused()) {
+						campaign.open()
+						mapOpen = true
 					}
 				}
 
 				// Check for quit
-				if keyState.IsPressed(input.KeyQuit) {
+				if cl.ShouldQuit() {
 					return nil
 				}
+				if freeCam != nil {
+					freeCam.Fly(freeCamSteer(cl))
+				}
+
+				if physics != nil {
+					profile, changed, err := physics.poll(time.Now())
+					switch {
+					case err != nil:
+						fmt.Fprintf(os.Stderr, "Warning: keeping old physics: %v\n", err)
+					case changed:
+						setPhysics(profile)
+						fmt.Printf("Reloaded physics: %+v\n", profile)
+					}
+				}
 
-				// Apply intents to world and update
-				world.SetPlayerIntent(1, keyState.ToIntents())
-				world.Update()
+				// Run as many ticks as the debug time controls allow. The
+				// game stops while paused and at match end; rendering
+				// carries on.
+				for range timeControl.Advance() {
+					if games != nil || mapOpen || cl.Paused() || results != nil {
+						break
+					}
+					if trial != nil {
+						trial.record(cl.Intents())
+					}
+					if scores != nil {
+						scores.record(cl.Intents())
+					}
+					reporter.RecordInput(world.Tick+1, 1, cl.Intents())
+					cl.Step()
+					effects := 1 // Ticks for effects; fewer while hitstop or slow motion holds them
+					if warp.Active() {
+						warpBuf = world.AppendRenderables(warpBuf[:0])
+						effects = warp.Step(warpBuf)
+					}
+					for range effects {
+						particles.Update()
+						feedback.Update()
+					}
+					weather.Update(renderer.View())
+					chargeMeter.Update(world.PlayerCharge(1))
+					comboMeter.Update(world.Combo(1))
+					opts.tele.updateCharge(world.PlayerCharge(1))
+					hints.Update(world.PlayerPosition(1))
+					if narrator != nil {
+						if text := narrator.Update(world, 1); text != "" {
+							fmt.Println(text)
+						}
+					}
+					if practice != nil {
+						practice.update(world.PlayerCharge(1))
+					}
+					if netGraph != nil && world.Tick%60 == 0 {
+						netGraph.Push(netSample(cl.NetStats().Sub(lastNet)))
+						lastNet = cl.NetStats()
+					}
+				}
 				lastUpdate = lastUpdate.Add(tickDuration)
 			}
 
-			// Render with clamped camera
-			playerX, playerY, _ := world.GetPlayerPosition()
-
-			// Calculate viewport size in world units
+			// Follow the player, settling on ledges as on the ground; a dead
+			// player leaves the camera where it was
 			tileSize := float64(render.GioTilePixels)
 			viewportW := float64(gtx.Constraints.Max.X) / tileSize
 			viewportH := float64(gtx.Constraints.Max.Y) / tileSize
-
-			// Clamp camera to keep map edges at screen edges
-			mapW := float64(tileMap.Width)
-			mapH := float64(tileMap.Height)
-
-			camX := playerX
-			camY := playerY
-
-			// Clamp horizontal
-			minCamX := viewportW / 2
-			maxCamX := mapW - viewportW/2
-			if maxCamX < minCamX {
-				camX = mapW / 2 // Map smaller than viewport, center it
-			} else if camX < minCamX {
-				camX = minCamX
-			} else if camX > maxCamX {
-				camX = maxCamX
+			if n := world.PlayerTeleports(1); n != teleports {
+				// Teleported, or a rollback undid a teleport: jump
+				// rather than pan across the level
+				cameraCtl.Cut(20)
+				teleports = n
 			}
-
-			// Clamp vertical
-			minCamY := viewportH / 2
-			maxCamY := mapH - viewportH/2
-			if maxCamY < minCamY {
-				camY = mapH / 2 // Map smaller than viewport, center it
-			} else if camY < minCamY {
-				camY = minCamY
-			} else if camY > maxCamY {
-				camY = maxCamY
+			if freeCam != nil {
+				camera = freeCam.View(cameraCtl, viewportW, viewportH)
+			} else if x, y, ok := world.PlayerPosition(1); ok {
+				x, y = warp.PlayerPosition(1, x, y)
+				camera = cameraCtl.Update(x, y, world.PlayerOnGround(1) || world.PlayerHanging(1), viewportW, viewportH)
+			}
+			renderer.SetCamera(camera)
+			renderer.SetFade(cameraCtl.Fade())
+			if hitboxes != nil {
+				// Bounds where the camera is clamped from: the free
+				// camera's position, or the player's while alive
+				x, y, ok := world.PlayerPosition(1)
+				if freeCam != nil {
+					x, y = freeCam.X, freeCam.Y
+				} else if !ok {
+					x, y = camera.X, camera.Y
+				}
+				debugBoxes = world.AppendDebugBoxes(debugBoxes[:0])
+				hitboxes.Tiles, hitboxes.Boxes, hitboxes.Clamp = world.TileMap, debugBoxes, cameraCtl.Bounds(x, y)
 			}
-
-			renderer.SetCamera(render.Camera{X: camX, Y: camY})
 			renderer.SetWorld(world)
+			if v := world.TilesVersion(); v != drawnTiles {
+				// Tiles were broken, a gate moved, or a restart or rollback
+				// restored them
+				renderer.SetTileMap(game.RenderTileMap(world.TileMap))
+				drawnTiles = v
+			}
 
-			hint := "Click window to focus | "
+			hint := tr.T("hud.focus") + " | "
 			if hasFocus {
 				hint = ""
 			}
-			renderer.SetHUD(fmt.Sprintf("%sTick: %d | WASD: Move | J: Attack | Q/Esc: Quit", hint, world.Tick))
+			speed := ""
+			if timeControl.Paused() || timeControl.Scale() != 1 {
+				speed = fmt.Sprintf(" [%s]", timeControl.String())
+			}
+			if trial != nil {
+				hint += trial.hud(tr) + " | "
+			}
+			if practice != nil {
+				hint += practice.hud() + " | "
+			}
+			if freeCam != nil {
+				hint += tr.T("hud.freecam", freeCam.X, freeCam.Y) + " | "
+			}
+			hud := hint + tr.T("hud.tick", world.Tick) + speed + " | " + tr.T("hud.controls")
+			if opts.streamer {
+				hud = lobby.Redact(hud)
+			}
+			renderer.SetHUD(hud)
+			if showDebug {
+				timings = authoritative.Systems().AppendTimings(timings[:0])
+				lines := debugLines(timings, authoritative.Systems().Total(), tickDuration)
+				lines = append(lines, fmt.Sprintf("prediction rollbacks %d", cl.Rollbacks()))
+				allocCounters = allocTracker.AppendCounters(allocCounters[:0])
+				lines = append(lines, allocLines(allocCounters)...)
+				if !lastBehind.IsZero() && now.Sub(lastBehind) < behindWarning {
+					lines = append(lines, fmt.Sprintf("SIMULATION BEHIND: %d ticks dropped", droppedTicks))
+				}
+				lines = append(lines, "", combatLog.Header())
+				lines = append(lines, combatLog.View(combatLogLines)...)
+				if opts.streamer {
+					for i := range lines {
+						lines[i] = lobby.Redact(lines[i])
+					}
+				}
+				renderer.SetDebugLines(lines)
+			} else {
+				renderer.SetDebugLines(nil)
+			}
+			var lb *render.Leaderboard
+			switch {
+			case board != nil:
+				lb = board.View()
+			case scores != nil:
+				lb = scores.View()
+			}
+			renderer.SetLeaderboard(lb)
+			var vote *render.VotePanel
+			if status := cl.Vote(); status.Kind != protocol.VoteNone {
+				vote = &render.VotePanel{Status: status, Tick: world.Tick, Lang: tr}
+				if p, ok := rosterPlayer(cl, status.Caller); ok {
+					vote.Caller = p.Name
+				}
+				if opts.streamer {
+					vote.Status.Subject = lobby.Redact(vote.Status.Subject)
+				}
+			}
+			renderer.SetVote(vote)
+			var dialogue *render.DialogueBox
+			if state, ok := world.Dialogue(1); ok {
+				dialogue = &render.DialogueBox{State: state, Lang: tr}
+			}
+			renderer.SetDialogue(dialogue)
+			prompt := ""
+			if obj, ok := world.InteractTarget(1); ok {
+				prompt = render.InteractPrompt(tr, obj)
+			}
+			renderer.SetPrompt(prompt)
+			renderer.SetKeys(world.Keys())
+			if consoleOpen {
+				renderer.SetConsole(console.View())
+			} else {
+				renderer.SetConsole(nil)
+			}
+			footer := tr.T("results.footer")
+			if campaign != nil {
+				footer = tr.T("results.campaign_footer")
+			}
+			renderer.SetResults(nil)
+			switch {
+			case lb != nil:
+				renderer.SetScoreboard(nil)
+			case finished != nil:
+				finished.Footer = footer
+				renderer.SetResults(finished)
+				renderer.SetScoreboard(nil)
+			case results != nil:
+				results.Stats = authoritative.Stats()
+				results.Footer = footer
+				renderer.SetScoreboard(results)
+			case cl.IsPressed(input.KeyScoreboard):
+				renderer.SetScoreboard(&render.Scoreboard{Title: tr.T("scoreboard.title"), Stats: authoritative.Stats(), Lang: tr})
+			default:
+				renderer.SetScoreboard(nil)
+			}
+			if hosting != nil {
+				menu := hosting.Menu()
+				if opts.streamer { // Player names
+					for i := range menu.Items {
+						menu.Items[i].Label = lobby.Redact(menu.Items[i].Label)
+					}
+					for i := range menu.Notes {
+						menu.Notes[i] = lobby.Redact(menu.Notes[i])
+					}
+				}
+				renderer.SetMenu(menu)
+			} else if cl.Paused() && board == nil && !mapOpen {
+				menu := render.PauseMenu(tr, false, opts.colors)
+				if opts.scoresURL != "" {
+					menu.Items = slices.Insert(menu.Items, len(menu.Items)-1, render.MenuItem{Key: "B", Label: tr.T("menu.leaderboards")})
+				}
+				if cl.IsHost() {
+					menu.Items = slices.Insert(menu.Items, len(menu.Items)-1, render.MenuItem{Key: "H", Label: tr.T("menu.host")})
+				}
+				if campaign != nil {
+					menu.Items = slices.Insert(menu.Items, len(menu.Items)-1, render.MenuItem{Key: "M", Label: tr.T("menu.worldmap")})
+				}
+				menu.Notes = update.Notes()
+				if opts.tele != nil {
+					label := tr.T("menu.telemetry_off")
+					if opts.tele.Enabled() {
+						label = tr.T("menu.telemetry_on")
+					}
+					menu.Items = slices.Insert(menu.Items, len(menu.Items)-1, render.MenuItem{Key: "T", Label: label})
+					menu.Notes = append(menu.Notes, tr.T("menu.telemetry_note"))
+				}
+				renderer.SetMenu(menu)
+			} else {
+				renderer.SetMenu(nil)
+			}
+			if games != nil {
+				view := games.View()
+				if opts.streamer {
+					view.Status = lobby.Redact(view.Status)
+					for i := range view.Rooms {
+						view.Rooms[i].Name = lobby.Redact(view.Rooms[i].Name) // Hosts put invites in names
+					}
+				}
+				renderer.SetBrowser(view)
+			}
+			if mapOpen {
+				renderer.SetWorldMap(campaign.View())
+			} else {
+				renderer.SetWorldMap(nil)
+			}
+			span := allocTracker.Begin(allocs.Renderer)
 			renderer.Layout(gtx)
+			span.End()
 
+			switch {
+			case opts.uncapped:
+				window.Invalidate()
+			case simulating() || freeCam != nil || inputSystem.Pending():
+				gtx.Execute(op.InvalidateCmd{At: lastUpdate.Add(tickDuration)})
+			}
 			e.Frame(gtx.Ops)
-			window.Invalidate()
 		}
 	}
 }
+
+// handleDebugKey applies the single-player time controls:
+// F5 pause/resume, F6 step one tick, F7 slower, F8 faster.
+func handleDebugKey(tc *server.TimeControl, k input.GameKey) {
+	switch k {
+	case input.KeyDebugPause:
+		tc.TogglePause()
+	case input.KeyDebugStep:
+		tc.Step(1)
+	case input.KeyDebugSlower:
+		tc.Slower()
+	case input.KeyDebugFaster:
+		tc.Faster()
+	}
+}
+
+// debugLines formats per-system tick timings for the debug overlay
+func debugLines(timings []game.SystemTiming, total game.SystemTiming, budget time.Duration) []string {
+	lines := make([]string, 0, len(timings)+2)
+	for _, t := range timings {
+		lines = append(lines, fmt.Sprintf("%-10s avg %8s  max %8s", t.Name, t.Avg, t.Max))
+	}
+	lines = append(lines,
+		fmt.Sprintf("%-10s avg %8s  max %8s", "tick", total.Avg, total.Max),
+		fmt.Sprintf("budget %s (%.1f%% used)", budget, 100*float64(total.Avg)/float64(budget)),
+	)
+	return lines
+}
+
+// allocLines formats the heap allocations per subsystem for the debug
+// overlay: per span (a frame, or a tick's share of the subsystem's work),
+// the latest and the most in one
+func allocLines(counters []allocs.Counter) []string {
+	lines := make([]string, 0, len(counters))
+	for _, c := range counters {
+		n, bytes := c.PerSpan()
+		lines = append(lines, fmt.Sprintf("allocs %-10s avg %6.1f %8s  last %4d  max %4d",
+			c.Name, n, fmt.Sprintf("%.1f KB", bytes/1024), c.LastAllocs, c.MaxAllocs))
+	}
+	return lines
+}
+
+// netSample converts one second of client traffic for the net graph
+func netSample(n server.NetStats) render.NetSample {
+	return render.NetSample{
+		BytesIn:         int(n.BytesIn),
+		BytesOut:        int(n.BytesOut),
+		FullSnapshots:   int(n.FullSnapshots),
+		DeltaSnapshots:  int(n.DeltaSnapshots),
+		AvgSnapshotSize: n.AvgSnapshotSize(),
+	}
+}
+
+// rosterPlayer returns a connected player's roster entry
+func rosterPlayer(cl *client.Client, playerID int) (protocol.PlayerInfo, bool) {
+	for _, p := range cl.Server().Roster() {
+		if p.PlayerID == playerID {
+			return p, true
+		}
+	}
+	return protocol.PlayerInfo{}, false
+}
@@ -0,0 +1,16 @@
+//go:build !gio
+
+// Command rayman-gui is the graphical game client using Gio. Built without
+// the gio tag it only says how to build it, so `go build ./...` works
+// without Gio's C dependencies.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	fmt.Fprintln(os.Stderr, "rayman-gui was built without Gio; build it with: go build -tags gio ./cmd/rayman-gui")
+	os.Exit(2)
+}
@@ -0,0 +1,56 @@
+//go:build gio
+
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+)
+
+// physicsPoll is how often -dev checks the physics file for changes
+const physicsPoll = time.Second
+
+// physicsWatcher reloads a physics tunables file when it changes on disk,
+// so movement can be tuned while playing
+type physicsWatcher struct {
+	path     string
+	modified time.Time // Of the last load
+	checked  time.Time
+}
+
+// newPhysicsWatcher loads the profile at path and watches it from then on
+func newPhysicsWatcher(path string) (*physicsWatcher, game.PhysicsProfile, error) {
+	pw := &physicsWatcher{path: path}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, game.PhysicsProfile{}, err
+	}
+	profile, err := game.LoadPhysics(path)
+	if err != nil {
+		return nil, game.PhysicsProfile{}, err
+	}
+	pw.modified = info.ModTime()
+	return pw, profile, nil
+}
+
+// poll returns the reloaded profile if the file changed since the last
+// load. It checks at most once per physicsPoll; a file that fails to load
+// is reported once and the old profile kept.
+func (pw *physicsWatcher) poll(now time.Time) (game.PhysicsProfile, bool, error) {
+	if now.Sub(pw.checked) < physicsPoll {
+		return game.PhysicsProfile{}, false, nil
+	}
+	pw.checked = now
+	info, err := os.Stat(pw.path)
+	if err != nil || info.ModTime().Equal(pw.modified) {
+		return game.PhysicsProfile{}, false, nil
+	}
+	pw.modified = info.ModTime()
+	profile, err := game.LoadPhysics(pw.path)
+	if err != nil {
+		return game.PhysicsProfile{}, false, err
+	}
+	return profile, true, nil
+}
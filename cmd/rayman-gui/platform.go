@@ -0,0 +1,29 @@
+//go:build gio && !js
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// inBrowser is set in the js/wasm build
+const inBrowser = false
+
+// flagArgs are the command-line flags
+func flagArgs() []string {
+	return os.Args[1:]
+}
+
+// readSave reads a save file; a missing one fails with fs.ErrNotExist
+func readSave(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+// writeSave writes a save file, creating its directory if needed
+func writeSave(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,63 @@
+//go:build gio
+
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall/js"
+)
+
+// inBrowser is set in the js/wasm build
+const inBrowser = true
+
+// savePrefix namespaces save files in the browser's localStorage
+const savePrefix = "rayman-slides:"
+
+// flagArgs turns the page's query string into flags, so
+// index.html?daily&lang=nb runs as -daily -lang=nb
+func flagArgs() []string {
+	search := js.Global().Get("location").Get("search").String()
+	query, err := url.ParseQuery(strings.TrimPrefix(search, "?"))
+	if err != nil {
+		return nil
+	}
+	var args []string
+	for _, name := range slices.Sorted(maps.Keys(query)) {
+		for _, v := range query[name] {
+			if v == "" {
+				args = append(args, "-"+name)
+			} else {
+				args = append(args, "-"+name+"="+v)
+			}
+		}
+	}
+	return args
+}
+
+// readSave reads a save file from localStorage; a missing one fails with
+// fs.ErrNotExist
+func readSave(path string) ([]byte, error) {
+	v := js.Global().Get("localStorage").Call("getItem", savePrefix+filepath.ToSlash(path))
+	if v.IsNull() {
+		return nil, &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+	}
+	return []byte(v.String()), nil
+}
+
+// writeSave writes a save file to localStorage, which fails once the
+// origin's quota is used up
+func writeSave(path string, data []byte) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("save %s: %v", path, p)
+		}
+	}()
+	js.Global().Get("localStorage").Call("setItem", savePrefix+filepath.ToSlash(path), string(data))
+	return nil
+}
@@ -0,0 +1,120 @@
+//go:build gio
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+	"github.com/andersfylling/rayman-slides/internal/render"
+)
+
+// saveProfile is what the game remembers about one player between runs,
+// kept as a JSON file per profile name
+type saveProfile struct {
+	path string
+
+	SeenHints []string       `json:"seen_hints,omitempty"` // Tutorial hints already shown, by ID
+	Lang      string         `json:"lang,omitempty"`       // UI language, unless -lang overrides it
+	Colors    string         `json:"colors,omitempty"`     // Color vision mode (render.ColorMode), unless -colors overrides it
+	Motion    *render.Motion `json:"motion,omitempty"`     // Effect settings, unless -reduced-motion overrides them; full motion if nil
+	Name      string         `json:"name,omitempty"`       // Leaderboard name, unless -name overrides it
+	Streamer  bool           `json:"streamer,omitempty"`   // Hide join information on screen, as -streamer does
+	Telemetry bool           `json:"telemetry,omitempty"`  // Opted in to sending anonymous gameplay data
+
+	Campaigns map[string]game.CampaignProgress `json:"campaigns,omitempty"` // Stars earned, by campaign name
+	Levels    map[string]levelRecord           `json:"levels,omitempty"`    // Best results, by level name
+}
+
+// levelRecord is the best a profile has done on a level, each part from
+// whichever finish did best at it
+type levelRecord struct {
+	Grade  string `json:"grade"`  // Of the best ranked finish
+	Points int    `json:"points"` // Its score
+	Ticks  uint64 `json:"ticks"`  // Fastest finish
+	Orbs   int    `json:"orbs,omitempty"`
+	Cages  int    `json:"cages,omitempty"`
+}
+
+// defaultProfileDir is where save profiles are kept
+func defaultProfileDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "profiles"
+	}
+	return filepath.Join(dir, "rayman-slides", "profiles")
+}
+
+// loadProfile reads the named profile from dir; a profile that doesn't
+// exist yet starts empty
+func loadProfile(dir, name string) (*saveProfile, error) {
+	p := &saveProfile{path: filepath.Join(dir, name+".json")}
+	data, err := readSave(p.path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return p, nil
+	case err != nil:
+		return nil, err
+	}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("%s: %w", p.path, err)
+	}
+	return p, nil
+}
+
+// seeHint records a hint as shown and saves the profile
+func (p *saveProfile) seeHint(id string) error {
+	if slices.Contains(p.SeenHints, id) {
+		return nil
+	}
+	p.SeenHints = append(p.SeenHints, id)
+	return p.save()
+}
+
+// campaign returns the stars earned in the named campaign, which Record
+// adds to; save writes them
+func (p *saveProfile) campaign(name string) game.CampaignProgress {
+	if p.Campaigns[name] == nil {
+		if p.Campaigns == nil {
+			p.Campaigns = make(map[string]game.CampaignProgress)
+		}
+		p.Campaigns[name] = game.CampaignProgress{}
+	}
+	return p.Campaigns[name]
+}
+
+// finishLevel records a finish and saves the profile, reporting whether
+// the run's rank beat the best one so far on the level
+func (p *saveProfile) finishLevel(level string, ps protocol.PlayerStats, rank game.RunRank) (bestRank bool, err error) {
+	if p.Levels == nil {
+		p.Levels = make(map[string]levelRecord)
+	}
+	rec, seen := p.Levels[level]
+	bestRank = !seen || rank.Score > rec.Points
+	if bestRank {
+		rec.Grade, rec.Points = rank.Grade, rank.Score
+	}
+	if !seen || ps.FinishTicks < rec.Ticks {
+		rec.Ticks = ps.FinishTicks
+	}
+	rec.Orbs = max(rec.Orbs, ps.Orbs)
+	rec.Cages = max(rec.Cages, ps.Cages)
+	p.Levels[level] = rec
+	return bestRank, p.save()
+}
+
+// save writes the profile, creating its directory if needed
+func (p *saveProfile) save() error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeSave(p.path, data)
+}
@@ -0,0 +1,140 @@
+//go:build gio
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/telemetry"
+)
+
+// gameplayTelemetry feeds the local player's deaths, charges and finishes
+// to a telemetry.Sender, which only records them once the player opts in
+// from the pause menu. Events not sent by the time the game quits wait in
+// a queue file for the next run. A nil *gameplayTelemetry, without a
+// -telemetry endpoint, does nothing.
+type gameplayTelemetry struct {
+	sender *telemetry.Sender
+	path   string // Queue file
+	loaded bool   // The queue file had events, so it must be rewritten
+	level  string // Hash of the level played; empty records nothing
+	charge int    // Ticks of the charge in progress
+	stop   chan struct{}
+}
+
+// telemetryQueuePath is where unsent events wait between runs, next to the
+// profiles
+func telemetryQueuePath() string {
+	return filepath.Join(filepath.Dir(defaultProfileDir()), "telemetry.json")
+}
+
+// newTelemetry starts sending to url in the background, opted in if
+// enabled, with the events left over from the last run
+func newTelemetry(url string, enabled bool) *gameplayTelemetry {
+	t := &gameplayTelemetry{
+		sender: telemetry.NewSender(url, Version),
+		path:   telemetryQueuePath(),
+		stop:   make(chan struct{}),
+	}
+	t.sender.SetEnabled(enabled)
+	data, err := readSave(t.path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Warning: could not read the telemetry queue: %v\n", err)
+	default:
+		t.loaded = true
+		if err := t.sender.UnmarshalQueue(data); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	go t.sender.Run(t.stop)
+	return t
+}
+
+// setLevel records events against a level from now on; nil records none,
+// for play that would skew the data, such as other physics
+func (t *gameplayTelemetry) setLevel(level *game.Level) {
+	if t == nil {
+		return
+	}
+	t.level, t.charge = "", 0
+	if level != nil {
+		t.level, _ = levelKey(level)
+	}
+}
+
+// record queues an event for the current level
+func (t *gameplayTelemetry) record(e telemetry.Event) {
+	if t == nil || t.level == "" {
+		return
+	}
+	e.Level = t.level
+	t.sender.Record(e)
+}
+
+// death records where in the level the local player died
+func (t *gameplayTelemetry) death(x float64) {
+	t.record(telemetry.Event{Kind: telemetry.KindDeath, Segment: telemetry.Segment(x)})
+}
+
+// finish records how long the local player took to finish the level
+func (t *gameplayTelemetry) finish(ticks uint64) {
+	t.record(telemetry.Event{Kind: telemetry.KindFinish, Ticks: int(ticks)})
+}
+
+// updateCharge takes the local player's charge once per tick, recording
+// how long each charge was held when the attack is released
+func (t *gameplayTelemetry) updateCharge(progress float64, _ int, charging bool) {
+	if t == nil {
+		return
+	}
+	if charging {
+		t.charge = int(math.Round(progress * game.MaxChargeTicks))
+		return
+	}
+	if t.charge > 0 {
+		t.record(telemetry.Event{Kind: telemetry.KindCharge, Ticks: t.charge})
+		t.charge = 0
+	}
+}
+
+// Enabled reports whether the player opted in
+func (t *gameplayTelemetry) Enabled() bool {
+	return t != nil && t.sender.Enabled()
+}
+
+// toggle opts in or out and returns the new setting, for the profile
+func (t *gameplayTelemetry) toggle() bool {
+	on := !t.sender.Enabled()
+	t.sender.SetEnabled(on)
+	return on
+}
+
+// close stops sending and keeps the unsent events for the next run
+func (t *gameplayTelemetry) close() {
+	if t == nil {
+		return
+	}
+	close(t.stop)
+	data, err := t.sender.MarshalQueue()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not save the telemetry queue: %v\n", err)
+		return
+	}
+	if data == nil {
+		if !t.loaded {
+			return
+		}
+		data = []byte("[]") // Sent or opted out: don't send them again
+	}
+	if err := writeSave(t.path, data); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not save the telemetry queue: %v\n", err)
+	}
+}
@@ -0,0 +1,103 @@
+//go:build gio
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/i18n"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+	"github.com/andersfylling/rayman-slides/internal/render"
+)
+
+// timeTrial records every run of a level and races it against a ghost of
+// the personal best, which is kept as a replay file per level
+type timeTrial struct {
+	path  string // Best replay file
+	level *game.Level
+	name  string // Player name in recorded replays
+
+	best  *game.Replay // nil until the level is finished once
+	run   *game.Replay // Current attempt
+	ghost *game.Ghost  // Plays best, nil without one
+}
+
+// defaultReplayDir is where best runs are kept unless -replays is given
+func defaultReplayDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "replays"
+	}
+	return filepath.Join(dir, "rayman-slides", "replays")
+}
+
+// newTimeTrial loads the personal best for the level from dir, if any
+func newTimeTrial(dir string, level *game.Level, name string) (*timeTrial, error) {
+	tt := &timeTrial{
+		path:  filepath.Join(dir, level.Name+".json"),
+		level: level,
+		name:  name,
+	}
+
+	data, err := readSave(tt.path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		if tt.best, err = game.ReadReplay(bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("%s: %w", tt.path, err)
+		}
+	}
+
+	tt.restart()
+	return tt, nil
+}
+
+// restart begins a new attempt with the ghost back at the start
+func (tt *timeTrial) restart() {
+	tt.run = &game.Replay{Level: tt.level.Name, Player: tt.name}
+	tt.ghost = nil
+	if tt.best != nil {
+		tt.ghost = game.NewGhost(tt.level, tt.best)
+	}
+}
+
+// record adds the intents of the tick about to run and moves the ghost
+func (tt *timeTrial) record(intents protocol.Intent) {
+	tt.run.Record(intents)
+	if tt.ghost != nil {
+		tt.ghost.Step()
+	}
+}
+
+// finish ends the attempt at the exit, saving it if it is a new best
+func (tt *timeTrial) finish() (bool, error) {
+	tt.run.FinishTicks = uint64(tt.run.Len())
+	if !tt.run.Faster(tt.best) {
+		return false, nil
+	}
+	tt.best = tt.run
+
+	var buf bytes.Buffer
+	if err := game.WriteReplay(&buf, tt.best); err != nil {
+		return true, err
+	}
+	return true, writeSave(tt.path, buf.Bytes())
+}
+
+// hud formats the running time against the best in tr's language, e.g.
+// "Time 0:12.50 | Best 0:10.00"
+func (tt *timeTrial) hud(tr *i18n.Catalog) string {
+	best := "-"
+	if tt.best != nil {
+		best = render.FormatTicks(tt.best.FinishTicks)
+	}
+	return tr.T("hud.time_trial", render.FormatTicks(uint64(tt.run.Len())), best)
+}
@@ -0,0 +1,51 @@
+//go:build gio
+
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+)
+
+// trainingHUD times attack charges in the training room: the charge being
+// held, and the charge and range of the last throw, so they can be checked
+// against the dummies standing at game.TrainingDistances
+type trainingHUD struct {
+	charge int // Ticks held so far, while charging
+	last   int // Ticks the last throw was charged; 0 before the first
+}
+
+// update takes the local player's charge once per tick
+func (tr *trainingHUD) update(progress float64, _ int, charging bool) {
+	if charging {
+		tr.charge = int(math.Round(progress * game.MaxChargeTicks))
+		return
+	}
+	if tr.charge > 0 {
+		tr.last = tr.charge
+		tr.charge = 0
+	}
+}
+
+// hud formats the timing, e.g. "Charge 1.25s/3.00s | Last 0.50s, 4.2 tiles"
+func (tr *trainingHUD) hud() string {
+	s := fmt.Sprintf("Charge %.2fs/%.2fs", seconds(tr.charge), seconds(game.MaxChargeTicks))
+	if tr.last > 0 {
+		s += fmt.Sprintf(" | Last %.2fs, %.1f tiles", seconds(tr.last), fistDistance(tr.last))
+	}
+	return s
+}
+
+// seconds converts ticks to seconds at 60 TPS
+func seconds(ticks int) float64 {
+	return float64(ticks) / 60
+}
+
+// fistDistance is how far a fist charged for ticks flies, as the attack
+// system computes it
+func fistDistance(ticks int) float64 {
+	ratio := float64(ticks) / float64(game.MaxChargeTicks)
+	return game.MinFistDistance + ratio*(game.MaxFistDistance-game.MinFistDistance)
+}
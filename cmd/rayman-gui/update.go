@@ -0,0 +1,50 @@
+//go:build gio
+
+package main
+
+import (
+	"sync"
+
+	"github.com/andersfylling/rayman-slides/internal/i18n"
+	"github.com/andersfylling/rayman-slides/internal/lobby"
+)
+
+// updateCheck asks the lookup service for the latest release in the
+// background, so a slow or unreachable service never delays the game. A
+// newer release shows as a notice in the pause menu.
+type updateCheck struct {
+	mu    sync.Mutex
+	notes []string
+}
+
+// checkForUpdate starts the check of this build against lookupURL's latest
+// release and redraws the window if there is a newer one
+func checkForUpdate(lookupURL, current string, tr *i18n.Catalog, invalidate func()) *updateCheck {
+	u := &updateCheck{}
+	go func() {
+		info, err := lobby.NewLookupClient(lookupURL).Version()
+		if err != nil || !lobby.NewerVersion(current, info.Latest) {
+			return // Not worth bothering the player about
+		}
+		notes := []string{tr.T("menu.update", info.Latest)}
+		if info.URL != "" {
+			notes = append(notes, info.URL)
+		}
+		u.mu.Lock()
+		u.notes = notes
+		u.mu.Unlock()
+		invalidate()
+	}()
+	return u
+}
+
+// Notes returns the notice for the pause menu, none until a newer release
+// is found
+func (u *updateCheck) Notes() []string {
+	if u == nil {
+		return nil
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.notes
+}
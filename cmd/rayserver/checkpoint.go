@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/andersfylling/rayman-slides/internal/server"
+)
+
+// resume continues from the checkpoint at path. A missing file is not an
+// error, so -resume can always be passed: the server then starts fresh.
+func resume(srv *server.Server, path string) error {
+	cp, err := server.ReadCheckpoint(path)
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Println("No checkpoint, starting fresh")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := srv.Resume(cp); err != nil {
+		return err
+	}
+	fmt.Printf("Resumed tick %d with %d players from %s (saved %s)\n",
+		cp.Tick, len(cp.Players), path, cp.SavedAt.Format(time.RFC3339))
+	return nil
+}
+
+// saveCheckpoints writes a checkpoint to path every interval until stop is
+// closed, then closes done: after done no write is running or to come, so
+// the file can be removed
+func saveCheckpoints(srv *server.Server, path string, every time.Duration, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := server.WriteCheckpoint(path, srv.Checkpoint()); err != nil {
+				fmt.Fprintf(os.Stderr, "checkpoint: %v\n", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andersfylling/rayman-slides/internal/lobby"
+	"github.com/andersfylling/rayman-slides/internal/server"
+)
+
+// sdNotify sends a state such as READY=1 to systemd when it started us
+// with Type=notify, and does nothing otherwise
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:] // Abstract socket namespace
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// writePIDFile writes the process ID to path, replacing a stale file
+func writePIDFile(path string) error {
+	return os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0o644)
+}
+
+// drain refuses new players and waits for the current match to end or
+// everyone to leave, at most timeout. Another signal stops waiting.
+func drain(srv *server.Server, timeout time.Duration, signals <-chan os.Signal) {
+	srv.Drain()
+	fmt.Printf("Draining: waiting up to %s for the match to finish\n", timeout)
+	if err := sdNotify("STOPPING=1\nSTATUS=Draining"); err != nil {
+		fmt.Fprintf(os.Stderr, "sd_notify: %v\n", err)
+	}
+
+	deadline := time.After(timeout)
+	poll := time.NewTicker(time.Second)
+	defer poll.Stop()
+	for !srv.Drained() {
+		select {
+		case <-poll.C:
+		case <-deadline:
+			fmt.Println("Drain timeout, disconnecting players")
+			return
+		case <-signals:
+			fmt.Println("Stopping now")
+			return
+		}
+	}
+}
+
+// registration is this server's room at the lookup service
+type registration struct {
+	lookup *lobby.LookupClient
+	req    lobby.CreateRequest
+	file   string // Keeps the code across restarts, empty for none
+
+	streamer bool   // Keep the code off the console
+	key      string // Room key of a private room, empty for an open one
+
+	mu   sync.Mutex
+	code string
+}
+
+// registerRoom creates a room for this server. The lookup service fills in
+// the address it sees us from. With a room file, a code saved by an earlier
+// run is asked for again, so invites survive a restart.
+func registerRoom(lookup *lobby.LookupClient, req lobby.CreateRequest, file string) (*registration, error) {
+	r := &registration{lookup: lookup, req: req, file: file}
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if code := strings.TrimSpace(string(data)); lobby.ValidCode(code) {
+			r.req.Code = code
+		}
+	}
+	if err := r.create(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Code returns the room code
+func (r *registration) Code() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.code
+}
+
+// Invite returns what players join with: the code and, for a private
+// room, the key
+func (r *registration) Invite() string {
+	return lobby.FormatInvite(r.Code(), r.key)
+}
+
+// shown returns the invite to print on the console, hidden in streamer mode
+func (r *registration) shown() string {
+	if r.streamer {
+		return lobby.HiddenCode
+	}
+	return r.Invite()
+}
+
+func (r *registration) create() error {
+	room, err := r.lookup.Create(r.req)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.code = room.Code
+	r.req.Code = room.Code
+	r.mu.Unlock()
+	if r.file != "" {
+		return os.WriteFile(r.file, []byte(room.Code+"\n"), 0o644)
+	}
+	return nil
+}
+
+// keepAlive sends a heartbeat with the player count every
+// lobby.HeartbeatInterval. If the service has lost the room (it restarted,
+// or heartbeats failed for too long) the room is created again under the
+// same code.
+func (r *registration) keepAlive(srv *server.Server) {
+	for range time.Tick(lobby.HeartbeatInterval) {
+		err := r.lookup.Heartbeat(r.Code(), srv.SessionCount())
+		if errors.Is(err, lobby.ErrNotFound) {
+			if err = r.create(); err == nil {
+				fmt.Printf("Registered again, room code: %s\n", r.shown())
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "heartbeat: %s\n", redact(err.Error(), r.streamer))
+		}
+	}
+}
+
+// close removes the room; the room file stays for the next start
+func (r *registration) close() {
+	if err := r.lookup.Delete(r.Code()); err != nil {
+		fmt.Fprintf(os.Stderr, "deregister: %v\n", err)
+	}
+}
@@ -2,22 +2,327 @@
 package main
 
 import (
+	"bufio"
+	"crypto/tls"
+	"flag"
 	"fmt"
+	"net/http"
+	_ "net/http/pprof" // Profiling handlers for -pprof
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/andersfylling/rayman-slides/internal/crash"
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/lobby"
+	"github.com/andersfylling/rayman-slides/internal/network"
+	"github.com/andersfylling/rayman-slides/internal/scripting"
+	"github.com/andersfylling/rayman-slides/internal/server"
 )
 
 // Version is set at build time
 var Version = "dev"
 
 func main() {
+	cfg := server.DefaultConfig()
+	flag.IntVar(&cfg.Port, "port", cfg.Port, "TCP port to listen on")
+	flag.IntVar(&cfg.MaxPlayers, "max-players", cfg.MaxPlayers, "maximum connected players")
+	flag.StringVar(&cfg.MapPath, "map", cfg.MapPath, "level file to load (JSON, see assets/levels)")
+	flag.BoolVar(&cfg.SendLevel, "send-level", cfg.SendLevel, "send the map to clients whose copy differs (false rejects them)")
+	flag.BoolVar(&cfg.HostControls, "host-controls", cfg.HostControls, "let the longest connected player kick players and change settings as host")
+	flag.IntVar(&cfg.AntiCheat.KickAfter, "kick-after", cfg.AntiCheat.KickAfter, "kick a client after this many implausible inputs (0 = only log)")
+	mapDir := flag.String("maps", "", "directory of level files players may vote to change to (/votemap)")
+	votes := flag.Bool("votes", true, "let players vote to kick, change the map and restart (without -host-controls)")
+	afk := flag.Duration("afk", time.Minute, "flag players who send no input this long as AFK; in coop they spectate (0 = off)")
+	afkKick := flag.Duration("afk-kick", 5*time.Minute, "with -public, remove AFK players after this much longer to free their slot (0 = never)")
+	physicsPath := flag.String("physics", "", "player physics tunables file (JSON, see assets/physics.json); clients must use the same")
+	atlasPath := flag.String("atlas", "", "sprite atlas definition (atlas.json) to send to joining clients")
+	modeName := flag.String("mode", "coop", "game mode: coop, race, deathmatch or horde")
+	metricsAddr := flag.String("metrics", "", "serve Prometheus metrics on this address (e.g. :9100)")
+	trackAllocs := flag.Bool("allocs", false, "count allocations per subsystem for -metrics and the allocs command (reads runtime.MemStats a few times a tick)")
+	pprofAddr := flag.String("pprof", "", "serve /debug/pprof profiling on this address (e.g. localhost:6060)")
+	daemon := flag.Bool("daemon", false, "run without the stdin console, for systemd (Type=notify)")
+	pidFile := flag.String("pidfile", "", "write the process ID to this file")
+	checkpoint := flag.String("checkpoint", "", "save the match to this file periodically, to survive a crash")
+	checkpointEvery := flag.Duration("checkpoint-every", 10*time.Second, "how often to save the -checkpoint file")
+	resumeMatch := flag.Bool("resume", false, "continue from the -checkpoint file if there is one")
+	drainTimeout := flag.Duration("drain-timeout", 10*time.Minute, "on SIGTERM, how long to let the current match finish")
+	register := flag.Bool("register", false, "register a room code with the lookup service")
+	lookupURL := flag.String("lookup", "http://localhost:8080", "lookup service URL for -register")
+	roomName := flag.String("name", "Rayman Slides", "room name shown by the lookup service")
+	roomFile := flag.String("room-file", "", "keep the room code in this file to get it back after a restart")
+	public := flag.Bool("public", false, "list the room in the server browser")
+	region := flag.String("region", "", "region tag for the server browser (e.g. eu)")
+	crashDir := flag.String("crash-dir", crash.DefaultDir(), "write a crash report here if the server crashes")
+	tlsCert := flag.String("tls-cert", "", "TLS certificate file (PEM); with -tls-key, clients connect over TLS")
+	tlsKey := flag.String("tls-key", "", "TLS private key file (PEM) for -tls-cert")
+	private := flag.Bool("private", false, "make a room key: only players with the invite (code and key) can join")
+	streamer := flag.Bool("streamer", false, "keep the room code and IP addresses out of console output; the console's code command shows the code")
+	flag.Parse()
+	if *checkpoint != "" && *checkpointEvery <= 0 {
+		fmt.Fprintln(os.Stderr, "-checkpoint-every must be positive")
+		os.Exit(1)
+	}
+	cfg.Build = Version
+	if !*votes {
+		cfg.Votes.Duration = 0
+	}
+	cfg.AFK.After = uint64(afk.Seconds() * float64(cfg.TickRate))
+	if *public {
+		cfg.AFK.Kick = uint64(afkKick.Seconds() * float64(cfg.TickRate))
+	}
+
+	// Everything printed from here on is kept for a crash report
+	reporter := &crash.Reporter{App: "rayserver", Version: Version, Renderer: "none", Dir: *crashDir}
+	if err := reporter.CaptureOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: crash reports won't include output: %v\n", err)
+	}
+	defer reporter.Exit(0) // Runs last: exit once all output is printed
+	defer reporter.Recover()
+
 	fmt.Printf("Rayman Server v%s\n", Version)
 	fmt.Println("Server starting...")
 
-	// TODO: Parse flags (--port, --max-players, --map)
-	// TODO: Load map
-	// TODO: Initialize ECS world
-	// TODO: Start network listener
-	// TODO: Run tick loop
+	transport := network.NewTCPTransport()
+	if *tlsCert != "" || *tlsKey != "" {
+		cert, err := tls.LoadX509KeyPair(*tlsCert, *tlsKey)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "tls: %v\n", err)
+			reporter.Exit(1)
+		}
+		transport = network.NewTLSTransport(&tls.Config{Certificates: []tls.Certificate{cert}})
+	}
+	if err := transport.Listen(fmt.Sprintf(":%d", cfg.Port)); err != nil {
+		fmt.Fprintf(os.Stderr, "listen: %v\n", err)
+		reporter.Exit(1)
+	}
+	defer transport.Close()
+	var roomKey string
+	if *private {
+		roomKey = lobby.NewRoomKey()
+	}
+
+	level := game.NewDemoLevel(80, 45)
+	if cfg.MapPath != "" {
+		var err error
+		level, err = game.ReadLevelFile(os.DirFS(filepath.Dir(cfg.MapPath)), filepath.Base(cfg.MapPath))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "load map: %v\n", err)
+			reporter.Exit(1)
+		}
+	}
+
+	world := game.NewWorld()
+	scripts := scripting.New(world)
+	scripts.OnError = func(err error) { fmt.Fprintf(os.Stderr, "%v\n", err) }
+	scripts.Print = func(script, msg string) { fmt.Printf("[%s] %s\n", script, msg) }
+	world.LoadLevel(level)
+	if err := scripts.LoadLevel(level); err != nil {
+		fmt.Fprintf(os.Stderr, "load map: %v\n", err)
+		reporter.Exit(1)
+	}
+
+	mode, err := server.NewGameMode(*modeName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		reporter.Exit(1)
+	}
+
+	srv := server.New(cfg)
+	srv.SetWorld(world)
+	srv.SetInputObserver(reporter.RecordInput)
+	srv.SetPanicHandler(reporter.Crash)
+	reporter.Checksum = func() (uint64, uint32) {
+		state := srv.World().Snapshot()
+		return state.Tick, state.Checksum
+	}
+	srv.SetGameMode(mode)
+	if *mapDir != "" {
+		maps, err := filepath.Glob(filepath.Join(*mapDir, "*.json"))
+		if err != nil || len(maps) == 0 {
+			fmt.Fprintf(os.Stderr, "maps: no level files in %s\n", *mapDir)
+			reporter.Exit(1)
+		}
+		srv.SetMaps(maps)
+	}
+	if *physicsPath != "" {
+		physics, err := game.LoadPhysics(*physicsPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "load physics: %v\n", err)
+			reporter.Exit(1)
+		}
+		srv.SetPhysics(physics)
+	}
+	if *atlasPath != "" {
+		atlas, err := os.ReadFile(*atlasPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "load atlas: %v\n", err)
+			reporter.Exit(1)
+		}
+		srv.SetAtlas(atlas)
+	}
+	srv.SetViolationCallback(func(v server.Violation) {
+		fmt.Fprintf(os.Stderr, "anti-cheat: %s\n", redact(v.String(), *streamer))
+	})
+	if *resumeMatch {
+		if *checkpoint == "" {
+			fmt.Fprintln(os.Stderr, "-resume needs -checkpoint")
+			reporter.Exit(1)
+		}
+		if err := resume(srv, *checkpoint); err != nil {
+			fmt.Fprintf(os.Stderr, "resume: %v\n", err)
+			reporter.Exit(1)
+		}
+	}
+	remotes := server.NewRemotes(srv, lobby.KeyPSK(roomKey))
+	go func() {
+		for {
+			conn, err := transport.Accept()
+			if err != nil {
+				return // Closed on exit
+			}
+			go func() {
+				if err := remotes.Serve(conn); err != nil {
+					fmt.Fprintf(os.Stderr, "connection: %s\n", redact(err.Error(), *streamer))
+				}
+			}()
+		}
+	}()
+	srv.Allocs().SetEnabled(*trackAllocs)
+	if err := srv.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "start: %v\n", err)
+		reporter.Exit(1)
+	}
+	defer srv.Stop()
+
+	if *metricsAddr != "" {
+		go func() {
+			if err := srv.ServeMetrics(*metricsAddr); err != nil {
+				fmt.Fprintf(os.Stderr, "metrics: %v\n", err)
+			}
+		}()
+	}
+
+	if *pprofAddr != "" {
+		go func() {
+			if err := http.ListenAndServe(*pprofAddr, nil); err != nil {
+				fmt.Fprintf(os.Stderr, "pprof: %v\n", err)
+			}
+		}()
+	}
+
+	stopCheckpoints := make(chan struct{})
+	checkpointsDone := make(chan struct{})
+	if *checkpoint != "" {
+		go saveCheckpoints(srv, *checkpoint, *checkpointEvery, stopCheckpoints, checkpointsDone)
+	}
+
+	if *pidFile != "" {
+		if err := writePIDFile(*pidFile); err != nil {
+			fmt.Fprintf(os.Stderr, "pid file: %v\n", err)
+			reporter.Exit(1)
+		}
+		defer os.Remove(*pidFile)
+	}
+
+	admin := server.NewAdmin(srv)
+	if *register {
+		reg, err := registerRoom(lobby.NewLookupClient(*lookupURL), lobby.CreateRequest{
+			Host:       fmt.Sprintf(":%d", cfg.Port),
+			Name:       *roomName,
+			MaxPlayers: cfg.MaxPlayers,
+			Public:     *public,
+			Region:     *region,
+		}, *roomFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "register: %s\n", redact(err.Error(), *streamer))
+			reporter.Exit(1)
+		}
+		reg.streamer = *streamer
+		reg.key = roomKey
+		if roomKey != "" {
+			fmt.Printf("Invite: %s\n", reg.shown())
+		} else {
+			fmt.Printf("Room code: %s\n", reg.shown())
+		}
+		if *streamer {
+			fmt.Println("Streamer mode: type code to show it")
+		}
+		admin.Register("code", server.AdminCommand{Help: "show the room code, or the invite of a -private room, even in streamer mode", Run: func([]string) (string, error) {
+			return reg.Invite(), nil
+		}})
+		go reg.keepAlive(srv)
+		defer reg.close()
+	} else if roomKey != "" {
+		if *streamer {
+			fmt.Println("Streamer mode: type code to show the room key")
+		} else {
+			fmt.Printf("Room key: %s\n", roomKey)
+		}
+		admin.Register("code", server.AdminCommand{Help: "show the room key, even in streamer mode", Run: func([]string) (string, error) {
+			return roomKey, nil
+		}})
+	}
+
+	// SIGTERM (systemd stop) and Ctrl-C drain the server; in the foreground
+	// closing stdin stops it at once
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	consoleClosed := make(chan struct{})
+	if !*daemon {
+		go func() {
+			runConsole(admin, *streamer)
+			close(consoleClosed)
+		}()
+	}
+	if err := sdNotify("READY=1"); err != nil {
+		fmt.Fprintf(os.Stderr, "sd_notify: %v\n", err)
+	}
+
+	select {
+	case <-signals:
+		drain(srv, *drainTimeout, signals)
+	case <-consoleClosed:
+	}
+	srv.DisconnectAll(server.ReasonShutdown)
+
+	// A clean stop ends the match; only a crash leaves a checkpoint to resume
+	if *checkpoint != "" {
+		close(stopCheckpoints)
+		<-checkpointsDone
+		os.Remove(*checkpoint)
+	}
+}
+
+// runConsole runs the admin console on stdin, one command per line, until
+// stdin closes. In streamer mode output is redacted, except from code, the
+// one command asked to reveal something.
+func runConsole(admin *server.Admin, streamer bool) {
+	scanner := bufio.NewScanner(os.Stdin)
+	fmt.Print("> ")
+	for scanner.Scan() {
+		line := scanner.Text()
+		out, err := admin.Exec(line)
+		if fields := strings.Fields(line); len(fields) == 0 || !strings.EqualFold(fields[0], "code") {
+			out = redact(out, streamer)
+		}
+		switch {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "error: %s\n", redact(err.Error(), streamer))
+		case out != "":
+			fmt.Println(out)
+		}
+		fmt.Print("> ")
+	}
+}
 
-	os.Exit(0)
+// redact hides room codes and IP addresses in streamer mode
+func redact(s string, streamer bool) string {
+	if !streamer {
+		return s
+	}
+	return lobby.Redact(s)
 }
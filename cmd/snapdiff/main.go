@@ -0,0 +1,126 @@
+// Command snapdiff prints how two world states differ, entity by entity and
+// component field by field, for debugging reconciliation mismatches. It
+// compares two state files (game.WriteState output or rayserver
+// checkpoints), or two ticks of a replay.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+)
+
+func main() {
+	replayPath := flag.String("replay", "", "compare two ticks of this replay instead of two files")
+	mapPath := flag.String("map", "", "level the replay was recorded on (JSON, default the demo level)")
+	from := flag.Uint64("from", 0, "first tick to compare with -replay")
+	to := flag.Uint64("to", 0, "second tick to compare with -replay")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: snapdiff a.json b.json")
+		fmt.Fprintln(os.Stderr, "       snapdiff -replay run.json [-map level.json] -from 100 -to 200")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var a, b *game.WorldState
+	var err error
+	switch {
+	case *replayPath != "":
+		a, b, err = replayStates(*replayPath, *mapPath, *from, *to)
+	case flag.NArg() == 2:
+		if a, err = readState(flag.Arg(0)); err == nil {
+			b, err = readState(flag.Arg(1))
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "snapdiff: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !printDiff(a, b) {
+		os.Exit(1)
+	}
+}
+
+// readState reads a world state file, or the world of a rayserver checkpoint
+func readState(path string) (*game.WorldState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var checkpoint struct{ World *game.WorldState }
+	if err := json.Unmarshal(data, &checkpoint); err == nil && checkpoint.World != nil {
+		return checkpoint.World, nil
+	}
+	state, err := game.ReadState(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return state, nil
+}
+
+// replayStates plays a replay on its level and returns the states at two
+// ticks
+func replayStates(path, mapPath string, from, to uint64) (*game.WorldState, *game.WorldState, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	replay, err := game.ReadReplay(f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	level := game.NewDemoLevel(80, 45)
+	if mapPath != "" {
+		if level, err = game.ReadLevelFile(os.DirFS(filepath.Dir(mapPath)), filepath.Base(mapPath)); err != nil {
+			return nil, nil, err
+		}
+	}
+	a, err := replay.StateAt(level, from)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := replay.StateAt(level, to)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &a, &b, nil
+}
+
+// printDiff prints the differences and reports whether the states match
+func printDiff(a, b *game.WorldState) bool {
+	fmt.Printf("a: tick %d, %d entities, checksum %#x\n", a.Tick, len(a.Entities), a.Checksum)
+	fmt.Printf("b: tick %d, %d entities, checksum %#x\n", b.Tick, len(b.Entities), b.Checksum)
+	if diff := a.Components.Diff(&b.Components); diff != 0 {
+		fmt.Printf("components differ: %s\n", diff)
+	}
+
+	diffs := game.DiffStates(a, b)
+	for _, d := range diffs {
+		switch d.OnlyIn {
+		case 1:
+			fmt.Printf("entity %d: only in a\n", d.ID)
+		case 2:
+			fmt.Printf("entity %d: only in b\n", d.ID)
+		default:
+			fmt.Printf("entity %d:\n", d.ID)
+			for _, f := range d.Fields {
+				fmt.Printf("  %-22s %s -> %s\n", f.Name, f.A, f.B)
+			}
+		}
+	}
+	if len(diffs) == 0 {
+		fmt.Println("entities match")
+	}
+	return len(diffs) == 0
+}
@@ -12,6 +12,8 @@ import (
 	"image/png"
 	"os"
 	"sort"
+
+	"github.com/andersfylling/rayman-slides/internal/debugdraw"
 )
 
 type SpriteRegion struct {
@@ -38,6 +40,9 @@ var data AtlasData
 var atlasImg image.Image
 var palette color.Palette
 
+// labelColor is the scene labels' text, drawn with a black shadow
+var labelColor = color.RGBA{220, 220, 220, 255}
+
 func main() {
 	if err := run(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -143,8 +148,8 @@ func generateStaticGrid(names []string) error {
 		offsetY := cellY + (cellH-region.H)/2
 
 		drawRegion(frame, atlasImg, spriteRect, image.Pt(offsetX, offsetY), region.FlipX)
-		drawDot(frame, offsetX+region.AnchorX, offsetY+region.AnchorY, color.RGBA{255, 0, 0, 255})
-		drawBorder(frame, offsetX, offsetY, region.W, region.H, color.RGBA{100, 100, 100, 255})
+		debugdraw.Dot(frame, offsetX+region.AnchorX, offsetY+region.AnchorY, color.RGBA{255, 0, 0, 255})
+		debugdraw.Border(frame, offsetX, offsetY, region.W, region.H, color.RGBA{100, 100, 100, 255}, 1)
 	}
 
 	outGif.Image = append(outGif.Image, frame)
@@ -186,7 +191,7 @@ func generateAnimatedPreview() error {
 		}
 
 		// ======== SCENE 1: WORLD (top half) ========
-		drawLabel(frame, 10, 10, "WORLD SCENE")
+		debugdraw.ShadowLabel(frame, 10, 10, "WORLD SCENE", labelColor, color.Black)
 
 		groundY := 220
 		tileSize := 50
@@ -246,7 +251,7 @@ func generateAnimatedPreview() error {
 		drawSpriteAtScaled(frame, orbSprite, 370, groundY-120, 0.5)
 
 		// ======== SCENE 2: COMBAT (middle) ========
-		drawLabel(frame, 10, 280, "COMBAT SCENE")
+		debugdraw.ShadowLabel(frame, 10, 280, "COMBAT SCENE", labelColor, color.Black)
 
 		combatGroundY := 420
 		// Draw dirt ground for combat area
@@ -280,18 +285,18 @@ func generateAnimatedPreview() error {
 		drawSpriteAtScaled(frame, "cage_closed", 800, combatGroundY, 0.6)
 
 		// ======== SCENE 3: HAZARDS (bottom) ========
-		drawLabel(frame, 10, 480, "HAZARDS + TILES")
+		debugdraw.ShadowLabel(frame, 10, 480, "HAZARDS + TILES", labelColor, color.Black)
 
 		hazardY := 620
 		// Draw various hazard tiles
 		drawSpriteAtSize(frame, "tile_spikes", 50, hazardY, 60, 60)
-		drawLabel(frame, 50, hazardY+65, "SPIKES")
+		debugdraw.ShadowLabel(frame, 50, hazardY+65, "SPIKES", labelColor, color.Black)
 
 		drawSpriteAtSize(frame, "tile_water", 150, hazardY, 60, 60)
-		drawLabel(frame, 150, hazardY+65, "WATER")
+		debugdraw.ShadowLabel(frame, 150, hazardY+65, "WATER", labelColor, color.Black)
 
 		drawSpriteAtSize(frame, "tile_fire", 250, hazardY, 60, 70)
-		drawLabel(frame, 250, hazardY+75, "FIRE")
+		debugdraw.ShadowLabel(frame, 250, hazardY+75, "FIRE", labelColor, color.Black)
 
 		// Show tile types
 		drawSpriteAtSize(frame, "tile_grass", 380, hazardY, 50, 50)
@@ -397,12 +402,12 @@ func generateAtlasOverlay() error {
 		}
 
 		// Draw border rectangle (2px thick for visibility)
-		drawBorderRGBA(overlay, region.X, region.Y, region.W, region.H, borderColor, 2)
+		debugdraw.Border(overlay, region.X, region.Y, region.W, region.H, borderColor, 2)
 
 		// Draw hitbox if different from visual bounds
 		if region.HitW > 0 && region.HitH > 0 {
 			if region.HitX != 0 || region.HitY != 0 || region.HitW != region.W || region.HitH != region.H {
-				drawBorderRGBA(overlay, region.X+region.HitX, region.Y+region.HitY,
+				debugdraw.Border(overlay, region.X+region.HitX, region.Y+region.HitY,
 					region.HitW, region.HitH, hitboxColor, 1)
 			}
 		}
@@ -410,11 +415,11 @@ func generateAtlasOverlay() error {
 		// Draw anchor point as a cross
 		anchorX := region.X + region.AnchorX
 		anchorY := region.Y + region.AnchorY
-		drawCrossRGBA(overlay, anchorX, anchorY, colorAnchor, 5)
+		debugdraw.Cross(overlay, anchorX, anchorY, colorAnchor, 5)
 
 		// Draw label with ID at top-left of sprite region
 		bgColor := color.RGBA{0, 0, 0, 200}
-		drawLabelRGBA(overlay, region.X+3, region.Y+3, name, borderColor, bgColor)
+		debugdraw.Label(overlay, region.X+3, region.Y+3, name, borderColor, bgColor)
 	}
 
 	// Save as PNG
@@ -435,71 +440,6 @@ func generateAtlasOverlay() error {
 	return nil
 }
 
-// drawBorderRGBA draws a rectangle border on an RGBA image
-func drawBorderRGBA(img *image.RGBA, x, y, w, h int, c color.RGBA, thickness int) {
-	// Top and bottom edges
-	for dx := 0; dx < w; dx++ {
-		for t := 0; t < thickness; t++ {
-			img.Set(x+dx, y+t, c)
-			img.Set(x+dx, y+h-1-t, c)
-		}
-	}
-	// Left and right edges
-	for dy := 0; dy < h; dy++ {
-		for t := 0; t < thickness; t++ {
-			img.Set(x+t, y+dy, c)
-			img.Set(x+w-1-t, y+dy, c)
-		}
-	}
-}
-
-// drawCrossRGBA draws a cross marker on an RGBA image
-func drawCrossRGBA(img *image.RGBA, x, y int, c color.RGBA, size int) {
-	// Horizontal line
-	for dx := -size; dx <= size; dx++ {
-		img.Set(x+dx, y, c)
-	}
-	// Vertical line
-	for dy := -size; dy <= size; dy++ {
-		img.Set(x, y+dy, c)
-	}
-}
-
-// drawLabelRGBA draws text on an RGBA image
-func drawLabelRGBA(img *image.RGBA, x, y int, text string, textColor, bgColor color.RGBA) {
-	charWidth := 6 // 5 pixels + 1 spacing
-	textWidth := len(text) * charWidth
-	textHeight := 9
-
-	// Draw background rectangle
-	for dy := -1; dy < textHeight; dy++ {
-		for dx := -2; dx < textWidth+2; dx++ {
-			img.Set(x+dx, y+dy, bgColor)
-		}
-	}
-
-	for i, c := range text {
-		// Convert to uppercase
-		if c >= 'a' && c <= 'z' {
-			c = c - 'a' + 'A'
-		}
-
-		glyph, ok := font5x7[c]
-		if !ok {
-			continue
-		}
-
-		charX := x + i*charWidth
-		for row := 0; row < 7; row++ {
-			for col := 0; col < 5; col++ {
-				if glyph[row]&(1<<(4-col)) != 0 {
-					img.Set(charX+col, y+row, textColor)
-				}
-			}
-		}
-	}
-}
-
 func drawSpriteAt(frame *image.Paletted, spriteName string, x, y int) {
 	region, ok := data.Sprites[spriteName]
 	if !ok {
@@ -514,7 +454,7 @@ func drawSpriteAt(frame *image.Paletted, spriteName string, x, y int) {
 	drawRegion(frame, atlasImg, spriteRect, image.Pt(drawX, drawY), region.FlipX)
 
 	// Draw anchor point
-	drawDot(frame, x, y, color.RGBA{255, 0, 0, 255})
+	debugdraw.Dot(frame, x, y, color.RGBA{255, 0, 0, 255})
 }
 
 func drawSpriteAtSize(frame *image.Paletted, spriteName string, x, y, w, h int) {
@@ -546,94 +486,6 @@ func drawSpriteAtScaled(frame *image.Paletted, spriteName string, x, y int, scal
 	drawRegionScaled(frame, atlasImg, spriteRect, image.Pt(drawX, drawY), w, h)
 }
 
-// 5x7 bitmap font for A-Z, 0-9, and common punctuation
-var font5x7 = map[rune][7]uint8{
-	'A': {0x0E, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11},
-	'B': {0x1E, 0x11, 0x11, 0x1E, 0x11, 0x11, 0x1E},
-	'C': {0x0E, 0x11, 0x10, 0x10, 0x10, 0x11, 0x0E},
-	'D': {0x1E, 0x11, 0x11, 0x11, 0x11, 0x11, 0x1E},
-	'E': {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x1F},
-	'F': {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x10},
-	'G': {0x0E, 0x11, 0x10, 0x17, 0x11, 0x11, 0x0E},
-	'H': {0x11, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11},
-	'I': {0x0E, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0E},
-	'J': {0x07, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0C},
-	'K': {0x11, 0x12, 0x14, 0x18, 0x14, 0x12, 0x11},
-	'L': {0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x1F},
-	'M': {0x11, 0x1B, 0x15, 0x15, 0x11, 0x11, 0x11},
-	'N': {0x11, 0x11, 0x19, 0x15, 0x13, 0x11, 0x11},
-	'O': {0x0E, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
-	'P': {0x1E, 0x11, 0x11, 0x1E, 0x10, 0x10, 0x10},
-	'Q': {0x0E, 0x11, 0x11, 0x11, 0x15, 0x12, 0x0D},
-	'R': {0x1E, 0x11, 0x11, 0x1E, 0x14, 0x12, 0x11},
-	'S': {0x0E, 0x11, 0x10, 0x0E, 0x01, 0x11, 0x0E},
-	'T': {0x1F, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},
-	'U': {0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
-	'V': {0x11, 0x11, 0x11, 0x11, 0x11, 0x0A, 0x04},
-	'W': {0x11, 0x11, 0x11, 0x15, 0x15, 0x1B, 0x11},
-	'X': {0x11, 0x11, 0x0A, 0x04, 0x0A, 0x11, 0x11},
-	'Y': {0x11, 0x11, 0x0A, 0x04, 0x04, 0x04, 0x04},
-	'Z': {0x1F, 0x01, 0x02, 0x04, 0x08, 0x10, 0x1F},
-	'0': {0x0E, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0E},
-	'1': {0x04, 0x0C, 0x04, 0x04, 0x04, 0x04, 0x0E},
-	'2': {0x0E, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1F},
-	'3': {0x0E, 0x11, 0x01, 0x06, 0x01, 0x11, 0x0E},
-	'4': {0x02, 0x06, 0x0A, 0x12, 0x1F, 0x02, 0x02},
-	'5': {0x1F, 0x10, 0x1E, 0x01, 0x01, 0x11, 0x0E},
-	'6': {0x06, 0x08, 0x10, 0x1E, 0x11, 0x11, 0x0E},
-	'7': {0x1F, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
-	'8': {0x0E, 0x11, 0x11, 0x0E, 0x11, 0x11, 0x0E},
-	'9': {0x0E, 0x11, 0x11, 0x0F, 0x01, 0x02, 0x0C},
-	' ': {0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
-	'-': {0x00, 0x00, 0x00, 0x1F, 0x00, 0x00, 0x00},
-	'>': {0x08, 0x04, 0x02, 0x01, 0x02, 0x04, 0x08},
-	'<': {0x02, 0x04, 0x08, 0x10, 0x08, 0x04, 0x02},
-	'+': {0x00, 0x04, 0x04, 0x1F, 0x04, 0x04, 0x00},
-	'.': {0x00, 0x00, 0x00, 0x00, 0x00, 0x0C, 0x0C},
-	',': {0x00, 0x00, 0x00, 0x00, 0x04, 0x04, 0x08},
-	'!': {0x04, 0x04, 0x04, 0x04, 0x04, 0x00, 0x04},
-	'?': {0x0E, 0x11, 0x01, 0x02, 0x04, 0x00, 0x04},
-	'/': {0x01, 0x01, 0x02, 0x04, 0x08, 0x10, 0x10},
-	'(': {0x02, 0x04, 0x08, 0x08, 0x08, 0x04, 0x02},
-	')': {0x08, 0x04, 0x02, 0x02, 0x02, 0x04, 0x08},
-	':': {0x00, 0x0C, 0x0C, 0x00, 0x0C, 0x0C, 0x00},
-}
-
-func drawLabel(frame *image.Paletted, x, y int, text string) {
-	textColor := color.RGBA{220, 220, 220, 255}
-	shadowColor := color.RGBA{0, 0, 0, 255}
-	charWidth := 6 // 5 pixels + 1 spacing
-
-	for i, c := range text {
-		// Convert to uppercase
-		if c >= 'a' && c <= 'z' {
-			c = c - 'a' + 'A'
-		}
-
-		glyph, ok := font5x7[c]
-		if !ok {
-			continue // Skip unknown characters
-		}
-
-		charX := x + i*charWidth
-		for row := 0; row < 7; row++ {
-			for col := 0; col < 5; col++ {
-				if glyph[row]&(1<<(4-col)) != 0 {
-					// Draw shadow (offset by 1,1)
-					frame.Set(charX+col+1, y+row+1, shadowColor)
-				}
-			}
-		}
-		for row := 0; row < 7; row++ {
-			for col := 0; col < 5; col++ {
-				if glyph[row]&(1<<(4-col)) != 0 {
-					frame.Set(charX+col, y+row, textColor)
-				}
-			}
-		}
-	}
-}
-
 // buildPalette creates a 256-color palette from the atlas image
 func buildPalette(img image.Image) color.Palette {
 	palette := color.Palette{
@@ -730,24 +582,3 @@ func drawRegionScaled(dst *image.Paletted, src image.Image, srcRect image.Rectan
 		}
 	}
 }
-
-func drawDot(dst *image.Paletted, x, y int, c color.Color) {
-	for dy := -1; dy <= 1; dy++ {
-		for dx := -1; dx <= 1; dx++ {
-			if dx*dx+dy*dy <= 1 {
-				dst.Set(x+dx, y+dy, c)
-			}
-		}
-	}
-}
-
-func drawBorder(dst *image.Paletted, x, y, w, h int, c color.Color) {
-	for dx := 0; dx < w; dx++ {
-		dst.Set(x+dx, y, c)
-		dst.Set(x+dx, y+h-1, c)
-	}
-	for dy := 0; dy < h; dy++ {
-		dst.Set(x, y+dy, c)
-		dst.Set(x+w-1, y+dy, c)
-	}
-}
@@ -0,0 +1,15 @@
+//go:build !gio
+
+// Command sprite-editor is an interactive tool for defining sprite regions.
+// Built without the gio tag it only says how to build it.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	fmt.Fprintln(os.Stderr, "sprite-editor was built without Gio; run it with: make sprite-editor")
+	os.Exit(2)
+}
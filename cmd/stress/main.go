@@ -0,0 +1,79 @@
+// Command stress load-tests the game server: it runs the server rayserver
+// runs with a swarm of headless clients playing scripted inputs, reports
+// tick stability, snapshot latency and bandwidth, and exits 1 if any of
+// them is past its threshold.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/server"
+	"github.com/andersfylling/rayman-slides/internal/stress"
+)
+
+func main() {
+	cfg := stress.DefaultConfig()
+	limits := stress.DefaultThresholds()
+	scripts := make([]string, 0, len(stress.Scripts))
+	for name := range stress.Scripts {
+		scripts = append(scripts, name)
+	}
+	slices.Sort(scripts)
+
+	flag.IntVar(&cfg.Clients, "clients", cfg.Clients, "number of clients")
+	flag.DurationVar(&cfg.Duration, "duration", cfg.Duration, "how long the clients play")
+	flag.StringVar(&cfg.Script, "script", cfg.Script, "client inputs: "+strings.Join(scripts, ", "))
+	flag.Uint64Var(&cfg.Seed, "seed", cfg.Seed, "random seed for the random script")
+	flag.IntVar(&cfg.Server.TickRate, "tick-rate", cfg.Server.TickRate, "server ticks per second")
+	mapPath := flag.String("map", "", "level to play (JSON, default the demo level)")
+	modeName := flag.String("mode", "coop", "game mode: coop, race, deathmatch or horde")
+	flag.DurationVar(&limits.TickInterval, "max-tick", limits.TickInterval, "largest p99 time between ticks (0 = no limit)")
+	flag.Float64Var(&limits.MissedTicks, "max-missed", limits.MissedTicks, "largest share of ticks missed (0 = no limit)")
+	flag.DurationVar(&limits.Latency, "max-latency", limits.Latency, "largest p99 snapshot latency (0 = no limit)")
+	flag.Float64Var(&limits.Bandwidth, "max-bandwidth", limits.Bandwidth, "most snapshot bytes per second to a client (0 = no limit)")
+	flag.Parse()
+
+	if *mapPath != "" {
+		level, err := game.ReadLevelFile(os.DirFS(filepath.Dir(*mapPath)), filepath.Base(*mapPath))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "stress: load map: %v\n", err)
+			os.Exit(2)
+		}
+		cfg.Level = level
+	}
+	mode, err := server.NewGameMode(*modeName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "stress: %v\n", err)
+		os.Exit(2)
+	}
+	cfg.Mode = mode
+
+	report, err := stress.Run(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+
+	fmt.Printf("%d clients for %.1f s\n", report.Clients, report.Duration.Seconds())
+	fmt.Printf("ticks      %d run, %d missed, budget %v\n", report.Ticks, report.MissedTicks, report.Budget)
+	fmt.Printf("interval   %v\n", report.TickInterval)
+	fmt.Printf("tick time  %v\n", report.TickTime)
+	fmt.Printf("latency    %v\n", report.Latency)
+	fmt.Printf("snapshots  %d handled, %d dropped\n", report.Snapshots, report.Dropped)
+	fmt.Printf("bandwidth  %.0f B/s down, %.0f B/s up per client; busiest %.0f B/s down\n",
+		report.BytesDown, report.BytesUp, report.MaxBytesDown)
+
+	if failed := report.Check(limits); len(failed) > 0 {
+		for _, f := range failed {
+			fmt.Println("FAIL", f)
+		}
+		os.Exit(1)
+	}
+	fmt.Println("ok")
+}
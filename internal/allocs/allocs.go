@@ -0,0 +1,150 @@
+// Package allocs counts heap allocations per subsystem from runtime.MemStats
+// deltas around the work each one does, so a change that starts churning
+// slices every frame or tick shows up in the debug overlay and metrics
+// rather than only in a profile.
+package allocs
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Subsystems the game measures
+const (
+	Renderer   = "renderer"   // Drawing a frame
+	Simulation = "simulation" // Running ticks, authoritative and predicted
+	Network    = "network"    // Building snapshots and encoding messages
+)
+
+// Counter is one subsystem's allocations over the spans measured since the
+// last reset
+type Counter struct {
+	Name       string
+	Spans      uint64 // Spans measured
+	Allocs     uint64 // Heap objects allocated, over all spans
+	Bytes      uint64 // Heap bytes allocated, over all spans
+	LastAllocs uint64 // Objects in the latest span
+	LastBytes  uint64 // Bytes in the latest span
+	MaxAllocs  uint64 // Most objects in one span
+}
+
+// PerSpan returns the mean objects and bytes allocated per span
+func (c Counter) PerSpan() (allocs, bytes float64) {
+	if c.Spans == 0 {
+		return 0, 0
+	}
+	return float64(c.Allocs) / float64(c.Spans), float64(c.Bytes) / float64(c.Spans)
+}
+
+// Tracker measures spans of work per subsystem while enabled. Reading
+// MemStats briefly stops the world, so it starts disabled and costs nothing
+// until turned on. The counts are process-wide deltas: another goroutine
+// allocating during a span is counted in it, so spans should cover work
+// done on one goroutine and not nest. A nil *Tracker measures nothing. Its
+// methods may be called from any goroutine.
+type Tracker struct {
+	enabled atomic.Bool
+
+	mu       sync.Mutex
+	counters []Counter // In order of first use
+	stats    runtime.MemStats
+}
+
+// NewTracker creates a disabled tracker
+func NewTracker() *Tracker {
+	return &Tracker{}
+}
+
+// SetEnabled turns measuring on or off; counts are kept either way
+func (t *Tracker) SetEnabled(on bool) {
+	t.enabled.Store(on)
+}
+
+// Enabled reports whether spans are measured
+func (t *Tracker) Enabled() bool {
+	return t != nil && t.enabled.Load()
+}
+
+// Span is a stretch of work being measured, from Begin to End
+type Span struct {
+	t       *Tracker
+	name    string
+	mallocs uint64
+	bytes   uint64
+}
+
+// Begin starts measuring work for a subsystem. It returns a span that
+// does nothing while the tracker is disabled.
+func (t *Tracker) Begin(name string) Span {
+	if !t.Enabled() {
+		return Span{}
+	}
+	mallocs, bytes := t.read()
+	return Span{t: t, name: name, mallocs: mallocs, bytes: bytes}
+}
+
+// End adds the allocations since Begin to the subsystem's counter
+func (s Span) End() {
+	if s.t == nil {
+		return
+	}
+	mallocs, bytes := s.t.read()
+	s.t.add(s.name, mallocs-s.mallocs, bytes-s.bytes)
+}
+
+// read returns the process's cumulative heap objects and bytes. The
+// MemStats buffer is the tracker's, so reading allocates nothing.
+func (t *Tracker) read() (mallocs, bytes uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	runtime.ReadMemStats(&t.stats)
+	return t.stats.Mallocs, t.stats.TotalAlloc
+}
+
+func (t *Tracker) add(name string, allocs, bytes uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	i := t.index(name)
+	c := &t.counters[i]
+	c.Spans++
+	c.Allocs += allocs
+	c.Bytes += bytes
+	c.LastAllocs, c.LastBytes = allocs, bytes
+	c.MaxAllocs = max(c.MaxAllocs, allocs)
+}
+
+// index returns a subsystem's counter, adding it on first use; t.mu must
+// be held
+func (t *Tracker) index(name string) int {
+	for i := range t.counters {
+		if t.counters[i].Name == name {
+			return i
+		}
+	}
+	t.counters = append(t.counters, Counter{Name: name})
+	return len(t.counters) - 1
+}
+
+// AppendCounters appends every subsystem's counter to dst, in order of
+// first use
+func (t *Tracker) AppendCounters(dst []Counter) []Counter {
+	if t == nil {
+		return dst
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append(dst, t.counters...)
+}
+
+// Reset zeroes the counters, e.g. when the debug overlay opens
+func (t *Tracker) Reset() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range t.counters {
+		t.counters[i] = Counter{Name: t.counters[i].Name}
+	}
+}
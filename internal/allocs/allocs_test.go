@@ -0,0 +1,57 @@
+package allocs
+
+import (
+	"testing"
+)
+
+var sink [][]byte
+
+// TestTracker tests that spans count nothing while disabled, count a
+// subsystem's allocations once enabled, and that measuring allocates
+// nothing itself, so an allocation-free span reads zero.
+func TestTracker(t *testing.T) {
+	tr := NewTracker()
+	span := tr.Begin(Renderer)
+	sink = append(sink, make([]byte, 1024))
+	span.End()
+	if got := tr.AppendCounters(nil); len(got) != 0 {
+		t.Fatalf("Counted %+v while disabled", got)
+	}
+
+	tr.SetEnabled(true)
+	span = tr.Begin(Renderer)
+	for range 10 {
+		sink = append(sink[:0], make([]byte, 1024))
+	}
+	span.End()
+	tr.Begin(Simulation).End()
+
+	got := tr.AppendCounters(nil)
+	if len(got) != 2 || got[0].Name != Renderer || got[1].Name != Simulation {
+		t.Fatalf("Counters %+v, want renderer then simulation", got)
+	}
+	if r := got[0]; r.Allocs < 10 || r.Bytes < 10*1024 || r.LastAllocs != r.Allocs || r.MaxAllocs != r.Allocs {
+		t.Errorf("Renderer counter %+v, want at least 10 allocations of 1 KiB", r)
+	}
+	if s := got[1]; s.Spans != 1 || s.Allocs != 0 {
+		t.Errorf("Simulation counter %+v, want one span without allocations", s)
+	}
+	if allocs, bytes := got[0].PerSpan(); allocs < 10 || bytes < 10*1024 {
+		t.Errorf("PerSpan = %v, %v", allocs, bytes)
+	}
+
+	if n := testing.AllocsPerRun(100, func() { tr.Begin(Network).End() }); n != 0 {
+		t.Errorf("Measuring a span allocates %v times", n)
+	}
+
+	tr.Reset()
+	if got := tr.AppendCounters(nil); got[0].Spans != 0 || got[0].Allocs != 0 || got[0].Name != Renderer {
+		t.Errorf("After Reset %+v, want zeroed counters kept by name", got[0])
+	}
+
+	var none *Tracker
+	none.Begin(Renderer).End()
+	if none.Enabled() || none.AppendCounters(nil) != nil {
+		t.Error("A nil tracker should measure nothing")
+	}
+}
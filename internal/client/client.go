@@ -3,84 +3,457 @@
 package client
 
 import (
+	"github.com/andersfylling/rayman-slides/internal/allocs"
 	"github.com/andersfylling/rayman-slides/internal/game"
 	"github.com/andersfylling/rayman-slides/internal/input"
 	"github.com/andersfylling/rayman-slides/internal/protocol"
+	"github.com/andersfylling/rayman-slides/internal/scripting"
 	"github.com/andersfylling/rayman-slides/internal/server"
 )
 
+// predictionTicks is how many ticks of inputs and predicted states are kept
+// for reconciliation
+const predictionTicks = 128
+
 // Client connects input system to server and provides state for rendering.
-// In single-player, the server runs locally (embedded).
+// In single-player, the server runs locally (embedded) and the client
+// predicts and reconciles against it exactly as it would against a remote
+// one, so both modes run the same code.
 // In multiplayer, inputs are also sent to an external server.
 type Client struct {
 	playerID  int
 	sessionID int
+	name      string
 
 	// Input state
-	keyState *input.KeyState
+	keys *input.IntentTracker
+	quit bool // The input system asked to quit
 
 	// Internal server (always runs locally for prediction)
-	server *server.Server
+	server  *server.Server
+	session *server.Session
+	scripts *scripting.Engine // The embedded server's level scripts
+
+	// Traffic this client would put on the wire: encoded inputs out, its
+	// session's snapshots in
+	net     server.NetStats
+	encoded []byte
+
+	// Prediction: the world the player sees runs ahead on local input and
+	// is reconciled with each authoritative state from the server
+	world       *game.World
+	predictions *PredictionBuffer
+	reconciler  *Reconciler
+	pending     *game.WorldState // Latest server state not yet reconciled
+	rollbacks   int
+
+	// Server states held back by SetFakeLag, oldest first
+	fakeLag int
+	delayed []game.WorldState
+
+	// Host and settings from the latest snapshot that carried them
+	settings protocol.HostSettings
+
+	// Players' vote from the latest snapshot that carried one, and chat
+	// lines for this client
+	vote   protocol.VoteStatus
+	onChat func(msg protocol.Chat)
 
 	// External server connection (nil for single-player)
 	// TODO: externalConn *network.Connection
 
 	// State for multiplayer sync
 	lastSentTick uint64
+
+	// Pause menu. Single-player also freezes the embedded server's tick
+	// loop; multiplayer only shows the menu.
+	paused      bool
+	multiplayer bool
+
+	// Keys steer something else, such as a free camera; see SetDetached
+	detached bool
 }
 
 // New creates a new client.
 func New(playerID int) *Client {
+	predictions := NewPredictionBuffer(predictionTicks)
+	reconciler := NewReconciler(predictions)
+	reconciler.SetPlayerID(playerID)
 	return &Client{
-		playerID:  playerID,
-		sessionID: 1, // Single-player uses session 1
-		keyState:  input.NewKeyState(),
+		playerID:    playerID,
+		sessionID:   1, // Single-player uses session 1
+		name:        "Player",
+		keys:        input.NewIntentTracker(),
+		predictions: predictions,
+		reconciler:  reconciler,
 	}
 }
 
+// NewEmbedded creates a single-player client with an embedded server, both
+// playing the level. The server is driven by Update, not by its own tick
+// loop. The level's scripts run in both worlds, so predictions include
+// them; an error means a script failed to load.
+func NewEmbedded(playerID int, name string, level *game.Level) (*Client, error) {
+	c := New(playerID)
+	c.name = name
+
+	authoritative := game.NewWorld()
+	authoritative.DialogueHold = true // Nobody else plays on while the player reads
+	c.scripts = scripting.New(authoritative)
+	authoritative.LoadLevel(level)
+	cfg := server.DefaultConfig()
+	cfg.HostControls = true // The local player hosts
+	cfg.AFK.After = 0       // Nobody waits on a lone player
+	srv := server.New(cfg)
+	srv.SetWorld(authoritative)
+	c.SetServer(srv)
+
+	// The predicted world starts identical: same level, same spawn order,
+	// so network IDs line up. Scripts spawn last, as after a reset.
+	spawn := level.PlayerSpawn(0)
+	authoritative.SpawnPlayer(playerID, name, spawn.X, spawn.Y)
+	if err := c.scripts.LoadLevel(level); err != nil {
+		return nil, err
+	}
+
+	c.world = game.NewWorld()
+	c.world.DialogueHold = true
+	predicted := scripting.New(c.world) // Reports nothing; the server's engine does
+	c.world.LoadLevel(level)
+	if p, ok := c.rosterEntry(); ok {
+		c.world.SetPlayerColor(playerID, p.Color)
+	}
+	c.world.SpawnPlayer(playerID, name, spawn.X, spawn.Y)
+	if err := predicted.LoadLevel(level); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
 // SetServer sets the internal server.
 func (c *Client) SetServer(s *server.Server) {
 	c.server = s
 	// Register ourselves as a session
-	c.server.AddSession(c.sessionID, c.playerID, "Player")
+	c.session = c.server.AddSession(c.sessionID, c.playerID, c.name)
+	c.server.SetStateUpdateCallback(func(state game.WorldState) {
+		if c.fakeLag > 0 {
+			c.delayed = append(c.delayed, state)
+			return
+		}
+		c.pending = &state
+	})
+	c.server.SetSnapshotCallback(func(sessionID int, snap protocol.StateSnapshot) {
+		if sessionID != c.sessionID {
+			return
+		}
+		c.net.PacketsIn++
+		c.net.BytesIn += uint64(snap.Size())
+		c.net.AddSnapshot(&snap)
+		c.session.AckSnapshot(snap.Tick)
+		if snap.Settings != nil {
+			c.applySettings(*snap.Settings)
+		}
+		if snap.Vote != nil {
+			c.vote = *snap.Vote
+		}
+	})
+	c.server.SetChatCallback(func(sessionID int, msg protocol.Chat) {
+		if sessionID == c.sessionID && c.onChat != nil {
+			c.onChat(msg)
+		}
+	})
+	if c.world == nil {
+		c.world = s.World()
+	}
 }
 
-// ProcessInput handles input events and sends them to the server.
-func (c *Client) ProcessInput(events []input.KeyEvent) {
-	// Update local key state
-	for _, ev := range events {
-		switch ev.Type {
-		case input.KeyDown:
-			c.keyState.SetPressed(ev.Key, true)
-		case input.KeyUp:
-			c.keyState.SetPressed(ev.Key, false)
+// rosterEntry returns this client's player in the server's roster
+func (c *Client) rosterEntry() (protocol.PlayerInfo, bool) {
+	for _, p := range c.server.Roster() {
+		if p.PlayerID == c.playerID {
+			return p, true
 		}
 	}
+	return protocol.PlayerInfo{}, false
+}
+
+// ReadInput polls an input system and processes its events, which it
+// returns for UI keys the caller handles itself.
+func (c *Client) ReadInput(sys input.System) []input.KeyEvent {
+	events := sys.Poll()
+	c.ProcessInput(events)
+	c.quit = c.quit || sys.ShouldQuit()
+	return events
+}
+
+// SetIntentTracker replaces how key events become held intents, e.g. with
+// input.NewTerminalTracker for a frontend without key-up events. The
+// default holds keys from key-down to key-up.
+func (c *Client) SetIntentTracker(t *input.IntentTracker) {
+	c.keys = t
+}
+
+// ProcessInput updates the held keys from input events. The intents are
+// sent with the next tick.
+func (c *Client) ProcessInput(events []input.KeyEvent) {
+	c.keys.Process(events)
+}
+
+// Intents returns what the player's input asks for this tick. A player in
+// the menu, or detached from their keys, stands still; the menu only
+// matters in multiplayer where the game runs on.
+func (c *Client) Intents() protocol.Intent {
+	if c.paused || c.detached {
+		return protocol.IntentNone
+	}
+	return c.keys.Intents()
+}
 
-	// Convert to intents and send to server
-	intents := c.keyState.ToIntents()
-	tick := c.server.Tick()
+// Update runs as many ticks as the embedded server's time controls allow
+// (none while paused) and returns how many ran.
+func (c *Client) Update() int {
+	n := c.server.TimeControl().Advance()
+	for range n {
+		c.Step()
+	}
+	return n
+}
 
+// Step runs one tick: predict it locally, send the input, advance the
+// embedded server, and reconcile with any state it broadcast.
+func (c *Client) Step() {
+	intents := c.Intents()
+	c.keys.Tick()
 	frame := protocol.InputFrame{
-		Tick:    tick + 1, // Input is for the next tick
+		Tick:    c.world.Tick + 1, // Input is for the next tick
 		Intents: intents,
 	}
 
-	// Send to internal server (local prediction)
+	// Send to internal server
+	tracker := c.server.Allocs()
+	c.predictions.RecordInput(frame)
+	span := tracker.Begin(allocs.Network)
 	c.server.QueueInput(c.sessionID, frame)
+	c.lastSentTick = frame.Tick
+	c.encoded = protocol.AppendInputFrame(c.encoded[:0], frame)
+	c.net.PacketsOut++
+	c.net.BytesOut += uint64(len(c.encoded))
+	span.End()
 
 	// TODO: Also send to external server for multiplayer
 	// if c.externalConn != nil {
 	//     c.externalConn.Send(frame)
 	// }
+
+	if c.world != c.server.World() {
+		span := tracker.Begin(allocs.Simulation)
+		c.world.SetPlayerIntent(c.playerID, intents)
+		c.world.Update()
+		state := c.world.Snapshot()
+		c.predictions.RecordState(ConvertToWorldSnapshot(&state))
+		span.End()
+	}
+
+	c.server.Step()
+	for len(c.delayed) > 0 && c.delayed[0].Tick+uint64(c.fakeLag) <= c.server.Tick() {
+		state := c.delayed[0]
+		c.pending = &state
+		c.delayed = c.delayed[1:]
+	}
+	if c.pending != nil && c.world != c.server.World() {
+		span := tracker.Begin(allocs.Simulation)
+		if result := c.reconciler.Reconcile(c.world, c.pending, c.world.Tick); result.RolledBack {
+			c.rollbacks++
+		}
+		span.End()
+	}
+	c.pending = nil
+}
+
+// Restart restarts the level on the embedded server and the predicted
+// world together.
+func (c *Client) Restart() {
+	c.LoadLevel(nil)
 }
 
-// World returns the current game world state (for rendering).
+// LoadLevel switches the embedded server and the predicted world to
+// another level together, such as the next one of a campaign; nil
+// restarts the current one
+func (c *Client) LoadLevel(level *game.Level) {
+	c.server.ResetLevel(level)
+	if c.world != c.server.World() {
+		c.world.Reset(level)
+	}
+	c.reconciler.Reset()
+	c.pending, c.delayed = nil, nil
+}
+
+// HostCommand sends a host command to the server and returns its answer. A
+// restart also restarts the predicted world, as Restart does.
+func (c *Client) HostCommand(cmd protocol.HostCommand) protocol.HostResult {
+	c.encoded = protocol.AppendHostCommand(c.encoded[:0], cmd)
+	c.net.PacketsOut++
+	c.net.BytesOut += uint64(len(c.encoded))
+
+	result := c.server.HostCommand(c.sessionID, cmd)
+	if result.OK {
+		// The host changes settings from the pause menu, where the
+		// embedded server sends no snapshots
+		c.applySettings(c.server.Settings())
+	}
+	if result.OK && cmd.Action == protocol.HostRestart {
+		if c.world != c.server.World() {
+			c.world.Reset(nil)
+		}
+		c.reconciler.Reset()
+		c.pending, c.delayed = nil, nil
+	}
+	return result
+}
+
+// Settings returns the host and the settings the host controls, as of the
+// latest snapshot
+func (c *Client) Settings() protocol.HostSettings {
+	return c.settings
+}
+
+// IsHost reports whether this client's player is the host
+func (c *Client) IsHost() bool {
+	return c.settings.Host == c.playerID
+}
+
+// applySettings keeps the predicted world's rules in step with the
+// server's, so predictions don't roll back over them
+func (c *Client) applySettings(settings protocol.HostSettings) {
+	c.settings = settings
+	if c.world != c.server.World() {
+		c.world.FriendlyFire = settings.FriendlyFire
+		c.world.Difficulty = game.Difficulty(settings.Difficulty)
+	}
+}
+
+// Chat sends a chat line or command, such as /votekick, to the server
+func (c *Client) Chat(text string) {
+	c.encoded = protocol.AppendChat(c.encoded[:0], protocol.Chat{Text: text})
+	c.net.PacketsOut++
+	c.net.BytesOut += uint64(len(c.encoded))
+	c.server.Chat(c.sessionID, text)
+}
+
+// CastVote votes yes or no on the players' vote
+func (c *Client) CastVote(yes bool) {
+	if yes {
+		c.Chat("/yes")
+	} else {
+		c.Chat("/no")
+	}
+}
+
+// Vote returns the players' vote as of the latest snapshot; Kind is
+// protocol.VoteNone when there is none
+func (c *Client) Vote() protocol.VoteStatus {
+	return c.vote
+}
+
+// SetChatCallback sets the function shown the chat lines the server sends
+// this client: other players' messages and the server's replies
+func (c *Client) SetChatCallback(cb func(msg protocol.Chat)) {
+	c.onChat = cb
+}
+
+// NetStats returns the traffic this client has sent and received. With the
+// embedded server nothing goes over a network; the counts are what would.
+func (c *Client) NetStats() server.NetStats {
+	return c.net
+}
+
+// SetFakeLag delays the server's states by a number of ticks, at most
+// MaxFakeLag, as if they came over a slow network, to test prediction and
+// reconciling. Zero turns it off.
+func (c *Client) SetFakeLag(ticks int) {
+	c.fakeLag = min(max(ticks, 0), MaxFakeLag)
+}
+
+// FakeLag returns the delay set by SetFakeLag, in ticks
+func (c *Client) FakeLag() int {
+	return c.fakeLag
+}
+
+// Rollbacks returns how many reconciliations had to roll back and replay
+func (c *Client) Rollbacks() int {
+	return c.rollbacks
+}
+
+// World returns the world to render: the predicted one.
 func (c *Client) World() *game.World {
-	return c.server.World()
+	return c.world
+}
+
+// Server returns the embedded server.
+func (c *Client) Server() *server.Server {
+	return c.server
+}
+
+// Scripts returns the embedded server's script engine, for routing script
+// errors and output.
+func (c *Client) Scripts() *scripting.Engine {
+	return c.scripts
+}
+
+// TimeControl returns the embedded server's pause/step/speed controls,
+// which also pace the client's prediction.
+func (c *Client) TimeControl() *server.TimeControl {
+	return c.server.TimeControl()
+}
+
+// SetMultiplayer marks the game as shared with other players, where pausing
+// only opens the menu.
+func (c *Client) SetMultiplayer(multiplayer bool) {
+	c.multiplayer = multiplayer
+}
+
+// Multiplayer reports whether the game is shared with other players.
+func (c *Client) Multiplayer() bool {
+	return c.multiplayer
+}
+
+// TogglePause opens or closes the pause menu and returns whether it is open.
+// In single-player this also pauses the embedded server's simulation.
+func (c *Client) TogglePause() bool {
+	c.paused = !c.paused
+	if c.server != nil && !c.multiplayer {
+		if c.paused {
+			c.server.TimeControl().Pause()
+		} else {
+			c.server.TimeControl().Resume()
+		}
+	}
+	return c.paused
+}
+
+// Paused reports whether the pause menu is open.
+func (c *Client) Paused() bool {
+	return c.paused
+}
+
+// SetDetached detaches the player from the keys, or reattaches them: while
+// detached the player stands still, as in the menu, and the held keys
+// (IsPressed) steer something else, such as the GUI's free camera.
+func (c *Client) SetDetached(detached bool) {
+	c.detached = detached
+}
+
+// Detached reports whether the player is detached from the keys
+func (c *Client) Detached() bool {
+	return c.detached
+}
+
+// IsPressed reports whether a key is held.
+func (c *Client) IsPressed(k input.GameKey) bool {
+	return c.keys.IsPressed(k)
 }
 
 // ShouldQuit checks if quit was requested.
 func (c *Client) ShouldQuit() bool {
-	return c.keyState.IsPressed(input.KeyQuit)
+	return c.quit || c.keys.IsPressed(input.KeyQuit)
 }
@@ -0,0 +1,215 @@
+package client
+
+import (
+	"os"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/input"
+)
+
+// TestReadInput tests that a client driven by a scripted input system
+// moves its player and quits when asked.
+func TestReadInput(t *testing.T) {
+	cl, err := NewEmbedded(1, "Player", game.NewDemoLevel(80, 45))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := input.NewQueue()
+	for range 10 {
+		cl.ReadInput(keys)
+		cl.Step()
+	}
+	startX, _, _ := cl.World().PlayerPosition(1)
+
+	keys.Press(input.KeyRight)
+	for range 10 {
+		cl.ReadInput(keys)
+		cl.Step()
+	}
+	if x, _, _ := cl.World().PlayerPosition(1); x <= startX {
+		t.Errorf("Player at x %v after holding right, want past %v", x, startX)
+	}
+
+	keys.Release(input.KeyRight)
+	if events := cl.ReadInput(keys); len(events) != 1 || cl.IsPressed(input.KeyRight) {
+		t.Errorf("Got events %v, want the release only", events)
+	}
+	if cl.ShouldQuit() {
+		t.Fatal("Should not quit before asked")
+	}
+	keys.Quit()
+	cl.ReadInput(keys)
+	if !cl.ShouldQuit() {
+		t.Error("Should quit once the input system asks")
+	}
+}
+
+// attackState returns the player's attack state in the authoritative world
+func attackState(t *testing.T, cl *Client) game.AttackState {
+	t.Helper()
+	state := cl.Server().World().Snapshot()
+	for _, es := range state.Entities {
+		if es.HasPlayer && es.Player.ID == 1 {
+			return es.Attack
+		}
+	}
+	t.Fatal("Player not found")
+	return game.AttackState{}
+}
+
+// TestAttackCharge tests that holding attack charges a fist the same way
+// with real key-ups (Gio) and with terminal auto-repeat presses, and that
+// releasing throws it.
+func TestAttackCharge(t *testing.T) {
+	const held = 90 // Ticks attack is held down
+
+	tests := []struct {
+		name    string
+		tracker *input.IntentTracker
+		press   func(keys *input.Queue, tick int) // Input before each tick
+		release int                               // Last tick still charging
+	}{
+		{"gio", input.NewIntentTracker(), func(keys *input.Queue, tick int) {
+			switch tick {
+			case 0:
+				keys.Press(input.KeyAttack)
+			case held:
+				keys.Release(input.KeyAttack)
+			}
+		}, held - 1},
+		// One press, then auto-repeat from 500 ms at 30 Hz; never a key-up
+		{"terminal", input.NewTerminalTracker(), func(keys *input.Queue, tick int) {
+			if tick == 0 || (tick >= 30 && tick < held && tick%2 == 0) {
+				keys.Press(input.KeyAttack)
+			}
+		}, held - 2 + input.TerminalHold - 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cl, err := NewEmbedded(1, "Player", game.NewDemoLevel(80, 45))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cl.SetIntentTracker(tc.tracker)
+			keys := input.NewQueue()
+
+			charge := 0
+			for tick := range tc.release + 2 {
+				tc.press(keys, tick)
+				cl.ReadInput(keys)
+				cl.Step()
+
+				attack := attackState(t, cl)
+				if tick <= tc.release {
+					if !attack.Charging {
+						t.Fatalf("Charge broke off at tick %d", tick)
+					}
+					charge = attack.ChargeTicks
+				} else if attack.Charging || !attack.Attacking {
+					t.Fatalf("Fist not thrown at tick %d", tick)
+				}
+			}
+			if charge < held-1 {
+				t.Errorf("Charged %d ticks, want at least %d", charge, held-1)
+			}
+		})
+	}
+}
+
+// TestEmbeddedScripts tests that the bundled demo level's scripts run in
+// both the embedded server and the predicted world, so the bat's patrol
+// doesn't roll predictions back, and that a restart reloads them in both.
+func TestEmbeddedScripts(t *testing.T) {
+	level, err := game.ReadLevelFile(os.DirFS("../../assets/levels"), "demo.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl, err := NewEmbedded(1, "Player", level)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errs []error
+	cl.Scripts().OnError = func(err error) { errs = append(errs, err) }
+
+	keys := input.NewQueue()
+	keys.Press(input.KeyRight)
+	for range 120 {
+		cl.ReadInput(keys)
+		cl.Step()
+	}
+	cl.Restart()
+	for range 60 {
+		cl.ReadInput(keys)
+		cl.Step()
+	}
+
+	server := cl.Server().World().Snapshot()
+	predicted := cl.World().Snapshot()
+	if len(server.Scripts) == 0 || string(predicted.Scripts["demo.star"]) != string(server.Scripts["demo.star"]) {
+		t.Errorf("Script state %s, want the server's %s", predicted.Scripts, server.Scripts)
+	}
+	if n := cl.Rollbacks(); n != 0 {
+		t.Errorf("%d rollbacks, want predictions to include the scripts", n)
+	}
+	if len(errs) != 0 {
+		t.Errorf("Script errors: %v", errs)
+	}
+}
+
+// TestEmbeddedPrediction tests that the predicted world keeps in step with
+// the embedded server: same tick and checksum without rollbacks, also
+// after a restart.
+func TestEmbeddedPrediction(t *testing.T) {
+	level := game.NewDemoLevel(80, 45)
+	cl, err := NewEmbedded(1, "Player", level)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := input.NewQueue()
+	play := func(ticks int) {
+		for tick := range ticks {
+			switch tick % 90 {
+			case 0:
+				keys.Press(input.KeyRight)
+			case 20:
+				keys.Press(input.KeyJump)
+			case 30:
+				keys.Release(input.KeyJump)
+				keys.Press(input.KeyAttack)
+			case 60:
+				keys.Release(input.KeyAttack)
+				keys.Release(input.KeyRight)
+			}
+			cl.ReadInput(keys)
+			cl.Step()
+		}
+	}
+	check := func(when string) {
+		t.Helper()
+		server := cl.Server().World().Snapshot()
+		predicted := cl.World().Snapshot()
+		if predicted.Tick != server.Tick || predicted.Checksum != server.Checksum {
+			t.Errorf("%s: predicted checksum %08x at tick %d, want the server's %08x at tick %d",
+				when, predicted.Checksum, predicted.Tick, server.Checksum, server.Tick)
+		}
+		if n := cl.Rollbacks(); n != 0 {
+			t.Errorf("%s: %d rollbacks, want none", when, n)
+		}
+	}
+
+	play(300)
+	startX := level.PlayerSpawn(0).X
+	if x, _, _ := cl.World().PlayerPosition(1); x <= startX {
+		t.Fatalf("Player at x %v after playing, want past the spawn at %v", x, startX)
+	}
+	check("after playing")
+
+	cl.Restart()
+	if x, _, _ := cl.World().PlayerPosition(1); x != startX {
+		t.Errorf("Player at x %v after the restart, want the spawn at %v", x, startX)
+	}
+	check("after the restart")
+	play(120)
+	check("playing after the restart")
+}
@@ -0,0 +1,261 @@
+package client
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+)
+
+// MaxFakeLag is the longest delay SetFakeLag takes, in ticks: well inside
+// the predictions kept for reconciling, so a late state still finds the
+// tick it answers
+const MaxFakeLag = 60
+
+// ConsoleCommand is a developer console command handler. It receives the
+// words after the command name and returns text to print.
+type ConsoleCommand struct {
+	Usage string
+	Help  string
+	Cheat bool // Changes the game, so refused where CheatsAllowed is false
+	Run   func(args []string) (string, error)
+}
+
+// Console is the developer console: commands that spawn enemies, move the
+// local player and tune the simulation of a running game. Like
+// server.Admin it parses one command per line; the GUI opens it with the
+// tilde key in dev mode.
+type Console struct {
+	client   *Client
+	commands map[string]ConsoleCommand
+}
+
+// NewConsole creates a console for the client with the built-in commands
+func NewConsole(c *Client) *Console {
+	con := &Console{client: c, commands: make(map[string]ConsoleCommand)}
+
+	con.Register("help", ConsoleCommand{Help: "list commands", Run: con.help})
+	con.Register("spawn", ConsoleCommand{Usage: "<enemy> [x y]", Help: "spawn an enemy, by default ahead of you", Cheat: true, Run: con.spawn})
+	con.Register("give", ConsoleCommand{Usage: "<orbs [n]|health|key color>", Help: "give orbs, full health or a key", Cheat: true, Run: con.give})
+	con.Register("tp", ConsoleCommand{Usage: "<x> <y>", Help: "teleport to a tile position", Cheat: true, Run: con.teleport})
+	con.Register("noclip", ConsoleCommand{Usage: "[on|off]", Help: "toggle flying through tiles", Cheat: true, Run: con.noclip})
+	con.Register("setspeed", ConsoleCommand{Usage: "<scale>", Help: "set time scale, 0.25 to 4", Cheat: true, Run: con.setSpeed})
+	con.Register("checksum", ConsoleCommand{Help: "show the server's and the predicted world's checksums", Run: con.checksum})
+	con.Register("net_fakelag", ConsoleCommand{Usage: "<ms>", Help: "delay server states by ms", Run: con.fakeLag})
+
+	return con
+}
+
+// Register adds or replaces a command
+func (con *Console) Register(name string, cmd ConsoleCommand) {
+	con.commands[name] = cmd
+}
+
+// Exec runs one console line
+func (con *Console) Exec(line string) (string, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	name := strings.ToLower(fields[0])
+	cmd, ok := con.commands[name]
+	if !ok {
+		return "", fmt.Errorf("unknown command %q (try help)", fields[0])
+	}
+	if cmd.Cheat && !con.client.CheatsAllowed() {
+		return "", fmt.Errorf("%s: not allowed in a competitive match", name)
+	}
+	return cmd.Run(fields[1:])
+}
+
+// CheatsAllowed reports whether the developer console may change the game:
+// always in single-player, and in multiplayer unless the match is of a
+// competitive mode
+func (c *Client) CheatsAllowed() bool {
+	if !c.multiplayer {
+		return true
+	}
+	status, ok := c.server.MatchStatus()
+	return !ok || !status.Competitive
+}
+
+// worlds returns the server's world and, if it is another one, the
+// predicted world, for changes that must not wait for reconciling
+func (con *Console) worlds() []*game.World {
+	c := con.client
+	if c.world == c.server.World() {
+		return []*game.World{c.world}
+	}
+	return []*game.World{c.server.World(), c.world}
+}
+
+func (con *Console) help([]string) (string, error) {
+	names := make([]string, 0, len(con.commands))
+	for name := range con.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		cmd := con.commands[name]
+		fmt.Fprintf(&b, "%-28s %s\n", strings.TrimSpace(name+" "+cmd.Usage), cmd.Help)
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// position parses a tile position from two arguments
+func position(cmd string, args []string) (x, y float64, err error) {
+	if len(args) != 2 {
+		return 0, 0, fmt.Errorf("%s: want x and y", cmd)
+	}
+	x, errX := strconv.ParseFloat(args[0], 64)
+	y, errY := strconv.ParseFloat(args[1], 64)
+	if errX != nil || errY != nil {
+		return 0, 0, fmt.Errorf("%s: invalid position %s %s", cmd, args[0], args[1])
+	}
+	return x, y, nil
+}
+
+func (con *Console) spawn(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("spawn: which enemy? (%s)", strings.Join(game.EnemyTypes, ", "))
+	}
+	enemy := strings.ToLower(args[0])
+	if !slices.Contains(game.EnemyTypes, enemy) {
+		return "", fmt.Errorf("spawn: unknown enemy %q (%s)", args[0], strings.Join(game.EnemyTypes, ", "))
+	}
+	w := con.client.server.World()
+	x, y, ok := w.PlayerPosition(con.client.playerID)
+	x, y = x+3, y-1
+	switch {
+	case len(args) > 1:
+		var err error
+		if x, y, err = position("spawn", args[1:]); err != nil {
+			return "", err
+		}
+	case !ok:
+		return "", fmt.Errorf("spawn: no player to spawn ahead of; give x and y")
+	}
+	// The predicted world gets it at the next reconcile, as Restore
+	// respawns entities it lacks
+	w.SpawnEnemy(enemy, x, y)
+	return fmt.Sprintf("spawned %s at %.1f, %.1f", enemy, x, y), nil
+}
+
+func (con *Console) give(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("give: what? (orbs, health, key)")
+	}
+	c := con.client
+	switch strings.ToLower(args[0]) {
+	case "orbs", "orb":
+		n := 1
+		if len(args) > 1 {
+			v, err := strconv.Atoi(args[1])
+			if err != nil || v < 1 {
+				return "", fmt.Errorf("give: invalid orb count %q", args[1])
+			}
+			n = v
+		}
+		c.server.World().CollectOrb(c.playerID, n)
+		return fmt.Sprintf("gave %d orb(s)", n), nil
+	case "health":
+		for _, w := range con.worlds() {
+			if !w.HealPlayer(c.playerID) {
+				return "", fmt.Errorf("give: no player to heal")
+			}
+		}
+		return "health refilled", nil
+	case "key":
+		if len(args) < 2 {
+			return "", fmt.Errorf("give: which key color?")
+		}
+		color := strings.ToLower(args[1])
+		for _, w := range con.worlds() {
+			if err := w.GiveKey(color); err != nil {
+				return "", fmt.Errorf("give: %w", err)
+			}
+		}
+		return "gave the " + color + " key", nil
+	}
+	return "", fmt.Errorf("give: unknown item %q; there are no powerups, only orbs, health and keys", args[0])
+}
+
+func (con *Console) teleport(args []string) (string, error) {
+	x, y, err := position("tp", args)
+	if err != nil {
+		return "", err
+	}
+	for _, w := range con.worlds() {
+		if !w.MovePlayer(con.client.playerID, x, y) {
+			return "", fmt.Errorf("tp: no player to move")
+		}
+	}
+	return fmt.Sprintf("moved to %.1f, %.1f", x, y), nil
+}
+
+func (con *Console) noclip(args []string) (string, error) {
+	c := con.client
+	on := !c.world.Noclip(c.playerID)
+	if len(args) > 0 {
+		switch strings.ToLower(args[0]) {
+		case "on":
+			on = true
+		case "off":
+			on = false
+		default:
+			return "", fmt.Errorf("noclip: want on or off, not %q", args[0])
+		}
+	}
+	for _, w := range con.worlds() {
+		w.SetNoclip(c.playerID, on)
+	}
+	if on {
+		return "noclip on", nil
+	}
+	return "noclip off", nil
+}
+
+func (con *Console) setSpeed(args []string) (string, error) {
+	tc := con.client.TimeControl()
+	if len(args) == 0 {
+		return tc.String(), nil
+	}
+	scale, err := strconv.ParseFloat(strings.TrimPrefix(args[0], "x"), 64)
+	if err != nil {
+		return "", fmt.Errorf("setspeed: invalid scale %q", args[0])
+	}
+	if err := tc.SetScale(scale); err != nil {
+		return "", err
+	}
+	return tc.String(), nil
+}
+
+func (con *Console) checksum([]string) (string, error) {
+	c := con.client
+	authoritative := c.server.World().Snapshot()
+	predicted := c.world.Snapshot()
+	return fmt.Sprintf("server tick %d checksum %08x\npredicted tick %d checksum %08x",
+		authoritative.Tick, authoritative.Checksum, predicted.Tick, predicted.Checksum), nil
+}
+
+func (con *Console) fakeLag(args []string) (string, error) {
+	const tickMillis = 1000 / 60.0
+	c := con.client
+	if len(args) > 0 {
+		ms, err := strconv.Atoi(strings.TrimSuffix(args[0], "ms"))
+		if err != nil || ms < 0 {
+			return "", fmt.Errorf("net_fakelag: invalid delay %q", args[0])
+		}
+		ticks := int(float64(ms)/tickMillis + 0.5)
+		if ticks > MaxFakeLag {
+			return "", fmt.Errorf("net_fakelag: at most %d ms", int(MaxFakeLag*tickMillis))
+		}
+		c.SetFakeLag(ticks)
+	}
+	return fmt.Sprintf("fake lag %d ticks (%.0f ms)", c.FakeLag(), float64(c.FakeLag())*tickMillis), nil
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/input"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+	"github.com/andersfylling/rayman-slides/internal/server"
+)
+
+// TestConsole tests that console commands change both the server's and
+// the predicted world, that fake lag delays reconciling, that a detached
+// player stands still, and that cheats are refused in competitive
+// multiplayer.
+func TestConsole(t *testing.T) {
+	cl, err := NewEmbedded(1, "Player", game.NewDemoLevel(80, 45))
+	if err != nil {
+		t.Fatal(err)
+	}
+	con := NewConsole(cl)
+	exec := func(line string) string {
+		t.Helper()
+		out, err := con.Exec(line)
+		if err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+		return out
+	}
+
+	exec("tp 10 5")
+	for _, w := range []*game.World{cl.World(), cl.Server().World()} {
+		if x, y, _ := w.PlayerPosition(1); x != 10 || y != 5 {
+			t.Errorf("Player at (%v, %v) after tp, want (10, 5)", x, y)
+		}
+	}
+	exec("noclip")
+	if !cl.World().Noclip(1) || !cl.Server().World().Noclip(1) {
+		t.Error("noclip should be on in both worlds")
+	}
+	for range 30 {
+		cl.Step()
+	}
+	if _, y, _ := cl.World().PlayerPosition(1); y != 5 {
+		t.Errorf("Noclip player fell to y %v", y)
+	}
+
+	bats := len(cl.Server().World().EntitiesWithSprite("bat"))
+	exec("spawn bat")
+	if n := len(cl.Server().World().EntitiesWithSprite("bat")); n != bats+1 {
+		t.Errorf("%d bats after spawn, want %d", n, bats+1)
+	}
+	if _, err := con.Exec("spawn dragon"); err == nil {
+		t.Error("An unknown enemy should be refused")
+	}
+	if _, err := con.Exec("give powerup"); err == nil {
+		t.Error("An unknown item should be refused")
+	}
+
+	if out := exec("net_fakelag 100"); cl.FakeLag() != 6 || !strings.HasPrefix(out, "fake lag 6 ticks") {
+		t.Errorf("net_fakelag 100 = %q, lag %d ticks", out, cl.FakeLag())
+	}
+	if _, err := con.Exec("net_fakelag 5000"); err == nil {
+		t.Error("A lag past MaxFakeLag should be refused")
+	}
+	for range 30 {
+		cl.Step()
+	}
+	if out := exec("checksum"); strings.Count(out, "checksum") != 2 {
+		t.Errorf("checksum = %q", out)
+	}
+
+	// Detached, as for the free camera, keys are held but not sent
+	keys := input.NewQueue()
+	keys.Press(input.KeyRight)
+	cl.ReadInput(keys)
+	cl.SetDetached(true)
+	if cl.Intents() != protocol.IntentNone || !cl.IsPressed(input.KeyRight) {
+		t.Errorf("Detached client sends %v, holding right %t", cl.Intents(), cl.IsPressed(input.KeyRight))
+	}
+	cl.SetDetached(false)
+
+	cl.SetMultiplayer(true)
+	race, err := server.NewGameMode("race")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl.Server().SetGameMode(race)
+	if cl.CheatsAllowed() {
+		t.Fatal("Cheats should be off in a competitive multiplayer match")
+	}
+	if _, err := con.Exec("noclip off"); err == nil {
+		t.Error("noclip should be refused in a race")
+	}
+	exec("checksum")
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// InputSender builds redundant input messages and tracks input loss from
+// server acknowledgements.
+type InputSender struct {
+	recent     []protocol.InputFrame // Last N frames, oldest first
+	redundancy int
+
+	lastAck     protocol.InputAck
+	ackedTicks  uint64 // Ticks covered by acks in the window
+	missedTicks uint64 // Of those, simulated without input
+}
+
+// NewInputSender creates a sender repeating the last redundancy frames
+func NewInputSender(redundancy int) *InputSender {
+	if redundancy < 1 {
+		redundancy = protocol.DefaultInputRedundancy
+	}
+	return &InputSender{
+		recent:     make([]protocol.InputFrame, 0, redundancy),
+		redundancy: redundancy,
+	}
+}
+
+// Next records a new frame and returns the message to send for it.
+// The returned message shares storage with the sender; encode it before
+// calling Next again.
+func (s *InputSender) Next(frame protocol.InputFrame) protocol.InputMessage {
+	if len(s.recent) == s.redundancy {
+		copy(s.recent, s.recent[1:])
+		s.recent = s.recent[:len(s.recent)-1]
+	}
+	s.recent = append(s.recent, frame)
+	return protocol.InputMessage{Frames: s.recent}
+}
+
+// OnAck processes an input acknowledgement from the server
+func (s *InputSender) OnAck(ack protocol.InputAck) {
+	if ack.LastProcessedTick <= s.lastAck.LastProcessedTick {
+		return // Stale or duplicate ack
+	}
+	if s.lastAck.LastProcessedTick > 0 {
+		s.ackedTicks += ack.LastProcessedTick - s.lastAck.LastProcessedTick
+		s.missedTicks += uint64(ack.Missed - s.lastAck.Missed)
+	}
+	s.lastAck = ack
+}
+
+// LastAck returns the most recent acknowledgement
+func (s *InputSender) LastAck() protocol.InputAck {
+	return s.lastAck
+}
+
+// LossRate returns the share of acked ticks the server simulated without
+// a fresh input, in [0, 1]
+func (s *InputSender) LossRate() float64 {
+	if s.ackedTicks == 0 {
+		return 0
+	}
+	return float64(s.missedTicks) / float64(s.ackedTicks+s.missedTicks)
+}
+
+// ResetStats starts a new loss measurement window
+func (s *InputSender) ResetStats() {
+	s.ackedTicks = 0
+	s.missedTicks = 0
+}
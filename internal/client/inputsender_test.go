@@ -0,0 +1,53 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// TestInputSenderRedundancy tests that each message repeats the last frames,
+// oldest first.
+func TestInputSenderRedundancy(t *testing.T) {
+	s := NewInputSender(3)
+	var msg protocol.InputMessage
+	for tick := uint64(1); tick <= 5; tick++ {
+		msg = s.Next(protocol.InputFrame{Tick: tick})
+	}
+	if len(msg.Frames) != 3 || msg.Frames[0].Tick != 3 || msg.Frames[2].Tick != 5 {
+		t.Errorf("Message carries %+v, want ticks 3 to 5", msg.Frames)
+	}
+	if got := NewInputSender(0).Next(protocol.InputFrame{}); cap(got.Frames) != protocol.DefaultInputRedundancy {
+		t.Errorf("Default sender keeps %d frames, want %d", cap(got.Frames), protocol.DefaultInputRedundancy)
+	}
+}
+
+// TestInputSenderLoss tests that the loss rate counts the ticks the server
+// simulated without input between acks, ignoring stale acks.
+func TestInputSenderLoss(t *testing.T) {
+	s := NewInputSender(4)
+	acks := []protocol.InputAck{
+		{LastProcessedTick: 10, Missed: 2}, // First ack only sets the start
+		{LastProcessedTick: 16, Missed: 2},
+		{LastProcessedTick: 12, Missed: 9}, // Stale, arrived late
+		{LastProcessedTick: 20, Missed: 4},
+	}
+	for _, ack := range acks {
+		s.OnAck(ack)
+	}
+	if got := s.LastAck(); got != acks[3] {
+		t.Errorf("LastAck = %+v, want %+v", got, acks[3])
+	}
+	if got, want := s.LossRate(), 2.0/12; got != want {
+		t.Errorf("LossRate = %v, want %v", got, want)
+	}
+
+	s.ResetStats()
+	if got := s.LossRate(); got != 0 {
+		t.Errorf("LossRate = %v after a reset, want 0", got)
+	}
+	s.OnAck(protocol.InputAck{LastProcessedTick: 30, Missed: 4})
+	if got := s.LossRate(); got != 0 {
+		t.Errorf("LossRate = %v without missed ticks, want 0", got)
+	}
+}
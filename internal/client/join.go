@@ -0,0 +1,82 @@
+package client
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/network"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// ErrLevelMismatch is returned when a join bundle has no level data and the
+// local level's hash differs from the server's
+var ErrLevelMismatch = errors.New("level differs from the server's")
+
+// NewHandshake returns a handshake for playing level, the client's copy of
+// the server's level (nil if it has none), so the server can tell whether
+// to send it. build is the client's release, which the server must match.
+func NewHandshake(playerName, build string, level *game.Level, physics game.PhysicsProfile) (protocol.Handshake, error) {
+	h := protocol.NewHandshake(playerName)
+	h.PhysicsHash = physics.Hash()
+	h.Build = build
+	if level == nil {
+		return h, nil
+	}
+	hash, err := game.LevelHash(level)
+	if err != nil {
+		return protocol.Handshake{}, err
+	}
+	h.LevelHash = hash
+	return h, nil
+}
+
+// CachedLevel returns a level transferred earlier, by hash
+func CachedLevel(cache *network.AssetCache, hash [32]byte) (*game.Level, bool) {
+	data, ok := cache.Get(hash)
+	if !ok {
+		return nil, false
+	}
+	level, err := game.DecodeLevel(data)
+	if err != nil {
+		return nil, false
+	}
+	return level, true
+}
+
+// JoinWorld builds the world a late joiner starts from: the bundle's level
+// (or local, if the hashes match) at the server's tick and level start. The
+// first snapshot after the bundle is full and replaces its entities.
+func JoinWorld(b protocol.JoinBundle, local *game.Level) (*game.World, error) {
+	level, err := bundleLevel(b, local)
+	if err != nil {
+		return nil, err
+	}
+	w := game.NewWorld()
+	w.Tick = b.Tick
+	w.LoadLevel(level)
+	w.SetLevelStart(b.LevelStart)
+	return w, nil
+}
+
+// bundleLevel returns the level a join bundle describes
+func bundleLevel(b protocol.JoinBundle, local *game.Level) (*game.Level, error) {
+	if len(b.LevelData) > 0 {
+		if sha256.Sum256(b.LevelData) != b.LevelHash {
+			return nil, fmt.Errorf("join: level %s: data doesn't match its hash", b.Level)
+		}
+		return game.DecodeLevel(b.LevelData)
+	}
+	if local == nil {
+		return nil, fmt.Errorf("join: level %s: %w", b.Level, ErrLevelMismatch)
+	}
+	hash, err := game.LevelHash(local)
+	if err != nil {
+		return nil, fmt.Errorf("join: %w", err)
+	}
+	if hash != b.LevelHash {
+		return nil, fmt.Errorf("join: level %s: %w", b.Level, ErrLevelMismatch)
+	}
+	return local, nil
+}
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"github.com/andersfylling/rayman-slides/internal/game"
 	"github.com/andersfylling/rayman-slides/internal/protocol"
 )
 
@@ -19,6 +20,8 @@ type WorldSnapshot struct {
 	Tick     uint64
 	Entities []EntitySnapshot
 	Checksum uint32 // Fast comparison hash
+
+	Components game.ComponentChecksums // Per component, to localize mismatches
 }
 
 // PredictionBuffer stores recent inputs and predicted states for reconciliation
@@ -1,7 +1,10 @@
 package client
 
 import (
+	"fmt"
+
 	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
 )
 
 // Reconciler handles comparing server state to client predictions
@@ -9,6 +12,7 @@ import (
 type Reconciler struct {
 	predictions *PredictionBuffer
 	tolerance   float64 // Position difference tolerance for matching
+	playerID    int     // Player whose inputs are replayed
 }
 
 // NewReconciler creates a reconciler with the given prediction buffer
@@ -16,14 +20,27 @@ func NewReconciler(predictions *PredictionBuffer) *Reconciler {
 	return &Reconciler{
 		predictions: predictions,
 		tolerance:   0.01, // Small tolerance for floating point comparison
+		playerID:    1,
 	}
 }
 
+// SetPlayerID sets the local player, whose inputs are replayed after a
+// rollback
+func (r *Reconciler) SetPlayerID(playerID int) {
+	r.playerID = playerID
+}
+
 // SetTolerance sets the position mismatch tolerance
 func (r *Reconciler) SetTolerance(tolerance float64) {
 	r.tolerance = tolerance
 }
 
+// Reset drops all predictions. Call it when a snapshot arrives with Reset
+// set: the entities the predictions refer to no longer exist.
+func (r *Reconciler) Reset() {
+	r.predictions.Clear()
+}
+
 // ReconcileResult contains information about a reconciliation attempt
 type ReconcileResult struct {
 	Reconciled     bool   // Whether reconciliation was performed
@@ -83,7 +100,7 @@ func (r *Reconciler) Reconcile(
 
 	// Step 3: Replay each input
 	for _, input := range inputs {
-		world.SetPlayerIntent(1, input.Intents) // TODO: proper player ID
+		world.SetPlayerIntent(r.playerID, input.Intents)
 		world.Update()
 	}
 
@@ -101,6 +118,12 @@ func (r *Reconciler) statesMatch(predicted *WorldSnapshot, server *game.WorldSta
 		if predicted.Checksum == server.Checksum {
 			return true
 		}
+		// Positions are compared within tolerance below; any other
+		// component that differs (velocity, health, attack...) is a
+		// misprediction even while positions still agree
+		if predicted.Components.Diff(&server.Components)&^protocol.CompPosition != 0 {
+			return false
+		}
 	}
 
 	// Detailed comparison
@@ -112,6 +135,10 @@ func (r *Reconciler) statesMatch(predicted *WorldSnapshot, server *game.WorldSta
 		pe := &predicted.Entities[i]
 		se := &server.Entities[i]
 
+		if pe.ID != se.ID {
+			return false
+		}
+
 		// Compare positions within tolerance
 		dx := pe.PositionX - se.Position.X
 		dy := pe.PositionY - se.Position.Y
@@ -145,6 +172,10 @@ func (r *Reconciler) describeMismatch(predicted *WorldSnapshot, server *game.Wor
 		pe := &predicted.Entities[i]
 		se := &server.Entities[i]
 
+		if pe.ID != se.ID {
+			return "entity set mismatch"
+		}
+
 		dx := pe.PositionX - se.Position.X
 		dy := pe.PositionY - se.Position.Y
 		if dx < 0 {
@@ -163,6 +194,9 @@ func (r *Reconciler) describeMismatch(predicted *WorldSnapshot, server *game.Wor
 		}
 	}
 
+	if diff := predicted.Components.Diff(&server.Components); diff != 0 {
+		return fmt.Sprintf("%s mismatch", diff)
+	}
 	return "checksum mismatch (detailed comparison passed)"
 }
 
@@ -170,13 +204,15 @@ func (r *Reconciler) describeMismatch(predicted *WorldSnapshot, server *game.Wor
 // for storing in the prediction buffer
 func ConvertToWorldSnapshot(state *game.WorldState) WorldSnapshot {
 	ws := WorldSnapshot{
-		Tick:     state.Tick,
-		Checksum: state.Checksum,
-		Entities: make([]EntitySnapshot, 0, len(state.Entities)),
+		Tick:       state.Tick,
+		Checksum:   state.Checksum,
+		Components: state.Components,
+		Entities:   make([]EntitySnapshot, 0, len(state.Entities)),
 	}
 
 	for _, es := range state.Entities {
 		ws.Entities = append(ws.Entities, EntitySnapshot{
+			ID:        es.ID,
 			PositionX: es.Position.X,
 			PositionY: es.Position.Y,
 			VelocityX: es.Velocity.X,
@@ -0,0 +1,224 @@
+package client
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+
+	"github.com/andersfylling/rayman-slides/internal/network"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// Remote is a connection to a server.Remotes on another machine, after the
+// server accepted the handshake
+type Remote struct {
+	conn   network.Connection
+	Reply  protocol.HandshakeReply
+	Bundle protocol.JoinBundle
+	Assets map[protocol.TransferKind][32]byte // Hashes of the transferred assets, in the cache
+
+	cache     *network.AssetCache
+	downloads map[[32]byte]*network.Download
+}
+
+// Dial connects to the server at addr and joins it with h: over TLS if
+// tlsConfig is set, through the room key handshake if psk is set (see
+// lobby.KeyPSK; the server must use the same key), then the protocol
+// handshake. A rejected join returns the server's reason.
+//
+// Assets the server pushes are kept in cache. If h's level differs from
+// the server's, Dial waits for the level transfer, so CachedLevel has it
+// for Bundle.LevelHash. Without a cache transfers are ignored, and the
+// client must already have the level.
+func Dial(addr string, tlsConfig *tls.Config, psk []byte, cache *network.AssetCache, h protocol.Handshake) (*Remote, error) {
+	t := network.NewTCPTransport()
+	if tlsConfig != nil {
+		t = network.NewTLSTransport(tlsConfig)
+	}
+	if err := t.Connect(addr); err != nil {
+		return nil, err
+	}
+	r := &Remote{
+		conn:      t.Conn(),
+		Assets:    make(map[protocol.TransferKind][32]byte),
+		cache:     cache,
+		downloads: make(map[[32]byte]*network.Download),
+	}
+	if err := r.join(psk, h); err != nil {
+		r.closeDownloads()
+		r.conn.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// join runs the handshakes, reads the join bundle and, if the server sends
+// the level as a transfer, waits for it
+func (r *Remote) join(psk []byte, h protocol.Handshake) error {
+	if psk != nil {
+		sc, err := network.SecureClient(r.conn, psk)
+		if err != nil {
+			return err
+		}
+		r.conn = sc
+	}
+	if err := r.send(protocol.MsgHandshake, protocol.AppendHandshake(nil, h)); err != nil {
+		return err
+	}
+
+	payload, err := r.expect(protocol.MsgHandshakeReply)
+	if err != nil {
+		return err
+	}
+	if r.Reply, _, err = protocol.DecodeHandshakeReply(payload); err != nil {
+		return err
+	}
+	if !r.Reply.Accepted {
+		return fmt.Errorf("join: %s", r.Reply.Reason)
+	}
+
+	if payload, err = r.expect(protocol.MsgJoinBundle); err != nil {
+		return err
+	}
+	if r.Bundle, _, err = protocol.DecodeJoinBundle(payload, r.Reply.Version); err != nil {
+		return err
+	}
+
+	for r.awaitsLevel(h) {
+		t, payload, err := r.recv()
+		if err != nil {
+			return err
+		}
+		switch t {
+		case protocol.MsgTransferChunk:
+			if err := r.transfer(payload); err != nil {
+				return err
+			}
+		case protocol.MsgJoinBundle: // The map changed during the download
+			if r.Bundle, _, err = protocol.DecodeJoinBundle(payload, r.Reply.Version); err != nil {
+				return err
+			}
+		case protocol.MsgDisconnect:
+			d, _, _ := protocol.DecodeDisconnect(payload)
+			return fmt.Errorf("join: %s", d.Reason)
+		}
+	}
+	return nil
+}
+
+// awaitsLevel reports whether the server is still sending the level
+func (r *Remote) awaitsLevel(h protocol.Handshake) bool {
+	if r.cache == nil || r.Reply.Version < 8 || len(r.Bundle.LevelData) > 0 || h.LevelHash == r.Bundle.LevelHash {
+		return false
+	}
+	return r.Assets[protocol.TransferLevel] != r.Bundle.LevelHash
+}
+
+// transfer stores a chunk of a pushed asset. A download left by an earlier
+// connection resumes, and one that fails its checksum restarts, by asking
+// the server for the rest.
+func (r *Remote) transfer(payload []byte) error {
+	chunk, _, err := protocol.DecodeTransferChunk(payload)
+	if err != nil {
+		return fmt.Errorf("remote: transfer: %w", err)
+	}
+	if r.cache == nil || r.Assets[chunk.Kind] == chunk.Hash {
+		return nil
+	}
+	d := r.downloads[chunk.Hash]
+	if d == nil {
+		if _, ok := r.cache.Get(chunk.Hash); ok {
+			r.Assets[chunk.Kind] = chunk.Hash
+			return nil
+		}
+		if d, err = r.cache.Download(chunk); err != nil {
+			return fmt.Errorf("remote: transfer: %w", err)
+		}
+		r.downloads[chunk.Hash] = d
+		if d.Offset() > 0 {
+			if err := r.send(protocol.MsgTransferRequest, protocol.AppendTransferRequest(nil, d.Request())); err != nil {
+				return err
+			}
+		}
+	}
+	done, err := d.Add(chunk)
+	if errors.Is(err, network.ErrChecksum) {
+		return r.send(protocol.MsgTransferRequest, protocol.AppendTransferRequest(nil, d.Request()))
+	}
+	if err != nil {
+		return fmt.Errorf("remote: transfer: %w", err)
+	}
+	if done {
+		delete(r.downloads, chunk.Hash)
+		r.Assets[chunk.Kind] = chunk.Hash
+	}
+	return nil
+}
+
+// closeDownloads stops the unfinished downloads, keeping their data for
+// the next connection
+func (r *Remote) closeDownloads() {
+	for hash, d := range r.downloads {
+		d.Close()
+		delete(r.downloads, hash)
+	}
+}
+
+// expect receives the next message, which must be of type t
+func (r *Remote) expect(t protocol.MsgType) ([]byte, error) {
+	msg, err := r.conn.Recv()
+	if err != nil {
+		return nil, err
+	}
+	if len(msg) == 0 || protocol.MsgType(msg[0]) != t {
+		return nil, errors.New("join: unexpected message from the server")
+	}
+	return msg[1:], nil
+}
+
+func (r *Remote) send(t protocol.MsgType, payload []byte) error {
+	return r.conn.Send(append([]byte{byte(t)}, payload...))
+}
+
+// Recv receives the server's next message: input acks, chat, map changes
+// and the disconnect. Transfer chunks are stored in the cache instead; a
+// finished asset shows up in Assets.
+func (r *Remote) Recv() (protocol.MsgType, []byte, error) {
+	for {
+		t, payload, err := r.recv()
+		if err != nil || t != protocol.MsgTransferChunk {
+			return t, payload, err
+		}
+		if err := r.transfer(payload); err != nil {
+			return 0, nil, err
+		}
+	}
+}
+
+func (r *Remote) recv() (protocol.MsgType, []byte, error) {
+	msg, err := r.conn.Recv()
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(msg) == 0 {
+		return 0, nil, errors.New("remote: empty message from the server")
+	}
+	return protocol.MsgType(msg[0]), msg[1:], nil
+}
+
+// SendInputs sends a redundant input message
+func (r *Remote) SendInputs(msg protocol.InputMessage) error {
+	return r.send(protocol.MsgInput, protocol.AppendInputMessage(nil, msg))
+}
+
+// Chat sends a chat line
+func (r *Remote) Chat(text string) error {
+	return r.send(protocol.MsgChat, protocol.AppendChat(nil, protocol.Chat{Text: text}))
+}
+
+// Close tells the server the player is leaving and closes the connection
+func (r *Remote) Close() error {
+	r.send(protocol.MsgDisconnect, protocol.AppendDisconnect(nil, protocol.Disconnect{Reason: "left"}))
+	r.closeDownloads()
+	return r.conn.Close()
+}
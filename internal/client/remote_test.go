@@ -0,0 +1,114 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/lobby"
+	"github.com/andersfylling/rayman-slides/internal/network"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+	"github.com/andersfylling/rayman-slides/internal/server"
+)
+
+// TestRemoteJoin tests joining a server over TCP with and without the room
+// key, receiving the level as a resumed transfer, sending inputs, and being
+// kicked.
+func TestRemoteJoin(t *testing.T) {
+	level := game.NewDemoLevel(80, 45)
+	world := game.NewWorld()
+	world.LoadLevel(level)
+	srv := server.New(server.DefaultConfig())
+	srv.SetWorld(world)
+	key := lobby.NewRoomKey()
+	remotes := server.NewRemotes(srv, lobby.KeyPSK(key))
+
+	transport := network.NewTCPTransport()
+	if err := transport.Listen("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	defer transport.Close()
+	go func() {
+		for {
+			conn, err := transport.Accept()
+			if err != nil {
+				return
+			}
+			go remotes.Serve(conn)
+		}
+	}()
+	addr := transport.Addr().String()
+
+	h, err := NewHandshake("Remote", "", nil, game.DefaultPhysics())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Dial(addr, nil, lobby.KeyPSK(lobby.NewRoomKey()), nil, h); err == nil {
+		t.Fatal("Dial with the wrong room key succeeded")
+	}
+	if _, err := Dial(addr, nil, nil, nil, h); err == nil {
+		t.Fatal("Dial without the room key succeeded")
+	}
+
+	// Half the level from an earlier connection, to resume from
+	cache, err := network.NewAssetCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := game.EncodeLevel(level)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := network.SplitTransfer(protocol.TransferLevel, data, 0, len(data)/2)
+	d, err := cache.Download(chunks[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Add(chunks[0])
+	d.Close()
+
+	remote, err := Dial(addr, nil, lobby.KeyPSK(key), cache, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.Close()
+	if !remote.Reply.Accepted || remote.Reply.PlayerID == 0 {
+		t.Fatalf("Reply = %+v, want accepted with a player", remote.Reply)
+	}
+	if len(remote.Bundle.LevelData) != 0 || remote.Bundle.Level != level.Name {
+		t.Errorf("Bundle has level %q with %d bytes, want %q as a transfer", remote.Bundle.Level, len(remote.Bundle.LevelData), level.Name)
+	}
+	if _, ok := CachedLevel(cache, remote.Bundle.LevelHash); !ok || remote.Assets[protocol.TransferLevel] != remote.Bundle.LevelHash {
+		t.Errorf("Level not transferred: cached %v, assets %x", ok, remote.Assets)
+	}
+	if n := srv.SessionCount(); n != 1 {
+		t.Errorf("SessionCount = %d, want 1", n)
+	}
+
+	frame := protocol.InputFrame{Tick: srv.Tick() + 1, Intents: protocol.IntentRight}
+	if err := remote.SendInputs(protocol.InputMessage{Frames: []protocol.InputFrame{frame}}); err != nil {
+		t.Fatal(err)
+	}
+	typ, payload, err := remote.Recv()
+	if err != nil || typ != protocol.MsgInputAck {
+		t.Fatalf("Recv = %v, %v, want an input ack", typ, err)
+	}
+	if ack, _, err := protocol.DecodeInputAck(payload); err != nil || ack.LastProcessedTick != 0 {
+		t.Errorf("Ack = %+v (%v), want nothing processed before a tick", ack, err)
+	}
+
+	srv.Kick(remote.Reply.PlayerID, "bye") // Session IDs are player IDs
+	typ, payload, err = remote.Recv()
+	if err != nil || typ != protocol.MsgDisconnect {
+		t.Fatalf("Recv = %v, %v, want a disconnect", typ, err)
+	}
+	if d, _, _ := protocol.DecodeDisconnect(payload); d.Reason != "bye" {
+		t.Errorf("Disconnect reason = %q, want bye", d.Reason)
+	}
+	for deadline := time.Now().Add(time.Second); srv.SessionCount() != 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("Session still there after the kick")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// Roster maps player IDs to names and colors, from the handshake reply and
+// the roster in snapshots. The server only sends it when it changes.
+type Roster struct {
+	LocalPlayer int // This client's player, from the handshake reply
+	players     map[int]protocol.PlayerInfo
+}
+
+// Accept records the local player from an accepted handshake reply
+func (r *Roster) Accept(reply protocol.HandshakeReply) {
+	r.LocalPlayer = reply.PlayerID
+}
+
+// Apply replaces the roster if the snapshot carries one
+func (r *Roster) Apply(snap *protocol.StateSnapshot) {
+	if snap.Players == nil {
+		return
+	}
+	r.players = make(map[int]protocol.PlayerInfo, len(snap.Players))
+	for _, p := range snap.Players {
+		r.players[p.PlayerID] = p
+	}
+}
+
+// Player returns a player's name and color
+func (r *Roster) Player(playerID int) (protocol.PlayerInfo, bool) {
+	p, ok := r.players[playerID]
+	return p, ok
+}
+
+// Remote reports whether a player is someone other than this client
+func (r *Roster) Remote(playerID int) bool {
+	return playerID != r.LocalPlayer
+}
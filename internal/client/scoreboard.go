@@ -0,0 +1,71 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// Scoreboard keeps the latest player stats and match result from snapshots.
+// Stats are only sent when they change, so the last ones received stay
+// current until replaced.
+type Scoreboard struct {
+	Stats  []protocol.PlayerStats
+	Result *protocol.MatchResult // Non-nil once the match is over
+}
+
+// Apply updates the scoreboard from a snapshot
+func (sb *Scoreboard) Apply(snap *protocol.StateSnapshot) {
+	if snap.Reset {
+		sb.Result = nil
+	}
+	if snap.Stats != nil {
+		sb.Stats = append(sb.Stats[:0], snap.Stats...)
+	}
+	if snap.Result != nil {
+		sb.Result = snap.Result
+	}
+}
+
+// ApplyBundle sets the scoreboard from a join bundle, for a player joining a
+// running or finished match
+func (sb *Scoreboard) ApplyBundle(b *protocol.JoinBundle) {
+	sb.Stats = append(sb.Stats[:0], b.Stats...)
+	sb.Result = b.Result
+}
+
+// Over reports whether the match has ended
+func (sb *Scoreboard) Over() bool {
+	return sb.Result != nil
+}
+
+// Winner formats the result headline, e.g. "Alice wins (frag limit)"
+func (sb *Scoreboard) Winner() string {
+	if sb.Result == nil {
+		return ""
+	}
+	if len(sb.Result.Winners) == 0 {
+		return fmt.Sprintf("No winner (%s)", sb.Result.Reason)
+	}
+
+	names := ""
+	for i, id := range sb.Result.Winners {
+		if i > 0 {
+			names += " & "
+		}
+		names += sb.name(id)
+	}
+	if len(sb.Result.Winners) > 1 {
+		return fmt.Sprintf("%s draw (%s)", names, sb.Result.Reason)
+	}
+	return fmt.Sprintf("%s wins (%s)", names, sb.Result.Reason)
+}
+
+func (sb *Scoreboard) name(playerID int) string {
+	for _, ps := range sb.Stats {
+		if ps.PlayerID == playerID && ps.Name != "" {
+			return ps.Name
+		}
+	}
+	return fmt.Sprintf("Player %d", playerID)
+}
@@ -6,12 +6,13 @@ package collision
 type TileFlag uint8
 
 const (
-	TileEmpty    TileFlag = 0
-	TileSolid    TileFlag = 1 << iota // Blocks movement from all directions
-	TilePlatform                      // Blocks from below only (pass-through)
-	TileHazard                        // Damages on contact
-	TileLadder                        // Allows climbing
-	TileWater                         // Slows movement, allows swimming
+	TileEmpty     TileFlag = 0
+	TileSolid     TileFlag = 1 << iota // Blocks movement from all directions
+	TilePlatform                       // Blocks from below only (pass-through)
+	TileHazard                         // Damages on contact
+	TileLadder                         // Allows climbing
+	TileWater                          // Slows movement, allows swimming
+	TileBreakable                      // Solid until broken by a ground pound
 )
 
 // TileMap holds collision data for the world
@@ -51,7 +52,28 @@ func (m *TileMap) IsSolid(x, y int) bool {
 	return m.Get(x, y)&TileSolid != 0
 }
 
+// IsBreakable checks if the tile can be broken
+func (m *TileMap) IsBreakable(x, y int) bool {
+	return m.Get(x, y)&TileBreakable != 0
+}
+
+// IsHazard checks if the tile damages on contact
+func (m *TileMap) IsHazard(x, y int) bool {
+	return m.Get(x, y)&TileHazard != 0
+}
+
 // IsPlatform checks if the tile is a pass-through platform
 func (m *TileMap) IsPlatform(x, y int) bool {
 	return m.Get(x, y)&TilePlatform != 0
 }
+
+// Clone returns a deep copy of the tile map
+func (m *TileMap) Clone() *TileMap {
+	c := &TileMap{
+		Width:  m.Width,
+		Height: m.Height,
+		Tiles:  make([]TileFlag, len(m.Tiles)),
+	}
+	copy(c.Tiles, m.Tiles)
+	return c
+}
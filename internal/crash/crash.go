@@ -0,0 +1,326 @@
+// Package crash writes a bundle of diagnostics when the client or server
+// panics, for attaching to a bug report.
+package crash
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// Bundle contents
+const (
+	LogLines   = 500    // Most recent output lines kept
+	InputTicks = 5 * 60 // Ticks of input kept: 5 seconds at 60 TPS
+)
+
+// IssueURL is where the instructions send players with a crash report
+const IssueURL = "https://github.com/andersfylling/rayman-slides/issues/new"
+
+// checksumTimeout bounds the wait for the world checksum; a crash can leave
+// the world locked
+const checksumTimeout = time.Second
+
+// Input is one player's intents for one tick
+type Input struct {
+	Tick    uint64          `json:"tick"`
+	Player  int             `json:"player"`
+	Intents protocol.Intent `json:"intents"` // protocol.Intent bits
+}
+
+// Reporter collects what a crash report needs while the program runs and
+// writes it out when a goroutine panics. Set the fields before the first
+// goroutine it covers starts; Write and RecordInput may be called from any
+// goroutine.
+type Reporter struct {
+	App      string // Program name, e.g. "rayserver"
+	Version  string
+	Renderer string // Renderer mode, "none" for the dedicated server
+	Dir      string // Where bundles go; DefaultDir if empty
+
+	// Checksum returns the world's current tick and checksum; nil leaves
+	// them out. It runs on a goroutine of its own and is abandoned after a
+	// second, so a world left locked by the crash doesn't hang the report.
+	Checksum func() (tick uint64, sum uint32)
+
+	mu      sync.Mutex
+	lines   [LogLines]string // Ring of output lines
+	next    int              // Index the next line goes to
+	full    bool             // The ring has wrapped
+	partial []byte           // Output after the last newline
+	inputs  []Input          // Oldest first, the last InputTicks ticks
+	console io.Writer        // Where the crash message goes
+
+	// Output capture: the pipes' write ends, which replaced os.Stdout and
+	// os.Stderr, the original files and the goroutines copying the pipes
+	pipes    []*os.File
+	original [2]*os.File
+	copying  sync.WaitGroup
+}
+
+// DefaultDir is the directory bundles are written to without Dir: the
+// user cache directory, or the temp directory if there is none
+func DefaultDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "rayman-slides", "crashes")
+}
+
+// Write adds program output to the log ring. It never fails, so it can sit
+// behind an io.MultiWriter.
+func (r *Reporter) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.partial = append(r.partial, p...)
+	for {
+		i := bytes.IndexByte(r.partial, '\n')
+		if i < 0 {
+			break
+		}
+		r.lines[r.next] = string(r.partial[:i])
+		r.next = (r.next + 1) % LogLines
+		r.full = r.full || r.next == 0
+		r.partial = r.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// Lines returns the kept output lines, oldest first, including an
+// unterminated last line
+func (r *Reporter) Lines() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var lines []string
+	if r.full {
+		lines = append(lines, r.lines[r.next:]...)
+	}
+	lines = append(lines, r.lines[:r.next]...)
+	if len(r.partial) > 0 {
+		lines = append(lines, string(r.partial))
+	}
+	return lines
+}
+
+// CaptureOutput routes os.Stdout and os.Stderr through pipes that copy
+// everything to the original files and to the log ring, so output printed
+// anywhere in the program ends up in the bundle. The crash message itself
+// goes straight to the original stderr.
+func (r *Reporter) CaptureOutput() error {
+	stdout, err := r.tee(os.Stdout)
+	if err != nil {
+		return err
+	}
+	stderr, err := r.tee(os.Stderr)
+	if err != nil {
+		stdout.Close()
+		return err
+	}
+	r.mu.Lock()
+	r.console = os.Stderr
+	r.pipes = []*os.File{stdout, stderr}
+	r.original = [2]*os.File{os.Stdout, os.Stderr}
+	r.mu.Unlock()
+	os.Stdout, os.Stderr = stdout, stderr
+	return nil
+}
+
+// tee returns the write end of a pipe copying to f and the ring
+func (r *Reporter) tee(f *os.File) (*os.File, error) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	r.copying.Add(1)
+	go func() {
+		defer r.copying.Done()
+		io.Copy(io.MultiWriter(f, r), pr)
+	}()
+	return pw, nil
+}
+
+// Exit exits with the status code once everything printed so far has gone
+// through the pipes from CaptureOutput, which os.Exit alone would cut off.
+// Like os.Exit, it doesn't run deferred calls.
+func (r *Reporter) Exit(code int) {
+	r.flush()
+	os.Exit(code)
+}
+
+// flush puts back the original os.Stdout and os.Stderr, closes the pipes
+// and waits until they are copied out. Output from then on goes straight
+// to the original files.
+func (r *Reporter) flush() {
+	r.mu.Lock()
+	pipes := r.pipes
+	r.pipes = nil
+	if pipes != nil {
+		os.Stdout, os.Stderr = r.original[0], r.original[1]
+	}
+	r.mu.Unlock()
+	for _, p := range pipes {
+		p.Close()
+	}
+	r.copying.Wait()
+}
+
+// RecordInput keeps a player's intents for a tick, dropping those more than
+// InputTicks ticks old
+func (r *Reporter) RecordInput(tick uint64, player int, intents protocol.Intent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inputs = append(r.inputs, Input{Tick: tick, Player: player, Intents: intents})
+	for len(r.inputs) > 0 && r.inputs[0].Tick+InputTicks <= tick {
+		r.inputs = r.inputs[1:]
+	}
+}
+
+// Inputs returns the recorded inputs, oldest first
+func (r *Reporter) Inputs() []Input {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Input(nil), r.inputs...)
+}
+
+// Recover reports a panic in the calling goroutine and exits with status 2,
+// as an unrecovered panic would. Defer it first in every goroutine it
+// should cover:
+//
+//	go func() {
+//		defer reporter.Recover()
+//		...
+//	}()
+func (r *Reporter) Recover() {
+	if p := recover(); p != nil {
+		r.Crash(p, debug.Stack())
+	}
+}
+
+// Crash writes a bundle for the panic value p, prints the panic and how to
+// report it, and exits with status 2
+func (r *Reporter) Crash(p any, stack []byte) {
+	r.flush() // The bundle gets the last lines printed
+	path, err := r.WriteBundle(fmt.Sprint(p), stack, time.Now())
+
+	r.mu.Lock()
+	console := r.console
+	r.mu.Unlock()
+	if console == nil {
+		console = os.Stderr
+	}
+	fmt.Fprintf(console, "\npanic: %v\n\n%s\n", p, stack)
+	if err != nil {
+		fmt.Fprintf(console, "%s crashed, and the crash report could not be written: %v\n", r.App, err)
+	} else {
+		fmt.Fprint(console, Instructions(r.App, path))
+	}
+	os.Exit(2)
+}
+
+// Instructions tells the player where the bundle is and how to report it
+func Instructions(app, path string) string {
+	return fmt.Sprintf(`%s crashed. A crash report was written to:
+
+    %s
+
+Please open an issue at %s, say what you were doing
+when it happened and attach that file (drag it into the issue text box).
+It holds the last %d lines of output, so check it for anything private first.
+`, app, path, IssueURL, LogLines)
+}
+
+// WriteBundle writes a crash report zip to the directory and returns its
+// path. It holds report.txt (version, OS, renderer, reason and world
+// checksum), stack.txt, log.txt and inputs.json.
+func (r *Reporter) WriteBundle(reason string, stack []byte, now time.Time) (string, error) {
+	dir := r.Dir
+	if dir == "" {
+		dir = DefaultDir()
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s-crash-%s.zip", r.App, now.UTC().Format("20060102-150405"))
+	path := filepath.Join(dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	inputs, err := json.MarshalIndent(r.Inputs(), "", "  ")
+	if err != nil {
+		return "", err
+	}
+	zw := zip.NewWriter(f)
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{
+		{"report.txt", []byte(r.report(reason, now))},
+		{"stack.txt", stack},
+		{"log.txt", []byte(strings.Join(r.Lines(), "\n") + "\n")},
+		{"inputs.json", inputs},
+	} {
+		w, err := zw.Create(file.name)
+		if err != nil {
+			return "", err
+		}
+		if _, err := w.Write(file.data); err != nil {
+			return "", err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return path, f.Close()
+}
+
+// report is report.txt: one "Key: value" line each
+func (r *Reporter) report(reason string, now time.Time) string {
+	var w strings.Builder
+	fmt.Fprintf(&w, "App: %s\n", r.App)
+	fmt.Fprintf(&w, "Version: %s\n", r.Version)
+	fmt.Fprintf(&w, "Go: %s\n", runtime.Version())
+	fmt.Fprintf(&w, "OS: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&w, "Renderer: %s\n", r.Renderer)
+	fmt.Fprintf(&w, "Time: %s\n", now.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&w, "Reason: %s\n", reason)
+	fmt.Fprintf(&w, "World: %s\n", r.checksum())
+	return w.String()
+}
+
+// checksum describes the world's checksum, or why it is missing
+func (r *Reporter) checksum() string {
+	if r.Checksum == nil {
+		return "none"
+	}
+	done := make(chan string, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Sprintf("unavailable (%v)", p)
+			}
+		}()
+		tick, sum := r.Checksum()
+		done <- fmt.Sprintf("checksum %08x at tick %d", sum, tick)
+	}()
+	select {
+	case s := <-done:
+		return s
+	case <-time.After(checksumTimeout):
+		return "unavailable (timed out)"
+	}
+}
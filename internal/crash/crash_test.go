@@ -0,0 +1,141 @@
+package crash
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+func TestLogRing(t *testing.T) {
+	var r Reporter
+	fmt.Fprint(&r, "first\nsec")
+	fmt.Fprint(&r, "ond\n")
+	if got := r.Lines(); len(got) != 2 || got[0] != "first" || got[1] != "second" {
+		t.Fatalf("lines = %q", got)
+	}
+
+	for i := range LogLines + 10 {
+		fmt.Fprintf(&r, "line %d\n", i)
+	}
+	fmt.Fprint(&r, "unterminated")
+	got := r.Lines()
+	if len(got) != LogLines+1 {
+		t.Fatalf("kept %d lines, want %d", len(got), LogLines+1)
+	}
+	if got[0] != "line 10" || got[LogLines-1] != fmt.Sprintf("line %d", LogLines+9) || got[LogLines] != "unterminated" {
+		t.Errorf("lines = %q ... %q", got[0], got[LogLines-1:])
+	}
+}
+
+func TestCaptureOutputFlush(t *testing.T) {
+	stdout, stderr := os.Stdout, os.Stderr
+	var r Reporter
+	if err := r.CaptureOutput(); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println("to stdout")
+	fmt.Fprint(os.Stderr, "to stderr")
+	r.flush()
+
+	if os.Stdout != stdout || os.Stderr != stderr {
+		t.Error("flush didn't restore stdout and stderr")
+	}
+	got := strings.Join(r.Lines(), "|")
+	if !strings.Contains(got, "to stdout") || !strings.Contains(got, "to stderr") {
+		t.Errorf("lines after flush = %q", got)
+	}
+	r.flush() // Again, as a second crash would
+}
+
+func TestRecordInput(t *testing.T) {
+	var r Reporter
+	for tick := uint64(1); tick <= 1000; tick++ {
+		r.RecordInput(tick, 1, protocol.IntentRight)
+		r.RecordInput(tick, 2, protocol.IntentLeft)
+	}
+	inputs := r.Inputs()
+	if len(inputs) != 2*InputTicks {
+		t.Fatalf("kept %d inputs, want %d", len(inputs), 2*InputTicks)
+	}
+	if inputs[0].Tick != 1000-InputTicks+1 || inputs[len(inputs)-1].Tick != 1000 {
+		t.Errorf("ticks %d to %d", inputs[0].Tick, inputs[len(inputs)-1].Tick)
+	}
+}
+
+func TestWriteBundle(t *testing.T) {
+	r := &Reporter{
+		App:      "rayserver",
+		Version:  "1.2.3",
+		Renderer: "none",
+		Dir:      t.TempDir(),
+		Checksum: func() (uint64, uint32) { return 42, 0xdeadbeef },
+	}
+	fmt.Fprintln(r, "Server starting...")
+	r.RecordInput(41, 1, protocol.IntentJump)
+
+	now := time.Date(2026, 10, 16, 12, 30, 0, 0, time.UTC)
+	path, err := r.WriteBundle("boom", []byte("goroutine 1 [running]:"), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(path, "rayserver-crash-20261016-123000.zip") {
+		t.Errorf("path = %s", path)
+	}
+
+	files := readZip(t, path)
+	for _, want := range []string{"Version: 1.2.3", "Renderer: none", "Reason: boom", "World: checksum deadbeef at tick 42", "OS: "} {
+		if !strings.Contains(files["report.txt"], want) {
+			t.Errorf("report.txt lacks %q:\n%s", want, files["report.txt"])
+		}
+	}
+	if files["stack.txt"] != "goroutine 1 [running]:" {
+		t.Errorf("stack.txt = %q", files["stack.txt"])
+	}
+	if files["log.txt"] != "Server starting...\n" {
+		t.Errorf("log.txt = %q", files["log.txt"])
+	}
+	var inputs []Input
+	if err := json.Unmarshal([]byte(files["inputs.json"]), &inputs); err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) != 1 || inputs[0] != (Input{Tick: 41, Player: 1, Intents: protocol.IntentJump}) {
+		t.Errorf("inputs = %+v", inputs)
+	}
+}
+
+func TestChecksumPanics(t *testing.T) {
+	r := &Reporter{Checksum: func() (uint64, uint32) { panic("world corrupted") }}
+	if got := r.checksum(); got != "unavailable (world corrupted)" {
+		t.Errorf("checksum = %q", got)
+	}
+}
+
+func readZip(t *testing.T, path string) map[string]string {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(data)
+	}
+	return files
+}
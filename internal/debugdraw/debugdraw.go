@@ -0,0 +1,153 @@
+// Package debugdraw draws debug markers on images: outlines, crosses, dots
+// and labels in a 5x7 bitmap font. It works on any draw.Image, so paletted
+// GIF frames and RGBA PNGs share it; sprite-debug and heatmap use it.
+package debugdraw
+
+import (
+	"image/color"
+	"image/draw"
+)
+
+// Label sizes, in pixels
+const (
+	CharWidth  = 6 // 5 pixels and a space
+	CharHeight = 7
+)
+
+// Border draws a rectangle's outline, thickness pixels wide, inside it
+func Border(img draw.Image, x, y, w, h int, c color.Color, thickness int) {
+	// Top and bottom edges
+	for dx := 0; dx < w; dx++ {
+		for t := 0; t < thickness; t++ {
+			img.Set(x+dx, y+t, c)
+			img.Set(x+dx, y+h-1-t, c)
+		}
+	}
+	// Left and right edges
+	for dy := 0; dy < h; dy++ {
+		for t := 0; t < thickness; t++ {
+			img.Set(x+t, y+dy, c)
+			img.Set(x+w-1-t, y+dy, c)
+		}
+	}
+}
+
+// Cross draws a cross marker reaching size pixels from its center
+func Cross(img draw.Image, x, y int, c color.Color, size int) {
+	// Horizontal line
+	for dx := -size; dx <= size; dx++ {
+		img.Set(x+dx, y, c)
+	}
+	// Vertical line
+	for dy := -size; dy <= size; dy++ {
+		img.Set(x, y+dy, c)
+	}
+}
+
+// Dot draws a 3x3 dot without its corners
+func Dot(img draw.Image, x, y int, c color.Color) {
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			if dx*dx+dy*dy <= 1 {
+				img.Set(x+dx, y+dy, c)
+			}
+		}
+	}
+}
+
+// Label draws text on a filled background, top left at (x, y)
+func Label(img draw.Image, x, y int, text string, textColor, bgColor color.Color) {
+	textWidth := len([]rune(text)) * CharWidth
+	for dy := -1; dy < CharHeight+2; dy++ {
+		for dx := -2; dx < textWidth+2; dx++ {
+			img.Set(x+dx, y+dy, bgColor)
+		}
+	}
+	Text(img, x, y, text, textColor)
+}
+
+// ShadowLabel draws text with a shadow one pixel down and right, so it
+// reads on any background
+func ShadowLabel(img draw.Image, x, y int, text string, textColor, shadowColor color.Color) {
+	Text(img, x+1, y+1, text, shadowColor)
+	Text(img, x, y, text, textColor)
+}
+
+// Text draws text, top left at (x, y). Letters are drawn in upper case and
+// characters the font lacks are left blank.
+func Text(img draw.Image, x, y int, text string, c color.Color) {
+	i := 0
+	for _, r := range text {
+		// Convert to uppercase
+		if r >= 'a' && r <= 'z' {
+			r = r - 'a' + 'A'
+		}
+		charX := x + i*CharWidth
+		i++
+
+		glyph, ok := font5x7[r]
+		if !ok {
+			continue // Skip unknown characters
+		}
+		for row := 0; row < CharHeight; row++ {
+			for col := 0; col < 5; col++ {
+				if glyph[row]&(1<<(4-col)) != 0 {
+					img.Set(charX+col, y+row, c)
+				}
+			}
+		}
+	}
+}
+
+// 5x7 bitmap font for A-Z, 0-9, and common punctuation
+var font5x7 = map[rune][7]uint8{
+	'A': {0x0E, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11},
+	'B': {0x1E, 0x11, 0x11, 0x1E, 0x11, 0x11, 0x1E},
+	'C': {0x0E, 0x11, 0x10, 0x10, 0x10, 0x11, 0x0E},
+	'D': {0x1E, 0x11, 0x11, 0x11, 0x11, 0x11, 0x1E},
+	'E': {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x1F},
+	'F': {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x10},
+	'G': {0x0E, 0x11, 0x10, 0x17, 0x11, 0x11, 0x0E},
+	'H': {0x11, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11},
+	'I': {0x0E, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'J': {0x07, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0C},
+	'K': {0x11, 0x12, 0x14, 0x18, 0x14, 0x12, 0x11},
+	'L': {0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x1F},
+	'M': {0x11, 0x1B, 0x15, 0x15, 0x11, 0x11, 0x11},
+	'N': {0x11, 0x11, 0x19, 0x15, 0x13, 0x11, 0x11},
+	'O': {0x0E, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
+	'P': {0x1E, 0x11, 0x11, 0x1E, 0x10, 0x10, 0x10},
+	'Q': {0x0E, 0x11, 0x11, 0x11, 0x15, 0x12, 0x0D},
+	'R': {0x1E, 0x11, 0x11, 0x1E, 0x14, 0x12, 0x11},
+	'S': {0x0E, 0x11, 0x10, 0x0E, 0x01, 0x11, 0x0E},
+	'T': {0x1F, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},
+	'U': {0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
+	'V': {0x11, 0x11, 0x11, 0x11, 0x11, 0x0A, 0x04},
+	'W': {0x11, 0x11, 0x11, 0x15, 0x15, 0x1B, 0x11},
+	'X': {0x11, 0x11, 0x0A, 0x04, 0x0A, 0x11, 0x11},
+	'Y': {0x11, 0x11, 0x0A, 0x04, 0x04, 0x04, 0x04},
+	'Z': {0x1F, 0x01, 0x02, 0x04, 0x08, 0x10, 0x1F},
+	'0': {0x0E, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0E},
+	'1': {0x04, 0x0C, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'2': {0x0E, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1F},
+	'3': {0x0E, 0x11, 0x01, 0x06, 0x01, 0x11, 0x0E},
+	'4': {0x02, 0x06, 0x0A, 0x12, 0x1F, 0x02, 0x02},
+	'5': {0x1F, 0x10, 0x1E, 0x01, 0x01, 0x11, 0x0E},
+	'6': {0x06, 0x08, 0x10, 0x1E, 0x11, 0x11, 0x0E},
+	'7': {0x1F, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+	'8': {0x0E, 0x11, 0x11, 0x0E, 0x11, 0x11, 0x0E},
+	'9': {0x0E, 0x11, 0x11, 0x0F, 0x01, 0x02, 0x0C},
+	' ': {0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+	'-': {0x00, 0x00, 0x00, 0x1F, 0x00, 0x00, 0x00},
+	'>': {0x08, 0x04, 0x02, 0x01, 0x02, 0x04, 0x08},
+	'<': {0x02, 0x04, 0x08, 0x10, 0x08, 0x04, 0x02},
+	'+': {0x00, 0x04, 0x04, 0x1F, 0x04, 0x04, 0x00},
+	'.': {0x00, 0x00, 0x00, 0x00, 0x00, 0x0C, 0x0C},
+	',': {0x00, 0x00, 0x00, 0x00, 0x04, 0x04, 0x08},
+	'!': {0x04, 0x04, 0x04, 0x04, 0x04, 0x00, 0x04},
+	'?': {0x0E, 0x11, 0x01, 0x02, 0x04, 0x00, 0x04},
+	'/': {0x01, 0x01, 0x02, 0x04, 0x08, 0x10, 0x10},
+	'(': {0x02, 0x04, 0x08, 0x08, 0x08, 0x04, 0x02},
+	')': {0x08, 0x04, 0x02, 0x02, 0x02, 0x04, 0x08},
+	':': {0x00, 0x0C, 0x0C, 0x00, 0x0C, 0x0C, 0x00},
+}
@@ -0,0 +1,45 @@
+package debugdraw
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// TestLabel tests that a label fills its background around the text and
+// draws letters in upper case, skipping characters the font lacks.
+func TestLabel(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 12))
+	white, black := color.RGBA{255, 255, 255, 255}, color.RGBA{0, 0, 0, 255}
+	Label(img, 2, 1, "a~", white, black)
+
+	if img.RGBAAt(0, 0) != black || img.RGBAAt(2+2*CharWidth+1, 1+CharHeight+1) != black {
+		t.Error("Background should reach 2 pixels left, 1 up and 2 past the text")
+	}
+	if img.RGBAAt(2+2*CharWidth+2, 1) == black {
+		t.Error("Background drawn past the text")
+	}
+
+	lower, upper := image.NewRGBA(img.Rect), image.NewRGBA(img.Rect)
+	Text(lower, 2, 1, "a", white)
+	Text(upper, 2, 1, "A", white)
+	lit := 0
+	for i := range lower.Pix {
+		if lower.Pix[i] != upper.Pix[i] {
+			t.Fatal("Lower case letters should be drawn in upper case")
+		}
+		if lower.Pix[i] != 0 {
+			lit++
+		}
+	}
+	if lit == 0 {
+		t.Error("Nothing drawn for A")
+	}
+	for y := 1; y < 1+CharHeight; y++ {
+		for x := 2 + CharWidth; x < 2+2*CharWidth; x++ {
+			if img.RGBAAt(x, y) != black {
+				t.Fatalf("Pixel (%d, %d) drawn for a character the font lacks", x, y)
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// TestAimAssist tests that a fist thrown at a floating bat above its path
+// only hits when the bat is inside the player's aim cone, and never with
+// aim assist turned off by the mode.
+func TestAimAssist(t *testing.T) {
+	tests := []struct {
+		name   string
+		assist AimAssist
+		off    bool    // NoAimAssist
+		above  float64 // Bat center above the fist's path, 4 tiles ahead
+		hits   int
+	}{
+		{"off, slightly above", AimAssistOff, false, 0.9, 0},
+		{"low, slightly above", AimAssistLow, false, 0.9, 1},
+		{"low, well above", AimAssistLow, false, 1.5, 0},
+		{"high, well above", AimAssistHigh, false, 1.5, 1},
+		{"high, competitive", AimAssistHigh, true, 1.5, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newScenario(t, arenaLevel()).spawn(1, 5, 10)
+			s.wait(30).expectNear(1, 5, 10.1, 0.01)
+			s.world.SetAimAssist(1, tt.assist)
+			s.world.NoAimAssist = tt.off
+
+			// Chest height is 0.5 above the feet; the bat's center is 0.4
+			// above its position
+			bat := s.world.NetIDOf(s.world.SpawnEnemy("bat", 9, 10.1-0.5-tt.above+0.4))
+			s.world.SetEntityGravity(bat, 0)
+
+			s.press(1, protocol.IntentAttack, 40).wait(20)
+			s.expectEvents(EventDamage, tt.hits)
+		})
+	}
+}
+
+// TestParseAimAssist tests that every level parses from its name
+func TestParseAimAssist(t *testing.T) {
+	for a := AimAssistOff; a <= AimAssistHigh; a++ {
+		if got, err := ParseAimAssist(a.String()); err != nil || got != a {
+			t.Errorf("ParseAimAssist(%q) = %v, %v", a.String(), got, err)
+		}
+	}
+	if _, err := ParseAimAssist("max"); err == nil {
+		t.Error("Unknown levels should be rejected")
+	}
+}
@@ -0,0 +1,113 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Weather is a level's weather effect
+type Weather string
+
+const (
+	WeatherNone Weather = ""
+	WeatherRain Weather = "rain"
+	WeatherSnow Weather = "snow"
+)
+
+// DefaultFogColor is the fog's color when a level sets none: a pale
+// blue-grey
+const DefaultFogColor HexColor = 0x9AA4B0
+
+// Ambience is a level's mood: a color tint over the scene, fog thickening
+// toward the bottom of the screen and weather. It is cosmetic; renderers
+// apply it and the simulation never reads it.
+type Ambience struct {
+	Tint      HexColor   `json:"tint,omitempty"`       // Color laid over the scene
+	TintAlpha float64    `json:"tint_alpha,omitempty"` // How strongly, 0 to 1
+	Fog       float64    `json:"fog,omitempty"`        // Density at the bottom of the screen, 0 to 1
+	FogColor  HexColor   `json:"fog_color,omitempty"`  // DefaultFogColor if unset
+	Weather   Weather    `json:"weather,omitempty"`
+	Intensity float64    `json:"intensity,omitempty"` // Scales the weather's particles; 1 if unset
+	Wind      float64    `json:"wind,omitempty"`      // Tiles per tick sideways, positive to the right
+	WindZones []WindZone `json:"wind_zones,omitempty"`
+}
+
+// WindZone overrides the level's wind for weather inside Area. The first
+// zone containing a particle wins.
+type WindZone struct {
+	Area Rect    `json:"area"`
+	Wind float64 `json:"wind"`
+}
+
+// WindAt returns the wind at a point
+func (a *Ambience) WindAt(x, y float64) float64 {
+	for _, z := range a.WindZones {
+		if z.Area.Contains(x, y) {
+			return z.Wind
+		}
+	}
+	return a.Wind
+}
+
+// FogRGB returns the fog's color, 0xRRGGBB
+func (a *Ambience) FogRGB() uint32 {
+	if a.FogColor == 0 {
+		return uint32(DefaultFogColor)
+	}
+	return uint32(a.FogColor)
+}
+
+// WeatherIntensity returns the weather's particle scale
+func (a *Ambience) WeatherIntensity() float64 {
+	if a.Intensity == 0 {
+		return 1
+	}
+	return a.Intensity
+}
+
+// validate checks the values a level file may get wrong
+func (a *Ambience) validate() error {
+	switch a.Weather {
+	case WeatherNone, WeatherRain, WeatherSnow:
+	default:
+		return fmt.Errorf("unknown weather %q (want rain or snow)", a.Weather)
+	}
+	if a.TintAlpha < 0 || a.TintAlpha > 1 {
+		return fmt.Errorf("tint_alpha %v outside 0 to 1", a.TintAlpha)
+	}
+	if a.Fog < 0 || a.Fog > 1 {
+		return fmt.Errorf("fog %v outside 0 to 1", a.Fog)
+	}
+	if a.Intensity < 0 {
+		return fmt.Errorf("negative weather intensity %v", a.Intensity)
+	}
+	return nil
+}
+
+// HexColor is a 0xRRGGBB color written "#rrggbb" in level files
+type HexColor uint32
+
+// MarshalJSON writes the color as "#rrggbb"
+func (c HexColor) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fmt.Sprintf("#%06x", uint32(c)&0xFFFFFF))
+}
+
+// UnmarshalJSON reads a "#rrggbb" color
+func (c *HexColor) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	hex, ok := strings.CutPrefix(s, "#")
+	if !ok || len(hex) != 6 {
+		return fmt.Errorf("color %q: want #rrggbb", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return fmt.Errorf("color %q: want #rrggbb", s)
+	}
+	*c = HexColor(v)
+	return nil
+}
@@ -0,0 +1,45 @@
+package game
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestAmbienceFile tests that a level file's ambience reads and writes its
+// colors as hex, checks its values and finds the wind by zone.
+func TestAmbienceFile(t *testing.T) {
+	var a Ambience
+	data := `{"tint": "#ffb070", "tint_alpha": 0.2, "weather": "snow", "wind": 0.05,
+		"wind_zones": [{"area": {"x": 10, "y": 0, "w": 5, "h": 20}, "wind": -0.1}]}`
+	if err := json.Unmarshal([]byte(data), &a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Tint != 0xFFB070 || a.FogRGB() != uint32(DefaultFogColor) || a.WeatherIntensity() != 1 {
+		t.Errorf("Ambience = %+v", a)
+	}
+	if err := a.validate(); err != nil {
+		t.Error(err)
+	}
+	if w := a.WindAt(12, 5); w != -0.1 {
+		t.Errorf("Wind in the zone = %v, want -0.1", w)
+	}
+	if w := a.WindAt(2, 5); w != 0.05 {
+		t.Errorf("Wind outside = %v, want the level's 0.05", w)
+	}
+
+	out, err := json.Marshal(a.Tint)
+	if err != nil || string(out) != `"#ffb070"` {
+		t.Errorf("Tint encodes as %s (%v)", out, err)
+	}
+
+	for _, bad := range []string{`{"tint": "orange"}`, `{"tint": "#fff"}`} {
+		if err := json.Unmarshal([]byte(bad), &a); err == nil {
+			t.Errorf("%s should not parse", bad)
+		}
+	}
+	for _, bad := range []Ambience{{Weather: "hail"}, {Fog: 1.5}, {TintAlpha: -1}, {Intensity: -2}} {
+		if err := bad.validate(); err == nil {
+			t.Errorf("%+v should not validate", bad)
+		}
+	}
+}
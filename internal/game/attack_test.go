@@ -238,3 +238,37 @@ func TestAttackNoFireWhileHolding(t *testing.T) {
 		t.Fatalf("Expected exactly 1 fist after release, got %d", countFists())
 	}
 }
+
+// TestPlayerCharge tests that the reported charge follows the held attack
+// and that its stage matches the charge sprite.
+func TestPlayerCharge(t *testing.T) {
+	world := NewWorld()
+	player := world.SpawnPlayer(1, "Test", 10, 10)
+
+	if _, _, charging := world.PlayerCharge(1); charging {
+		t.Fatal("Should not be charging before attack is pressed")
+	}
+	for tick := 1; tick <= MaxChargeTicks+10; tick++ {
+		world.SetPlayerIntent(1, protocol.IntentAttack)
+		world.Update()
+		progress, level, charging := world.PlayerCharge(1)
+		if !charging {
+			t.Fatalf("Tick %d: should be charging while attack is held", tick)
+		}
+		if want := chargeSprite(true, level); world.spriteMap.Get(player).ID != want {
+			t.Fatalf("Tick %d: stage %d but sprite %v", tick, level, world.spriteMap.Get(player).ID)
+		}
+		if want := float64(min(tick, MaxChargeTicks)) / MaxChargeTicks; progress != want {
+			t.Fatalf("Tick %d: progress %v, want %v", tick, progress, want)
+		}
+	}
+	if progress, level, _ := world.PlayerCharge(1); progress != 1 || level != ChargeLevels {
+		t.Errorf("Full charge reported as %v at stage %d", progress, level)
+	}
+
+	world.SetPlayerIntent(1, protocol.IntentNone)
+	world.Update()
+	if _, _, charging := world.PlayerCharge(1); charging {
+		t.Error("Releasing attack should end the charge")
+	}
+}
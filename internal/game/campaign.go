@@ -0,0 +1,185 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math/bits"
+	"path"
+	"strings"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// Campaign is an ordered run of levels, each unlocked by finishing earlier
+// ones and earning stars
+type Campaign struct {
+	Name   string          `json:"name"`
+	Levels []CampaignLevel `json:"levels"`
+
+	fsys fs.FS  // Where the level files are
+	dir  string // Of the campaign file, which level files are relative to
+}
+
+// CampaignLevel is one level of a campaign, with what unlocks it and the
+// goals its stars are for
+type CampaignLevel struct {
+	ID       string   `json:"id,omitempty"`        // Defaults to the file name without its extension
+	Title    string   `json:"title,omitempty"`     // Defaults to the ID
+	File     string   `json:"file"`                // Level file, relative to the campaign file
+	Requires []string `json:"requires,omitempty"`  // Levels to finish first; the one before if unset
+	Stars    int      `json:"stars,omitempty"`     // Stars needed across the campaign
+	Orbs     int      `json:"orbs,omitempty"`      // Orbs in the level, all needed for StarOrbs
+	Cages    int      `json:"cages,omitempty"`     // Cages in the level, all needed for StarCages
+	TimeGoal float64  `json:"time_goal,omitempty"` // Seconds to finish in for StarTime; 0 awards it on finishing
+}
+
+// Stars is the set of stars earned on a level
+type Stars uint8
+
+// A level's stars
+const (
+	StarFinished Stars = 1 << iota // Reached the exit
+	StarCages                      // Freed every cage
+	StarOrbs                       // Collected every orb
+	StarTime                       // Finished within the time goal
+
+	AllStars = StarFinished | StarCages | StarOrbs | StarTime
+)
+
+// Count returns how many stars are set
+func (s Stars) Count() int {
+	return bits.OnesCount8(uint8(s))
+}
+
+// CampaignProgress is the stars earned on each level of a campaign, by
+// level ID, as a save file keeps them
+type CampaignProgress map[string]Stars
+
+// Total returns the stars earned across the campaign
+func (p CampaignProgress) Total() int {
+	n := 0
+	for _, s := range p {
+		n += s.Count()
+	}
+	return n
+}
+
+// Record adds stars earned on a level, reporting whether any are new
+func (p CampaignProgress) Record(id string, s Stars) bool {
+	if p[id]|s == p[id] {
+		return false
+	}
+	p[id] |= s
+	return true
+}
+
+// ReadCampaign loads a campaign file from fsys. Its levels are read with
+// LoadLevel when played.
+func ReadCampaign(fsys fs.FS, name string) (*Campaign, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	c := &Campaign{fsys: fsys, dir: path.Dir(name)}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("campaign %s: %w", name, err)
+	}
+	if c.Name == "" {
+		c.Name = strings.TrimSuffix(path.Base(name), path.Ext(name))
+	}
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("campaign %s: %w", name, err)
+	}
+	return c, nil
+}
+
+// validate fills in default IDs and titles and checks that every level
+// requires only levels before it
+func (c *Campaign) validate() error {
+	if len(c.Levels) == 0 {
+		return fmt.Errorf("no levels")
+	}
+	seen := make(map[string]bool, len(c.Levels))
+	for i := range c.Levels {
+		l := &c.Levels[i]
+		if l.File == "" {
+			return fmt.Errorf("level %d: no file", i)
+		}
+		if l.ID == "" {
+			l.ID = strings.TrimSuffix(path.Base(l.File), path.Ext(l.File))
+		}
+		if l.Title == "" {
+			l.Title = l.ID
+		}
+		if seen[l.ID] {
+			return fmt.Errorf("level %d: duplicate ID %q", i, l.ID)
+		}
+		for _, r := range l.Requires {
+			if !seen[r] {
+				return fmt.Errorf("level %s: requires %q, which is not an earlier level", l.ID, r)
+			}
+		}
+		seen[l.ID] = true
+	}
+	return nil
+}
+
+// LoadLevel reads the i-th level's file
+func (c *Campaign) LoadLevel(i int) (*Level, error) {
+	return ReadLevelFile(c.fsys, path.Join(c.dir, c.Levels[i].File))
+}
+
+// Missing returns what still locks the i-th level: the required levels not
+// finished yet, in campaign order, and how many more stars are needed.
+// Both are empty once it is unlocked.
+func (c *Campaign) Missing(i int, p CampaignProgress) (levels []string, stars int) {
+	l := &c.Levels[i]
+	requires := l.Requires
+	if requires == nil && i > 0 {
+		requires = []string{c.Levels[i-1].ID}
+	}
+	for _, r := range requires {
+		if p[r]&StarFinished == 0 {
+			levels = append(levels, r)
+		}
+	}
+	return levels, max(l.Stars-p.Total(), 0)
+}
+
+// Unlocked reports whether the i-th level can be played
+func (c *Campaign) Unlocked(i int, p CampaignProgress) bool {
+	levels, stars := c.Missing(i, p)
+	return len(levels) == 0 && stars == 0
+}
+
+// Next returns the level to offer first: the first unlocked level not
+// finished yet, or the first level if all are
+func (c *Campaign) Next(p CampaignProgress) int {
+	for i := range c.Levels {
+		if p[c.Levels[i].ID]&StarFinished == 0 && c.Unlocked(i, p) {
+			return i
+		}
+	}
+	return 0
+}
+
+// Earned returns the stars a player's stats earn on the level. A level
+// without orbs or cages awards their stars on finishing, as does one
+// without a time goal.
+func (l *CampaignLevel) Earned(ps protocol.PlayerStats) Stars {
+	if ps.FinishTicks == 0 {
+		return 0
+	}
+	s := StarFinished
+	if ps.Cages >= l.Cages {
+		s |= StarCages
+	}
+	if ps.Orbs >= l.Orbs {
+		s |= StarOrbs
+	}
+	if l.TimeGoal <= 0 || float64(ps.FinishTicks) <= l.TimeGoal*60 {
+		s |= StarTime
+	}
+	return s
+}
@@ -0,0 +1,77 @@
+package game
+
+import (
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// TestCampaign tests level defaults, unlocking by finished levels and
+// stars, the stars stats earn, and loading levels relative to the file.
+func TestCampaign(t *testing.T) {
+	fsys := fstest.MapFS{
+		"c/campaign.json": {Data: []byte(`{"levels": [
+			{"file": "levels/one.json", "cages": 2, "time_goal": 10},
+			{"id": "two", "title": "Two", "file": "levels/one.json"},
+			{"id": "bonus", "file": "levels/one.json", "requires": ["one"], "stars": 6}]}`)},
+		"c/levels/one.json": {Data: []byte(`{"name": "one", "tiles": ["#"]}`)},
+	}
+	c, err := ReadCampaign(fsys, "c/campaign.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "campaign" || c.Levels[0].ID != "one" || c.Levels[0].Title != "one" {
+		t.Errorf("Defaults: name %q, first level %+v", c.Name, c.Levels[0])
+	}
+	if level, err := c.LoadLevel(1); err != nil || level.Name != "one" {
+		t.Errorf("LoadLevel = %v, %v", level, err)
+	}
+
+	p := CampaignProgress{}
+	if !c.Unlocked(0, p) || c.Unlocked(1, p) || c.Next(p) != 0 {
+		t.Fatal("Only the first level should start unlocked")
+	}
+
+	// Finished in time but missing a cage
+	earned := c.Levels[0].Earned(protocol.PlayerStats{Cages: 1, FinishTicks: 600})
+	if earned != StarFinished|StarOrbs|StarTime {
+		t.Errorf("Earned = %04b, want all but the cages", earned)
+	}
+	if !p.Record("one", earned) || p.Record("one", StarFinished) {
+		t.Error("Record should report only new stars")
+	}
+	if !c.Unlocked(1, p) || c.Next(p) != 1 {
+		t.Error("Finishing the first level should unlock the second")
+	}
+	if levels, stars := c.Missing(2, p); len(levels) != 0 || stars != 3 {
+		t.Errorf("Bonus missing %q and %d stars, want 3 stars", levels, stars)
+	}
+	p.Record("two", c.Levels[1].Earned(protocol.PlayerStats{FinishTicks: 1 << 20}))
+	if !c.Unlocked(2, p) || p.Total() != 7 {
+		t.Errorf("%d stars should unlock the bonus level", p.Total())
+	}
+	if c.Levels[0].Earned(protocol.PlayerStats{Cages: 2}) != 0 {
+		t.Error("No stars without finishing")
+	}
+
+	bad := fstest.MapFS{"c.json": {Data: []byte(`{"levels": [{"file": "a.json", "requires": ["b"]}, {"file": "b.json"}]}`)}}
+	if _, err := ReadCampaign(bad, "c.json"); err == nil {
+		t.Error("Requiring a later level should be refused")
+	}
+}
+
+// TestBundledCampaign tests that the campaign in assets loads with every
+// level
+func TestBundledCampaign(t *testing.T) {
+	c, err := ReadCampaign(os.DirFS("../../assets"), "campaign.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range c.Levels {
+		if _, err := c.LoadLevel(i); err != nil {
+			t.Error(err)
+		}
+	}
+}
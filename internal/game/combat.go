@@ -0,0 +1,174 @@
+package game
+
+import (
+	"github.com/andersfylling/rayman-slides/internal/collision"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+	"github.com/mlange-42/ark/ecs"
+)
+
+// FistDamage is the health a fist takes on hit
+const FistDamage = 1
+
+// fistSize is the side of a fist's square hitbox
+const fistSize = 0.4
+
+// exitRadius is how close a player must get to the level exit to finish
+const exitRadius = 1.0
+
+// hitbox returns an entity's box. Positions are at the feet, horizontally
+// centered, matching how renderers draw sprites.
+func hitbox(pos *Position, col *Collider) collision.AABB {
+	return collision.NewAABB(pos.X-col.Width/2+col.OffsetX, pos.Y-col.Height+col.OffsetY, col.Width, col.Height)
+}
+
+// fistBox returns a fist's box, centered on its position
+func fistBox(pos *Position) collision.AABB {
+	return collision.NewAABB(pos.X-fistSize/2, pos.Y-fistSize/2, fistSize, fistSize)
+}
+
+// runCombatSystem applies fist hits. A fist hits the first entity with
+// health it overlaps, other than its owner, and is consumed. Players are
+// only hit when FriendlyFire is set. Spiky enemies deflect fists coming
+// down on them and shielded ones fists from the front. Enemies that survive
+// are knocked back by the fist's charge.
+func (w *World) runCombatSystem() {
+	hits := w.fistHits[:0]
+
+	fists := w.fistFilter.Query()
+	for fists.Next() {
+		pos, _, fist := fists.Get()
+		box := fistBox(pos)
+
+		targets := w.targetFilter.Query()
+		for targets.Next() {
+			tpos, col, _ := targets.Get()
+			target := targets.Entity()
+			if w.playerMap.HasAll(target) {
+				if !w.FriendlyFire || w.playerMap.Get(target).ID == fist.OwnerID {
+					continue
+				}
+			}
+			if tbox := hitbox(tpos, col); tbox.Overlaps(box) {
+				fromAbove := pos.Y < tbox.Y
+				deflected := w.deflects(target, fromAbove, !fist.FacingRight)
+				hits = append(hits, fistHit{
+					fist: fists.Entity(), target: target, attacker: fist.OwnerID, deflected: deflected,
+					right: fist.FacingRight, charge: fist.Charge(),
+				})
+				targets.Close()
+				break
+			}
+		}
+	}
+
+	// Apply after the queries; a target may be hit by several fists
+	for _, h := range hits {
+		if w.isActive(h.fist) {
+			w.releaseFist(h.fist)
+		}
+		switch {
+		case !w.ECS.Alive(h.target):
+		case h.deflected:
+			w.block(h.target, h.attacker)
+		default:
+			w.damageCharged(h.target, h.attacker, FistDamage, h.charge)
+			if w.ECS.Alive(h.target) {
+				w.knockback(h.target, h.attacker, h.right, h.charge)
+			}
+		}
+	}
+	w.fistHits = hits
+}
+
+// fistHit is a fist overlapping a target, applied once the combat queries
+// are done
+type fistHit struct {
+	fist, target ecs.Entity
+	attacker     int
+	deflected    bool
+	right        bool
+	charge       float64
+}
+
+// damage applies damage from a player to an entity with health, killing it
+// at zero
+func (w *World) damage(target ecs.Entity, attacker, amount int) {
+	w.damageCharged(target, attacker, amount, 0)
+}
+
+// damageCharged is damage from a fist thrown with the given charge, which
+// the damage event carries for client effects
+func (w *World) damageCharged(target ecs.Entity, attacker, amount int, charge float64) {
+	id := w.NetIDOf(target)
+	player := 0
+	if w.playerMap.HasAll(target) {
+		player = w.playerMap.Get(target).ID
+	}
+	if w.spectating[player] {
+		return // Spectators can't be hurt
+	}
+
+	hit := Event{Type: EventDamage, Tick: w.Tick, Entity: id, Player: player, Attacker: attacker, Amount: amount, Charge: charge, Kind: w.kindOf(target)}
+	if pos, _, _ := w.bodyMap.Get(target); pos != nil {
+		hit.X, hit.Y = pos.X, pos.Y
+	}
+
+	enemy := w.enemyMap.HasAll(target)
+	health := w.healthMap.Get(target)
+	health.Current -= amount
+	w.changeStats(attacker, func(ps *protocol.PlayerStats) { ps.Damage += amount })
+	w.changeStats(player, func(ps *protocol.PlayerStats) { ps.DamageTaken += amount })
+	w.comboBreak(player)
+	w.emit(hit)
+
+	if health.Current <= 0 && w.dummyMap.HasAll(target) {
+		health.Current = health.Max
+	}
+	if health.Current <= 0 {
+		w.removeEntity(target)
+		w.changeStats(player, func(ps *protocol.PlayerStats) { ps.Deaths++ })
+		if enemy {
+			w.comboKill(attacker)
+		}
+		w.emit(Event{Type: EventDeath, Tick: w.Tick, Entity: id, Player: player, Attacker: attacker, X: hit.X, Y: hit.Y, Kind: hit.Kind})
+	}
+}
+
+// kindOf returns an entity's enemy type, or its sprite for other entities
+func (w *World) kindOf(entity ecs.Entity) string {
+	switch {
+	case w.enemyMap.HasAll(entity):
+		return w.enemyMap.Get(entity).Type
+	case w.spriteMap.HasAll(entity):
+		return w.spriteMap.Get(entity).ID.String()
+	}
+	return ""
+}
+
+// runGoalSystem emits EventFinish the first time each player reaches the
+// level exit
+func (w *World) runGoalSystem() {
+	if w.level == nil || w.level.Exit == nil {
+		return
+	}
+	exit := w.level.Exit
+
+	var finished []int
+	query := w.playerFilter.Query()
+	for query.Next() {
+		pos, player := query.Get()
+		if w.finished[player.ID] {
+			continue
+		}
+		dx, dy := pos.X-exit.X, pos.Y-exit.Y
+		if dx*dx+dy*dy <= exitRadius*exitRadius {
+			finished = append(finished, player.ID)
+		}
+	}
+
+	for _, id := range finished {
+		w.finished[id] = true
+		w.changeStats(id, func(ps *protocol.PlayerStats) { ps.FinishTicks = max(w.Tick-w.levelStart, 1) })
+		w.emit(Event{Type: EventFinish, Tick: w.Tick, Player: id})
+	}
+}
@@ -0,0 +1,83 @@
+package game
+
+import (
+	"testing"
+)
+
+// TestCombatStats tests that a killing blow credits damage to the attacker,
+// and that Reset respawns a dead player and clears the stats.
+func TestCombatStats(t *testing.T) {
+	world := NewWorld()
+	world.FriendlyFire = true
+	world.LoadLevel(&Level{Name: "test", TileMap: DemoLevel(), PlayerSpawns: []SpawnPoint{{X: 5, Y: 10}}})
+	world.SpawnPlayer(1, "One", 5, 10)
+	victim := world.SpawnPlayer(2, "Two", 20, 10)
+	world.healthMap.Get(victim).Current = 1
+
+	var deaths []Event
+	world.Subscribe(func(e Event) {
+		if e.Type == EventDeath {
+			deaths = append(deaths, e)
+		}
+	})
+
+	x, y, _ := world.PlayerPosition(2)
+	world.SpawnFist(x-FistSpeed, y, true, MaxFistDistance, 1)
+	world.Update()
+
+	if len(deaths) != 1 || deaths[0].Player != 2 || deaths[0].Attacker != 1 {
+		t.Fatalf("Expected player 2 killed by player 1, got %+v", deaths)
+	}
+	stats := world.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("Expected stats for 2 players, got %d", len(stats))
+	}
+	if stats[0].Damage != FistDamage || stats[1].Deaths != 1 {
+		t.Errorf("Unexpected stats after the kill: %+v", stats)
+	}
+
+	world.Reset(nil)
+
+	if _, _, ok := world.PlayerPosition(2); !ok {
+		t.Error("Dead player should be respawned by Reset")
+	}
+	for _, ps := range world.Stats() {
+		if ps.Damage != 0 || ps.Deaths != 0 || ps.Name == "" {
+			t.Errorf("Stats should restart with the level, got %+v", ps)
+		}
+	}
+}
+
+// TestFistPool tests that a spent fist is hidden from queries and lookups
+// until a new throw reuses it under a new network ID.
+func TestFistPool(t *testing.T) {
+	world := NewWorld()
+	fist := world.SpawnFist(5, 5, true, MinFistDistance, 1)
+	oldID := world.NetIDOf(fist)
+	for range 3 {
+		world.Update()
+	}
+
+	query := world.fistFilter.Query()
+	if n := query.Count(); n != 0 {
+		t.Fatalf("Expected no active fists after the throw, got %d", n)
+	}
+	query.Close()
+	if _, ok := world.EntityByNetID(oldID); ok {
+		t.Error("A spent fist should not be found by its network ID")
+	}
+	if len(world.GetRenderables()) != 0 {
+		t.Error("A spent fist should not be rendered")
+	}
+
+	again := world.SpawnFist(8, 5, false, MinFistDistance, 2)
+	if again != fist {
+		t.Error("The next throw should reuse the spent fist")
+	}
+	if id := world.NetIDOf(again); id == oldID || id == 0 {
+		t.Errorf("Reused fist has network ID %d, want a new one (was %d)", id, oldID)
+	}
+	if _, _, sprite, f := world.fistMapper.Get(again); sprite.ID != SpriteFistLeft || f.OwnerID != 2 || f.StartX != 8 {
+		t.Errorf("Reused fist kept stale state: %v %+v", sprite.ID, *f)
+	}
+}
@@ -0,0 +1,47 @@
+package game
+
+import "github.com/andersfylling/rayman-slides/internal/protocol"
+
+// ComboTicks is how long a player has after each enemy kill to make the
+// next one and keep their combo going: 3 seconds at 60 TPS
+const ComboTicks = 180
+
+// A combo is a player's enemy kills in a row without taking damage. It is
+// kept in their stats (Combo, ComboEnd and BestCombo), so it rolls back and
+// replicates with them and the best one feeds the level's rank.
+
+// comboKill counts an enemy kill toward a player's combo and restarts its
+// window
+func (w *World) comboKill(playerID int) {
+	w.changeStats(playerID, func(ps *protocol.PlayerStats) {
+		ps.Combo++
+		ps.ComboEnd = w.Tick + ComboTicks
+		ps.BestCombo = max(ps.BestCombo, ps.Combo)
+	})
+}
+
+// comboBreak ends a player's combo, if they have one
+func (w *World) comboBreak(playerID int) {
+	if ps, ok := w.playerStats[playerID]; ok && ps.Combo > 0 {
+		w.changeStats(playerID, func(ps *protocol.PlayerStats) { ps.Combo, ps.ComboEnd = 0, 0 })
+	}
+}
+
+// runComboSystem ends the combos whose window ran out without a kill
+func (w *World) runComboSystem() {
+	for id, ps := range w.playerStats {
+		if ps.Combo > 0 && w.Tick >= ps.ComboEnd {
+			w.comboBreak(id)
+		}
+	}
+}
+
+// Combo returns a player's current combo and the ticks left to extend it
+// with another kill; no kills when they have none going
+func (w *World) Combo(playerID int) (kills, ticksLeft int) {
+	ps, ok := w.playerStats[playerID]
+	if !ok || ps.Combo == 0 || w.Tick >= ps.ComboEnd {
+		return 0, 0
+	}
+	return ps.Combo, int(ps.ComboEnd - w.Tick)
+}
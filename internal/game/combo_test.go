@@ -0,0 +1,45 @@
+package game
+
+import "testing"
+
+// TestCombo tests that kills within ComboTicks of each other build a combo,
+// that it runs out without another kill and ends when the player is hurt,
+// and that the best one is kept in the stats.
+func TestCombo(t *testing.T) {
+	world := NewWorld()
+	world.LoadLevel(&Level{Name: "test", TileMap: DemoLevel(), PlayerSpawns: []SpawnPoint{{X: 5, Y: 10}}})
+	player := world.SpawnPlayer(1, "One", 5, 10)
+	kill := func() {
+		enemy := world.SpawnEnemy("slime", 20, 10)
+		world.damage(enemy, 1, world.healthMap.Get(enemy).Current)
+	}
+
+	kill()
+	kill()
+	if kills, left := world.Combo(1); kills != 2 || left != ComboTicks {
+		t.Fatalf("Combo after two kills = %d with %d ticks left, want 2 with %d", kills, left, ComboTicks)
+	}
+	for range ComboTicks - 1 {
+		world.Update()
+	}
+	if kills, left := world.Combo(1); kills != 2 || left != 1 {
+		t.Fatalf("Combo on its last tick = %d with %d ticks left, want 2 with 1", kills, left)
+	}
+	kill()
+	for range ComboTicks {
+		world.Update()
+	}
+	if kills, _ := world.Combo(1); kills != 0 {
+		t.Errorf("Combo should run out without a kill, still %d", kills)
+	}
+
+	kill()
+	world.damage(player, 0, 1)
+	if kills, _ := world.Combo(1); kills != 0 {
+		t.Errorf("Combo should end when the player is hurt, still %d", kills)
+	}
+	stats := world.Stats()
+	if len(stats) != 1 || stats[0].BestCombo != 3 || stats[0].Combo != 0 {
+		t.Errorf("Expected a best combo of 3 and none going, got %+v", stats)
+	}
+}
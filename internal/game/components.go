@@ -20,8 +20,8 @@ type Collider struct {
 // Sprite component (for rendering)
 // Uses abstract sprite IDs - renderers map these to their native format
 type Sprite struct {
-	ID    string // Sprite identifier (e.g., "player", "slime", "platform")
-	Color uint32 // RGB color hint (renderers may use or ignore)
+	ID    SpriteID // Sprite identifier (e.g., SpritePlayer, SpriteSlime)
+	Color uint32   // RGB color hint (renderers may use or ignore)
 }
 
 // Player component (marks player-controlled entities)
@@ -63,6 +63,9 @@ type AttackState struct {
 
 	// Attack key tracking for edge detection
 	AttackWasPressed bool // Was attack key pressed last frame (for edge detection)
+	UseWasPressed    bool // Was use pressed last frame, for using objects (see Interactable)
+
+	Pounding bool // Ground pound: slamming straight down until landing
 }
 
 // AttackCooldown is how many ticks must pass before another attack can be initiated
@@ -77,12 +80,31 @@ const (
 	MinFistDistance = 1.0  // Minimum distance (no charge)
 	MaxFistDistance = 20.0 // Maximum distance (full charge) - 20x character width
 	FistSpeed       = 0.8  // Speed of the flying fist per tick
+	ChargeLevels    = 3    // Stages shown by the charge sprites and HUD
 )
 
+// ChargeLevel returns the charge stage, from 1 to ChargeLevels, for how long
+// the attack key has been held
+func ChargeLevel(chargeTicks int) int {
+	level := 1
+	if chargeTicks > MaxChargeTicks/3 {
+		level = 2
+	}
+	if chargeTicks > MaxChargeTicks*2/3 {
+		level = 3
+	}
+	return level
+}
+
 // Fist component marks a flying fist projectile
 type Fist struct {
-	StartX       float64 // Starting X position
-	MaxDistance  float64 // Maximum distance to travel
-	FacingRight  bool    // Direction of travel
-	OwnerID      int     // Player who threw the fist
+	StartX      float64 // Starting X position
+	MaxDistance float64 // Maximum distance to travel
+	FacingRight bool    // Direction of travel
+	OwnerID     int     // Player who threw the fist
+}
+
+// Charge returns how charged the fist was when thrown, from 0 to 1
+func (f *Fist) Charge() float64 {
+	return (f.MaxDistance - MinFistDistance) / (MaxFistDistance - MinFistDistance)
 }
@@ -0,0 +1,158 @@
+package game
+
+import (
+	"fmt"
+
+	"github.com/andersfylling/rayman-slides/internal/collision"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+	"github.com/mlange-42/ark/ecs"
+)
+
+// NoclipSpeed is how far a noclipping player flies per tick, in tiles
+const NoclipSpeed = 0.4
+
+// SetNoclip lets a player fly through tiles, or clears it: left, right,
+// jump and down move them without gravity, collision or ledges. It is for
+// the developer console. Like spectating it is kept across respawns and is
+// not part of WorldState, so a predicting client sets it on both worlds.
+func (w *World) SetNoclip(playerID int, on bool) {
+	if !on {
+		delete(w.noclip, playerID)
+		return
+	}
+	w.noclip[playerID] = true
+}
+
+// Noclip reports whether a player flies through tiles
+func (w *World) Noclip(playerID int) bool {
+	return w.noclip[playerID]
+}
+
+// noclipped reports whether an entity is a noclipping player
+func (w *World) noclipped(entity ecs.Entity) bool {
+	return len(w.noclip) > 0 && w.playerMap.HasAll(entity) && w.noclip[w.playerMap.Get(entity).ID]
+}
+
+// fly sets a noclipping player's velocity from their intents
+func fly(vel *Velocity, ctrl *Controller) {
+	vel.X, vel.Y = 0, 0
+	switch {
+	case ctrl.Intents&protocol.IntentLeft != 0:
+		vel.X = -NoclipSpeed
+	case ctrl.Intents&protocol.IntentRight != 0:
+		vel.X = NoclipSpeed
+	}
+	switch {
+	case ctrl.Intents&protocol.IntentJump != 0:
+		vel.Y = -NoclipSpeed
+	case ctrl.Intents&protocol.IntentDown != 0:
+		vel.Y = NoclipSpeed
+	}
+}
+
+// MovePlayer puts a live player at a position, at rest and off any ledge.
+// It reports whether the player was found.
+func (w *World) MovePlayer(playerID int, x, y float64) bool {
+	query := w.playerFilter.Query()
+	for query.Next() {
+		pos, player := query.Get()
+		if player.ID != playerID {
+			continue
+		}
+		entity := query.Entity()
+		query.Close()
+		pos.X, pos.Y = x, y
+		if w.bodyMap.HasAll(entity) {
+			_, vel, _ := w.bodyMap.Get(entity)
+			vel.X, vel.Y = 0, 0
+		}
+		if w.ledgeMapper.HasAll(entity) {
+			w.ledgeMapper.Get(entity).Hanging = false
+		}
+		return true
+	}
+	return false
+}
+
+// HealPlayer refills a live player's health. It reports whether the player
+// was found.
+func (w *World) HealPlayer(playerID int) bool {
+	query := w.playerFilter.Query()
+	for query.Next() {
+		if _, player := query.Get(); player.ID == playerID {
+			entity := query.Entity()
+			query.Close()
+			if !w.healthMap.HasAll(entity) {
+				return false
+			}
+			health := w.healthMap.Get(entity)
+			health.Current = health.Max
+			return true
+		}
+	}
+	return false
+}
+
+// GiveKey hands the players a key of the given color, as if picked up,
+// without an EventKey
+func (w *World) GiveKey(color string) error {
+	if _, ok := KeyColors[color]; !ok {
+		return fmt.Errorf("unknown key color %q", color)
+	}
+	if w.keys[color] {
+		return nil
+	}
+	w.keys[color] = true
+	w.keysVersion++
+	w.syncKeys()
+	return nil
+}
+
+// DebugBoxKind says what a DebugBox outlines
+type DebugBoxKind uint8
+
+const (
+	DebugBody   DebugBoxKind = iota // The box physics collides with tiles
+	DebugHitbox                     // The box fists hit
+	DebugFist                       // A flying fist's box
+)
+
+// DebugBox is a collision box for the debug overlay
+type DebugBox struct {
+	Box   collision.AABB
+	Kind  DebugBoxKind
+	Reach float64 // For a fist, the distance it has left to travel; negative going left
+}
+
+// AppendDebugBoxes appends the boxes the simulation collides with: the
+// tile collision box of every body under physics, the hitbox of every
+// entity with health, and each flying fist's box with its remaining
+// travel. Pass a reused slice to avoid allocating every frame.
+func (w *World) AppendDebugBoxes(dst []DebugBox) []DebugBox {
+	const colW, colH = 0.8, 0.9
+	bodies := w.physicsFilter.Query()
+	for bodies.Next() {
+		pos, _, _, _ := bodies.Get()
+		dst = append(dst, DebugBox{Box: collision.NewAABB(pos.X-colW/2, pos.Y, colW, colH), Kind: DebugBody})
+	}
+	targets := w.targetFilter.Query()
+	for targets.Next() {
+		pos, col, _ := targets.Get()
+		dst = append(dst, DebugBox{Box: hitbox(pos, col), Kind: DebugHitbox})
+	}
+	fists := w.fistFilter.Query()
+	for fists.Next() {
+		pos, _, fist := fists.Get()
+		traveled := pos.X - fist.StartX
+		reach := fist.MaxDistance - traveled
+		if !fist.FacingRight {
+			reach = -(fist.MaxDistance + traveled)
+		}
+		dst = append(dst, DebugBox{
+			Box:   fistBox(pos),
+			Kind:  DebugFist,
+			Reach: reach,
+		})
+	}
+	return dst
+}
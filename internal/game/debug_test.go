@@ -0,0 +1,118 @@
+package game
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// TestNoclip tests that a noclipping player flies through walls and hangs
+// in the air, and falls again once moved back with noclip off.
+func TestNoclip(t *testing.T) {
+	level, err := DecodeLevel([]byte(keyLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	world := NewWorld()
+	world.LoadLevel(level)
+	world.SpawnPlayer(1, "Test", 2, 4)
+	for range 10 {
+		world.Update()
+	}
+
+	world.SetNoclip(1, true)
+	world.SetPlayerIntent(1, protocol.IntentRight)
+	for range 20 {
+		world.Update()
+	}
+	if x, _, _ := world.PlayerPosition(1); x < 9 {
+		t.Errorf("Noclip player at x %.1f, want through the gate at 7", x)
+	}
+	world.SetPlayerIntent(1, protocol.IntentJump)
+	for range 5 {
+		world.Update()
+	}
+	_, y, _ := world.PlayerPosition(1)
+	if y > 3 {
+		t.Errorf("Noclip player at y %.1f, want flown up from the floor", y)
+	}
+	world.SetPlayerIntent(1, protocol.IntentNone)
+	for range 30 {
+		world.Update()
+	}
+	if _, y2, _ := world.PlayerPosition(1); y2 != y {
+		t.Errorf("Noclip player drifted from y %.2f to %.2f with no input", y, y2)
+	}
+
+	world.SetNoclip(1, false)
+	if world.Noclip(1) {
+		t.Fatal("Noclip still set")
+	}
+	if !world.MovePlayer(1, 2, 2) {
+		t.Fatal("MovePlayer did not find the player")
+	}
+	for range 60 {
+		world.Update()
+	}
+	if x, y, _ := world.PlayerPosition(1); x != 2 || !world.PlayerOnGround(1) || y > 5 {
+		t.Errorf("Player at (%.2f, %.2f), want landed at x 2", x, y)
+	}
+}
+
+// TestGiveKey tests that a key given by the console is held like one
+// picked up, and that unknown colors are refused.
+func TestGiveKey(t *testing.T) {
+	level, err := DecodeLevel([]byte(keyLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	world := NewWorld()
+	world.LoadLevel(level)
+	if err := world.GiveKey("red"); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(world.Keys(), []string{"red"}) {
+		t.Errorf("Keys = %q, want red", world.Keys())
+	}
+	for _, r := range world.GetRenderables() {
+		if r.SpriteID.String() == KeySprite("red") {
+			t.Error("The given key still lies in the level")
+		}
+	}
+	if err := world.GiveKey("purple"); err == nil {
+		t.Error("An unknown key color should be refused")
+	}
+}
+
+// TestDebugBoxes tests that the overlay's boxes cover a player's body and
+// hitbox and a fist with the distance it has left, going left.
+func TestDebugBoxes(t *testing.T) {
+	world := NewWorld()
+	world.SpawnPlayer(1, "Test", 5, 5)
+	world.SpawnFist(10, 4, false, 3, 1)
+
+	var body, hit, fist int
+	for _, b := range world.AppendDebugBoxes(nil) {
+		switch b.Kind {
+		case DebugBody:
+			body++
+			if !b.Box.Contains(5, 5.5) {
+				t.Errorf("Body box %+v misses the player's middle", b.Box)
+			}
+		case DebugHitbox:
+			hit++
+			if !b.Box.Contains(5, 4.5) {
+				t.Errorf("Hitbox %+v misses the player", b.Box)
+			}
+		case DebugFist:
+			fist++
+			if !b.Box.Contains(10, 3.5) || b.Reach != -3 {
+				t.Errorf("Fist box %+v with reach %v, want at (10, 3.5) with -3 left", b.Box, b.Reach)
+			}
+		}
+	}
+	if body != 1 || hit != 1 || fist != 1 {
+		t.Errorf("%d body, %d hit and %d fist boxes, want one each", body, hit, fist)
+	}
+}
@@ -1,15 +1,22 @@
 package game
 
 import (
+	"cmp"
+	"encoding/binary"
+	"encoding/json"
+	"hash"
 	"hash/fnv"
+	"maps"
+	"slices"
 
 	"github.com/andersfylling/rayman-slides/internal/protocol"
 	"github.com/mlange-42/ark/ecs"
 )
 
-// EntityState captures the full state of an entity for snapshot/restore
+// EntityState captures the full state of an entity for snapshot/restore.
+// Entities are identified by their stable network ID, not by ecs handle.
 type EntityState struct {
-	Entity    ecs.Entity
+	ID        protocol.EntityID
 	Position  Position
 	Velocity  Velocity
 	Grounded  Grounded
@@ -17,22 +24,76 @@ type EntityState struct {
 	Player    Player
 	HasAttack bool
 	Attack    AttackState
+	HasLedge  bool
+	Ledge     Ledge
+	HasWarp   bool
+	Warp      Warp
+	HasSprite bool
+	Sprite    Sprite
+	HasHealth bool
+	Health    Health
+	HasEnemy  bool
+	Enemy     Enemy
+	HasPatrol bool
+	Patrol    Patrol
+	HasDiver  bool
+	Diver     Diver
+}
+
+// ComponentChecksums hashes one replicated component across all entities
+// per entry, indexed by protocol component bit (0 is position), so a
+// checksum mismatch can be narrowed down to the components that diverged
+type ComponentChecksums [protocol.ComponentCount]uint32
+
+// Diff returns the components whose checksums differ
+func (c *ComponentChecksums) Diff(other *ComponentChecksums) protocol.ComponentMask {
+	var m protocol.ComponentMask
+	for i := range c {
+		if c[i] != other[i] {
+			m |= 1 << i
+		}
+	}
+	return m
 }
 
 // WorldState is a complete snapshot of the game world for rollback
 type WorldState struct {
-	Tick     uint64
-	Entities []EntityState
-	Checksum uint32
+	Tick       uint64
+	Entities   []EntityState // Sorted by ID
+	Checksum   uint32        // Over the tick and Components
+	Components ComponentChecksums
+
+	Stats        []protocol.PlayerStats // By player ID
+	StatsVersion uint64
+
+	Broken []int    // Tile indexes broken since the level started, in order
+	Gates  []string // Gates toggled from how the level starts, sorted
+	Keys   []string // Key colors the players hold, sorted
+
+	LevelVersion uint64 // Changes whenever Broken, Gates or Keys do
+
+	Flags   map[string]int             // Script flags, see SetFlag
+	Scripts map[string]json.RawMessage // Level scripts' own data by script name, see ScriptState
+}
+
+// ScriptState saves and restores the level scripts' own data with the
+// world's snapshots, so rollbacks and checkpoints keep it. The scripting
+// engine sets itself with SetScriptState.
+type ScriptState interface {
+	SaveScripts() map[string]json.RawMessage
+	RestoreScripts(map[string]json.RawMessage)
+}
+
+// SetScriptState makes Snapshot and Restore include the scripts' data
+func (w *World) SetScriptState(s ScriptState) {
+	w.scriptState = s
 }
 
 // Snapshot creates a complete snapshot of the current world state
 // This captures all entity states needed for rollback and replay
 func (w *World) Snapshot() WorldState {
-	state := WorldState{
-		Tick:     w.Tick,
-		Entities: make([]EntityState, 0),
-	}
+	state := WorldState{Tick: w.Tick}
+	entities := make([]EntityState, 0, len(w.netEntities))
 
 	// Capture all physics entities (players and enemies)
 	query := w.physicsFilter.Query()
@@ -41,123 +102,207 @@ func (w *World) Snapshot() WorldState {
 		pos, vel, _, grounded := query.Get()
 
 		es := EntityState{
-			Entity:   entity,
+			ID:       w.NetIDOf(entity),
 			Position: *pos,
 			Velocity: *vel,
 			Grounded: *grounded,
 		}
 
-		// Check if this entity has Player component
-		playerQuery := w.playerFilter.Query()
-		for playerQuery.Next() {
-			if playerQuery.Entity() == entity {
-				_, player := playerQuery.Get()
-				es.HasPlayer = true
-				es.Player = *player
-				break
-			}
+		if w.playerMap.HasAll(entity) {
+			es.HasPlayer = true
+			es.Player = *w.playerMap.Get(entity)
 		}
-		playerQuery.Close()
-
-		// Check if this entity has AttackState component
-		attackQuery := w.attackFilter.Query()
-		for attackQuery.Next() {
-			if attackQuery.Entity() == entity {
-				_, _, _, attack, _, _ := attackQuery.Get()
-				es.HasAttack = true
-				es.Attack = *attack
-				break
-			}
+		if w.attackMapper.HasAll(entity) {
+			es.HasAttack = true
+			es.Attack = *w.attackMapper.Get(entity)
+		}
+		if w.ledgeMapper.HasAll(entity) {
+			es.HasLedge = true
+			es.Ledge = *w.ledgeMapper.Get(entity)
+		}
+		if w.warpMapper.HasAll(entity) {
+			es.HasWarp = true
+			es.Warp = *w.warpMapper.Get(entity)
+		}
+		if w.spriteMap.HasAll(entity) {
+			es.HasSprite = true
+			es.Sprite = *w.spriteMap.Get(entity)
+		}
+		if w.healthMap.HasAll(entity) {
+			es.HasHealth = true
+			es.Health = *w.healthMap.Get(entity)
+		}
+		if w.enemyMap.HasAll(entity) {
+			es.HasEnemy = true
+			es.Enemy = *w.enemyMap.Get(entity)
+		}
+		if w.patrolMap.HasAll(entity) {
+			es.HasPatrol = true
+			es.Patrol = *w.patrolMap.Get(entity)
+		}
+		if w.diverMap.HasAll(entity) {
+			es.HasDiver = true
+			es.Diver = *w.diverMap.Get(entity)
 		}
-		attackQuery.Close()
 
-		state.Entities = append(state.Entities, es)
+		entities = append(entities, es)
+	}
+
+	// Query order depends on archetypes; ID order is stable across worlds.
+	// Sorting small keys and copying each state once is much cheaper than
+	// swapping the large states themselves, which was most of the time.
+	type key struct {
+		id    protocol.EntityID
+		index int
+	}
+	keys := make([]key, len(entities))
+	for i := range entities {
+		keys[i] = key{entities[i].ID, i}
+	}
+	slices.SortFunc(keys, func(a, b key) int { return cmp.Compare(a.id, b.id) })
+	state.Entities = make([]EntityState, len(entities))
+	for i, k := range keys {
+		state.Entities[i] = entities[k.index]
 	}
 
 	// Calculate checksum for fast comparison
-	state.Checksum = state.computeChecksum()
+	state.Checksum, state.Components = state.computeChecksums()
+	state.Stats = w.Stats()
+	state.StatsVersion = w.statsVersion
+	state.Broken = slices.Clone(w.broken)
+	state.Gates = w.toggledGates()
+	state.Keys = w.Keys()
+	state.LevelVersion = w.tilesVersion + w.keysVersion
+	if len(w.flags) > 0 {
+		state.Flags = maps.Clone(w.flags)
+	}
+	if w.scriptState != nil {
+		state.Scripts = w.scriptState.SaveScripts()
+	}
 
 	return state
 }
 
-// Restore applies a saved world state, rolling back to that point in time
+// Restore applies a saved world state, rolling back to that point in time.
+// Entities are matched by network ID: ones despawned since the snapshot are
+// recreated with their old ID, and physics entities spawned since are removed.
 func (w *World) Restore(state WorldState) {
 	w.Tick = state.Tick
 
-	for _, es := range state.Entities {
-		// Find and update the entity
-		// We use the physics filter since all relevant entities have physics
-		query := w.physicsFilter.Query()
-		for query.Next() {
-			if query.Entity() == es.Entity {
-				pos, vel, _, grounded := query.Get()
-				*pos = es.Position
-				*vel = es.Velocity
-				*grounded = es.Grounded
-				break
-			}
+	keep := make(map[protocol.EntityID]bool, len(state.Entities))
+	for i := range state.Entities {
+		es := &state.Entities[i]
+		keep[es.ID] = true
+
+		entity, ok := w.EntityByNetID(es.ID)
+		if !ok {
+			entity = w.respawn(es)
 		}
-		query.Close()
-
-		// Restore attack state if present
-		if es.HasAttack {
-			attackQuery := w.attackFilter.Query()
-			for attackQuery.Next() {
-				if attackQuery.Entity() == es.Entity {
-					_, _, _, attack, _, _ := attackQuery.Get()
-					*attack = es.Attack
-					break
-				}
-			}
-			attackQuery.Close()
+
+		pos, vel, grounded := w.bodyMap.Get(entity)
+		*pos = es.Position
+		*vel = es.Velocity
+		*grounded = es.Grounded
+
+		if es.HasAttack && w.attackMapper.HasAll(entity) {
+			*w.attackMapper.Get(entity) = es.Attack
+		}
+		if es.HasLedge && w.ledgeMapper.HasAll(entity) {
+			*w.ledgeMapper.Get(entity) = es.Ledge
+		}
+		if es.HasWarp && w.warpMapper.HasAll(entity) {
+			*w.warpMapper.Get(entity) = es.Warp
+		}
+		if es.HasSprite && w.spriteMap.HasAll(entity) {
+			*w.spriteMap.Get(entity) = es.Sprite
+		}
+		if es.HasHealth && w.healthMap.HasAll(entity) {
+			*w.healthMap.Get(entity) = es.Health
+		}
+		if es.HasEnemy && w.enemyMap.HasAll(entity) {
+			*w.enemyMap.Get(entity) = es.Enemy
+		}
+		if es.HasPatrol && w.patrolMap.HasAll(entity) {
+			*w.patrolMap.Get(entity) = es.Patrol
+		}
+		if es.HasDiver && w.diverMap.HasAll(entity) {
+			*w.diverMap.Get(entity) = es.Diver
 		}
 	}
+
+	// Remove physics entities the snapshot doesn't know about
+	var toRemove []ecs.Entity
+	query := w.physicsFilter.Query()
+	for query.Next() {
+		if !keep[w.NetIDOf(query.Entity())] {
+			toRemove = append(toRemove, query.Entity())
+		}
+	}
+	for _, e := range toRemove {
+		w.removeEntity(e)
+	}
+
+	clear(w.playerStats)
+	for _, ps := range state.Stats {
+		w.playerStats[ps.PlayerID] = &ps
+	}
+	w.statsVersion++
+	w.SetLevelState(state.Broken, state.Gates, state.Keys)
+	clear(w.flags)
+	maps.Copy(w.flags, state.Flags)
+	if w.scriptState != nil {
+		w.scriptState.RestoreScripts(state.Scripts)
+	}
 }
 
-// computeChecksum calculates a fast hash for comparing world states
-func (state *WorldState) computeChecksum() uint32 {
-	h := fnv.New32a()
+// respawn recreates a despawned entity from its saved state
+func (w *World) respawn(es *EntityState) ecs.Entity {
+	if es.HasPlayer {
+		return w.spawnPlayer(es.ID, es.Player.ID, es.Player.Name, es.Position.X, es.Position.Y)
+	}
+	return w.spawnEnemy(es.ID, es.Enemy.Type, es.Sprite, es.Position.X, es.Position.Y)
+}
 
-	// Hash tick
-	tickBytes := make([]byte, 8)
-	tickBytes[0] = byte(state.Tick)
-	tickBytes[1] = byte(state.Tick >> 8)
-	tickBytes[2] = byte(state.Tick >> 16)
-	tickBytes[3] = byte(state.Tick >> 24)
-	tickBytes[4] = byte(state.Tick >> 32)
-	tickBytes[5] = byte(state.Tick >> 40)
-	tickBytes[6] = byte(state.Tick >> 48)
-	tickBytes[7] = byte(state.Tick >> 56)
-	h.Write(tickBytes)
-
-	// Hash each entity's position (most important for mismatch detection)
-	for _, es := range state.Entities {
-		// Convert float64 to bytes for hashing
-		// Using a simple representation - position * 1000 to preserve some precision
-		posX := int64(es.Position.X * 1000)
-		posY := int64(es.Position.Y * 1000)
-
-		posBytes := make([]byte, 16)
-		posBytes[0] = byte(posX)
-		posBytes[1] = byte(posX >> 8)
-		posBytes[2] = byte(posX >> 16)
-		posBytes[3] = byte(posX >> 24)
-		posBytes[4] = byte(posX >> 32)
-		posBytes[5] = byte(posX >> 40)
-		posBytes[6] = byte(posX >> 48)
-		posBytes[7] = byte(posX >> 56)
-		posBytes[8] = byte(posY)
-		posBytes[9] = byte(posY >> 8)
-		posBytes[10] = byte(posY >> 16)
-		posBytes[11] = byte(posY >> 24)
-		posBytes[12] = byte(posY >> 32)
-		posBytes[13] = byte(posY >> 40)
-		posBytes[14] = byte(posY >> 48)
-		posBytes[15] = byte(posY >> 56)
-		h.Write(posBytes)
+// computeChecksums hashes every replicated component field, as encoded for
+// the wire, per component and overall. Sprites are hashed by name rather
+// than by their interned index.
+func (state *WorldState) computeChecksums() (uint32, ComponentChecksums) {
+	var hashes [protocol.ComponentCount]hash.Hash32
+	for i := range hashes {
+		hashes[i] = fnv.New32a()
+	}
+	sprites := protocol.NewSpriteTable()
+
+	buf := make([]byte, 0, 64)
+	for i := range state.Entities {
+		es := &state.Entities[i]
+		c := es.Components(sprites)
+		for bit := range protocol.ComponentCount {
+			one := c
+			one.Mask &= 1 << bit
+			if one.Mask == 0 {
+				continue
+			}
+			buf = binary.LittleEndian.AppendUint64(buf[:0], uint64(es.ID))
+			if one.Mask == protocol.CompSprite {
+				buf = append(buf, es.Sprite.ID.String()...)
+				buf = binary.LittleEndian.AppendUint32(buf, es.Sprite.Color)
+			} else {
+				buf = protocol.AppendComponents(buf, &one)
+			}
+			hashes[bit].Write(buf)
+		}
 	}
 
-	return h.Sum32()
+	var components ComponentChecksums
+	buf = binary.LittleEndian.AppendUint64(buf[:0], state.Tick)
+	for i, h := range hashes {
+		components[i] = h.Sum32()
+		buf = binary.LittleEndian.AppendUint32(buf, components[i])
+	}
+	h := fnv.New32a()
+	h.Write(buf)
+	return h.Sum32(), components
 }
 
 // StatesMatch compares two world states for equivalence within tolerance
@@ -167,6 +312,11 @@ func StatesMatch(a, b *WorldState, tolerance float64) bool {
 		return true
 	}
 
+	// Positions may differ within tolerance, anything else must match
+	if a.Components.Diff(&b.Components)&^protocol.CompPosition != 0 {
+		return false
+	}
+
 	// If checksums differ, do detailed comparison
 	if len(a.Entities) != len(b.Entities) {
 		return false
@@ -176,6 +326,10 @@ func StatesMatch(a, b *WorldState, tolerance float64) bool {
 		ea := &a.Entities[i]
 		eb := &b.Entities[i]
 
+		if ea.ID != eb.ID {
+			return false
+		}
+
 		// Compare positions within tolerance
 		dx := ea.Position.X - eb.Position.X
 		dy := ea.Position.Y - eb.Position.Y
@@ -199,65 +353,64 @@ func StatesMatch(a, b *WorldState, tolerance float64) bool {
 	return true
 }
 
-// ToProtocolSnapshot converts a WorldState to a protocol.StateSnapshot for network transmission
-func (state *WorldState) ToProtocolSnapshot() protocol.StateSnapshot {
+// ToProtocolSnapshot converts a WorldState to a protocol.StateSnapshot for network transmission.
+// Sprite IDs are interned into sprites; the full table is included since the snapshot is full.
+func (state *WorldState) ToProtocolSnapshot(sprites *protocol.SpriteTable) protocol.StateSnapshot {
 	snapshot := protocol.StateSnapshot{
 		Tick:     state.Tick,
 		Full:     true,
 		Entities: make([]protocol.EntityState, 0, len(state.Entities)),
 	}
 
-	for _, es := range state.Entities {
-		// Serialize entity state to bytes
-		// Format: [posX:8][posY:8][velX:8][velY:8][grounded:1][hasPlayer:1][playerID:4]
-		data := make([]byte, 0, 38)
-
-		// Position (as int64 * 1000 for precision)
-		posX := int64(es.Position.X * 1000)
-		posY := int64(es.Position.Y * 1000)
-		data = appendInt64(data, posX)
-		data = appendInt64(data, posY)
-
-		// Velocity
-		velX := int64(es.Velocity.X * 1000)
-		velY := int64(es.Velocity.Y * 1000)
-		data = appendInt64(data, velX)
-		data = appendInt64(data, velY)
-
-		// Grounded
-		if es.Grounded.OnGround {
-			data = append(data, 1)
-		} else {
-			data = append(data, 0)
-		}
-
-		// Player info
-		if es.HasPlayer {
-			data = append(data, 1)
-			data = appendInt32(data, int32(es.Player.ID))
-		} else {
-			data = append(data, 0)
-		}
-
+	for i := range state.Entities {
+		c := state.Entities[i].Components(sprites)
 		snapshot.Entities = append(snapshot.Entities, protocol.EntityState{
-			ID:         protocol.EntityID(es.Entity.ID()),
-			Components: data,
+			ID:         state.Entities[i].ID,
+			Components: protocol.AppendComponents(make([]byte, 0, protocol.EncodedSize(c.Mask)), &c),
 		})
 	}
+	snapshot.Sprites = sprites.Since(0)
 
 	return snapshot
 }
 
-// Helper functions for byte serialization
-func appendInt64(data []byte, v int64) []byte {
-	return append(data,
-		byte(v), byte(v>>8), byte(v>>16), byte(v>>24),
-		byte(v>>32), byte(v>>40), byte(v>>48), byte(v>>56),
-	)
-}
-
-func appendInt32(data []byte, v int32) []byte {
-	return append(data,
-		byte(v), byte(v>>8), byte(v>>16), byte(v>>24),
-	)
+// Components converts an entity state to its wire representation
+func (es *EntityState) Components(sprites *protocol.SpriteTable) protocol.EntityComponents {
+	c := protocol.EntityComponents{
+		Mask:     protocol.CompPosition | protocol.CompVelocity | protocol.CompGrounded,
+		Position: protocol.PositionData{X: protocol.ToFixed(es.Position.X), Y: protocol.ToFixed(es.Position.Y)},
+		Velocity: protocol.VelocityData{X: protocol.ToFixed(es.Velocity.X), Y: protocol.ToFixed(es.Velocity.Y)},
+		Grounded: es.Grounded.OnGround,
+	}
+	if es.HasPlayer {
+		c.Mask |= protocol.CompPlayer
+		c.PlayerID = uint32(es.Player.ID)
+	}
+	if es.HasSprite {
+		c.Mask |= protocol.CompSprite
+		c.Sprite = protocol.SpriteData{Index: sprites.Intern(es.Sprite.ID.String()), Color: es.Sprite.Color}
+	}
+	if es.HasHealth {
+		c.Mask |= protocol.CompHealth
+		c.Health = protocol.HealthData{Current: int16(es.Health.Current), Max: int16(es.Health.Max)}
+	}
+	if es.HasAttack {
+		c.Mask |= protocol.CompAttack
+		var flags uint8
+		if es.Attack.Attacking {
+			flags |= protocol.AttackFlagAttacking
+		}
+		if es.Attack.FacingRight {
+			flags |= protocol.AttackFlagFacingRight
+		}
+		if es.Attack.Charging {
+			flags |= protocol.AttackFlagCharging
+		}
+		c.Attack = protocol.AttackData{
+			Flags:       flags,
+			TicksLeft:   uint8(es.Attack.TicksLeft),
+			ChargeTicks: uint16(es.Attack.ChargeTicks),
+		}
+	}
+	return c
 }
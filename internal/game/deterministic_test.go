@@ -0,0 +1,160 @@
+package game
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// TestRestoreAcrossDespawn tests that Restore matches entities by network ID,
+// recreating ones removed after the snapshot and removing ones spawned after.
+func TestRestoreAcrossDespawn(t *testing.T) {
+	world := NewWorld()
+	world.SpawnPlayer(1, "Test", 10, 10)
+	slime := world.SpawnEnemy("slime", 15, 10)
+	slimeID := world.NetIDOf(slime)
+
+	saved := world.Snapshot()
+	if len(saved.Entities) != 2 {
+		t.Fatalf("Expected 2 entities in snapshot, got %d", len(saved.Entities))
+	}
+
+	// Despawn the slime and spawn a bat; ark may reuse the slime's handle
+	world.removeEntity(slime)
+	batID := world.NetIDOf(world.SpawnEnemy("bat", 20, 10))
+	if batID == slimeID {
+		t.Fatal("Network IDs must not be reused")
+	}
+	for i := 0; i < 5; i++ {
+		world.Update()
+	}
+
+	world.Restore(saved)
+
+	if _, ok := world.EntityByNetID(batID); ok {
+		t.Error("Entity spawned after the snapshot should be removed")
+	}
+	entity, ok := world.EntityByNetID(slimeID)
+	if !ok {
+		t.Fatal("Despawned entity should be recreated with its old ID")
+	}
+	if sprite := world.spriteMap.Get(entity); sprite.ID != SpriteSlime {
+		t.Errorf("Recreated entity has sprite %v, want slime", sprite.ID)
+	}
+
+	restored := world.Snapshot()
+	if restored.Checksum != saved.Checksum || !StatesMatch(&restored, &saved, 0) {
+		t.Error("Restored state should match the snapshot")
+	}
+}
+
+// TestComponentChecksums tests that divergence in a component other than
+// position changes the checksum and is attributed to that component.
+func TestComponentChecksums(t *testing.T) {
+	world := NewWorld()
+	player := world.SpawnPlayer(1, "Test", 10, 10)
+	world.SpawnEnemy("slime", 15, 10)
+	before := world.Snapshot()
+
+	_, vel, _ := world.bodyMap.Get(player)
+	vel.X += 0.25
+	world.healthMap.Get(player).Current--
+	after := world.Snapshot()
+
+	if after.Checksum == before.Checksum {
+		t.Fatal("Velocity and health changes should change the checksum")
+	}
+	if diff := after.Components.Diff(&before.Components); diff != protocol.CompVelocity|protocol.CompHealth {
+		t.Errorf("Components differ in %v, want velocity+health", diff)
+	}
+	if StatesMatch(&after, &before, 1) {
+		t.Error("States with equal positions but different velocities should not match")
+	}
+}
+
+// Golden result of determinismRun. If a change to the simulation is meant to
+// alter it, update both from the test's failure message.
+const (
+	goldenChecksum  uint32 = 0x3b7d1c42
+	goldenExactHash uint64 = 0xf21ddb6ac3255b70
+)
+
+// determinismRun plays a fixed pseudo-random 10,000-tick input script for
+// two players on the demo level and returns the final state
+func determinismRun() WorldState {
+	world := NewWorld()
+	world.FriendlyFire = true
+	level := NewDemoLevel(80, 30)
+	world.LoadLevel(level)
+	for id := 1; id <= 2; id++ {
+		spawn := level.PlayerSpawn(id - 1)
+		world.SpawnPlayer(id, fmt.Sprintf("P%d", id), spawn.X, spawn.Y)
+	}
+
+	// A fixed LCG, so the script doesn't depend on math/rand's algorithms
+	seed := uint32(1)
+	intents := [2]protocol.Intent{}
+	for tick := 0; tick < 10000; tick++ {
+		for i := range intents {
+			seed = seed*1664525 + 1013904223
+			if seed>>28 == 0 { // Change intents every ~16 ticks
+				intents[i] = protocol.Intent(seed>>8) & (protocol.IntentLeft | protocol.IntentRight | protocol.IntentJump | protocol.IntentAttack)
+			}
+			world.SetPlayerIntent(i+1, intents[i])
+		}
+		world.Update()
+	}
+	return world.Snapshot()
+}
+
+// exactHash hashes every bit of the entity state and the player stats.
+// Checksum only keeps positions to 1/1000, which can hide float drift.
+// Stats are hashed field by field, so a new field only changes the golden
+// once it is added here.
+func exactHash(state *WorldState) uint64 {
+	h := fnv.New64a()
+	for _, es := range state.Entities {
+		binary.Write(h, binary.LittleEndian, es.ID)
+		for _, f := range []float64{es.Position.X, es.Position.Y, es.Velocity.X, es.Velocity.Y} {
+			binary.Write(h, binary.LittleEndian, math.Float64bits(f))
+		}
+		binary.Write(h, binary.LittleEndian, int64(es.Health.Current))
+	}
+	for _, ps := range state.Stats {
+		h.Write([]byte(ps.Name))
+		for _, n := range []int{ps.PlayerID, ps.Orbs, ps.Cages, ps.Damage, ps.DamageTaken, ps.Deaths, ps.Combo, ps.BestCombo} {
+			binary.Write(h, binary.LittleEndian, int64(n))
+		}
+		binary.Write(h, binary.LittleEndian, ps.FinishTicks)
+		binary.Write(h, binary.LittleEndian, ps.ComboEnd)
+	}
+	return h.Sum64()
+}
+
+// TestDeterminismGolden tests that a long scripted run ends in exactly the
+// committed state. CI runs it on linux/amd64 and darwin/arm64, so
+// nondeterminism (map iteration order, platform float differences) shows up
+// as a mismatch.
+func TestDeterminismGolden(t *testing.T) {
+	state := determinismRun()
+	if again := determinismRun(); exactHash(&again) != exactHash(&state) {
+		t.Fatal("Two runs in the same process differ")
+	}
+
+	if state.Checksum != goldenChecksum || exactHash(&state) != goldenExactHash {
+		t.Errorf("Final state changed: checksum %#x, exact hash %#x (golden %#x, %#x)",
+			state.Checksum, exactHash(&state), goldenChecksum, goldenExactHash)
+	}
+}
+
+func BenchmarkSnapshot(b *testing.B) {
+	world := updateWorld(10)
+	b.ReportAllocs()
+	for b.Loop() {
+		world.Snapshot()
+	}
+}
@@ -0,0 +1,221 @@
+package game
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// DialogueSlowEvery is how many ticks apart a slowed world simulates while
+// a dialogue holds it, see World.DialogueHold
+const DialogueSlowEvery = 4
+
+// Intents that turn a dialogue's pages, and pick the selected choice on the
+// last page. Left and right move between choices.
+const dialogueNext = protocol.IntentJump | protocol.IntentAttack | protocol.IntentUse
+
+// DialoguePage is one text box of a dialogue
+type DialoguePage struct {
+	Speaker  string `json:"speaker,omitempty"`
+	Portrait string `json:"portrait,omitempty"` // Atlas sprite shown beside the text
+	Text     string `json:"text"`
+}
+
+// DialogueChoice is an answer offered on a dialogue's last page. Picking it
+// sets the script flag Flag to Value, if Flag is set.
+type DialogueChoice struct {
+	Text  string `json:"text"`
+	Flag  string `json:"flag,omitempty"`
+	Value int    `json:"value,omitempty"`
+}
+
+// Dialogue is a conversation shown to one player as paged text boxes. A
+// dialogue with an Area opens for the first player to enter it, once per
+// level start; scripts open any dialogue by ID.
+type Dialogue struct {
+	ID      string           `json:"id"`
+	Area    *Rect            `json:"area,omitempty"`
+	Pages   []DialoguePage   `json:"pages"`
+	Choices []DialogueChoice `json:"choices,omitempty"`
+	Slow    bool             `json:"slow,omitempty"` // Slow the world instead of pausing it, see World.DialogueHold
+}
+
+// DialogueState is where a player is in an open dialogue
+type DialogueState struct {
+	Dialogue *Dialogue
+	Page     int
+	Choice   int // Selected choice, on the last page
+}
+
+// LastPage reports whether the dialogue is on its last page
+func (d DialogueState) LastPage() bool {
+	return d.Page >= len(d.Dialogue.Pages)-1
+}
+
+// openDialogue is a player's open dialogue with the intents held last tick,
+// so only fresh presses turn pages
+type openDialogue struct {
+	DialogueState
+	held protocol.Intent
+}
+
+// StartDialogue opens one of the level's dialogues for a player, reporting
+// false if there is no such dialogue or player, or the player already has
+// one open. The buttons held when it opens don't count until released.
+func (w *World) StartDialogue(playerID int, id string) bool {
+	if w.level == nil || w.dialogues[playerID] != nil {
+		return false
+	}
+	for i := range w.level.Dialogues {
+		if d := &w.level.Dialogues[i]; d.ID == id && len(d.Pages) > 0 {
+			return w.beginDialogue(playerID, d)
+		}
+	}
+	return false
+}
+
+// beginDialogue opens a dialogue for a player, with the buttons they hold
+// not counting until released
+func (w *World) beginDialogue(playerID int, d *Dialogue) bool {
+	ctrl := w.playerControl(playerID)
+	if ctrl == nil || w.dialogues[playerID] != nil {
+		return false
+	}
+	w.dialogues[playerID] = &openDialogue{DialogueState: DialogueState{Dialogue: d}, held: ctrl.Intents}
+	return true
+}
+
+// Dialogue returns a player's open dialogue, if any
+func (w *World) Dialogue(playerID int) (DialogueState, bool) {
+	if d := w.dialogues[playerID]; d != nil {
+		return d.DialogueState, true
+	}
+	return DialogueState{}, false
+}
+
+// Flag returns a script flag, 0 if never set
+func (w *World) Flag(name string) int {
+	return w.flags[name]
+}
+
+// SetFlag sets a script flag. Flags are shared by scripts and dialogue
+// choices and cleared when the level restarts.
+func (w *World) SetFlag(name string, value int) {
+	w.flags[name] = value
+}
+
+// runDialogues opens dialogues for players entering their areas and feeds
+// each open dialogue its player's intents, which then don't move the
+// player. It runs before the systems every tick and reports whether the
+// world should hold this tick: with DialogueHold set, an open dialogue
+// pauses the world, or slows it to a tick in DialogueSlowEvery if every
+// open dialogue is Slow.
+func (w *World) runDialogues() bool {
+	if w.level != nil && len(w.level.Dialogues) > 0 {
+		w.triggerDialogues()
+	}
+
+	for playerID, mask := range w.dialogueMask {
+		ctrl := w.playerControl(playerID)
+		if ctrl == nil || ctrl.Intents&mask == 0 {
+			delete(w.dialogueMask, playerID)
+			continue
+		}
+		w.dialogueMask[playerID] = ctrl.Intents & mask
+		ctrl.Intents &^= mask
+	}
+
+	// In player order, so choices emit their events deterministically
+	hold, slow := false, true
+	for _, playerID := range slices.Sorted(maps.Keys(w.dialogues)) {
+		d := w.dialogues[playerID]
+		ctrl := w.playerControl(playerID)
+		if ctrl == nil {
+			delete(w.dialogues, playerID) // Died or left
+			continue
+		}
+		intents := ctrl.Intents
+		ctrl.Intents = 0
+		pressed := intents &^ d.held
+		d.held = intents
+		if w.advanceDialogue(playerID, d, pressed) {
+			// Buttons still held on closing don't reach the player until released
+			delete(w.dialogues, playerID)
+			w.dialogueMask[playerID] = intents
+			continue
+		}
+		hold, slow = true, slow && d.Dialogue.Slow
+	}
+	if !hold || !w.DialogueHold {
+		return false
+	}
+	return !slow || w.Tick%DialogueSlowEvery != 0
+}
+
+// advanceDialogue applies a player's fresh presses to their dialogue and
+// reports whether it closed. Picking a choice sets its flag and emits
+// EventChoice.
+func (w *World) advanceDialogue(playerID int, d *openDialogue, pressed protocol.Intent) bool {
+	if !d.LastPage() {
+		if pressed&dialogueNext != 0 {
+			d.Page++
+		}
+		return false
+	}
+	choices := d.Dialogue.Choices
+	switch {
+	case pressed&protocol.IntentLeft != 0:
+		d.Choice = max(d.Choice-1, 0)
+	case pressed&protocol.IntentRight != 0:
+		d.Choice = max(min(d.Choice+1, len(choices)-1), 0)
+	}
+	if pressed&dialogueNext == 0 {
+		return false
+	}
+	if len(choices) > 0 {
+		choice := choices[d.Choice]
+		if choice.Flag != "" {
+			w.flags[choice.Flag] = choice.Value
+		}
+		w.emit(Event{Type: EventChoice, Tick: w.Tick, Player: playerID, Kind: d.Dialogue.ID, Amount: d.Choice})
+	}
+	return true
+}
+
+// triggerDialogues opens area dialogues for the first player inside
+func (w *World) triggerDialogues() {
+	query := w.playerFilter.Query()
+	for query.Next() {
+		pos, player := query.Get()
+		if w.dialogues[player.ID] != nil {
+			continue
+		}
+		for i := range w.level.Dialogues {
+			d := &w.level.Dialogues[i]
+			if d.Area == nil || w.dialogueSeen[d.ID] || len(d.Pages) == 0 || !d.Area.Contains(pos.X, pos.Y) {
+				continue
+			}
+			w.dialogueSeen[d.ID] = true
+			held := protocol.IntentNone
+			if w.controlMap.HasAll(query.Entity()) {
+				held = w.controlMap.Get(query.Entity()).Intents
+			}
+			w.dialogues[player.ID] = &openDialogue{DialogueState: DialogueState{Dialogue: d}, held: held}
+			break
+		}
+	}
+}
+
+// playerControl returns a live player's controller, nil if there is none
+func (w *World) playerControl(playerID int) *Controller {
+	query := w.playerFilter.Query()
+	for query.Next() {
+		if _, player := query.Get(); player.ID == playerID && w.controlMap.HasAll(query.Entity()) {
+			entity := query.Entity()
+			query.Close()
+			return w.controlMap.Get(entity)
+		}
+	}
+	return nil
+}
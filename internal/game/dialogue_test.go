@@ -0,0 +1,84 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// TestDialogue tests that an area opens a dialogue once, fresh presses turn
+// its pages and pick a choice that sets a flag, the world holds while it is
+// open, and the button that closed it doesn't reach the player.
+func TestDialogue(t *testing.T) {
+	level := NewDemoLevel(40, 20)
+	spawn := level.PlayerSpawn(0)
+	level.Dialogues = []Dialogue{{
+		ID:      "ferry",
+		Area:    &Rect{X: spawn.X - 2, Y: spawn.Y - 2, W: 4, H: 4},
+		Pages:   []DialoguePage{{Speaker: "Ferryman", Text: "Hello."}, {Text: "Pay?"}},
+		Choices: []DialogueChoice{{Text: "Yes", Flag: "paid", Value: 2}, {Text: "No"}},
+	}}
+	world := NewWorld()
+	world.DialogueHold = true
+	world.LoadLevel(level)
+	world.SpawnPlayer(1, "Test", spawn.X, spawn.Y)
+	var choices []Event
+	world.Subscribe(func(e Event) {
+		if e.Type == EventChoice {
+			choices = append(choices, e)
+		}
+	})
+
+	// Jump is held as the player walks in; it doesn't count until released
+	step := func(intents protocol.Intent) {
+		world.SetPlayerIntent(1, intents)
+		world.Update()
+	}
+	step(protocol.IntentJump)
+	state, ok := world.Dialogue(1)
+	if !ok || state.Page != 0 {
+		t.Fatalf("Dialogue = %+v, %v; want the first page open", state, ok)
+	}
+	x, y, _ := world.PlayerPosition(1)
+	step(protocol.IntentJump | protocol.IntentRight)
+	if state, _ := world.Dialogue(1); state.Page != 0 {
+		t.Error("A held button turned the page")
+	}
+	if x2, y2, _ := world.PlayerPosition(1); x2 != x || y2 != y {
+		t.Error("The world should hold while the dialogue is open")
+	}
+
+	step(0)
+	step(protocol.IntentAttack)
+	step(0)
+	step(protocol.IntentRight)
+	step(protocol.IntentLeft)
+	if state, _ := world.Dialogue(1); !state.LastPage() || state.Choice != 0 {
+		t.Fatalf("Dialogue = %+v, want the first choice on the last page", state)
+	}
+	step(protocol.IntentUse)
+	if _, ok := world.Dialogue(1); ok {
+		t.Fatal("Picking a choice should close the dialogue")
+	}
+	if world.Flag("paid") != 2 || len(choices) != 1 || choices[0].Kind != "ferry" || choices[0].Amount != 0 {
+		t.Errorf("Flag paid = %d, choice events %+v", world.Flag("paid"), choices)
+	}
+
+	// Use stays masked until released, and the area doesn't open it again
+	step(protocol.IntentUse)
+	if ctrl := world.playerControl(1); ctrl.Intents != 0 {
+		t.Errorf("Intents = %v, want the closing button masked", ctrl.Intents)
+	}
+	step(0)
+	if _, ok := world.Dialogue(1); ok {
+		t.Error("An area dialogue opens once per level start")
+	}
+
+	world.Reset(nil)
+	if world.Flag("paid") != 0 {
+		t.Error("A restart should clear the flags")
+	}
+	if !world.StartDialogue(1, "ferry") || world.StartDialogue(1, "ferry") || world.StartDialogue(1, "nowhere") {
+		t.Error("StartDialogue should open a known dialogue once")
+	}
+}
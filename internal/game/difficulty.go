@@ -0,0 +1,84 @@
+package game
+
+import "fmt"
+
+// Difficulty scales how hard the level's enemies are. Normal is the zero
+// value, so worlds, replays and checkpoints from before difficulties play
+// as they did.
+type Difficulty uint8
+
+const (
+	DifficultyNormal Difficulty = iota
+	DifficultyEasy              // Players take two more hits
+	DifficultyHard              // Enemies take two hits and deal double damage
+)
+
+// Player and enemy health by difficulty
+const (
+	PlayerHealth     = 3
+	EasyPlayerHealth = PlayerHealth + 2
+	EnemyHealth      = 1
+	HardEnemyHealth  = 2 * EnemyHealth
+)
+
+func (d Difficulty) String() string {
+	switch d {
+	case DifficultyNormal:
+		return "normal"
+	case DifficultyEasy:
+		return "easy"
+	case DifficultyHard:
+		return "hard"
+	}
+	return fmt.Sprintf("Difficulty(%d)", uint8(d))
+}
+
+// Valid reports whether d is a known difficulty
+func (d Difficulty) Valid() bool {
+	return d <= DifficultyHard
+}
+
+// Next returns the difficulty after d, from easy to hard and round again
+func (d Difficulty) Next() Difficulty {
+	switch d {
+	case DifficultyEasy:
+		return DifficultyNormal
+	case DifficultyNormal:
+		return DifficultyHard
+	}
+	return DifficultyEasy
+}
+
+// ParseDifficulty parses "easy", "normal" or "hard"
+func ParseDifficulty(s string) (Difficulty, error) {
+	for d := DifficultyNormal; d.Valid(); d++ {
+		if d.String() == s {
+			return d, nil
+		}
+	}
+	return DifficultyNormal, fmt.Errorf("unknown difficulty %q (want easy, normal or hard)", s)
+}
+
+// playerHealth is a newly spawned player's health
+func (d Difficulty) playerHealth() int {
+	if d == DifficultyEasy {
+		return EasyPlayerHealth
+	}
+	return PlayerHealth
+}
+
+// enemyHealth is a newly spawned enemy's health; dummies keep their own
+func (d Difficulty) enemyHealth() int {
+	if d == DifficultyHard {
+		return HardEnemyHealth
+	}
+	return EnemyHealth
+}
+
+// enemyDamage scales damage an enemy deals to a player
+func (d Difficulty) enemyDamage(amount int) int {
+	if d == DifficultyHard {
+		return 2 * amount
+	}
+	return amount
+}
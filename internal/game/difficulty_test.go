@@ -0,0 +1,46 @@
+package game
+
+import "testing"
+
+// TestDifficulty tests that players and enemies spawn with the difficulty's
+// health, and that names round-trip.
+func TestDifficulty(t *testing.T) {
+	tests := []struct {
+		difficulty    Difficulty
+		player, enemy int
+	}{
+		{DifficultyEasy, EasyPlayerHealth, EnemyHealth},
+		{DifficultyNormal, PlayerHealth, EnemyHealth},
+		{DifficultyHard, PlayerHealth, HardEnemyHealth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.difficulty.String(), func(t *testing.T) {
+			if d, err := ParseDifficulty(tt.difficulty.String()); err != nil || d != tt.difficulty {
+				t.Errorf("ParseDifficulty(%q) = %v, %v", tt.difficulty, d, err)
+			}
+
+			w := NewWorld()
+			w.LoadLevel(arenaLevel())
+			w.Difficulty = tt.difficulty
+			w.SpawnPlayer(1, "Alice", 5, 10)
+			if _, max, _ := w.PlayerHealth(1); max != tt.player {
+				t.Errorf("player health %d, want %d", max, tt.player)
+			}
+			slime := w.SpawnEnemy("slime", 9, 10)
+			if got := w.healthMap.Get(slime).Max; got != tt.enemy {
+				t.Errorf("enemy health %d, want %d", got, tt.enemy)
+			}
+			dummy := w.SpawnEnemy(EnemyDummy, 12, 10)
+			if got := w.healthMap.Get(dummy).Max; got != DummyHealth {
+				t.Errorf("dummy health %d, want %d", got, DummyHealth)
+			}
+		})
+	}
+
+	if _, err := ParseDifficulty("nightmare"); err == nil {
+		t.Error("unknown difficulty parsed")
+	}
+	if d := DifficultyHard.Next(); d != DifficultyEasy {
+		t.Errorf("after hard comes %v, want easy", d)
+	}
+}
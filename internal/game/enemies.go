@@ -0,0 +1,258 @@
+package game
+
+import (
+	"math"
+
+	"github.com/andersfylling/rayman-slides/internal/collision"
+	"github.com/mlange-42/ark/ecs"
+)
+
+// Enemy types with behavior beyond standing still
+const (
+	EnemySpikySlime  = "spiky_slime"  // Patrols; can't be hit from above
+	EnemyShieldSlime = "shield_slime" // Patrols; only vulnerable from behind
+	EnemyDiveBat     = "dive_bat"     // Hovers, then dives at players below
+	EnemyDummy       = "dummy"        // Training target: can't die or be knocked back
+)
+
+// EnemyTypes are the enemy types with their own sprite; SpawnEnemy draws
+// any other type as a generic enemy
+var EnemyTypes = []string{"slime", "bat", EnemySpikySlime, EnemyShieldSlime, EnemyDiveBat, EnemyDummy}
+
+// Enemy behavior tuning
+const (
+	PatrolSpeed     = 0.05 // Walking speed of patrolling slimes
+	DiveRange       = 4.0  // Horizontal distance a bat spots players from
+	DiveDepth       = 8.0  // How far below its perch a bat spots players
+	DiveTelegraph   = 30   // Ticks a bat flashes before diving
+	DiveSpeed       = 0.4  // Dive speed, in world units per tick
+	DiveTicks       = 40   // Longest dive before turning back
+	DiveReturn      = 0.15 // Speed flying back to the perch
+	DiveCooldown    = 60   // Ticks perched before the next dive
+	DiveDamage      = 1    // Damage to a player the dive hits
+	DummyHealth     = 10   // A dummy's health bar, refilled when emptied
+	SpikeDamage     = 1    // Damage to a player pounding onto spikes
+	patrolLookahead = 0.05 // How far past its edge a patroller checks ahead
+)
+
+// Enemy records an enemy's type, so rollback can rebuild it, and whether
+// it is tumbling from a knockback
+type Enemy struct {
+	Type string
+
+	Tumble    int // Ticks left tumbling: no AI, physics only
+	KnockedBy int // Player ID of the last knockback, credited for hazard kills
+}
+
+// Patrol walks a grounded enemy back and forth, turning at walls and
+// ledges. Right is also the way it faces.
+type Patrol struct {
+	Speed float64
+	Right bool
+}
+
+// Spiky marks an enemy that can't be hit from above: fists coming down on
+// it and ground pounds glance off, and pounding onto it hurts the player.
+type Spiky struct{}
+
+// Shielded marks an enemy that blocks hits on the side it faces
+type Shielded struct{}
+
+// Dummy marks a training target. Hits damage it as usual, so they show up
+// as damage numbers and in the health bar, but a dummy at zero health is
+// refilled instead of dying, and it isn't knocked back.
+type Dummy struct{}
+
+// DiveState is a diving enemy's behavior
+type DiveState uint8
+
+const (
+	DivePerched   DiveState = iota // Waiting at the perch for a target
+	DiveWindup                     // Telegraphing the dive
+	DiveDiving                     // Flying at the target
+	DiveReturning                  // Flying back to the perch
+)
+
+// Diver is a flying enemy that perches, telegraphs when a player passes
+// below, dives at where the player was and flies back
+type Diver struct {
+	HomeX, HomeY     float64
+	State            DiveState
+	Timer            int // Ticks left in the state (cooldown while perched)
+	TargetX, TargetY float64
+}
+
+// addEnemyBehavior adds the components for an enemy type's behavior
+func (w *World) addEnemyBehavior(entity ecs.Entity, enemyType string, x, y float64) {
+	w.enemyMap.Add(entity, &Enemy{Type: enemyType})
+	switch enemyType {
+	case EnemySpikySlime:
+		w.patrolMap.Add(entity, &Patrol{Speed: PatrolSpeed, Right: true})
+		w.spikyMap.Add(entity, &Spiky{})
+	case EnemyShieldSlime:
+		w.patrolMap.Add(entity, &Patrol{Speed: PatrolSpeed, Right: true})
+		w.shieldMap.Add(entity, &Shielded{})
+	case EnemyDiveBat:
+		w.gravityMap.Get(entity).Scale = 0
+		w.diverMap.Add(entity, &Diver{HomeX: x, HomeY: y})
+	case EnemyDummy:
+		*w.healthMap.Get(entity) = Health{Current: DummyHealth, Max: DummyHealth}
+		w.dummyMap.Add(entity, &Dummy{})
+	}
+}
+
+// runAISystem moves enemies with behavior: patrollers walk their platform
+// and divers run their perch, windup, dive and return cycle. A dive that
+// reaches a player damages them and turns back.
+func (w *World) runAISystem() {
+	if w.TileMap != nil {
+		w.runTumbles()
+		w.runPatrols()
+	}
+
+	var strikes []ecs.Entity
+
+	query := w.diverFilter.Query()
+	for query.Next() {
+		pos, vel, col, sprite, diver := query.Get()
+		if w.enemyMap.Get(query.Entity()).Tumble > 0 {
+			diver.State = DiveReturning
+			continue
+		}
+		switch diver.State {
+		case DivePerched:
+			vel.X, vel.Y = 0, 0
+			sprite.ID = SpriteDiveBat
+			if diver.Timer > 0 {
+				diver.Timer--
+				break
+			}
+			if x, y, ok := w.nearestBelow(pos.X, pos.Y); ok {
+				diver.State, diver.Timer = DiveWindup, DiveTelegraph
+				diver.TargetX, diver.TargetY = x, y
+				sprite.ID = SpriteDiveBatAlert
+			}
+		case DiveWindup:
+			vel.X, vel.Y = 0, 0
+			if diver.Timer--; diver.Timer <= 0 {
+				dx, dy := diver.TargetX-pos.X, diver.TargetY-pos.Y
+				dist := math.Max(math.Sqrt(dx*dx+dy*dy), 1e-9)
+				vel.X, vel.Y = float64(dx/dist*DiveSpeed), float64(dy/dist*DiveSpeed)
+				diver.State, diver.Timer = DiveDiving, DiveTicks
+				sprite.ID = SpriteDiveBatDive
+			}
+		case DiveDiving:
+			if hit, ok := w.playerTouching(hitbox(pos, col)); ok {
+				strikes = append(strikes, hit)
+				diver.Timer = 0
+			}
+			if diver.Timer--; diver.Timer <= 0 || (vel.X == 0 && vel.Y == 0) {
+				diver.State = DiveReturning
+				sprite.ID = SpriteDiveBat
+			}
+		case DiveReturning:
+			dx, dy := diver.HomeX-pos.X, diver.HomeY-pos.Y
+			dist := math.Sqrt(dx*dx + dy*dy)
+			if dist <= DiveReturn {
+				pos.X, pos.Y = diver.HomeX, diver.HomeY
+				vel.X, vel.Y = 0, 0
+				diver.State, diver.Timer = DivePerched, DiveCooldown
+				break
+			}
+			vel.X, vel.Y = float64(dx/dist*DiveReturn), float64(dy/dist*DiveReturn)
+		}
+	}
+
+	for _, player := range strikes {
+		if w.ECS.Alive(player) {
+			w.damage(player, 0, w.Difficulty.enemyDamage(DiveDamage))
+		}
+	}
+}
+
+// runPatrols walks patrolling enemies, turning them before walls and, on
+// the ground, before ledges
+func (w *World) runPatrols() {
+	const colW, colH = 0.8, 0.9
+
+	query := w.patrolFilter.Query()
+	for query.Next() {
+		pos, vel, grounded, patrol := query.Get()
+		if w.enemyMap.Get(query.Entity()).Tumble > 0 {
+			continue
+		}
+		ahead := int(pos.X - colW/2 - patrolLookahead)
+		if patrol.Right {
+			ahead = int(pos.X + colW/2 + patrolLookahead)
+		}
+		wall := w.TileMap.IsSolid(ahead, int(pos.Y+colH/2))
+		ledge := grounded.OnGround && !w.TileMap.IsSolid(ahead, int(math.Round(pos.Y+colH)))
+		if wall || ledge {
+			patrol.Right = !patrol.Right
+		}
+		vel.X = patrol.Speed
+		if !patrol.Right {
+			vel.X = -patrol.Speed
+		}
+	}
+}
+
+// nearestBelow returns the position of the nearest living player a diver
+// at (x, y) can see: within DiveRange to the side and DiveDepth below
+func (w *World) nearestBelow(x, y float64) (px, py float64, ok bool) {
+	best := math.Inf(1)
+	query := w.playerFilter.Query()
+	for query.Next() {
+		pos, player := query.Get()
+		dx, dy := math.Abs(pos.X-x), pos.Y-y
+		if w.spectating[player.ID] || dx > DiveRange || dy <= 0 || dy > DiveDepth || dx >= best {
+			continue
+		}
+		best, px, py, ok = dx, pos.X, pos.Y, true
+	}
+	return px, py, ok
+}
+
+// Enemies returns how many enemies are alive, not counting training dummies
+func (w *World) Enemies() int {
+	n := 0
+	query := w.targetFilter.Query()
+	for query.Next() {
+		entity := query.Entity()
+		if w.enemyMap.HasAll(entity) && !w.dummyMap.HasAll(entity) {
+			n++
+		}
+	}
+	return n
+}
+
+// playerTouching returns a player whose hitbox overlaps box
+func (w *World) playerTouching(box collision.AABB) (ecs.Entity, bool) {
+	query := w.targetFilter.Query()
+	for query.Next() {
+		pos, col, _ := query.Get()
+		if w.playerMap.HasAll(query.Entity()) && hitbox(pos, col).Overlaps(box) {
+			entity := query.Entity()
+			query.Close()
+			return entity, true
+		}
+	}
+	return ecs.Entity{}, false
+}
+
+// deflects reports whether the target shrugs off a hit. fromAbove is for
+// hits coming down on it, fromRight for hits from its right side.
+func (w *World) deflects(target ecs.Entity, fromAbove, fromRight bool) bool {
+	if fromAbove && w.spikyMap.HasAll(target) {
+		return true
+	}
+	if w.shieldMap.HasAll(target) && w.patrolMap.HasAll(target) {
+		return w.patrolMap.Get(target).Right == fromRight
+	}
+	return false
+}
+
+// block emits EventBlock for a deflected hit
+func (w *World) block(target ecs.Entity, attacker int) {
+	w.emit(Event{Type: EventBlock, Tick: w.Tick, Entity: w.NetIDOf(target), Attacker: attacker, Kind: w.kindOf(target)})
+}
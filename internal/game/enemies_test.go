@@ -0,0 +1,153 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/collision"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// enemyLevel is a floor with a one-tile pit at x 10 walled in on row 10, so
+// a slime dropped into it can't patrol away
+func enemyLevel() *Level {
+	tm := collision.NewTileMap(20, 12)
+	for x := range 20 {
+		tm.Set(x, 11, collision.TileSolid)
+	}
+	tm.Set(9, 10, collision.TileSolid)
+	tm.Set(11, 10, collision.TileSolid)
+	return &Level{Name: "enemies", TileMap: tm}
+}
+
+// collect records the world's events of the given type
+func collect(world *World, typ EventType) *[]Event {
+	var events []Event
+	world.Subscribe(func(e Event) {
+		if e.Type == typ {
+			events = append(events, e)
+		}
+	})
+	return &events
+}
+
+// TestShieldedEnemy tests that a shielded slime blocks fists from the way
+// it faces and dies to one from behind.
+func TestShieldedEnemy(t *testing.T) {
+	world := NewWorld()
+	world.LoadLevel(enemyLevel())
+	slime := world.NetIDOf(world.SpawnEnemy(EnemyShieldSlime, 3, 9))
+	blocks := collect(world, EventBlock)
+	for range 10 {
+		world.Update()
+	}
+
+	x, y, _ := world.EntityPosition(slime)
+	if x <= 3 {
+		t.Fatalf("Shielded slime should patrol right, got x %v", x)
+	}
+
+	world.SpawnFist(x+0.5, y+0.2, false, 1, 1) // From the front
+	world.Update()
+	if len(*blocks) != 1 {
+		t.Fatalf("A fist from the front should be blocked, got %d blocks", len(*blocks))
+	}
+
+	x, y, _ = world.EntityPosition(slime)
+	world.SpawnFist(x-0.5, y+0.2, true, 1, 1) // From behind
+	world.Update()
+	if _, _, ok := world.EntityPosition(slime); ok {
+		t.Error("A fist from behind should kill the shielded slime")
+	}
+}
+
+// TestSpikyEnemy tests that a spiky slime deflects fists from above and
+// hurts a player pounding onto it, but not a fist from the side.
+func TestSpikyEnemy(t *testing.T) {
+	world := NewWorld()
+	world.LoadLevel(enemyLevel())
+	slime := world.NetIDOf(world.SpawnEnemy(EnemySpikySlime, 10.5, 9))
+	blocks := collect(world, EventBlock)
+	damage := collect(world, EventDamage)
+	for range 10 {
+		world.Update()
+	}
+
+	x, y, _ := world.EntityPosition(slime)
+	world.SpawnFist(x-0.6, y-0.4, true, 1, 1) // Clipping its top
+	world.Update()
+	if len(*blocks) != 1 {
+		t.Fatalf("A fist from above should be blocked, got %d blocks", len(*blocks))
+	}
+
+	world.SpawnPlayer(1, "One", 10.5, 2)
+	world.SetPlayerIntent(1, protocol.IntentDown|protocol.IntentAttack|protocol.IntentPound)
+	for range 20 {
+		world.Update()
+	}
+	if len(*blocks) != 2 {
+		t.Fatalf("The pound should glance off, got %d blocks", len(*blocks))
+	}
+	if len(*damage) != 1 || (*damage)[0].Player != 1 || (*damage)[0].Amount != SpikeDamage {
+		t.Fatalf("Only the player should be hurt, got %+v", *damage)
+	}
+
+	x, y, _ = world.EntityPosition(slime)
+	world.SpawnFist(x-0.5, y+0.2, true, 1, 1)
+	world.Update()
+	if _, _, ok := world.EntityPosition(slime); ok {
+		t.Error("A fist from the side should kill the spiky slime")
+	}
+}
+
+// TestDivingBat tests that a bat telegraphs before diving at a player below,
+// hits them once and flies back to its perch.
+func TestDivingBat(t *testing.T) {
+	world := NewWorld()
+	world.LoadLevel(enemyLevel())
+	world.SpawnPlayer(1, "One", 5, 10.1)
+	bat := world.SpawnEnemy(EnemyDiveBat, 6, 4)
+	damage := collect(world, EventDamage)
+
+	world.Update()
+	if diver := world.diverMap.Get(bat); diver.State != DiveWindup {
+		t.Fatalf("Bat should spot the player below, state %d", diver.State)
+	}
+	for range DiveTelegraph - 1 {
+		world.Update()
+		if x, y, _ := world.EntityPosition(world.NetIDOf(bat)); x != 6 || y != 4 {
+			t.Fatalf("Bat should hold still while telegraphing, at (%v, %v)", x, y)
+		}
+	}
+
+	for range 100 {
+		world.Update()
+	}
+	if len(*damage) != 1 || (*damage)[0].Player != 1 || (*damage)[0].Amount != DiveDamage {
+		t.Fatalf("The dive should hit the player once, got %+v", *damage)
+	}
+	diver := world.diverMap.Get(bat)
+	if x, y, _ := world.EntityPosition(world.NetIDOf(bat)); diver.State != DivePerched || x != 6 || y != 4 {
+		t.Errorf("Bat should be back on its perch, state %d at (%v, %v)", diver.State, x, y)
+	}
+}
+
+// TestTrainingDummies tests that the training room's nearest dummy takes
+// more hits than its health without dying or being knocked away.
+func TestTrainingDummies(t *testing.T) {
+	level := NewTrainingLevel()
+	s := newScenario(t, level).spawn(1, level.PlayerSpawns[0].X, level.PlayerSpawns[0].Y)
+	if n := len(s.world.EntitiesWithSprite(EnemyDummy)); n != len(TrainingDistances) {
+		t.Fatalf("Expected %d dummies, got %d", len(TrainingDistances), n)
+	}
+	dummy := s.world.EntitiesWithSprite(EnemyDummy)[0]
+	s.wait(30)
+	x, _, _ := s.world.EntityPosition(dummy)
+
+	for range DummyHealth + 2 {
+		s.press(1, protocol.IntentAttack, 60).wait(20)
+	}
+	s.expectEvents(EventDamage, DummyHealth+2).expectEvents(EventDeath, 0)
+	if nx, _, ok := s.world.EntityPosition(dummy); !ok || nx != x {
+		t.Errorf("Dummy moved from x %v to %v (alive %v)", x, nx, ok)
+	}
+}
@@ -0,0 +1,113 @@
+package game
+
+import (
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// EventType identifies a world event
+type EventType uint8
+
+const (
+	// EventReset is emitted after Reset. Clients drop prediction state that
+	// refers to the old entities.
+	EventReset EventType = iota
+
+	// EventDamage is emitted when a fist hits an entity with health
+	EventDamage
+
+	// EventDeath is emitted when an entity's health reaches zero. The
+	// entity has already been removed.
+	EventDeath
+
+	// EventFinish is emitted the first time a player reaches the level exit
+	EventFinish
+
+	// EventPound is emitted where a ground pound lands
+	EventPound
+
+	// EventBreak is emitted for every tile a ground pound breaks
+	EventBreak
+
+	// EventBlock is emitted when a hit glances off a spiky or shielded
+	// enemy without damage
+	EventBlock
+
+	// EventChoice is emitted when a player picks a dialogue choice: Kind is
+	// the dialogue's ID and Amount the choice's index
+	EventChoice
+
+	// EventInteract is emitted when a player uses an object: Kind is the
+	// object's ID, or its type if it has none, and for levers Amount is 1
+	// if the gate opened and 0 if it closed
+	EventInteract
+
+	// EventKey is emitted when a player picks up a key: Kind is its color
+	EventKey
+
+	// EventUnlock is emitted when a player holding its key opens a locked
+	// gate: Kind is the gate's ID
+	EventUnlock
+
+	// EventTeleport is emitted when a player steps onto a teleporter pad:
+	// Kind is the teleporter's ID, X and Y where the player came out, and
+	// Amount 1 if they went from its B pad to its A pad
+	EventTeleport
+)
+
+// Event is something that happened in the world during a tick
+type Event struct {
+	Type     EventType
+	Tick     uint64
+	Level    string            // Level name, for EventReset
+	Entity   protocol.EntityID // Entity hit, blocking or killed
+	Player   int               // Player ID of the entity hit, killed or finishing (0 = not a player)
+	Attacker int               // Player ID that dealt the damage
+	Amount   int               // Damage dealt, or the choice picked
+	Charge   float64           // How charged the fist was, 0 to 1, for EventDamage from a fist
+	X, Y     float64           // Where, for EventDamage, EventDeath, EventPound and EventBreak (the tile)
+	Kind     string            // Enemy type or sprite of the entity hit, blocking or killed; dialogue ID for EventChoice; object for EventInteract; key color or gate for EventKey and EventUnlock; teleporter for EventTeleport
+}
+
+// Subscribe registers a handler that is called synchronously for every
+// world event, in subscription order
+func (w *World) Subscribe(fn func(Event)) {
+	w.handlers = append(w.handlers, fn)
+}
+
+func (w *World) emit(e Event) {
+	for _, fn := range w.handlers {
+		fn(e)
+	}
+}
+
+// String returns the event name used by scripts
+func (t EventType) String() string {
+	switch t {
+	case EventReset:
+		return "reset"
+	case EventDamage:
+		return "damage"
+	case EventDeath:
+		return "death"
+	case EventFinish:
+		return "finish"
+	case EventPound:
+		return "pound"
+	case EventBreak:
+		return "break"
+	case EventBlock:
+		return "block"
+	case EventChoice:
+		return "choice"
+	case EventInteract:
+		return "interact"
+	case EventKey:
+		return "key"
+	case EventUnlock:
+		return "unlock"
+	case EventTeleport:
+		return "teleport"
+	default:
+		return "unknown"
+	}
+}
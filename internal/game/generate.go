@@ -0,0 +1,113 @@
+package game
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/andersfylling/rayman-slides/internal/collision"
+)
+
+// Generated level size, in tiles
+const (
+	generatedWidth  = 160
+	generatedHeight = 20
+)
+
+// Generated ground heights, as the row of the top ground tile. Steps up
+// never exceed generatedStep and pits never exceed generatedGap, both well
+// inside a default jump (about 6 tiles high and 12 across).
+const (
+	generatedLowest  = generatedHeight - 3
+	generatedHighest = generatedHeight - 9
+	generatedStep    = 2
+	generatedGap     = 4
+)
+
+// DailySeed returns the generator seed for t's UTC date, so every player
+// gets the same daily challenge wherever they are
+func DailySeed(t time.Time) uint64 {
+	y, m, d := t.UTC().Date()
+	return uint64(y)*10000 + uint64(m)*100 + uint64(d)
+}
+
+// DailyDate returns t's UTC date as the daily challenge names it, e.g.
+// "2026-10-16"
+func DailyDate(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+// NewDailyLevel returns the daily challenge level for t's UTC date
+func NewDailyLevel(t time.Time) *Level {
+	level := GenerateLevel(DailySeed(t))
+	level.Name = "daily-" + DailyDate(t)
+	return level
+}
+
+// GenerateLevel builds a run-to-the-exit level from a seed: ground that
+// steps up and down, pits, spikes, floating platforms and enemies, with
+// the exit at the far end. The same seed always gives the same level, on
+// every platform.
+func GenerateLevel(seed uint64) *Level {
+	rng := rand.New(rand.NewPCG(seed, seed^0x9E3779B97F4A7C15))
+	tm := collision.NewTileMap(generatedWidth, generatedHeight)
+	level := &Level{Name: fmt.Sprintf("generated-%d", seed), TileMap: tm}
+
+	top := generatedLowest
+	ground := func(from, to int) {
+		for x := from; x < to; x++ {
+			for y := top; y < generatedHeight; y++ {
+				tm.Set(x, y, collision.TileSolid)
+			}
+		}
+	}
+
+	for y := range generatedHeight {
+		tm.Set(0, y, collision.TileSolid)
+		tm.Set(generatedWidth-1, y, collision.TileSolid)
+	}
+	ground(1, 9)
+	level.PlayerSpawns = []SpawnPoint{{X: 3, Y: float64(top - 1)}}
+
+	x := 9
+	for x < generatedWidth-16 {
+		switch rng.IntN(5) {
+		case 0: // Flat run, maybe guarded
+			n := 5 + rng.IntN(5)
+			ground(x, x+n)
+			if rng.IntN(2) == 0 {
+				level.Enemies = append(level.Enemies, EnemySpawn{Type: "slime", X: float64(x+n/2) + 0.5, Y: float64(top - 1)})
+			}
+			x += n
+		case 1: // Step up or down
+			top = min(max(top+(1+rng.IntN(generatedStep))*(1-2*rng.IntN(2)), generatedHighest), generatedLowest)
+			n := 3 + rng.IntN(4)
+			ground(x, x+n)
+			x += n
+		case 2: // Pit, with ground to land on
+			x += 2 + rng.IntN(generatedGap-1)
+			ground(x, x+3)
+			x += 3
+		case 3: // Spikes to jump
+			ground(x, x+7)
+			for i := range 1 + rng.IntN(2) {
+				tm.Set(x+3+i, top-1, collision.TileHazard)
+			}
+			x += 7
+		case 4: // Platform over the ground, maybe with a bat above it
+			n := 8
+			ground(x, x+n)
+			for i := 2; i < 6; i++ {
+				tm.Set(x+i, top-4, collision.TilePlatform)
+			}
+			if rng.IntN(2) == 0 {
+				level.Enemies = append(level.Enemies, EnemySpawn{Type: "bat", X: float64(x) + 4, Y: float64(top - 6)})
+			}
+			x += n
+		}
+	}
+
+	ground(x, generatedWidth-1)
+	level.Exit = &SpawnPoint{X: float64(generatedWidth - 5), Y: float64(top - 1)}
+	return level
+}
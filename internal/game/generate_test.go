@@ -0,0 +1,77 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+// TestGenerateLevelDeterministic tests that a seed always gives the same
+// level and different seeds different ones.
+func TestGenerateLevelDeterministic(t *testing.T) {
+	a, err := LevelHash(GenerateLevel(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := LevelHash(GenerateLevel(42))
+	c, _ := LevelHash(GenerateLevel(43))
+	if a != b {
+		t.Error("Expected the same seed to give the same level")
+	}
+	if a == c {
+		t.Error("Expected different seeds to give different levels")
+	}
+}
+
+// TestDailySeed tests that the seed follows the UTC date, not the local one.
+func TestDailySeed(t *testing.T) {
+	oslo := time.FixedZone("CEST", 2*60*60)
+	late := time.Date(2026, 10, 16, 23, 30, 0, 0, time.UTC)
+	if got, want := DailySeed(late.In(oslo)), DailySeed(late); got != want {
+		t.Errorf("Expected the same seed in any zone, got %d and %d", got, want)
+	}
+	if DailySeed(late) == DailySeed(late.Add(time.Hour)) {
+		t.Error("Expected a new seed after UTC midnight")
+	}
+	if got := NewDailyLevel(late).Name; got != "daily-2026-10-16" {
+		t.Errorf("Expected daily-2026-10-16, got %q", got)
+	}
+}
+
+// TestGenerateLevelPlayable tests that generated levels stay within a
+// default jump: no pit wider than generatedGap, no step up higher than
+// generatedStep, and spawn and exit standing on ground.
+func TestGenerateLevelPlayable(t *testing.T) {
+	for seed := range uint64(200) {
+		level := GenerateLevel(seed)
+		tm := level.TileMap
+		top := func(x int) int {
+			for y := range tm.Height {
+				if tm.IsSolid(x, y) {
+					return y
+				}
+			}
+			return tm.Height
+		}
+
+		gap, prev := 0, top(1)
+		for x := 1; x < tm.Width-1; x++ {
+			y := top(x)
+			if y == tm.Height {
+				if gap++; gap > generatedGap {
+					t.Fatalf("Seed %d: pit wider than %d at x=%d", seed, generatedGap, x)
+				}
+				continue
+			}
+			if prev-y > generatedStep {
+				t.Fatalf("Seed %d: step up of %d at x=%d", seed, prev-y, x)
+			}
+			gap, prev = 0, y
+		}
+
+		for _, p := range []SpawnPoint{level.PlayerSpawns[0], *level.Exit} {
+			if x := int(p.X); top(x) != int(p.Y)+1 {
+				t.Fatalf("Seed %d: point (%v, %v) isn't standing on ground", seed, p.X, p.Y)
+			}
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package game
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/andersfylling/rayman-slides/internal/collision"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// InteractRange is how close, in tiles along each axis, a player must be to
+// an object to use it
+const InteractRange = 1.5
+
+// Level object types
+const (
+	ObjectSign  = "sign"  // Shows its text in a dialogue box
+	ObjectLever = "lever" // Opens or closes its gate
+	ObjectNPC   = "npc"   // Opens its dialogue
+)
+
+// LevelObject is a sign, lever or NPC placed in a level, used with the use
+// intent from within InteractRange
+type LevelObject struct {
+	Type     string  `json:"type"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	ID       string  `json:"id,omitempty"`       // For scripts, see EventInteract
+	Name     string  `json:"name,omitempty"`     // NPCs: who the prompt offers to talk to
+	Sprite   string  `json:"sprite,omitempty"`   // Instead of the type's own
+	Text     string  `json:"text,omitempty"`     // Signs
+	Dialogue string  `json:"dialogue,omitempty"` // NPCs: the dialogue opened
+	Gate     string  `json:"gate,omitempty"`     // Levers: the gate toggled
+}
+
+// Gate is a block of tiles levers open and close: solid while closed and
+// empty while open. A gate doesn't close on anything standing in it. A
+// locked gate opens for good when a player holding its key comes up to it.
+type Gate struct {
+	ID   string `json:"id"`
+	Area Rect   `json:"area"`           // The tiles it covers
+	Open bool   `json:"open,omitempty"` // Open when the level starts
+	Lock string `json:"lock,omitempty"` // Key color that opens it, see KeyColors
+}
+
+// Interactable component marks a level object players can use
+type Interactable struct {
+	Object *LevelObject
+	Index  int       // In Level.Objects, breaking ties between objects as near
+	sign   *Dialogue // The sign's text as a one-page dialogue
+}
+
+// validateObjects checks that levers and NPCs refer to gates and dialogues
+// the level has
+func (l *Level) validateObjects() error {
+	for i, o := range l.Objects {
+		switch o.Type {
+		case ObjectSign:
+		case ObjectLever:
+			if l.gate(o.Gate) == nil {
+				return fmt.Errorf("object %d: no gate %q", i, o.Gate)
+			}
+		case ObjectNPC:
+			if !slices.ContainsFunc(l.Dialogues, func(d Dialogue) bool { return d.ID == o.Dialogue }) {
+				return fmt.Errorf("object %d: no dialogue %q", i, o.Dialogue)
+			}
+		default:
+			return fmt.Errorf("object %d: unknown type %q", i, o.Type)
+		}
+	}
+	return nil
+}
+
+// gate returns the level's gate with the given ID, nil if there is none
+func (l *Level) gate(id string) *Gate {
+	for i := range l.Gates {
+		if l.Gates[i].ID == id {
+			return &l.Gates[i]
+		}
+	}
+	return nil
+}
+
+// spawnObjects creates the level's objects, for LoadLevel
+func (w *World) spawnObjects() {
+	for i := range w.level.Objects {
+		o := &w.level.Objects[i]
+		sprite := Sprite{ID: InternSprite(o.Type)}
+		switch o.Type {
+		case ObjectSign:
+			sprite.Color = 0xC08040
+		case ObjectLever:
+			sprite.Color = 0xA0A0A0
+		case ObjectNPC:
+			sprite.Color = 0x4080FF
+		}
+		if o.Sprite != "" {
+			sprite.ID = InternSprite(o.Sprite)
+		}
+		in := Interactable{Object: o, Index: i}
+		if o.Type == ObjectSign {
+			in.sign = &Dialogue{ID: ObjectSign + "." + strconv.Itoa(i), Pages: []DialoguePage{{Text: o.Text}}}
+		}
+		w.objectMapper.NewEntity(&Position{X: o.X, Y: o.Y}, &sprite, &in)
+	}
+	w.syncLevers()
+}
+
+// InteractTarget returns the object a player would use: the nearest within
+// InteractRange, for prompts. ok is false if there is none, or the player
+// has a dialogue open.
+func (w *World) InteractTarget(playerID int) (obj LevelObject, ok bool) {
+	if w.dialogues[playerID] != nil {
+		return LevelObject{}, false
+	}
+	x, y, alive := w.PlayerPosition(playerID)
+	if !alive {
+		return LevelObject{}, false
+	}
+	if in := w.nearestObject(x, y); in != nil {
+		return *in.Object, true
+	}
+	return LevelObject{}, false
+}
+
+// nearestObject returns the nearest object within InteractRange of a
+// point, the first placed on a tie, or nil
+func (w *World) nearestObject(x, y float64) *Interactable {
+	var nearest *Interactable
+	best := 0.0
+	query := w.objectFilter.Query()
+	for query.Next() {
+		pos, _, in := query.Get()
+		dx, dy := pos.X-x, pos.Y-y
+		if dx < -InteractRange || dx > InteractRange || dy < -InteractRange || dy > InteractRange {
+			continue
+		}
+		d := dx*dx + dy*dy
+		if nearest == nil || d < best || d == best && in.Index < nearest.Index {
+			nearest, best = in, d
+		}
+	}
+	return nearest
+}
+
+// runInteractSystem uses the nearest object for every player pressing use,
+// in player order so levers and events are deterministic
+func (w *World) runInteractSystem() {
+	if w.level == nil || len(w.level.Objects) == 0 {
+		return
+	}
+	type press struct {
+		player int
+		x, y   float64
+	}
+	var presses []press
+	query := w.attackFilter.Query()
+	for query.Next() {
+		pos, _, ctrl, attack, _, player := query.Get()
+		pressed := ctrl.Intents&protocol.IntentUse != 0
+		if pressed && !attack.UseWasPressed {
+			presses = append(presses, press{player.ID, pos.X, pos.Y})
+		}
+		attack.UseWasPressed = pressed
+	}
+	slices.SortFunc(presses, func(a, b press) int { return a.player - b.player })
+
+	for _, p := range presses {
+		in := w.nearestObject(p.x, p.y)
+		if in == nil {
+			continue
+		}
+		o := in.Object
+		e := Event{Type: EventInteract, Tick: w.Tick, Player: p.player, Kind: o.ID, X: o.X, Y: o.Y}
+		if e.Kind == "" {
+			e.Kind = o.Type
+		}
+		switch o.Type {
+		case ObjectSign:
+			w.beginDialogue(p.player, in.sign)
+		case ObjectNPC:
+			w.StartDialogue(p.player, o.Dialogue)
+		case ObjectLever:
+			if !w.toggleGate(o.Gate) {
+				continue // Blocked
+			}
+			if w.GateOpen(o.Gate) {
+				e.Amount = 1
+			}
+		}
+		w.emit(e)
+	}
+}
+
+// GateOpen reports whether one of the level's gates is open
+func (w *World) GateOpen(id string) bool {
+	if w.level == nil {
+		return false
+	}
+	g := w.level.gate(id)
+	return g != nil && g.Open != w.gates[id]
+}
+
+// toggleGate opens or closes a gate, reporting false if there is no such
+// gate or something stands in its way while closing
+func (w *World) toggleGate(id string) bool {
+	g := w.level.gate(id)
+	if g == nil {
+		return false
+	}
+	open := w.GateOpen(id)
+	if open && w.gateBlocked(g) {
+		return false
+	}
+	if w.gates[id] {
+		delete(w.gates, id)
+	} else {
+		w.gates[id] = true
+	}
+	setGateTiles(w.TileMap, g, !open)
+	w.tilesVersion++
+	w.syncLevers()
+	w.syncKeys()
+	return true
+}
+
+// gateBlocked reports whether any body overlaps a gate's tiles
+func (w *World) gateBlocked(g *Gate) bool {
+	const colW, colH = 0.8, 0.9
+	query := w.physicsFilter.Query()
+	for query.Next() {
+		pos, _, _, _ := query.Get()
+		if g.Area.Overlaps(Rect{X: pos.X - colW/2, Y: pos.Y, W: colW, H: colH}) {
+			query.Close()
+			return true
+		}
+	}
+	return false
+}
+
+// setGateTiles fills or empties a gate's tiles
+func setGateTiles(tm *collision.TileMap, g *Gate, open bool) {
+	tile := collision.TileSolid
+	if open {
+		tile = collision.TileEmpty
+	}
+	for y := int(g.Area.Y); y < int(g.Area.Y+g.Area.H); y++ {
+		for x := int(g.Area.X); x < int(g.Area.X+g.Area.W); x++ {
+			tm.Set(x, y, tile)
+		}
+	}
+}
+
+// applyGates sets every gate's tiles for its state, on a fresh copy of the
+// level's tilemap
+func (w *World) applyGates() {
+	for i := range w.level.Gates {
+		g := &w.level.Gates[i]
+		setGateTiles(w.TileMap, g, g.Open != w.gates[g.ID])
+	}
+}
+
+// toggledGates returns the IDs of the gates toggled from how the level
+// starts, sorted, for WorldState
+func (w *World) toggledGates() []string {
+	if len(w.gates) == 0 {
+		return nil
+	}
+	return slices.Sorted(maps.Keys(w.gates))
+}
+
+// syncLevers shows every lever as pulled while its gate is toggled
+func (w *World) syncLevers() {
+	query := w.objectFilter.Query()
+	for query.Next() {
+		_, sprite, in := query.Get()
+		o := in.Object
+		if o.Type != ObjectLever || o.Sprite != "" {
+			continue
+		}
+		sprite.ID = InternSprite(ObjectLever)
+		if w.gates[o.Gate] {
+			sprite.ID = InternSprite(ObjectLever + "_on")
+		}
+	}
+}
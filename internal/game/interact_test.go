@@ -0,0 +1,111 @@
+package game
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+const leverLevel = `{"name": "levers", "tiles": [
+	"#..........#",
+	"#......#...#",
+	"#......#...#",
+	"#......#...#",
+	"#......#...#",
+	"############"],
+	"spawns": [{"x": 2, "y": 4}],
+	"objects": [{"type": "lever", "id": "door_lever", "x": 3, "y": 4, "gate": "door"},
+		{"type": "sign", "x": 5, "y": 4, "text": "Pull the lever."}],
+	"gates": [{"id": "door", "area": {"x": 7, "y": 1, "w": 1, "h": 4}}]}`
+
+// TestInteract tests that a fresh use press pulls the nearest lever, its
+// gate opens and closes and survives rollback, a gate doesn't close on a
+// player, and signs open their text.
+func TestInteract(t *testing.T) {
+	level, err := DecodeLevel([]byte(leverLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	world := NewWorld()
+	world.LoadLevel(level)
+	world.SpawnPlayer(1, "Test", 2, 4)
+	var events []Event
+	world.Subscribe(func(e Event) {
+		if e.Type == EventInteract {
+			events = append(events, e)
+		}
+	})
+	step := func(intents protocol.Intent) {
+		world.SetPlayerIntent(1, intents)
+		world.Update()
+	}
+	for range 30 {
+		step(0)
+	}
+
+	if obj, ok := world.InteractTarget(1); !ok || obj.ID != "door_lever" {
+		t.Fatalf("InteractTarget = %+v, %v; want the lever", obj, ok)
+	}
+	if world.GateOpen("door") || !world.TileMap.IsSolid(7, 2) {
+		t.Fatal("The gate should start closed")
+	}
+	version := world.TilesVersion()
+	step(protocol.IntentUse)
+	if !world.GateOpen("door") || world.TileMap.IsSolid(7, 2) || world.TilesVersion() == version {
+		t.Fatal("Using the lever should open the gate")
+	}
+	if len(events) != 1 || events[0].Kind != "door_lever" || events[0].Amount != 1 || events[0].Player != 1 {
+		t.Errorf("Events = %+v, want the lever opening the gate", events)
+	}
+	opened := world.Snapshot()
+	if strings.Join(opened.Gates, ",") != "door" {
+		t.Errorf("State gates = %q, want door", opened.Gates)
+	}
+
+	step(protocol.IntentUse)
+	if !world.GateOpen("door") {
+		t.Error("Holding use pulled the lever again")
+	}
+	step(0)
+	step(protocol.IntentUse)
+	if world.GateOpen("door") || !world.TileMap.IsSolid(7, 2) {
+		t.Fatal("Using the lever again should close the gate")
+	}
+	world.Restore(opened)
+	if !world.GateOpen("door") || world.TileMap.IsSolid(7, 2) {
+		t.Fatal("Restore should reopen the gate")
+	}
+
+	// Someone standing in the gate keeps it open
+	world.SpawnPlayer(2, "Blocker", 7.5, 4)
+	for range 30 {
+		step(0)
+	}
+	step(protocol.IntentUse)
+	if !world.GateOpen("door") {
+		t.Error("The gate closed on a player")
+	}
+
+	// The sign is nearer than the lever from here
+	world.SpawnPlayer(3, "Reader", 5, 4)
+	world.SetPlayerIntent(3, protocol.IntentUse)
+	world.Update()
+	state, ok := world.Dialogue(3)
+	if !ok || state.Dialogue.Pages[0].Text != "Pull the lever." {
+		t.Errorf("Dialogue = %+v, %v; want the sign's text", state, ok)
+	}
+	if _, ok := world.InteractTarget(3); ok {
+		t.Error("No prompt while reading")
+	}
+
+	world.Reset(nil)
+	if world.GateOpen("door") || !world.TileMap.IsSolid(7, 2) {
+		t.Error("A restart should close the gate")
+	}
+
+	bad := strings.Replace(leverLevel, `"gate": "door"`, `"gate": "hatch"`, 1)
+	if _, err := DecodeLevel([]byte(bad)); err == nil || !strings.Contains(err.Error(), "hatch") {
+		t.Errorf("A lever without its gate: %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package game
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// Physics limits the invariants check against, matching DefaultPhysics and
+// the collision system
+const (
+	maxMoveSpeed = 0.5 // PhysicsProfile.MoveSpeed
+	maxFallSpeed = 1.0 // PhysicsProfile.MaxFall
+	maxJumpSpeed = 1.0 // PhysicsProfile.JumpSpeed
+	colliderW    = 0.8 // runCollisionSystem default collider
+	colliderH    = 0.9
+)
+
+// fuzzIntents are the intents the harness presses, alone or combined
+var fuzzIntents = []protocol.Intent{
+	protocol.IntentNone,
+	protocol.IntentLeft,
+	protocol.IntentRight,
+	protocol.IntentJump,
+	protocol.IntentAttack,
+	protocol.IntentLeft | protocol.IntentJump,
+	protocol.IntentRight | protocol.IntentJump,
+	protocol.IntentRight | protocol.IntentAttack,
+}
+
+// invariantWorld returns the demo level with two players, so players also
+// collide with each other's fists
+func invariantWorld() *World {
+	world := NewWorld()
+	world.FriendlyFire = true
+	level := NewDemoLevel(60, 24)
+	world.LoadLevel(level)
+	for id := 1; id <= 2; id++ {
+		spawn := level.PlayerSpawn(id - 1)
+		world.SpawnPlayer(id, fmt.Sprintf("P%d", id), spawn.X, spawn.Y)
+	}
+	return world
+}
+
+// checkInvariants returns the first physics invariant a moving entity
+// breaks: it must stay inside the map, its body must not be inside a solid
+// tile, and its velocity must stay within the movement caps
+func checkInvariants(w *World) error {
+	tm := w.TileMap
+	query := w.physicsFilter.Query()
+	for query.Next() {
+		pos, vel, _, _ := query.Get()
+		entity := query.Entity()
+		switch {
+		case math.IsNaN(pos.X) || math.IsNaN(pos.Y) || math.IsNaN(vel.X) || math.IsNaN(vel.Y):
+			query.Close()
+			return fmt.Errorf("entity %d: NaN position %+v or velocity %+v", w.NetIDOf(entity), *pos, *vel)
+		case pos.X < colliderW/2 || pos.X > float64(tm.Width)-colliderW/2 || pos.Y < 0 || pos.Y > float64(tm.Height)-colliderH:
+			query.Close()
+			return fmt.Errorf("entity %d: out of bounds at %+v", w.NetIDOf(entity), *pos)
+		case tm.IsSolid(int(pos.X), int(pos.Y+colliderH/2)):
+			query.Close()
+			return fmt.Errorf("entity %d: inside solid tile at %+v", w.NetIDOf(entity), *pos)
+		case math.Abs(vel.X) > maxMoveSpeed || vel.Y > maxFallSpeed || vel.Y < -maxJumpSpeed:
+			query.Close()
+			return fmt.Errorf("entity %d: velocity %+v over the cap", w.NetIDOf(entity), *vel)
+		}
+	}
+	return nil
+}
+
+// runIntents drives both players with held intents, from one byte per
+// stretch: the low bits pick the intents, the high bits how many ticks they
+// are held. It stops at the first broken invariant.
+func runIntents(w *World, script []byte) error {
+	for i, b := range script {
+		intents := fuzzIntents[int(b)%len(fuzzIntents)]
+		hold := 1 + int(b>>3)
+		for range hold {
+			w.SetPlayerIntent(1, intents)
+			w.SetPlayerIntent(2, fuzzIntents[(i+int(w.Tick))%len(fuzzIntents)])
+			w.Update()
+			if err := checkInvariants(w); err != nil {
+				return fmt.Errorf("tick %d: %w", w.Tick, err)
+			}
+		}
+	}
+	return nil
+}
+
+// TestWorldInvariants drives the world with random intent sequences for
+// thousands of ticks and checks the physics invariants after every tick.
+func TestWorldInvariants(t *testing.T) {
+	for seed := uint64(1); seed <= 8; seed++ {
+		rng := rand.New(rand.NewPCG(seed, 0))
+		script := make([]byte, 500)
+		for i := range script {
+			script[i] = byte(rng.UintN(256))
+		}
+
+		world := invariantWorld()
+		if err := runIntents(world, script); err != nil {
+			t.Errorf("seed %d: %v", seed, err)
+		}
+	}
+}
+
+// FuzzWorldInvariants checks the physics invariants for arbitrary intent
+// scripts. Run with: go test ./internal/game -fuzz FuzzWorldInvariants
+func FuzzWorldInvariants(f *testing.F) {
+	f.Add([]byte{0x12, 0xfa, 0x33, 0x0b})
+	f.Add([]byte{0xf2, 0xf2, 0xf2, 0xf5, 0xf5})
+	f.Fuzz(func(t *testing.T, script []byte) {
+		// Long scripts add little but make every minimization run slow
+		if len(script) > 200 {
+			script = script[:200]
+		}
+		if err := runIntents(invariantWorld(), script); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -0,0 +1,169 @@
+package game
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// KeyColors are the colors keys and locked gates come in, with the color
+// each is drawn in
+var KeyColors = map[string]uint32{
+	"red":    0xE04040,
+	"green":  0x40C040,
+	"blue":   0x4070E0,
+	"yellow": 0xE0C020,
+}
+
+// KeyReach is how close, in tiles, a player must come to a key to pick it
+// up, and to a locked gate to open it
+const KeyReach = 0.75
+
+// KeySpawn places a key in a level
+type KeySpawn struct {
+	Color string  `json:"color"`
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+}
+
+// KeyPickup component marks a key lying in the level
+type KeyPickup struct {
+	Color string
+}
+
+// Door component marks a tile of a locked gate, drawn in its key's color
+// while the gate is closed
+type Door struct {
+	Gate string
+}
+
+// KeySprite returns the sprite of a key of the given color
+func KeySprite(color string) string {
+	return "key_" + color
+}
+
+// DoorSprite returns the sprite of a locked gate's tiles
+func DoorSprite(color string) string {
+	return "door_" + color
+}
+
+// validateKeys checks that keys and locked gates have known colors
+func (l *Level) validateKeys() error {
+	for i, k := range l.Keys {
+		if _, ok := KeyColors[k.Color]; !ok {
+			return fmt.Errorf("key %d: unknown color %q", i, k.Color)
+		}
+	}
+	for _, g := range l.Gates {
+		if _, ok := KeyColors[g.Lock]; g.Lock != "" && !ok {
+			return fmt.Errorf("gate %s: unknown lock color %q", g.ID, g.Lock)
+		}
+	}
+	return nil
+}
+
+// Keys returns the colors of the keys the players hold, sorted. Keys are
+// shared: any player's key opens its gates for everyone.
+func (w *World) Keys() []string {
+	if len(w.keys) == 0 {
+		return nil
+	}
+	return slices.Sorted(maps.Keys(w.keys))
+}
+
+// runKeySystem picks up keys players touch and opens locked gates players
+// holding the key come up to, in player order
+func (w *World) runKeySystem() {
+	if w.level == nil || len(w.level.Keys) == 0 {
+		return
+	}
+	type body struct {
+		player int
+		x, y   float64
+	}
+	var bodies []body
+	query := w.playerFilter.Query()
+	for query.Next() {
+		pos, player := query.Get()
+		if !w.spectating[player.ID] {
+			bodies = append(bodies, body{player.ID, pos.X, pos.Y})
+		}
+	}
+	slices.SortFunc(bodies, func(a, b body) int { return a.player - b.player })
+
+	const colW, colH = 0.8, 0.9
+	changed := false
+	for _, b := range bodies {
+		for _, k := range w.level.Keys {
+			if w.keys[k.Color] || k.X-b.x > KeyReach || b.x-k.X > KeyReach || k.Y-b.y > KeyReach || b.y-k.Y > KeyReach {
+				continue
+			}
+			w.keys[k.Color] = true
+			w.keysVersion++
+			changed = true
+			w.emit(Event{Type: EventKey, Tick: w.Tick, Player: b.player, Kind: k.Color, X: k.X, Y: k.Y})
+		}
+		reach := Rect{X: b.x - colW/2 - KeyReach, Y: b.y - KeyReach, W: colW + 2*KeyReach, H: colH + 2*KeyReach}
+		for i := range w.level.Gates {
+			g := &w.level.Gates[i]
+			if g.Lock == "" || !w.keys[g.Lock] || w.GateOpen(g.ID) || !g.Area.Overlaps(reach) {
+				continue
+			}
+			w.toggleGate(g.ID)
+			w.emit(Event{Type: EventUnlock, Tick: w.Tick, Player: b.player, Kind: g.ID, X: g.Area.X, Y: g.Area.Y})
+		}
+	}
+	if changed {
+		w.syncKeys()
+	}
+}
+
+// syncKeys respawns the keys nobody holds yet and the door tiles of the
+// closed locked gates
+func (w *World) syncKeys() {
+	w.ECS.RemoveEntities(w.keyFilter.Batch(), nil)
+	w.ECS.RemoveEntities(w.doorFilter.Batch(), nil)
+	if w.level == nil {
+		return
+	}
+	for _, k := range w.level.Keys {
+		if !w.keys[k.Color] {
+			sprite := Sprite{ID: InternSprite(KeySprite(k.Color)), Color: KeyColors[k.Color]}
+			w.keyMapper.NewEntity(&Position{X: k.X, Y: k.Y}, &sprite, &KeyPickup{Color: k.Color})
+		}
+	}
+	for i := range w.level.Gates {
+		g := &w.level.Gates[i]
+		if g.Lock == "" || w.GateOpen(g.ID) {
+			continue
+		}
+		sprite := Sprite{ID: InternSprite(DoorSprite(g.Lock)), Color: KeyColors[g.Lock]}
+		// Anchored at the bottom center of each tile, as renderers anchor sprites
+		for y := int(g.Area.Y); y < int(g.Area.Y+g.Area.H); y++ {
+			for x := int(g.Area.X); x < int(g.Area.X+g.Area.W); x++ {
+				w.doorMapper.NewEntity(&Position{X: float64(x) + 0.5, Y: float64(y + 1)}, &sprite, &Door{Gate: g.ID})
+			}
+		}
+	}
+}
+
+// restoreKeys sets the keys held, for Restore
+func (w *World) restoreKeys(keys []string) {
+	if slices.Equal(keys, w.Keys()) {
+		return
+	}
+	clear(w.keys)
+	for _, k := range keys {
+		w.keys[k] = true
+	}
+	w.keysVersion++
+	w.syncKeys()
+}
+
+// SetLevelState sets the tiles broken, the gates toggled and the keys held,
+// as a WorldState or a snapshot's protocol.LevelState carries them, for
+// clients that receive them apart from a full state
+func (w *World) SetLevelState(broken []int, gates, keys []string) {
+	w.restoreTiles(broken, gates)
+	w.restoreKeys(keys)
+}
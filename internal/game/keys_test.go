@@ -0,0 +1,85 @@
+package game
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+const keyLevel = `{"name": "vault", "tiles": [
+	"#..........#",
+	"#......#...#",
+	"#......#...#",
+	"#......#...#",
+	"#......#...#",
+	"############"],
+	"spawns": [{"x": 2, "y": 4}],
+	"keys": [{"color": "red", "x": 4, "y": 4}],
+	"gates": [{"id": "vault", "area": {"x": 7, "y": 1, "w": 1, "h": 4}, "lock": "red"}]}`
+
+// TestKeys tests that walking over a key picks it up, walking up to its
+// locked gate opens it, and rollback puts the key and the gate back.
+func TestKeys(t *testing.T) {
+	level, err := DecodeLevel([]byte(keyLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	world := NewWorld()
+	world.LoadLevel(level)
+	world.SpawnPlayer(1, "Test", 2, 4)
+	var events []Event
+	world.Subscribe(func(e Event) {
+		if e.Type == EventKey || e.Type == EventUnlock {
+			events = append(events, e)
+		}
+	})
+	sprites := func() (keys, doors int) {
+		for _, r := range world.GetRenderables() {
+			switch r.SpriteID.String() {
+			case KeySprite("red"):
+				keys++
+			case DoorSprite("red"):
+				doors++
+			}
+		}
+		return keys, doors
+	}
+	if keys, doors := sprites(); keys != 1 || doors != 4 {
+		t.Fatalf("%d keys and %d door tiles, want 1 and 4", keys, doors)
+	}
+	start := world.Snapshot()
+
+	for range 120 {
+		world.SetPlayerIntent(1, protocol.IntentRight)
+		world.Update()
+	}
+	if !slices.Equal(world.Keys(), []string{"red"}) {
+		t.Fatalf("Keys = %q, want red", world.Keys())
+	}
+	if !world.GateOpen("vault") || world.TileMap.IsSolid(7, 2) {
+		t.Fatal("Walking up to the gate with its key should open it")
+	}
+	if len(events) != 2 || events[0].Type != EventKey || events[0].Kind != "red" || events[1].Type != EventUnlock || events[1].Kind != "vault" {
+		t.Errorf("Events = %+v, want the key then the unlock", events)
+	}
+	if keys, doors := sprites(); keys != 0 || doors != 0 {
+		t.Errorf("%d keys and %d door tiles left, want none", keys, doors)
+	}
+	if state := world.Snapshot(); state.LevelVersion == start.LevelVersion || len(state.Keys) != 1 || len(state.Gates) != 1 {
+		t.Errorf("State keys %q, gates %q; want both in the state with a new version", state.Keys, state.Gates)
+	}
+
+	world.Restore(start)
+	if world.Keys() != nil || world.GateOpen("vault") || !world.TileMap.IsSolid(7, 2) {
+		t.Error("Restore should put the key and the locked gate back")
+	}
+	if keys, doors := sprites(); keys != 1 || doors != 4 {
+		t.Errorf("%d keys and %d door tiles after restore, want 1 and 4", keys, doors)
+	}
+
+	bad := []byte(`{"tiles": ["#"], "keys": [{"color": "mauve"}]}`)
+	if _, err := DecodeLevel(bad); err == nil {
+		t.Error("A key of an unknown color should be refused")
+	}
+}
@@ -0,0 +1,86 @@
+package game
+
+import (
+	"github.com/mlange-42/ark/ecs"
+)
+
+// Knockback tuning. Speeds stay within the player's move and jump speeds,
+// so the collision system resolves tumbling enemies like players.
+const (
+	KnockbackSpeed    = 0.15 // Horizontal speed of an uncharged hit
+	KnockbackMaxSpeed = 0.5  // Horizontal speed of a fully charged hit
+	KnockbackLift     = 0.2  // Upward speed of an uncharged hit
+	KnockbackMaxLift  = 0.8  // Upward speed of a fully charged hit
+	TumbleTicks       = 30   // Minimum ticks an enemy tumbles after a hit
+	tumbleFriction    = 0.8  // Horizontal speed kept per tick on the ground
+	tumbleDrag        = 0.95 // Speed kept per tick in the air
+)
+
+// knockback throws an enemy away from a hit, harder the more charged it
+// was, and sets it tumbling. Players aren't knocked back: their input sets
+// their speed every tick, and training dummies stay put.
+func (w *World) knockback(target ecs.Entity, attacker int, right bool, charge float64) {
+	if !w.enemyMap.HasAll(target) || w.dummyMap.HasAll(target) {
+		return
+	}
+	charge = min(max(charge, 0), 1)
+	_, vel, grounded := w.bodyMap.Get(target)
+	vel.X = KnockbackSpeed + charge*(KnockbackMaxSpeed-KnockbackSpeed)
+	if !right {
+		vel.X = -vel.X
+	}
+	vel.Y = -(KnockbackLift + charge*(KnockbackMaxLift-KnockbackLift))
+	grounded.OnGround = false
+
+	enemy := w.enemyMap.Get(target)
+	enemy.Tumble = TumbleTicks
+	enemy.KnockedBy = attacker
+}
+
+// runTumbles slows tumbling enemies down and hands them back to their AI
+// once they come to rest. A tumbling enemy that touches a hazard tile dies,
+// credited to whoever knocked it there.
+func (w *World) runTumbles() {
+	const colH = 0.9
+	var killed []ecs.Entity
+	var killers []int
+
+	query := w.tumbleFilter.Query()
+	for query.Next() {
+		pos, vel, grav, grounded, enemy := query.Get()
+		if enemy.Tumble == 0 {
+			continue
+		}
+
+		x := int(pos.X)
+		if w.TileMap.IsHazard(x, int(pos.Y+colH/2)) || w.TileMap.IsHazard(x, int(pos.Y+colH-0.01)) {
+			killed = append(killed, query.Entity())
+			killers = append(killers, enemy.KnockedBy)
+			continue
+		}
+
+		if grounded.OnGround {
+			vel.X = float64(vel.X * tumbleFriction)
+		} else {
+			vel.X = float64(vel.X * tumbleDrag)
+		}
+		if grav.Scale == 0 {
+			vel.Y = float64(vel.Y * tumbleDrag) // Flyers have no gravity to stop them
+		}
+		if enemy.Tumble > 1 || grounded.OnGround || grav.Scale == 0 {
+			enemy.Tumble-- // Ground enemies tumble until they land
+		}
+		if enemy.Tumble == 0 {
+			vel.X = 0
+			if grav.Scale == 0 {
+				vel.Y = 0
+			}
+		}
+	}
+
+	for i, entity := range killed {
+		if w.ECS.Alive(entity) {
+			w.damage(entity, killers[i], w.healthMap.Get(entity).Current)
+		}
+	}
+}
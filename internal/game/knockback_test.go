@@ -0,0 +1,61 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/collision"
+	"github.com/mlange-42/ark/ecs"
+)
+
+// knockbackLevel is a floor with a spike pit from x 13 to 20
+func knockbackLevel() *Level {
+	tm := collision.NewTileMap(24, 12)
+	for x := range 24 {
+		tm.Set(x, 11, collision.TileSolid)
+	}
+	for x := 13; x <= 20; x++ {
+		tm.Set(x, 10, collision.TileHazard)
+	}
+	return &Level{Name: "knockback", TileMap: tm}
+}
+
+// punch throws a fist of the given charge into the enemy from its left
+func punch(world *World, id int, enemy ecs.Entity, charge float64) {
+	x, y, _ := world.EntityPosition(world.NetIDOf(enemy))
+	distance := MinFistDistance + charge*(MaxFistDistance-MinFistDistance)
+	world.SpawnFist(x-0.6, y+0.2, true, distance, id)
+}
+
+// TestKnockback tests that knockback scales with charge, that a knocked
+// enemy settles back down, and that one knocked into spikes dies, credited
+// to the player who hit it.
+func TestKnockback(t *testing.T) {
+	world := NewWorld()
+	world.LoadLevel(knockbackLevel())
+	weak := world.SpawnEnemy("slime", 4, 9)
+	strong := world.SpawnEnemy("slime", 10, 9)
+	for _, e := range []ecs.Entity{weak, strong} {
+		world.SetEntityHealth(world.NetIDOf(e), 3, 3)
+	}
+	deaths := collect(world, EventDeath)
+	for range 10 {
+		world.Update()
+	}
+
+	punch(world, 1, weak, 0)
+	punch(world, 1, strong, 1)
+	for range 60 {
+		world.Update()
+	}
+
+	x, _, ok := world.EntityPosition(world.NetIDOf(weak))
+	if !ok || x <= 4 || x >= 7 {
+		t.Errorf("An uncharged hit should push the slime a little, at x %v", x)
+	}
+	if world.enemyMap.Get(weak).Tumble != 0 {
+		t.Error("The slime should have stopped tumbling")
+	}
+	if len(*deaths) != 1 || (*deaths)[0].Attacker != 1 || (*deaths)[0].X <= 13 {
+		t.Fatalf("A charged hit should send the slime into the spikes, got %+v", *deaths)
+	}
+}
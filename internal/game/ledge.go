@@ -0,0 +1,117 @@
+package game
+
+import (
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// Ledge grab tuning
+const (
+	LedgeReach    = 0.1 // How close to a wall the player must be to grab it
+	LedgeCooldown = 12  // Ticks after letting go before another grab
+)
+
+// Ledge tracks a player hanging from a tile edge. While hanging the player
+// is pinned with the head level with the top of the ledge tile.
+type Ledge struct {
+	Hanging      bool
+	TileX, TileY int  // The tile hung from
+	Right        bool // The ledge is to the player's right
+	Cooldown     int  // Ticks until the player may grab again
+
+	JumpWasPressed bool // For edge detection: holding jump doesn't pull up
+}
+
+// runLedgeSystem grabs, holds and climbs ledges. A falling player grabs a
+// solid tile beside them with free space above it once their upper body
+// passes its top edge. Hanging cancels gravity; jump pulls up onto the
+// ledge and down lets go.
+func (w *World) runLedgeSystem() {
+	if w.TileMap == nil {
+		return
+	}
+	const colW, colH = 0.8, 0.9
+
+	query := w.ledgeFilter.Query()
+	for query.Next() {
+		pos, vel, grounded, ctrl, ledge, sprite := query.Get()
+		jumpPressed := ctrl.Intents&protocol.IntentJump != 0
+		jumpJustPressed := jumpPressed && !ledge.JumpWasPressed
+		ledge.JumpWasPressed = jumpPressed
+		if ledge.Cooldown > 0 {
+			ledge.Cooldown--
+		}
+
+		entity := query.Entity()
+		if w.noclipped(entity) {
+			ledge.Hanging = false
+			continue
+		}
+		pounding := w.attackMapper.HasAll(entity) && w.attackMapper.Get(entity).Pounding
+
+		if ledge.Hanging {
+			switch {
+			case ctrl.Intents&protocol.IntentDown != 0 || pounding:
+				ledge.Hanging = false
+				ledge.Cooldown = LedgeCooldown
+				continue
+			case jumpJustPressed:
+				// Pull up: stand on the ledge, just past its edge
+				ledge.Hanging = false
+				pos.Y = float64(ledge.TileY) - colH
+				if ledge.Right {
+					pos.X = float64(ledge.TileX) + colW/2
+				} else {
+					pos.X = float64(ledge.TileX+1) - colW/2
+				}
+				vel.X, vel.Y = 0, 0
+				grounded.OnGround = true
+				continue
+			}
+			w.hang(pos, vel, grounded, ledge)
+			sprite.ID = SpritePlayerHang
+			continue
+		}
+
+		if grounded.OnGround || vel.Y <= 0 || pounding || ledge.Cooldown > 0 ||
+			ctrl.Intents&protocol.IntentDown != 0 {
+			continue
+		}
+
+		// The ledge's top edge must have passed between where the head was
+		// last tick and the middle of the body
+		prevHead := pos.Y - vel.Y
+		edge := int(pos.Y + colH/2)
+		if float64(edge) < prevHead || float64(edge) > pos.Y+colH/2 {
+			continue
+		}
+		for _, right := range []bool{true, false} {
+			x := int(pos.X - colW/2 - LedgeReach)
+			if right {
+				x = int(pos.X + colW/2 + LedgeReach)
+			}
+			if x == int(pos.X) || !w.TileMap.IsSolid(x, edge) || w.TileMap.IsSolid(x, edge-1) {
+				continue
+			}
+			*ledge = Ledge{Hanging: true, TileX: x, TileY: edge, Right: right, JumpWasPressed: jumpPressed}
+			w.hang(pos, vel, grounded, ledge)
+			sprite.ID = SpritePlayerHang
+			if w.attackMapper.HasAll(entity) {
+				w.attackMapper.Get(entity).FacingRight = right
+			}
+			break
+		}
+	}
+}
+
+// hang pins a player to their ledge, against its wall
+func (w *World) hang(pos *Position, vel *Velocity, grounded *Grounded, ledge *Ledge) {
+	const colW = 0.8
+	pos.Y = float64(ledge.TileY)
+	if ledge.Right {
+		pos.X = float64(ledge.TileX) - colW/2
+	} else {
+		pos.X = float64(ledge.TileX+1) + colW/2
+	}
+	vel.X, vel.Y = 0, 0
+	grounded.OnGround = false
+}
@@ -0,0 +1,81 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/collision"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// ledgeLevel is a floor with a wall rising from it to row 6
+func ledgeLevel() *Level {
+	tm := collision.NewTileMap(20, 12)
+	for x := range 20 {
+		tm.Set(x, 11, collision.TileSolid)
+	}
+	for y := 6; y < 11; y++ {
+		tm.Set(10, y, collision.TileSolid)
+	}
+	return &Level{Name: "ledge", TileMap: tm, PlayerSpawns: []SpawnPoint{{X: 9.6, Y: 2}}}
+}
+
+// TestLedgeGrab tests that falling past a ledge grabs it, hanging holds the
+// player still until jump pulls them up onto it, and down lets go.
+func TestLedgeGrab(t *testing.T) {
+	world := NewWorld()
+	world.LoadLevel(ledgeLevel())
+	world.SpawnPlayer(1, "One", 9.6, 2)
+
+	for range 40 {
+		world.Update()
+	}
+	if !world.PlayerHanging(1) {
+		t.Fatal("Player falling past the ledge should grab it")
+	}
+	if x, y, _ := world.PlayerPosition(1); x != 9.6 || y != 6 {
+		t.Fatalf("Player should hang at (9.6, 6), got (%v, %v)", x, y)
+	}
+
+	// Hanging survives a rollback
+	state := world.Snapshot()
+	world.SetPlayerIntent(1, protocol.IntentDown)
+	world.Update()
+	world.Restore(state)
+	if !world.PlayerHanging(1) {
+		t.Fatal("Restore should put the player back on the ledge")
+	}
+
+	world.SetPlayerIntent(1, protocol.IntentJump)
+	world.Update()
+	if world.PlayerHanging(1) || !world.PlayerOnGround(1) {
+		t.Fatal("Jump should pull the player up onto the ledge")
+	}
+	if x, y, _ := world.PlayerPosition(1); x < 10 || y != 5.1 {
+		t.Errorf("Player should stand on top of the wall, got (%v, %v)", x, y)
+	}
+
+}
+
+// TestLedgeLetGo tests that down drops from a ledge without grabbing it
+// again on the way down.
+func TestLedgeLetGo(t *testing.T) {
+	world := NewWorld()
+	world.LoadLevel(ledgeLevel())
+	world.SpawnPlayer(1, "One", 9.6, 2)
+	for range 40 {
+		world.Update()
+	}
+	if !world.PlayerHanging(1) {
+		t.Fatal("Player falling past the ledge should grab it")
+	}
+
+	world.SetPlayerIntent(1, protocol.IntentDown)
+	world.Update()
+	world.SetPlayerIntent(1, protocol.IntentNone)
+	for range 30 {
+		world.Update()
+	}
+	if world.PlayerHanging(1) || !world.PlayerOnGround(1) {
+		t.Error("Letting go should drop the player to the floor")
+	}
+}
@@ -4,20 +4,210 @@ import (
 	"github.com/andersfylling/rayman-slides/internal/collision"
 )
 
+// SpawnPoint is where a player starts a level
+type SpawnPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// EnemySpawn places an enemy when a level starts
+type EnemySpawn struct {
+	Type string  `json:"type"`
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+}
+
+// EnemySpawner is a point enemies keep appearing at during waves (horde mode)
+type EnemySpawner struct {
+	X     float64  `json:"x"`
+	Y     float64  `json:"y"`
+	Types []string `json:"types,omitempty"` // Enemy types spawned in turn; default slime
+}
+
+// Rect is an axis-aligned area of a level, in world units
+type Rect struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	W float64 `json:"w"`
+	H float64 `json:"h"`
+}
+
+// Contains reports whether the point is inside the rect
+func (r Rect) Contains(x, y float64) bool {
+	return x >= r.X && x < r.X+r.W && y >= r.Y && y < r.Y+r.H
+}
+
+// Overlaps reports whether two rects share any area
+func (r Rect) Overlaps(o Rect) bool {
+	return r.X < o.X+o.W && o.X < r.X+r.W && r.Y < o.Y+o.H && o.Y < r.Y+r.H
+}
+
+// CameraZone overrides the camera while the player is inside Area: Lock
+// fixes the camera's center (arenas), Clamp keeps the view inside a rect
+// instead of the whole map (vertical shafts). Lock wins if both are set.
+type CameraZone struct {
+	Area  Rect        `json:"area"`
+	Lock  *SpawnPoint `json:"lock,omitempty"`
+	Clamp *Rect       `json:"clamp,omitempty"`
+}
+
+// Hint is tutorial text shown the first time a player enters Area. IDs
+// name the lesson, not the place: save profiles remember them across
+// levels, so a hint with an ID already seen isn't shown again.
+type Hint struct {
+	ID   string `json:"id"`
+	Area Rect   `json:"area"`
+	Text string `json:"text"`
+}
+
+// Level describes everything needed to start, or restart, a level.
+// TileMap is the pristine map; the world plays on a copy of it.
+type Level struct {
+	Name         string
+	TileMap      *collision.TileMap
+	PlayerSpawns []SpawnPoint
+	Enemies      []EnemySpawn
+	Spawners     []EnemySpawner // Wave spawn points; horde mode falls back to Enemies
+	Exit         *SpawnPoint    // Goal for race mode, nil if the level has none
+	CameraZones  []CameraZone   // First zone containing the player wins
+	Hints        []Hint         // First unseen hint containing the player shows
+	Ambience     *Ambience      // Tint, fog and weather, nil for none
+	Dialogues    []Dialogue     // Opened by area, by scripts or by NPCs, by ID
+	Objects      []LevelObject  // Signs, levers and NPCs
+	Gates        []Gate         // Opened and closed by levers, or unlocked by keys
+	Keys         []KeySpawn     // Picked up by touch, shared by the players
+	Teleporters  []Teleporter   // Pairs of pads players step between
+	Rank         *RankGoals     // What finishes are ranked against, nil for the defaults
+	Scripts      []Script       // Run by the scripting engine, see internal/scripting
+}
+
+// PlayerSpawn returns the spawn point for the i-th player, cycling through
+// the level's spawn points
+func (l *Level) PlayerSpawn(i int) SpawnPoint {
+	if len(l.PlayerSpawns) == 0 {
+		return SpawnPoint{X: 1, Y: 1}
+	}
+	return l.PlayerSpawns[i%len(l.PlayerSpawns)]
+}
+
+// NewDemoLevel returns the demo level with its spawn points
+func NewDemoLevel(width, height int) *Level {
+	tm := DemoLevelForViewport(width, height)
+	return &Level{
+		Name:         "demo",
+		TileMap:      tm,
+		PlayerSpawns: []SpawnPoint{{X: 5, Y: 10}, {X: 7, Y: 10}, {X: 9, Y: 10}, {X: 11, Y: 10}},
+		Enemies: []EnemySpawn{
+			{Type: "slime", X: 15, Y: 10},
+			{Type: "slime", X: 28, Y: 14},
+		},
+		Exit: &SpawnPoint{X: float64(tm.Width - 3), Y: float64(tm.Height) - 1.5},
+	}
+}
+
+// Training room layout: the player starts at trainingStart and dummies
+// stand TrainingDistances tiles ahead, so fist ranges can be read off them
+const (
+	trainingWidth  = 48
+	trainingHeight = 16
+	trainingStart  = 4.0
+)
+
+// TrainingDistances are how far ahead of the spawn the training dummies
+// stand, from a quick tap out to a full charge (MaxFistDistance)
+var TrainingDistances = []float64{2, 5, 10, 15, MaxFistDistance}
+
+// NewTrainingLevel returns a flat walled room with a target dummy at each
+// of TrainingDistances, for trying out movement and combat changes
+func NewTrainingLevel() *Level {
+	tm := collision.NewTileMap(trainingWidth, trainingHeight)
+	for x := range trainingWidth {
+		tm.Set(x, trainingHeight-1, collision.TileSolid)
+	}
+	for y := range trainingHeight {
+		tm.Set(0, y, collision.TileSolid)
+		tm.Set(trainingWidth-1, y, collision.TileSolid)
+	}
+
+	floor := float64(trainingHeight - 2)
+	level := &Level{
+		Name:         "training",
+		TileMap:      tm,
+		PlayerSpawns: []SpawnPoint{{X: trainingStart, Y: floor}},
+	}
+	for _, d := range TrainingDistances {
+		level.Enemies = append(level.Enemies, EnemySpawn{Type: EnemyDummy, X: trainingStart + d, Y: floor})
+	}
+	return level
+}
+
+// tutorialTiles is the tutorial's map: open ground to walk, a step to jump
+// onto, a slime to punch, then a tower and a ledge too far apart to jump
+// between without gliding
+var tutorialTiles = []string{
+	"#                                                              #",
+	"#                                                              #",
+	"#                                                              #",
+	"#                                                              #",
+	"#                                                              #",
+	"#                                                              #",
+	"#                                                              #",
+	"#                                                              #",
+	"#                                                              #",
+	"#                                                              #",
+	"#                               ####                ############",
+	"#                               ####                           #",
+	"#                               ####                           #",
+	"#                               ####                           #",
+	"#             ##            ##  ####                           #",
+	"#             ##            ##  ####                           #",
+	"#             ##            ##  ####                           #",
+	"################################################################",
+}
+
+// TutorialHints teach the controls as the tutorial's player reaches each
+// section, in the order they come up
+var TutorialHints = []Hint{
+	{ID: "move", Area: Rect{X: 1, Y: 0, W: 9, H: 17}, Text: "Walk with A and D or the arrow keys."},
+	{ID: "jump", Area: Rect{X: 10, Y: 0, W: 4, H: 17}, Text: "Jump with W, Space or Up. Hop onto the step ahead."},
+	{ID: "charge", Area: Rect{X: 17, Y: 0, W: 6, H: 17}, Text: "Hold J to wind up your fist and let go to throw it. The longer you hold, the farther it flies."},
+	{ID: "glide", Area: Rect{X: 30, Y: 0, W: 6, H: 10.5}, Text: "The ledge is too far to jump. Jump, then hold L in the air to glide with your hair."},
+}
+
+// NewTutorialLevel returns the first level, which teaches moving, jumping,
+// charging the fist and gliding through TutorialHints
+func NewTutorialLevel() *Level {
+	lf := LevelFile{
+		Name:    "tutorial",
+		Tiles:   tutorialTiles,
+		Spawns:  []SpawnPoint{{X: 3, Y: 16}},
+		Enemies: []EnemySpawn{{Type: "slime", X: 25, Y: 16}},
+		Exit:    &SpawnPoint{X: 60, Y: 9},
+		Hints:   TutorialHints,
+	}
+	level, _ := lf.level(lf.Name) // Only fails without tiles
+	return level
+}
+
 // DemoLevel creates a simple test level with default size
 func DemoLevel() *collision.TileMap {
 	return DemoLevelForViewport(40, 20)
 }
 
+// Demo level size bounds. The layout needs the minimum to fit its platforms.
+const (
+	MinDemoWidth  = 40
+	MinDemoHeight = 20
+	MaxDemoWidth  = 512
+	MaxDemoHeight = 256
+)
+
 // DemoLevelForViewport creates a test level sized to fit the given viewport
 func DemoLevelForViewport(width, height int) *collision.TileMap {
-	// Ensure minimum size for playability
-	if width < 40 {
-		width = 40
-	}
-	if height < 20 {
-		height = 20
-	}
+	// Ensure minimum size for playability, and bound huge or bogus viewport
+	// sizes (a terminal mid-resize can report anything)
+	width = min(max(width, MinDemoWidth), MaxDemoWidth)
+	height = min(max(height, MinDemoHeight), MaxDemoHeight)
 	tm := collision.NewTileMap(width, height)
 
 	// Floor
@@ -68,6 +258,8 @@ func RenderTileMap(tm *collision.TileMap) [][]rune {
 		for x := 0; x < tm.Width; x++ {
 			tile := tm.Get(x, y)
 			switch {
+			case tile&collision.TileBreakable != 0:
+				result[y][x] = '%'
 			case tile&collision.TileSolid != 0:
 				result[y][x] = '#'
 			case tile&collision.TilePlatform != 0:
@@ -0,0 +1,202 @@
+package game
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+
+	"github.com/andersfylling/rayman-slides/internal/collision"
+)
+
+// LevelFile is the JSON form of a level. Tiles are rows of the same
+// characters RenderTileMap produces: '#' solid, '%' breakable, '=' platform,
+// '^' hazard, 'H' ladder, '~' water, anything else empty.
+type LevelFile struct {
+	Name      string         `json:"name"`
+	Tiles     []string       `json:"tiles"`
+	Spawns    []SpawnPoint   `json:"spawns"`
+	Enemies   []EnemySpawn   `json:"enemies,omitempty"`
+	Spawners  []EnemySpawner `json:"spawners,omitempty"`
+	Exit      *SpawnPoint    `json:"exit,omitempty"`
+	Camera    []CameraZone   `json:"camera_zones,omitempty"`
+	Hints     []Hint         `json:"hints,omitempty"`
+	Ambience  *Ambience      `json:"ambience,omitempty"`
+	Dialogues []Dialogue     `json:"dialogues,omitempty"`
+	Objects   []LevelObject  `json:"objects,omitempty"`
+	Gates     []Gate         `json:"gates,omitempty"`
+	Keys      []KeySpawn     `json:"keys,omitempty"`
+	Teleports []Teleporter   `json:"teleporters,omitempty"`
+	Rank      *RankGoals     `json:"rank,omitempty"`
+	Scripts   []string       `json:"scripts,omitempty"` // Paths relative to the level file
+	Sources   []Script       `json:"sources,omitempty"` // Inline scripts, see EncodeLevel
+}
+
+// Script is level script source, loaded with the level
+type Script struct {
+	Name   string `json:"name"`
+	Source []byte `json:"source"`
+}
+
+// ReadLevelFile loads a level and the scripts it references from fsys
+func ReadLevelFile(fsys fs.FS, name string) (*Level, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var lf LevelFile
+	if err := json.Unmarshal(data, &lf); err != nil {
+		return nil, fmt.Errorf("level %s: %w", name, err)
+	}
+	level, err := lf.level(name)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := path.Dir(name)
+	for _, script := range lf.Scripts {
+		p := path.Join(dir, script)
+		src, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return nil, fmt.Errorf("level %s: script: %w", name, err)
+		}
+		level.Scripts = append(level.Scripts, Script{Name: p, Source: src})
+	}
+
+	return level, nil
+}
+
+// EncodeLevel encodes a level as a self-contained level file, scripts
+// inline, for sending to clients that don't have it. The encoding is
+// deterministic, so it also identifies the level (see LevelHash).
+func EncodeLevel(l *Level) ([]byte, error) {
+	lf := LevelFile{
+		Name:      l.Name,
+		Spawns:    l.PlayerSpawns,
+		Enemies:   l.Enemies,
+		Spawners:  l.Spawners,
+		Exit:      l.Exit,
+		Camera:    l.CameraZones,
+		Hints:     l.Hints,
+		Ambience:  l.Ambience,
+		Dialogues: l.Dialogues,
+		Objects:   l.Objects,
+		Gates:     l.Gates,
+		Keys:      l.Keys,
+		Teleports: l.Teleporters,
+		Rank:      l.Rank,
+		Sources:   l.Scripts,
+	}
+	for _, row := range RenderTileMap(l.TileMap) {
+		lf.Tiles = append(lf.Tiles, string(row))
+	}
+	return json.Marshal(lf)
+}
+
+// DecodeLevel decodes a level encoded by EncodeLevel
+func DecodeLevel(data []byte) (*Level, error) {
+	var lf LevelFile
+	if err := json.Unmarshal(data, &lf); err != nil {
+		return nil, fmt.Errorf("level: %w", err)
+	}
+	if len(lf.Scripts) > 0 {
+		return nil, fmt.Errorf("level %s: script paths in an encoded level", lf.Name)
+	}
+	level, err := lf.level(lf.Name)
+	if err != nil {
+		return nil, err
+	}
+	level.Scripts = lf.Sources
+	return level, nil
+}
+
+// LevelHash returns the SHA-256 of the encoded level. Two levels with the
+// same hash have the same tiles, spawns, enemies and scripts.
+func LevelHash(l *Level) ([32]byte, error) {
+	data, err := EncodeLevel(l)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return sha256.Sum256(data), nil
+}
+
+// level builds the level described by the file, without its script paths
+// resolved
+func (lf *LevelFile) level(name string) (*Level, error) {
+	if len(lf.Tiles) == 0 {
+		return nil, fmt.Errorf("level %s: no tiles", name)
+	}
+
+	width := 0
+	for _, row := range lf.Tiles {
+		width = max(width, len(row))
+	}
+	tm := collision.NewTileMap(width, len(lf.Tiles))
+	for y, row := range lf.Tiles {
+		for x, ch := range []byte(row) {
+			tm.Set(x, y, tileFromChar(ch))
+		}
+	}
+
+	level := &Level{
+		Name:         lf.Name,
+		TileMap:      tm,
+		PlayerSpawns: lf.Spawns,
+		Enemies:      lf.Enemies,
+		Spawners:     lf.Spawners,
+		Exit:         lf.Exit,
+		CameraZones:  lf.Camera,
+		Hints:        lf.Hints,
+		Ambience:     lf.Ambience,
+		Dialogues:    lf.Dialogues,
+		Objects:      lf.Objects,
+		Gates:        lf.Gates,
+		Keys:         lf.Keys,
+		Teleporters:  lf.Teleports,
+		Rank:         lf.Rank,
+	}
+	if lf.Ambience != nil {
+		if err := lf.Ambience.validate(); err != nil {
+			return nil, fmt.Errorf("level %s: ambience: %w", name, err)
+		}
+	}
+	if lf.Rank != nil {
+		if err := lf.Rank.validate(); err != nil {
+			return nil, fmt.Errorf("level %s: rank: %w", name, err)
+		}
+	}
+	if err := level.validateObjects(); err != nil {
+		return nil, fmt.Errorf("level %s: %w", name, err)
+	}
+	if err := level.validateKeys(); err != nil {
+		return nil, fmt.Errorf("level %s: %w", name, err)
+	}
+	if err := level.validateTeleporters(); err != nil {
+		return nil, fmt.Errorf("level %s: %w", name, err)
+	}
+	if level.Name == "" {
+		level.Name = path.Base(name)
+	}
+	return level, nil
+}
+
+func tileFromChar(ch byte) collision.TileFlag {
+	switch ch {
+	case '#':
+		return collision.TileSolid
+	case '%':
+		return collision.TileSolid | collision.TileBreakable
+	case '=':
+		return collision.TilePlatform
+	case '^':
+		return collision.TileHazard
+	case 'H':
+		return collision.TileLadder
+	case '~':
+		return collision.TileWater
+	default:
+		return collision.TileEmpty
+	}
+}
@@ -0,0 +1,239 @@
+package game
+
+import (
+	"sort"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+	"github.com/mlange-42/ark/ecs"
+)
+
+// NetID component holds an entity's stable network ID. Unlike ecs.Entity
+// handles, which ark recycles once an entity is removed, a NetID is never
+// reused within a world, so snapshots, restores and deltas can refer to
+// entities across despawns and rollbacks.
+type NetID struct {
+	ID protocol.EntityID
+}
+
+// LastNetID returns the highest network ID handed out so far
+func (w *World) LastNetID() protocol.EntityID {
+	return w.nextNetID
+}
+
+// ReserveNetIDs makes sure IDs up to last are never handed out again, for a
+// world resumed from a saved state whose dead entities used them
+func (w *World) ReserveNetIDs(last protocol.EntityID) {
+	w.nextNetID = max(w.nextNetID, last)
+}
+
+// bindNetID gives a new entity a network ID. A zero id allocates the next
+// free one; a non-zero id is used as is, for entities recreated by Restore.
+func (w *World) bindNetID(entity ecs.Entity, id protocol.EntityID) protocol.EntityID {
+	if id == 0 {
+		w.nextNetID++
+		id = w.nextNetID
+	} else if id > w.nextNetID {
+		w.nextNetID = id
+	}
+	w.netIDMap.Add(entity, &NetID{ID: id})
+	w.netEntities[id] = entity
+	return id
+}
+
+// renewNetID gives a reused entity the next free network ID
+func (w *World) renewNetID(entity ecs.Entity) protocol.EntityID {
+	w.nextNetID++
+	id := w.nextNetID
+	w.netIDMap.Get(entity).ID = id
+	w.netEntities[id] = entity
+	return id
+}
+
+// removeEntity removes an entity and forgets its network ID
+func (w *World) removeEntity(entity ecs.Entity) {
+	if w.netIDMap.HasAll(entity) {
+		delete(w.netEntities, w.netIDMap.Get(entity).ID)
+	}
+	w.ECS.RemoveEntity(entity)
+}
+
+// EntityByNetID returns the entity with the given network ID
+func (w *World) EntityByNetID(id protocol.EntityID) (ecs.Entity, bool) {
+	entity, ok := w.netEntities[id]
+	if !ok || !w.ECS.Alive(entity) {
+		return ecs.Entity{}, false
+	}
+	return entity, true
+}
+
+// NetIDOf returns an entity's network ID, or 0 if it has none
+func (w *World) NetIDOf(entity ecs.Entity) protocol.EntityID {
+	if !w.ECS.Alive(entity) || !w.netIDMap.HasAll(entity) {
+		return 0
+	}
+	return w.netIDMap.Get(entity).ID
+}
+
+// EntityPosition returns the position of the entity with the given ID
+func (w *World) EntityPosition(id protocol.EntityID) (x, y float64, ok bool) {
+	entity, ok := w.EntityByNetID(id)
+	if !ok || !w.bodyMap.HasAll(entity) {
+		return 0, 0, false
+	}
+	pos, _, _ := w.bodyMap.Get(entity)
+	return pos.X, pos.Y, true
+}
+
+// SetEntityVelocity sets the velocity of the entity with the given ID.
+// Player velocity is overwritten by the input system every tick.
+func (w *World) SetEntityVelocity(id protocol.EntityID, vx, vy float64) bool {
+	entity, ok := w.EntityByNetID(id)
+	if !ok || !w.bodyMap.HasAll(entity) {
+		return false
+	}
+	_, vel, _ := w.bodyMap.Get(entity)
+	vel.X, vel.Y = vx, vy
+	return true
+}
+
+// EntityVelocity returns the velocity of the entity with the given ID
+func (w *World) EntityVelocity(id protocol.EntityID) (vx, vy float64, ok bool) {
+	entity, ok := w.EntityByNetID(id)
+	if !ok || !w.bodyMap.HasAll(entity) {
+		return 0, 0, false
+	}
+	_, vel, _ := w.bodyMap.Get(entity)
+	return vel.X, vel.Y, true
+}
+
+// SetEntityGravity sets the gravity scale of the entity with the given ID
+func (w *World) SetEntityGravity(id protocol.EntityID, scale float64) bool {
+	entity, ok := w.EntityByNetID(id)
+	if !ok || !w.gravityMap.HasAll(entity) {
+		return false
+	}
+	w.gravityMap.Get(entity).Scale = scale
+	return true
+}
+
+// SetEntityHealth sets the current and maximum health of the entity with
+// the given ID
+func (w *World) SetEntityHealth(id protocol.EntityID, current, max int) bool {
+	entity, ok := w.EntityByNetID(id)
+	if !ok || !w.healthMap.HasAll(entity) {
+		return false
+	}
+	*w.healthMap.Get(entity) = Health{Current: current, Max: max}
+	return true
+}
+
+// Despawn removes the entity with the given ID
+func (w *World) Despawn(id protocol.EntityID) bool {
+	entity, ok := w.EntityByNetID(id)
+	if !ok {
+		return false
+	}
+	w.removeEntity(entity)
+	return true
+}
+
+// EntitiesWithSprite returns the IDs of physics entities using the sprite,
+// in ID order
+func (w *World) EntitiesWithSprite(spriteID string) []protocol.EntityID {
+	var ids []protocol.EntityID
+	query := w.physicsFilter.Query()
+	for query.Next() {
+		entity := query.Entity()
+		if w.spriteMap.HasAll(entity) && w.spriteMap.Get(entity).ID.String() == spriteID {
+			ids = append(ids, w.NetIDOf(entity))
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// PlayerPosition returns the position of the player with the given player ID
+func (w *World) PlayerPosition(playerID int) (x, y float64, ok bool) {
+	query := w.playerFilter.Query()
+	for query.Next() {
+		pos, player := query.Get()
+		if player.ID == playerID {
+			query.Close()
+			return pos.X, pos.Y, true
+		}
+	}
+	return 0, 0, false
+}
+
+// PlayerOnGround reports whether the player with the given player ID is
+// standing on something
+func (w *World) PlayerOnGround(playerID int) bool {
+	query := w.playerFilter.Query()
+	for query.Next() {
+		_, player := query.Get()
+		if player.ID == playerID {
+			entity := query.Entity()
+			query.Close()
+			_, _, grounded := w.bodyMap.Get(entity)
+			return grounded.OnGround
+		}
+	}
+	return false
+}
+
+// PlayerHanging reports whether the player with the given player ID is
+// hanging from a ledge
+func (w *World) PlayerHanging(playerID int) bool {
+	query := w.playerFilter.Query()
+	for query.Next() {
+		_, player := query.Get()
+		if player.ID == playerID {
+			entity := query.Entity()
+			query.Close()
+			return w.ledgeMapper.HasAll(entity) && w.ledgeMapper.Get(entity).Hanging
+		}
+	}
+	return false
+}
+
+// PlayerCharge returns how far a player's attack is charged, from 0 to 1 at
+// MaxChargeTicks, and its ChargeLevel, the stage the charge sprite shows.
+// charging is false unless the player is holding attack.
+func (w *World) PlayerCharge(playerID int) (progress float64, level int, charging bool) {
+	query := w.playerFilter.Query()
+	for query.Next() {
+		_, player := query.Get()
+		if player.ID == playerID {
+			entity := query.Entity()
+			query.Close()
+			if !w.attackMapper.HasAll(entity) {
+				return 0, 0, false
+			}
+			attack := w.attackMapper.Get(entity)
+			if !attack.Charging {
+				return 0, 0, false
+			}
+			return float64(attack.ChargeTicks) / MaxChargeTicks, ChargeLevel(attack.ChargeTicks), true
+		}
+	}
+	return 0, 0, false
+}
+
+// PlayerHealth returns the health of the player with the given player ID;
+// ok is false if they have no body
+func (w *World) PlayerHealth(playerID int) (current, max int, ok bool) {
+	query := w.playerFilter.Query()
+	for query.Next() {
+		_, player := query.Get()
+		if player.ID == playerID {
+			entity := query.Entity()
+			query.Close()
+			if !w.healthMap.HasAll(entity) {
+				return 0, 0, false
+			}
+			health := w.healthMap.Get(entity)
+			return health.Current, health.Max, true
+		}
+	}
+	return 0, 0, false
+}
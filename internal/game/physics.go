@@ -0,0 +1,63 @@
+package game
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// PhysicsProfile is the player movement tuning the input and physics
+// systems run with. Every peer must use the same profile for the
+// simulation to stay deterministic, so its Hash goes in the handshake.
+type PhysicsProfile struct {
+	MoveSpeed float64 `json:"move_speed"` // Horizontal speed while a direction is held
+	JumpSpeed float64 `json:"jump_speed"` // Upward speed a jump starts with
+	Gravity   float64 `json:"gravity"`    // Downward acceleration per tick
+	MaxFall   float64 `json:"max_fall"`   // Fall speed cap
+	GlideFall float64 `json:"glide_fall"` // Fall speed while gliding (IntentGlide)
+}
+
+// DefaultPhysics returns the profile the game is tuned for
+func DefaultPhysics() PhysicsProfile {
+	return PhysicsProfile{MoveSpeed: 0.5, JumpSpeed: 1.0, Gravity: 0.08, MaxFall: 1.0, GlideFall: 0.15}
+}
+
+// LoadPhysics reads a profile from a JSON tunables file. Fields the file
+// leaves out keep their defaults.
+func LoadPhysics(path string) (PhysicsProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return PhysicsProfile{}, err
+	}
+	p := DefaultPhysics()
+	if err := json.Unmarshal(data, &p); err != nil {
+		return PhysicsProfile{}, fmt.Errorf("physics %s: %w", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return PhysicsProfile{}, fmt.Errorf("physics %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Validate checks that every value is positive. The fall cap must stay at
+// most one tile per tick, or falling entities could pass through floors,
+// and gliding must fall slower than that.
+func (p PhysicsProfile) Validate() error {
+	switch {
+	case p.MoveSpeed <= 0 || p.JumpSpeed <= 0 || p.Gravity <= 0 || p.MaxFall <= 0 || p.GlideFall <= 0:
+		return fmt.Errorf("values must be positive: %+v", p)
+	case p.MoveSpeed > 1 || p.MaxFall > 1:
+		return fmt.Errorf("move_speed and max_fall must be at most 1 tile per tick")
+	case p.GlideFall >= p.MaxFall:
+		return fmt.Errorf("glide_fall must be below max_fall")
+	}
+	return nil
+}
+
+// Hash returns the SHA-256 of the profile's JSON encoding, for comparing
+// profiles between server and clients
+func (p PhysicsProfile) Hash() [32]byte {
+	data, _ := json.Marshal(p) // Plain floats always encode
+	return sha256.Sum256(data)
+}
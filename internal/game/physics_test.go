@@ -0,0 +1,56 @@
+package game
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// TestLoadPhysics tests that a tunables file overrides only the fields it
+// sets, and that invalid profiles are rejected.
+func TestLoadPhysics(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	p, err := LoadPhysics(write("low.json", `{"gravity": 0.04}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultPhysics()
+	want.Gravity = 0.04
+	if p != want {
+		t.Errorf("Got %+v, want %+v", p, want)
+	}
+	if p.Hash() == DefaultPhysics().Hash() {
+		t.Error("Different profiles should hash differently")
+	}
+
+	for _, data := range []string{`{"gravity": 0}`, `{"max_fall": 2}`, `{"glide_fall": 1}`, `{"jump_speed": "high"}`} {
+		if _, err := LoadPhysics(write("bad.json", data)); err == nil {
+			t.Errorf("%s should be rejected", data)
+		}
+	}
+}
+
+// TestTutorialGlide tests that the tutorial's far ledge can only be reached
+// by gliding: the same jump off the tower without it lands on the floor.
+func TestTutorialGlide(t *testing.T) {
+	s := newScenario(t, NewTutorialLevel()).spawn(1, 35, 9)
+	s.wait(5).expectGrounded(1, true).expectNear(1, 35, 9.1, 0.1)
+	s.press(1, protocol.IntentRight|protocol.IntentJump, 1)
+	s.press(1, protocol.IntentRight|protocol.IntentGlide, 90)
+	s.expectGrounded(1, true).expectNear(1, 62.6, 9.1, 0.1).expectEvents(EventFinish, 1)
+
+	s = newScenario(t, NewTutorialLevel()).spawn(1, 35, 9)
+	s.wait(5).press(1, protocol.IntentRight|protocol.IntentJump, 1)
+	s.press(1, protocol.IntentRight, 90)
+	s.expectGrounded(1, true).expectNear(1, 62.6, 16.1, 0.1).expectEvents(EventFinish, 0)
+}
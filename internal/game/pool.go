@@ -0,0 +1,40 @@
+package game
+
+import (
+	"github.com/mlange-42/ark/ecs"
+)
+
+// Pooled marks a deactivated entity kept for reuse. Fists are thrown and
+// consumed every few ticks in a fight; instead of removing them, a spent
+// fist is tagged Pooled and the next SpawnFist takes it back. Filters over
+// pooled kinds exclude the tag, so a pooled entity is invisible to systems,
+// rendering and network lookups.
+type Pooled struct{}
+
+// acquireFist takes a pooled fist back into play, or returns false if the
+// pool is empty. The caller sets its components.
+func (w *World) acquireFist() (ecs.Entity, bool) {
+	n := len(w.fistPool)
+	if n == 0 {
+		return ecs.Entity{}, false
+	}
+	entity := w.fistPool[n-1]
+	w.fistPool = w.fistPool[:n-1]
+	w.pooledMap.Remove(entity)
+	return entity, true
+}
+
+// releaseFist deactivates a spent fist and keeps it for reuse. Its network
+// ID is forgotten like a removed entity's; a reused fist gets a new one.
+func (w *World) releaseFist(entity ecs.Entity) {
+	netID := w.netIDMap.Get(entity)
+	delete(w.netEntities, netID.ID)
+	netID.ID = 0
+	w.pooledMap.Add(entity, &Pooled{})
+	w.fistPool = append(w.fistPool, entity)
+}
+
+// isActive reports whether an entity is alive and not pooled
+func (w *World) isActive(entity ecs.Entity) bool {
+	return w.ECS.Alive(entity) && !w.pooledMap.HasAll(entity)
+}
@@ -0,0 +1,159 @@
+package game
+
+import (
+	"math"
+	"slices"
+
+	"github.com/andersfylling/rayman-slides/internal/collision"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+	"github.com/mlange-42/ark/ecs"
+)
+
+// Ground pound tuning
+const (
+	PoundSpeed   = 1.0 // Fall speed while pounding: the physics cap, reached at once
+	PoundDamage  = 2   // Damage to every target in range on landing
+	PoundRadius  = 2.0 // Horizontal reach of the landing, in world units
+	PoundReachUp = 1.5 // Vertical reach of the landing, in world units
+)
+
+// runPoundSystem starts and drives ground pounds. IntentPound in the air
+// cancels any charge and slams the player straight down at full fall speed,
+// ignoring horizontal input, until runImpactSystem sees it land.
+func (w *World) runPoundSystem() {
+	query := w.poundFilter.Query()
+	for query.Next() {
+		_, vel, grounded, ctrl, attack := query.Get()
+		if !attack.Pounding && ctrl.Intents&protocol.IntentPound != 0 && !grounded.OnGround {
+			attack.Pounding = true
+			attack.Charging = false
+			attack.ChargeTicks = 0
+			attack.Attacking = false
+		}
+		if attack.Pounding {
+			vel.X = 0
+			vel.Y = PoundSpeed
+		}
+	}
+}
+
+// runImpactSystem lands ground pounds. Breakable tiles under the player are
+// broken and the pound goes on through them; anything else ends it, hurting
+// every target with health within PoundRadius. Pounds glance off spiky
+// enemies, whose spikes hurt the player, and the shields of shielded ones.
+func (w *World) runImpactSystem() {
+	type landing struct {
+		entity ecs.Entity
+		x, y   float64
+		player int
+	}
+	var landings []landing
+
+	query := w.poundFilter.Query()
+	for query.Next() {
+		pos, vel, grounded, _, attack := query.Get()
+		if !attack.Pounding || !grounded.OnGround {
+			continue
+		}
+		entity := query.Entity()
+		player := 0
+		if w.playerMap.HasAll(entity) {
+			player = w.playerMap.Get(entity).ID
+		}
+
+		// The collider's feet rest on this row; break what's under them
+		const colW, colH = 0.8, 0.9
+		row := int(math.Round(pos.Y + colH))
+		broke := false
+		for x := int(pos.X - colW/2); x <= int(pos.X+colW/2); x++ {
+			if w.TileMap != nil && w.TileMap.IsBreakable(x, row) {
+				w.breakTile(x, row, player)
+				broke = true
+			}
+		}
+		if broke {
+			grounded.OnGround = false
+			vel.Y = PoundSpeed
+			continue
+		}
+
+		attack.Pounding = false
+		landings = append(landings, landing{entity: entity, x: pos.X, y: pos.Y, player: player})
+	}
+
+	for _, l := range landings {
+		var hit, deflected []ecs.Entity
+		spiked := false
+		targets := w.targetFilter.Query()
+		for targets.Next() {
+			tpos, col, _ := targets.Get()
+			target := targets.Entity()
+			if target == l.entity || (w.playerMap.HasAll(target) && !w.FriendlyFire) {
+				continue
+			}
+			if math.Abs(tpos.X-l.x) > PoundRadius || math.Abs(tpos.Y-l.y) > PoundReachUp {
+				continue
+			}
+			if w.deflects(target, true, l.x > tpos.X) {
+				deflected = append(deflected, target)
+				spiked = spiked || (w.spikyMap.HasAll(target) && math.Abs(tpos.X-l.x) < col.Width)
+				continue
+			}
+			hit = append(hit, target)
+		}
+		w.emit(Event{Type: EventPound, Tick: w.Tick, Player: l.player, X: l.x, Y: l.y})
+		for _, target := range deflected {
+			w.block(target, l.player)
+		}
+		for _, target := range hit {
+			if w.ECS.Alive(target) {
+				w.damage(target, l.player, PoundDamage)
+			}
+		}
+		if spiked && w.ECS.Alive(l.entity) {
+			w.damage(l.entity, 0, w.Difficulty.enemyDamage(SpikeDamage))
+		}
+	}
+}
+
+// breakTile empties a tile for the rest of the level
+func (w *World) breakTile(x, y, player int) {
+	w.TileMap.Set(x, y, collision.TileEmpty)
+	w.broken = append(w.broken, y*w.TileMap.Width+x)
+	w.tilesVersion++
+	w.emit(Event{Type: EventBreak, Tick: w.Tick, Player: player, X: float64(x), Y: float64(y)})
+}
+
+// BrokenTiles returns how many tiles have been broken since the level
+// started
+func (w *World) BrokenTiles() int {
+	return len(w.broken)
+}
+
+// TilesVersion changes whenever the tilemap does: tiles broken, gates
+// opened or closed, the level restarted or rolled back. Renderers redraw
+// the map when it does.
+func (w *World) TilesVersion() uint64 {
+	return w.tilesVersion
+}
+
+// restoreTiles puts the tilemap back to the level's with the given tiles
+// broken and gates toggled, for Restore
+func (w *World) restoreTiles(broken []int, gates []string) {
+	if w.level == nil || slices.Equal(broken, w.broken) && slices.Equal(gates, w.toggledGates()) {
+		return
+	}
+	w.TileMap = w.level.TileMap.Clone()
+	clear(w.gates)
+	for _, id := range gates {
+		w.gates[id] = true
+	}
+	w.applyGates()
+	for _, i := range broken {
+		w.TileMap.Tiles[i] = collision.TileEmpty
+	}
+	w.broken = append(w.broken[:0], broken...)
+	w.tilesVersion++
+	w.syncLevers()
+	w.syncKeys()
+}
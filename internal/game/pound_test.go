@@ -0,0 +1,91 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/collision"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// poundLevel is a floor with a breakable ledge above it
+func poundLevel() *Level {
+	tm := collision.NewTileMap(20, 12)
+	for x := range 20 {
+		tm.Set(x, 11, collision.TileSolid)
+	}
+	for x := 3; x <= 7; x++ {
+		tm.Set(x, 6, collision.TileSolid|collision.TileBreakable)
+	}
+	return &Level{Name: "pound", TileMap: tm, PlayerSpawns: []SpawnPoint{{X: 5, Y: 2}}}
+}
+
+// TestGroundPound tests that a pound breaks through breakable tiles, lands
+// on solid ground, and hurts enemies in range only.
+func TestGroundPound(t *testing.T) {
+	world := NewWorld()
+	world.LoadLevel(poundLevel())
+	world.SpawnPlayer(1, "One", 5, 2)
+	near := world.NetIDOf(world.SpawnEnemy("slime", 6.5, 10))
+	far := world.NetIDOf(world.SpawnEnemy("slime", 12, 10))
+	before := world.Snapshot()
+
+	var events []Event
+	world.Subscribe(func(e Event) { events = append(events, e) })
+
+	world.SetPlayerIntent(1, protocol.IntentDown|protocol.IntentAttack|protocol.IntentPound)
+	for range 30 {
+		world.Update()
+	}
+
+	var breaks, pounds int
+	killed := false
+	for _, e := range events {
+		switch e.Type {
+		case EventBreak:
+			breaks++
+		case EventPound:
+			pounds++
+			if e.X != 5 {
+				t.Errorf("Pound landed at x %v, want 5 (no drift)", e.X)
+			}
+		case EventDeath:
+			killed = killed || (e.Entity == near && e.Attacker == 1)
+		}
+	}
+	if breaks != 2 || pounds != 1 {
+		t.Fatalf("Got %d tiles broken and %d landings, want 2 and 1", breaks, pounds)
+	}
+	if world.TileMap.IsSolid(4, 6) || world.TileMap.IsSolid(5, 6) || !world.TileMap.IsBreakable(6, 6) {
+		t.Error("Only the tiles under the player should break")
+	}
+	if !killed {
+		t.Error("Enemy next to the landing should be killed")
+	}
+	if _, _, ok := world.EntityPosition(far); !ok {
+		t.Error("Enemy out of range should survive")
+	}
+	if x, y, _ := world.PlayerPosition(1); x != 5 || y < 10 {
+		t.Errorf("Player at (%v, %v), want on the floor below", x, y)
+	}
+
+	world.Restore(before)
+	if !world.TileMap.IsBreakable(5, 6) {
+		t.Error("Restore should bring broken tiles back")
+	}
+}
+
+// TestGroundPoundNeedsAir tests that the pound intent does nothing on the
+// ground.
+func TestGroundPoundNeedsAir(t *testing.T) {
+	world := NewWorld()
+	world.LoadLevel(poundLevel())
+	player := world.SpawnPlayer(1, "One", 10, 10.1)
+	for range 5 {
+		world.Update()
+	}
+	world.SetPlayerIntent(1, protocol.IntentPound)
+	world.Update()
+	if world.attackMapper.Get(player).Pounding {
+		t.Error("Pound should not start on the ground")
+	}
+}
@@ -0,0 +1,108 @@
+package game
+
+import (
+	"fmt"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// Rank scoring: a finished run scores up to RankMaxScore points, shared out
+// between the parts of a run. The best combo earns style points on top,
+// making up for points lost elsewhere but never past RankMaxScore.
+const (
+	RankMaxScore     = 100
+	RankOrbPoints    = 25 // Scaled by the share of the level's orbs collected
+	RankCagePoints   = 25 // Scaled by the share of its cages freed
+	RankTimePoints   = 30 // All within par, then scaled by par over the time
+	RankHealthPoints = 20 // Less RankHitCost per damage taken and RankDeathCost per death
+	RankHitCost      = 5
+	RankDeathCost    = 10
+	RankComboPoints  = 2  // Per kill in the best combo after the first
+	RankStylePoints  = 10 // Most the combo earns
+
+	LowestGrade = "D" // Grade of a run below every threshold
+)
+
+// Grade is a letter rank and the lowest score that earns it
+type Grade struct {
+	Letter string `json:"letter"`
+	Score  int    `json:"score"`
+}
+
+// DefaultGrades are the thresholds of a level that sets none, best first
+var DefaultGrades = []Grade{{"S", 95}, {"A", 80}, {"B", 60}, {"C", 40}}
+
+// RankGoals are what a level's runs are ranked against: its totals of orbs
+// and cages, a par time and the grade thresholds. A level without them
+// gives the orb, cage and time points to every finish.
+type RankGoals struct {
+	Orbs   int     `json:"orbs,omitempty"`   // Orbs in the level
+	Cages  int     `json:"cages,omitempty"`  // Cages in the level
+	Par    float64 `json:"par,omitempty"`    // Seconds to finish in for every time point
+	Grades []Grade `json:"grades,omitempty"` // Best first; DefaultGrades if unset
+}
+
+// RunRank is a finished run's score out of RankMaxScore and the grade it
+// earned
+type RunRank struct {
+	Score int
+	Grade string
+}
+
+// Rank scores a finished run from its player's stats. Goals may be nil,
+// for a level that sets none.
+func (g *RankGoals) Rank(ps protocol.PlayerStats) RunRank {
+	var goals RankGoals
+	if g != nil {
+		goals = *g
+	}
+	points := share(RankOrbPoints, ps.Orbs, goals.Orbs) + share(RankCagePoints, ps.Cages, goals.Cages)
+	seconds := float64(ps.FinishTicks) / 60
+	if goals.Par <= 0 || seconds <= goals.Par {
+		points += RankTimePoints
+	} else {
+		points += RankTimePoints * goals.Par / seconds
+	}
+	points += float64(max(RankHealthPoints-RankHitCost*ps.DamageTaken-RankDeathCost*ps.Deaths, 0))
+	points += float64(min(RankComboPoints*max(ps.BestCombo-1, 0), RankStylePoints))
+
+	r := RunRank{Score: min(int(points), RankMaxScore), Grade: LowestGrade}
+	grades := goals.Grades
+	if len(grades) == 0 {
+		grades = DefaultGrades
+	}
+	for _, gr := range grades {
+		if r.Score >= gr.Score {
+			r.Grade = gr.Letter
+			break
+		}
+	}
+	return r
+}
+
+// share returns the points for having n of total, all of them when the
+// total is unknown
+func share(points, n, total int) float64 {
+	if total <= 0 || n >= total {
+		return float64(points)
+	}
+	return float64(points) * float64(n) / float64(total)
+}
+
+// validate checks the totals and that grades are named and best first
+func (g *RankGoals) validate() error {
+	if g.Orbs < 0 || g.Cages < 0 || g.Par < 0 {
+		return fmt.Errorf("negative goal")
+	}
+	for i, gr := range g.Grades {
+		switch {
+		case gr.Letter == "" || len(gr.Letter) > 4:
+			return fmt.Errorf("grade %d: letter must be 1 to 4 bytes", i)
+		case gr.Score < 0 || gr.Score > RankMaxScore:
+			return fmt.Errorf("grade %s: score %d outside 0 to %d", gr.Letter, gr.Score, RankMaxScore)
+		case i > 0 && gr.Score >= g.Grades[i-1].Score:
+			return fmt.Errorf("grade %s: scores must fall from best to worst", gr.Letter)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// TestRank tests scoring against a level's goals, the default grades and
+// grade thresholds set in a level file.
+func TestRank(t *testing.T) {
+	goals := &RankGoals{Orbs: 20, Cages: 2, Par: 30}
+	tests := []struct {
+		name string
+		ps   protocol.PlayerStats
+		want RunRank
+	}{
+		{"perfect", protocol.PlayerStats{Orbs: 20, Cages: 2, FinishTicks: 25 * 60}, RunRank{100, "S"}},
+		{"half the orbs", protocol.PlayerStats{Orbs: 10, Cages: 2, FinishTicks: 25 * 60}, RunRank{87, "A"}},
+		{"twice par", protocol.PlayerStats{Orbs: 20, Cages: 2, FinishTicks: 60 * 60}, RunRank{85, "A"}},
+		{"hurt", protocol.PlayerStats{Orbs: 20, Cages: 1, DamageTaken: 2, Deaths: 1, FinishTicks: 60 * 60}, RunRank{52, "C"}},
+		{"rushed", protocol.PlayerStats{DamageTaken: 9, FinishTicks: 120 * 60}, RunRank{7, LowestGrade}},
+		{"hurt with style", protocol.PlayerStats{Orbs: 20, Cages: 1, DamageTaken: 2, Deaths: 1, FinishTicks: 60 * 60, BestCombo: 5}, RunRank{60, "B"}},
+		{"style caps", protocol.PlayerStats{Orbs: 20, Cages: 2, FinishTicks: 60 * 60, BestCombo: 30}, RunRank{95, "S"}},
+		{"perfect with style", protocol.PlayerStats{Orbs: 20, Cages: 2, FinishTicks: 25 * 60, BestCombo: 8}, RunRank{100, "S"}},
+	}
+	for _, tc := range tests {
+		if got := goals.Rank(tc.ps); got != tc.want {
+			t.Errorf("%s: Rank = %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+
+	var none *RankGoals
+	if got := none.Rank(protocol.PlayerStats{FinishTicks: 1 << 20}); got != (RunRank{100, "S"}) {
+		t.Errorf("Without goals every finish should score full marks, got %+v", got)
+	}
+
+	lf := LevelFile{Tiles: []string{"#"}, Rank: &RankGoals{Grades: []Grade{{"Gold", 90}, {"Silver", 50}}}}
+	if _, err := lf.level("bad"); err == nil {
+		t.Error("A grade letter over 4 bytes should be refused")
+	}
+	lf.Rank.Grades = []Grade{{"A", 50}, {"B", 90}}
+	if _, err := lf.level("bad"); err == nil {
+		t.Error("Grades out of order should be refused")
+	}
+	lf.Rank.Grades = []Grade{{"+", 90}, {"-", 50}}
+	level, err := lf.level("ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := level.Rank.Rank(protocol.PlayerStats{DamageTaken: 4, FinishTicks: 60}); got != (RunRank{80, "-"}) {
+		t.Errorf("Rank with the level's grades = %+v", got)
+	}
+}
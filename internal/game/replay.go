@@ -0,0 +1,164 @@
+package game
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// Replay is one player's run through a level: the intents applied on every
+// tick from the level start, run-length encoded. The simulation is
+// deterministic, so replaying the inputs on the same level reproduces the run.
+type Replay struct {
+	Level       string      `json:"level"`
+	Player      string      `json:"player"`
+	FinishTicks uint64      `json:"finish_ticks,omitempty"` // 0 = did not finish
+	Runs        []ReplayRun `json:"runs"`
+}
+
+// ReplayRun is a stretch of ticks with the same intents
+type ReplayRun struct {
+	Intents protocol.Intent `json:"i"`
+	Ticks   int             `json:"n"`
+}
+
+// Record appends the intents of the next tick
+func (r *Replay) Record(intents protocol.Intent) {
+	if n := len(r.Runs); n > 0 && r.Runs[n-1].Intents == intents {
+		r.Runs[n-1].Ticks++
+		return
+	}
+	r.Runs = append(r.Runs, ReplayRun{Intents: intents, Ticks: 1})
+}
+
+// Len returns the number of recorded ticks
+func (r *Replay) Len() int {
+	n := 0
+	for _, run := range r.Runs {
+		n += run.Ticks
+	}
+	return n
+}
+
+// Faster reports whether r finished in fewer ticks than other. A finished
+// replay beats an unfinished or missing one.
+func (r *Replay) Faster(other *Replay) bool {
+	if r.FinishTicks == 0 {
+		return false
+	}
+	return other == nil || other.FinishTicks == 0 || r.FinishTicks < other.FinishTicks
+}
+
+// WriteReplay writes a replay as JSON
+func WriteReplay(w io.Writer, r *Replay) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// ReadReplay reads a replay written by WriteReplay
+func ReadReplay(rd io.Reader) (*Replay, error) {
+	var r Replay
+	if err := json.NewDecoder(rd).Decode(&r); err != nil {
+		return nil, fmt.Errorf("replay: %w", err)
+	}
+	return &r, nil
+}
+
+// ReplayHash returns the SHA-256 of a replay as WriteReplay writes it, the
+// same as hashing its file. Leaderboards keep it with a time so the run
+// can be checked against the replay later.
+func ReplayHash(r *Replay) ([32]byte, error) {
+	var buf bytes.Buffer
+	if err := WriteReplay(&buf, r); err != nil {
+		return [32]byte{}, err
+	}
+	return sha256.Sum256(buf.Bytes()), nil
+}
+
+// StateAt replays the run on level up to tick (counted from the level
+// start) and returns the world state there. Level scripts are not run.
+func (r *Replay) StateAt(level *Level, tick uint64) (WorldState, error) {
+	if tick > uint64(r.Len()) {
+		return WorldState{}, fmt.Errorf("replay: tick %d is past the end (%d ticks)", tick, r.Len())
+	}
+	g := NewGhost(level, r)
+	for g.world.Tick < tick {
+		g.Step()
+	}
+	return g.world.Snapshot(), nil
+}
+
+// ghostPlayerID is the player a ghost's private world simulates
+const ghostPlayerID = 1
+
+// Ghost plays a replay back as a translucent player. It simulates the run in
+// a private world on the same level, so it never collides with or affects
+// the real one. Level scripts are not run.
+type Ghost struct {
+	replay *Replay
+	world  *World
+	run    int // Current run
+	used   int // Ticks of the current run already played
+}
+
+// NewGhost starts a replay from the beginning of the level
+func NewGhost(level *Level, replay *Replay) *Ghost {
+	world := NewWorld()
+	world.LoadLevel(level)
+	spawn := level.PlayerSpawn(0)
+	world.SpawnPlayer(ghostPlayerID, replay.Player, spawn.X, spawn.Y)
+	return &Ghost{replay: replay, world: world}
+}
+
+// Replay returns the replay being played
+func (g *Ghost) Replay() *Replay {
+	return g.replay
+}
+
+// Done reports whether the whole replay has been played
+func (g *Ghost) Done() bool {
+	return g.run >= len(g.replay.Runs)
+}
+
+// Step advances the ghost by one tick. After the replay ends, the ghost
+// stays where it stopped.
+func (g *Ghost) Step() {
+	if g.Done() {
+		return
+	}
+	g.world.SetPlayerIntent(ghostPlayerID, g.replay.Runs[g.run].Intents)
+	g.world.Update()
+
+	g.used++
+	if g.used >= g.replay.Runs[g.run].Ticks {
+		g.run++
+		g.used = 0
+	}
+}
+
+// AppendRenderables appends the ghost player, flagged Ghost, to dst
+func (g *Ghost) AppendRenderables(dst []Renderable) []Renderable {
+	query := g.world.playerRenderFilter.Query()
+	for query.Next() {
+		pos, sprite, attack, player := query.Get()
+		dst = append(dst, Renderable{
+			X: pos.X, Y: pos.Y, SpriteID: sprite.ID, Color: sprite.Color,
+			FlipX: !attack.FacingRight, Name: player.Name, Ghost: true,
+		})
+	}
+	return dst
+}
+
+// Position returns the ghost player's position, if it is alive
+func (g *Ghost) Position() (x, y float64, ok bool) {
+	return g.world.PlayerPosition(ghostPlayerID)
+}
+
+// Subscribe registers a handler for the events of the ghost's private
+// world, such as where the ghost player died
+func (g *Ghost) Subscribe(fn func(Event)) {
+	g.world.Subscribe(fn)
+}
@@ -0,0 +1,85 @@
+package game
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// TestGhostFollowsReplay tests that a recorded run, saved and loaded, plays
+// back to the same position in a ghost without touching the real world.
+func TestGhostFollowsReplay(t *testing.T) {
+	level := NewDemoLevel(40, 20)
+	world := NewWorld()
+	world.LoadLevel(level)
+	spawn := level.PlayerSpawn(0)
+	world.SpawnPlayer(1, "Runner", spawn.X, spawn.Y)
+
+	replay := &Replay{Level: level.Name, Player: "Runner"}
+	for tick := 0; tick < 120; tick++ {
+		intents := protocol.IntentRight
+		if tick%30 == 10 {
+			intents |= protocol.IntentJump
+		}
+		replay.Record(intents)
+		world.SetPlayerIntent(1, intents)
+		world.Update()
+	}
+	if replay.Len() != 120 {
+		t.Fatalf("Recorded %d ticks, want 120", replay.Len())
+	}
+
+	var buf bytes.Buffer
+	if err := WriteReplay(&buf, replay); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadReplay(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := world.Snapshot().Checksum
+	ghost := NewGhost(level, loaded)
+	for !ghost.Done() {
+		ghost.Step()
+	}
+
+	wantX, wantY, _ := world.PlayerPosition(1)
+	gotX, gotY, ok := ghost.Position()
+	if !ok || gotX != wantX || gotY != wantY {
+		t.Errorf("Ghost at (%v, %v), want (%v, %v)", gotX, gotY, wantX, wantY)
+	}
+	if world.Snapshot().Checksum != before {
+		t.Error("Ghost must not change the real world")
+	}
+	if r := ghost.AppendRenderables(nil); len(r) != 1 || !r[0].Ghost {
+		t.Errorf("Expected one ghost renderable, got %+v", r)
+	}
+}
+
+// TestReplayHash tests that a replay's hash matches its file and changes
+// with its runs.
+func TestReplayHash(t *testing.T) {
+	r := &Replay{Level: "demo", Player: "ada", FinishTicks: 3}
+	r.Record(protocol.IntentRight)
+	r.Record(protocol.IntentRight)
+	r.Record(protocol.IntentJump)
+
+	var buf bytes.Buffer
+	if err := WriteReplay(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := ReplayHash(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != sha256.Sum256(buf.Bytes()) {
+		t.Error("Expected the hash of the written file")
+	}
+	r.Record(protocol.IntentJump)
+	if other, _ := ReplayHash(r); other == hash {
+		t.Error("Expected a longer run to hash differently")
+	}
+}
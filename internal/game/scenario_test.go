@@ -0,0 +1,151 @@
+package game
+
+import (
+	"math"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/collision"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// scenario drives a world tick by tick for gameplay tests that read like
+// the bug report they encode:
+//
+//	s := newScenario(t, arenaLevel()).spawn(1, 10, 10)
+//	s.press(1, protocol.IntentAttack, 10).expectFists(0)
+//	s.wait(1).expectFists(1)
+//
+// Every step fails the test with the tick it was checked at.
+type scenario struct {
+	t       *testing.T
+	world   *World
+	players []int
+	events  []Event
+}
+
+// newScenario starts a world on level, which may be nil for an empty map
+func newScenario(t *testing.T, level *Level) *scenario {
+	s := &scenario{t: t, world: NewWorld()}
+	if level != nil {
+		s.world.LoadLevel(level)
+	}
+	s.world.Subscribe(func(e Event) { s.events = append(s.events, e) })
+	return s
+}
+
+// spawn adds a player
+func (s *scenario) spawn(id int, x, y float64) *scenario {
+	s.world.SpawnPlayer(id, "Player", x, y)
+	s.players = append(s.players, id)
+	return s
+}
+
+// press holds intents for one player for ticks, then lets go
+func (s *scenario) press(id int, intents protocol.Intent, ticks int) *scenario {
+	for range ticks {
+		s.world.SetPlayerIntent(id, intents)
+		s.world.Update()
+	}
+	s.world.SetPlayerIntent(id, protocol.IntentNone)
+	return s
+}
+
+// wait runs ticks with no player pressing anything
+func (s *scenario) wait(ticks int) *scenario {
+	for _, id := range s.players {
+		s.world.SetPlayerIntent(id, protocol.IntentNone)
+	}
+	for range ticks {
+		s.world.Update()
+	}
+	return s
+}
+
+// expectNear checks that a player is within tolerance of (x, y)
+func (s *scenario) expectNear(id int, x, y, tolerance float64) *scenario {
+	s.t.Helper()
+	px, py, ok := s.world.PlayerPosition(id)
+	switch {
+	case !ok:
+		s.t.Fatalf("Tick %d: player %d is gone", s.world.Tick, id)
+	case math.Abs(px-x) > tolerance || math.Abs(py-y) > tolerance:
+		s.t.Fatalf("Tick %d: player %d at (%.2f, %.2f), want within %v of (%v, %v)", s.world.Tick, id, px, py, tolerance, x, y)
+	}
+	return s
+}
+
+// expectGrounded checks whether a player stands on the ground
+func (s *scenario) expectGrounded(id int, grounded bool) *scenario {
+	s.t.Helper()
+	if got := s.world.PlayerOnGround(id); got != grounded {
+		s.t.Fatalf("Tick %d: player %d on ground = %v, want %v", s.world.Tick, id, got, grounded)
+	}
+	return s
+}
+
+// expectFists checks how many fists are flying
+func (s *scenario) expectFists(n int) *scenario {
+	s.t.Helper()
+	count := 0
+	query := s.world.fistFilter.Query()
+	for query.Next() {
+		count++
+	}
+	if count != n {
+		s.t.Fatalf("Tick %d: %d fists, want %d", s.world.Tick, count, n)
+	}
+	return s
+}
+
+// expectEvents checks how many events of a type fired since the start
+func (s *scenario) expectEvents(typ EventType, n int) *scenario {
+	s.t.Helper()
+	count := 0
+	for _, e := range s.events {
+		if e.Type == typ {
+			count++
+		}
+	}
+	if count != n {
+		s.t.Fatalf("Tick %d: %d %v events, want %d", s.world.Tick, count, typ, n)
+	}
+	return s
+}
+
+// arenaLevel is a floor at row 11 with a wall at column 15
+func arenaLevel() *Level {
+	tm := collision.NewTileMap(20, 12)
+	for x := range 20 {
+		tm.Set(x, 11, collision.TileSolid)
+	}
+	for y := range 11 {
+		tm.Set(15, y, collision.TileSolid)
+	}
+	return &Level{Name: "arena", TileMap: tm, PlayerSpawns: []SpawnPoint{{X: 5, Y: 10}}}
+}
+
+// TestScenarioAttackTiming tests that a fist leaves on the tick attack is
+// released, however long it was charged, and not again until the cooldown
+// has passed.
+func TestScenarioAttackTiming(t *testing.T) {
+	s := newScenario(t, arenaLevel()).spawn(1, 5, 10)
+	s.press(1, protocol.IntentAttack, 1).expectFists(0)
+	s.wait(1).expectFists(1)
+
+	s.wait(60).expectFists(0) // Fist spent, cooldown over
+	s.press(1, protocol.IntentAttack, 60).expectFists(0)
+	s.wait(1).expectFists(1)
+
+	s.press(1, protocol.IntentAttack, 1).wait(1).expectFists(1) // Still cooling down
+}
+
+// TestScenarioWall tests that running into a wall stops the player beside
+// it, on the ground, and that a jump lands back on the floor.
+func TestScenarioWall(t *testing.T) {
+	s := newScenario(t, arenaLevel()).spawn(1, 5, 10)
+	s.wait(5).expectGrounded(1, true).expectNear(1, 5, 10.1, 0.1)
+
+	s.press(1, protocol.IntentRight, 60).expectNear(1, 14.6, 10.1, 0.1).expectGrounded(1, true)
+	s.press(1, protocol.IntentJump, 1).wait(5).expectGrounded(1, false)
+	s.wait(60).expectGrounded(1, true).expectNear(1, 14.6, 10.1, 0.1)
+}
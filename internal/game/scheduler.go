@@ -0,0 +1,176 @@
+package game
+
+import (
+	"fmt"
+	"time"
+)
+
+// SystemTiming is the measured cost of one system
+type SystemTiming struct {
+	Name string
+	Last time.Duration // Most recent tick
+	Avg  time.Duration // Moving average, 1/16 weight per tick
+	Max  time.Duration // Worst tick since the last ResetTimings
+}
+
+type system struct {
+	name   string
+	after  []string
+	run    func()
+	timing SystemTiming
+}
+
+// Scheduler runs named systems in dependency order and times each one.
+// Order is resolved once, on the first Run after a change, and is stable:
+// systems without constraints between them keep their registration order,
+// which keeps the simulation deterministic.
+type Scheduler struct {
+	systems []*system
+	order   []*system // Resolved run order; nil when stale
+	total   SystemTiming
+
+	// Hook, if set, is called after each system with its elapsed time
+	Hook func(name string, elapsed time.Duration)
+}
+
+// NewScheduler creates an empty scheduler
+func NewScheduler() *Scheduler {
+	return &Scheduler{total: SystemTiming{Name: "total"}}
+}
+
+// Add registers a system that runs after the named systems. Adding a name
+// twice replaces the earlier system.
+func (s *Scheduler) Add(name string, run func(), after ...string) {
+	s.order = nil
+	for _, sys := range s.systems {
+		if sys.name == name {
+			sys.run, sys.after = run, after
+			return
+		}
+	}
+	s.systems = append(s.systems, &system{name: name, after: after, run: run, timing: SystemTiming{Name: name}})
+}
+
+// Order resolves the run order, failing on unknown dependencies and cycles
+func (s *Scheduler) Order() ([]string, error) {
+	order, err := s.resolve()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(order))
+	for i, sys := range order {
+		names[i] = sys.name
+	}
+	return names, nil
+}
+
+func (s *Scheduler) resolve() ([]*system, error) {
+	byName := make(map[string]*system, len(s.systems))
+	for _, sys := range s.systems {
+		byName[sys.name] = sys
+	}
+	for _, sys := range s.systems {
+		for _, dep := range sys.after {
+			if _, ok := byName[dep]; !ok {
+				return nil, fmt.Errorf("system %q depends on unknown system %q", sys.name, dep)
+			}
+		}
+	}
+
+	// Repeatedly take the first registered system whose dependencies are done
+	done := make(map[string]bool, len(s.systems))
+	order := make([]*system, 0, len(s.systems))
+	for len(order) < len(s.systems) {
+		progressed := false
+		for _, sys := range s.systems {
+			if done[sys.name] || !depsDone(sys, done) {
+				continue
+			}
+			done[sys.name] = true
+			order = append(order, sys)
+			progressed = true
+			break
+		}
+		if !progressed {
+			return nil, fmt.Errorf("system dependency cycle among %d systems", len(s.systems)-len(order))
+		}
+	}
+	return order, nil
+}
+
+func depsDone(sys *system, done map[string]bool) bool {
+	for _, dep := range sys.after {
+		if !done[dep] {
+			return false
+		}
+	}
+	return true
+}
+
+// Run runs every system once. It panics if the order cannot be resolved,
+// which is a programming error caught the first time the world ticks.
+func (s *Scheduler) Run() {
+	if s.order == nil {
+		order, err := s.resolve()
+		if err != nil {
+			panic(err)
+		}
+		s.order = order
+	}
+
+	start := time.Now()
+	prev := start
+	for _, sys := range s.order {
+		sys.run()
+		now := time.Now()
+		elapsed := now.Sub(prev)
+		prev = now
+		sys.timing.record(elapsed)
+		if s.Hook != nil {
+			s.Hook(sys.name, elapsed)
+		}
+	}
+	s.total.record(prev.Sub(start))
+}
+
+func (t *SystemTiming) record(d time.Duration) {
+	t.Last = d
+	if t.Avg == 0 {
+		t.Avg = d
+	} else {
+		t.Avg += (d - t.Avg) / 16
+	}
+	if d > t.Max {
+		t.Max = d
+	}
+}
+
+// Timings returns per-system timings in run order
+func (s *Scheduler) Timings() []SystemTiming {
+	return s.AppendTimings(nil)
+}
+
+// AppendTimings appends per-system timings in run order to dst
+func (s *Scheduler) AppendTimings(dst []SystemTiming) []SystemTiming {
+	systems := s.order
+	if systems == nil {
+		systems = s.systems
+	}
+	for _, sys := range systems {
+		dst = append(dst, sys.timing)
+	}
+	return dst
+}
+
+// Total returns the timing of whole ticks
+func (s *Scheduler) Total() SystemTiming {
+	return s.total
+}
+
+// ResetTimings clears the Max of every system
+func (s *Scheduler) ResetTimings() {
+	for _, sys := range s.systems {
+		sys.timing.Max = 0
+	}
+	s.total.Max = 0
+}
@@ -0,0 +1,149 @@
+package game
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSchedulerOrder tests that systems run after their dependencies and
+// otherwise in registration order, and that bad dependencies are reported.
+func TestSchedulerOrder(t *testing.T) {
+	type sys struct {
+		name  string
+		after []string
+	}
+	tests := []struct {
+		name    string
+		systems []sys
+		want    []string
+		wantErr string
+	}{
+		{
+			name:    "registration order",
+			systems: []sys{{"input", nil}, {"physics", nil}, {"render", nil}},
+			want:    []string{"input", "physics", "render"},
+		},
+		{
+			name:    "dependency first",
+			systems: []sys{{"render", []string{"physics"}}, {"physics", []string{"input"}}, {"input", nil}},
+			want:    []string{"input", "physics", "render"},
+		},
+		{
+			name: "stable among independents",
+			systems: []sys{
+				{"b", []string{"a"}}, {"c", nil}, {"a", nil}, {"d", []string{"a"}}, {"e", nil},
+			},
+			want: []string{"c", "a", "b", "d", "e"},
+		},
+		{
+			name:    "unknown dependency",
+			systems: []sys{{"physics", []string{"input"}}},
+			wantErr: `system "physics" depends on unknown system "input"`,
+		},
+		{
+			name:    "cycle",
+			systems: []sys{{"x", nil}, {"a", []string{"c"}}, {"b", []string{"a"}}, {"c", []string{"b"}}},
+			wantErr: "system dependency cycle among 3 systems",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewScheduler()
+			var ran []string
+			for _, sys := range tc.systems {
+				name := sys.name
+				s.Add(name, func() { ran = append(ran, name) }, sys.after...)
+			}
+			order, err := s.Order()
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("Order error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(order, tc.want) {
+				t.Errorf("Order = %v, want %v", order, tc.want)
+			}
+			s.Run()
+			if !slices.Equal(ran, tc.want) {
+				t.Errorf("Ran %v, want %v", ran, tc.want)
+			}
+		})
+	}
+}
+
+// TestSchedulerReplace tests that adding a name again replaces the system
+// and its dependencies, and the order is resolved again.
+func TestSchedulerReplace(t *testing.T) {
+	s := NewScheduler()
+	var ran []string
+	s.Add("a", func() { ran = append(ran, "a") })
+	s.Add("b", func() { ran = append(ran, "b") })
+	s.Run()
+	s.Add("a", func() { ran = append(ran, "a2") }, "b")
+	s.Run()
+	if want := []string{"a", "b", "b", "a2"}; !slices.Equal(ran, want) {
+		t.Errorf("Ran %v, want %v", ran, want)
+	}
+}
+
+// TestSchedulerCyclePanics tests that Run panics on a cycle rather than
+// skipping systems.
+func TestSchedulerCyclePanics(t *testing.T) {
+	s := NewScheduler()
+	s.Add("a", func() {}, "b")
+	s.Add("b", func() {}, "a")
+	defer func() {
+		p := recover()
+		if err, ok := p.(error); !ok || !strings.Contains(err.Error(), "cycle") {
+			t.Errorf("Run panicked with %v, want a cycle error", p)
+		}
+	}()
+	s.Run()
+	t.Error("Run didn't panic")
+}
+
+// TestSchedulerTimings tests that each system's time is measured, averaged
+// and kept as a maximum until reset, and passed to the hook.
+func TestSchedulerTimings(t *testing.T) {
+	const slow = 2 * time.Millisecond
+	s := NewScheduler()
+	sleep := slow
+	s.Add("slow", func() { time.Sleep(sleep) })
+	s.Add("fast", func() {})
+	hooked := map[string]time.Duration{}
+	s.Hook = func(name string, elapsed time.Duration) { hooked[name] = elapsed }
+
+	s.Run()
+	timings := s.Timings()
+	if len(timings) != 2 || timings[0].Name != "slow" || timings[1].Name != "fast" {
+		t.Fatalf("Timings = %+v, want slow then fast", timings)
+	}
+	first := timings[0]
+	if first.Last < slow || first.Avg != first.Last || first.Max != first.Last {
+		t.Errorf("Slow system after one tick = %+v, want at least %v everywhere", first, slow)
+	}
+	if hooked["slow"] != first.Last || hooked["fast"] != timings[1].Last {
+		t.Errorf("Hook saw %v, want %v", hooked, timings)
+	}
+	if total := s.Total(); total.Last < first.Last+timings[1].Last {
+		t.Errorf("Total %v is less than its systems", total.Last)
+	}
+
+	sleep = 0
+	s.Run()
+	second := s.Timings()[0]
+	if second.Max != first.Max || second.Avg >= first.Avg || second.Avg <= second.Last {
+		t.Errorf("Slow system after a fast tick = %+v, want the max kept and the average between", second)
+	}
+
+	s.ResetTimings()
+	if got := s.Timings()[0]; got.Max != 0 || got.Last != second.Last || s.Total().Max != 0 {
+		t.Errorf("After ResetTimings = %+v, total %+v, want only the max cleared", got, s.Total())
+	}
+}
@@ -0,0 +1,19 @@
+package game
+
+// SetSpectating flags a player as spectating, or clears the flag. A
+// spectating player takes no damage, enemies don't dive at them and
+// renderers draw them translucent; the server flags players who went idle
+// in co-op. Like colors it is kept across respawns, and like aim assist it
+// is not part of WorldState: a predicting client sets it from the roster.
+func (w *World) SetSpectating(playerID int, on bool) {
+	if !on {
+		delete(w.spectating, playerID)
+		return
+	}
+	w.spectating[playerID] = true
+}
+
+// Spectating reports whether a player is flagged spectating
+func (w *World) Spectating(playerID int) bool {
+	return w.spectating[playerID]
+}
@@ -0,0 +1,118 @@
+package game
+
+import (
+	"sync"
+)
+
+// SpriteID identifies a sprite by a small integer, so systems and renderers
+// compare and switch on numbers instead of strings. The game's own sprites
+// are constants; other names (enemy types from level files) are interned on
+// first use. IDs are only meaningful within a process: snapshots go on the
+// wire and to disk by name.
+type SpriteID uint16
+
+// Built-in sprites
+const (
+	SpriteNone SpriteID = iota
+	SpritePlayer
+	SpritePlayerPound
+	SpritePlayerHang
+	SpritePlayerChargeLeft1
+	SpritePlayerChargeLeft2
+	SpritePlayerChargeLeft3
+	SpritePlayerChargeRight1
+	SpritePlayerChargeRight2
+	SpritePlayerChargeRight3
+	SpritePlayerPunchLeft
+	SpritePlayerPunchRight
+	SpriteFistLeft
+	SpriteFistRight
+	SpriteEnemy // Unknown enemy type
+	SpriteSlime
+	SpriteBat
+	SpriteSpikySlime
+	SpriteShieldSlime
+	SpriteDiveBat
+	SpriteDiveBatAlert
+	SpriteDiveBatDive
+
+	spriteBuiltin // First interned ID
+)
+
+var builtinSprites = [spriteBuiltin]string{
+	"", "player", "player_pound", "player_hang",
+	"player_charge_left_1", "player_charge_left_2", "player_charge_left_3",
+	"player_charge_right_1", "player_charge_right_2", "player_charge_right_3",
+	"player_punch_left", "player_punch_right", "fist_left", "fist_right",
+	"enemy", "slime", "bat", EnemySpikySlime, EnemyShieldSlime,
+	EnemyDiveBat, EnemyDiveBat + "_alert", EnemyDiveBat + "_dive",
+}
+
+// sprites interns sprite names beyond the built-in ones. The simulation and
+// renderers may run on different goroutines.
+var sprites = struct {
+	sync.RWMutex
+	ids   map[string]SpriteID
+	names []string // By ID - spriteBuiltin
+}{ids: builtinSpriteIDs()}
+
+func builtinSpriteIDs() map[string]SpriteID {
+	ids := make(map[string]SpriteID, spriteBuiltin)
+	for id, name := range builtinSprites {
+		ids[name] = SpriteID(id)
+	}
+	return ids
+}
+
+// InternSprite returns the ID for a sprite name, assigning one on first use
+func InternSprite(name string) SpriteID {
+	sprites.RLock()
+	id, ok := sprites.ids[name]
+	sprites.RUnlock()
+	if ok {
+		return id
+	}
+
+	sprites.Lock()
+	defer sprites.Unlock()
+	if id, ok := sprites.ids[name]; ok {
+		return id
+	}
+	id = spriteBuiltin + SpriteID(len(sprites.names))
+	sprites.ids[name] = id
+	sprites.names = append(sprites.names, name)
+	return id
+}
+
+// String returns the sprite's name, as used in atlases
+func (id SpriteID) String() string {
+	if id < spriteBuiltin {
+		return builtinSprites[id]
+	}
+	sprites.RLock()
+	defer sprites.RUnlock()
+	if i := int(id - spriteBuiltin); i < len(sprites.names) {
+		return sprites.names[i]
+	}
+	return ""
+}
+
+// MarshalText encodes the sprite by name, since IDs differ between processes
+func (id SpriteID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText interns the sprite name
+func (id *SpriteID) UnmarshalText(text []byte) error {
+	*id = InternSprite(string(text))
+	return nil
+}
+
+// chargeSprite returns the charging player's sprite for a charge level
+// from 1 to 3
+func chargeSprite(facingRight bool, level int) SpriteID {
+	if facingRight {
+		return SpritePlayerChargeRight1 + SpriteID(level-1)
+	}
+	return SpritePlayerChargeLeft1 + SpriteID(level-1)
+}
@@ -0,0 +1,34 @@
+package game
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestSpriteIDs tests that built-in and interned sprites keep their names,
+// and that states save sprites by name.
+func TestSpriteIDs(t *testing.T) {
+	if id := InternSprite("player"); id != SpritePlayer {
+		t.Errorf("player interned as %d, want the built-in %d", id, SpritePlayer)
+	}
+	if chargeSprite(true, 2).String() != "player_charge_right_2" || chargeSprite(false, 3).String() != "player_charge_left_3" {
+		t.Error("Charge sprites should match their names")
+	}
+
+	orb := InternSprite("orb")
+	if orb < spriteBuiltin || InternSprite("orb") != orb || orb.String() != "orb" {
+		t.Errorf("orb interned as %d (%q), want one stable new ID", orb, orb)
+	}
+
+	data, err := json.Marshal(Sprite{ID: orb, Color: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"ID":"orb","Color":1}` {
+		t.Errorf("Sprite encodes as %s, want its name", data)
+	}
+	var back Sprite
+	if err := json.Unmarshal(data, &back); err != nil || back.ID != orb {
+		t.Errorf("Decoded %+v (%v), want %v", back, err, orb)
+	}
+}
@@ -0,0 +1,86 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// EntityDiff is how one entity differs between two world states
+type EntityDiff struct {
+	ID     protocol.EntityID
+	OnlyIn int // 1 or 2 when the entity is in one state only, else 0
+	Fields []FieldDiff
+}
+
+// FieldDiff is one component field that differs, e.g. Velocity.X
+type FieldDiff struct {
+	Name string
+	A, B string // Formatted values
+}
+
+// DiffStates compares two world states entity by entity, and field by field
+// for entities in both, for tracking down desyncs. Entities are matched by
+// network ID; only differing ones are returned, in ID order.
+func DiffStates(a, b *WorldState) []EntityDiff {
+	var diffs []EntityDiff
+	i, j := 0, 0
+	for i < len(a.Entities) || j < len(b.Entities) {
+		switch {
+		case j == len(b.Entities) || (i < len(a.Entities) && a.Entities[i].ID < b.Entities[j].ID):
+			diffs = append(diffs, EntityDiff{ID: a.Entities[i].ID, OnlyIn: 1})
+			i++
+		case i == len(a.Entities) || b.Entities[j].ID < a.Entities[i].ID:
+			diffs = append(diffs, EntityDiff{ID: b.Entities[j].ID, OnlyIn: 2})
+			j++
+		default:
+			if fields := diffFields("", reflect.ValueOf(a.Entities[i]), reflect.ValueOf(b.Entities[j]), nil); len(fields) > 0 {
+				diffs = append(diffs, EntityDiff{ID: a.Entities[i].ID, Fields: fields})
+			}
+			i++
+			j++
+		}
+	}
+	return diffs
+}
+
+// diffFields appends the leaf fields of two values of the same struct type
+// that differ, named by their path
+func diffFields(prefix string, a, b reflect.Value, dst []FieldDiff) []FieldDiff {
+	if a.Kind() != reflect.Struct {
+		if !a.Equal(b) {
+			dst = append(dst, FieldDiff{Name: prefix, A: fmt.Sprint(a), B: fmt.Sprint(b)})
+		}
+		return dst
+	}
+	for k := range a.NumField() {
+		field := a.Type().Field(k)
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Name
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		dst = diffFields(name, a.Field(k), b.Field(k), dst)
+	}
+	return dst
+}
+
+// WriteState writes a world state as JSON, for comparing it later with
+// cmd/snapdiff
+func WriteState(w io.Writer, state *WorldState) error {
+	return json.NewEncoder(w).Encode(state)
+}
+
+// ReadState reads a world state written by WriteState
+func ReadState(rd io.Reader) (*WorldState, error) {
+	var state WorldState
+	if err := json.NewDecoder(rd).Decode(&state); err != nil {
+		return nil, fmt.Errorf("state: %w", err)
+	}
+	return &state, nil
+}
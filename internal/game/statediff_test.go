@@ -0,0 +1,44 @@
+package game
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestDiffStates tests that a saved state diffs against a later one by
+// field, and that entities present in one state only are reported.
+func TestDiffStates(t *testing.T) {
+	world := NewWorld()
+	player := world.SpawnPlayer(1, "Test", 10, 10)
+	slime := world.SpawnEnemy("slime", 15, 10)
+
+	var buf bytes.Buffer
+	before := world.Snapshot()
+	if err := WriteState(&buf, &before); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := ReadState(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diffs := DiffStates(saved, &before); len(diffs) != 0 {
+		t.Fatalf("A state read back should match itself, got %+v", diffs)
+	}
+
+	world.healthMap.Get(player).Current = 1
+	slimeID := world.NetIDOf(slime)
+	world.removeEntity(slime)
+	batID := world.NetIDOf(world.SpawnEnemy("bat", 20, 10))
+	after := world.Snapshot()
+
+	diffs := DiffStates(saved, &after)
+	if len(diffs) != 3 {
+		t.Fatalf("Got %+v, want the player, the slime and the bat", diffs)
+	}
+	if d := diffs[0]; d.ID != world.NetIDOf(player) || len(d.Fields) != 1 || d.Fields[0].Name != "Health.Current" {
+		t.Errorf("Player diff %+v, want Health.Current only", d)
+	}
+	if diffs[1].ID != slimeID || diffs[1].OnlyIn != 1 || diffs[2].ID != batID || diffs[2].OnlyIn != 2 {
+		t.Errorf("Got %+v and %+v, want the slime only in a and the bat only in b", diffs[1], diffs[2])
+	}
+}
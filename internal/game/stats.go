@@ -0,0 +1,52 @@
+package game
+
+import (
+	"sort"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// stats returns a player's stats entry, creating it on first use
+func (w *World) stats(playerID int) *protocol.PlayerStats {
+	ps, ok := w.playerStats[playerID]
+	if !ok {
+		ps = &protocol.PlayerStats{PlayerID: playerID}
+		w.playerStats[playerID] = ps
+	}
+	return ps
+}
+
+// changeStats applies fn to a player's stats and marks them changed
+func (w *World) changeStats(playerID int, fn func(ps *protocol.PlayerStats)) {
+	if playerID == 0 {
+		return // Enemies and the environment keep no stats
+	}
+	fn(w.stats(playerID))
+	w.statsVersion++
+}
+
+// Stats returns every player's stats for the current level, by player ID
+func (w *World) Stats() []protocol.PlayerStats {
+	stats := make([]protocol.PlayerStats, 0, len(w.playerStats))
+	for _, ps := range w.playerStats {
+		stats = append(stats, *ps)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].PlayerID < stats[j].PlayerID })
+	return stats
+}
+
+// StatsVersion increases whenever any player's stats change, so senders can
+// skip unchanged stats
+func (w *World) StatsVersion() uint64 {
+	return w.statsVersion
+}
+
+// CollectOrb credits a player with n orbs
+func (w *World) CollectOrb(playerID, n int) {
+	w.changeStats(playerID, func(ps *protocol.PlayerStats) { ps.Orbs += n })
+}
+
+// FreeCage credits a player with breaking open a cage
+func (w *World) FreeCage(playerID int) {
+	w.changeStats(playerID, func(ps *protocol.PlayerStats) { ps.Cages++ })
+}
@@ -0,0 +1,142 @@
+package game
+
+import (
+	"fmt"
+	"slices"
+)
+
+// TeleportReach is how close, in tiles along each axis, a player must come
+// to a teleporter pad for it to fire
+const TeleportReach = 0.5
+
+// TeleportCooldown is how many ticks after teleporting a player can't
+// teleport again, even after stepping off the pad they came out on
+const TeleportCooldown = 45
+
+// SpriteTeleporter is the sprite of teleporter pads
+const SpriteTeleporter = "teleporter"
+
+// Teleporter is a pair of pads: a player stepping onto either comes out on
+// the other, keeping their velocity
+type Teleporter struct {
+	ID string     `json:"id,omitempty"` // For scripts, see EventTeleport
+	A  SpawnPoint `json:"a"`
+	B  SpawnPoint `json:"b"`
+}
+
+// TeleportPad component marks one end of a teleporter
+type TeleportPad struct {
+	Teleporter int // In Level.Teleporters
+}
+
+// Warp tracks a player's teleports. It is part of EntityState, so rollback
+// and replay teleport the same way as the first run.
+type Warp struct {
+	Cooldown int  // Ticks until the player may teleport again
+	OnPad    bool // Standing on a pad: it fires only once they step off and back on
+	Count    int  // Teleports so far, so views can cut the camera when it changes
+}
+
+// validateTeleporters checks that teleporter pads lie inside the level and
+// are apart
+func (l *Level) validateTeleporters() error {
+	w, h := float64(l.TileMap.Width), float64(l.TileMap.Height)
+	for i, t := range l.Teleporters {
+		for _, p := range []SpawnPoint{t.A, t.B} {
+			if p.X < 0 || p.X > w || p.Y < 0 || p.Y > h {
+				return fmt.Errorf("teleporter %d: pad at %v,%v outside the level", i, p.X, p.Y)
+			}
+		}
+		if onPad(t.A, t.B.X, t.B.Y) {
+			return fmt.Errorf("teleporter %d: pads overlap", i)
+		}
+	}
+	return nil
+}
+
+// onPad reports whether a player at (x, y) is on a pad
+func onPad(pad SpawnPoint, x, y float64) bool {
+	return x-pad.X <= TeleportReach && pad.X-x <= TeleportReach &&
+		y-pad.Y <= TeleportReach && pad.Y-y <= TeleportReach
+}
+
+// spawnTeleporters creates both pads of the level's teleporters, for
+// LoadLevel
+func (w *World) spawnTeleporters() {
+	sprite := Sprite{ID: InternSprite(SpriteTeleporter), Color: 0x40E0E0}
+	for i, t := range w.level.Teleporters {
+		w.padMapper.NewEntity(&Position{X: t.A.X, Y: t.A.Y}, &sprite, &TeleportPad{Teleporter: i})
+		w.padMapper.NewEntity(&Position{X: t.B.X, Y: t.B.Y}, &sprite, &TeleportPad{Teleporter: i})
+	}
+}
+
+// PlayerTeleports returns how many times a player has teleported, for
+// views that cut the camera when it changes. A rollback that undoes a
+// teleport changes it back.
+func (w *World) PlayerTeleports(playerID int) int {
+	query := w.warpFilter.Query()
+	for query.Next() {
+		_, warp, player := query.Get()
+		if player.ID == playerID {
+			query.Close()
+			return warp.Count
+		}
+	}
+	return 0
+}
+
+// runTeleportSystem moves players stepping onto a teleporter pad to its
+// other end. A player must step off the pad they came out on, and wait out
+// TeleportCooldown, before teleporting again, so pads don't ping-pong.
+func (w *World) runTeleportSystem() {
+	if w.level == nil || len(w.level.Teleporters) == 0 {
+		return
+	}
+	var events []Event
+	query := w.warpFilter.Query()
+	for query.Next() {
+		pos, warp, player := query.Get()
+		if warp.Cooldown > 0 {
+			warp.Cooldown--
+		}
+
+		var to SpawnPoint
+		on, index, back := false, 0, false
+		for i, t := range w.level.Teleporters {
+			if onPad(t.A, pos.X, pos.Y) {
+				on, index, to = true, i, t.B
+				break
+			}
+			if onPad(t.B, pos.X, pos.Y) {
+				on, index, to, back = true, i, t.A, true
+				break
+			}
+		}
+		stepped := on && !warp.OnPad
+		warp.OnPad = on
+		if !stepped || warp.Cooldown > 0 {
+			continue
+		}
+
+		entity := query.Entity()
+		if w.ledgeMapper.HasAll(entity) && w.ledgeMapper.Get(entity).Hanging {
+			continue
+		}
+		pos.X, pos.Y = to.X, to.Y
+		warp.Cooldown = TeleportCooldown
+		warp.Count++
+		e := Event{Type: EventTeleport, Tick: w.Tick, Player: player.ID, Kind: w.level.Teleporters[index].ID, X: to.X, Y: to.Y}
+		if e.Kind == "" {
+			e.Kind = SpriteTeleporter
+		}
+		if back {
+			e.Amount = 1
+		}
+		events = append(events, e)
+	}
+	// Query order depends on archetypes; emit in player order
+	slices.SortFunc(events, func(a, b Event) int { return a.Player - b.Player })
+	for _, e := range events {
+		w.emit(e)
+	}
+}
@@ -0,0 +1,86 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+const teleportLevel = `{"name": "pads", "tiles": [
+	"#..........#",
+	"#.....#....#",
+	"#.....#....#",
+	"#.....#....#",
+	"#.....#....#",
+	"############"],
+	"spawns": [{"x": 1, "y": 4}],
+	"teleporters": [{"id": "hop", "a": {"x": 3, "y": 4}, "b": {"x": 9, "y": 4}}]}`
+
+// TestTeleport tests that stepping onto a pad moves the player to the other
+// one, standing on or stepping back onto the pad they came out on doesn't
+// send them back until the cooldown is over, and rollback and replay
+// teleport the same way.
+func TestTeleport(t *testing.T) {
+	level, err := DecodeLevel([]byte(teleportLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	world := NewWorld()
+	world.LoadLevel(level)
+	world.SpawnPlayer(1, "Test", 1, 4)
+	var events []Event
+	world.Subscribe(func(e Event) {
+		if e.Type == EventTeleport {
+			events = append(events, e)
+		}
+	})
+	step := func(intents protocol.Intent, ticks int) {
+		for range ticks {
+			world.SetPlayerIntent(1, intents)
+			world.Update()
+		}
+	}
+	step(0, 30)
+	before := world.Snapshot()
+
+	step(protocol.IntentRight, 3)
+	x, y, _ := world.PlayerPosition(1)
+	if world.PlayerTeleports(1) != 1 || x != 9 {
+		t.Fatalf("At %v,%v after %d teleports, want on the far pad after 1", x, y, world.PlayerTeleports(1))
+	}
+	if len(events) != 1 || events[0].Kind != "hop" || events[0].Amount != 0 || events[0].X != 9 {
+		t.Errorf("Events = %+v, want the teleport to the B pad", events)
+	}
+
+	world.Restore(before)
+	if world.PlayerTeleports(1) != 0 {
+		t.Fatal("Restore should undo the teleport")
+	}
+	step(protocol.IntentRight, 3)
+	if rx, ry, _ := world.PlayerPosition(1); rx != x || ry != y || world.PlayerTeleports(1) != 1 {
+		t.Fatalf("Replay ended at %v,%v, want %v,%v", rx, ry, x, y)
+	}
+
+	// Off the pad and straight back on, then standing on it
+	step(protocol.IntentRight, 2)
+	step(protocol.IntentLeft, 2)
+	step(0, TeleportCooldown)
+	if n := world.PlayerTeleports(1); n != 1 {
+		t.Fatalf("%d teleports, want no ping-pong", n)
+	}
+
+	// Once the cooldown is over, stepping back on returns the player
+	step(protocol.IntentRight, 2)
+	step(protocol.IntentLeft, 1)
+	if x, _, _ := world.PlayerPosition(1); world.PlayerTeleports(1) != 2 || x != 3 {
+		t.Errorf("At x %v after %d teleports, want back on the A pad", x, world.PlayerTeleports(1))
+	}
+	if last := events[len(events)-1]; last.Amount != 1 || last.X != 3 {
+		t.Errorf("Last event %+v, want the teleport back", last)
+	}
+
+	bad := []byte(`{"tiles": ["...."], "teleporters": [{"a": {"x": 1, "y": 0}, "b": {"x": 1.2, "y": 0}}]}`)
+	if _, err := DecodeLevel(bad); err == nil {
+		t.Error("Overlapping pads should be refused")
+	}
+}
@@ -1,7 +1,7 @@
 package game
 
 import (
-	"fmt"
+	"sort"
 
 	"github.com/andersfylling/rayman-slides/internal/collision"
 	"github.com/andersfylling/rayman-slides/internal/protocol"
@@ -15,20 +15,113 @@ type World struct {
 	TileMap  *collision.TileMap
 	TileSize float64 // Size of each tile in world units
 
+	// FriendlyFire lets fists damage players other than their owner
+	FriendlyFire bool
+
+	// NoAimAssist turns every player's aim assist off, for competitive modes
+	NoAimAssist bool
+
+	// Difficulty sets the health of players and enemies as they spawn, and
+	// the damage enemies deal
+	Difficulty Difficulty
+
+	// Physics is the movement tuning, DefaultPhysics unless changed
+	Physics PhysicsProfile
+
+	// DialogueHold pauses the world while a player has a dialogue open, or
+	// slows it for Slow dialogues. Single-player sets it; in multiplayer
+	// the world runs on around a player reading.
+	DialogueHold bool
+
 	// Mappers for entity creation
 	playerMapper *ecs.Map9[Position, Velocity, Collider, Sprite, Player, Health, Gravity, Grounded, Controller]
 	enemyMapper  *ecs.Map7[Position, Velocity, Collider, Sprite, Health, Gravity, Grounded]
 	attackMapper *ecs.Map1[AttackState] // Separate mapper for attack state
+	ledgeMapper  *ecs.Map1[Ledge]
+	warpMapper   *ecs.Map1[Warp]
+	enemyMap     *ecs.Map1[Enemy]
+	patrolMap    *ecs.Map1[Patrol]
+	spikyMap     *ecs.Map1[Spiky]
+	shieldMap    *ecs.Map1[Shielded]
+	dummyMap     *ecs.Map1[Dummy]
+	diverMap     *ecs.Map1[Diver]
 	fistMapper   *ecs.Map4[Position, Velocity, Sprite, Fist]
 	fistChecker  *ecs.Map1[Fist] // For checking if entity has Fist component
+	spriteMap    *ecs.Map1[Sprite]
+	healthMap    *ecs.Map1[Health]
+	playerMap    *ecs.Map1[Player]
+	gravityMap   *ecs.Map1[Gravity]
+	controlMap   *ecs.Map1[Controller]
+	bodyMap      *ecs.Map3[Position, Velocity, Grounded]
+	netIDMap     *ecs.Map1[NetID]
+	pooledMap    *ecs.Map1[Pooled]
+	objectMapper *ecs.Map3[Position, Sprite, Interactable]
+	keyMapper    *ecs.Map3[Position, Sprite, KeyPickup]
+	doorMapper   *ecs.Map3[Position, Sprite, Door]
+	padMapper    *ecs.Map3[Position, Sprite, TeleportPad]
 
 	// Filters for queries
 	playerFilter  *ecs.Filter2[Position, Player]
 	physicsFilter *ecs.Filter4[Position, Velocity, Gravity, Grounded]
-	renderFilter  *ecs.Filter2[Position, Sprite]
+	renderFilter  *ecs.Filter2[Position, Sprite] // Without a facing direction
 	controlFilter *ecs.Filter3[Velocity, Grounded, Controller]
 	attackFilter  *ecs.Filter6[Position, Sprite, Controller, AttackState, Velocity, Player]
+	poundFilter   *ecs.Filter5[Position, Velocity, Grounded, Controller, AttackState]
+	ledgeFilter   *ecs.Filter6[Position, Velocity, Grounded, Controller, Ledge, Sprite]
+	patrolFilter  *ecs.Filter4[Position, Velocity, Grounded, Patrol]
+	diverFilter   *ecs.Filter5[Position, Velocity, Collider, Sprite, Diver]
+	tumbleFilter  *ecs.Filter5[Position, Velocity, Gravity, Grounded, Enemy]
 	fistFilter    *ecs.Filter3[Position, Velocity, Fist]
+	targetFilter  *ecs.Filter3[Position, Collider, Health]
+	objectFilter  *ecs.Filter3[Position, Sprite, Interactable]
+	keyFilter     *ecs.Filter1[KeyPickup]
+	doorFilter    *ecs.Filter1[Door]
+	warpFilter    *ecs.Filter3[Position, Warp, Player]
+	allFilter     *ecs.Filter0
+
+	// Registered (cached) filters for rendering, split by facing source
+	fistRenderFilter   *ecs.Filter3[Position, Sprite, Fist]
+	playerRenderFilter *ecs.Filter4[Position, Sprite, AttackState, Player]
+
+	fistSpawns  []fistSpawn  // Attack system scratch, reused every tick
+	fistExpired []ecs.Entity // Fist system scratch, reused every tick
+	fistHits    []fistHit    // Combat system scratch, reused every tick
+	fistPool    []ecs.Entity // Spent fists for SpawnFist to reuse, see Pooled
+
+	systems  *Scheduler    // Runs the systems each tick
+	level    *Level        // Current level, for Reset
+	finished map[int]bool  // Players that reached the exit, by player ID
+	handlers []func(Event) // Event subscribers
+
+	// Per-player stats for the current level, see Stats
+	playerStats  map[int]*protocol.PlayerStats
+	statsVersion uint64
+	levelStart   uint64 // Tick the current level was (re)started
+	broken       []int  // Tile indexes broken since the level started
+	tilesVersion uint64 // Changes whenever the tilemap does, see TilesVersion
+
+	// Gates toggled from how the level starts, by ID, and the keys the
+	// players hold, by color
+	gates       map[string]bool
+	keys        map[string]bool
+	keysVersion uint64
+
+	playerColors map[int]uint32    // Assigned colors by player ID, kept across respawns
+	aimAssist    map[int]AimAssist // By player ID, see SetAimAssist
+	spectating   map[int]bool      // By player ID, see SetSpectating
+	noclip       map[int]bool      // By player ID, see SetNoclip
+
+	// Dialogues open by player ID, the area dialogues already opened and
+	// the buttons held when a dialogue closed, until released; script flags
+	dialogues    map[int]*openDialogue
+	dialogueSeen map[string]bool
+	dialogueMask map[int]protocol.Intent
+	flags        map[string]int
+	scriptState  ScriptState // Saved with snapshots, see SetScriptState
+
+	// Stable network IDs, see NetID
+	netEntities map[protocol.EntityID]ecs.Entity
+	nextNetID   protocol.EntityID
 }
 
 // Controller tracks which intents are active for an entity
@@ -39,7 +132,21 @@ type Controller struct {
 // NewWorld creates a new game world
 func NewWorld() *World {
 	w := &World{
-		TileSize: 1.0,
+		TileSize:     1.0,
+		Physics:      DefaultPhysics(),
+		netEntities:  make(map[protocol.EntityID]ecs.Entity),
+		finished:     make(map[int]bool),
+		playerStats:  make(map[int]*protocol.PlayerStats),
+		playerColors: make(map[int]uint32),
+		aimAssist:    make(map[int]AimAssist),
+		spectating:   make(map[int]bool),
+		noclip:       make(map[int]bool),
+		dialogues:    make(map[int]*openDialogue),
+		dialogueSeen: make(map[string]bool),
+		dialogueMask: make(map[int]protocol.Intent),
+		flags:        make(map[string]int),
+		gates:        make(map[string]bool),
+		keys:         make(map[string]bool),
 	}
 	w.ECS = ecs.NewWorld()
 
@@ -47,16 +154,71 @@ func NewWorld() *World {
 	w.playerMapper = ecs.NewMap9[Position, Velocity, Collider, Sprite, Player, Health, Gravity, Grounded, Controller](w.ECS)
 	w.enemyMapper = ecs.NewMap7[Position, Velocity, Collider, Sprite, Health, Gravity, Grounded](w.ECS)
 	w.attackMapper = ecs.NewMap1[AttackState](w.ECS)
+	w.ledgeMapper = ecs.NewMap1[Ledge](w.ECS)
+	w.warpMapper = ecs.NewMap1[Warp](w.ECS)
+	w.enemyMap = ecs.NewMap1[Enemy](w.ECS)
+	w.patrolMap = ecs.NewMap1[Patrol](w.ECS)
+	w.spikyMap = ecs.NewMap1[Spiky](w.ECS)
+	w.shieldMap = ecs.NewMap1[Shielded](w.ECS)
+	w.dummyMap = ecs.NewMap1[Dummy](w.ECS)
+	w.diverMap = ecs.NewMap1[Diver](w.ECS)
 	w.fistMapper = ecs.NewMap4[Position, Velocity, Sprite, Fist](w.ECS)
 	w.fistChecker = ecs.NewMap1[Fist](w.ECS)
+	w.spriteMap = ecs.NewMap1[Sprite](w.ECS)
+	w.healthMap = ecs.NewMap1[Health](w.ECS)
+	w.playerMap = ecs.NewMap1[Player](w.ECS)
+	w.gravityMap = ecs.NewMap1[Gravity](w.ECS)
+	w.controlMap = ecs.NewMap1[Controller](w.ECS)
+	w.bodyMap = ecs.NewMap3[Position, Velocity, Grounded](w.ECS)
+	w.netIDMap = ecs.NewMap1[NetID](w.ECS)
+	w.pooledMap = ecs.NewMap1[Pooled](w.ECS)
+	w.objectMapper = ecs.NewMap3[Position, Sprite, Interactable](w.ECS)
+	w.keyMapper = ecs.NewMap3[Position, Sprite, KeyPickup](w.ECS)
+	w.doorMapper = ecs.NewMap3[Position, Sprite, Door](w.ECS)
+	w.padMapper = ecs.NewMap3[Position, Sprite, TeleportPad](w.ECS)
 
 	// Initialize filters
 	w.playerFilter = ecs.NewFilter2[Position, Player](w.ECS)
 	w.physicsFilter = ecs.NewFilter4[Position, Velocity, Gravity, Grounded](w.ECS)
-	w.renderFilter = ecs.NewFilter2[Position, Sprite](w.ECS)
+	w.renderFilter = ecs.NewFilter2[Position, Sprite](w.ECS).
+		Without(ecs.C[Fist](), ecs.C[AttackState]()).
+		Register()
 	w.controlFilter = ecs.NewFilter3[Velocity, Grounded, Controller](w.ECS)
 	w.attackFilter = ecs.NewFilter6[Position, Sprite, Controller, AttackState, Velocity, Player](w.ECS)
-	w.fistFilter = ecs.NewFilter3[Position, Velocity, Fist](w.ECS)
+	w.poundFilter = ecs.NewFilter5[Position, Velocity, Grounded, Controller, AttackState](w.ECS)
+	w.ledgeFilter = ecs.NewFilter6[Position, Velocity, Grounded, Controller, Ledge, Sprite](w.ECS)
+	w.patrolFilter = ecs.NewFilter4[Position, Velocity, Grounded, Patrol](w.ECS)
+	w.diverFilter = ecs.NewFilter5[Position, Velocity, Collider, Sprite, Diver](w.ECS)
+	w.tumbleFilter = ecs.NewFilter5[Position, Velocity, Gravity, Grounded, Enemy](w.ECS)
+	w.fistFilter = ecs.NewFilter3[Position, Velocity, Fist](w.ECS).Without(ecs.C[Pooled]())
+	w.targetFilter = ecs.NewFilter3[Position, Collider, Health](w.ECS)
+	w.objectFilter = ecs.NewFilter3[Position, Sprite, Interactable](w.ECS)
+	w.keyFilter = ecs.NewFilter1[KeyPickup](w.ECS)
+	w.doorFilter = ecs.NewFilter1[Door](w.ECS)
+	w.warpFilter = ecs.NewFilter3[Position, Warp, Player](w.ECS)
+	w.allFilter = ecs.NewFilter0(w.ECS)
+	w.fistRenderFilter = ecs.NewFilter3[Position, Sprite, Fist](w.ECS).
+		Without(ecs.C[Pooled]()).
+		Register()
+	w.playerRenderFilter = ecs.NewFilter4[Position, Sprite, AttackState, Player](w.ECS).Register()
+
+	// Systems, in dependency order
+	w.systems = NewScheduler()
+	w.systems.Add("input", w.runInputSystem)
+	w.systems.Add("pound", w.runPoundSystem, "input")
+	w.systems.Add("attack", w.runAttackSystem, "pound")
+	w.systems.Add("fist", w.runFistSystem, "attack")
+	w.systems.Add("ai", w.runAISystem)
+	w.systems.Add("physics", w.runPhysicsSystem, "pound", "ai")
+	w.systems.Add("collision", w.runCollisionSystem, "physics")
+	w.systems.Add("ledge", w.runLedgeSystem, "collision")
+	w.systems.Add("impact", w.runImpactSystem, "collision")
+	w.systems.Add("combat", w.runCombatSystem, "fist", "impact")
+	w.systems.Add("goal", w.runGoalSystem, "collision")
+	w.systems.Add("interact", w.runInteractSystem, "collision")
+	w.systems.Add("keys", w.runKeySystem, "collision")
+	w.systems.Add("teleport", w.runTeleportSystem, "collision")
+	w.systems.Add("combo", w.runComboSystem, "combat")
 
 	return w
 }
@@ -66,24 +228,112 @@ func (w *World) SetTileMap(tm *collision.TileMap) {
 	w.TileMap = tm
 }
 
+// LevelStart returns the tick the current level was (re)started at
+func (w *World) LevelStart() uint64 {
+	return w.levelStart
+}
+
+// SetLevelStart sets the level start tick, for a world resumed from a saved
+// state, so finish times keep counting from the original start
+func (w *World) SetLevelStart(tick uint64) {
+	w.levelStart = tick
+}
+
+// LoadLevel sets up a level on an empty world: the tilemap, its enemies,
+// objects, keys and teleporters. Players are spawned separately, at Level.PlayerSpawn.
+func (w *World) LoadLevel(level *Level) {
+	w.level = level
+	w.levelStart = w.Tick
+	w.TileMap = level.TileMap.Clone()
+	w.broken = w.broken[:0]
+	clear(w.gates)
+	w.applyGates()
+	w.tilesVersion++
+	clear(w.keys)
+	w.keysVersion++
+	for _, e := range level.Enemies {
+		w.SpawnEnemy(e.Type, e.X, e.Y)
+	}
+	w.spawnObjects()
+	w.syncKeys()
+	w.spawnTeleporters()
+}
+
+// Level returns the current level, or nil if none was loaded
+func (w *World) Level() *Level {
+	return w.level
+}
+
+// Reset restarts a level without recreating the world. All entities are
+// despawned, the tilemap is reloaded and every player, dead or alive, is
+// respawned at a spawn point with the same ID and name, so sessions stay attached. A nil level
+// restarts the current one. The tick counter keeps running so tick numbers
+// stay monotonic for clients; they get an EventReset instead.
+func (w *World) Reset(level *Level) {
+	if level == nil {
+		level = w.level
+	}
+	if level == nil {
+		return
+	}
+
+	// Spawn order decides spawn points; Players is sorted by ID. Players
+	// dead at the time of the reset are known from their stats.
+	players := w.Players()
+	for _, ps := range w.Stats() {
+		if _, _, alive := w.PlayerPosition(ps.PlayerID); !alive {
+			players = append(players, Player{ID: ps.PlayerID, Name: ps.Name})
+		}
+	}
+	sort.Slice(players, func(i, j int) bool { return players[i].ID < players[j].ID })
+
+	w.ECS.RemoveEntities(w.allFilter.Batch(), nil)
+	clear(w.netEntities)
+	w.fistPool = w.fistPool[:0]
+	clear(w.finished)
+	clear(w.playerStats)
+	clear(w.dialogues)
+	clear(w.dialogueSeen)
+	clear(w.dialogueMask)
+	clear(w.flags)
+	w.statsVersion++
+	w.LoadLevel(level)
+	for i, p := range players {
+		spawn := level.PlayerSpawn(i)
+		w.SpawnPlayer(p.ID, p.Name, spawn.X, spawn.Y)
+	}
+
+	w.emit(Event{Type: EventReset, Tick: w.Tick, Level: level.Name})
+}
+
 // Update advances the world by one tick
 func (w *World) Update() {
 	w.Tick++
-	w.runInputSystem()
-	w.runAttackSystem()
-	w.runFistSystem()
-	w.runPhysicsSystem()
-	w.runCollisionSystem()
+	if w.runDialogues() {
+		return // Held for a dialogue, see DialogueHold
+	}
+	w.systems.Run()
+}
+
+// Systems returns the world's system scheduler, for registering systems and
+// reading per-system timings
+func (w *World) Systems() *Scheduler {
+	return w.systems
 }
 
 // runInputSystem applies player intents to velocity
 func (w *World) runInputSystem() {
-	const moveSpeed = 0.5
-	const jumpSpeed = 1.0
+	moveSpeed := w.Physics.MoveSpeed
+	jumpSpeed := w.Physics.JumpSpeed
+	glideFall := w.Physics.GlideFall
 
 	query := w.controlFilter.Query()
 	for query.Next() {
 		vel, grounded, ctrl := query.Get()
+		if w.noclipped(query.Entity()) {
+			fly(vel, ctrl)
+			continue
+		}
 
 		// Reset horizontal velocity
 		vel.X = 0
@@ -100,6 +350,11 @@ func (w *World) runInputSystem() {
 			vel.Y = -jumpSpeed
 			grounded.OnGround = false
 		}
+
+		// Glide: the hair slows a fall, not a rise
+		if ctrl.Intents&protocol.IntentGlide != 0 && !grounded.OnGround && vel.Y > glideFall {
+			vel.Y = glideFall
+		}
 	}
 }
 
@@ -108,13 +363,7 @@ func (w *World) runInputSystem() {
 // Longer charge = greater fist travel distance.
 func (w *World) runAttackSystem() {
 	// Collect fists to spawn (can't spawn during query iteration)
-	type fistSpawn struct {
-		x, y        float64
-		facingRight bool
-		distance    float64
-		ownerID     int
-	}
-	var fistsToSpawn []fistSpawn
+	fistsToSpawn := w.fistSpawns[:0]
 
 	query := w.attackFilter.Query()
 	for query.Next() {
@@ -136,6 +385,12 @@ func (w *World) runAttackSystem() {
 		// Update state for next frame's edge detection
 		attack.AttackWasPressed = attackPressed
 
+		// No punching mid-pound
+		if attack.Pounding {
+			sprite.ID = SpritePlayerPound
+			continue
+		}
+
 		// Start charging on key press (if not in cooldown)
 		if attackJustPressed && !attack.Attacking && !attack.Charging {
 			attack.Charging = true
@@ -174,34 +429,22 @@ func (w *World) runAttackSystem() {
 		// Update sprite based on state
 		if attack.Charging {
 			// Charging animation - 3 levels based on charge progress
-			chargeLevel := 1
-			if attack.ChargeTicks > MaxChargeTicks/3 {
-				chargeLevel = 2
-			}
-			if attack.ChargeTicks > MaxChargeTicks*2/3 {
-				chargeLevel = 3
-			}
-
-			if attack.FacingRight {
-				sprite.ID = fmt.Sprintf("player_charge_right_%d", chargeLevel)
-			} else {
-				sprite.ID = fmt.Sprintf("player_charge_left_%d", chargeLevel)
-			}
+			sprite.ID = chargeSprite(attack.FacingRight, ChargeLevel(attack.ChargeTicks))
 		} else if attack.Attacking {
 			if attack.TicksLeft > 0 {
 				attack.TicksLeft--
 				// Punch animation (arm extended)
 				if attack.FacingRight {
-					sprite.ID = "player_punch_right"
+					sprite.ID = SpritePlayerPunchRight
 				} else {
-					sprite.ID = "player_punch_left"
+					sprite.ID = SpritePlayerPunchLeft
 				}
 			} else {
 				attack.Attacking = false
-				sprite.ID = "player"
+				sprite.ID = SpritePlayer
 			}
 		} else {
-			sprite.ID = "player"
+			sprite.ID = SpritePlayer
 		}
 	}
 
@@ -209,20 +452,30 @@ func (w *World) runAttackSystem() {
 	for _, f := range fistsToSpawn {
 		w.SpawnFist(f.x, f.y, f.facingRight, f.distance, f.ownerID)
 	}
+	w.fistSpawns = fistsToSpawn
+}
+
+// fistSpawn is a fist the attack system throws once its query is done
+type fistSpawn struct {
+	x, y        float64
+	facingRight bool
+	distance    float64
+	ownerID     int
 }
 
 // runFistSystem updates flying fist projectiles
 func (w *World) runFistSystem() {
-	// Collect entities to remove (can't remove during query)
-	var toRemove []ecs.Entity
+	// Collect fists to release (can't change entities during query)
+	toRemove := w.fistExpired[:0]
 
 	query := w.fistFilter.Query()
 	for query.Next() {
 		pos, vel, fist := query.Get()
 		entity := query.Entity()
 
-		// Move the fist
+		// Move the fist; only aim assist gives it a vertical speed
 		pos.X += vel.X
+		pos.Y += vel.Y
 
 		// Check if fist has traveled max distance
 		traveled := pos.X - fist.StartX
@@ -235,52 +488,68 @@ func (w *World) runFistSystem() {
 		}
 	}
 
-	// Remove fists that have traveled their distance
+	// Release fists that have traveled their distance
 	for _, e := range toRemove {
-		w.ECS.RemoveEntity(e)
+		w.releaseFist(e)
 	}
+	w.fistExpired = toRemove
 }
 
-// SpawnFist creates a flying fist projectile
+// SpawnFist creates a flying fist projectile, reusing a spent one if any
 // The fist spawns at chest height (0.5 units above the character's foot position)
 func (w *World) SpawnFist(x, y float64, facingRight bool, maxDistance float64, ownerID int) ecs.Entity {
 	velX := FistSpeed
-	spriteID := "fist_right"
+	spriteID := SpriteFistRight
 	if !facingRight {
 		velX = -FistSpeed
-		spriteID = "fist_left"
+		spriteID = SpriteFistLeft
 	}
 
 	// Offset Y to chest level (character position is at feet, chest is about 0.5 units up)
 	chestY := y - 0.5
 
-	return w.fistMapper.NewEntity(
-		&Position{X: x, Y: chestY},
-		&Velocity{X: velX, Y: 0},
-		&Sprite{ID: spriteID, Color: 0xFFFF00},
-		&Fist{
-			StartX:      x,
-			MaxDistance: maxDistance,
-			FacingRight: facingRight,
-			OwnerID:     ownerID,
-		},
-	)
+	pos := Position{X: x, Y: chestY}
+	vel := Velocity{X: velX, Y: FistSpeed * w.aimSlope(ownerID, x, chestY, facingRight, maxDistance)}
+	sprite := Sprite{ID: spriteID, Color: 0xFFFF00}
+	fist := Fist{
+		StartX:      x,
+		MaxDistance: maxDistance,
+		FacingRight: facingRight,
+		OwnerID:     ownerID,
+	}
+
+	if entity, ok := w.acquireFist(); ok {
+		p, v, s, f := w.fistMapper.Get(entity)
+		*p, *v, *s, *f = pos, vel, sprite, fist
+		w.renewNetID(entity)
+		return entity
+	}
+	entity := w.fistMapper.NewEntity(&pos, &vel, &sprite, &fist)
+	w.bindNetID(entity, 0)
+	return entity
 }
 
 // runPhysicsSystem applies gravity and velocity
 func (w *World) runPhysicsSystem() {
-	const gravityAccel = 0.08
+	gravityAccel := w.Physics.Gravity
 
 	query := w.physicsFilter.Query()
 	for query.Next() {
 		pos, vel, grav, grounded := query.Get()
+		if w.noclipped(query.Entity()) {
+			pos.X += vel.X
+			pos.Y += vel.Y
+			continue
+		}
 
-		// Apply gravity
-		vel.Y += gravityAccel * grav.Scale
+		// Apply gravity. The explicit conversion rounds the product, so
+		// arm64 can't fuse it into a multiply-add that rounds differently
+		// from amd64 (see the determinism test)
+		vel.Y += float64(gravityAccel * grav.Scale)
 
 		// Cap fall speed
-		if vel.Y > 1.0 {
-			vel.Y = 1.0
+		if vel.Y > w.Physics.MaxFall {
+			vel.Y = w.Physics.MaxFall
 		}
 
 		// Apply velocity
@@ -301,6 +570,9 @@ func (w *World) runCollisionSystem() {
 	query := w.physicsFilter.Query()
 	for query.Next() {
 		pos, vel, _, grounded := query.Get()
+		if w.noclipped(query.Entity()) {
+			continue
+		}
 
 		// Default collider size
 		colW, colH := 0.8, 0.9
@@ -327,17 +599,19 @@ func (w *World) runCollisionSystem() {
 			vel.Y = 0
 		}
 
-		// Wall collision (left)
+		// Wall collision (left). Walls only push against the direction of
+		// travel: moving right into a platform corner, the left edge can
+		// be inside the same tile, and pushing right would go through it.
 		wallTileX := int(pos.X - colW/2)
 		wallTileY := int(pos.Y + colH/2)
-		if w.TileMap.IsSolid(wallTileX, wallTileY) {
+		if vel.X <= 0 && w.TileMap.IsSolid(wallTileX, wallTileY) {
 			pos.X = float64(wallTileX+1) + colW/2
 			vel.X = 0
 		}
 
 		// Wall collision (right)
 		wallTileX = int(pos.X + colW/2)
-		if w.TileMap.IsSolid(wallTileX, wallTileY) {
+		if vel.X >= 0 && w.TileMap.IsSolid(wallTileX, wallTileY) {
 			pos.X = float64(wallTileX) - colW/2
 			vel.X = 0
 		}
@@ -362,25 +636,38 @@ func (w *World) runCollisionSystem() {
 
 // SpawnPlayer creates a player entity
 func (w *World) SpawnPlayer(id int, name string, x, y float64) ecs.Entity {
+	return w.spawnPlayer(0, id, name, x, y)
+}
+
+// spawnPlayer creates a player entity with the given network ID (0 = new)
+func (w *World) spawnPlayer(netID protocol.EntityID, id int, name string, x, y float64) ecs.Entity {
+	color, ok := w.playerColors[id]
+	if !ok {
+		color = 0x00FF00
+	}
 	entity := w.playerMapper.NewEntity(
 		&Position{X: x, Y: y},
 		&Velocity{X: 0, Y: 0},
 		&Collider{Width: 0.8, Height: 0.9},
-		&Sprite{ID: "player", Color: 0x00FF00},
+		&Sprite{ID: SpritePlayer, Color: color},
 		&Player{ID: id, Name: name},
-		&Health{Current: 3, Max: 3},
+		&Health{Current: w.Difficulty.playerHealth(), Max: w.Difficulty.playerHealth()},
 		&Gravity{Scale: 1.0},
 		&Grounded{OnGround: false},
 		&Controller{Intents: protocol.IntentNone},
 	)
 	// Add attack state component
 	w.attackMapper.Add(entity, &AttackState{FacingRight: true})
+	w.ledgeMapper.Add(entity, &Ledge{})
+	w.warpMapper.Add(entity, &Warp{})
+	w.bindNetID(entity, netID)
+	w.changeStats(id, func(ps *protocol.PlayerStats) { ps.Name = name })
 	return entity
 }
 
 // SpawnEnemy creates an enemy entity
 func (w *World) SpawnEnemy(enemyType string, x, y float64) ecs.Entity {
-	spriteID := enemyType // Use enemy type as sprite ID
+	spriteID := InternSprite(enemyType) // Use enemy type as sprite ID
 	color := uint32(0xFF0000)
 
 	switch enemyType {
@@ -388,80 +675,149 @@ func (w *World) SpawnEnemy(enemyType string, x, y float64) ecs.Entity {
 		color = 0x00FF00
 	case "bat":
 		color = 0x800080
+	case EnemySpikySlime:
+		color = 0x40A040
+	case EnemyShieldSlime:
+		color = 0x008060
+	case EnemyDiveBat:
+		color = 0xA000A0
+	case EnemyDummy:
+		color = 0xC08040
 	default:
-		spriteID = "enemy"
+		spriteID = SpriteEnemy
 	}
 
-	return w.enemyMapper.NewEntity(
+	return w.spawnEnemy(0, enemyType, Sprite{ID: spriteID, Color: color}, x, y)
+}
+
+// spawnEnemy creates an enemy entity with the given network ID (0 = new)
+// and its type's behavior
+func (w *World) spawnEnemy(netID protocol.EntityID, enemyType string, sprite Sprite, x, y float64) ecs.Entity {
+	entity := w.enemyMapper.NewEntity(
 		&Position{X: x, Y: y},
 		&Velocity{X: 0, Y: 0},
 		&Collider{Width: 0.8, Height: 0.8},
-		&Sprite{ID: spriteID, Color: color},
-		&Health{Current: 1, Max: 1},
+		&sprite,
+		&Health{Current: w.Difficulty.enemyHealth(), Max: w.Difficulty.enemyHealth()},
 		&Gravity{Scale: 1.0},
 		&Grounded{OnGround: false},
 	)
+	w.addEnemyBehavior(entity, enemyType, x, y)
+	w.bindNetID(entity, netID)
+	return entity
 }
 
-// SetPlayerIntent sets the input intent for all players
+// SetPlayerIntent sets the input intent of the player with the given ID
 func (w *World) SetPlayerIntent(playerID int, intents protocol.Intent) {
-	query := w.controlFilter.Query()
+	// Only player archetypes are visited, not every controlled entity
+	query := w.playerFilter.Query()
 	for query.Next() {
-		_, _, ctrl := query.Get()
-		ctrl.Intents = intents
+		if _, player := query.Get(); player.ID == playerID && w.controlMap.HasAll(query.Entity()) {
+			w.controlMap.Get(query.Entity()).Intents = intents
+		}
 	}
 }
 
 // Renderable represents an entity that can be drawn
 type Renderable struct {
 	X, Y     float64
-	SpriteID string
+	SpriteID SpriteID
 	Color    uint32 // Color hint (renderers may use their atlas colors instead)
 	FlipX    bool   // Flip sprite horizontally (facing left)
+	PlayerID int    // Players only, 0 otherwise
+	Name     string // Player name, for name tags
+	Ghost    bool   // Replay ghost: draw translucent, no name tag
+	Spectate bool   // Spectating (idle) player: draw translucent, name tag kept
+
+	ID                protocol.EntityID // Network ID, for per-entity effects (0 for ghosts)
+	Health, MaxHealth int               // Entities with health only, for health bars
 }
 
-// GetRenderables returns all entities with position and sprite for rendering
+// GetRenderables returns all entities with position and sprite for rendering.
+// It allocates a new slice; per-frame callers should use AppendRenderables.
 func (w *World) GetRenderables() []Renderable {
-	var result []Renderable
+	return w.AppendRenderables(nil)
+}
 
+// AppendRenderables appends all entities with position and sprite to dst and
+// returns the extended slice. Pass the previous frame's slice truncated to
+// zero length to render without allocating. Plain entities come first, then
+// fists, then players, so players draw on top.
+func (w *World) AppendRenderables(dst []Renderable) []Renderable {
+	// Entities without a facing direction (enemies, pickups); patrollers
+	// face the way they walk
 	query := w.renderFilter.Query()
 	for query.Next() {
 		pos, sprite := query.Get()
 		entity := query.Entity()
-
-		// Determine facing direction
-		flipX := false
-
-		// Check if entity has AttackState (player) - FacingRight=false means facing left
-		if w.attackMapper.HasAll(entity) {
-			attack := w.attackMapper.Get(entity)
-			flipX = !attack.FacingRight
+		r := Renderable{X: pos.X, Y: pos.Y, SpriteID: sprite.ID, Color: sprite.Color, ID: w.NetIDOf(entity)}
+		if w.patrolMap.HasAll(entity) {
+			r.FlipX = !w.patrolMap.Get(entity).Right
 		}
-
-		// Check if entity is a Fist - FacingRight=false means facing left
-		if w.fistChecker.HasAll(entity) {
-			fist := w.fistChecker.Get(entity)
-			flipX = !fist.FacingRight
+		if w.healthMap.HasAll(entity) {
+			health := w.healthMap.Get(entity)
+			r.Health, r.MaxHealth = health.Current, health.Max
 		}
+		dst = append(dst, r)
+	}
 
-		result = append(result, Renderable{
-			X:        pos.X,
-			Y:        pos.Y,
-			SpriteID: sprite.ID,
-			Color:    sprite.Color,
-			FlipX:    flipX,
+	// Fists face their direction of travel
+	fists := w.fistRenderFilter.Query()
+	for fists.Next() {
+		pos, sprite, fist := fists.Get()
+		dst = append(dst, Renderable{
+			X: pos.X, Y: pos.Y, SpriteID: sprite.ID, Color: sprite.Color,
+			FlipX: !fist.FacingRight,
 		})
 	}
 
-	return result
+	// Players face their last attack/move direction
+	players := w.playerRenderFilter.Query()
+	for players.Next() {
+		pos, sprite, attack, player := players.Get()
+		dst = append(dst, Renderable{
+			X: pos.X, Y: pos.Y, SpriteID: sprite.ID, Color: sprite.Color,
+			FlipX: !attack.FacingRight, PlayerID: player.ID, Name: player.Name,
+			Spectate: w.spectating[player.ID], ID: w.NetIDOf(players.Entity()),
+		})
+	}
+
+	return dst
+}
+
+// SetPlayerColor assigns a player's color (0xRRGGBB). It applies to the
+// live player and to every later respawn.
+func (w *World) SetPlayerColor(playerID int, color uint32) {
+	w.playerColors[playerID] = color
+	query := w.playerFilter.Query()
+	for query.Next() {
+		if _, player := query.Get(); player.ID == playerID {
+			entity := query.Entity()
+			query.Close()
+			w.spriteMap.Get(entity).Color = color
+			return
+		}
+	}
+}
+
+// Players returns the live players, in player ID order
+func (w *World) Players() []Player {
+	var players []Player
+	query := w.playerFilter.Query()
+	for query.Next() {
+		_, player := query.Get()
+		players = append(players, *player)
+	}
+	sort.Slice(players, func(i, j int) bool { return players[i].ID < players[j].ID })
+	return players
 }
 
 // GetPlayerPosition returns the first player's position
 func (w *World) GetPlayerPosition() (float64, float64, bool) {
 	query := w.playerFilter.Query()
-	defer query.Close()
 	if query.Next() {
 		pos, _ := query.Get()
+		query.Close() // Only when breaking off; an exhausted query closes itself
 		return pos.X, pos.Y, true
 	}
 	return 0, 0, false
@@ -0,0 +1,211 @@
+package game
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// benchWorld returns a world with one player, n enemies and a flying fist
+func benchWorld(n int) *World {
+	world := NewWorld()
+	world.SpawnPlayer(1, "Test", 10, 10)
+	for i := 0; i < n; i++ {
+		world.SpawnEnemy("slime", float64(i%100), float64(i/100))
+	}
+	world.SpawnFist(10, 10, false, MaxFistDistance, 1)
+	return world
+}
+
+// TestAppendRenderablesNoAlloc tests that a reused buffer renders without
+// allocating, and that facing is reported for players and fists.
+func TestAppendRenderablesNoAlloc(t *testing.T) {
+	world := benchWorld(1000)
+
+	buf := world.AppendRenderables(nil)
+	if len(buf) != 1002 {
+		t.Fatalf("Expected 1002 renderables, got %d", len(buf))
+	}
+
+	var flipped int
+	for _, r := range buf {
+		if r.FlipX {
+			flipped++
+		}
+	}
+	if flipped != 1 {
+		t.Errorf("Expected only the left-facing fist flipped, got %d", flipped)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = world.AppendRenderables(buf[:0])
+	})
+	if allocs != 0 {
+		t.Errorf("Expected 0 allocations with a reused buffer, got %v", allocs)
+	}
+}
+
+func BenchmarkGetRenderables(b *testing.B) {
+	world := benchWorld(1000)
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = world.GetRenderables()
+	}
+}
+
+func BenchmarkAppendRenderables(b *testing.B) {
+	world := benchWorld(1000)
+	var buf []Renderable
+	b.ReportAllocs()
+	for b.Loop() {
+		buf = world.AppendRenderables(buf[:0])
+	}
+}
+
+// updateWorld returns the demo level with players attacking and walking
+// through 1000 enemies
+func updateWorld(players int) *World {
+	world := NewWorld()
+	world.LoadLevel(NewDemoLevel(200, 45))
+	for i := range players {
+		world.SpawnPlayer(i+1, "Test", float64(5+i%150), 5)
+	}
+	for i := range 1000 {
+		world.SpawnEnemy("slime", float64(5+i%190), float64(2+i/190*4))
+	}
+	return world
+}
+
+func BenchmarkUpdate(b *testing.B) {
+	for _, players := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("players=%d", players), func(b *testing.B) {
+			world := updateWorld(players)
+			b.ReportAllocs()
+			tick := 0
+			for b.Loop() {
+				// Walk and punch in bursts so fists keep spawning
+				intents := protocol.IntentRight
+				if tick%20 < 10 {
+					intents |= protocol.IntentAttack
+				}
+				for id := 1; id <= players; id++ {
+					world.SetPlayerIntent(id, intents)
+				}
+				world.Update()
+				tick++
+			}
+		})
+	}
+}
+
+// BenchmarkCombat has every player tap attack as fast as the cooldown
+// allows, so short fists are thrown and consumed every few ticks
+func BenchmarkCombat(b *testing.B) {
+	for _, players := range []int{10, 100} {
+		b.Run(fmt.Sprintf("players=%d", players), func(b *testing.B) {
+			world := updateWorld(players)
+			b.ReportAllocs()
+			tick := 0
+			for b.Loop() {
+				intents := protocol.IntentRight
+				if tick%(AttackCooldown+1) == 0 {
+					intents |= protocol.IntentAttack
+				}
+				for id := 1; id <= players; id++ {
+					world.SetPlayerIntent(id, intents)
+				}
+				world.Update()
+				tick++
+			}
+		})
+	}
+}
+
+// BenchmarkFistChurn throws 100 short fists a tick into an empty level, the
+// spawn and despawn cost of a crowded fight without the rest of the world
+func BenchmarkFistChurn(b *testing.B) {
+	world := NewWorld()
+	b.ReportAllocs()
+	for b.Loop() {
+		for i := range 100 {
+			world.SpawnFist(float64(i), 5, i%2 == 0, MinFistDistance, 1)
+		}
+		world.Update()
+	}
+}
+
+// TestWorldReset tests that a reset reloads the level, or switches to a new
+// one, with every player respawned in ID order, dead ones included, and the
+// level's enemies, stats and flags back to their start.
+func TestWorldReset(t *testing.T) {
+	level := func(name string, spawnX float64) *Level {
+		return &Level{
+			Name:         name,
+			TileMap:      DemoLevel(),
+			PlayerSpawns: []SpawnPoint{{X: spawnX, Y: 10}, {X: spawnX + 5, Y: 10}, {X: spawnX + 10, Y: 10}},
+			Enemies:      []EnemySpawn{{Type: "slime", X: 30, Y: 10}},
+		}
+	}
+	tests := []struct {
+		name  string
+		level *Level // Passed to Reset
+		want  string // Level name after the reset
+		x     float64
+	}{
+		{"same level", nil, "first", 5},
+		{"new level", level("second", 15), "second", 15},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			world := NewWorld()
+			world.LoadLevel(level("first", 5))
+			world.SpawnPlayer(3, "Three", 20, 10)
+			world.SpawnPlayer(1, "One", 25, 10)
+			dead := world.SpawnPlayer(2, "Two", 30, 10)
+			world.CollectOrb(2, 5)
+			world.removeEntity(dead)
+			world.SpawnEnemy("bat", 12, 5)
+			world.SetFlag("door", 1)
+			for range 10 {
+				world.Update()
+			}
+			tick := world.Tick
+
+			var events []Event
+			world.Subscribe(func(e Event) { events = append(events, e) })
+			world.Reset(tc.level)
+
+			if world.Level().Name != tc.want || world.Tick != tick {
+				t.Errorf("Reset to %q at tick %d, want %q at %d", world.Level().Name, world.Tick, tc.want, tick)
+			}
+			if len(events) != 1 || events[0].Type != EventReset || events[0].Level != tc.want {
+				t.Errorf("Events = %+v, want one reset of %q", events, tc.want)
+			}
+			for i, id := range []int{1, 2, 3} {
+				x, _, ok := world.PlayerPosition(id)
+				if !ok || x != tc.x+float64(5*i) {
+					t.Errorf("Player %d at x %v (alive %v), want spawn point %d", id, x, ok, i)
+				}
+			}
+			if len(world.EntitiesWithSprite("bat")) != 0 || len(world.EntitiesWithSprite("slime")) != 1 {
+				t.Error("Enemies should be the level's own after a reset")
+			}
+			if world.Flag("door") != 0 {
+				t.Error("Flags should be cleared by a reset")
+			}
+			for _, ps := range world.Stats() {
+				if ps.Orbs != 0 {
+					t.Errorf("Stats should restart with the level, got %+v", ps)
+				}
+			}
+		})
+	}
+
+	empty := NewWorld()
+	empty.SpawnPlayer(1, "One", 7, 7)
+	empty.Reset(nil)
+	if x, _, ok := empty.PlayerPosition(1); !ok || x != 7 {
+		t.Error("Reset without a level should do nothing")
+	}
+}
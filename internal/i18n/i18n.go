@@ -0,0 +1,141 @@
+// Package i18n translates the text players read: the HUD, menus, results
+// screens and tutorial hints. Messages are looked up by key in a catalog per
+// language, embedded from catalogs/<lang>.json, and fall back to English.
+// Debug overlays and server logs stay English.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+//go:embed catalogs/*.json
+var catalogFS embed.FS
+
+// DefaultLang is the language every other catalog falls back to
+const DefaultLang = "en"
+
+// Catalog holds one language's messages. A nil *Catalog is English, so
+// zero-value views (Scoreboard{}, Browser{}) need no catalog set.
+type Catalog struct {
+	lang     string
+	messages map[string]string
+	fallback *Catalog // English, nil for English itself
+}
+
+// english is the fallback catalog, parsed once
+var english = mustParse(DefaultLang)
+
+// Languages returns the codes of the embedded catalogs, sorted
+func Languages() []string {
+	entries, _ := catalogFS.ReadDir("catalogs")
+	langs := make([]string, 0, len(entries))
+	for _, e := range entries {
+		langs = append(langs, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	slices.Sort(langs)
+	return langs
+}
+
+// Load returns the catalog for a language code such as "nb". An empty code
+// is English.
+func Load(lang string) (*Catalog, error) {
+	if lang == "" || lang == DefaultLang {
+		return english, nil
+	}
+	if !slices.Contains(Languages(), lang) {
+		return nil, fmt.Errorf("unknown language %q (have %s)", lang, strings.Join(Languages(), ", "))
+	}
+	c, err := parse(lang)
+	if err != nil {
+		return nil, err
+	}
+	c.fallback = english
+	return c, nil
+}
+
+// FromLocale extracts the language from a POSIX locale such as
+// "nb_NO.UTF-8" ($LANG), or returns "" if there is no catalog for it.
+// Norwegian "no" and Bokmål "nb" both give "nb".
+func FromLocale(locale string) string {
+	lang, _, _ := strings.Cut(locale, ".")
+	lang, _, _ = strings.Cut(lang, "_")
+	lang = strings.ToLower(lang)
+	if lang == "no" {
+		lang = "nb"
+	}
+	if !slices.Contains(Languages(), lang) {
+		return ""
+	}
+	return lang
+}
+
+// Lang returns the catalog's language code
+func (c *Catalog) Lang() string {
+	if c == nil {
+		return DefaultLang
+	}
+	return c.lang
+}
+
+// T returns the message for key formatted with args as by fmt.Sprintf.
+// Keys missing from the catalog come from English; keys missing there too
+// are returned as is, so a typo shows up on screen rather than as blank.
+func (c *Catalog) T(key string, args ...any) string {
+	msg, ok := c.lookup(key)
+	if !ok {
+		msg = key
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// Or returns the message for key, or text if no catalog has one. Level
+// data such as hint text carries its own English, which translations
+// override by key.
+func (c *Catalog) Or(key, text string) string {
+	if msg, ok := c.lookup(key); ok {
+		return msg
+	}
+	return text
+}
+
+// lookup finds key in the catalog or its fallback
+func (c *Catalog) lookup(key string) (string, bool) {
+	if c == nil {
+		c = english
+	}
+	for ; c != nil; c = c.fallback {
+		if msg, ok := c.messages[key]; ok {
+			return msg, true
+		}
+	}
+	return "", false
+}
+
+// parse reads an embedded catalog
+func parse(lang string) (*Catalog, error) {
+	data, err := catalogFS.ReadFile("catalogs/" + lang + ".json")
+	if err != nil {
+		return nil, err
+	}
+	c := &Catalog{lang: lang}
+	if err := json.Unmarshal(data, &c.messages); err != nil {
+		return nil, fmt.Errorf("catalog %s: %w", lang, err)
+	}
+	return c, nil
+}
+
+// mustParse parses a catalog that ships with the binary
+func mustParse(lang string) *Catalog {
+	c, err := parse(lang)
+	if err != nil {
+		panic(err) // The embedded English catalog is broken: a build error
+	}
+	return c
+}
@@ -0,0 +1,106 @@
+package i18n
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+// verbs matches fmt verbs, which translations must keep in order
+var verbs = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z]`)
+
+// TestCatalogsComplete tests that every catalog translates every English
+// message, with the same format verbs in the same order.
+func TestCatalogsComplete(t *testing.T) {
+	if !slices.Equal(Languages(), []string{"de", "en", "nb"}) {
+		t.Fatalf("Unexpected catalogs %v", Languages())
+	}
+	for _, lang := range Languages() {
+		c, err := Load(lang)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for key, msg := range english.messages {
+			got, ok := c.messages[key]
+			if !ok {
+				t.Errorf("%s: missing %s", lang, key)
+				continue
+			}
+			if want := verbs.FindAllString(msg, -1); !slices.Equal(verbs.FindAllString(got, -1), want) {
+				t.Errorf("%s: %s should use %v: %q", lang, key, want, got)
+			}
+		}
+		for key := range c.messages {
+			if _, ok := english.messages[key]; !ok {
+				t.Errorf("%s: %s isn't an English key", lang, key)
+			}
+		}
+	}
+}
+
+// TestLookup tests formatting, the English fallback and nil catalogs.
+func TestLookup(t *testing.T) {
+	nb, err := Load("nb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nb.T("scoreboard.unnamed", 3); got != "Spiller 3" {
+		t.Errorf("Got %q", got)
+	}
+	delete(nb.messages, "menu.quit")
+	if got := nb.T("menu.quit"); got != "Quit" {
+		t.Errorf("Missing messages should come from English, got %q", got)
+	}
+	if got := nb.T("no.such.key"); got != "no.such.key" {
+		t.Errorf("Unknown keys should show as is, got %q", got)
+	}
+	if got := nb.Or("hint.custom", "Level text"); got != "Level text" {
+		t.Errorf("Or should fall back to the given text, got %q", got)
+	}
+
+	var none *Catalog
+	if none.T("menu.paused") != "Paused" || none.Lang() != "en" {
+		t.Error("A nil catalog should be English")
+	}
+	if _, err := Load("xx"); err == nil {
+		t.Error("Unknown languages should be rejected")
+	}
+}
+
+// TestFromLocale tests picking a catalog from $LANG.
+func TestFromLocale(t *testing.T) {
+	for locale, want := range map[string]string{
+		"nb_NO.UTF-8": "nb",
+		"no_NO":       "nb",
+		"de_DE.UTF-8": "de",
+		"en_US.UTF-8": "en",
+		"C":           "",
+		"fr_FR.UTF-8": "",
+		"":            "",
+	} {
+		if got := FromLocale(locale); got != want {
+			t.Errorf("%q: got %q, want %q", locale, got, want)
+		}
+	}
+}
+
+// TestWidth tests counting terminal cells rather than bytes or runes.
+func TestWidth(t *testing.T) {
+	for s, want := range map[string]int{
+		"Spieler": 7,
+		"Käfige":  6,
+		"Døde":    4,
+		"日本語":     6,
+		"e\u0301": 1, // e and a combining acute
+	} {
+		if got := Width(s); got != want {
+			t.Errorf("Width(%q) = %d, want %d", s, got, want)
+		}
+	}
+	if got := PadRight("Døde", 6); got != "Døde  " {
+		t.Errorf("PadRight gave %q", got)
+	}
+	if got := PadLeft("日本語", 5); got != " 日本" {
+		t.Errorf("PadLeft should truncate before a wide rune would overflow, got %q", got)
+	}
+}
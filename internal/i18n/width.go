@@ -0,0 +1,63 @@
+package i18n
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/width"
+)
+
+// Terminal cells are counted per rune, not per byte: "ø" is two bytes but
+// one cell, and East Asian wide and fullwidth runes ("日") take two cells.
+// Cell renderers must lay out translated text with these rather than len
+// or fmt's %-Ns, which counts runes.
+
+// RuneWidth returns how many terminal cells a rune takes: 0 for combining
+// marks and control characters, 2 for wide runes, 1 otherwise
+func RuneWidth(r rune) int {
+	if unicode.Is(unicode.Mn, r) || unicode.IsControl(r) {
+		return 0
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
+
+// Width returns how many terminal cells s takes
+func Width(s string) int {
+	n := 0
+	for _, r := range s {
+		n += RuneWidth(r)
+	}
+	return n
+}
+
+// Truncate cuts s to at most cells terminal cells, never splitting a wide
+// rune
+func Truncate(s string, cells int) string {
+	n := 0
+	for i, r := range s {
+		w := RuneWidth(r)
+		if n+w > cells {
+			return s[:i]
+		}
+		n += w
+	}
+	return s
+}
+
+// PadRight left-aligns s in a column of cells terminal cells, truncating it
+// if it is wider
+func PadRight(s string, cells int) string {
+	s = Truncate(s, cells)
+	return s + strings.Repeat(" ", cells-Width(s))
+}
+
+// PadLeft right-aligns s in a column of cells terminal cells, truncating it
+// if it is wider
+func PadLeft(s string, cells int) string {
+	s = Truncate(s, cells)
+	return strings.Repeat(" ", cells-Width(s)) + s
+}
@@ -3,6 +3,8 @@
 package input
 
 import (
+	"runtime"
+
 	"gioui.org/io/key"
 )
 
@@ -15,6 +17,8 @@ type GioInput struct {
 	events    []KeyEvent
 }
 
+var _ System = (*GioInput)(nil)
+
 // NewGioInput creates a new Gio input system.
 func NewGioInput() *GioInput {
 	return &GioInput{}
@@ -51,6 +55,11 @@ func (g *GioInput) Poll() []KeyEvent {
 	return events
 }
 
+// Pending reports whether key events are waiting to be polled.
+func (g *GioInput) Pending() bool {
+	return len(g.events) > 0
+}
+
 // ShouldQuit returns true if quit was requested.
 func (g *GioInput) ShouldQuit() bool {
 	return g.quitFlag
@@ -69,9 +78,68 @@ func gioKeyToGameKey(name key.Name) GameKey {
 		return KeyAttack
 	case "K":
 		return KeyUse
-	case key.NameEscape, "Q":
+	case "L":
+		return KeyGlide
+	case key.NameDownArrow, "S":
+		return KeyCrouch
+	case key.NameEscape:
+		return KeyPause
+	case "Q":
 		return KeyQuit
-	default:
-		return KeyCount // Invalid
+	case key.NameTab:
+		return KeyScoreboard
+	case "R":
+		return KeyRestart
+	case "C":
+		return KeyColorMode
+	case "B":
+		return KeyLeaderboards
+	case "H":
+		return KeyHostMenu
+	case "M":
+		return KeyWorldMap
+	case "T":
+		return KeyTelemetry
+	case key.NameF1:
+		return KeyVoteYes
+	case key.NameF2:
+		return KeyVoteNo
+	case key.NameF4:
+		return KeyNetGraph
+	case key.NameF3:
+		return KeyDebugOverlay
+	case key.NamePageUp:
+		return KeyLogOlder
+	case key.NamePageDown:
+		return KeyLogNewer
+	case key.NameF5:
+		return KeyDebugPause
+	case key.NameF6:
+		return KeyDebugStep
+	case key.NameF7:
+		return KeyDebugSlower
+	case key.NameF8:
+		return KeyDebugFaster
+	case key.NameF9:
+		return KeyHitboxes
+	case "`", "~":
+		return KeyConsole
 	}
+	if gk, ok := browserKeys[name]; ok && runtime.GOOS == "js" {
+		return gk
+	}
+	return KeyCount // Invalid
+}
+
+// browserKeys stand in for the function keys browsers keep for themselves
+// (F3 find, F5 reload, F6 address bar, F7 caret browsing) in the js/wasm
+// build. F4, F8 and F9 get digits too, so the debug keys stay in one row.
+var browserKeys = map[key.Name]GameKey{
+	"3": KeyDebugOverlay,
+	"4": KeyNetGraph,
+	"5": KeyDebugPause,
+	"6": KeyDebugStep,
+	"7": KeyDebugSlower,
+	"8": KeyDebugFaster,
+	"9": KeyHitboxes,
 }
@@ -13,7 +13,34 @@ const (
 	KeyJump
 	KeyAttack
 	KeyUse
+	KeyCrouch
+	KeyGlide // Helicopter hair, held in the air
 	KeyQuit
+
+	// UI keys (never sent as intents)
+	KeyPause        // Open or close the pause menu
+	KeyScoreboard   // Held to show the scoreboard
+	KeyRestart      // Play again from the results screen
+	KeyColorMode    // Cycle color vision modes in the pause menu
+	KeyLeaderboards // Open the leaderboard browser from the pause menu
+	KeyHostMenu     // Open the host menu from the pause menu
+	KeyVoteYes      // Vote yes on the players' vote
+	KeyVoteNo       // Vote no
+	KeyWorldMap     // Open the campaign's world map from the pause menu or results screen
+	KeyTelemetry    // Opt in or out of anonymous telemetry in the pause menu
+
+	// Debug time controls (single-player only, never sent as intents)
+	KeyDebugPause
+	KeyDebugStep
+	KeyDebugSlower
+	KeyDebugFaster
+	KeyDebugOverlay
+	KeyNetGraph
+	KeyLogOlder // Scroll the debug overlay's combat log back
+	KeyLogNewer // Scroll the combat log forward
+	KeyConsole  // Open the developer console (GUI -dev mode)
+	KeyHitboxes // Toggle the collision overlay
+
 	KeyCount // Sentinel for array sizing
 )
 
@@ -75,6 +102,15 @@ func (s *KeyState) ToIntents() protocol.Intent {
 	if s.pressed[KeyUse] {
 		intents |= protocol.IntentUse
 	}
+	if s.pressed[KeyGlide] {
+		intents |= protocol.IntentGlide
+	}
+	if s.pressed[KeyCrouch] {
+		intents |= protocol.IntentDown
+		if s.pressed[KeyAttack] {
+			intents |= protocol.IntentPound // Down+attack
+		}
+	}
 	return intents
 }
 
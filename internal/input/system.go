@@ -0,0 +1,55 @@
+package input
+
+// System is a source of key events. Each backend implements it (GioInput
+// for the GUI), so client code doesn't depend on a windowing or terminal
+// library.
+type System interface {
+	// Poll returns the key events since the last call
+	Poll() []KeyEvent
+	// ShouldQuit reports whether the user asked to quit, e.g. by closing
+	// the window
+	ShouldQuit() bool
+}
+
+// Queue is a System fed by calls instead of a device, for tests and
+// scripted input
+type Queue struct {
+	events []KeyEvent
+	quit   bool
+}
+
+// NewQueue creates an empty input queue
+func NewQueue() *Queue {
+	return &Queue{}
+}
+
+// Press queues key down events
+func (q *Queue) Press(keys ...GameKey) {
+	for _, k := range keys {
+		q.events = append(q.events, KeyEvent{Type: KeyDown, Key: k})
+	}
+}
+
+// Release queues key up events
+func (q *Queue) Release(keys ...GameKey) {
+	for _, k := range keys {
+		q.events = append(q.events, KeyEvent{Type: KeyUp, Key: k})
+	}
+}
+
+// Quit makes ShouldQuit report true
+func (q *Queue) Quit() {
+	q.quit = true
+}
+
+// Poll returns the queued events.
+func (q *Queue) Poll() []KeyEvent {
+	events := q.events
+	q.events = nil
+	return events
+}
+
+// ShouldQuit returns true once Quit was called.
+func (q *Queue) ShouldQuit() bool {
+	return q.quit
+}
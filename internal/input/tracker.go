@@ -0,0 +1,90 @@
+package input
+
+import (
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// TerminalHold is how many ticks a terminal key stays held after a press
+// with no key-up: long enough to bridge a typical 500 ms auto-repeat delay,
+// so holding attack charges a fist. A tap therefore holds keys this long.
+const TerminalHold = 36
+
+// IntentTracker turns key events into the intents held each tick, the same
+// way for every frontend. By default a key is held from key-down to key-up,
+// as Gio reports them. Terminals mostly report presses only; a key with a
+// hold window (SetHold) is released that many ticks after its last press
+// instead, unless a key-up comes first.
+type IntentTracker struct {
+	keys KeyState
+	hold [KeyCount]int // Hold window per key in ticks, 0 until key-up
+	left [KeyCount]int // Ticks left of each held key's window
+}
+
+// NewIntentTracker creates a tracker that holds keys until key-up
+func NewIntentTracker() *IntentTracker {
+	return &IntentTracker{}
+}
+
+// NewTerminalTracker creates a tracker that holds every key for
+// TerminalHold ticks after its last press
+func NewTerminalTracker() *IntentTracker {
+	t := NewIntentTracker()
+	for k := range KeyCount {
+		t.SetHold(k, TerminalHold)
+	}
+	return t
+}
+
+// SetHold sets a key's hold window in ticks; 0 holds it until key-up
+func (t *IntentTracker) SetHold(key GameKey, ticks int) {
+	if key < KeyCount {
+		t.hold[key] = ticks
+	}
+}
+
+// Process applies key events. A repeated press restarts the key's window.
+func (t *IntentTracker) Process(events []KeyEvent) {
+	for _, ev := range events {
+		if ev.Key >= KeyCount {
+			continue
+		}
+		switch ev.Type {
+		case KeyDown:
+			t.keys.SetPressed(ev.Key, true)
+			t.left[ev.Key] = t.hold[ev.Key]
+		case KeyUp:
+			t.keys.SetPressed(ev.Key, false)
+			t.left[ev.Key] = 0
+		}
+	}
+}
+
+// Tick ages the hold windows by one tick, releasing keys whose window ran
+// out. Call it once per simulated tick, after reading Intents.
+func (t *IntentTracker) Tick() {
+	for k := range KeyCount {
+		if t.hold[k] == 0 || t.left[k] == 0 {
+			continue
+		}
+		t.left[k]--
+		if t.left[k] == 0 {
+			t.keys.SetPressed(k, false)
+		}
+	}
+}
+
+// Intents returns the intents of the held keys
+func (t *IntentTracker) Intents() protocol.Intent {
+	return t.keys.ToIntents()
+}
+
+// IsPressed reports whether a key is held
+func (t *IntentTracker) IsPressed(key GameKey) bool {
+	return t.keys.IsPressed(key)
+}
+
+// Reset releases every key
+func (t *IntentTracker) Reset() {
+	t.keys.Reset()
+	t.left = [KeyCount]int{}
+}
@@ -0,0 +1,34 @@
+package input
+
+import (
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// TestIntentTrackerHold tests that keys with a hold window are released
+// after it unless pressed again, and that a key-up ends it early.
+func TestIntentTrackerHold(t *testing.T) {
+	tr := NewIntentTracker()
+	tr.SetHold(KeyRight, 3)
+
+	tr.Process([]KeyEvent{{Type: KeyDown, Key: KeyRight}, {Type: KeyDown, Key: KeyJump}})
+	for tick := range 5 {
+		want := protocol.IntentRight | protocol.IntentJump
+		if tick >= 4 {
+			want = protocol.IntentJump // Window since the repeat over; jump has none
+		}
+		if got := tr.Intents(); got != want {
+			t.Errorf("Tick %d: intents %v, want %v", tick, got, want)
+		}
+		if tick == 1 {
+			tr.Process([]KeyEvent{{Type: KeyDown, Key: KeyRight}}) // Repeat restarts the window
+		}
+		tr.Tick()
+	}
+	tr.Process([]KeyEvent{{Type: KeyDown, Key: KeyRight}})
+	tr.Process([]KeyEvent{{Type: KeyUp, Key: KeyRight}, {Type: KeyUp, Key: KeyJump}})
+	if tr.Intents() != protocol.IntentNone {
+		t.Errorf("Key-up should release at once, got %v", tr.Intents())
+	}
+}
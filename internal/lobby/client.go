@@ -0,0 +1,163 @@
+package lobby
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned by LookupClient when the service doesn't know the
+// room, e.g. because it expired
+var ErrNotFound = errors.New("room not found")
+
+// LookupClient talks to a lookup service
+type LookupClient struct {
+	BaseURL string // e.g. https://lookup.example.com
+	HTTP    *http.Client
+}
+
+// NewLookupClient creates a client for the service at baseURL
+func NewLookupClient(baseURL string) *LookupClient {
+	return &LookupClient{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		HTTP:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Create registers a room. A host of ":port" lets the service fill in the
+// address it sees the request from.
+func (c *LookupClient) Create(req CreateRequest) (*Room, error) {
+	var room Room
+	if err := c.do(http.MethodPost, "/rooms", req, &room); err != nil {
+		return nil, err
+	}
+	return &room, nil
+}
+
+// Lookup finds a room by code
+func (c *LookupClient) Lookup(code string) (*Room, error) {
+	var room Room
+	if err := c.do(http.MethodGet, "/rooms/"+url.PathEscape(code), nil, &room); err != nil {
+		return nil, err
+	}
+	return &room, nil
+}
+
+// List returns one page of public rooms
+func (c *LookupClient) List(f ListFilter) (*RoomList, error) {
+	q := url.Values{}
+	if f.Page > 0 {
+		q.Set("page", strconv.Itoa(f.Page))
+	}
+	if f.PerPage > 0 {
+		q.Set("per_page", strconv.Itoa(f.PerPage))
+	}
+	if f.NotFull {
+		q.Set("not_full", "true")
+	}
+	if f.Region != "" {
+		q.Set("region", f.Region)
+	}
+	var list RoomList
+	if err := c.do(http.MethodGet, "/rooms?"+q.Encode(), nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// Heartbeat keeps a room alive; call it every HeartbeatInterval
+func (c *LookupClient) Heartbeat(code string, players int) error {
+	return c.do(http.MethodPut, "/rooms/"+url.PathEscape(code)+"/heartbeat", HeartbeatRequest{Players: players}, nil)
+}
+
+// Delete removes a room
+func (c *LookupClient) Delete(code string) error {
+	return c.do(http.MethodDelete, "/rooms/"+url.PathEscape(code), nil, nil)
+}
+
+// SubmitScore uploads a run and returns the player's rank on its board
+// (ScoreList.Rank and Total, without scores)
+func (c *LookupClient) SubmitScore(req ScoreRequest) (*ScoreList, error) {
+	var list ScoreList
+	if err := c.do(http.MethodPost, "/scores", req, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// Scores returns the top of a leaderboard: a day's daily challenge board
+// if q.Date is set, otherwise a level's board for q.Mode
+func (c *LookupClient) Scores(q ScoreQuery) (*ScoreList, error) {
+	v := url.Values{}
+	v.Set("level", q.Level)
+	path := "/scores"
+	if q.Date != "" {
+		path += "/" + url.PathEscape(q.Date)
+	} else if q.Mode != "" {
+		v.Set("mode", q.Mode)
+	}
+	if q.Sort != "" {
+		v.Set("sort", q.Sort)
+	}
+	if q.Limit > 0 {
+		v.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Name != "" {
+		v.Set("name", q.Name)
+	}
+	var list ScoreList
+	if err := c.do(http.MethodGet, path+"?"+v.Encode(), nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// Version returns the latest release the service knows of
+func (c *LookupClient) Version() (*VersionInfo, error) {
+	var info VersionInfo
+	if err := c.do(http.MethodGet, "/version", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (c *LookupClient) do(method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.BaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("lookup %s %s: %w", method, path, ErrNotFound)
+		}
+		return fmt.Errorf("lookup %s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package lobby
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+)
+
+// roomKeyLen is the length of a room key: 130 bits, so the key is as strong
+// as the AES keys derived from it, not just against online guessing
+const roomKeyLen = 26
+
+// NewRoomKey returns a random key for a private room, from the room code
+// alphabet. Unlike codes it comes from crypto/rand.
+func NewRoomKey() string {
+	key := make([]byte, roomKeyLen)
+	rand.Read(key)
+	for i, b := range key {
+		key[i] = codeCharset[int(b)%len(codeCharset)] // 32 divides 256: no bias
+	}
+	return string(key)
+}
+
+// FormatInvite joins a room code and key into the string a host shares,
+// e.g. "ABCD-1234-K7MPQ2XZR4WTHNC8VJ6YDLEBG3". An empty key gives the plain code.
+func FormatInvite(code, key string) string {
+	if key == "" {
+		return code
+	}
+	return code + "-" + key
+}
+
+// ParseInvite splits an invite into the room code, which is looked up, and
+// the room key, which never leaves the players' machines. Input is
+// case-insensitive; the key is empty for open rooms.
+func ParseInvite(invite string) (code, key string, err error) {
+	parts := strings.Split(strings.ToUpper(strings.TrimSpace(invite)), "-")
+	if len(parts) < 2 || len(parts) > 3 || len(parts[0]) != 4 || len(parts[1]) != 4 {
+		return "", "", fmt.Errorf("invalid invite: %q", invite)
+	}
+	if len(parts) == 3 {
+		key = parts[2]
+		if len(key) != roomKeyLen {
+			return "", "", fmt.Errorf("invalid room key in invite: %q", invite)
+		}
+	}
+	for _, p := range parts {
+		if strings.Trim(p, codeCharset) != "" {
+			return "", "", fmt.Errorf("invalid character in invite: %q", invite)
+		}
+	}
+	return parts[0] + "-" + parts[1], key, nil
+}
+
+// KeyPSK turns a room key into the pre-shared key for
+// network.SecureClient and SecureServer, or nil for an open room
+func KeyPSK(key string) []byte {
+	if key == "" {
+		return nil
+	}
+	sum := sha256.Sum256([]byte("rayman-slides room key " + key))
+	return sum[:]
+}
@@ -0,0 +1,49 @@
+package lobby
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestInvite tests that invites with a fresh room key survive formatting and
+// parsing, and that malformed ones are rejected.
+func TestInvite(t *testing.T) {
+	key := NewRoomKey()
+	if len(key) != roomKeyLen || strings.Trim(key, codeCharset) != "" {
+		t.Fatalf("NewRoomKey = %q, want %d characters from the code alphabet", key, roomKeyLen)
+	}
+	if NewRoomKey() == key {
+		t.Error("NewRoomKey repeated a key")
+	}
+
+	tests := []struct {
+		invite  string
+		code    string
+		key     string
+		wantErr bool
+	}{
+		{invite: FormatInvite("ABCD-2345", key), code: "ABCD-2345", key: key},
+		{invite: " abcd-2345-" + strings.ToLower(key) + "\n", code: "ABCD-2345", key: key},
+		{invite: FormatInvite("ABCD-2345", ""), code: "ABCD-2345"},
+		{invite: "ABCD-2345-K7MPQ2XZ", wantErr: true}, // Too short a key
+		{invite: "ABCD-2345-" + key + "A", wantErr: true},
+		{invite: "ABCD-2345-" + key[:25] + "0", wantErr: true},
+		{invite: "ABCD", wantErr: true},
+		{invite: "ABCD-2345-" + key + "-ABCD", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.invite, func(t *testing.T) {
+			code, key, err := ParseInvite(tc.invite)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseInvite error = %v, want error %v", err, tc.wantErr)
+			}
+			if code != tc.code || key != tc.key {
+				t.Errorf("ParseInvite = %q, %q, want %q, %q", code, key, tc.code, tc.key)
+			}
+		})
+	}
+
+	if KeyPSK("") != nil || len(KeyPSK(key)) != 32 {
+		t.Errorf("KeyPSK = %x for an open room and %d bytes for a key, want nil and 32", KeyPSK(""), len(KeyPSK(key)))
+	}
+}
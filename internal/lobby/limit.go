@@ -0,0 +1,124 @@
+package lobby
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket per key (client IP): rate tokens per minute,
+// up to burst saved up
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64 // Tokens per second
+	burst   float64
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(perMinute, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// allow takes a token for key, reporting false and how long until the next
+// one if there is none. A zero rate allows everything.
+func (l *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	if l.rate == 0 {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*l.rate, l.burst)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// sweep forgets keys whose bucket has refilled
+func (l *rateLimiter) sweep(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// backoff blocks keys (client IPs) that keep looking up codes that don't
+// exist: after free failures, each further failure doubles the time the key
+// must wait, up to max. Failures are forgotten after max without one, not
+// on success, so guessing between valid lookups doesn't help.
+type backoff struct {
+	mu       sync.Mutex
+	free     int
+	max      time.Duration
+	failures map[string]*failures
+}
+
+type failures struct {
+	count int
+	last  time.Time
+	until time.Time // Blocked until
+}
+
+func newBackoff(free int, max time.Duration) *backoff {
+	return &backoff{free: free, max: max, failures: make(map[string]*failures)}
+}
+
+// blocked reports whether key must wait, and how long
+func (b *backoff) blocked(key string, now time.Time) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	f, ok := b.failures[key]
+	if !ok || !now.Before(f.until) {
+		return false, 0
+	}
+	return true, f.until.Sub(now)
+}
+
+// fail records a failed lookup by key
+func (b *backoff) fail(key string, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	f, ok := b.failures[key]
+	if !ok || now.Sub(f.last) > b.max {
+		f = &failures{}
+		b.failures[key] = f
+	}
+	f.count++
+	f.last = now
+	if over := f.count - b.free; over > 0 {
+		wait := b.max
+		if over < 32 {
+			wait = min(time.Second<<(over-1), b.max)
+		}
+		f.until = now.Add(wait)
+	}
+}
+
+// sweep forgets keys without a failure for max
+func (b *backoff) sweep(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for key, f := range b.failures {
+		if now.Sub(f.last) > b.max {
+			delete(b.failures, key)
+		}
+	}
+}
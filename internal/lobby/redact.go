@@ -0,0 +1,44 @@
+package lobby
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Stand-ins for what streamer mode hides
+const (
+	HiddenCode = "****-****"
+	HiddenAddr = "[hidden]"
+)
+
+var (
+	// inviteRE matches a room code, or an invite with its key
+	inviteRE = regexp.MustCompile(`\b[` + codeCharset + `]{4}-[` + codeCharset + `]{4}(-[` + codeCharset + `]{` + strconv.Itoa(roomKeyLen) + `})?\b`)
+	// addrRE matches what might be an IP address with an optional port;
+	// candidates are checked with net.ParseIP so times like 12:30:45 stay
+	addrRE = regexp.MustCompile(`\[[0-9A-Fa-f:.]+\](:\d+)?|[0-9A-Fa-f]*:[0-9A-Fa-f:.]*:[0-9A-Fa-f:.]*|\b\d{1,3}(\.\d{1,3}){3}(:\d+)?\b`)
+)
+
+// Redact hides join information in text shown to an audience, for
+// streamer mode: room codes and invites become HiddenCode and IP
+// addresses, with their port, HiddenAddr
+func Redact(s string) string {
+	s = inviteRE.ReplaceAllStringFunc(s, func(code string) string {
+		if !ValidCode(code[:9]) {
+			return code
+		}
+		return HiddenCode
+	})
+	return addrRE.ReplaceAllStringFunc(s, func(addr string) string {
+		host := addr
+		if h, _, err := net.SplitHostPort(addr); err == nil {
+			host = h
+		}
+		if net.ParseIP(strings.Trim(host, "[]")) == nil {
+			return addr
+		}
+		return HiddenAddr
+	})
+}
@@ -0,0 +1,23 @@
+package lobby
+
+import "testing"
+
+// TestRedact tests that codes, invites and addresses are hidden while
+// times and words that only look similar are kept.
+func TestRedact(t *testing.T) {
+	for in, want := range map[string]string{
+		"Room code: ABCD-2345":                        "Room code: ****-****",
+		"Invite ABCD-2345-K7MPQ2XZR4WTHNC8VJ6YDLEBG3": "Invite ****-****",
+		"Game is up at 192.168.1.100:7777":            "Game is up at [hidden]",
+		"from 10.0.0.1, ping 20ms":                    "from [hidden], ping 20ms",
+		"host [2001:db8::1]:7777 reachable":           "host [hidden] reachable",
+		"peer fe80::1 joined":                         "peer [hidden] joined",
+		"Time 0:12.50 | Best 12:30:45":                "Time 0:12.50 | Best 12:30:45",
+		"Not a code: ABCD-1234 or abcd-2345":          "Not a code: ABCD-1234 or abcd-2345",
+		"tick 300.5.2":                                "tick 300.5.2",
+	} {
+		if got := Redact(in); got != want {
+			t.Errorf("Redact(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -2,8 +2,13 @@
 package lobby
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
+	"net"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -14,8 +19,61 @@ type Room struct {
 	Name       string    `json:"name"`
 	Players    int       `json:"players"`
 	MaxPlayers int       `json:"max_players"`
+	Public     bool      `json:"public,omitempty"` // Listed by the server browser
+	Region     string    `json:"region,omitempty"` // Free-form tag, e.g. "eu"
 	CreatedAt  time.Time `json:"created_at"`
 	ExpiresAt  time.Time `json:"expires_at"`
+	LastSeen   time.Time `json:"last_seen,omitzero"` // Last heartbeat, zero before the first
+}
+
+// HeartbeatInterval is how often a host should call Heartbeat
+const HeartbeatInterval = 30 * time.Second
+
+// HeartbeatTimeout expires a room whose host missed three heartbeats
+const HeartbeatTimeout = 3 * HeartbeatInterval
+
+// ErrUnreachable is returned by Lookup when the store's probe can't reach
+// the room's host; the room is removed
+var ErrUnreachable = errors.New("host unreachable")
+
+// DialProbe returns a probe that checks a host accepts TCP connections
+// within timeout
+func DialProbe(timeout time.Duration) func(host string) error {
+	return func(host string) error {
+		_, err := Ping(host, timeout)
+		return err
+	}
+}
+
+// PlayablePing is the round trip below which a room is suggested to players
+const PlayablePing = 100 * time.Millisecond
+
+// EstimateRTT pings a host up to samples times and returns the fastest
+// round trip, which filters out one-off delays; the first failure ends it
+func EstimateRTT(host string, samples int, timeout time.Duration) (time.Duration, error) {
+	var best time.Duration
+	for i := 0; i < samples; i++ {
+		rtt, err := Ping(host, timeout)
+		if err != nil {
+			return 0, err
+		}
+		if i == 0 || rtt < best {
+			best = rtt
+		}
+	}
+	return best, nil
+}
+
+// Ping measures how long a host takes to accept a TCP connection, about one
+// round trip
+func Ping(host string, timeout time.Duration) (time.Duration, error) {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", host, timeout)
+	if err != nil {
+		return 0, err
+	}
+	rtt := time.Since(start)
+	return rtt, conn.Close()
 }
 
 // CodeGenerator generates room codes
@@ -30,24 +88,44 @@ func NewCodeGenerator() *CodeGenerator {
 	}
 }
 
+// codeCharset is the room code alphabet, also used for room keys
+const codeCharset = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789" // No I, O, 0, 1 (ambiguous)
+
 // Generate creates a new room code in format XXXX-XXXX
 func (g *CodeGenerator) Generate() string {
-	const charset = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789" // No I, O, 0, 1 (ambiguous)
 	code := make([]byte, 9)
 	for i := 0; i < 4; i++ {
-		code[i] = charset[g.rng.Intn(len(charset))]
+		code[i] = codeCharset[g.rng.Intn(len(codeCharset))]
 	}
 	code[4] = '-'
 	for i := 5; i < 9; i++ {
-		code[i] = charset[g.rng.Intn(len(charset))]
+		code[i] = codeCharset[g.rng.Intn(len(codeCharset))]
 	}
 	return string(code)
 }
 
-// RoomStore stores active rooms (in-memory implementation)
+// ValidCode reports whether code has the XXXX-XXXX room code format
+func ValidCode(code string) bool {
+	if len(code) != 9 || code[4] != '-' {
+		return false
+	}
+	for i := 0; i < len(code); i++ {
+		if i != 4 && strings.IndexByte(codeCharset, code[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// RoomStore stores active rooms (in-memory implementation). It is safe for
+// concurrent use.
 type RoomStore struct {
+	mu    sync.Mutex
 	rooms map[string]*Room
 	ttl   time.Duration
+
+	// Probe, if set, checks a room's host before Lookup returns it
+	Probe func(host string) error
 }
 
 // NewRoomStore creates a room store
@@ -58,8 +136,22 @@ func NewRoomStore(ttl time.Duration) *RoomStore {
 	}
 }
 
-// Create creates a new room and returns the code
-func (s *RoomStore) Create(host, name string, maxPlayers int) (*Room, error) {
+// Create creates a room from the host, name, player limit and listing
+// fields of room and returns it with its code. A valid room.Code is kept
+// if no live room has it, so a restarted host gets its code back;
+// otherwise a new one is generated. The room lives for the store's TTL, or
+// HeartbeatTimeout past the host's last heartbeat.
+func (s *RoomStore) Create(room Room) (*Room, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if room.Code != "" {
+		if !ValidCode(room.Code) {
+			return nil, fmt.Errorf("invalid room code: %q", room.Code)
+		}
+		if _, err := s.live(room.Code); err != nil {
+			return s.add(room), nil
+		}
+	}
 	gen := NewCodeGenerator()
 	code := gen.Generate()
 
@@ -71,21 +163,101 @@ func (s *RoomStore) Create(host, name string, maxPlayers int) (*Room, error) {
 		code = gen.Generate()
 	}
 
-	room := &Room{
-		Code:       code,
-		Host:       host,
-		Name:       name,
-		Players:    1,
-		MaxPlayers: maxPlayers,
-		CreatedAt:  time.Now(),
-		ExpiresAt:  time.Now().Add(s.ttl),
+	room.Code = code
+	return s.add(room), nil
+}
+
+// add stores a room under its code. s.mu must be held.
+func (s *RoomStore) add(room Room) *Room {
+	now := time.Now()
+	room.Players = 1
+	room.CreatedAt = now
+	room.ExpiresAt = now.Add(s.ttl)
+	room.LastSeen = time.Time{}
+	s.rooms[room.Code] = &room
+	r := room
+	return &r
+}
+
+// ListFilter selects and pages public rooms
+type ListFilter struct {
+	NotFull bool   // Only rooms with a free slot
+	Region  string // Only rooms with this region tag; empty for all
+	Page    int    // 1-based
+	PerPage int
+}
+
+// List returns one page of live public rooms matching the filter, oldest
+// first, and how many match in total
+func (s *RoomStore) List(f ListFilter) ([]Room, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	var matches []Room
+	for _, room := range s.rooms {
+		switch {
+		case !room.Public, now.After(room.ExpiresAt):
+		case f.NotFull && room.MaxPlayers > 0 && room.Players >= room.MaxPlayers:
+		case f.Region != "" && room.Region != f.Region:
+		default:
+			matches = append(matches, *room)
+		}
 	}
-	s.rooms[code] = room
-	return room, nil
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].CreatedAt.Equal(matches[j].CreatedAt) {
+			return matches[i].CreatedAt.Before(matches[j].CreatedAt)
+		}
+		return matches[i].Code < matches[j].Code
+	})
+
+	start := min((max(f.Page, 1)-1)*f.PerPage, len(matches))
+	end := min(start+f.PerPage, len(matches))
+	return matches[start:end], len(matches)
+}
+
+// Heartbeat marks a room's host alive and updates its player count. The
+// room then expires HeartbeatTimeout from now, unless beaten again.
+func (s *RoomStore) Heartbeat(code string, players int) (*Room, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	room, err := s.live(code)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	room.LastSeen = now
+	room.ExpiresAt = now.Add(HeartbeatTimeout)
+	room.Players = players
+	r := *room
+	return &r, nil
 }
 
-// Lookup finds a room by code
+// Lookup finds a room by code. With a Probe set, a room whose host doesn't
+// answer is removed and ErrUnreachable returned.
 func (s *RoomStore) Lookup(code string) (*Room, error) {
+	s.mu.Lock()
+	room, err := s.live(code)
+	var r Room
+	if err == nil {
+		r = *room
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// Probe without the lock; it may wait on the network
+	if s.Probe != nil {
+		if err := s.Probe(r.Host); err != nil {
+			s.Delete(code)
+			return nil, fmt.Errorf("room %s: %w: %v", code, ErrUnreachable, err)
+		}
+	}
+	return &r, nil
+}
+
+// live returns an unexpired room, removing it if expired. s.mu must be held.
+func (s *RoomStore) live(code string) (*Room, error) {
 	room, exists := s.rooms[code]
 	if !exists {
 		return nil, fmt.Errorf("room not found: %s", code)
@@ -99,11 +271,15 @@ func (s *RoomStore) Lookup(code string) (*Room, error) {
 
 // Delete removes a room
 func (s *RoomStore) Delete(code string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	delete(s.rooms, code)
 }
 
 // Cleanup removes expired rooms
 func (s *RoomStore) Cleanup() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	now := time.Now()
 	for code, room := range s.rooms {
 		if now.After(room.ExpiresAt) {
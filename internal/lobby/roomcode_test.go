@@ -0,0 +1,133 @@
+package lobby
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestRoomStoreHeartbeat tests that heartbeats keep a room alive past its
+// TTL and update its players, and that a room whose host went quiet expires
+// and its code can be reclaimed.
+func TestRoomStoreHeartbeat(t *testing.T) {
+	store := NewRoomStore(time.Minute)
+	room, err := store.Create(Room{Host: "10.0.0.1:7777", Name: "Game"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ValidCode(room.Code) || room.Players != 1 || !room.LastSeen.IsZero() {
+		t.Fatalf("Created %+v, want a valid code, 1 player and no heartbeat yet", room)
+	}
+
+	before := time.Now()
+	beat, err := store.Heartbeat(room.Code, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if beat.Players != 3 || beat.LastSeen.Before(before) || beat.ExpiresAt.Before(before.Add(HeartbeatTimeout)) {
+		t.Errorf("After a heartbeat %+v, want 3 players, seen now and %v to live", beat, HeartbeatTimeout)
+	}
+	if got, _ := store.Lookup(room.Code); got == nil || got.Players != 3 {
+		t.Errorf("Lookup = %+v, want the heartbeat's player count", got)
+	}
+
+	// The host misses its heartbeats
+	store.rooms[room.Code].ExpiresAt = time.Now().Add(-time.Second)
+	if _, err := store.Heartbeat(room.Code, 3); err == nil {
+		t.Error("Heartbeat for an expired room should fail")
+	}
+	if _, err := store.Lookup(room.Code); err == nil {
+		t.Error("Lookup of an expired room should fail")
+	}
+	again, err := store.Create(Room{Code: room.Code, Host: "10.0.0.1:7777"})
+	if err != nil || again.Code != room.Code {
+		t.Errorf("Recreated room %+v (%v), want code %s back", again, err, room.Code)
+	}
+	if _, err := store.Create(Room{Code: "bad"}); err == nil {
+		t.Error("Create with a malformed code should fail")
+	}
+	taken, _ := store.Create(Room{Code: room.Code, Host: "10.0.0.2:7777"})
+	if taken == nil || taken.Code == room.Code {
+		t.Errorf("Create with a live room's code got %+v, want a new code", taken)
+	}
+
+	store.rooms[taken.Code].ExpiresAt = time.Now().Add(-time.Second)
+	store.Cleanup()
+	if _, ok := store.rooms[taken.Code]; ok || len(store.rooms) != 1 {
+		t.Errorf("Cleanup left %d rooms, want only the live one", len(store.rooms))
+	}
+}
+
+// TestRoomStoreProbe tests that a lookup drops a room whose host doesn't
+// answer the probe.
+func TestRoomStoreProbe(t *testing.T) {
+	store := NewRoomStore(time.Minute)
+	up, _ := store.Create(Room{Host: "10.0.0.1:7777"})
+	down, _ := store.Create(Room{Host: "10.0.0.2:7777"})
+	store.Probe = func(host string) error {
+		if host == down.Host {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+
+	if _, err := store.Lookup(up.Code); err != nil {
+		t.Errorf("Lookup of a reachable room: %v", err)
+	}
+	if _, err := store.Lookup(down.Code); !errors.Is(err, ErrUnreachable) {
+		t.Errorf("Lookup of an unreachable room: %v, want ErrUnreachable", err)
+	}
+	if _, ok := store.rooms[down.Code]; ok {
+		t.Error("Unreachable room should be removed")
+	}
+}
+
+// TestRoomStoreList tests that only live public rooms are listed, filtered
+// and paged oldest first.
+func TestRoomStoreList(t *testing.T) {
+	store := NewRoomStore(time.Minute)
+	add := func(name, region string, public bool, players, maxPlayers int) string {
+		room, err := store.Create(Room{Host: "10.0.0.1:7777", Name: name, Public: public, Region: region, MaxPlayers: maxPlayers})
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.rooms[room.Code].Players = players
+		// Creation order decides listing order
+		store.rooms[room.Code].CreatedAt = time.Unix(int64(len(store.rooms)), 0)
+		return room.Code
+	}
+	add("eu", "eu", true, 1, 4)
+	add("private", "eu", false, 1, 4)
+	add("full", "eu", true, 4, 4)
+	add("us", "us", true, 2, 0)
+	expired := add("expired", "eu", true, 1, 4)
+	store.rooms[expired].ExpiresAt = time.Now().Add(-time.Second)
+	add("eu2", "eu", true, 1, 2)
+
+	tests := []struct {
+		name   string
+		filter ListFilter
+		want   []string
+		total  int
+	}{
+		{"all", ListFilter{PerPage: 10}, []string{"eu", "full", "us", "eu2"}, 4},
+		{"not full", ListFilter{NotFull: true, PerPage: 10}, []string{"eu", "us", "eu2"}, 3},
+		{"region", ListFilter{Region: "eu", PerPage: 10}, []string{"eu", "full", "eu2"}, 3},
+		{"first page", ListFilter{Page: 1, PerPage: 3}, []string{"eu", "full", "us"}, 4},
+		{"second page", ListFilter{Page: 2, PerPage: 3}, []string{"eu2"}, 4},
+		{"past the end", ListFilter{Page: 3, PerPage: 3}, nil, 4},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rooms, total := store.List(tc.filter)
+			var names []string
+			for _, r := range rooms {
+				names = append(names, r.Name)
+			}
+			if !slices.Equal(names, tc.want) || total != tc.total {
+				t.Errorf("List = %v of %d, want %v of %d", names, total, tc.want, tc.total)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package lobby
+
+import (
+	"cmp"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Leaderboard modes: the daily challenge has a board per UTC date, time
+// trial one per level for all time
+const (
+	ModeDaily     = "daily"
+	ModeTimeTrial = "timetrial"
+)
+
+// Leaderboard orders
+const (
+	SortTime  = "time"  // Fastest first
+	SortOrbs  = "orbs"  // Most orbs first
+	SortScore = "score" // Best ranked first
+)
+
+// Leaderboard limits
+const (
+	MaxScoreName   = 16           // Runes in a leaderboard name
+	MaxScoreTicks  = 60 * 60 * 60 // An hour at 60 TPS; slower runs aren't kept
+	MaxScoreOrbs   = 10000
+	MaxScorePoints = 100   // game.RankMaxScore
+	MaxScoreGrade  = 4     // Bytes in a grade
+	ScoreDays      = 3     // Days of daily challenge boards kept
+	maxScoreLimit  = 100   // Scores returned by one GET /scores
+	maxScoreBoards = 10000 // Boards kept at once; each level hash starts one
+)
+
+// Errors from ScoreStore.Submit
+var (
+	ErrReplayReused  = errors.New("replay already submitted under another name")
+	ErrTooManyBoards = errors.New("too many leaderboards")
+)
+
+// Score is one player's entry on a leaderboard: their fastest time with
+// the hash of its replay, the most orbs they collected in a run and their
+// best ranked run's grade and points
+type Score struct {
+	Name   string `json:"name"`
+	Ticks  int    `json:"ticks"`
+	Orbs   int    `json:"orbs,omitempty"`
+	Replay string `json:"replay,omitempty"`
+	Grade  string `json:"grade,omitempty"`
+	Points int    `json:"points,omitempty"`
+}
+
+// ScoreRequest is the body of POST /scores
+type ScoreRequest struct {
+	Mode   string `json:"mode"`           // ModeDaily or ModeTimeTrial
+	Date   string `json:"date,omitempty"` // UTC date of a daily challenge, YYYY-MM-DD
+	Level  string `json:"level"`          // Level hash, so clients with different levels don't share a board
+	Name   string `json:"name"`
+	Ticks  int    `json:"ticks"`
+	Orbs   int    `json:"orbs,omitempty"`
+	Replay string `json:"replay"`           // SHA-256 of the run's replay file, hex
+	Grade  string `json:"grade,omitempty"`  // Rank the run earned (game.RunRank), if ranked
+	Points int    `json:"points,omitempty"` // And its score out of MaxScorePoints
+}
+
+// ScoreQuery picks a leaderboard and how much of it to return
+type ScoreQuery struct {
+	Mode  string
+	Date  string // Daily challenge only
+	Level string
+	Sort  string // SortTime, SortOrbs or SortScore, time if empty
+	Limit int    // Scores returned; 0 for just the rank and total
+	Name  string // Player whose rank to return, if set
+}
+
+// ScoreList is GET /scores: the top of one leaderboard
+type ScoreList struct {
+	Mode   string  `json:"mode"`
+	Date   string  `json:"date,omitempty"`
+	Sort   string  `json:"sort"`
+	Scores []Score `json:"scores"`
+	Total  int     `json:"total"`          // Players on the board
+	Rank   int     `json:"rank,omitempty"` // 1-based rank of ?name, 0 if not on the board
+}
+
+// ScoreStore keeps leaderboards in memory: each player's best per mode,
+// level and, for the daily challenge, date. Daily boards are kept for
+// ScoreDays days.
+type ScoreStore struct {
+	mu     sync.Mutex
+	boards map[scoreBoard]map[string]*Score // By name
+}
+
+type scoreBoard struct {
+	mode, date, level string
+}
+
+// NewScoreStore creates an empty score store
+func NewScoreStore() *ScoreStore {
+	return &ScoreStore{boards: make(map[scoreBoard]map[string]*Score)}
+}
+
+// Submit records a run, keeping the player's fastest time, most orbs and
+// best ranked run.
+// A replay hash another player already submitted on the board is refused,
+// so a copied replay file can't claim someone else's time.
+func (s *ScoreStore) Submit(req ScoreRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := scoreBoard{req.Mode, req.Date, req.Level}
+	board := s.boards[key]
+	if board == nil {
+		if len(s.boards) >= maxScoreBoards {
+			return ErrTooManyBoards
+		}
+		board = make(map[string]*Score)
+		s.boards[key] = board
+	}
+	for name, sc := range board {
+		if sc.Replay == req.Replay && name != req.Name {
+			return ErrReplayReused
+		}
+	}
+	sc := board[req.Name]
+	if sc == nil {
+		sc = &Score{Name: req.Name, Ticks: req.Ticks, Replay: req.Replay}
+		board[req.Name] = sc
+	}
+	if req.Ticks < sc.Ticks {
+		sc.Ticks, sc.Replay = req.Ticks, req.Replay
+	}
+	sc.Orbs = max(sc.Orbs, req.Orbs)
+	if req.Grade != "" && (sc.Grade == "" || req.Points > sc.Points) {
+		sc.Grade, sc.Points = req.Grade, req.Points
+	}
+	return nil
+}
+
+// Top returns the top of a board in the query's order, the number of
+// players on it and the named player's rank. Ties go to the name sorting
+// first.
+func (s *ScoreStore) Top(q ScoreQuery) ScoreList {
+	s.mu.Lock()
+	board := s.boards[scoreBoard{q.Mode, q.Date, q.Level}]
+	scores := make([]Score, 0, len(board))
+	for _, sc := range board {
+		scores = append(scores, *sc)
+	}
+	s.mu.Unlock()
+
+	order := cmp.Or(q.Sort, SortTime)
+	slices.SortFunc(scores, func(a, b Score) int {
+		switch order {
+		case SortOrbs:
+			return cmp.Or(cmp.Compare(b.Orbs, a.Orbs), cmp.Compare(a.Ticks, b.Ticks), cmp.Compare(a.Name, b.Name))
+		case SortScore:
+			return cmp.Or(cmp.Compare(b.Points, a.Points), cmp.Compare(a.Ticks, b.Ticks), cmp.Compare(a.Name, b.Name))
+		}
+		return cmp.Or(cmp.Compare(a.Ticks, b.Ticks), cmp.Compare(a.Name, b.Name))
+	})
+	list := ScoreList{Mode: q.Mode, Date: q.Date, Sort: order, Total: len(scores)}
+	if q.Name != "" {
+		if i := slices.IndexFunc(scores, func(sc Score) bool { return sc.Name == q.Name }); i >= 0 {
+			list.Rank = i + 1
+		}
+	}
+	list.Scores = scores[:max(0, min(q.Limit, len(scores)))]
+	return list
+}
+
+// Cleanup drops daily boards older than ScoreDays; call it periodically
+func (s *ScoreStore) Cleanup(now time.Time) {
+	oldest := now.UTC().AddDate(0, 0, 1-ScoreDays).Format(time.DateOnly)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.boards {
+		if key.mode == ModeDaily && key.date < oldest {
+			delete(s.boards, key)
+		}
+	}
+}
+
+// validScore checks a submission: a daily challenge on today's or
+// yesterday's UTC date, so a run finishing just after midnight still
+// counts, or a time trial without one; a short name, a plausible time, a
+// replay hash and, if ranked, a short grade
+func validScore(req ScoreRequest, now time.Time) string {
+	today := now.UTC()
+	switch req.Mode {
+	case ModeDaily:
+		if req.Date != today.Format(time.DateOnly) && req.Date != today.AddDate(0, 0, -1).Format(time.DateOnly) {
+			return "bad date"
+		}
+	case ModeTimeTrial:
+		if req.Date != "" {
+			return "bad date"
+		}
+	default:
+		return "bad mode"
+	}
+	switch {
+	case req.Name == "" || utf8.RuneCountInString(req.Name) > MaxScoreName || !utf8.ValidString(req.Name):
+		return "bad name"
+	case req.Ticks <= 0 || req.Ticks > MaxScoreTicks:
+		return "bad ticks"
+	case req.Orbs < 0 || req.Orbs > MaxScoreOrbs:
+		return "bad orbs"
+	case req.Points < 0 || req.Points > MaxScorePoints:
+		return "bad points"
+	case len(req.Grade) > MaxScoreGrade || !utf8.ValidString(req.Grade) || req.Grade == "" && req.Points != 0:
+		return "bad grade"
+	case req.Level == "" || len(req.Level) > 64:
+		return "bad level"
+	case !validHash(req.Replay):
+		return "bad replay"
+	}
+	return ""
+}
+
+// validHash reports whether s is a hex SHA-256
+func validHash(s string) bool {
+	b, err := hex.DecodeString(s)
+	return err == nil && len(b) == 32
+}
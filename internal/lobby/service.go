@@ -0,0 +1,443 @@
+package lobby
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Browser page sizes for GET /rooms
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// CreateRequest is the body of POST /rooms
+type CreateRequest struct {
+	Host       string `json:"host"` // IP:port, or :port to use the caller's IP
+	Name       string `json:"name"`
+	MaxPlayers int    `json:"max_players"`
+	Public     bool   `json:"public,omitempty"` // List in the server browser
+	Region     string `json:"region,omitempty"`
+	Code       string `json:"code,omitempty"` // Code to reclaim after a restart, kept if free
+}
+
+// RoomList is one page of GET /rooms
+type RoomList struct {
+	Rooms   []Room `json:"rooms"`
+	Total   int    `json:"total"` // Matching rooms on all pages
+	Page    int    `json:"page"`
+	PerPage int    `json:"per_page"`
+}
+
+// HeartbeatRequest is the body of PUT /rooms/{code}/heartbeat
+type HeartbeatRequest struct {
+	Players int `json:"players"`
+}
+
+// ServiceConfig limits what one client IP may do, since the service faces
+// the internet
+type ServiceConfig struct {
+	CreatePerMinute int // Rooms created per IP per minute, 0 = unlimited
+	CreateBurst     int
+	LookupPerMinute int // Lookups and listings per IP per minute, 0 = unlimited
+	LookupBurst     int
+
+	// Failed lookups per IP before each further one doubles the wait, up
+	// to MaxBackoff; stops room codes being guessed
+	FreeFailures int
+	MaxBackoff   time.Duration
+
+	MaxBodyBytes int64 // Request body limit
+	TrustProxy   bool  // Take the client IP from X-Forwarded-For
+
+	// GET /version: the latest release and where to get it, for clients
+	// to show an update notice. Empty tells clients nothing.
+	LatestVersion string
+	ReleaseURL    string
+
+	// Origin whose pages may call the service, e.g. where the js/wasm
+	// client is hosted; "*" for any. Empty sends no CORS headers, so
+	// browsers only allow pages served by the service itself.
+	AllowOrigin string
+
+	Logger *slog.Logger // Access log, nil for none
+}
+
+// DefaultServiceConfig returns limits generous enough for players and hosts
+func DefaultServiceConfig() ServiceConfig {
+	return ServiceConfig{
+		CreatePerMinute: 6,
+		CreateBurst:     3,
+		LookupPerMinute: 60,
+		LookupBurst:     20,
+		FreeFailures:    5,
+		MaxBackoff:      10 * time.Minute,
+		MaxBodyBytes:    4 << 10,
+	}
+}
+
+// Service serves the lookup service API on a store:
+//
+//	POST   /rooms                  create a room, returns it with its code
+//	GET    /rooms                  list public rooms (?page, per_page, not_full, region)
+//	GET    /rooms/{code}           look up a room
+//	PUT    /rooms/{code}/heartbeat keep a room alive, update its player count
+//	DELETE /rooms/{code}           remove a room
+//	POST   /scores                 submit a time trial or daily challenge run
+//	GET    /scores                 a level's best runs (?level, mode, sort, limit, name)
+//	GET    /scores/{date}          a day's daily challenge board (same query)
+//	GET    /version                the latest release
+type Service struct {
+	store   *RoomStore
+	scores  *ScoreStore
+	config  ServiceConfig
+	mux     *http.ServeMux
+	creates *rateLimiter
+	lookups *rateLimiter
+	guesses *backoff
+}
+
+// NewService creates the API for a store
+func NewService(store *RoomStore, config ServiceConfig) *Service {
+	s := &Service{
+		store:   store,
+		scores:  NewScoreStore(),
+		config:  config,
+		mux:     http.NewServeMux(),
+		creates: newRateLimiter(config.CreatePerMinute, config.CreateBurst),
+		lookups: newRateLimiter(config.LookupPerMinute, config.LookupBurst),
+		guesses: newBackoff(config.FreeFailures, config.MaxBackoff),
+	}
+	s.mux.HandleFunc("POST /rooms", s.create)
+	s.mux.HandleFunc("GET /rooms", s.list)
+	s.mux.HandleFunc("GET /rooms/{code}", s.lookup)
+	s.mux.HandleFunc("PUT /rooms/{code}/heartbeat", s.heartbeat)
+	s.mux.HandleFunc("DELETE /rooms/{code}", s.delete)
+	s.mux.HandleFunc("POST /scores", s.submitScore)
+	s.mux.HandleFunc("GET /scores", s.listScores)
+	s.mux.HandleFunc("GET /scores/{date}", s.listScores)
+	s.mux.HandleFunc("GET /version", s.version)
+	return s
+}
+
+// ServeHTTP limits the body size, serves the request and logs it
+func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	if s.config.MaxBodyBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, s.config.MaxBodyBytes)
+	}
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	if s.config.AllowOrigin != "" {
+		rec.Header().Set("Access-Control-Allow-Origin", s.config.AllowOrigin)
+		rec.Header().Add("Vary", "Origin")
+	}
+	if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+		s.preflight(rec)
+	} else {
+		s.mux.ServeHTTP(rec, r)
+	}
+	if s.config.Logger != nil {
+		s.config.Logger.Info("request",
+			"ip", s.clientIP(r),
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"bytes", rec.bytes,
+			"duration", time.Since(start),
+		)
+	}
+}
+
+// preflight answers a browser's CORS preflight request; without
+// AllowOrigin the browser finds no Allow-Origin header and gives up
+func (s *Service) preflight(w http.ResponseWriter) {
+	if s.config.AllowOrigin != "" {
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Max-Age", "3600")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Cleanup forgets rate limit and backoff state that has run out and old
+// leaderboards; call it periodically along with RoomStore.Cleanup
+func (s *Service) Cleanup() {
+	now := time.Now()
+	s.creates.sweep(now)
+	s.lookups.sweep(now)
+	s.guesses.sweep(now)
+	s.scores.Cleanup(now)
+}
+
+func (s *Service) create(w http.ResponseWriter, r *http.Request) {
+	ip := s.clientIP(r)
+	if !s.allow(w, s.creates, ip) {
+		return
+	}
+	var req CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badBody(w, err)
+		return
+	}
+	if strings.HasPrefix(req.Host, ":") {
+		req.Host = net.JoinHostPort(ip, req.Host[1:])
+	}
+	if _, _, err := net.SplitHostPort(req.Host); err != nil {
+		http.Error(w, "bad host: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Code != "" && !ValidCode(req.Code) {
+		http.Error(w, "bad code", http.StatusBadRequest)
+		return
+	}
+	room, err := s.store.Create(Room{
+		Code:       req.Code,
+		Host:       req.Host,
+		Name:       req.Name,
+		MaxPlayers: req.MaxPlayers,
+		Public:     req.Public,
+		Region:     req.Region,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, room)
+}
+
+func (s *Service) list(w http.ResponseWriter, r *http.Request) {
+	if !s.allow(w, s.lookups, s.clientIP(r)) {
+		return
+	}
+	q := r.URL.Query()
+	f := ListFilter{Page: 1, PerPage: defaultPerPage, Region: q.Get("region")}
+	var err error
+	if v := q.Get("page"); v != "" {
+		if f.Page, err = strconv.Atoi(v); err != nil || f.Page < 1 {
+			http.Error(w, "bad page", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := q.Get("per_page"); v != "" {
+		if f.PerPage, err = strconv.Atoi(v); err != nil || f.PerPage < 1 {
+			http.Error(w, "bad per_page", http.StatusBadRequest)
+			return
+		}
+		f.PerPage = min(f.PerPage, maxPerPage)
+	}
+	if v := q.Get("not_full"); v != "" {
+		if f.NotFull, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, "bad not_full", http.StatusBadRequest)
+			return
+		}
+	}
+	rooms, total := s.store.List(f)
+	if rooms == nil {
+		rooms = []Room{}
+	}
+	writeJSON(w, http.StatusOK, RoomList{Rooms: rooms, Total: total, Page: f.Page, PerPage: f.PerPage})
+}
+
+func (s *Service) lookup(w http.ResponseWriter, r *http.Request) {
+	ip := s.clientIP(r)
+	now := time.Now()
+	if !s.allowCode(w, ip, now) {
+		return
+	}
+	room, err := s.store.Lookup(r.PathValue("code"))
+	if err != nil {
+		status := http.StatusNotFound
+		if errors.Is(err, ErrUnreachable) {
+			status = http.StatusGone
+		} else {
+			s.guesses.fail(ip, now)
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	writeJSON(w, http.StatusOK, room)
+}
+
+func (s *Service) heartbeat(w http.ResponseWriter, r *http.Request) {
+	ip := s.clientIP(r)
+	now := time.Now()
+	if !s.allowCode(w, ip, now) {
+		return
+	}
+	var req HeartbeatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badBody(w, err)
+		return
+	}
+	room, err := s.store.Heartbeat(r.PathValue("code"), req.Players)
+	if err != nil {
+		s.guesses.fail(ip, now)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, room)
+}
+
+func (s *Service) delete(w http.ResponseWriter, r *http.Request) {
+	if !s.allowCode(w, s.clientIP(r), time.Now()) {
+		return
+	}
+	s.store.Delete(r.PathValue("code"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Service) submitScore(w http.ResponseWriter, r *http.Request) {
+	if !s.allow(w, s.creates, s.clientIP(r)) {
+		return
+	}
+	var req ScoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badBody(w, err)
+		return
+	}
+	if msg := validScore(req, time.Now()); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if err := s.scores.Submit(req); err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, ErrTooManyBoards) {
+			status = http.StatusServiceUnavailable
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	q := ScoreQuery{Mode: req.Mode, Date: req.Date, Level: req.Level, Name: req.Name}
+	writeJSON(w, http.StatusOK, s.scores.Top(q))
+}
+
+// listScores serves both GET /scores, a time trial board by default, and
+// GET /scores/{date}, a daily challenge board
+func (s *Service) listScores(w http.ResponseWriter, r *http.Request) {
+	if !s.allow(w, s.lookups, s.clientIP(r)) {
+		return
+	}
+	query := r.URL.Query()
+	q := ScoreQuery{
+		Mode:  cmp.Or(query.Get("mode"), ModeTimeTrial),
+		Level: query.Get("level"),
+		Sort:  cmp.Or(query.Get("sort"), SortTime),
+		Limit: 10,
+		Name:  query.Get("name"),
+	}
+	if date := r.PathValue("date"); date != "" {
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			http.Error(w, "bad date", http.StatusBadRequest)
+			return
+		}
+		q.Mode, q.Date = ModeDaily, date
+	}
+	if q.Sort != SortTime && q.Sort != SortOrbs && q.Sort != SortScore {
+		http.Error(w, "bad sort", http.StatusBadRequest)
+		return
+	}
+	if v := query.Get("limit"); v != "" {
+		var err error
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit < 1 {
+			http.Error(w, "bad limit", http.StatusBadRequest)
+			return
+		}
+		q.Limit = min(q.Limit, maxScoreLimit)
+	}
+	writeJSON(w, http.StatusOK, s.scores.Top(q))
+}
+
+// version answers the client's startup check. It is cheap and the same for
+// everyone, so it isn't rate limited and may be cached for an hour.
+func (s *Service) version(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	writeJSON(w, http.StatusOK, VersionInfo{Latest: s.config.LatestVersion, URL: s.config.ReleaseURL})
+}
+
+// allow takes a token from limiter for ip, answering 429 if there is none
+func (s *Service) allow(w http.ResponseWriter, limiter *rateLimiter, ip string) bool {
+	ok, wait := limiter.allow(ip, time.Now())
+	if !ok {
+		tooMany(w, wait)
+	}
+	return ok
+}
+
+// allowCode gates requests naming a room code: refused while ip is backing
+// off from failed guesses, then rate limited as lookups
+func (s *Service) allowCode(w http.ResponseWriter, ip string, now time.Time) bool {
+	if blocked, wait := s.guesses.blocked(ip, now); blocked {
+		tooMany(w, wait)
+		return false
+	}
+	return s.allow(w, s.lookups, ip)
+}
+
+// clientIP returns the caller's IP: the last X-Forwarded-For entry behind
+// a trusted proxy, otherwise the connection's address. Proxies append the
+// address they saw, so only the last entry comes from the proxy; anything
+// before it is whatever the client sent.
+func (s *Service) clientIP(r *http.Request) string {
+	if s.config.TrustProxy {
+		if fwd := r.Header.Values("X-Forwarded-For"); len(fwd) > 0 {
+			last := fwd[len(fwd)-1]
+			if i := strings.LastIndexByte(last, ','); i >= 0 {
+				last = last[i+1:]
+			}
+			if ip := strings.TrimSpace(last); ip != "" {
+				return ip
+			}
+		}
+	}
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+func tooMany(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+	http.Error(w, "too many requests", http.StatusTooManyRequests)
+}
+
+func badBody(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	http.Error(w, "bad request: "+err.Error(), http.StatusBadRequest)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// statusRecorder remembers the status and size of a response for the
+// access log
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
@@ -0,0 +1,300 @@
+package lobby
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestServiceAbuseLimits tests that room creation is rate limited per IP,
+// repeated failed lookups back off even for valid codes, and oversized
+// bodies are refused.
+func TestServiceAbuseLimits(t *testing.T) {
+	cfg := DefaultServiceConfig()
+	cfg.CreateBurst = 2
+	cfg.FreeFailures = 3
+	cfg.MaxBodyBytes = 256
+	service := NewService(NewRoomStore(time.Hour), cfg)
+
+	do := func(method, path, body, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.RemoteAddr = ip + ":5555"
+		rec := httptest.NewRecorder()
+		service.ServeHTTP(rec, req)
+		return rec
+	}
+	const room = `{"host":"10.0.0.1:7777","name":"Game","max_players":4}`
+
+	// Creation: the burst, then 429 with Retry-After; other IPs unaffected
+	for i := 0; i < cfg.CreateBurst; i++ {
+		if rec := do("POST", "/rooms", room, "1.1.1.1"); rec.Code != http.StatusCreated {
+			t.Fatalf("Create %d: got %d, want 201", i, rec.Code)
+		}
+	}
+	rec := do("POST", "/rooms", room, "1.1.1.1")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("Create over the limit: got %d (Retry-After %q), want 429 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	rec = do("POST", "/rooms", room, "2.2.2.2")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Create from another IP: got %d, want 201", rec.Code)
+	}
+	var created Room
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	code := created.Code
+
+	// Guessing: free failures answer 404, then even a valid code waits
+	for i := 0; i < cfg.FreeFailures; i++ {
+		if rec := do("GET", "/rooms/AAAA-AAAA", "", "3.3.3.3"); rec.Code != http.StatusNotFound {
+			t.Fatalf("Failed lookup %d: got %d, want 404", i, rec.Code)
+		}
+	}
+	if rec := do("GET", "/rooms/"+code, "", "3.3.3.3"); rec.Code != http.StatusOK {
+		t.Fatalf("Lookup before backoff: got %d, want 200", rec.Code)
+	}
+	do("GET", "/rooms/AAAA-AAAA", "", "3.3.3.3")
+	if rec := do("GET", "/rooms/"+code, "", "3.3.3.3"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Lookup while backing off: got %d, want 429", rec.Code)
+	}
+	if rec := do("GET", "/rooms/"+code, "", "4.4.4.4"); rec.Code != http.StatusOK {
+		t.Errorf("Lookup from another IP: got %d, want 200", rec.Code)
+	}
+
+	// Body limit
+	big := `{"host":"10.0.0.1:7777","name":"` + strings.Repeat("x", 512) + `"}`
+	if rec := do("POST", "/rooms", big, "5.5.5.5"); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Oversized body: got %d, want 413", rec.Code)
+	}
+}
+
+// TestServiceClientIP tests that behind a trusted proxy the client IP is the
+// X-Forwarded-For entry the proxy appended, not one the client made up.
+func TestServiceClientIP(t *testing.T) {
+	tests := []struct {
+		name  string
+		trust bool
+		fwd   []string
+		want  string
+	}{
+		{"direct", false, nil, "9.9.9.9"},
+		{"header ignored", false, []string{"1.1.1.1"}, "9.9.9.9"},
+		{"proxy", true, []string{"1.1.1.1"}, "1.1.1.1"},
+		{"spoofed entry", true, []string{"6.6.6.6, 1.1.1.1"}, "1.1.1.1"},
+		{"repeated header", true, []string{"6.6.6.6", "1.1.1.1"}, "1.1.1.1"},
+		{"no header", true, nil, "9.9.9.9"},
+		{"empty entry", true, []string{"1.1.1.1, "}, "9.9.9.9"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := DefaultServiceConfig()
+			cfg.TrustProxy = tc.trust
+			service := NewService(NewRoomStore(time.Hour), cfg)
+			req := httptest.NewRequest("GET", "/rooms", nil)
+			req.RemoteAddr = "9.9.9.9:5555"
+			for _, v := range tc.fwd {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			if got := service.clientIP(req); got != tc.want {
+				t.Errorf("clientIP = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestServiceScores tests that leaderboards keep each player's fastest
+// time, most orbs and best rank, order by any of them, keep boards apart by mode,
+// date and level, and refuse bad runs and reused replays.
+func TestServiceScores(t *testing.T) {
+	cfg := DefaultServiceConfig()
+	cfg.CreateBurst = 20
+	server := httptest.NewServer(NewService(NewRoomStore(time.Hour), cfg))
+	defer server.Close()
+	client := NewLookupClient(server.URL)
+	today := time.Now().UTC().Format(time.DateOnly)
+	replay := func(n int) string { return fmt.Sprintf("%064x", n) }
+
+	for i, sc := range []ScoreRequest{
+		{Name: "ada", Ticks: 900, Orbs: 20, Grade: "A", Points: 85},
+		{Name: "bob", Ticks: 700, Orbs: 5, Grade: "S", Points: 96},
+		{Name: "ada", Ticks: 650, Orbs: 3, Grade: "C", Points: 50},
+		{Name: "ada", Ticks: 800}, // Slower, not kept
+	} {
+		sc.Mode, sc.Level, sc.Replay = ModeTimeTrial, "abc", replay(i)
+		if _, err := client.SubmitScore(sc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list, err := client.Scores(ScoreQuery{Mode: ModeTimeTrial, Level: "abc", Limit: 10, Name: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Score{{"ada", 650, 20, replay(2), "A", 85}, {"bob", 700, 5, replay(1), "S", 96}}
+	if !slices.Equal(list.Scores, want) {
+		t.Errorf("Expected %v, got %v", want, list.Scores)
+	}
+	if list.Rank != 2 || list.Total != 2 {
+		t.Errorf("Expected bob ranked 2 of 2, got %d of %d", list.Rank, list.Total)
+	}
+	byOrbs, _ := client.Scores(ScoreQuery{Mode: ModeTimeTrial, Level: "abc", Sort: SortOrbs, Limit: 1})
+	if byOrbs == nil || len(byOrbs.Scores) != 1 || byOrbs.Scores[0].Name != "ada" {
+		t.Errorf("Expected ada first by orbs, got %+v", byOrbs)
+	}
+	byScore, _ := client.Scores(ScoreQuery{Mode: ModeTimeTrial, Level: "abc", Sort: SortScore, Limit: 1})
+	if byScore == nil || len(byScore.Scores) != 1 || byScore.Scores[0].Name != "bob" {
+		t.Errorf("Expected bob first by rank, got %+v", byScore)
+	}
+
+	daily := ScoreRequest{Mode: ModeDaily, Date: today, Level: "abc", Name: "eve", Ticks: 100, Replay: replay(9)}
+	if _, err := client.SubmitScore(daily); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := client.Scores(ScoreQuery{Date: today, Level: "abc", Limit: 10}); list == nil || list.Total != 1 {
+		t.Errorf("Expected the daily board apart from time trial, got %+v", list)
+	}
+	if other, _ := client.Scores(ScoreQuery{Mode: ModeTimeTrial, Level: "def", Limit: 10}); other == nil || other.Total != 0 {
+		t.Error("Expected another level's board to be empty")
+	}
+
+	for _, bad := range []ScoreRequest{
+		{Mode: ModeDaily, Date: "2001-01-01", Level: "abc", Name: "eve", Ticks: 100, Replay: replay(10)},
+		{Mode: ModeTimeTrial, Date: today, Level: "abc", Name: "eve", Ticks: 100, Replay: replay(11)},
+		{Mode: "race", Level: "abc", Name: "eve", Ticks: 100, Replay: replay(12)},
+		{Mode: ModeTimeTrial, Level: "abc", Name: "eve", Ticks: 0, Replay: replay(13)},
+		{Mode: ModeTimeTrial, Level: "abc", Name: strings.Repeat("e", MaxScoreName+1), Ticks: 100, Replay: replay(14)},
+		{Mode: ModeTimeTrial, Level: "abc", Name: "eve", Ticks: 100, Replay: "abc"},
+		{Mode: ModeTimeTrial, Level: "abc", Name: "eve", Ticks: 100, Replay: replay(15), Grade: "S", Points: 101},
+		{Mode: ModeTimeTrial, Level: "abc", Name: "eve", Ticks: 100, Replay: replay(16), Grade: "Platinum", Points: 99},
+		{Mode: ModeTimeTrial, Level: "abc", Name: "eve", Ticks: 100, Replay: replay(1)}, // bob's
+	} {
+		if _, err := client.SubmitScore(bad); err == nil {
+			t.Errorf("Expected %+v to be refused", bad)
+		}
+	}
+}
+
+// TestServiceHeartbeat tests registering a room with the caller's address,
+// heartbeats updating it, and heartbeats for unknown or deleted rooms.
+func TestServiceHeartbeat(t *testing.T) {
+	server := httptest.NewServer(NewService(NewRoomStore(time.Hour), DefaultServiceConfig()))
+	defer server.Close()
+	client := NewLookupClient(server.URL)
+
+	room, err := client.Create(CreateRequest{Host: ":7777", Name: "Game", MaxPlayers: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if room.Host != "127.0.0.1:7777" {
+		t.Errorf("Host = %q, want the caller's IP with the port", room.Host)
+	}
+	if err := client.Heartbeat(room.Code, 3); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := client.Lookup(room.Code); err != nil || got.Players != 3 || got.LastSeen.IsZero() {
+		t.Errorf("Lookup = %+v (%v), want 3 players and the heartbeat seen", got, err)
+	}
+
+	if err := client.Heartbeat("AAAA-AAAA", 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Heartbeat for an unknown room: %v, want ErrNotFound", err)
+	}
+	if err := client.Delete(room.Code); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Heartbeat(room.Code, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Heartbeat after deleting: %v, want ErrNotFound", err)
+	}
+}
+
+// TestServiceList tests the public room listing through the client, and
+// that bad paging parameters are refused.
+func TestServiceList(t *testing.T) {
+	cfg := DefaultServiceConfig()
+	cfg.CreateBurst = 10
+	server := httptest.NewServer(NewService(NewRoomStore(time.Hour), cfg))
+	defer server.Close()
+	client := NewLookupClient(server.URL)
+
+	for _, req := range []CreateRequest{
+		{Host: ":7001", Name: "a", MaxPlayers: 1, Public: true, Region: "eu"},
+		{Host: ":7002", Name: "b", MaxPlayers: 4, Public: true, Region: "eu"},
+		{Host: ":7003", Name: "c", MaxPlayers: 4},
+		{Host: ":7004", Name: "d", MaxPlayers: 4, Public: true, Region: "us"},
+	} {
+		if _, err := client.Create(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list, err := client.List(ListFilter{NotFull: true, Region: "eu"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Rooms) != 1 || list.Rooms[0].Name != "b" || list.Total != 1 || list.Page != 1 || list.PerPage != 20 {
+		t.Errorf("Open eu rooms %+v, want b alone on page 1 of 20", list)
+	}
+	if list, _ := client.List(ListFilter{Page: 2, PerPage: 1000}); list == nil || len(list.Rooms) != 0 || list.Total != 3 || list.PerPage != 100 {
+		t.Errorf("Second page %+v, want empty with 3 in total and at most 100 per page", list)
+	}
+
+	for _, query := range []string{"page=0", "page=x", "per_page=0", "not_full=maybe"} {
+		resp, err := http.Get(server.URL + "/rooms?" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("GET /rooms?%s: got %d, want 400", query, resp.StatusCode)
+		}
+	}
+}
+
+func TestServiceVersion(t *testing.T) {
+	cfg := DefaultServiceConfig()
+	cfg.LatestVersion, cfg.ReleaseURL = "1.4.0", "https://example.com/releases"
+	server := httptest.NewServer(NewService(NewRoomStore(time.Hour), cfg))
+	defer server.Close()
+
+	info, err := NewLookupClient(server.URL).Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *info != (VersionInfo{Latest: "1.4.0", URL: "https://example.com/releases"}) {
+		t.Errorf("version = %+v", info)
+	}
+}
+
+func TestServiceCORS(t *testing.T) {
+	for _, origin := range []string{"", "https://play.example.com"} {
+		cfg := DefaultServiceConfig()
+		cfg.AllowOrigin = origin
+		s := NewService(NewRoomStore(time.Hour), cfg)
+
+		req := httptest.NewRequest(http.MethodOptions, "/scores", nil)
+		req.Header.Set("Origin", "https://play.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNoContent {
+			t.Errorf("origin %q: preflight status %d", origin, rec.Code)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != origin {
+			t.Errorf("origin %q: preflight allows %q", origin, got)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Methods"); (got != "") != (origin != "") {
+			t.Errorf("origin %q: preflight allows methods %q", origin, got)
+		}
+
+		rec = httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+		if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != origin {
+			t.Errorf("origin %q: GET /version status %d, allows %q", origin, rec.Code, rec.Header().Get("Access-Control-Allow-Origin"))
+		}
+	}
+}
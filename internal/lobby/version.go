@@ -0,0 +1,55 @@
+package lobby
+
+import (
+	"strconv"
+	"strings"
+)
+
+// VersionInfo is GET /version: the latest release, for clients to show an
+// update notice
+type VersionInfo struct {
+	Latest string `json:"latest"`        // Empty if the service wasn't told
+	URL    string `json:"url,omitempty"` // Where to download it
+}
+
+// NewerVersion reports whether latest is a newer release than current.
+// Releases are up to three dot-separated numbers with an optional "v" and
+// "-suffix"; a suffixed pre-release sorts before its release. Anything
+// else, such as a "dev" build, is never compared.
+func NewerVersion(current, latest string) bool {
+	cur, curPre, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	next, nextPre, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	for i := range cur {
+		if next[i] != cur[i] {
+			return next[i] > cur[i]
+		}
+	}
+	return curPre && !nextPre
+}
+
+// parseVersion splits a release into its numbers, and whether it is a
+// pre-release
+func parseVersion(v string) (nums [3]int, pre bool, ok bool) {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexByte(v, '-'); i >= 0 {
+		v, pre = v[:i], true
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) > len(nums) {
+		return nums, false, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nums, false, false
+		}
+		nums[i] = n
+	}
+	return nums, pre, true
+}
@@ -0,0 +1,27 @@
+package lobby
+
+import "testing"
+
+func TestNewerVersion(t *testing.T) {
+	tests := []struct {
+		current, latest string
+		newer           bool
+	}{
+		{"1.4.0", "1.4.1", true},
+		{"1.4.0", "1.10.0", true},
+		{"v1.4.0", "2", true},
+		{"1.4.0-rc1", "1.4.0", true},
+		{"1.4.0", "1.4.0", false},
+		{"1.4.1", "1.4.0", false},
+		{"1.4.0", "1.4.0-rc1", false},
+		{"1.4", "1.4.0", false},
+		{"dev", "1.4.0", false},
+		{"1.4.0", "", false},
+		{"1.4.0", "latest", false},
+	}
+	for _, tc := range tests {
+		if got := NewerVersion(tc.current, tc.latest); got != tc.newer {
+			t.Errorf("NewerVersion(%q, %q) = %v, want %v", tc.current, tc.latest, got, tc.newer)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Package netsim runs a server and several predicting clients in one process
+// over simulated network links, for soak-testing reconciliation under
+// latency, jitter, reordering and loss.
+package netsim
+
+import (
+	"math/rand/v2"
+	"sort"
+)
+
+// Conditions describe a one-way network path. Times are in simulation
+// ticks, so runs are deterministic for a seed.
+type Conditions struct {
+	Latency int     // Base delay
+	Jitter  int     // Extra random delay, up to this many ticks
+	Loss    float64 // Share of messages dropped, in [0, 1]
+	Reorder float64 // Share of messages held back a further Latency+Jitter ticks, so later ones overtake them
+}
+
+// Link is a one-way simulated network path carrying messages of type T
+type Link[T any] struct {
+	cond     Conditions
+	rng      *rand.Rand
+	inFlight []delivery[T]
+	seq      uint64 // Send order, to keep equal-time deliveries stable
+
+	Sent, Dropped int
+}
+
+type delivery[T any] struct {
+	at  uint64 // Tick the message arrives
+	seq uint64
+	msg T
+}
+
+// NewLink creates a link with the given conditions and random seed
+func NewLink[T any](cond Conditions, seed uint64) *Link[T] {
+	return &Link[T]{cond: cond, rng: rand.New(rand.NewPCG(seed, 0))}
+}
+
+// Send puts a message on the link at tick now
+func (l *Link[T]) Send(now uint64, msg T) {
+	l.Sent++
+	if l.rng.Float64() < l.cond.Loss {
+		l.Dropped++
+		return
+	}
+	delay := l.cond.Latency
+	if l.cond.Jitter > 0 {
+		delay += l.rng.IntN(l.cond.Jitter + 1)
+	}
+	if l.rng.Float64() < l.cond.Reorder {
+		delay += l.cond.Latency + l.cond.Jitter + 1
+	}
+	l.seq++
+	l.inFlight = append(l.inFlight, delivery[T]{at: now + uint64(delay), seq: l.seq, msg: msg})
+}
+
+// Receive returns the messages that have arrived by tick now, in arrival
+// order
+func (l *Link[T]) Receive(now uint64) []T {
+	sort.Slice(l.inFlight, func(i, j int) bool {
+		a, b := l.inFlight[i], l.inFlight[j]
+		return a.at < b.at || a.at == b.at && a.seq < b.seq
+	})
+	n := 0
+	for n < len(l.inFlight) && l.inFlight[n].at <= now {
+		n++
+	}
+	out := make([]T, n)
+	for i := range n {
+		out[i] = l.inFlight[i].msg
+	}
+	l.inFlight = append(l.inFlight[:0], l.inFlight[n:]...)
+	return out
+}
+
+// Pending returns the number of messages still in flight
+func (l *Link[T]) Pending() int {
+	return len(l.inFlight)
+}
@@ -0,0 +1,268 @@
+package netsim
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"github.com/andersfylling/rayman-slides/internal/client"
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+	"github.com/andersfylling/rayman-slides/internal/server"
+)
+
+// Config describes a soak run
+type Config struct {
+	Clients    int
+	Ticks      int        // Ticks of random play
+	Settle     int        // Idle ticks after play, for the last states to arrive
+	Up, Down   Conditions // Client to server, server to client
+	Lead       int        // Ticks clients run ahead of the server; 0 picks Up.Latency+Up.Jitter+2
+	Redundancy int        // Input frames repeated per message
+	Tolerance  float64    // Largest allowed position difference at the end
+	Seed       uint64
+}
+
+// DefaultConfig returns a four-client run on a mediocre connection
+func DefaultConfig() Config {
+	cond := Conditions{Latency: 4, Jitter: 3, Loss: 0.05, Reorder: 0.05}
+	return Config{
+		Clients:    4,
+		Ticks:      3000,
+		Settle:     120,
+		Up:         cond,
+		Down:       cond,
+		Redundancy: protocol.DefaultInputRedundancy,
+		Tolerance:  0.01,
+		Seed:       1,
+	}
+}
+
+// ClientReport is one client's outcome
+type ClientReport struct {
+	PlayerID   int
+	Rollbacks  int     // Reconciliations that rolled back and replayed
+	StaleDrops int     // States dropped for arriving after a newer one
+	InputLoss  float64 // Share of ticks the server simulated without this client's input
+	MaxError   float64 // Largest own-player prediction error seen at a reconciliation
+	Divergence float64 // Largest position difference from the server at the end
+}
+
+// Report is the outcome of a soak run
+type Report struct {
+	ServerTick uint64
+	Clients    []ClientReport
+}
+
+// Converged reports whether every client ended within tolerance of the server
+func (r *Report) Converged(tolerance float64) bool {
+	for _, c := range r.Clients {
+		if c.Divergence > tolerance {
+			return false
+		}
+	}
+	return true
+}
+
+// stateUpdate is what the server sends a client each sync tick
+type stateUpdate struct {
+	State game.WorldState
+	Ack   protocol.InputAck
+}
+
+// simClient is a remote client: it predicts its own player and reconciles
+// with the states it receives
+type simClient struct {
+	playerID    int
+	world       *game.World
+	predictions *client.PredictionBuffer
+	reconciler  *client.Reconciler
+	sender      *client.InputSender
+	up          *Link[[]byte]
+	down        *Link[stateUpdate]
+
+	rng      *rand.Rand
+	intents  protocol.Intent
+	hold     int    // Ticks left on the current intents
+	latest   uint64 // Newest state tick applied
+	report   ClientReport
+	encodeTo []byte
+}
+
+// newWorld loads the level and spawns every player the same way on the
+// server and each client, so network IDs line up
+func newWorld(level *game.Level, players int) *game.World {
+	world := game.NewWorld()
+	world.LoadLevel(level)
+	for i := range players {
+		spawn := level.PlayerSpawn(i)
+		world.SetPlayerColor(i+1, protocol.PlayerColor(i))
+		world.SpawnPlayer(i+1, fmt.Sprintf("P%d", i+1), spawn.X, spawn.Y)
+	}
+	return world
+}
+
+// Run plays a soak run and reports how each client fared
+func Run(cfg Config) Report {
+	if cfg.Lead == 0 {
+		cfg.Lead = cfg.Up.Latency + cfg.Up.Jitter + 2
+	}
+	level := game.NewDemoLevel(80, 30)
+
+	srv := server.New(server.DefaultConfig())
+	srv.SetWorld(newWorld(level, cfg.Clients))
+
+	clients := make([]*simClient, cfg.Clients)
+	for i := range clients {
+		playerID := i + 1
+		srv.AddSession(playerID, playerID, fmt.Sprintf("P%d", playerID))
+
+		predictions := client.NewPredictionBuffer(256)
+		reconciler := client.NewReconciler(predictions)
+		reconciler.SetPlayerID(playerID)
+		seed := cfg.Seed*1000 + uint64(playerID)
+		clients[i] = &simClient{
+			playerID:    playerID,
+			world:       newWorld(level, cfg.Clients),
+			predictions: predictions,
+			reconciler:  reconciler,
+			sender:      client.NewInputSender(cfg.Redundancy),
+			up:          NewLink[[]byte](cfg.Up, seed*2),
+			down:        NewLink[stateUpdate](cfg.Down, seed*2+1),
+			rng:         rand.New(rand.NewPCG(seed, 1)),
+			report:      ClientReport{PlayerID: playerID},
+		}
+	}
+
+	var now uint64
+	srv.SetStateUpdateCallback(func(state game.WorldState) {
+		for _, c := range clients {
+			ack, _ := srv.InputAck(c.playerID)
+			c.down.Send(now, stateUpdate{State: state, Ack: ack})
+		}
+	})
+
+	total := cfg.Lead + cfg.Ticks + cfg.Settle
+	for ; now < uint64(total); now++ {
+		playing := now < uint64(cfg.Lead+cfg.Ticks)
+		for _, c := range clients {
+			c.step(now, playing)
+		}
+
+		// The server starts Lead ticks behind, so inputs arrive in time
+		for _, c := range clients {
+			for _, data := range c.up.Receive(now) {
+				if msg, _, err := protocol.DecodeInputMessage(data); err == nil {
+					srv.QueueInputs(c.playerID, msg)
+				}
+			}
+		}
+		if now >= uint64(cfg.Lead) {
+			srv.Step()
+		}
+	}
+
+	// Every player idles through Settle, so once the last state is in, each
+	// client's world must agree with the server's
+	final := srv.World().Snapshot()
+	report := Report{ServerTick: final.Tick}
+	for _, c := range clients {
+		c.report.InputLoss = c.sender.LossRate()
+		c.report.Divergence = divergence(c.world.Snapshot(), final)
+		report.Clients = append(report.Clients, c.report)
+	}
+	return report
+}
+
+// step runs one client tick: receive states, predict the next tick on local
+// input and send it
+func (c *simClient) step(now uint64, playing bool) {
+	for _, u := range c.down.Receive(now) {
+		c.sender.OnAck(u.Ack)
+		if u.State.Tick <= c.latest {
+			c.report.StaleDrops++
+			continue
+		}
+		c.latest = u.State.Tick
+		if predicted := c.predictions.GetState(u.State.Tick); predicted != nil {
+			c.report.MaxError = math.Max(c.report.MaxError, ownError(predicted, &u.State, c.playerID))
+		}
+		state := u.State
+		if result := c.reconciler.Reconcile(c.world, &state, c.world.Tick); result.RolledBack {
+			c.report.Rollbacks++
+		}
+	}
+
+	switch {
+	case !playing:
+		c.intents = protocol.IntentNone
+	case c.hold == 0:
+		c.intents = randomIntents(c.rng)
+		c.hold = 1 + c.rng.IntN(30)
+	}
+	if c.hold > 0 {
+		c.hold--
+	}
+
+	frame := protocol.InputFrame{Tick: c.world.Tick + 1, Intents: c.intents}
+	c.predictions.RecordInput(frame)
+	c.encodeTo = protocol.AppendInputMessage(c.encodeTo[:0], c.sender.Next(frame))
+	c.up.Send(now, append([]byte(nil), c.encodeTo...))
+
+	c.world.SetPlayerIntent(c.playerID, c.intents)
+	c.world.Update()
+	state := c.world.Snapshot()
+	c.predictions.RecordState(client.ConvertToWorldSnapshot(&state))
+}
+
+// randomIntents picks held movement, jumps and attacks
+func randomIntents(rng *rand.Rand) protocol.Intent {
+	choices := []protocol.Intent{
+		protocol.IntentNone,
+		protocol.IntentLeft,
+		protocol.IntentRight,
+		protocol.IntentJump,
+		protocol.IntentLeft | protocol.IntentJump,
+		protocol.IntentRight | protocol.IntentJump,
+		protocol.IntentAttack,
+	}
+	return choices[rng.IntN(len(choices))]
+}
+
+// ownError returns how far the predicted own player was from the server's
+func ownError(predicted *client.WorldSnapshot, authoritative *game.WorldState, playerID int) float64 {
+	var id protocol.EntityID
+	for _, es := range authoritative.Entities {
+		if es.HasPlayer && es.Player.ID == playerID {
+			id = es.ID
+		}
+	}
+	for _, es := range authoritative.Entities {
+		if es.ID != id {
+			continue
+		}
+		for _, pe := range predicted.Entities {
+			if pe.ID == id {
+				return math.Hypot(pe.PositionX-es.Position.X, pe.PositionY-es.Position.Y)
+			}
+		}
+	}
+	return 0
+}
+
+// divergence returns the largest position difference between the entities
+// of two states, or +Inf if they don't have the same entities
+func divergence(a, b game.WorldState) float64 {
+	if len(a.Entities) != len(b.Entities) {
+		return math.Inf(1)
+	}
+	var worst float64
+	for i := range a.Entities {
+		ea, eb := &a.Entities[i], &b.Entities[i]
+		if ea.ID != eb.ID {
+			return math.Inf(1)
+		}
+		worst = math.Max(worst, math.Hypot(ea.Position.X-eb.Position.X, ea.Position.Y-eb.Position.Y))
+	}
+	return worst
+}
@@ -0,0 +1,26 @@
+package netsim
+
+import (
+	"testing"
+)
+
+// TestSoak runs a server and four clients over lossy, jittery, reordering
+// links and tests that every client ends in the server's state.
+func TestSoak(t *testing.T) {
+	cfg := DefaultConfig()
+	if testing.Short() {
+		cfg.Ticks = 600
+	}
+	report := Run(cfg)
+
+	for _, c := range report.Clients {
+		t.Logf("player %d: %d rollbacks, %d stale states, input loss %.1f%%, max error %.2f, divergence %.4f",
+			c.PlayerID, c.Rollbacks, c.StaleDrops, 100*c.InputLoss, c.MaxError, c.Divergence)
+		if c.Rollbacks == 0 {
+			t.Errorf("Player %d never rolled back; the links should cause mispredictions", c.PlayerID)
+		}
+	}
+	if !report.Converged(cfg.Tolerance) {
+		t.Error("Clients diverged from the server")
+	}
+}
@@ -0,0 +1,209 @@
+package network
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Errors from the secure layer
+var (
+	ErrAuthFailed = errors.New("network: peer failed authentication (wrong room key?)")
+	ErrReplayed   = errors.New("network: replayed or too old message")
+)
+
+// secureVersion is sent first in the handshake and bound into the keys
+const secureVersion = 1
+
+// replayWindow is how many messages behind the newest one a message may
+// arrive and still be accepted, for datagram transports that reorder
+const replayWindow = 64
+
+// confirm is the first sealed message each side sends, proving it derived
+// the same keys
+var confirm = []byte("rayman-slides confirm")
+
+// SecureConnection adds authenticated encryption to a message Connection.
+// It is meant for transports without TLS (UDP): each message is sealed on
+// its own with AES-256-GCM under an explicit sequence number, so loss and
+// reordering are fine and replays are rejected.
+//
+// Keys come from an ephemeral X25519 exchange mixed with an optional
+// pre-shared key, in the spirit of Noise NNpsk0. With a room key only peers
+// that know it can complete the handshake; without one the traffic is
+// still encrypted but either side could be anyone.
+type SecureConnection struct {
+	conn Connection
+	send cipher.AEAD
+	recv cipher.AEAD
+
+	sendSeq uint64
+	maxSeq  uint64 // Newest sequence number received
+	seen    uint64 // Bitmap of the replayWindow sequence numbers up to maxSeq
+}
+
+// SecureClient runs the client side of the handshake over conn.
+// psk is the room key from the invite, or nil for an open room.
+func SecureClient(conn Connection, psk []byte) (*SecureConnection, error) {
+	return secureHandshake(conn, psk, true)
+}
+
+// SecureServer runs the server side of the handshake over conn.
+// psk is the room key, or nil for an open room.
+func SecureServer(conn Connection, psk []byte) (*SecureConnection, error) {
+	return secureHandshake(conn, psk, false)
+}
+
+// secureHandshake exchanges ephemeral keys, derives one key per direction
+// and checks the peer derived the same ones. The handshake messages must
+// arrive, so over UDP the caller retransmits until Recv succeeds.
+func secureHandshake(conn Connection, psk []byte, initiator bool) (*SecureConnection, error) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	hello := append([]byte{secureVersion}, priv.PublicKey().Bytes()...)
+	if err := conn.Send(hello); err != nil {
+		return nil, err
+	}
+	peerHello, err := conn.Recv()
+	if err != nil {
+		return nil, err
+	}
+	if len(peerHello) != len(hello) || peerHello[0] != secureVersion {
+		return nil, fmt.Errorf("network: bad secure handshake (%d bytes)", len(peerHello))
+	}
+	peerPub, err := ecdh.X25519().NewPublicKey(peerHello[1:])
+	if err != nil {
+		return nil, err
+	}
+	shared, err := priv.ECDH(peerPub)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bind both hellos, in client-server order, into the keys
+	clientHello, serverHello := hello, peerHello
+	if !initiator {
+		clientHello, serverHello = peerHello, hello
+	}
+	info := append(append([]byte("rayman-slides secure v1"), clientHello...), serverHello...)
+	salt := sha256.Sum256(psk)
+	keys, err := hkdf.Key(sha256.New, shared, salt[:], string(info), 64)
+	if err != nil {
+		return nil, err
+	}
+	toServer, err := newAEAD(keys[:32])
+	if err != nil {
+		return nil, err
+	}
+	toClient, err := newAEAD(keys[32:])
+	if err != nil {
+		return nil, err
+	}
+
+	sc := &SecureConnection{conn: conn, send: toServer, recv: toClient}
+	if !initiator {
+		sc.send, sc.recv = toClient, toServer
+	}
+
+	if err := sc.Send(confirm); err != nil {
+		return nil, err
+	}
+	got, err := sc.Recv()
+	if err != nil || string(got) != string(confirm) {
+		return nil, ErrAuthFailed
+	}
+	return sc, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// nonce returns the GCM nonce for a sequence number
+func nonce(seq uint64) []byte {
+	n := make([]byte, 12)
+	binary.LittleEndian.PutUint64(n[4:], seq)
+	return n
+}
+
+// Send seals and sends a message: sequence number u64 | ciphertext
+func (c *SecureConnection) Send(data []byte) error {
+	c.sendSeq++
+	msg := binary.LittleEndian.AppendUint64(make([]byte, 0, 8+len(data)+c.send.Overhead()), c.sendSeq)
+	msg = c.send.Seal(msg, nonce(c.sendSeq), data, msg[:8])
+	return c.conn.Send(msg)
+}
+
+// Recv receives and opens the next message. A message that fails to
+// authenticate returns ErrAuthFailed and a replayed one ErrReplayed; over
+// a datagram transport the caller can drop it and keep receiving.
+func (c *SecureConnection) Recv() ([]byte, error) {
+	msg, err := c.conn.Recv()
+	if err != nil {
+		return nil, err
+	}
+	if len(msg) < 8+c.recv.Overhead() {
+		return nil, ErrAuthFailed
+	}
+	seq := binary.LittleEndian.Uint64(msg)
+	if !c.fresh(seq) {
+		return nil, ErrReplayed
+	}
+	data, err := c.recv.Open(nil, nonce(seq), msg[8:], msg[:8])
+	if err != nil {
+		return nil, ErrAuthFailed
+	}
+	c.markSeen(seq)
+	return data, nil
+}
+
+// fresh reports whether seq has not been received and is inside the window
+func (c *SecureConnection) fresh(seq uint64) bool {
+	switch {
+	case seq == 0:
+		return false
+	case seq > c.maxSeq:
+		return true
+	case c.maxSeq-seq >= replayWindow:
+		return false
+	default:
+		return c.seen&(1<<(c.maxSeq-seq)) == 0
+	}
+}
+
+// markSeen records an authenticated sequence number
+func (c *SecureConnection) markSeen(seq uint64) {
+	if seq > c.maxSeq {
+		shift := seq - c.maxSeq
+		if shift >= replayWindow {
+			c.seen = 0
+		} else {
+			c.seen <<= shift
+		}
+		c.maxSeq = seq
+	}
+	c.seen |= 1 << (c.maxSeq - seq)
+}
+
+// Close closes the underlying connection
+func (c *SecureConnection) Close() error {
+	return c.conn.Close()
+}
+
+// RemoteAddr returns the remote address
+func (c *SecureConnection) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
@@ -0,0 +1,211 @@
+package network
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// ErrChecksum is returned when a finished download doesn't match its hash
+var ErrChecksum = errors.New("network: transferred asset doesn't match its checksum")
+
+// ErrTransferTooLarge is returned for an asset over protocol.MaxTransferSize
+var ErrTransferTooLarge = errors.New("network: transferred asset too large")
+
+// SplitTransfer cuts an asset into chunks of chunkSize bytes, starting at
+// offset (0 for the whole asset, more to resume a download)
+func SplitTransfer(kind protocol.TransferKind, data []byte, offset, chunkSize int) []protocol.TransferChunk {
+	if chunkSize <= 0 {
+		chunkSize = protocol.TransferChunkSize
+	}
+	hash := sha256.Sum256(data)
+	var chunks []protocol.TransferChunk
+	for off := min(offset, len(data)); off < len(data); off += chunkSize {
+		chunks = append(chunks, protocol.TransferChunk{
+			Kind:   kind,
+			Hash:   hash,
+			Size:   uint32(len(data)),
+			Offset: uint32(off),
+			Data:   data[off:min(off+chunkSize, len(data))],
+		})
+	}
+	return chunks
+}
+
+// AssetCache keeps transferred assets on disk, one file per asset named by
+// its hash. Downloads in progress are kept next to them as .part files, so
+// they survive a disconnect or restart.
+type AssetCache struct {
+	dir string
+}
+
+// NewAssetCache opens a cache in dir, creating it if needed
+func NewAssetCache(dir string) (*AssetCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &AssetCache{dir: dir}, nil
+}
+
+// DefaultAssetCacheDir returns the per-user asset cache directory
+func DefaultAssetCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "rayman-slides", "assets"), nil
+}
+
+func (c *AssetCache) path(hash [32]byte) string {
+	return filepath.Join(c.dir, hex.EncodeToString(hash[:]))
+}
+
+// Get returns a cached asset. A file that no longer matches its hash is
+// removed and reported missing.
+func (c *AssetCache) Get(hash [32]byte) ([]byte, bool) {
+	data, err := os.ReadFile(c.path(hash))
+	if err != nil {
+		return nil, false
+	}
+	if sha256.Sum256(data) != hash {
+		os.Remove(c.path(hash))
+		return nil, false
+	}
+	return data, true
+}
+
+// Put stores an asset under its hash
+func (c *AssetCache) Put(data []byte) ([32]byte, error) {
+	hash := sha256.Sum256(data)
+	tmp := c.path(hash) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return hash, err
+	}
+	return hash, os.Rename(tmp, c.path(hash))
+}
+
+// Download starts or resumes downloading the asset a chunk belongs to. Bytes
+// from an earlier attempt are kept; Offset says where to continue. Assets
+// over protocol.MaxTransferSize are refused with ErrTransferTooLarge.
+func (c *AssetCache) Download(chunk protocol.TransferChunk) (*Download, error) {
+	if chunk.Size > protocol.MaxTransferSize {
+		return nil, ErrTransferTooLarge
+	}
+	part := c.path(chunk.Hash) + ".part"
+	f, err := os.OpenFile(part, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	have, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if have > int64(chunk.Size) {
+		// Not ours: start over
+		if err := f.Truncate(0); err != nil {
+			f.Close()
+			return nil, err
+		}
+		have, _ = f.Seek(0, io.SeekStart)
+	}
+	return &Download{
+		cache: c,
+		kind:  chunk.Kind,
+		hash:  chunk.Hash,
+		size:  int64(chunk.Size),
+		have:  have,
+		part:  f,
+	}, nil
+}
+
+// Download is an asset being received chunk by chunk. Chunks must arrive in
+// order; others are ignored, so duplicates and chunks sent before a resume
+// request took effect are harmless.
+type Download struct {
+	cache *AssetCache
+	kind  protocol.TransferKind
+	hash  [32]byte
+	size  int64
+	have  int64
+	part  *os.File
+}
+
+// Kind returns what the asset is
+func (d *Download) Kind() protocol.TransferKind {
+	return d.kind
+}
+
+// Hash returns the asset's hash, its key in the cache
+func (d *Download) Hash() [32]byte {
+	return d.hash
+}
+
+// Offset returns how many bytes have been received
+func (d *Download) Offset() int {
+	return int(d.have)
+}
+
+// Request returns the request that resumes this download
+func (d *Download) Request() protocol.TransferRequest {
+	return protocol.TransferRequest{Hash: d.hash, Offset: uint32(d.have)}
+}
+
+// Done reports whether the whole asset has arrived
+func (d *Download) Done() bool {
+	return d.have == d.size
+}
+
+// Add writes a chunk and reports whether the download is complete. The
+// finished asset is checked against its hash and moved into the cache; on a
+// mismatch the partial data is dropped and ErrChecksum returned, so the
+// download starts over from offset 0.
+func (d *Download) Add(chunk protocol.TransferChunk) (bool, error) {
+	if chunk.Hash != d.hash || int64(chunk.Offset) != d.have {
+		return d.Done(), nil
+	}
+	if d.have+int64(len(chunk.Data)) > d.size {
+		return false, fmt.Errorf("network: transfer chunk past the end of the asset")
+	}
+	if _, err := d.part.WriteAt(chunk.Data, d.have); err != nil {
+		return false, err
+	}
+	d.have += int64(len(chunk.Data))
+	if !d.Done() {
+		return false, nil
+	}
+	return true, d.finish()
+}
+
+// finish checks the complete asset and moves it into the cache
+func (d *Download) finish() error {
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(d.part, 0, d.size)); err != nil {
+		return err
+	}
+	if [32]byte(h.Sum(nil)) != d.hash {
+		d.have = 0
+		if err := d.part.Truncate(0); err != nil {
+			return err
+		}
+		return ErrChecksum
+	}
+	if err := d.part.Close(); err != nil {
+		return err
+	}
+	return os.Rename(d.part.Name(), d.cache.path(d.hash))
+}
+
+// Close stops the download, keeping what arrived for a later resume
+func (d *Download) Close() error {
+	if d.Done() {
+		return nil // Closed by finish
+	}
+	return d.part.Close()
+}
@@ -0,0 +1,104 @@
+package network
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// TestTransferResume tests that a download interrupted halfway continues
+// from the bytes already on disk and ends up in the cache.
+func TestTransferResume(t *testing.T) {
+	data := make([]byte, 10_000)
+	rand.Read(data)
+	chunks := SplitTransfer(protocol.TransferLevel, data, 0, 1024)
+	if len(chunks) != 10 {
+		t.Fatalf("got %d chunks, want 10", len(chunks))
+	}
+
+	cache, err := NewAssetCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := cache.Download(chunks[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range chunks[:4] {
+		if done, err := d.Add(c); done || err != nil {
+			t.Fatalf("Add = %v, %v", done, err)
+		}
+	}
+	d.Add(chunks[2]) // Duplicates are ignored
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reconnect: the first chunk seen is a fresh push from the start
+	d, err = cache.Download(chunks[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := d.Request()
+	if req.Offset != 4096 {
+		t.Fatalf("resume offset = %d, want 4096", req.Offset)
+	}
+	var done bool
+	for _, c := range SplitTransfer(protocol.TransferLevel, data, int(req.Offset), 1024) {
+		if done, err = d.Add(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !done {
+		t.Fatal("download not done after the last chunk")
+	}
+	got, ok := cache.Get(chunks[0].Hash)
+	if !ok || !bytes.Equal(got, data) {
+		t.Fatalf("cached asset missing or different (ok %v)", ok)
+	}
+}
+
+// TestTransferChecksum tests that corrupted data is rejected and the
+// download starts over.
+func TestTransferChecksum(t *testing.T) {
+	data := bytes.Repeat([]byte("level"), 1000)
+	chunks := SplitTransfer(protocol.TransferAtlas, data, 0, 2048)
+	chunks[1].Data = bytes.ToUpper(chunks[1].Data)
+
+	cache, err := NewAssetCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := cache.Download(chunks[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range chunks {
+		_, err = d.Add(c)
+	}
+	if !errors.Is(err, ErrChecksum) {
+		t.Fatalf("err = %v, want ErrChecksum", err)
+	}
+	if d.Offset() != 0 {
+		t.Errorf("offset = %d after a bad checksum, want 0", d.Offset())
+	}
+	if _, ok := cache.Get(chunks[0].Hash); ok {
+		t.Error("corrupted asset should not be cached")
+	}
+}
+
+// TestTransferTooLarge tests that a chunk claiming an oversized asset is
+// refused before anything is written.
+func TestTransferTooLarge(t *testing.T) {
+	cache, err := NewAssetCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := protocol.TransferChunk{Kind: protocol.TransferLevel, Size: protocol.MaxTransferSize + 1, Data: []byte("x")}
+	if _, err := cache.Download(chunk); !errors.Is(err, ErrTransferTooLarge) {
+		t.Fatalf("err = %v, want ErrTransferTooLarge", err)
+	}
+}
@@ -2,9 +2,17 @@
 package network
 
 import (
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
 	"net"
 )
 
+// maxTCPMessage is the largest message a TCPConnection accepts, so a bad
+// length prefix can't make Recv allocate without bound
+const maxTCPMessage = 1 << 20
+
 // Transport abstracts the network connection
 type Transport interface {
 	// Connect establishes a connection to the server
@@ -32,10 +40,11 @@ type Connection interface {
 	RemoteAddr() net.Addr
 }
 
-// TCPTransport implements Transport over TCP
+// TCPTransport implements Transport over TCP, optionally with TLS
 type TCPTransport struct {
 	listener net.Listener
 	conn     net.Conn
+	tls      *tls.Config // nil for plain TCP
 }
 
 // NewTCPTransport creates a TCP transport
@@ -43,9 +52,21 @@ func NewTCPTransport() *TCPTransport {
 	return &TCPTransport{}
 }
 
+// NewTLSTransport creates a TCP transport that runs TLS with the given
+// config: a certificate to listen, or the server name and roots to connect.
+func NewTLSTransport(config *tls.Config) *TCPTransport {
+	return &TCPTransport{tls: config}
+}
+
 // Listen starts listening on the given address (server)
 func (t *TCPTransport) Listen(addr string) error {
-	ln, err := net.Listen("tcp", addr)
+	var ln net.Listener
+	var err error
+	if t.tls != nil {
+		ln, err = tls.Listen("tcp", addr, t.tls)
+	} else {
+		ln, err = net.Listen("tcp", addr)
+	}
 	if err != nil {
 		return err
 	}
@@ -55,7 +76,13 @@ func (t *TCPTransport) Listen(addr string) error {
 
 // Connect connects to a server (client)
 func (t *TCPTransport) Connect(addr string) error {
-	conn, err := net.Dial("tcp", addr)
+	var conn net.Conn
+	var err error
+	if t.tls != nil {
+		conn, err = tls.Dial("tcp", addr, t.tls)
+	} else {
+		conn, err = net.Dial("tcp", addr)
+	}
 	if err != nil {
 		return err
 	}
@@ -63,6 +90,17 @@ func (t *TCPTransport) Connect(addr string) error {
 	return nil
 }
 
+// Conn returns the connection opened by Connect
+func (t *TCPTransport) Conn() Connection {
+	return &TCPConnection{conn: t.conn}
+}
+
+// Addr returns the address the server listens on, e.g. to find the port
+// after listening on port 0
+func (t *TCPTransport) Addr() net.Addr {
+	return t.listener.Addr()
+}
+
 // Accept accepts a new connection (server)
 func (t *TCPTransport) Accept() (Connection, error) {
 	conn, err := t.listener.Accept()
@@ -83,25 +121,37 @@ func (t *TCPTransport) Close() error {
 	return nil
 }
 
-// TCPConnection wraps a TCP connection
+// TCPConnection wraps a TCP connection. TCP is a stream, so each message
+// is framed with its length:
+//
+//	length u32 | payload
 type TCPConnection struct {
 	conn net.Conn
 }
 
 func (c *TCPConnection) Send(data []byte) error {
-	// TODO: Length prefix for framing
-	_, err := c.conn.Write(data)
+	if len(data) > maxTCPMessage {
+		return fmt.Errorf("network: %d byte message exceeds %d", len(data), maxTCPMessage)
+	}
+	frame := binary.LittleEndian.AppendUint32(make([]byte, 0, 4+len(data)), uint32(len(data)))
+	_, err := c.conn.Write(append(frame, data...))
 	return err
 }
 
 func (c *TCPConnection) Recv() ([]byte, error) {
-	// TODO: Read length prefix, then payload
-	buf := make([]byte, 4096)
-	n, err := c.conn.Read(buf)
-	if err != nil {
+	var header [4]byte
+	if _, err := io.ReadFull(c.conn, header[:]); err != nil {
+		return nil, err
+	}
+	n := binary.LittleEndian.Uint32(header[:])
+	if n > maxTCPMessage {
+		return nil, fmt.Errorf("network: %d byte message exceeds %d", n, maxTCPMessage)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(c.conn, buf); err != nil {
 		return nil, err
 	}
-	return buf[:n], nil
+	return buf, nil
 }
 
 func (c *TCPConnection) Close() error {
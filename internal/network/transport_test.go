@@ -0,0 +1,47 @@
+package network
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestTCPFraming tests that messages keep their boundaries over the TCP
+// stream, and that oversized messages are refused.
+func TestTCPFraming(t *testing.T) {
+	server := NewTCPTransport()
+	if err := server.Listen("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	client := NewTCPTransport()
+	if err := client.Connect(server.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	conn, err := server.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	sizes := []int{0, 1, 4096, 100000, maxTCPMessage}
+	for _, size := range sizes {
+		if err := client.Conn().Send(bytes.Repeat([]byte{byte(size)}, size)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, size := range sizes {
+		got, err := conn.Recv()
+		if err != nil {
+			t.Fatalf("recv %d bytes: %v", size, err)
+		}
+		if !bytes.Equal(got, bytes.Repeat([]byte{byte(size)}, size)) {
+			t.Fatalf("got %d bytes, want %d", len(got), size)
+		}
+	}
+
+	if err := client.Conn().Send(make([]byte, maxTCPMessage+1)); err == nil {
+		t.Error("Send accepted a message over the limit")
+	}
+}
@@ -0,0 +1,376 @@
+//go:build !js
+
+package network
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// WebSocket framing limits
+const (
+	maxWebSocketMessage = 1 << 20 // Bytes in one message; a level transfer chunk is far smaller
+	webSocketGUID       = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+)
+
+// WebSocket opcodes (RFC 6455 section 5.2)
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// ErrMessageTooLarge is returned by Recv for a message over the size limit
+var ErrMessageTooLarge = errors.New("websocket message too large")
+
+// WebSocketTransport implements Transport over WebSockets, for browser
+// clients that can't open TCP or UDP sockets. Every Send is one binary
+// message, so unlike TCP no length prefix is needed. The server accepts
+// upgrades on any path.
+type WebSocketTransport struct {
+	tls      *tls.Config // nil for ws://
+	listener net.Listener
+	server   *http.Server
+	accepted chan *WebSocketConnection
+	closed   chan struct{}
+	once     sync.Once
+	conn     *WebSocketConnection // Client side, after Connect
+}
+
+// NewWebSocketTransport creates a WebSocket transport. config is the
+// certificate to listen with wss://, or the roots to connect to a wss://
+// URL with; nil listens on plain ws:// and connects with the system roots.
+func NewWebSocketTransport(config *tls.Config) *WebSocketTransport {
+	return &WebSocketTransport{
+		tls:      config,
+		accepted: make(chan *WebSocketConnection, 8),
+		closed:   make(chan struct{}),
+	}
+}
+
+// Listen starts serving WebSocket upgrades on the given address (server)
+func (t *WebSocketTransport) Listen(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	if t.tls != nil {
+		ln = tls.NewListener(ln, t.tls)
+	}
+	t.listener = ln
+	t.server = &http.Server{Handler: http.HandlerFunc(t.upgrade)}
+	go t.server.Serve(ln)
+	return nil
+}
+
+// Addr returns the address the server listens on, e.g. for ":0"
+func (t *WebSocketTransport) Addr() net.Addr {
+	return t.listener.Addr()
+}
+
+// upgrade completes the opening handshake and queues the connection for
+// Accept
+func (t *WebSocketTransport) upgrade(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || !headerHas(r.Header, "Connection", "upgrade") ||
+		!headerHas(r.Header, "Upgrade", "websocket") || key == "" {
+		http.Error(w, "websocket upgrade required", http.StatusUpgradeRequired)
+		return
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket upgrade unavailable", http.StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", acceptKey(key))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return
+	}
+	ws := &WebSocketConnection{conn: conn, r: rw.Reader}
+	select {
+	case t.accepted <- ws:
+	case <-t.closed:
+		ws.Close()
+	}
+}
+
+// Connect opens a WebSocket to a ws:// or wss:// URL, or to host:port
+// over ws:// (client)
+func (t *WebSocketTransport) Connect(addr string) error {
+	if !strings.Contains(addr, "://") {
+		addr = "ws://" + addr + "/"
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return err
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), map[string]string{"ws": "80", "wss": "443"}[u.Scheme])
+	}
+
+	var conn net.Conn
+	switch u.Scheme {
+	case "ws":
+		conn, err = net.Dial("tcp", host)
+	case "wss":
+		config := t.tls
+		if config == nil {
+			config = &tls.Config{ServerName: u.Hostname()}
+		}
+		conn, err = tls.Dial("tcp", host, config)
+	default:
+		return fmt.Errorf("websocket: unsupported scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+	req := &http.Request{
+		Method: http.MethodGet,
+		URL:    u,
+		Host:   u.Host,
+		Header: http.Header{
+			"Upgrade":               {"websocket"},
+			"Connection":            {"Upgrade"},
+			"Sec-WebSocket-Key":     {key},
+			"Sec-WebSocket-Version": {"13"},
+		},
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return err
+	}
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		conn.Close()
+		return fmt.Errorf("websocket: upgrade refused: %s", resp.Status)
+	}
+	t.conn = &WebSocketConnection{conn: conn, r: r, client: true}
+	return nil
+}
+
+// Conn returns the connection opened by Connect
+func (t *WebSocketTransport) Conn() *WebSocketConnection {
+	return t.conn
+}
+
+// Accept waits for the next upgraded connection (server)
+func (t *WebSocketTransport) Accept() (Connection, error) {
+	select {
+	case ws := <-t.accepted:
+		return ws, nil
+	case <-t.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close stops the server, or closes the client's connection
+func (t *WebSocketTransport) Close() error {
+	t.once.Do(func() { close(t.closed) })
+	if t.server != nil {
+		return t.server.Close()
+	}
+	if t.conn != nil {
+		return t.conn.Close()
+	}
+	return nil
+}
+
+// WebSocketConnection is one WebSocket. Send is safe to call alongside
+// Recv; Recv answers pings and the closing handshake itself.
+type WebSocketConnection struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	client bool // Clients mask what they send, servers must not
+
+	wmu sync.Mutex
+}
+
+// Send sends data as one binary message
+func (c *WebSocketConnection) Send(data []byte) error {
+	return c.writeFrame(opBinary, data)
+}
+
+// Recv returns the next message, reassembling fragments; it returns
+// io.EOF once the peer closes the connection
+func (c *WebSocketConnection) Recv() ([]byte, error) {
+	var msg []byte
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+		case opPong:
+		case opClose:
+			c.writeFrame(opClose, payload) // Echo the status, best effort
+			c.conn.Close()
+			return nil, io.EOF
+		case opText, opBinary, opContinuation:
+			if len(msg)+len(payload) > maxWebSocketMessage {
+				return nil, ErrMessageTooLarge
+			}
+			msg = append(msg, payload...)
+			if fin {
+				return msg, nil
+			}
+		default:
+			return nil, fmt.Errorf("websocket: unknown opcode %#x", op)
+		}
+	}
+}
+
+// Close sends a close frame and closes the connection
+func (c *WebSocketConnection) Close() error {
+	c.writeFrame(opClose, []byte{0x03, 0xE8}) // 1000: normal closure
+	return c.conn.Close()
+}
+
+// RemoteAddr returns the peer's address
+func (c *WebSocketConnection) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// readFrame reads one frame and unmasks its payload
+func (c *WebSocketConnection) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.r, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin, op = head[0]&0x80 != 0, head[0]&0x0F
+	masked := head[1]&0x80 != 0
+	if masked == c.client {
+		return false, 0, nil, errors.New("websocket: wrong frame masking")
+	}
+
+	n := uint64(head[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > maxWebSocketMessage {
+		return false, 0, nil, ErrMessageTooLarge
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.r, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, op, payload, nil
+}
+
+// writeFrame writes one unfragmented frame, masked from clients
+func (c *WebSocketConnection) writeFrame(op byte, payload []byte) error {
+	frame := make([]byte, 0, 14+len(payload))
+	frame = append(frame, 0x80|op)
+	maskBit := byte(0)
+	if c.client {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	if c.client {
+		var mask [4]byte
+		rand.Read(mask[:])
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		for i := range payload {
+			frame[start+i] ^= mask[i%4]
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// acceptKey is the Sec-WebSocket-Accept answer to a handshake key
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + webSocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerHas reports whether a comma-separated header lists token,
+// ignoring case
+func headerHas(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, part := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+package network
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"syscall/js"
+)
+
+// ErrNoListen is returned when a browser build is asked to serve
+var ErrNoListen = errors.New("websocket: browsers can't listen")
+
+// WebSocketTransport implements Transport over the browser's WebSocket
+// API. It can only connect; the TLS config is ignored, since the browser
+// checks wss:// certificates itself.
+type WebSocketTransport struct {
+	conn *WebSocketConnection
+}
+
+// NewWebSocketTransport creates a WebSocket transport
+func NewWebSocketTransport(config *tls.Config) *WebSocketTransport {
+	return &WebSocketTransport{}
+}
+
+// Listen is not possible in a browser
+func (t *WebSocketTransport) Listen(addr string) error {
+	return ErrNoListen
+}
+
+// Accept is not possible in a browser
+func (t *WebSocketTransport) Accept() (Connection, error) {
+	return nil, ErrNoListen
+}
+
+// Connect opens a WebSocket to a ws:// or wss:// URL, or to host:port
+// over ws://, and waits until it is open
+func (t *WebSocketTransport) Connect(addr string) error {
+	if !strings.Contains(addr, "://") {
+		addr = "ws://" + addr + "/"
+	}
+	c := &WebSocketConnection{
+		url:    addr,
+		ready:  make(chan struct{}, 1),
+		closed: make(chan struct{}),
+	}
+	opened := make(chan error, 1)
+
+	ws := js.Global().Get("WebSocket").New(addr)
+	ws.Set("binaryType", "arraybuffer")
+	c.ws = ws
+	c.funcs = []js.Func{
+		js.FuncOf(func(this js.Value, args []js.Value) any {
+			select {
+			case opened <- nil:
+			default:
+			}
+			return nil
+		}),
+		js.FuncOf(func(this js.Value, args []js.Value) any {
+			data := js.Global().Get("Uint8Array").New(args[0].Get("data"))
+			msg := make([]byte, data.Get("length").Int())
+			js.CopyBytesToGo(msg, data)
+			c.mu.Lock()
+			c.queue = append(c.queue, msg)
+			c.mu.Unlock()
+			select {
+			case c.ready <- struct{}{}:
+			default:
+			}
+			return nil
+		}),
+		js.FuncOf(func(this js.Value, args []js.Value) any {
+			select {
+			case opened <- errors.New("websocket: cannot connect to " + addr):
+			default:
+			}
+			return nil
+		}),
+		js.FuncOf(func(this js.Value, args []js.Value) any {
+			c.shut()
+			return nil
+		}),
+	}
+	ws.Set("onopen", c.funcs[0])
+	ws.Set("onmessage", c.funcs[1])
+	ws.Set("onerror", c.funcs[2])
+	ws.Set("onclose", c.funcs[3])
+
+	if err := <-opened; err != nil {
+		c.Close()
+		return err
+	}
+	t.conn = c
+	return nil
+}
+
+// Conn returns the connection opened by Connect
+func (t *WebSocketTransport) Conn() *WebSocketConnection {
+	return t.conn
+}
+
+// Close closes the connection
+func (t *WebSocketTransport) Close() error {
+	if t.conn != nil {
+		return t.conn.Close()
+	}
+	return nil
+}
+
+// WebSocketConnection is one browser WebSocket. Messages are queued by the
+// browser's event loop, which must never block, and read with Recv.
+type WebSocketConnection struct {
+	url    string
+	ws     js.Value
+	funcs  []js.Func // Event handlers, released on close
+	ready  chan struct{}
+	closed chan struct{}
+	once   sync.Once
+
+	mu    sync.Mutex
+	queue [][]byte // Received, not yet read
+}
+
+// Send sends data as one binary message
+func (c *WebSocketConnection) Send(data []byte) error {
+	select {
+	case <-c.closed:
+		return net.ErrClosed
+	default:
+	}
+	buf := js.Global().Get("Uint8Array").New(len(data))
+	js.CopyBytesToJS(buf, data)
+	c.ws.Call("send", buf)
+	return nil
+}
+
+// Recv returns the next message; it returns io.EOF once the connection
+// has closed and every message received before is read
+func (c *WebSocketConnection) Recv() ([]byte, error) {
+	for {
+		c.mu.Lock()
+		if len(c.queue) > 0 {
+			msg := c.queue[0]
+			c.queue = c.queue[1:]
+			c.mu.Unlock()
+			return msg, nil
+		}
+		c.mu.Unlock()
+		select {
+		case <-c.ready:
+		case <-c.closed:
+			c.mu.Lock()
+			empty := len(c.queue) == 0
+			c.mu.Unlock()
+			if empty {
+				return nil, io.EOF
+			}
+		}
+	}
+}
+
+// Close closes the WebSocket
+func (c *WebSocketConnection) Close() error {
+	c.ws.Call("close")
+	c.shut()
+	return nil
+}
+
+// RemoteAddr returns the URL connected to
+func (c *WebSocketConnection) RemoteAddr() net.Addr {
+	return webSocketAddr(c.url)
+}
+
+// shut marks the connection closed and releases the event handlers
+func (c *WebSocketConnection) shut() {
+	c.once.Do(func() {
+		close(c.closed)
+		for _, f := range c.funcs {
+			f.Release()
+		}
+	})
+}
+
+// webSocketAddr is a WebSocket URL as a net.Addr
+type webSocketAddr string
+
+func (a webSocketAddr) Network() string { return "websocket" }
+func (a webSocketAddr) String() string  { return string(a) }
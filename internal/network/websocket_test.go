@@ -0,0 +1,75 @@
+//go:build !js
+
+package network
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+)
+
+// TestWebSocketRoundTrip tests messages of every length encoding both ways,
+// and that closing reaches the other side as io.EOF.
+func TestWebSocketRoundTrip(t *testing.T) {
+	server := NewWebSocketTransport(nil)
+	if err := server.Listen("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	client := NewWebSocketTransport(nil)
+	if err := client.Connect("ws://" + server.Addr().String() + "/play"); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	conn, err := server.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, size := range []int{0, 5, 125, 126, 65535, 65536, 300000} {
+		msg := bytes.Repeat([]byte{byte(size)}, size)
+		if err := client.Conn().Send(msg); err != nil {
+			t.Fatal(err)
+		}
+		got, err := conn.Recv()
+		if err != nil {
+			t.Fatalf("server recv %d bytes: %v", size, err)
+		}
+		if !bytes.Equal(got, msg) {
+			t.Fatalf("server got %d bytes, want %d", len(got), size)
+		}
+		if err := conn.Send(msg); err != nil {
+			t.Fatal(err)
+		}
+		if got, err = client.Conn().Recv(); err != nil || !bytes.Equal(got, msg) {
+			t.Fatalf("client got %d bytes (%v), want %d", len(got), err, size)
+		}
+	}
+
+	conn.Close()
+	if _, err := client.Conn().Recv(); !errors.Is(err, io.EOF) {
+		t.Errorf("recv after close = %v, want EOF", err)
+	}
+}
+
+// TestWebSocketRejectsPlainHTTP tests that requests without an upgrade are
+// refused rather than handed to Accept.
+func TestWebSocketRejectsPlainHTTP(t *testing.T) {
+	server := NewWebSocketTransport(nil)
+	if err := server.Listen("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	resp, err := http.Get("http://" + server.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUpgradeRequired {
+		t.Errorf("status %d, want %d", resp.StatusCode, http.StatusUpgradeRequired)
+	}
+}
@@ -0,0 +1,545 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Wire format: all integers are little-endian. Strings are a uint8 length
+// followed by UTF-8 bytes (truncated to 255 bytes).
+
+var (
+	// ErrShortBuffer is returned when a message is truncated
+	ErrShortBuffer = errors.New("protocol: short buffer")
+)
+
+const inputFlagAxis = 1 << 0
+
+// AppendInputFrame encodes an InputFrame:
+//
+//	tick u64 | intents u16 | flags u8 | [axis x i8 | axis y i8]
+func AppendInputFrame(dst []byte, f InputFrame) []byte {
+	dst = binary.LittleEndian.AppendUint64(dst, f.Tick)
+	dst = binary.LittleEndian.AppendUint16(dst, uint16(f.Intents))
+	if f.Axis == nil {
+		return append(dst, 0)
+	}
+	return append(dst, inputFlagAxis, byte(f.Axis.X), byte(f.Axis.Y))
+}
+
+// DecodeInputFrame decodes an InputFrame and returns the bytes consumed
+func DecodeInputFrame(src []byte) (InputFrame, int, error) {
+	if len(src) < 11 {
+		return InputFrame{}, 0, ErrShortBuffer
+	}
+	f := InputFrame{
+		Tick:    binary.LittleEndian.Uint64(src),
+		Intents: Intent(binary.LittleEndian.Uint16(src[8:])),
+	}
+	n := 11
+	if src[10]&inputFlagAxis != 0 {
+		if len(src) < n+2 {
+			return InputFrame{}, 0, ErrShortBuffer
+		}
+		f.Axis = &AnalogAxis{X: int8(src[n]), Y: int8(src[n+1])}
+		n += 2
+	}
+	return f, n, nil
+}
+
+// AppendInputMessage encodes an InputMessage:
+//
+//	count u8 | count × InputFrame
+//
+// At most 255 frames are encoded; the newest ones are kept.
+func AppendInputMessage(dst []byte, m InputMessage) []byte {
+	frames := m.Frames
+	if len(frames) > 255 {
+		frames = frames[len(frames)-255:]
+	}
+	dst = append(dst, byte(len(frames)))
+	for _, f := range frames {
+		dst = AppendInputFrame(dst, f)
+	}
+	return dst
+}
+
+// DecodeInputMessage decodes an InputMessage and returns the bytes consumed
+func DecodeInputMessage(src []byte) (InputMessage, int, error) {
+	if len(src) < 1 {
+		return InputMessage{}, 0, ErrShortBuffer
+	}
+	count := int(src[0])
+	m := InputMessage{Frames: make([]InputFrame, 0, count)}
+	n := 1
+	for range count {
+		f, fn, err := DecodeInputFrame(src[n:])
+		if err != nil {
+			return InputMessage{}, 0, err
+		}
+		m.Frames = append(m.Frames, f)
+		n += fn
+	}
+	return m, n, nil
+}
+
+// AppendInputAck encodes an InputAck:
+//
+//	last processed tick u64 | missed u32
+func AppendInputAck(dst []byte, a InputAck) []byte {
+	dst = binary.LittleEndian.AppendUint64(dst, a.LastProcessedTick)
+	return binary.LittleEndian.AppendUint32(dst, a.Missed)
+}
+
+// DecodeInputAck decodes an InputAck and returns the bytes consumed
+func DecodeInputAck(src []byte) (InputAck, int, error) {
+	if len(src) < 12 {
+		return InputAck{}, 0, ErrShortBuffer
+	}
+	return InputAck{
+		LastProcessedTick: binary.LittleEndian.Uint64(src),
+		Missed:            binary.LittleEndian.Uint32(src[8:]),
+	}, 12, nil
+}
+
+// AppendHandshake encodes a Handshake:
+//
+//	version u16 | min version u16 | name string | level hash [32]u8 |
+//	physics hash [32]u8 | aim assist u8 | build string
+//
+// The version comes first so any future layout can still be rejected cleanly.
+// Handshakes before version 7 end after the name, before version 9 after the
+// level hash, before version 10 after the physics hash and before version 11
+// after the aim assist; missing fields decode as zero.
+func AppendHandshake(dst []byte, h Handshake) []byte {
+	dst = binary.LittleEndian.AppendUint16(dst, uint16(h.Version))
+	dst = binary.LittleEndian.AppendUint16(dst, uint16(h.MinVersion))
+	dst = appendString(dst, h.PlayerName)
+	dst = append(dst, h.LevelHash[:]...)
+	dst = append(dst, h.PhysicsHash[:]...)
+	dst = append(dst, h.AimAssist)
+	return appendString(dst, h.Build)
+}
+
+// DecodeHandshake decodes a Handshake and returns the bytes consumed
+func DecodeHandshake(src []byte) (Handshake, int, error) {
+	if len(src) < 4 {
+		return Handshake{}, 0, ErrShortBuffer
+	}
+	h := Handshake{
+		Version:    int(binary.LittleEndian.Uint16(src)),
+		MinVersion: int(binary.LittleEndian.Uint16(src[2:])),
+	}
+	name, n, err := decodeString(src[4:])
+	if err != nil {
+		return Handshake{}, 0, err
+	}
+	h.PlayerName = name
+	n += 4
+	if h.Version < 7 {
+		return h, n, nil
+	}
+	if len(src) < n+32 {
+		return Handshake{}, 0, ErrShortBuffer
+	}
+	copy(h.LevelHash[:], src[n:])
+	n += 32
+	if h.Version < 9 {
+		return h, n, nil
+	}
+	if len(src) < n+32 {
+		return Handshake{}, 0, ErrShortBuffer
+	}
+	copy(h.PhysicsHash[:], src[n:])
+	n += 32
+	if h.Version < 10 {
+		return h, n, nil
+	}
+	if len(src) < n+1 {
+		return Handshake{}, 0, ErrShortBuffer
+	}
+	h.AimAssist = src[n]
+	n++
+	if h.Version < 11 {
+		return h, n, nil
+	}
+	build, m, err := decodeString(src[n:])
+	if err != nil {
+		return Handshake{}, 0, err
+	}
+	h.Build = build
+	return h, n + m, nil
+}
+
+// AppendHandshakeReply encodes a HandshakeReply:
+//
+//	accepted u8 | version u16 | reason string [| player id u32 | color u32]
+//
+// Only accepted replies carry the player, so a rejection still decodes with
+// the layout of older versions.
+func AppendHandshakeReply(dst []byte, r HandshakeReply) []byte {
+	accepted := byte(0)
+	if r.Accepted {
+		accepted = 1
+	}
+	dst = append(dst, accepted)
+	dst = binary.LittleEndian.AppendUint16(dst, uint16(r.Version))
+	dst = appendString(dst, r.Reason)
+	if r.Accepted {
+		dst = binary.LittleEndian.AppendUint32(dst, uint32(r.PlayerID))
+		dst = binary.LittleEndian.AppendUint32(dst, r.Color)
+	}
+	return dst
+}
+
+// DecodeHandshakeReply decodes a HandshakeReply and returns the bytes consumed
+func DecodeHandshakeReply(src []byte) (HandshakeReply, int, error) {
+	if len(src) < 3 {
+		return HandshakeReply{}, 0, ErrShortBuffer
+	}
+	r := HandshakeReply{
+		Accepted: src[0] != 0,
+		Version:  int(binary.LittleEndian.Uint16(src[1:])),
+	}
+	reason, n, err := decodeString(src[3:])
+	if err != nil {
+		return HandshakeReply{}, 0, err
+	}
+	r.Reason = reason
+	n += 3
+	if r.Accepted {
+		if len(src) < n+8 {
+			return HandshakeReply{}, 0, ErrShortBuffer
+		}
+		r.PlayerID = int(binary.LittleEndian.Uint32(src[n:]))
+		r.Color = binary.LittleEndian.Uint32(src[n+4:])
+		n += 8
+	}
+	return r, n, nil
+}
+
+// AppendDisconnect encodes a Disconnect:
+//
+//	reason string
+func AppendDisconnect(dst []byte, d Disconnect) []byte {
+	return appendString(dst, d.Reason)
+}
+
+// DecodeDisconnect decodes a Disconnect and returns the bytes consumed
+func DecodeDisconnect(src []byte) (Disconnect, int, error) {
+	reason, n, err := decodeString(src)
+	if err != nil {
+		return Disconnect{}, 0, err
+	}
+	return Disconnect{Reason: reason}, n, nil
+}
+
+// AppendJoinBundle encodes a JoinBundle:
+//
+//	level string | level hash [32]u8 | level data u32 length + bytes |
+//	mode string | tick u64 | level start u64 | elapsed u64 |
+//	stats count u8 | count × (id u32 | name string | orbs u32 | cages u32 |
+//	damage u32 | damage taken u32 | deaths u32 | finish ticks u64 |
+//	combo u32 | combo end u64 | best combo u32) |
+//	has result u8 [| mode string | winners count u8 + count × u32 | reason string]
+//
+// At most 255 players' stats are encoded. The stats follow the negotiated
+// version: damage taken is left out before version 16 and the combo
+// before version 17.
+func AppendJoinBundle(dst []byte, b JoinBundle, version int) []byte {
+	dst = appendString(dst, b.Level)
+	dst = append(dst, b.LevelHash[:]...)
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(len(b.LevelData)))
+	dst = append(dst, b.LevelData...)
+	dst = appendString(dst, b.Mode)
+	dst = binary.LittleEndian.AppendUint64(dst, b.Tick)
+	dst = binary.LittleEndian.AppendUint64(dst, b.LevelStart)
+	dst = binary.LittleEndian.AppendUint64(dst, b.Elapsed)
+
+	stats := b.Stats[:min(len(b.Stats), 255)]
+	dst = append(dst, byte(len(stats)))
+	for _, ps := range stats {
+		dst = binary.LittleEndian.AppendUint32(dst, uint32(ps.PlayerID))
+		dst = appendString(dst, ps.Name)
+		dst = binary.LittleEndian.AppendUint32(dst, uint32(ps.Orbs))
+		dst = binary.LittleEndian.AppendUint32(dst, uint32(ps.Cages))
+		dst = binary.LittleEndian.AppendUint32(dst, uint32(ps.Damage))
+		if version >= 16 {
+			dst = binary.LittleEndian.AppendUint32(dst, uint32(ps.DamageTaken))
+		}
+		dst = binary.LittleEndian.AppendUint32(dst, uint32(ps.Deaths))
+		dst = binary.LittleEndian.AppendUint64(dst, ps.FinishTicks)
+		if version >= 17 {
+			dst = binary.LittleEndian.AppendUint32(dst, uint32(ps.Combo))
+			dst = binary.LittleEndian.AppendUint64(dst, ps.ComboEnd)
+			dst = binary.LittleEndian.AppendUint32(dst, uint32(ps.BestCombo))
+		}
+	}
+
+	if b.Result == nil {
+		return append(dst, 0)
+	}
+	dst = append(dst, 1)
+	dst = appendString(dst, b.Result.Mode)
+	winners := b.Result.Winners[:min(len(b.Result.Winners), 255)]
+	dst = append(dst, byte(len(winners)))
+	for _, id := range winners {
+		dst = binary.LittleEndian.AppendUint32(dst, uint32(id))
+	}
+	return appendString(dst, b.Result.Reason)
+}
+
+// DecodeJoinBundle decodes a JoinBundle encoded for the negotiated version
+// and returns the bytes consumed
+func DecodeJoinBundle(src []byte, version int) (JoinBundle, int, error) {
+	var b JoinBundle
+	level, n, err := decodeString(src)
+	if err != nil {
+		return JoinBundle{}, 0, err
+	}
+	b.Level = level
+	if len(src) < n+32+4 {
+		return JoinBundle{}, 0, ErrShortBuffer
+	}
+	copy(b.LevelHash[:], src[n:])
+	n += 32
+	size := int(binary.LittleEndian.Uint32(src[n:]))
+	n += 4
+	if len(src)-n < size {
+		return JoinBundle{}, 0, ErrShortBuffer
+	}
+	if size > 0 {
+		b.LevelData = append([]byte(nil), src[n:n+size]...)
+	}
+	n += size
+
+	mode, sn, err := decodeString(src[n:])
+	if err != nil {
+		return JoinBundle{}, 0, err
+	}
+	b.Mode = mode
+	n += sn
+	if len(src) < n+24+1 {
+		return JoinBundle{}, 0, ErrShortBuffer
+	}
+	b.Tick = binary.LittleEndian.Uint64(src[n:])
+	b.LevelStart = binary.LittleEndian.Uint64(src[n+8:])
+	b.Elapsed = binary.LittleEndian.Uint64(src[n+16:])
+	n += 24
+
+	count := int(src[n])
+	n++
+	b.Stats = make([]PlayerStats, 0, count)
+	for range count {
+		if len(src) < n+4 {
+			return JoinBundle{}, 0, ErrShortBuffer
+		}
+		ps := PlayerStats{PlayerID: int(binary.LittleEndian.Uint32(src[n:]))}
+		name, sn, err := decodeString(src[n+4:])
+		if err != nil {
+			return JoinBundle{}, 0, err
+		}
+		ps.Name = name
+		n += 4 + sn
+		size := 24
+		if version >= 16 {
+			size += 4
+		}
+		if version >= 17 {
+			size += 16
+		}
+		if len(src) < n+size {
+			return JoinBundle{}, 0, ErrShortBuffer
+		}
+		ps.Orbs = int(binary.LittleEndian.Uint32(src[n:]))
+		ps.Cages = int(binary.LittleEndian.Uint32(src[n+4:]))
+		ps.Damage = int(binary.LittleEndian.Uint32(src[n+8:]))
+		n += 12
+		if version >= 16 {
+			ps.DamageTaken = int(binary.LittleEndian.Uint32(src[n:]))
+			n += 4
+		}
+		ps.Deaths = int(binary.LittleEndian.Uint32(src[n:]))
+		ps.FinishTicks = binary.LittleEndian.Uint64(src[n+4:])
+		n += 12
+		if version >= 17 {
+			ps.Combo = int(binary.LittleEndian.Uint32(src[n:]))
+			ps.ComboEnd = binary.LittleEndian.Uint64(src[n+4:])
+			ps.BestCombo = int(binary.LittleEndian.Uint32(src[n+12:]))
+			n += 16
+		}
+		b.Stats = append(b.Stats, ps)
+	}
+
+	if len(src) < n+1 {
+		return JoinBundle{}, 0, ErrShortBuffer
+	}
+	hasResult := src[n] != 0
+	n++
+	if !hasResult {
+		return b, n, nil
+	}
+	result := &MatchResult{}
+	mode, sn, err = decodeString(src[n:])
+	if err != nil {
+		return JoinBundle{}, 0, err
+	}
+	result.Mode = mode
+	n += sn
+	if len(src) < n+1 {
+		return JoinBundle{}, 0, ErrShortBuffer
+	}
+	winners := int(src[n])
+	n++
+	if len(src) < n+4*winners {
+		return JoinBundle{}, 0, ErrShortBuffer
+	}
+	for range winners {
+		result.Winners = append(result.Winners, int(binary.LittleEndian.Uint32(src[n:])))
+		n += 4
+	}
+	reason, sn, err := decodeString(src[n:])
+	if err != nil {
+		return JoinBundle{}, 0, err
+	}
+	result.Reason = reason
+	b.Result = result
+	return b, n + sn, nil
+}
+
+// AppendTransferChunk encodes a TransferChunk:
+//
+//	kind u8 | hash [32]u8 | size u32 | offset u32 | data u32 length + bytes
+func AppendTransferChunk(dst []byte, c TransferChunk) []byte {
+	dst = append(dst, byte(c.Kind))
+	dst = append(dst, c.Hash[:]...)
+	dst = binary.LittleEndian.AppendUint32(dst, c.Size)
+	dst = binary.LittleEndian.AppendUint32(dst, c.Offset)
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(len(c.Data)))
+	return append(dst, c.Data...)
+}
+
+// DecodeTransferChunk decodes a TransferChunk and returns the bytes
+// consumed. Data is copied out of src.
+func DecodeTransferChunk(src []byte) (TransferChunk, int, error) {
+	const header = 1 + 32 + 4 + 4 + 4
+	if len(src) < header {
+		return TransferChunk{}, 0, ErrShortBuffer
+	}
+	c := TransferChunk{
+		Kind:   TransferKind(src[0]),
+		Size:   binary.LittleEndian.Uint32(src[33:]),
+		Offset: binary.LittleEndian.Uint32(src[37:]),
+	}
+	copy(c.Hash[:], src[1:])
+	size := int(binary.LittleEndian.Uint32(src[41:]))
+	if len(src)-header < size {
+		return TransferChunk{}, 0, ErrShortBuffer
+	}
+	c.Data = append([]byte(nil), src[header:header+size]...)
+	return c, header + size, nil
+}
+
+// AppendTransferRequest encodes a TransferRequest:
+//
+//	hash [32]u8 | offset u32
+func AppendTransferRequest(dst []byte, r TransferRequest) []byte {
+	dst = append(dst, r.Hash[:]...)
+	return binary.LittleEndian.AppendUint32(dst, r.Offset)
+}
+
+// DecodeTransferRequest decodes a TransferRequest and returns the bytes
+// consumed
+func DecodeTransferRequest(src []byte) (TransferRequest, int, error) {
+	if len(src) < 36 {
+		return TransferRequest{}, 0, ErrShortBuffer
+	}
+	var r TransferRequest
+	copy(r.Hash[:], src)
+	r.Offset = binary.LittleEndian.Uint32(src[32:])
+	return r, 36, nil
+}
+
+// AppendHostCommand encodes a HostCommand:
+//
+//	action u8 | target u32 | value u8
+func AppendHostCommand(dst []byte, c HostCommand) []byte {
+	dst = append(dst, byte(c.Action))
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(c.Target))
+	return append(dst, c.Value)
+}
+
+// DecodeHostCommand decodes a HostCommand and returns the bytes consumed
+func DecodeHostCommand(src []byte) (HostCommand, int, error) {
+	if len(src) < 6 {
+		return HostCommand{}, 0, ErrShortBuffer
+	}
+	return HostCommand{
+		Action: HostAction(src[0]),
+		Target: int(binary.LittleEndian.Uint32(src[1:])),
+		Value:  src[5],
+	}, 6, nil
+}
+
+// AppendHostResult encodes a HostResult:
+//
+//	action u8 | ok u8 | reason string
+func AppendHostResult(dst []byte, r HostResult) []byte {
+	ok := byte(0)
+	if r.OK {
+		ok = 1
+	}
+	dst = append(dst, byte(r.Action), ok)
+	return appendString(dst, r.Reason)
+}
+
+// DecodeHostResult decodes a HostResult and returns the bytes consumed
+func DecodeHostResult(src []byte) (HostResult, int, error) {
+	if len(src) < 2 {
+		return HostResult{}, 0, ErrShortBuffer
+	}
+	reason, n, err := decodeString(src[2:])
+	if err != nil {
+		return HostResult{}, 0, err
+	}
+	return HostResult{Action: HostAction(src[0]), OK: src[1] != 0, Reason: reason}, 2 + n, nil
+}
+
+// AppendChat encodes a Chat:
+//
+//	player u32 | text string
+func AppendChat(dst []byte, c Chat) []byte {
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(c.PlayerID))
+	return appendString(dst, c.Text)
+}
+
+// DecodeChat decodes a Chat and returns the bytes consumed
+func DecodeChat(src []byte) (Chat, int, error) {
+	if len(src) < 4 {
+		return Chat{}, 0, ErrShortBuffer
+	}
+	text, n, err := decodeString(src[4:])
+	if err != nil {
+		return Chat{}, 0, err
+	}
+	return Chat{PlayerID: int(binary.LittleEndian.Uint32(src)), Text: text}, 4 + n, nil
+}
+
+func appendString(dst []byte, s string) []byte {
+	if len(s) > 255 {
+		s = s[:255]
+	}
+	dst = append(dst, byte(len(s)))
+	return append(dst, s...)
+}
+
+func decodeString(src []byte) (string, int, error) {
+	if len(src) < 1 {
+		return "", 0, ErrShortBuffer
+	}
+	n := int(src[0])
+	if len(src) < 1+n {
+		return "", 0, ErrShortBuffer
+	}
+	return string(src[1 : 1+n]), 1 + n, nil
+}
@@ -0,0 +1,265 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestInputMessageRoundTrip tests frames with and without an analog axis,
+// truncated messages and the 255-frame cap.
+func TestInputMessageRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames []InputFrame
+		size   int
+	}{
+		{"empty", nil, 1},
+		{"digital", []InputFrame{{Tick: 1 << 40, Intents: IntentPound | IntentLeft}}, 1 + 11},
+		{"analog", []InputFrame{
+			{Tick: 7, Intents: IntentJump, Axis: &AnalogAxis{X: -127, Y: 127}},
+			{Tick: 8, Intents: IntentMenu},
+		}, 1 + 13 + 11},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := AppendInputMessage(nil, InputMessage{Frames: tc.frames})
+			if len(buf) != tc.size {
+				t.Fatalf("encoded %d bytes, want %d", len(buf), tc.size)
+			}
+			got, n, err := DecodeInputMessage(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != len(buf) || len(got.Frames) != len(tc.frames) || (len(tc.frames) > 0 && !reflect.DeepEqual(got.Frames, tc.frames)) {
+				t.Errorf("decoded %+v (%d bytes), want %+v", got.Frames, n, tc.frames)
+			}
+			if len(buf) > 1 {
+				if _, _, err := DecodeInputMessage(buf[:len(buf)-1]); err == nil {
+					t.Error("truncated message should fail to decode")
+				}
+			}
+		})
+	}
+
+	frames := make([]InputFrame, 300)
+	for i := range frames {
+		frames[i].Tick = uint64(i)
+	}
+	got, _, err := DecodeInputMessage(AppendInputMessage(nil, InputMessage{Frames: frames}))
+	if err != nil || len(got.Frames) != 255 || got.Frames[0].Tick != 45 {
+		t.Errorf("300 frames decoded as %d from tick %d (%v), want the newest 255", len(got.Frames), got.Frames[0].Tick, err)
+	}
+}
+
+func TestInputAckRoundTrip(t *testing.T) {
+	ack := InputAck{LastProcessedTick: 1 << 33, Missed: 12}
+	buf := AppendInputAck(nil, ack)
+	got, n, err := DecodeInputAck(buf)
+	if err != nil || n != 12 || got != ack {
+		t.Errorf("decoded %+v (%d bytes, %v), want %+v", got, n, err, ack)
+	}
+	if _, _, err := DecodeInputAck(buf[:len(buf)-1]); err == nil {
+		t.Error("truncated ack should fail to decode")
+	}
+}
+
+// TestHandshakeReplyRoundTrip tests that accepted replies carry the player
+// and rejections keep the pre-version-5 layout.
+func TestHandshakeReplyRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply HandshakeReply
+		size  int
+	}{
+		{"accepted", HandshakeReply{Accepted: true, Version: 5, PlayerID: 2, Color: PlayerColor(1)}, 3 + 1 + 8},
+		{"rejected", HandshakeReply{Version: 5, Reason: "full"}, 3 + 1 + 4},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := AppendHandshakeReply(nil, tc.reply)
+			if len(buf) != tc.size {
+				t.Fatalf("encoded %d bytes, want %d", len(buf), tc.size)
+			}
+			got, n, err := DecodeHandshakeReply(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != len(buf) || got != tc.reply {
+				t.Errorf("decoded %+v (%d bytes), want %+v", got, n, tc.reply)
+			}
+			if _, _, err := DecodeHandshakeReply(buf[:len(buf)-1]); err == nil {
+				t.Error("truncated reply should fail to decode")
+			}
+		})
+	}
+}
+
+// TestHandshakeRoundTrip tests that the level hash is carried from version 7
+// on, the aim assist from version 10, the build from version 11, and that
+// older handshakes still decode.
+func TestHandshakeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		hs   Handshake
+		size int
+	}{
+		{"current", Handshake{Version: 11, MinVersion: 5, PlayerName: "Alice", LevelHash: [32]byte{0: 1, 31: 2}, PhysicsHash: [32]byte{0: 3}, AimAssist: 2, Build: "1.4.0"}, 4 + 6 + 64 + 1 + 6},
+		{"version 10", Handshake{Version: 10, MinVersion: 5, PlayerName: "Alice", LevelHash: [32]byte{0: 1, 31: 2}, PhysicsHash: [32]byte{0: 3}, AimAssist: 2}, 4 + 6 + 64 + 1},
+		{"version 9", Handshake{Version: 9, MinVersion: 5, PlayerName: "Alice", LevelHash: [32]byte{0: 1, 31: 2}, PhysicsHash: [32]byte{0: 3}}, 4 + 6 + 64},
+		{"version 7", Handshake{Version: 7, MinVersion: 5, PlayerName: "Alice", LevelHash: [32]byte{0: 1, 31: 2}}, 4 + 6 + 32},
+		{"version 5", Handshake{Version: 5, MinVersion: 5, PlayerName: "Alice"}, 4 + 6},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := AppendHandshake(nil, tc.hs)
+			if tc.hs.Version < 11 {
+				buf = buf[:len(buf)-1] // Older clients stop after the aim assist
+			}
+			if tc.hs.Version < 10 {
+				buf = buf[:len(buf)-1] // Older clients stop after the physics hash
+			}
+			if tc.hs.Version < 9 {
+				buf = buf[:len(buf)-32] // Older clients stop after the level hash
+			}
+			if tc.hs.Version < 7 {
+				buf = buf[:len(buf)-32] // or after the name
+			}
+			if len(buf) != tc.size {
+				t.Fatalf("encoded %d bytes, want %d", len(buf), tc.size)
+			}
+			got, n, err := DecodeHandshake(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != len(buf) || got != tc.hs {
+				t.Errorf("decoded %+v (%d bytes), want %+v", got, n, tc.hs)
+			}
+		})
+	}
+}
+
+// TestJoinBundleRoundTrip tests bundles with and without the level data and
+// a match result, and stats in the layout of an older negotiated version.
+func TestJoinBundleRoundTrip(t *testing.T) {
+	stats := []PlayerStats{
+		{PlayerID: 1, Name: "Alice", Orbs: 12, Cages: 1, Damage: 30, DamageTaken: 3, Deaths: 2, FinishTicks: 3600, Combo: 2, ComboEnd: 3700, BestCombo: 5},
+		{PlayerID: 2, Name: "Bob", Orbs: 3},
+	}
+	old := make([]PlayerStats, len(stats))
+	for i, ps := range stats {
+		ps.DamageTaken = 0                            // Not sent before version 16
+		ps.Combo, ps.ComboEnd, ps.BestCombo = 0, 0, 0 // nor the combo before 17
+		old[i] = ps
+	}
+	tests := []struct {
+		name    string
+		version int
+		bundle  JoinBundle
+	}{
+		{"hash only", ProtocolVersion, JoinBundle{Level: "Demo", LevelHash: [32]byte{1, 2, 3}, Mode: "coop", Tick: 900, LevelStart: 60, Elapsed: 840, Stats: stats}},
+		{"version 15", 15, JoinBundle{Level: "Demo", LevelHash: [32]byte{1, 2, 3}, Mode: "coop", Tick: 900, LevelStart: 60, Elapsed: 840, Stats: old}},
+		{"level and result", ProtocolVersion, JoinBundle{
+			Level:      "Demo",
+			LevelHash:  [32]byte{31: 9},
+			LevelData:  []byte(`{"name":"Demo","tiles":["#"]}`),
+			Mode:       "deathmatch",
+			Tick:       5000,
+			LevelStart: 5000,
+			Elapsed:    4000,
+			Stats:      stats,
+			Result:     &MatchResult{Mode: "deathmatch", Winners: []int{1}, Reason: "frag limit"},
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := AppendJoinBundle(nil, tc.bundle, tc.version)
+			got, n, err := DecodeJoinBundle(buf, tc.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != len(buf) || !reflect.DeepEqual(got, tc.bundle) {
+				t.Errorf("decoded %+v (%d of %d bytes), want %+v", got, n, len(buf), tc.bundle)
+			}
+			for i := range len(buf) {
+				if _, _, err := DecodeJoinBundle(buf[:i], tc.version); err == nil {
+					t.Fatalf("bundle truncated to %d bytes should fail to decode", i)
+				}
+			}
+		})
+	}
+}
+
+// TestTransferChunkRoundTrip tests the chunk and resume request codecs.
+func TestTransferChunkRoundTrip(t *testing.T) {
+	chunk := TransferChunk{Kind: TransferAtlas, Hash: [32]byte{7}, Size: 40000, Offset: 16384, Data: []byte("sprites")}
+	buf := AppendTransferChunk(nil, chunk)
+	got, n, err := DecodeTransferChunk(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(buf) || !reflect.DeepEqual(got, chunk) {
+		t.Errorf("decoded %+v (%d of %d bytes), want %+v", got, n, len(buf), chunk)
+	}
+	if _, _, err := DecodeTransferChunk(buf[:len(buf)-1]); err == nil {
+		t.Error("truncated chunk should fail to decode")
+	}
+
+	req := TransferRequest{Hash: [32]byte{7}, Offset: 16384}
+	gotReq, n, err := DecodeTransferRequest(AppendTransferRequest(nil, req))
+	if err != nil || n != 36 || gotReq != req {
+		t.Errorf("decoded %+v (%d bytes, %v), want %+v", gotReq, n, err, req)
+	}
+}
+
+func TestHostCommandRoundTrip(t *testing.T) {
+	cmd := HostCommand{Action: HostKick, Target: 3, Value: 1}
+	buf := AppendHostCommand(nil, cmd)
+	got, n, err := DecodeHostCommand(buf)
+	if err != nil || n != len(buf) || got != cmd {
+		t.Errorf("decoded %+v (%d of %d bytes, %v), want %+v", got, n, len(buf), err, cmd)
+	}
+	if _, _, err := DecodeHostCommand(buf[:len(buf)-1]); err == nil {
+		t.Error("truncated command should fail to decode")
+	}
+
+	result := HostResult{Action: HostKick, Reason: "only the host can kick"}
+	buf = AppendHostResult(nil, result)
+	gotResult, n, err := DecodeHostResult(buf)
+	if err != nil || n != len(buf) || gotResult != result {
+		t.Errorf("decoded %+v (%d of %d bytes, %v), want %+v", gotResult, n, len(buf), err, result)
+	}
+	if _, _, err := DecodeHostResult(buf[:len(buf)-1]); err == nil {
+		t.Error("truncated result should fail to decode")
+	}
+}
+
+func TestChatRoundTrip(t *testing.T) {
+	chat := Chat{PlayerID: 2, Text: "/votekick bob"}
+	buf := AppendChat(nil, chat)
+	got, n, err := DecodeChat(buf)
+	if err != nil || n != len(buf) || got != chat {
+		t.Errorf("decoded %+v (%d of %d bytes, %v), want %+v", got, n, len(buf), err, chat)
+	}
+	if _, _, err := DecodeChat(buf[:len(buf)-1]); err == nil {
+		t.Error("truncated chat should fail to decode")
+	}
+}
+
+// TestAppendAllocs tests that encoding the per-tick messages into a reused
+// buffer allocates nothing, so the network path stays off the heap.
+func TestAppendAllocs(t *testing.T) {
+	input := InputMessage{Frames: []InputFrame{
+		{Tick: 1, Intents: IntentRight},
+		{Tick: 2, Intents: IntentRight | IntentJump, Axis: &AnalogAxis{}},
+	}}
+	comp := componentCases[1].comp
+	buf := make([]byte, 0, 256)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = AppendInputMessage(buf[:0], input)
+		buf = AppendInputAck(buf, InputAck{LastProcessedTick: 2})
+		buf = AppendComponents(buf, &comp)
+	})
+	if allocs != 0 {
+		t.Errorf("encoding allocated %v times per run, want 0", allocs)
+	}
+}
@@ -0,0 +1,289 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// ComponentMask records which components are present in an EntityState.
+// Components are encoded in bit order after the mask.
+type ComponentMask uint16
+
+const (
+	CompPosition ComponentMask = 1 << iota
+	CompVelocity
+	CompGrounded
+	CompPlayer
+	CompSprite
+	CompHealth
+	CompAttack
+
+	compKnown = CompPosition | CompVelocity | CompGrounded | CompPlayer | CompSprite | CompHealth | CompAttack
+)
+
+// ComponentCount is the number of known components; bit i of a mask is
+// component i
+const ComponentCount = 7
+
+var componentNames = [ComponentCount]string{"position", "velocity", "grounded", "player", "sprite", "health", "attack"}
+
+// String lists the mask's components, e.g. "velocity+health"
+func (m ComponentMask) String() string {
+	var names []string
+	for i, name := range componentNames {
+		if m&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	if m&^compKnown != 0 {
+		names = append(names, fmt.Sprintf("%#x", uint16(m&^compKnown)))
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "+")
+}
+
+// FixedScale converts world units to the fixed-point wire representation
+const FixedScale = 1000
+
+// Wire component layouts. Each component has a fixed width:
+//
+//	Position  x i32 | y i32            (world units × FixedScale)
+//	Velocity  x i32 | y i32            (world units/tick × FixedScale)
+//	Grounded  on ground u8
+//	Player    id u32
+//	Sprite    index u16 | color u32    (index into the snapshot's sprite table)
+//	Health    current i16 | max i16
+//	Attack    flags u8 | ticks left u8 | charge ticks u16
+const (
+	sizePosition = 8
+	sizeVelocity = 8
+	sizeGrounded = 1
+	sizePlayer   = 4
+	sizeSprite   = 6
+	sizeHealth   = 4
+	sizeAttack   = 4
+)
+
+// Attack flags
+const (
+	AttackFlagAttacking = 1 << iota
+	AttackFlagFacingRight
+	AttackFlagCharging
+)
+
+// PositionData is a position in fixed-point world units
+type PositionData struct{ X, Y int32 }
+
+// VelocityData is a velocity in fixed-point world units per tick
+type VelocityData struct{ X, Y int32 }
+
+// SpriteData references an interned sprite ID
+type SpriteData struct {
+	Index uint16
+	Color uint32
+}
+
+// HealthData is current and maximum health
+type HealthData struct{ Current, Max int16 }
+
+// AttackData is the network-relevant part of an attack state
+type AttackData struct {
+	Flags       uint8
+	TicksLeft   uint8
+	ChargeTicks uint16
+}
+
+// EntityComponents is the typed form of EntityState.Components.
+// Only fields whose bit is set in Mask are meaningful.
+type EntityComponents struct {
+	Mask     ComponentMask
+	Position PositionData
+	Velocity VelocityData
+	Grounded bool
+	PlayerID uint32
+	Sprite   SpriteData
+	Health   HealthData
+	Attack   AttackData
+}
+
+// Has reports whether all given components are present
+func (c *EntityComponents) Has(m ComponentMask) bool {
+	return c.Mask&m == m
+}
+
+// ToFixed converts a world-unit value to fixed point
+func ToFixed(v float64) int32 {
+	return int32(v * FixedScale)
+}
+
+// FromFixed converts a fixed-point value to world units
+func FromFixed(v int32) float64 {
+	return float64(v) / FixedScale
+}
+
+// EncodedSize returns the encoded size of components with the given mask
+func EncodedSize(m ComponentMask) int {
+	n := 2
+	if m&CompPosition != 0 {
+		n += sizePosition
+	}
+	if m&CompVelocity != 0 {
+		n += sizeVelocity
+	}
+	if m&CompGrounded != 0 {
+		n += sizeGrounded
+	}
+	if m&CompPlayer != 0 {
+		n += sizePlayer
+	}
+	if m&CompSprite != 0 {
+		n += sizeSprite
+	}
+	if m&CompHealth != 0 {
+		n += sizeHealth
+	}
+	if m&CompAttack != 0 {
+		n += sizeAttack
+	}
+	return n
+}
+
+// AppendComponents encodes components as mask u16 followed by each present
+// component in bit order
+func AppendComponents(dst []byte, c *EntityComponents) []byte {
+	le := binary.LittleEndian
+	dst = le.AppendUint16(dst, uint16(c.Mask))
+	if c.Mask&CompPosition != 0 {
+		dst = le.AppendUint32(dst, uint32(c.Position.X))
+		dst = le.AppendUint32(dst, uint32(c.Position.Y))
+	}
+	if c.Mask&CompVelocity != 0 {
+		dst = le.AppendUint32(dst, uint32(c.Velocity.X))
+		dst = le.AppendUint32(dst, uint32(c.Velocity.Y))
+	}
+	if c.Mask&CompGrounded != 0 {
+		g := byte(0)
+		if c.Grounded {
+			g = 1
+		}
+		dst = append(dst, g)
+	}
+	if c.Mask&CompPlayer != 0 {
+		dst = le.AppendUint32(dst, c.PlayerID)
+	}
+	if c.Mask&CompSprite != 0 {
+		dst = le.AppendUint16(dst, c.Sprite.Index)
+		dst = le.AppendUint32(dst, c.Sprite.Color)
+	}
+	if c.Mask&CompHealth != 0 {
+		dst = le.AppendUint16(dst, uint16(c.Health.Current))
+		dst = le.AppendUint16(dst, uint16(c.Health.Max))
+	}
+	if c.Mask&CompAttack != 0 {
+		dst = append(dst, c.Attack.Flags, c.Attack.TicksLeft)
+		dst = le.AppendUint16(dst, c.Attack.ChargeTicks)
+	}
+	return dst
+}
+
+// DecodeComponents decodes components written by AppendComponents and
+// returns the bytes consumed
+func DecodeComponents(src []byte) (EntityComponents, int, error) {
+	var c EntityComponents
+	if len(src) < 2 {
+		return c, 0, ErrShortBuffer
+	}
+	le := binary.LittleEndian
+	c.Mask = ComponentMask(le.Uint16(src))
+	if c.Mask&^compKnown != 0 {
+		return c, 0, fmt.Errorf("protocol: unknown component bits %#x", uint16(c.Mask&^compKnown))
+	}
+	size := EncodedSize(c.Mask)
+	if len(src) < size {
+		return c, 0, ErrShortBuffer
+	}
+
+	b := src[2:size]
+	if c.Mask&CompPosition != 0 {
+		c.Position = PositionData{X: int32(le.Uint32(b)), Y: int32(le.Uint32(b[4:]))}
+		b = b[sizePosition:]
+	}
+	if c.Mask&CompVelocity != 0 {
+		c.Velocity = VelocityData{X: int32(le.Uint32(b)), Y: int32(le.Uint32(b[4:]))}
+		b = b[sizeVelocity:]
+	}
+	if c.Mask&CompGrounded != 0 {
+		c.Grounded = b[0] != 0
+		b = b[sizeGrounded:]
+	}
+	if c.Mask&CompPlayer != 0 {
+		c.PlayerID = le.Uint32(b)
+		b = b[sizePlayer:]
+	}
+	if c.Mask&CompSprite != 0 {
+		c.Sprite = SpriteData{Index: le.Uint16(b), Color: le.Uint32(b[2:])}
+		b = b[sizeSprite:]
+	}
+	if c.Mask&CompHealth != 0 {
+		c.Health = HealthData{Current: int16(le.Uint16(b)), Max: int16(le.Uint16(b[2:]))}
+		b = b[sizeHealth:]
+	}
+	if c.Mask&CompAttack != 0 {
+		c.Attack = AttackData{Flags: b[0], TicksLeft: b[1], ChargeTicks: le.Uint16(b[2:])}
+	}
+	return c, size, nil
+}
+
+// SpriteTable interns sprite IDs to compact indices. It is append-only, so
+// an index stays valid for the lifetime of the table.
+type SpriteTable struct {
+	names   []string
+	indices map[string]uint16
+}
+
+// NewSpriteTable creates an empty table
+func NewSpriteTable() *SpriteTable {
+	return &SpriteTable{indices: make(map[string]uint16)}
+}
+
+// Intern returns the index for a sprite ID, adding it if needed
+func (t *SpriteTable) Intern(id string) uint16 {
+	if idx, ok := t.indices[id]; ok {
+		return idx
+	}
+	idx := uint16(len(t.names))
+	t.names = append(t.names, id)
+	t.indices[id] = idx
+	return idx
+}
+
+// Lookup returns the sprite ID for an index
+func (t *SpriteTable) Lookup(idx uint16) (string, bool) {
+	if int(idx) >= len(t.names) {
+		return "", false
+	}
+	return t.names[idx], true
+}
+
+// Len returns the number of interned IDs
+func (t *SpriteTable) Len() int {
+	return len(t.names)
+}
+
+// Since returns the IDs interned after the first n entries
+func (t *SpriteTable) Since(n int) []string {
+	if n >= len(t.names) {
+		return nil
+	}
+	return t.names[n:]
+}
+
+// Extend appends IDs received from the server, in order
+func (t *SpriteTable) Extend(ids []string) {
+	for _, id := range ids {
+		t.Intern(id)
+	}
+}
@@ -0,0 +1,130 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+// componentCases pin the wire format. Changing any golden file is a
+// protocol break and requires a ProtocolVersion bump.
+var componentCases = []struct {
+	name string
+	comp EntityComponents
+}{
+	{
+		name: "physics_only",
+		comp: EntityComponents{
+			Mask:     CompPosition | CompVelocity | CompGrounded,
+			Position: PositionData{X: ToFixed(12.5), Y: ToFixed(-3.25)},
+			Velocity: VelocityData{X: ToFixed(0.5), Y: 0},
+			Grounded: true,
+		},
+	},
+	{
+		name: "player",
+		comp: EntityComponents{
+			Mask:     CompPosition | CompVelocity | CompGrounded | CompPlayer | CompSprite | CompHealth | CompAttack,
+			Position: PositionData{X: 10000, Y: 20000},
+			Velocity: VelocityData{X: -500, Y: 1000},
+			Grounded: false,
+			PlayerID: 1,
+			Sprite:   SpriteData{Index: 0, Color: 0x00FF00},
+			Health:   HealthData{Current: 3, Max: 3},
+			Attack: AttackData{
+				Flags:       AttackFlagCharging | AttackFlagFacingRight,
+				ChargeTicks: 90,
+			},
+		},
+	},
+	{
+		name: "enemy",
+		comp: EntityComponents{
+			Mask:     CompPosition | CompVelocity | CompGrounded | CompSprite | CompHealth,
+			Position: PositionData{X: 30000, Y: 15000},
+			Grounded: true,
+			Sprite:   SpriteData{Index: 2, Color: 0xFF0000},
+			Health:   HealthData{Current: 1, Max: 1},
+		},
+	},
+}
+
+func TestComponentsGolden(t *testing.T) {
+	for _, tc := range componentCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := AppendComponents(nil, &tc.comp)
+			if len(got) != EncodedSize(tc.comp.Mask) {
+				t.Fatalf("encoded %d bytes, EncodedSize says %d", len(got), EncodedSize(tc.comp.Mask))
+			}
+
+			path := filepath.Join("testdata", tc.name+".golden")
+			if *update {
+				if err := os.WriteFile(path, []byte(hex.EncodeToString(got)+"\n"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read golden (run with -update to create): %v", err)
+			}
+			want, err := hex.DecodeString(strings.TrimSpace(string(data)))
+			if err != nil {
+				t.Fatalf("bad golden file: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("encoding changed\n got: %x\nwant: %x", got, want)
+			}
+
+			decoded, n, err := DecodeComponents(want)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if n != len(want) {
+				t.Errorf("decode consumed %d of %d bytes", n, len(want))
+			}
+			if decoded != tc.comp {
+				t.Errorf("round trip mismatch\n got: %+v\nwant: %+v", decoded, tc.comp)
+			}
+		})
+	}
+}
+
+func TestDecodeComponentsErrors(t *testing.T) {
+	full := AppendComponents(nil, &componentCases[1].comp)
+	for i := range len(full) {
+		if _, _, err := DecodeComponents(full[:i]); err == nil {
+			t.Errorf("truncated to %d bytes: expected error", i)
+		}
+	}
+
+	if _, _, err := DecodeComponents([]byte{0x00, 0x80}); err == nil {
+		t.Error("unknown component bit: expected error")
+	}
+}
+
+func TestSpriteTable(t *testing.T) {
+	server := NewSpriteTable()
+	if server.Intern("player") != 0 || server.Intern("slime") != 1 || server.Intern("player") != 0 {
+		t.Fatal("interning is not stable")
+	}
+
+	client := NewSpriteTable()
+	client.Extend(server.Since(0))
+	server.Intern("fist")
+	client.Extend(server.Since(client.Len()))
+
+	for i := range server.Len() {
+		want, _ := server.Lookup(uint16(i))
+		got, ok := client.Lookup(uint16(i))
+		if !ok || got != want {
+			t.Errorf("index %d: got %q, want %q", i, got, want)
+		}
+	}
+}
@@ -1,21 +1,51 @@
 package protocol
 
+import "fmt"
+
 // Intent represents a player input action as a bitmask
-type Intent uint8
+type Intent uint16
 
 const (
-	IntentNone   Intent = 0
-	IntentLeft   Intent = 1 << iota
+	IntentNone Intent = 0
+	IntentLeft Intent = 1 << iota
 	IntentRight
 	IntentJump
 	IntentAttack
 	IntentUse
+	IntentDown  // Crouch / drop through platforms
+	IntentGlide // Helicopter hair while airborne
+	IntentSlide // Slide on slopes
+	_           // Reserved: talking and opening use IntentUse
+	IntentMenu  // Open the in-game menu
+	IntentPound // Ground pound: slam straight down while airborne
 )
 
+// AnalogAxis is a stick position quantized to [-127, 127] per axis
+type AnalogAxis struct {
+	X, Y int8
+}
+
 // InputFrame contains player input for a single tick
 type InputFrame struct {
 	Tick    uint64
 	Intents Intent
+	Axis    *AnalogAxis // Optional, nil for digital-only input
+}
+
+// DefaultInputRedundancy is how many recent frames each InputMessage carries
+const DefaultInputRedundancy = 4
+
+// InputMessage carries the most recent input frames, oldest first.
+// Repeating earlier frames lets the server recover from a dropped packet
+// without a retransmit; it discards frames it has already seen by tick.
+type InputMessage struct {
+	Frames []InputFrame
+}
+
+// InputAck tells a client how far the server got with its inputs
+type InputAck struct {
+	LastProcessedTick uint64 // Highest input tick applied to the simulation
+	Missed            uint32 // Ticks simulated without a fresh input, since join
 }
 
 // EntityID uniquely identifies an entity
@@ -24,22 +54,264 @@ type EntityID uint64
 // EntityState is the serialized state of an entity
 type EntityState struct {
 	ID         EntityID
-	Components []byte // Serialized via ark-serde
+	Components []byte // Encoded with AppendComponents
 }
 
 // StateSnapshot contains game state for a tick
 type StateSnapshot struct {
 	Tick     uint64
-	Full     bool     // True = complete state, False = delta
-	Baseline uint64   // If delta, relative to this tick
+	Full     bool   // True = complete state, False = delta
+	Baseline uint64 // If delta, relative to this tick
 	Entities []EntityState
 	Removed  []EntityID // Entities removed since baseline
+	Sprites  []string   // Sprite table entries: all if Full, else those added since baseline
+	Reset    bool       // World was reset; drop predictions and interpolation history
+
+	Players  []PlayerInfo  // Player roster: if Full, or when it changed
+	Stats    []PlayerStats // All players' stats: if Full, or when any changed
+	Result   *MatchResult  // Set once the match is over
+	Settings *HostSettings // Host and room settings: if Full, or when they changed
+	Vote     *VoteStatus   // The vote in progress: if Full, or when it changed
+	Level    *LevelState   // Changes to the level: if Full, or when they changed
+}
+
+// PlayerStats are a player's running totals for the current level
+type PlayerStats struct {
+	PlayerID    int
+	Name        string
+	Orbs        int
+	Cages       int
+	Damage      int // Damage dealt
+	DamageTaken int // Damage received
+	Deaths      int
+	FinishTicks uint64 // Ticks from level start to the exit; 0 = not finished
+	Combo       int    // Enemy kills in a row without taking damage, still going
+	ComboEnd    uint64 // Tick the combo runs out without another kill
+	BestCombo   int    // Longest combo this level
+}
+
+// MatchResult is the outcome of a finished match
+type MatchResult struct {
+	Mode    string
+	Winners []int  // Player IDs; empty if nobody won
+	Reason  string // e.g. "frag limit", "time limit"
 }
 
 // Handshake is exchanged on connection
 type Handshake struct {
-	Version    int
+	Version    int // Highest version the sender speaks
+	MinVersion int // Lowest version the sender accepts
 	PlayerName string
+	LevelHash  [32]byte // The client's copy of the server's level; zero if it has none
+
+	// PhysicsHash is the hash of the client's physics profile; zero means
+	// the default profile
+	PhysicsHash [32]byte
+
+	AimAssist uint8 // Requested game.AimAssist level; 0 is off
+
+	// Build is the release the sender was built from, e.g. "1.4.0" or
+	// "dev"; servers refuse other builds. Empty before version 11.
+	Build string
+}
+
+// HandshakeReply is the server's answer to a Handshake
+type HandshakeReply struct {
+	Accepted bool
+	Version  int    // Negotiated version if accepted, server's version otherwise
+	Reason   string // Why the connection was rejected
+	PlayerID int    // The client's player, if accepted
+	Color    uint32 // The player's color, if accepted
+}
+
+// JoinBundle is everything a client joining a running match needs to build
+// the same world as the server before its first snapshot: the level, the
+// match and the scoreboard. It is sent after an accepted HandshakeReply.
+type JoinBundle struct {
+	Level      string   // Level name
+	LevelHash  [32]byte // SHA-256 of the encoded level, see game.LevelHash
+	LevelData  []byte   // The encoded level, if the client doesn't have it
+	Mode       string   // Game mode; empty if the server runs none
+	Tick       uint64   // Server tick the bundle was made at
+	LevelStart uint64   // Tick the level was started at, for finish times
+	Elapsed    uint64   // Ticks since the match started
+	Stats      []PlayerStats
+	Result     *MatchResult // Non-nil if the match is already over
+}
+
+// TransferKind says what a transferred asset is
+type TransferKind uint8
+
+const (
+	TransferLevel TransferKind = iota // Level encoded with game.EncodeLevel
+	TransferAtlas                     // Sprite atlas definition (atlas.json)
+)
+
+// TransferChunkSize is the default amount of asset data per chunk
+const TransferChunkSize = 16 * 1024
+
+// MaxTransferSize is the largest asset a client accepts
+const MaxTransferSize = 64 << 20
+
+// TransferChunk is one piece of an asset the server pushes to a client.
+// Every chunk names the whole asset, so a client can start or resume a
+// download from any of them.
+type TransferChunk struct {
+	Kind   TransferKind
+	Hash   [32]byte // SHA-256 of the whole asset
+	Size   uint32   // Size of the whole asset
+	Offset uint32   // Position of Data in the asset
+	Data   []byte
+}
+
+// TransferRequest asks the server to send an asset from Offset on, to
+// resume an interrupted download
+type TransferRequest struct {
+	Hash   [32]byte
+	Offset uint32
+}
+
+// HostAction is a command only the hosting player may give
+type HostAction uint8
+
+const (
+	HostKick         HostAction = iota + 1 // Remove player Target from the game
+	HostFriendlyFire                       // Value 1 turns friendly fire on, 0 off
+	HostRestart                            // Restart the level
+	HostDifficulty                         // Value is the game.Difficulty
+)
+
+func (a HostAction) String() string {
+	switch a {
+	case HostKick:
+		return "kick"
+	case HostFriendlyFire:
+		return "friendly fire"
+	case HostRestart:
+		return "restart"
+	case HostDifficulty:
+		return "difficulty"
+	}
+	return fmt.Sprintf("HostAction(%d)", uint8(a))
+}
+
+// HostCommand asks the server to change the game as the host. The server
+// checks the sender is the host and the values are valid.
+type HostCommand struct {
+	Action HostAction
+	Target int   // Player ID, for HostKick
+	Value  uint8 // Setting, for HostFriendlyFire and HostDifficulty
+}
+
+// HostResult answers a HostCommand
+type HostResult struct {
+	Action HostAction
+	OK     bool
+	Reason string // Why the command was refused
+}
+
+// HostSettings are what the host controls, sent to every client so the
+// host menu shows the current values
+type HostSettings struct {
+	Host         int // Host's player ID; 0 if the game has no host
+	FriendlyFire bool
+	Difficulty   uint8 // game.Difficulty
+}
+
+// MaxChatLength is the longest chat message, in bytes, the server relays
+const MaxChatLength = 200
+
+// Chat is a chat message. From a client it is what the player typed, and a
+// line starting with / is a command such as /votekick; from the server it is
+// a player's message or, with PlayerID 0, the server's own.
+type Chat struct {
+	PlayerID int
+	Text     string
+}
+
+// VoteKind is what a vote decides
+type VoteKind uint8
+
+const (
+	VoteNone    VoteKind = iota // No vote in progress
+	VoteKick                    // Remove the player named in Subject
+	VoteMap                     // Change to the map named in Subject
+	VoteRestart                 // Restart the level
+)
+
+func (k VoteKind) String() string {
+	switch k {
+	case VoteNone:
+		return "none"
+	case VoteKick:
+		return "kick"
+	case VoteMap:
+		return "map"
+	case VoteRestart:
+		return "restart"
+	}
+	return fmt.Sprintf("VoteKind(%d)", uint8(k))
+}
+
+// VoteResult is how a vote ended
+type VoteResult uint8
+
+const (
+	VoteOpen VoteResult = iota
+	VotePassed
+	VoteFailed
+)
+
+// VoteStatus is the vote in progress, or the one that just ended, for the
+// clients' voting overlay. Kind VoteNone clears it.
+type VoteStatus struct {
+	Kind    VoteKind
+	Subject string // Player name or map name
+	Caller  int    // Player ID of who called the vote
+	Yes     uint8
+	No      uint8
+	Needed  uint8  // Yes votes that pass it
+	Ends    uint64 // Tick the vote closes at
+	Result  VoteResult
+}
+
+// LevelState is how the level has changed since it started: tiles broken,
+// gates opened or closed and keys picked up. It is sent whole.
+type LevelState struct {
+	Broken []int    // Tile indexes, in the order they broke
+	Gates  []string // IDs of the gates toggled from how the level starts, sorted
+	Keys   []string // Colors of the keys the players hold, sorted
+}
+
+// Disconnect tells the other side the connection is closing and why
+type Disconnect struct {
+	Reason string
+}
+
+// PlayerInfo identifies a player to other clients
+type PlayerInfo struct {
+	PlayerID int
+	Name     string
+	Color    uint32 // 0xRRGGBB, from PlayerColors
+	AFK      bool   // Idle: sent no input for a while, see the server's AFKConfig
+}
+
+// PlayerColors is the palette the server assigns players from, in order.
+// The colors are far apart in hue so players are easy to tell apart.
+var PlayerColors = [...]uint32{
+	0x3FA9F5, // Blue
+	0xF5A623, // Orange
+	0x7ED321, // Green
+	0xD0021B, // Red
+	0xBD10E0, // Purple
+	0x50E3C2, // Teal
+	0xF8E71C, // Yellow
+	0xFF6FCF, // Pink
+}
+
+// PlayerColor returns the palette color for a slot, wrapping around
+func PlayerColor(slot int) uint32 {
+	return PlayerColors[slot%len(PlayerColors)]
 }
 
 // Message types for network protocol
@@ -53,4 +325,55 @@ const (
 	MsgPing
 	MsgPong
 	MsgDisconnect
+	MsgHandshakeReply
+	MsgInputAck
+	MsgJoinBundle
+	MsgTransferChunk
+	MsgTransferRequest
+	MsgHostCommand
+	MsgHostResult
+	MsgChat
 )
+
+// Size returns the snapshot's wire size in the codec's format: fixed-size
+// integers, length-prefixed strings and lists. Snapshots have no codec yet,
+// so this is what traffic statistics count.
+func (s *StateSnapshot) Size() int {
+	n := 8 + 1 + 8 // tick u64 | flags u8 | baseline u64
+	n += 2         // entity count u16
+	for _, e := range s.Entities {
+		n += 8 + len(e.Components)
+	}
+	n += 2 + 8*len(s.Removed)
+	n += 1
+	for _, sprite := range s.Sprites {
+		n += 1 + min(len(sprite), 255)
+	}
+	n += 1
+	for _, p := range s.Players {
+		n += 4 + 1 + min(len(p.Name), 255) + 4 + 1 // id | name | color | afk
+	}
+	n += 1
+	for _, ps := range s.Stats {
+		n += 4 + 1 + min(len(ps.Name), 255) + 7*4 + 8 + 8 // id | name | counters u32 | finish u64 | combo end u64
+	}
+	if s.Result != nil {
+		n += 1 + len(s.Result.Mode) + 1 + 4*len(s.Result.Winners) + 1 + len(s.Result.Reason)
+	}
+	if s.Settings != nil {
+		n += 1 + 4 + 1 + 1 // flag | host u32 | friendly fire u8 | difficulty u8
+	}
+	if s.Vote != nil {
+		n += 1 + 1 + 1 + min(len(s.Vote.Subject), 255) + 4 + 3 + 8 + 1 // flag | kind | subject | caller | counts | ends | result
+	}
+	if s.Level != nil {
+		n += 1 + 2 + 4*len(s.Level.Broken) // flag | broken count u16 + count × u32
+		for _, list := range [][]string{s.Level.Gates, s.Level.Keys} {
+			n++
+			for _, name := range list {
+				n += 1 + min(len(name), 255)
+			}
+		}
+	}
+	return n
+}
@@ -2,13 +2,75 @@
 // for client-server communication.
 package protocol
 
-// Version constants for compatibility checking
+import "fmt"
+
+// Version constants for compatibility checking.
+//
+// Version history:
+//   - 1: uint8 intents
+//   - 2: uint16 intents, optional analog axis in InputFrame
+//   - 3: redundant input messages, input acks
+//   - 4: component-mask entity encoding, interned sprite IDs
+//   - 5: player ID and color in accepted handshake replies, player roster
+//     in snapshots
+//   - 6: join bundle (level, match and scoreboard) after the handshake
+//   - 7: level hash in the handshake
+//   - 8: chunked asset transfer
+//   - 9: physics profile hash in the handshake
+//   - 10: aim assist level in the handshake
+//   - 11: build version in the handshake
+//   - 12: host commands and results, host settings in snapshots
+//   - 13: chat, vote status in snapshots
+//   - 14: AFK flag in the player roster
+//   - 15: level state (broken tiles, gates, keys) in snapshots
+//   - 16: damage taken in player stats
+//   - 17: combos in player stats
 const (
-	ProtocolVersion = 1
-	MinVersion      = 1
+	ProtocolVersion = 17
+	MinVersion      = 5
 )
 
+// VersionError reports a failed version negotiation
+type VersionError struct {
+	Local, LocalMin   int
+	Remote, RemoteMin int
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("protocol version mismatch: local %d-%d, remote %d-%d",
+		e.LocalMin, e.Local, e.RemoteMin, e.Remote)
+}
+
 // Compatible checks if two versions can communicate
 func Compatible(local, remote int) bool {
 	return remote >= MinVersion && local >= MinVersion
 }
+
+// Negotiate picks the highest version both sides support.
+// A remote MinVersion of 0 (peers predating negotiation) means "only Version".
+func Negotiate(remote Handshake) (int, error) {
+	remoteMin := remote.MinVersion
+	if remoteMin == 0 {
+		remoteMin = remote.Version
+	}
+
+	version := min(ProtocolVersion, remote.Version)
+	if version < MinVersion || version < remoteMin {
+		return 0, &VersionError{
+			Local:     ProtocolVersion,
+			LocalMin:  MinVersion,
+			Remote:    remote.Version,
+			RemoteMin: remoteMin,
+		}
+	}
+	return version, nil
+}
+
+// NewHandshake returns a handshake advertising this build's versions
+func NewHandshake(playerName string) Handshake {
+	return Handshake{
+		Version:    ProtocolVersion,
+		MinVersion: MinVersion,
+		PlayerName: playerName,
+	}
+}
@@ -0,0 +1,39 @@
+package protocol
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestNegotiate tests that the highest common version is picked and that
+// ranges that don't overlap are rejected.
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name       string
+		version    int
+		minVersion int
+		want       int
+		wantErr    bool
+	}{
+		{"same build", ProtocolVersion, MinVersion, ProtocolVersion, false},
+		{"newer client", ProtocolVersion + 3, MinVersion, ProtocolVersion, false},
+		{"older client", MinVersion + 1, MinVersion, MinVersion + 1, false},
+		{"client too old", MinVersion - 1, 1, 0, true},
+		{"client requires newer", ProtocolVersion + 3, ProtocolVersion + 1, 0, true},
+		{"no minimum means only its version", MinVersion + 1, 0, MinVersion + 1, false},
+		{"no minimum and too old", MinVersion - 1, 0, 0, true},
+		{"no minimum and newer", ProtocolVersion + 1, 0, 0, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Negotiate(Handshake{Version: tc.version, MinVersion: tc.minVersion})
+			if got != tc.want || (err != nil) != tc.wantErr {
+				t.Fatalf("Negotiate = %d, %v; want %d, error %v", got, err, tc.want, tc.wantErr)
+			}
+			var verr *VersionError
+			if tc.wantErr && (!errors.As(err, &verr) || verr.Remote != tc.version || verr.Local != ProtocolVersion) {
+				t.Errorf("Error = %#v, want a VersionError with both ranges", err)
+			}
+		})
+	}
+}
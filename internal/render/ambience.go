@@ -0,0 +1,166 @@
+package render
+
+import (
+	"math"
+	"math/rand/v2"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+)
+
+// GradeRGB applies a level's color grading to a 0xRRGGBB color: the tint
+// laid over it at its alpha, then the fog at its density scaled by depth,
+// the screen row from 0 at the top to 1 at the bottom. The Gio renderer
+// draws the same blend as overlays after the scene; cell renderers, which
+// can't, shift each cell's colors with this instead. A nil ambience leaves
+// the color as it is.
+func GradeRGB(amb *game.Ambience, rgb uint32, depth float64) uint32 {
+	if amb == nil {
+		return rgb
+	}
+	rgb = blendRGB(rgb, uint32(amb.Tint), amb.TintAlpha)
+	return blendRGB(rgb, amb.FogRGB(), FogAlpha(amb, depth))
+}
+
+// FogAlpha returns the fog's opacity at a depth, 0 at the top of the screen
+// and 1 at the bottom: none at the top, thickening to the ambience's
+// density at the bottom
+func FogAlpha(amb *game.Ambience, depth float64) float64 {
+	if amb == nil {
+		return 0
+	}
+	return amb.Fog * min(max(depth, 0), 1)
+}
+
+// GradeCell16 shifts a 16-color cell's palette by the level's grading: each
+// color is graded and mapped back to the nearest of the 16. The glyph
+// stays, so tiles and sprites remain distinct when their colors merge, and
+// a foreground never vanishes into its background.
+func GradeCell16(amb *game.Ambience, c Cell, depth float64) Cell {
+	if amb == nil {
+		return c
+	}
+	c.Fg = Nearest16(GradeRGB(amb, ansiRGB[c.Fg&0xF], depth))
+	if c.Bg != ANSIBlack {
+		c.Bg = Nearest16(GradeRGB(amb, ansiRGB[c.Bg&0xF], depth))
+	}
+	if c.Fg == c.Bg {
+		c.Fg = ANSIBrightWhite
+		if c.Bg == ANSIBrightWhite || c.Bg == ANSIWhite {
+			c.Fg = ANSIBlack
+		}
+	}
+	return c
+}
+
+// blendRGB mixes over into base by alpha, 0 to 1
+func blendRGB(base, over uint32, alpha float64) uint32 {
+	if alpha <= 0 {
+		return base
+	}
+	alpha = min(alpha, 1)
+	mix := func(shift uint) uint32 {
+		b, o := float64(base>>shift&0xFF), float64(over>>shift&0xFF)
+		return uint32(math.Round(b+(o-b)*alpha)) << shift
+	}
+	return mix(16) | mix(8) | mix(0)
+}
+
+// Weather particles per tick across a 40-tile wide view at intensity 1
+const (
+	RainRate = 6.0
+	SnowRate = 1.5
+)
+
+// Fall speeds in tiles per tick
+const (
+	RainSpeed = 0.6
+	SnowSpeed = 0.06
+)
+
+// WeatherParticles is a level's rain or snow. Drops and flakes start above
+// the view, fall through it carried by the wind where they are (see
+// game.Ambience.WindAt) and pass through tiles; like Particles they live
+// only on the client.
+type WeatherParticles struct {
+	// Density scales how many are spawned, from 1 down to 0 for none, the
+	// same as Particles.Density under Motion
+	Density float64
+
+	amb       *game.Ambience
+	particles []Particle
+	rng       *rand.Rand
+	owed      float64 // Fractional particles carried to the next tick
+}
+
+// NewWeatherParticles creates the weather for a level's ambience; with no
+// weather it stays empty
+func NewWeatherParticles(amb *game.Ambience) *WeatherParticles {
+	return &WeatherParticles{Density: 1, amb: amb, rng: rand.New(rand.NewPCG(3, 4))}
+}
+
+// Kind returns the weather drawn
+func (w *WeatherParticles) Kind() game.Weather {
+	if w.amb == nil {
+		return game.WeatherNone
+	}
+	return w.amb.Weather
+}
+
+// Update spawns new particles along the top of view, the visible part of
+// the level in tiles, then moves every particle one tick and drops those
+// that fell out of view or expired
+func (w *WeatherParticles) Update(view game.Rect) {
+	kind := w.Kind()
+	if kind == game.WeatherNone {
+		return
+	}
+	rate, speed := RainRate, RainSpeed
+	if kind == game.WeatherSnow {
+		rate, speed = SnowRate, SnowSpeed
+	}
+
+	// Spawn over a margin upwind too, so wind doesn't leave an empty edge
+	wind := w.amb.Wind
+	margin := math.Abs(wind) * view.H / speed
+	left, width := view.X, view.W+margin
+	if wind > 0 {
+		left -= margin
+	}
+	w.owed += rate * w.amb.WeatherIntensity() * min(max(w.Density, 0), 1) * width / 40
+	for ; w.owed >= 1; w.owed-- {
+		w.particles = append(w.particles, Particle{
+			X:    left + w.rng.Float64()*width,
+			Y:    view.Y - w.rng.Float64()*2,
+			VY:   speed * (0.8 + w.rng.Float64()*0.4),
+			Life: int((view.H+2)/speed*1.5) + 1,
+		})
+	}
+
+	bottom := view.Y + view.H
+	live := w.particles[:0]
+	for _, pt := range w.particles {
+		pt.Age++
+		if pt.Age >= pt.Life || pt.Y > bottom {
+			continue
+		}
+		pt.VX = w.amb.WindAt(pt.X, pt.Y)
+		if kind == game.WeatherSnow {
+			pt.VX += 0.02 * math.Sin(float64(pt.Age)/20+pt.Y) // Flakes drift
+		}
+		pt.X += pt.VX
+		pt.Y += pt.VY
+		live = append(live, pt)
+	}
+	w.particles = live
+}
+
+// Clear removes every particle, e.g. after a level restart
+func (w *WeatherParticles) Clear() {
+	w.particles = w.particles[:0]
+	w.owed = 0
+}
+
+// All returns the live particles
+func (w *WeatherParticles) All() []Particle {
+	return w.particles
+}
@@ -0,0 +1,72 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+)
+
+// TestGrading tests that the tint and the fog blend into colors, the fog
+// only toward the bottom, and that 16-color cells keep a visible glyph.
+func TestGrading(t *testing.T) {
+	if got := GradeRGB(nil, 0x123456, 1); got != 0x123456 {
+		t.Errorf("No ambience changed the color to %06x", got)
+	}
+	amb := &game.Ambience{Tint: 0xFF0000, TintAlpha: 0.5, Fog: 1, FogColor: 0xFFFFFF}
+	if got := GradeRGB(amb, 0x000000, 0); got != 0x800000 {
+		t.Errorf("Tinted black at the top = %06x, want 800000", got)
+	}
+	if got := GradeRGB(amb, 0x000000, 1); got != 0xFFFFFF {
+		t.Errorf("Black in full fog = %06x, want the fog's ffffff", got)
+	}
+	if a := FogAlpha(amb, 0.5); a != 0.5 {
+		t.Errorf("Fog halfway down = %v, want 0.5", a)
+	}
+
+	// Thick white fog turns everything white, but never white on white
+	fog := &game.Ambience{Fog: 1, FogColor: 0xFFFFFF}
+	c := GradeCell16(fog, Cell{Glyph: '~', Fg: ANSIBrightCyan, Bg: ANSIBlue}, 1)
+	if c.Glyph != '~' || c.Fg == c.Bg {
+		t.Errorf("Graded cell = %+v, want its glyph and a visible foreground", c)
+	}
+	if c := GradeCell16(fog, Cell{Glyph: '#', Fg: ANSIYellow}, 0); c.Fg != ANSIYellow {
+		t.Errorf("No fog at the top, yet the cell became %+v", c)
+	}
+}
+
+// TestWeather tests that rain falls through the view, blown by the wind of
+// the zone it is in, and that no weather and zero density spawn nothing.
+func TestWeather(t *testing.T) {
+	view := game.Rect{X: 0, Y: 0, W: 40, H: 20}
+	amb := &game.Ambience{Weather: game.WeatherRain, WindZones: []game.WindZone{{Area: game.Rect{X: 20, Y: -5, W: 60, H: 30}, Wind: 0.2}}}
+	w := NewWeatherParticles(amb)
+	for range 20 {
+		w.Update(view)
+	}
+	if len(w.All()) == 0 {
+		t.Fatal("Rain should fall")
+	}
+	for _, p := range w.All() {
+		if p.Y > view.Y+view.H {
+			t.Errorf("Drop at y %v below the view", p.Y)
+		}
+		if want := amb.WindAt(p.X-p.VX, p.Y-p.VY); p.VX != want {
+			t.Errorf("Drop at (%v, %v) blown %v, want %v", p.X, p.Y, p.VX, want)
+		}
+	}
+	w.Clear()
+	if len(w.All()) != 0 {
+		t.Error("Clear should remove the rain")
+	}
+
+	w.Density = 0
+	w.Update(view)
+	if len(w.All()) != 0 {
+		t.Error("Zero density should spawn nothing")
+	}
+	dry := NewWeatherParticles(nil)
+	dry.Update(view)
+	if len(dry.All()) != 0 || dry.Kind() != game.WeatherNone {
+		t.Error("No ambience should have no weather")
+	}
+}
@@ -1,13 +1,11 @@
-//go:build gio
-
 package render
 
 import (
 	"encoding/json"
 	"image"
-	"io/fs"
 	_ "image/jpeg"
 	_ "image/png"
+	"io/fs"
 )
 
 // SpriteRegion defines a rectangular region in the atlas
@@ -0,0 +1,31 @@
+package render
+
+import (
+	"image"
+	"os"
+	"testing"
+)
+
+// TestLoadAtlas tests that the GUI's atlas loads without the gio tag and
+// that every sprite lies inside the image.
+func TestLoadAtlas(t *testing.T) {
+	atlas, err := LoadAtlas(os.DirFS("../../cmd/rayman-gui"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(atlas.Sprites) == 0 {
+		t.Fatal("atlas has no sprites")
+	}
+	bounds := atlas.Image.Bounds()
+	for id, r := range atlas.Sprites {
+		if rect := image.Rect(r.X, r.Y, r.X+r.W, r.Y+r.H); rect.Empty() || !rect.In(bounds) {
+			t.Errorf("sprite %q at %v is outside the %v atlas", id, rect, bounds)
+		}
+	}
+	if _, ok := atlas.GetRegion("player_idle"); !ok {
+		t.Error("player_idle missing")
+	}
+	if _, ok := atlas.GetRegion("no_such_sprite"); ok {
+		t.Error("unknown sprite found")
+	}
+}
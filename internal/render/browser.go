@@ -0,0 +1,109 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/andersfylling/rayman-slides/internal/i18n"
+)
+
+// Browser is the server browser: public rooms from the lookup service with
+// the measured ping to each, one of them selected
+type Browser struct {
+	Rooms    []BrowserRoom
+	Selected int
+	Status   string        // Loading, errors, join progress
+	Playable time.Duration // Rooms pinging below this are marked *, 0 for none
+	Lang     *i18n.Catalog // Headings and key hints; nil for English
+}
+
+// BrowserRoom is one listed room
+type BrowserRoom struct {
+	Code       string
+	Name       string
+	Players    int
+	MaxPlayers int
+	Region     string
+	Ping       time.Duration // 0 while measuring, negative if unreachable
+}
+
+// SortByPing orders rooms by measured ping, fastest first, then rooms still
+// being measured, then unreachable ones. Ties keep their order, and the
+// selection stays on the same room.
+func (b *Browser) SortByPing() {
+	var selected string
+	if b.Selected < len(b.Rooms) {
+		selected = b.Rooms[b.Selected].Code
+	}
+	rank := func(ping time.Duration) (int, time.Duration) {
+		switch {
+		case ping > 0:
+			return 0, ping
+		case ping == 0:
+			return 1, 0
+		default:
+			return 2, 0
+		}
+	}
+	sort.SliceStable(b.Rooms, func(i, j int) bool {
+		ri, pi := rank(b.Rooms[i].Ping)
+		rj, pj := rank(b.Rooms[j].Ping)
+		if ri != rj {
+			return ri < rj
+		}
+		return pi < pj
+	})
+	for i, room := range b.Rooms {
+		if room.Code == selected {
+			b.Selected = i
+		}
+	}
+}
+
+// Lines formats the browser as fixed-width text rows, title first; the
+// selected room is marked with >
+func (b *Browser) Lines() []string {
+	tr := b.Lang
+	lines := make([]string, 0, len(b.Rooms)+7)
+	lines = append(lines, tr.T("browser.title"), "")
+	lines = append(lines, "  "+i18n.PadRight(tr.T("browser.name"), 24)+" "+i18n.PadLeft(tr.T("browser.players"), 8)+" "+
+		i18n.PadRight(tr.T("browser.region"), 6)+" "+i18n.PadLeft(tr.T("browser.ping"), 6))
+	for i, room := range b.Rooms {
+		marker := " "
+		if i == b.Selected {
+			marker = ">"
+		}
+		suggested := " "
+		if room.Ping > 0 && room.Ping < b.Playable {
+			suggested = "*"
+		}
+		players := fmt.Sprintf("%d/%d", room.Players, room.MaxPlayers)
+		lines = append(lines, marker+suggested+i18n.PadRight(room.Name, 24)+" "+i18n.PadLeft(players, 8)+" "+
+			i18n.PadRight(room.Region, 6)+" "+i18n.PadLeft(formatPing(room.Ping), 6))
+	}
+	if len(b.Rooms) == 0 {
+		lines = append(lines, "  "+tr.T("browser.empty"))
+	}
+	lines = append(lines, "")
+	if b.Status != "" {
+		lines = append(lines, b.Status)
+	}
+	if b.Playable > 0 {
+		lines = append(lines, tr.T("browser.suggested", b.Playable.Milliseconds()))
+	}
+	lines = append(lines, tr.T("browser.footer"))
+	return lines
+}
+
+// formatPing formats a measured ping in milliseconds
+func formatPing(ping time.Duration) string {
+	switch {
+	case ping < 0:
+		return "-"
+	case ping == 0:
+		return "..."
+	default:
+		return fmt.Sprintf("%dms", max(ping.Milliseconds(), 1))
+	}
+}
@@ -0,0 +1,67 @@
+package render
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestBrowserSortByPing tests that measured rooms come first by round trip,
+// then unmeasured, then unreachable ones, and the selection follows its room.
+func TestBrowserSortByPing(t *testing.T) {
+	b := &Browser{
+		Rooms: []BrowserRoom{
+			{Code: "DOWN", Ping: -1},
+			{Code: "SLOW", Ping: 180 * time.Millisecond},
+			{Code: "WAIT", Ping: 0},
+			{Code: "FAST", Ping: 20 * time.Millisecond},
+		},
+		Selected: 1,
+	}
+	b.SortByPing()
+
+	want := []string{"FAST", "SLOW", "WAIT", "DOWN"}
+	for i, code := range want {
+		if b.Rooms[i].Code != code {
+			t.Fatalf("Room %d is %s, want order %v", i, b.Rooms[i].Code, want)
+		}
+	}
+	if b.Selected != 1 {
+		t.Errorf("Selection should stay on SLOW at 1, got %d", b.Selected)
+	}
+}
+
+// TestBrowserLines tests the room rows: the selection marker, the suggested
+// mark for playable pings and how pings are shown.
+func TestBrowserLines(t *testing.T) {
+	b := &Browser{
+		Rooms: []BrowserRoom{
+			{Code: "FAST", Name: "Fast", Players: 2, MaxPlayers: 4, Region: "eu", Ping: 20 * time.Millisecond},
+			{Code: "WAIT", Name: "Wait", Players: 1, MaxPlayers: 8, Ping: 0},
+			{Code: "DOWN", Name: "Down", Players: 3, MaxPlayers: 4, Region: "us", Ping: -1},
+		},
+		Selected: 1,
+		Status:   "Loading...",
+		Playable: 100 * time.Millisecond,
+	}
+	want := []string{
+		"Browse games",
+		"",
+		"  Name                      Players Region   Ping",
+		" *Fast                          2/4 eu       20ms",
+		"> Wait                          1/8           ...",
+		"  Down                          3/4 us          -",
+		"",
+		"Loading...",
+		"* suggested: under 100ms",
+		"Up/Down: Select | Enter: Join | R: Refresh | Esc: Play offline",
+	}
+	if got := b.Lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Lines =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	empty := (&Browser{}).Lines()
+	if len(empty) != 6 || empty[3] != "  No open games" {
+		t.Errorf("Empty browser lines %q, want the no games row and no suggestion", empty)
+	}
+}
@@ -0,0 +1,259 @@
+package render
+
+import (
+	"math"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+)
+
+// CameraConfig tunes how a CameraController follows the player vertically.
+// Horizontal movement is always followed directly.
+type CameraConfig struct {
+	// DeadzoneY is how far, in world units, the player may move above or
+	// below the camera's resting height without it following. Small jumps
+	// stay inside it. It is capped to keep the player on screen.
+	DeadzoneY float64
+
+	// PanRate is the fraction of the remaining distance the camera closes
+	// each update when panning to a new resting height; 1 snaps.
+	PanRate float64
+}
+
+// DefaultCameraConfig suits the smoothly scrolled Gio renderer
+func DefaultCameraConfig() CameraConfig {
+	return CameraConfig{DeadzoneY: 3, PanRate: 0.1}
+}
+
+// TerminalCameraConfig suits cell-based renderers, where slow panning shows
+// as jitter: a smaller deadzone for the few rows, and snapping
+func TerminalCameraConfig() CameraConfig {
+	return CameraConfig{DeadzoneY: 2, PanRate: 1}
+}
+
+// CameraController moves the camera after the player. Vertically it rests at
+// the height the player last stood at: it pans when the player lands on a
+// platform at a new height, and follows at once when the player leaves the
+// deadzone (a long fall, a high jump). The camera is clamped so the map's
+// edges stay at the screen's edges. Camera zones from the level override
+// this while the player is inside one.
+type CameraController struct {
+	Config  CameraConfig
+	NoShake bool // Shake does nothing, see Motion
+
+	camera     Camera
+	restY      float64 // Height the camera settles at
+	mapW, mapH float64
+	zones      []game.CameraZone
+	locked     bool // Last update was in a lock zone
+	started    bool
+
+	shake      float64 // Shake strength in world units
+	shakeTicks int     // Updates left to shake
+	shakeTotal int
+
+	fadeTicks int // Updates left fading in after a Cut
+	fadeTotal int
+}
+
+// NewCameraController returns a controller with the given tuning
+func NewCameraController(cfg CameraConfig) *CameraController {
+	return &CameraController{Config: cfg}
+}
+
+// SetBounds sets the map size, in world units, to clamp the camera to
+func (c *CameraController) SetBounds(mapW, mapH float64) {
+	c.mapW, c.mapH = mapW, mapH
+}
+
+// SetZones sets the level's camera zones
+func (c *CameraController) SetZones(zones []game.CameraZone) {
+	c.zones = zones
+}
+
+// Reset makes the next Update jump straight to the player, e.g. after a
+// level restart
+func (c *CameraController) Reset() {
+	c.started = false
+}
+
+// Cut makes the next Update jump straight to the player, as Reset does,
+// and fades the screen in from black over the given number of updates, to
+// hide the jump, e.g. after a teleport
+func (c *CameraController) Cut(fadeTicks int) {
+	c.started = false
+	c.fadeTicks, c.fadeTotal = max(fadeTicks, 0), max(fadeTicks, 0)
+}
+
+// Fade returns how dark to draw the screen while fading in after a Cut,
+// from 1 (black) down to 0
+func (c *CameraController) Fade() float64 {
+	if c.fadeTicks <= 0 {
+		return 0
+	}
+	return float64(c.fadeTicks) / float64(c.fadeTotal)
+}
+
+// Shake jolts the camera by up to strength world units, fading out over the
+// given number of updates. A weaker shake doesn't cut a stronger one short.
+func (c *CameraController) Shake(strength float64, ticks int) {
+	if c.NoShake || ticks <= 0 || strength*float64(ticks) < c.shake*float64(c.shakeTicks) {
+		return
+	}
+	c.shake, c.shakeTicks, c.shakeTotal = strength, ticks, ticks
+}
+
+// Update moves the camera for the player at (x, y) and a viewport of the
+// given size in world units, and returns it
+func (c *CameraController) Update(x, y float64, onGround bool, viewW, viewH float64) Camera {
+	jump := !c.started
+	if jump {
+		c.camera.Y, c.restY = y, y
+		c.started = true
+	}
+	c.camera.Width, c.camera.Height = viewW, viewH
+	if c.fadeTicks > 0 {
+		c.fadeTicks--
+	}
+
+	zone := c.zoneAt(x, y)
+	if zone != nil && zone.Lock != nil {
+		if jump {
+			c.camera.X, c.camera.Y = zone.Lock.X, zone.Lock.Y
+		}
+		c.lockTo(zone.Lock.X, zone.Lock.Y)
+		return c.shaken(c.camera)
+	}
+	if c.locked {
+		// Leaving a lock zone: follow from where the camera is
+		c.restY = c.camera.Y
+		c.locked = false
+	}
+	if onGround {
+		c.restY = y // Landed: settle at the new height
+	}
+
+	c.camera.X = x
+	c.camera.Y += (c.restY - c.camera.Y) * math.Min(math.Max(c.Config.PanRate, 0), 1)
+
+	// Keep the player inside the deadzone, which must fit on screen
+	dz := math.Min(c.Config.DeadzoneY, viewH/2-1)
+	dz = math.Max(dz, 0)
+	if y > c.camera.Y+dz {
+		c.camera.Y = y - dz
+		c.restY = c.camera.Y
+	} else if y < c.camera.Y-dz {
+		c.camera.Y = y + dz
+		c.restY = c.camera.Y
+	}
+
+	return c.shaken(c.clamp(c.camera, zone))
+}
+
+// Clamp keeps a camera inside the bounds Update would clamp it to for a
+// player at (x, y): the map's, or those of the clamp zone there. A lock
+// zone there doesn't move it.
+func (c *CameraController) Clamp(cam Camera, x, y float64) Camera {
+	return c.clamp(cam, c.zoneAt(x, y))
+}
+
+// Bounds returns the rect Clamp keeps the view inside for a player at
+// (x, y), for the collision overlay. A width or height of 0 is unbounded.
+func (c *CameraController) Bounds(x, y float64) game.Rect {
+	return c.bounds(c.zoneAt(x, y))
+}
+
+// bounds returns the zone's clamp rect if it has one, else the map's
+func (c *CameraController) bounds(zone *game.CameraZone) game.Rect {
+	if zone != nil && zone.Clamp != nil {
+		return *zone.Clamp
+	}
+	return game.Rect{W: c.mapW, H: c.mapH}
+}
+
+// clamp keeps a camera inside the map, or inside the zone's clamp rect if
+// it has one
+func (c *CameraController) clamp(cam Camera, zone *game.CameraZone) Camera {
+	bounds := c.bounds(zone)
+	cam.X = clampAxis(cam.X, cam.Width, bounds.X, bounds.W)
+	cam.Y = clampAxis(cam.Y, cam.Height, bounds.Y, bounds.H)
+	return cam
+}
+
+// FreeCamSpeed is how far the free camera flies per tick, in tiles
+const FreeCamSpeed = 0.5
+
+// FreeCam is a debug camera detached from the player, flown around to
+// inspect large levels. It goes anywhere unless Clamp is set; then the
+// view is clamped as CameraController would clamp it for a player at the
+// free camera's position, zones included, so clamping bugs can be
+// reproduced anywhere in a level.
+type FreeCam struct {
+	X, Y  float64 // Where it is flown to, unclamped
+	Clamp bool
+}
+
+// Fly moves the camera by dx and dy times FreeCamSpeed, for directions
+// from -1 to 1
+func (f *FreeCam) Fly(dx, dy float64) {
+	f.X += dx * FreeCamSpeed
+	f.Y += dy * FreeCamSpeed
+}
+
+// View returns the camera for a viewport of the given size in world
+// units, clamped by ctl if Clamp is set
+func (f *FreeCam) View(ctl *CameraController, viewW, viewH float64) Camera {
+	cam := Camera{X: f.X, Y: f.Y, Width: viewW, Height: viewH}
+	if f.Clamp {
+		cam = ctl.Clamp(cam, f.X, f.Y)
+	}
+	return cam
+}
+
+// shaken offsets the camera by the current shake, after clamping so the
+// shake still shows at the map's edges
+func (c *CameraController) shaken(cam Camera) Camera {
+	if c.shakeTicks <= 0 {
+		return cam
+	}
+	amount := c.shake * float64(c.shakeTicks) / float64(c.shakeTotal)
+	// Alternate sides each update; the vertical jolt is stronger, as for a landing
+	if c.shakeTicks%2 == 0 {
+		amount = -amount
+	}
+	cam.X += amount / 2
+	cam.Y += amount
+	c.shakeTicks--
+	return cam
+}
+
+// zoneAt returns the first camera zone containing the point, or nil
+func (c *CameraController) zoneAt(x, y float64) *game.CameraZone {
+	for i := range c.zones {
+		if c.zones[i].Area.Contains(x, y) {
+			return &c.zones[i]
+		}
+	}
+	return nil
+}
+
+// lockTo pans the camera toward a fixed center at PanRate
+func (c *CameraController) lockTo(x, y float64) {
+	rate := math.Min(math.Max(c.Config.PanRate, 0), 1)
+	c.locked = true
+	c.camera.X += (x - c.camera.X) * rate
+	c.camera.Y += (y - c.camera.Y) * rate
+}
+
+// clampAxis keeps a camera coordinate so the view stays inside
+// [start, start+size], centering ranges smaller than the view. A size of 0
+// means unbounded.
+func clampAxis(v, view, start, size float64) float64 {
+	if size <= 0 {
+		return v
+	}
+	lo, hi := start+view/2, start+size-view/2
+	if hi < lo {
+		return start + size/2
+	}
+	return math.Min(math.Max(v, lo), hi)
+}
@@ -0,0 +1,149 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+)
+
+// TestCameraDeadzone tests that small jumps leave the camera still, landing
+// on a new platform pans to it, and a long fall is followed at once.
+func TestCameraDeadzone(t *testing.T) {
+	c := NewCameraController(CameraConfig{DeadzoneY: 3, PanRate: 1})
+	c.SetBounds(100, 100)
+
+	cam := c.Update(50, 50, true, 40, 20)
+	if cam.Y != 50 {
+		t.Fatalf("Camera should start on the player, got y=%v", cam.Y)
+	}
+
+	// A small jump stays inside the deadzone
+	for _, y := range []float64{49, 48, 47.5, 48, 49} {
+		if cam = c.Update(50, y, false, 40, 20); cam.Y != 50 {
+			t.Errorf("Jump to y=%v moved the camera to %v", y, cam.Y)
+		}
+	}
+
+	// Landing on a higher platform pans to it
+	if cam = c.Update(50, 48, true, 40, 20); cam.Y != 48 {
+		t.Errorf("Landing at y=48 should move the camera there, got %v", cam.Y)
+	}
+
+	// Falling past the deadzone is followed, keeping the player at its edge
+	if cam = c.Update(50, 60, false, 40, 20); cam.Y != 57 {
+		t.Errorf("Falling to y=60 should pull the camera to 57, got %v", cam.Y)
+	}
+
+	// Clamped to the map
+	if cam = c.Update(2, 98, true, 40, 20); cam.X != 20 || cam.Y != 90 {
+		t.Errorf("Camera should be clamped to (20, 90), got (%v, %v)", cam.X, cam.Y)
+	}
+}
+
+// TestCameraZones tests that a lock zone fixes the camera and a clamp zone
+// bounds it to its rect while the player is inside.
+func TestCameraZones(t *testing.T) {
+	c := NewCameraController(CameraConfig{DeadzoneY: 3, PanRate: 1})
+	c.SetBounds(100, 100)
+	c.SetZones([]game.CameraZone{
+		{Area: game.Rect{X: 0, Y: 0, W: 30, H: 100}, Lock: &game.SpawnPoint{X: 20, Y: 40}},
+		{Area: game.Rect{X: 60, Y: 0, W: 10, H: 100}, Clamp: &game.Rect{X: 50, Y: 0, W: 30, H: 100}},
+	})
+
+	if cam := c.Update(10, 50, true, 40, 20); cam.X != 20 || cam.Y != 40 {
+		t.Errorf("Lock zone should hold the camera at (20, 40), got (%v, %v)", cam.X, cam.Y)
+	}
+	if cam := c.Update(69, 50, true, 20, 20); cam.X != 69 {
+		t.Errorf("Inside the clamp rect the camera should follow, got x=%v", cam.X)
+	}
+	if cam := c.Update(69.9, 50, true, 40, 20); cam.X != 65 {
+		t.Errorf("A view wider than the clamp rect should center on it, got x=%v", cam.X)
+	}
+	if cam := c.Update(45, 50, true, 40, 20); cam.X != 45 {
+		t.Errorf("Outside any zone the camera should follow, got x=%v", cam.X)
+	}
+	if b := c.Bounds(65, 50); b != (game.Rect{X: 50, Y: 0, W: 30, H: 100}) {
+		t.Errorf("Bounds in the clamp zone = %+v, want its clamp rect", b)
+	}
+	if b := c.Bounds(45, 50); b != (game.Rect{W: 100, H: 100}) {
+		t.Errorf("Bounds outside any zone = %+v, want the map", b)
+	}
+}
+
+// TestCameraShake tests that a shake offsets the camera even when clamped
+// and settles back once it runs out.
+func TestCameraShake(t *testing.T) {
+	c := NewCameraController(CameraConfig{DeadzoneY: 3, PanRate: 1})
+	c.SetBounds(100, 100)
+	c.Update(20, 90, true, 40, 20) // Clamped to (20, 90)
+
+	c.Shake(0.5, 4)
+	moved := false
+	for range 4 {
+		if cam := c.Update(20, 90, true, 40, 20); cam.X != 20 || cam.Y != 90 {
+			moved = true
+		}
+	}
+	if !moved {
+		t.Error("Shake should move the clamped camera")
+	}
+	if cam := c.Update(20, 90, true, 40, 20); cam.X != 20 || cam.Y != 90 {
+		t.Errorf("Camera should settle at (20, 90) after the shake, got (%v, %v)", cam.X, cam.Y)
+	}
+}
+
+// TestCameraCut tests that a cut jumps to the player, even into a lock
+// zone, and the screen fades in from black.
+func TestCameraCut(t *testing.T) {
+	c := NewCameraController(CameraConfig{DeadzoneY: 3, PanRate: 0.1})
+	c.SetBounds(100, 100)
+	c.SetZones([]game.CameraZone{{Area: game.Rect{X: 60, Y: 0, W: 40, H: 100}, Lock: &game.SpawnPoint{X: 80, Y: 50}}})
+	c.Update(20, 50, true, 40, 20)
+
+	c.Cut(4)
+	if c.Fade() != 1 {
+		t.Errorf("Fade = %v right after a cut, want 1", c.Fade())
+	}
+	if cam := c.Update(30, 30, false, 40, 20); cam.X != 30 || cam.Y != 30 {
+		t.Errorf("Cut should jump to (30, 30), got (%v, %v)", cam.X, cam.Y)
+	}
+	c.Cut(4)
+	if cam := c.Update(70, 50, true, 40, 20); cam.X != 80 || cam.Y != 50 {
+		t.Errorf("Cut into a lock zone should jump to (80, 50), got (%v, %v)", cam.X, cam.Y)
+	}
+	last := c.Fade()
+	for range 3 {
+		c.Update(70, 50, true, 40, 20)
+		if f := c.Fade(); f >= last {
+			t.Fatalf("Fade went from %v to %v, want it to fall", last, f)
+		}
+		last = c.Fade()
+	}
+	if last != 0 {
+		t.Errorf("Fade = %v after the cut's updates, want 0", last)
+	}
+}
+
+// TestFreeCam tests that the free camera flies past the map's edges, and
+// with Clamp set is clamped as the following camera would be there.
+func TestFreeCam(t *testing.T) {
+	c := NewCameraController(CameraConfig{DeadzoneY: 3, PanRate: 1})
+	c.SetBounds(100, 100)
+	c.SetZones([]game.CameraZone{{Area: game.Rect{X: 60, Y: 0, W: 10, H: 100}, Clamp: &game.Rect{X: 50, Y: 0, W: 30, H: 100}}})
+
+	f := &FreeCam{X: 20, Y: 50}
+	for range 40 {
+		f.Fly(-1, 0)
+	}
+	if cam := f.View(c, 40, 20); cam.X != 0 || cam.Y != 50 {
+		t.Errorf("Free camera at (%v, %v), want past the edge at (0, 50)", cam.X, cam.Y)
+	}
+	f.Clamp = true
+	if cam := f.View(c, 40, 20); cam.X != 20 {
+		t.Errorf("Clamped free camera at x=%v, want 20", cam.X)
+	}
+	f.X = 69.9
+	if cam := f.View(c, 40, 20); cam.X != 65 {
+		t.Errorf("Free camera in the clamp zone at x=%v, want centered on it at 65", cam.X)
+	}
+}
@@ -0,0 +1,62 @@
+package render
+
+import "github.com/andersfylling/rayman-slides/internal/game"
+
+// ChargeFlashTicks is how long the charge meter flashes on reaching full
+// charge
+const ChargeFlashTicks = 12
+
+// ChargeMeter is the HUD's view of the local player's attack charge: a bar
+// in game.ChargeLevels stages, the same stages as the charge sprites, that
+// flashes briefly once the charge is full. It is fed World.PlayerCharge
+// once per tick.
+type ChargeMeter struct {
+	progress float64 // 0 to 1, full at game.MaxChargeTicks
+	level    int     // Charge stage, 0 when not charging
+	flash    int     // Flash ticks left
+}
+
+// NewChargeMeter creates a hidden charge meter
+func NewChargeMeter() *ChargeMeter {
+	return &ChargeMeter{}
+}
+
+// Update takes the local player's charge for this tick
+func (m *ChargeMeter) Update(progress float64, level int, charging bool) {
+	if !charging {
+		progress, level = 0, 0
+	}
+	if m.flash > 0 {
+		m.flash--
+	}
+	if progress >= 1 && m.progress < 1 {
+		m.flash = ChargeFlashTicks
+	}
+	if level == 0 {
+		m.flash = 0
+	}
+	m.progress, m.level = progress, level
+}
+
+// Visible reports whether the player is charging
+func (m *ChargeMeter) Visible() bool {
+	return m.level > 0
+}
+
+// Level returns the charge stage, from 1 to game.ChargeLevels, or 0 when not
+// charging
+func (m *ChargeMeter) Level() int {
+	return m.level
+}
+
+// Fill returns how full a stage's segment is, from 0 to 1. Stages are
+// numbered from 1; a segment fills as the charge crosses its third.
+func (m *ChargeMeter) Fill(stage int) float64 {
+	fill := m.progress*game.ChargeLevels - float64(stage-1)
+	return min(max(fill, 0), 1)
+}
+
+// Flash returns how much of the full-charge flash is left, from 1 down to 0
+func (m *ChargeMeter) Flash() float64 {
+	return float64(m.flash) / ChargeFlashTicks
+}
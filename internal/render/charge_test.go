@@ -0,0 +1,53 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// TestChargeMeter tests that the meter's stages follow the charge sprites,
+// that it flashes once on reaching full charge, and hides on release.
+func TestChargeMeter(t *testing.T) {
+	world := game.NewWorld()
+	world.SpawnPlayer(1, "Test", 10, 10)
+	m := NewChargeMeter()
+
+	flashes := 0
+	for tick := 1; tick <= game.MaxChargeTicks+ChargeFlashTicks+10; tick++ {
+		world.SetPlayerIntent(1, protocol.IntentAttack)
+		world.Update()
+		m.Update(world.PlayerCharge(1))
+		if !m.Visible() {
+			t.Fatalf("Tick %d: the meter should show while charging", tick)
+		}
+		if m.Level() != game.ChargeLevel(tick) {
+			t.Fatalf("Tick %d: stage %d, want the sprite's %d", tick, m.Level(), game.ChargeLevel(tick))
+		}
+		if m.Fill(m.Level()) == 0 {
+			t.Fatalf("Tick %d: the current stage's segment should be filling", tick)
+		}
+		if m.Flash() == 1 {
+			flashes++
+			if tick != game.MaxChargeTicks {
+				t.Errorf("Flash started at tick %d, want %d", tick, game.MaxChargeTicks)
+			}
+		}
+	}
+	if flashes != 1 || m.Flash() != 0 {
+		t.Errorf("Expected one flash that ends, got %d (flash %v)", flashes, m.Flash())
+	}
+	for stage := 1; stage <= game.ChargeLevels; stage++ {
+		if m.Fill(stage) != 1 {
+			t.Errorf("Stage %d should be full, got %v", stage, m.Fill(stage))
+		}
+	}
+
+	world.SetPlayerIntent(1, protocol.IntentNone)
+	world.Update()
+	m.Update(world.PlayerCharge(1))
+	if m.Visible() || m.Fill(1) != 0 {
+		t.Error("The meter should hide when the attack is released")
+	}
+}
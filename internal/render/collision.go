@@ -0,0 +1,172 @@
+package render
+
+import (
+	"math"
+
+	"github.com/andersfylling/rayman-slides/internal/collision"
+	"github.com/andersfylling/rayman-slides/internal/game"
+)
+
+// CollisionOverlay is the collision debug overlay: tiles tinted by their
+// collision flags, the boxes the simulation collides with and the rect the
+// camera is clamped to. The GUI builds one each frame from
+// game.World.AppendDebugBoxes and CameraController.Bounds.
+type CollisionOverlay struct {
+	Tiles *collision.TileMap
+	Boxes []game.DebugBox
+	Clamp game.Rect // Camera clamp bounds; not drawn with a zero width or height
+}
+
+// tileFlagLook is how the overlay shows one collision flag
+type tileFlagLook struct {
+	flag collision.TileFlag
+	rgb  uint32
+	cell Cell
+}
+
+// tileFlagLooks are in priority order: a tile with several flags, such as
+// a breakable solid one, shows the first that matches
+var tileFlagLooks = []tileFlagLook{
+	{collision.TileHazard, 0xFF3030, Cell{Glyph: '!', Fg: ANSIBrightRed, Bg: ANSIRed}},
+	{collision.TileBreakable, 0xE040E0, Cell{Glyph: '%', Fg: ANSIBrightMagenta, Bg: ANSIMagenta}},
+	{collision.TileSolid, 0x3070FF, Cell{Glyph: '#', Fg: ANSIBrightBlue, Bg: ANSIBlue}},
+	{collision.TilePlatform, 0xF0F0F0, Cell{Glyph: '=', Fg: ANSIBrightWhite, Bg: ANSIWhite}},
+	{collision.TileLadder, 0xE0C030, Cell{Glyph: 'H', Fg: ANSIBrightYellow, Bg: ANSIYellow}},
+	{collision.TileWater, 0x30D0E0, Cell{Glyph: '~', Fg: ANSIBrightCyan, Bg: ANSICyan}},
+}
+
+// flagLook returns the look of a tile's highest priority flag, or false for
+// a tile without any
+func flagLook(flag collision.TileFlag) (tileFlagLook, bool) {
+	for _, l := range tileFlagLooks {
+		if flag&l.flag != 0 {
+			return l, true
+		}
+	}
+	return tileFlagLook{}, false
+}
+
+// TileFlagColor returns the 0xRRGGBB color the overlay tints a tile with
+// for its collision flags, or false for a tile without any
+func TileFlagColor(flag collision.TileFlag) (uint32, bool) {
+	l, ok := flagLook(flag)
+	return l.rgb, ok
+}
+
+// DebugBoxColor returns the 0xRRGGBB color of a box's outline
+func DebugBoxColor(kind game.DebugBoxKind) uint32 {
+	switch kind {
+	case game.DebugHitbox:
+		return 0xFF40FF
+	case game.DebugFist:
+		return 0xFFFFFF
+	}
+	return 0xFFFF40
+}
+
+// ClampColor is the color of the camera clamp rect's outline
+const ClampColor = 0x40FF40
+
+// debugBoxCells are the glyphs a cell renderer outlines boxes with
+var debugBoxCells = map[game.DebugBoxKind]Cell{
+	game.DebugBody:   {Glyph: '.', Fg: ANSIBrightYellow, Bold: true},
+	game.DebugHitbox: {Glyph: ':', Fg: ANSIBrightMagenta, Bold: true},
+	game.DebugFist:   {Glyph: '-', Fg: ANSIBrightWhite, Bold: true},
+}
+
+// Apply draws the overlay over a cell renderer's frame of cols×rows cells
+// showing the view, in tiles. Every cell is tinted by the flags of the tile
+// under its center, and blank ones also get the flag's glyph, so flags
+// don't depend on color alone. Box outlines and a fist's remaining travel
+// are drawn in blank cells and the camera clamp rect's edges tinted green;
+// entity glyphs are kept.
+func (o *CollisionOverlay) Apply(cells []Cell, cols, rows int, view game.Rect) {
+	if cols <= 0 || rows <= 0 || len(cells) < cols*rows || view.W <= 0 || view.H <= 0 {
+		return
+	}
+	cellW, cellH := view.W/float64(cols), view.H/float64(rows)
+	// span returns the cells from a to b in world units along one axis,
+	// unclamped; the last is the one holding b, not one starting there
+	span := func(a, b, start, size float64) (int, int) {
+		return int(math.Floor((a - start) / size)), int(math.Ceil((b-start)/size)) - 1
+	}
+	at := func(x, y int) *Cell {
+		if x < 0 || x >= cols || y < 0 || y >= rows {
+			return nil
+		}
+		return &cells[y*cols+x]
+	}
+	blank := func(c *Cell) bool { return c.Glyph == ' ' || c.Glyph == 0 }
+
+	if o.Tiles != nil {
+		for y := range rows {
+			ty := int(math.Floor(view.Y + (float64(y)+0.5)*cellH))
+			for x := range cols {
+				tx := int(math.Floor(view.X + (float64(x)+0.5)*cellW))
+				if tx < 0 || tx >= o.Tiles.Width || ty < 0 || ty >= o.Tiles.Height {
+					continue
+				}
+				l, ok := flagLook(o.Tiles.Get(tx, ty))
+				if !ok {
+					continue
+				}
+				c := at(x, y)
+				if blank(c) {
+					*c = l.cell
+					continue
+				}
+				c.Bg = l.cell.Bg
+				if c.Fg == c.Bg {
+					c.Fg = ANSIBrightWhite // Never invisible on its background
+				}
+			}
+		}
+	}
+
+	mark := func(c *Cell, look Cell) {
+		if c != nil && (blank(c) || c.Glyph == look.Glyph) {
+			bg := c.Bg
+			*c = look
+			c.Bg = bg
+		}
+	}
+	for _, b := range o.Boxes {
+		look := debugBoxCells[b.Kind]
+		x0, x1 := span(b.Box.X, b.Box.X+b.Box.Width, view.X, cellW)
+		y0, y1 := span(b.Box.Y, b.Box.Y+b.Box.Height, view.Y, cellH)
+		for x := x0; x <= x1; x++ {
+			mark(at(x, y0), look)
+			mark(at(x, y1), look)
+		}
+		for y := y0 + 1; y < y1; y++ {
+			mark(at(x0, y), look)
+			mark(at(x1, y), look)
+		}
+		if b.Kind == game.DebugFist && b.Reach != 0 {
+			cx, cy := b.Box.Center()
+			from, to := span(min(cx, cx+b.Reach), max(cx, cx+b.Reach), view.X, cellW)
+			row := int(math.Floor((cy - view.Y) / cellH))
+			for x := from; x <= to; x++ {
+				mark(at(x, row), look)
+			}
+		}
+	}
+
+	if o.Clamp.W > 0 && o.Clamp.H > 0 {
+		x0, x1 := span(o.Clamp.X, o.Clamp.X+o.Clamp.W, view.X, cellW)
+		y0, y1 := span(o.Clamp.Y, o.Clamp.Y+o.Clamp.H, view.Y, cellH)
+		for y := max(y0, 0); y <= min(y1, rows-1); y++ {
+			for x := max(x0, 0); x <= min(x1, cols-1); x++ {
+				if x != x0 && x != x1 && y != y0 && y != y1 {
+					continue
+				}
+				if c := at(x, y); c != nil {
+					c.Bg = ANSIGreen
+					if c.Fg == c.Bg {
+						c.Fg = ANSIBrightWhite
+					}
+				}
+			}
+		}
+	}
+}
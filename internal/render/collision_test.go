@@ -0,0 +1,67 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/collision"
+	"github.com/andersfylling/rayman-slides/internal/game"
+)
+
+// TestCollisionOverlayCells tests that a cell renderer's overlay marks tile
+// flags by glyph as well as color, outlines boxes in blank cells while
+// keeping entity glyphs, draws a fist's remaining travel and tints the
+// clamp rect's edges.
+func TestCollisionOverlayCells(t *testing.T) {
+	tiles := collision.NewTileMap(10, 5)
+	tiles.Set(0, 4, collision.TileSolid|collision.TileBreakable)
+	tiles.Set(1, 4, collision.TileSolid)
+	tiles.Set(2, 4, collision.TileHazard)
+	tiles.Set(3, 4, collision.TilePlatform)
+
+	const cols, rows = 10, 5
+	cells := make([]Cell, cols*rows)
+	for i := range cells {
+		cells[i] = Cell{Glyph: ' '}
+	}
+	cells[3*cols+5] = Cell{Glyph: '@', Fg: ANSIBrightBlue, Bold: true}
+	cells[4*cols+1] = Cell{Glyph: '#', Fg: ANSIBlue}
+
+	o := &CollisionOverlay{
+		Tiles: tiles,
+		Boxes: []game.DebugBox{
+			{Box: collision.NewAABB(4.6, 3.1, 0.8, 0.9), Kind: game.DebugHitbox},
+			{Box: collision.NewAABB(1.8, 1.3, 0.4, 0.4), Kind: game.DebugFist, Reach: 3},
+		},
+		Clamp: game.Rect{X: 0, Y: 0, W: 10, H: 4},
+	}
+	o.Apply(cells, cols, rows, game.Rect{W: 10, H: 5})
+	cell := func(x, y int) Cell { return cells[y*cols+x] }
+
+	// Breakable wins over solid; a blank cell gets the flag's glyph
+	if c := cell(0, 4); c.Glyph != '%' || c.Bg != ANSIMagenta {
+		t.Errorf("Breakable solid tile = %+v, want %% on magenta", c)
+	}
+	if c := cell(1, 4); c.Glyph != '#' || c.Bg != ANSIBlue || c.Fg == c.Bg {
+		t.Errorf("Drawn solid tile = %+v, want its glyph on blue, still visible", c)
+	}
+	if c := cell(2, 4); c.Glyph != '!' {
+		t.Errorf("Hazard tile = %+v, want !", c)
+	}
+	if c := cell(5, 3); c.Glyph != '@' || c.Fg != ANSIBrightBlue {
+		t.Errorf("Player cell = %+v, want its glyph kept", c)
+	}
+	for x := 1; x <= 4; x++ {
+		if c := cell(x, 1); c.Glyph != '-' {
+			t.Errorf("Fist travel cell %d = %q, want -", x, c.Glyph)
+		}
+	}
+	if c := cell(5, 1); c.Glyph == '-' {
+		t.Error("Fist travel drawn past its reach")
+	}
+	if c := cell(9, 2); c.Bg != ANSIGreen {
+		t.Errorf("Clamp edge cell = %+v, want tinted green", c)
+	}
+	if c := cell(4, 2); c.Bg == ANSIGreen {
+		t.Error("Clamp rect filled, want its edges only")
+	}
+}
@@ -0,0 +1,83 @@
+package render
+
+import (
+	"fmt"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+)
+
+// CombatLog keeps the last combat events as text for the debug overlay,
+// newest last, and a scroll position into them
+type CombatLog struct {
+	lines  []string
+	size   int
+	scroll int // Lines scrolled back from the newest
+}
+
+// NewCombatLog creates a log keeping up to size lines
+func NewCombatLog(size int) *CombatLog {
+	return &CombatLog{size: size}
+}
+
+// Add logs a damage, block or death event; other events are ignored
+func (l *CombatLog) Add(e game.Event) {
+	var line string
+	switch e.Type {
+	case game.EventDamage:
+		line = fmt.Sprintf("%6d %s hit %s for %d", e.Tick, attackerName(e.Attacker), victimName(e), e.Amount)
+	case game.EventBlock:
+		line = fmt.Sprintf("%6d %s blocked %s", e.Tick, victimName(e), attackerName(e.Attacker))
+	case game.EventDeath:
+		line = fmt.Sprintf("%6d %s killed %s", e.Tick, attackerName(e.Attacker), victimName(e))
+	default:
+		return
+	}
+	if len(l.lines) == l.size {
+		copy(l.lines, l.lines[1:])
+		l.lines = l.lines[:l.size-1]
+	}
+	l.lines = append(l.lines, line)
+	if l.scroll > 0 {
+		// Keep the view on the same lines while scrolled back
+		l.scroll = min(l.scroll+1, len(l.lines)-1)
+	}
+}
+
+// Scroll moves the view n lines back (positive) or forward (negative),
+// staying within the log
+func (l *CombatLog) Scroll(n int) {
+	l.scroll = max(0, min(l.scroll+n, len(l.lines)-1))
+}
+
+// Len returns the number of lines kept
+func (l *CombatLog) Len() int {
+	return len(l.lines)
+}
+
+// View returns up to n lines ending at the scroll position, oldest first
+func (l *CombatLog) View(n int) []string {
+	end := len(l.lines) - l.scroll
+	return l.lines[max(0, end-n):end]
+}
+
+// Header returns a title line with the scroll position
+func (l *CombatLog) Header() string {
+	if l.scroll == 0 {
+		return fmt.Sprintf("combat log (%d) PgUp/PgDn", len(l.lines))
+	}
+	return fmt.Sprintf("combat log (%d, %d back) PgUp/PgDn", len(l.lines), l.scroll)
+}
+
+func attackerName(player int) string {
+	if player == 0 {
+		return "enemy"
+	}
+	return fmt.Sprintf("player %d", player)
+}
+
+func victimName(e game.Event) string {
+	if e.Player != 0 {
+		return fmt.Sprintf("player %d", e.Player)
+	}
+	return fmt.Sprintf("%s #%d", e.Kind, e.Entity)
+}
@@ -0,0 +1,42 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+)
+
+// TestCombatLog tests that the log keeps the newest lines, formats combat
+// events, and scrolls within its bounds.
+func TestCombatLog(t *testing.T) {
+	l := NewCombatLog(3)
+	l.Add(game.Event{Type: game.EventFinish, Player: 1}) // Not combat
+	l.Add(game.Event{Type: game.EventDamage, Tick: 1, Entity: 5, Kind: "slime", Attacker: 1, Amount: 2})
+	l.Add(game.Event{Type: game.EventDeath, Tick: 2, Player: 2})
+	if l.Len() != 2 {
+		t.Fatalf("Only combat events should be logged, got %d lines", l.Len())
+	}
+	if got, want := l.View(1)[0], "     2 enemy killed player 2"; got != want {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+
+	for tick := uint64(3); tick < 6; tick++ {
+		l.Add(game.Event{Type: game.EventBlock, Tick: tick, Entity: 7, Kind: "shield_slime", Attacker: 1})
+	}
+	if l.Len() != 3 {
+		t.Fatalf("Log should keep its last 3 lines, got %d", l.Len())
+	}
+
+	l.Scroll(10)
+	if v := l.View(3); len(v) != 1 || v[0] != "     3 shield_slime #7 blocked player 1" {
+		t.Errorf("Scrolled all the way back should show the oldest line, got %q", v)
+	}
+	l.Add(game.Event{Type: game.EventBlock, Tick: 6, Entity: 7, Kind: "shield_slime", Attacker: 1})
+	if v := l.View(3); len(v) != 1 || v[0] != "     4 shield_slime #7 blocked player 1" {
+		t.Errorf("Scrolled back, the view should stay on the oldest kept line, got %q", v)
+	}
+	l.Scroll(-10)
+	if v := l.View(1); v[0] != "     6 shield_slime #7 blocked player 1" {
+		t.Errorf("Scrolled forward should show the newest line, got %q", v)
+	}
+}
@@ -0,0 +1,78 @@
+package render
+
+import (
+	"strings"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/i18n"
+)
+
+// ComboTiers are the combo lengths at which the combo meter grows louder,
+// from the first one it shows at
+var ComboTiers = []int{2, 5, 10, 20}
+
+// ComboPopTicks is how long the combo count swells after each kill
+const ComboPopTicks = 10
+
+// ComboMeter is the HUD's view of the local player's combo: the kill count,
+// louder at every tier of ComboTiers, swelling on each kill, over a bar
+// draining until the combo runs out. It is fed World.Combo once per tick.
+type ComboMeter struct {
+	Lang *i18n.Catalog // nil for English
+
+	kills int // Kills in the combo, 0 when none
+	left  int // Ticks left to extend it
+	pop   int // Swell ticks left
+}
+
+// NewComboMeter creates a hidden combo meter
+func NewComboMeter() *ComboMeter {
+	return &ComboMeter{}
+}
+
+// Update takes the local player's combo for this tick
+func (m *ComboMeter) Update(kills, ticksLeft int) {
+	if m.pop > 0 {
+		m.pop--
+	}
+	if kills > m.kills {
+		m.pop = ComboPopTicks
+	}
+	if kills == 0 {
+		m.pop = 0
+	}
+	m.kills, m.left = kills, ticksLeft
+}
+
+// Visible reports whether the combo is long enough to show
+func (m *ComboMeter) Visible() bool {
+	return m.Tier() > 0
+}
+
+// Tier returns how many of ComboTiers the combo has reached, 0 when it is
+// too short to show
+func (m *ComboMeter) Tier() int {
+	tier := 0
+	for _, n := range ComboTiers {
+		if m.kills >= n {
+			tier++
+		}
+	}
+	return tier
+}
+
+// Text returns the count, with an exclamation mark per tier past the first
+func (m *ComboMeter) Text() string {
+	return m.Lang.T("hud.combo", m.kills) + strings.Repeat("!", max(m.Tier()-1, 0))
+}
+
+// Left returns how much of the window for the next kill is left, from 1
+// down to 0
+func (m *ComboMeter) Left() float64 {
+	return min(float64(m.left)/game.ComboTicks, 1)
+}
+
+// Pop returns how much of the swell after a kill is left, from 1 down to 0
+func (m *ComboMeter) Pop() float64 {
+	return float64(m.pop) / ComboPopTicks
+}
@@ -0,0 +1,41 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+)
+
+// TestComboMeter tests that the meter shows from the first tier, grows
+// louder at each one, swells on every kill and hides when the combo ends.
+func TestComboMeter(t *testing.T) {
+	m := NewComboMeter()
+	m.Update(1, game.ComboTicks)
+	if m.Visible() {
+		t.Error("A single kill shouldn't show")
+	}
+
+	m.Update(2, game.ComboTicks)
+	if !m.Visible() || m.Tier() != 1 || m.Text() != "Combo x2" {
+		t.Errorf("Two kills: visible %v, tier %d, %q", m.Visible(), m.Tier(), m.Text())
+	}
+	if m.Pop() != 1 || m.Left() != 1 {
+		t.Errorf("A kill should swell the count and fill the bar, got pop %v, left %v", m.Pop(), m.Left())
+	}
+	for range ComboPopTicks {
+		m.Update(2, game.ComboTicks/2)
+	}
+	if m.Pop() != 0 || m.Left() != 0.5 {
+		t.Errorf("Without kills the swell should end and the bar drain, got pop %v, left %v", m.Pop(), m.Left())
+	}
+
+	m.Update(10, game.ComboTicks)
+	if m.Tier() != 3 || m.Text() != "Combo x10!!" {
+		t.Errorf("Ten kills: tier %d, %q", m.Tier(), m.Text())
+	}
+
+	m.Update(0, 0)
+	if m.Visible() || m.Pop() != 0 {
+		t.Error("The meter should hide when the combo ends")
+	}
+}
@@ -0,0 +1,29 @@
+package render
+
+import "github.com/andersfylling/rayman-slides/internal/i18n"
+
+// ConsoleRows is how many lines of output the console shows by default
+const ConsoleRows = 12
+
+// Console is the developer console overlay: a title, the latest output,
+// oldest first, and the line being typed
+type Console struct {
+	Output []string
+	Input  string
+	Rows   int           // Output lines shown; ConsoleRows if zero
+	Lang   *i18n.Catalog // nil for English
+}
+
+// Lines formats the console as text rows, title first and the input line
+// last, with a cursor
+func (c *Console) Lines() []string {
+	rows := c.Rows
+	if rows <= 0 {
+		rows = ConsoleRows
+	}
+	output := c.Output[max(len(c.Output)-rows, 0):]
+	lines := make([]string, 0, len(output)+2)
+	lines = append(lines, c.Lang.T("console.title"))
+	lines = append(lines, output...)
+	return append(lines, "> "+c.Input+"_")
+}
@@ -0,0 +1,16 @@
+package render
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestConsoleLines tests that the console shows the latest output under
+// its title, above the input line.
+func TestConsoleLines(t *testing.T) {
+	c := &Console{Output: []string{"one", "two", "three"}, Input: "tp 3", Rows: 2}
+	want := []string{"Developer console (Esc to close, help for commands)", "two", "three", "> tp 3_"}
+	if got := c.Lines(); !slices.Equal(got, want) {
+		t.Errorf("Lines = %q, want %q", got, want)
+	}
+}
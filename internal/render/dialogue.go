@@ -0,0 +1,90 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/i18n"
+)
+
+// DialogueBox is the local player's open dialogue: the page's speaker,
+// portrait and text, and on the last page the choices with the selected one
+// marked. Level text is translated by key where the catalog has one:
+// dialogue.<id>.<page> for pages and dialogue.<id>.choice.<n> for choices.
+type DialogueBox struct {
+	State game.DialogueState
+	Lang  *i18n.Catalog // nil for English
+}
+
+// Visible reports whether there is a dialogue to show
+func (b *DialogueBox) Visible() bool {
+	return b != nil && b.State.Dialogue != nil && b.State.Page < len(b.State.Dialogue.Pages)
+}
+
+// page returns the page shown
+func (b *DialogueBox) page() game.DialoguePage {
+	return b.State.Dialogue.Pages[b.State.Page]
+}
+
+// Speaker returns who speaks on the page, "" for a narrator
+func (b *DialogueBox) Speaker() string {
+	return b.page().Speaker
+}
+
+// Portrait returns the atlas sprite beside the text, "" for none
+func (b *DialogueBox) Portrait() string {
+	return b.page().Portrait
+}
+
+// Text returns the page's text
+func (b *DialogueBox) Text() string {
+	d := b.State.Dialogue
+	return b.Lang.Or(fmt.Sprintf("dialogue.%s.%d", d.ID, b.State.Page), b.page().Text)
+}
+
+// Lines wraps the page's text to at most width cells per line, for
+// backends that lay out text themselves
+func (b *DialogueBox) Lines(width int) []string {
+	return wrapText(b.Text(), width)
+}
+
+// Choices returns the choices to show, none before the last page, and the
+// index of the selected one
+func (b *DialogueBox) Choices() (choices []string, selected int) {
+	d := b.State.Dialogue
+	if !b.State.LastPage() {
+		return nil, 0
+	}
+	for i, c := range d.Choices {
+		choices = append(choices, b.Lang.Or(fmt.Sprintf("dialogue.%s.choice.%d", d.ID, i), c.Text))
+	}
+	return choices, b.State.Choice
+}
+
+// Prompt returns the keys to press: to turn the page, choose, or close
+func (b *DialogueBox) Prompt() string {
+	switch {
+	case !b.State.LastPage():
+		return b.Lang.T("dialogue.next")
+	case len(b.State.Dialogue.Choices) > 0:
+		return b.Lang.T("dialogue.choose")
+	default:
+		return b.Lang.T("dialogue.close")
+	}
+}
+
+// Sentence returns the page as one line for a screen reader: the speaker,
+// the text and any choices, the selected one marked
+func (b *DialogueBox) Sentence() string {
+	text := b.Text()
+	if speaker := b.Speaker(); speaker != "" {
+		text = speaker + ": " + text
+	}
+	choices, selected := b.Choices()
+	if len(choices) == 0 {
+		return text
+	}
+	choices[selected] = "> " + choices[selected]
+	return text + " " + strings.Join(choices, ", ")
+}
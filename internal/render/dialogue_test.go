@@ -0,0 +1,51 @@
+package render
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+)
+
+// TestDialogueBox tests that the box shows the page, the choices only on
+// the last page, and the prompt for each.
+func TestDialogueBox(t *testing.T) {
+	d := &game.Dialogue{
+		ID: "ferry",
+		Pages: []game.DialoguePage{
+			{Speaker: "Ferryman", Portrait: "ferryman", Text: "The river is high today."},
+			{Speaker: "Ferryman", Text: "Two orbs to cross."},
+		},
+		Choices: []game.DialogueChoice{{Text: "Pay"}, {Text: "Swim"}},
+	}
+	var none *DialogueBox
+	if none.Visible() {
+		t.Error("A nil box should be hidden")
+	}
+
+	b := &DialogueBox{State: game.DialogueState{Dialogue: d}}
+	if !b.Visible() || b.Speaker() != "Ferryman" || b.Portrait() != "ferryman" {
+		t.Fatalf("First page: speaker %q, portrait %q", b.Speaker(), b.Portrait())
+	}
+	if got := b.Lines(12); !reflect.DeepEqual(got, []string{"The river is", "high today."}) {
+		t.Errorf("Lines = %q", got)
+	}
+	if choices, _ := b.Choices(); choices != nil {
+		t.Errorf("Choices before the last page: %q", choices)
+	}
+	if b.Prompt() != "Jump or attack: continue" {
+		t.Errorf("Prompt = %q", b.Prompt())
+	}
+
+	b.State.Page, b.State.Choice = 1, 1
+	choices, selected := b.Choices()
+	if !reflect.DeepEqual(choices, []string{"Pay", "Swim"}) || selected != 1 {
+		t.Errorf("Choices = %q, selected %d", choices, selected)
+	}
+	if got := b.Sentence(); got != "Ferryman: Two orbs to cross. Pay, > Swim" {
+		t.Errorf("Sentence = %q", got)
+	}
+	if b.Prompt() != "Left, right: choose; jump or attack: pick" {
+		t.Errorf("Prompt = %q", b.Prompt())
+	}
+}
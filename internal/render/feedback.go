@@ -0,0 +1,150 @@
+package render
+
+import (
+	"fmt"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// Hit feedback timing, in ticks
+const (
+	FlashTicks      = 6   // A hit entity draws white this long
+	HealthBarTicks  = 180 // A damaged enemy shows its health bar this long
+	DeathFrames     = 4   // Frames in a death animation
+	DeathFrameTicks = 6   // Ticks per death animation frame
+	NumberTicks     = 45  // A damage number floats up this long
+	NumberRise      = 0.03
+)
+
+// DamageNumber is a damage amount floating up from where it was dealt
+type DamageNumber struct {
+	X, Y   float64
+	Amount int
+	Age    int
+}
+
+// Fade returns how much of the number's life is left, from 1 down to 0
+func (n DamageNumber) Fade() float64 {
+	return 1 - float64(n.Age)/NumberTicks
+}
+
+// Death is a death animation playing where an entity died
+type Death struct {
+	X, Y float64
+	Kind string // Enemy type or sprite of the dead entity
+	Age  int
+}
+
+// Frame returns the animation frame, from 1 to DeathFrames
+func (d Death) Frame() int {
+	return d.Age/DeathFrameTicks + 1
+}
+
+// SpriteID returns the sprite for the current frame, e.g. "slime_death_2"
+func (d Death) SpriteID() string {
+	return fmt.Sprintf("%s_death_%d", d.Kind, d.Frame())
+}
+
+// HitFeedback shows what combat does: hit entities flash white, enemies
+// show a health bar for a while after each hit, damage numbers float up
+// from them, and dead ones leave a death animation. It is fed the world's
+// damage and death events and advanced once per tick; like particles it
+// only exists on the client.
+type HitFeedback struct {
+	// MinFlashGap, if set, is the fewest ticks from one flash of an
+	// entity to its next; hits in between don't flash again. See Motion.
+	MinFlashGap int
+
+	hits    map[protocol.EntityID]int // Ticks since the last hit
+	flashes map[protocol.EntityID]int // Ticks since the last flash started
+	deaths  []Death
+	numbers []DamageNumber
+}
+
+// NewHitFeedback creates feedback with nothing showing
+func NewHitFeedback() *HitFeedback {
+	return &HitFeedback{hits: make(map[protocol.EntityID]int), flashes: make(map[protocol.EntityID]int)}
+}
+
+// Hit records a hit on an entity at (x, y) for amount damage
+func (f *HitFeedback) Hit(id protocol.EntityID, x, y float64, amount int) {
+	f.hits[id] = 0
+	if age, ok := f.flashes[id]; !ok || age >= f.MinFlashGap {
+		f.flashes[id] = 0
+	}
+	f.numbers = append(f.numbers, DamageNumber{X: x, Y: y, Amount: amount})
+}
+
+// Death starts a death animation and forgets the entity's hits
+func (f *HitFeedback) Death(id protocol.EntityID, x, y float64, kind string) {
+	delete(f.hits, id)
+	delete(f.flashes, id)
+	f.deaths = append(f.deaths, Death{X: x, Y: y, Kind: kind})
+}
+
+// Update ages everything one tick, dropping what has run out
+func (f *HitFeedback) Update() {
+	for id, age := range f.hits {
+		if age+1 >= HealthBarTicks {
+			delete(f.hits, id)
+			continue
+		}
+		f.hits[id] = age + 1
+	}
+	for id, age := range f.flashes {
+		if age+1 >= max(FlashTicks, f.MinFlashGap) {
+			delete(f.flashes, id)
+			continue
+		}
+		f.flashes[id] = age + 1
+	}
+	live := f.deaths[:0]
+	for _, d := range f.deaths {
+		d.Age++
+		if d.Age < DeathFrames*DeathFrameTicks {
+			live = append(live, d)
+		}
+	}
+	f.deaths = live
+
+	numbers := f.numbers[:0]
+	for _, n := range f.numbers {
+		n.Age++
+		n.Y -= NumberRise
+		if n.Age < NumberTicks {
+			numbers = append(numbers, n)
+		}
+	}
+	f.numbers = numbers
+}
+
+// Flashing reports whether an entity's last flash started within
+// FlashTicks
+func (f *HitFeedback) Flashing(id protocol.EntityID) bool {
+	age, ok := f.flashes[id]
+	return ok && age < FlashTicks
+}
+
+// ShowHealth reports whether an entity's health bar is showing
+func (f *HitFeedback) ShowHealth(id protocol.EntityID) bool {
+	_, ok := f.hits[id]
+	return ok
+}
+
+// Deaths returns the death animations playing
+func (f *HitFeedback) Deaths() []Death {
+	return f.deaths
+}
+
+// Numbers returns the damage numbers floating
+func (f *HitFeedback) Numbers() []DamageNumber {
+	return f.numbers
+}
+
+// Clear drops all feedback, e.g. after a level restart
+func (f *HitFeedback) Clear() {
+	clear(f.hits)
+	clear(f.flashes)
+	f.deaths = f.deaths[:0]
+	f.numbers = f.numbers[:0]
+}
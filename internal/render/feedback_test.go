@@ -0,0 +1,46 @@
+package render
+
+import "testing"
+
+// TestHitFeedback tests that a hit flashes briefly, keeps the health bar up
+// longer, and that death animations run their frames and end.
+func TestHitFeedback(t *testing.T) {
+	f := NewHitFeedback()
+	f.Hit(7, 1, 2, 3)
+	if n := f.Numbers(); len(n) != 1 || n[0].Amount != 3 {
+		t.Fatalf("A hit should show its damage, got %+v", n)
+	}
+	if !f.Flashing(7) || !f.ShowHealth(7) || f.Flashing(8) {
+		t.Fatal("A hit entity should flash and show its health")
+	}
+	for range FlashTicks {
+		f.Update()
+	}
+	if f.Flashing(7) || !f.ShowHealth(7) {
+		t.Error("The flash should end before the health bar")
+	}
+	for range HealthBarTicks {
+		f.Update()
+	}
+	if f.ShowHealth(7) || len(f.Numbers()) != 0 {
+		t.Error("The health bar and damage number should be gone after HealthBarTicks")
+	}
+
+	f.Hit(9, 3, 4, 1)
+	f.Death(9, 3, 4, "slime")
+	if f.ShowHealth(9) || len(f.Deaths()) != 1 || f.Deaths()[0].SpriteID() != "slime_death_1" {
+		t.Fatalf("Death should replace the hit with an animation, got %+v", f.Deaths())
+	}
+	for range DeathFrameTicks * (DeathFrames - 1) {
+		f.Update()
+	}
+	if got := f.Deaths()[0].SpriteID(); got != "slime_death_4" {
+		t.Errorf("Last frame should be slime_death_4, got %s", got)
+	}
+	for range DeathFrameTicks {
+		f.Update()
+	}
+	if len(f.Deaths()) != 0 {
+		t.Error("The animation should end after its last frame")
+	}
+}
@@ -7,6 +7,9 @@ import (
 	"image"
 	"image/color"
 	"io/fs"
+	"math"
+	"strconv"
+	"strings"
 
 	"gioui.org/f32"
 	"gioui.org/layout"
@@ -14,6 +17,7 @@ import (
 	"gioui.org/op/clip"
 	"gioui.org/op/paint"
 	"gioui.org/text"
+	"gioui.org/unit"
 	"gioui.org/widget/material"
 
 	"github.com/andersfylling/rayman-slides/internal/game"
@@ -33,9 +37,41 @@ type GioRenderer struct {
 	hudText  string
 	theme    *material.Theme
 
+	renderables []game.Renderable // Reused every frame
+	debugLines  []string          // Debug overlay, hidden when empty
+	scoreboard  *Scoreboard       // Scoreboard or results screen, hidden when nil
+	leaderboard *Leaderboard      // Daily challenge results, hidden when nil
+	results     *LevelResults     // End-of-level stats and rank, hidden when nil
+	localPlayer int               // Player without a name tag
+	ghost       *game.Ghost       // Replay ghost, drawn behind everything
+	menu        *Menu             // Pause menu, drawn on top, hidden when nil
+	netGraph    *NetGraph         // Traffic graph, hidden when nil
+	browser     *Browser          // Server browser, drawn on top, hidden when nil
+	worldMap    *WorldMap         // Campaign level select, drawn on top, hidden when nil
+	console     *Console          // Developer console, drawn over everything, hidden when nil
+	collision   *CollisionOverlay // Collision boxes and tile flags over the scene, hidden when nil
+	particles   *Particles        // Dust and other effects, drawn over entities
+	feedback    *HitFeedback      // Hit flashes, health bars and death animations
+	warp        *TimeWarp         // Hitstop and slow motion, nil for none
+	charge      *ChargeMeter      // Local player's attack charge, shown while charging
+	combo       *ComboMeter       // Local player's combo, shown from its first tier
+	hints       *HintBox          // Tutorial hint, shown while one is up
+	vote        *VotePanel        // Players' vote, shown while there is one
+	dialogue    *DialogueBox      // Local player's dialogue, shown while open
+	prompt      string            // What the use key does here, see InteractPrompt
+	keys        []string          // Key colors the players hold, drawn as icons
+	ambience    *game.Ambience    // Level's tint and fog, drawn over the scene
+	weather     *WeatherParticles // Rain or snow, drawn over the scene
+	fade        float64           // Black over the scene, from 0 to 1, see CameraController.Fade
+	view        game.Rect         // Part of the level drawn last frame, in tiles
+	colorMode   ColorMode         // Color vision mode for sprites and UI colors
+	palette     Palette           // colorMode's hue-coded colors
+
 	// Sprite atlas
 	atlas    *Atlas
 	atlasOp  paint.ImageOp
+	regions  []atlasRegion // By game.SpriteID, see region
+	whiteOp  paint.ImageOp // The atlas in solid white, for hit flashes
 	useAtlas bool
 }
 
@@ -45,6 +81,7 @@ func NewGioRenderer() *GioRenderer {
 		tileSize: GioTilePixels,
 		theme:    material.NewTheme(),
 		useAtlas: false,
+		palette:  ColorNormal.Palette(),
 	}
 }
 
@@ -55,12 +92,28 @@ func (r *GioRenderer) LoadSprites(fsys fs.FS) error {
 		return err
 	}
 	r.atlas = atlas
-	r.atlasOp = paint.NewImageOp(atlas.Image)
+	r.regions = nil
+	r.atlasOp = paint.NewImageOp(r.colorMode.RemapImage(atlas.Image))
+	r.whiteOp = paint.NewImageOp(whiten(atlas.Image))
 	r.useAtlas = true
 	fmt.Println("Sprite atlas loaded successfully")
 	return nil
 }
 
+// SetColorMode adapts sprites, player colors and hue-coded UI such as
+// health bars for a color vision deficiency. Changing it remaps the whole
+// atlas, so call it when the setting changes, not every frame.
+func (r *GioRenderer) SetColorMode(m ColorMode) {
+	if m == r.colorMode {
+		return
+	}
+	r.colorMode = m
+	r.palette = m.Palette()
+	if r.atlas != nil {
+		r.atlasOp = paint.NewImageOp(m.RemapImage(r.atlas.Image))
+	}
+}
+
 // SetTileMap sets the background tile map.
 func (r *GioRenderer) SetTileMap(tiles [][]rune) {
 	r.tileMap = tiles
@@ -81,6 +134,153 @@ func (r *GioRenderer) SetHUD(text string) {
 	r.hudText = text
 }
 
+// SetDebugLines sets the debug overlay text, one entry per line.
+// An empty slice hides the overlay.
+func (r *GioRenderer) SetDebugLines(lines []string) {
+	r.debugLines = lines
+}
+
+// SetBrowser shows the server browser; nil hides it
+func (r *GioRenderer) SetBrowser(b *Browser) {
+	r.browser = b
+}
+
+// SetWorldMap shows the campaign's world map; nil hides it
+func (r *GioRenderer) SetWorldMap(m *WorldMap) {
+	r.worldMap = m
+}
+
+// SetConsole shows the developer console across the top of the window;
+// nil hides it
+func (r *GioRenderer) SetConsole(c *Console) {
+	r.console = c
+}
+
+// SetCollisionOverlay draws collision boxes, tile flags and the camera
+// clamp rect over the scene; nil hides it
+func (r *GioRenderer) SetCollisionOverlay(o *CollisionOverlay) {
+	r.collision = o
+}
+
+// SetNetGraph shows a traffic graph in the top-right corner; nil hides it
+func (r *GioRenderer) SetNetGraph(g *NetGraph) {
+	r.netGraph = g
+}
+
+// SetParticles sets the effects to draw over the world's entities
+func (r *GioRenderer) SetParticles(p *Particles) {
+	r.particles = p
+}
+
+// SetFeedback sets the hit feedback to draw: flashing hit entities, enemy
+// health bars and death animations
+func (r *GioRenderer) SetFeedback(f *HitFeedback) {
+	r.feedback = f
+}
+
+// SetTimeWarp draws entities where a time warp has them rather than where
+// the world does; nil draws the world as it is
+func (r *GioRenderer) SetTimeWarp(t *TimeWarp) {
+	r.warp = t
+}
+
+// SetComboMeter sets the combo to show at the bottom right of the screen
+func (r *GioRenderer) SetComboMeter(m *ComboMeter) {
+	r.combo = m
+}
+
+// SetChargeMeter sets the attack charge to show at the bottom of the screen
+func (r *GioRenderer) SetChargeMeter(m *ChargeMeter) {
+	r.charge = m
+}
+
+// SetHints sets the tutorial hints to show in a box near the top of the
+// screen
+func (r *GioRenderer) SetHints(b *HintBox) {
+	r.hints = b
+}
+
+// SetVote sets the voting overlay, shown on the left while there is a vote
+func (r *GioRenderer) SetVote(v *VotePanel) {
+	r.vote = v
+}
+
+// SetDialogue sets the local player's dialogue box; nil or a closed
+// dialogue hides it
+func (r *GioRenderer) SetDialogue(b *DialogueBox) {
+	r.dialogue = b
+}
+
+// SetPrompt sets the prompt shown above the bottom edge while the local
+// player stands by an object they can use; "" hides it
+func (r *GioRenderer) SetPrompt(text string) {
+	r.prompt = text
+}
+
+// SetKeys sets the colors of the keys the players hold (see
+// game.World.Keys), shown as icons at the bottom left
+func (r *GioRenderer) SetKeys(keys []string) {
+	r.keys = keys
+}
+
+// SetAmbience sets the level's color grading, drawn over the scene below
+// the HUD; nil for none
+func (r *GioRenderer) SetAmbience(amb *game.Ambience) {
+	r.ambience = amb
+}
+
+// SetFade sets how dark to draw the scene, below the HUD, from 0 to 1:
+// the fade in after a camera cut
+func (r *GioRenderer) SetFade(fade float64) {
+	r.fade = fade
+}
+
+// SetWeather sets the rain or snow to draw; nil for none
+func (r *GioRenderer) SetWeather(w *WeatherParticles) {
+	r.weather = w
+}
+
+// View returns the part of the level drawn in the last frame, in tiles, for
+// effects that spawn in view such as WeatherParticles
+func (r *GioRenderer) View() game.Rect {
+	return r.view
+}
+
+// SetLocalPlayer sets the player this client controls. Every other player
+// gets a name tag.
+func (r *GioRenderer) SetLocalPlayer(playerID int) {
+	r.localPlayer = playerID
+}
+
+// SetGhost sets a replay ghost to draw translucent behind the world's
+// entities, or none if nil.
+func (r *GioRenderer) SetGhost(ghost *game.Ghost) {
+	r.ghost = ghost
+}
+
+// SetMenu shows a menu centered over the game, or hides it when nil.
+func (r *GioRenderer) SetMenu(menu *Menu) {
+	r.menu = menu
+}
+
+// SetScoreboard shows the scoreboard centered over the game, or hides it
+// when nil.
+func (r *GioRenderer) SetScoreboard(sb *Scoreboard) {
+	r.scoreboard = sb
+}
+
+// SetResults shows the end-of-level screen centered over the game, or
+// hides it when nil.
+func (r *GioRenderer) SetResults(res *LevelResults) {
+	r.results = res
+}
+
+// SetLeaderboard shows the daily challenge leaderboard centered over the
+// game, or hides it when nil.
+func (r *GioRenderer) SetLeaderboard(lb *Leaderboard) {
+	r.leaderboard = lb
+}
+
 // ViewportSize returns viewport in world units.
 func (r *GioRenderer) ViewportSize(gtx layout.Context) (width, height float64) {
 	return float64(gtx.Constraints.Max.X) / float64(r.tileSize),
@@ -101,21 +301,109 @@ func (r *GioRenderer) Layout(gtx layout.Context) layout.Dimensions {
 	screenH := float64(gtx.Constraints.Max.Y)
 	cameraOffsetX := screenW/2 - r.camera.X*float64(r.tileSize)
 	cameraOffsetY := screenH/2 - r.camera.Y*float64(r.tileSize)
+	ts := float64(r.tileSize)
+	r.view = game.Rect{X: -cameraOffsetX / ts, Y: -cameraOffsetY / ts, W: screenW / ts, H: screenH / ts}
 
 	// Render tile map
 	if r.tileMap != nil {
 		r.drawTileMap(gtx.Ops, cameraOffsetX, cameraOffsetY, screenW, screenH)
 	}
 
-	// Render entities
-	for _, entity := range r.world.GetRenderables() {
+	// Render entities, the ghost first so it stays behind
+	r.renderables = r.renderables[:0]
+	if r.ghost != nil {
+		r.renderables = r.ghost.AppendRenderables(r.renderables)
+	}
+	r.renderables = r.world.AppendRenderables(r.renderables)
+	if r.warp != nil {
+		r.warp.Apply(r.renderables)
+	}
+	for _, entity := range r.renderables {
+		if entity.Ghost || entity.Spectate {
+			opacity := paint.PushOpacity(gtx.Ops, 0.4)
+			r.drawEntity(gtx.Ops, entity, cameraOffsetX, cameraOffsetY)
+			opacity.Pop()
+			continue
+		}
 		r.drawEntity(gtx.Ops, entity, cameraOffsetX, cameraOffsetY)
 	}
+	for _, entity := range r.renderables {
+		if entity.PlayerID != 0 {
+			r.drawPlayerTag(gtx, entity, cameraOffsetX, cameraOffsetY)
+		}
+	}
+	if r.feedback != nil {
+		r.drawFeedback(gtx.Ops, cameraOffsetX, cameraOffsetY)
+		r.drawDamageNumbers(gtx, cameraOffsetX, cameraOffsetY)
+	}
+	if r.particles != nil {
+		r.drawParticles(gtx.Ops, cameraOffsetX, cameraOffsetY)
+	}
+	if r.weather != nil {
+		r.drawWeather(gtx.Ops, cameraOffsetX, cameraOffsetY)
+	}
+	if r.ambience != nil {
+		r.drawGrading(gtx.Ops, gtx.Constraints.Max)
+	}
+	if r.collision != nil {
+		r.drawCollision(gtx, cameraOffsetX, cameraOffsetY)
+	}
+	if r.fade > 0 {
+		size := gtx.Constraints.Max
+		drawRect(gtx.Ops, 0, 0, size.X, size.Y, color.NRGBA{A: uint8(min(r.fade, 1) * 255)})
+	}
 
 	// Draw HUD
 	if r.hudText != "" {
 		r.drawHUD(gtx)
 	}
+	if r.charge != nil && r.charge.Visible() {
+		r.drawChargeMeter(gtx)
+	}
+	if r.combo != nil && r.combo.Visible() {
+		r.drawComboMeter(gtx)
+	}
+	if len(r.keys) > 0 {
+		r.drawKeys(gtx)
+	}
+	if r.hints != nil && r.hints.Visible() {
+		r.drawHintBox(gtx)
+	}
+	if r.dialogue.Visible() {
+		r.drawDialogue(gtx)
+	} else if r.prompt != "" {
+		r.drawPrompt(gtx)
+	}
+	if r.vote.Visible() {
+		r.drawVote(gtx)
+	}
+	if len(r.debugLines) > 0 {
+		r.drawDebugOverlay(gtx)
+	}
+	if r.netGraph != nil {
+		r.drawNetGraph(gtx)
+	}
+	if r.scoreboard != nil {
+		r.drawScoreboard(gtx)
+	}
+	if r.results != nil {
+		r.drawPanel(gtx, r.results.Lines(), r.results.Title != "", 400)
+	}
+	if r.leaderboard != nil {
+		r.drawPanel(gtx, r.leaderboard.Lines(), r.leaderboard.Title != "", 480)
+	}
+	if r.menu != nil {
+		r.drawPanel(gtx, r.menu.Lines(), true, 320)
+	}
+	if r.browser != nil {
+		r.drawPanel(gtx, r.browser.Lines(), true, 640)
+	}
+	if r.worldMap != nil {
+		r.drawPanel(gtx, r.worldMap.Lines(), true, 640)
+	}
+	if r.console != nil {
+		r.drawConsole(gtx)
+	}
 
 	return layout.Dimensions{Size: gtx.Constraints.Max}
 }
@@ -160,7 +448,7 @@ func (r *GioRenderer) drawTileMap(ops *op.Ops, offsetX, offsetY, screenW, screen
 				}
 
 				if region, ok := r.atlas.GetRegion(spriteID); ok {
-					r.drawSprite(ops, int(px), int(py), r.tileSize, r.tileSize, region, false)
+					r.drawSprite(ops, int(px), int(py), r.tileSize, r.tileSize, region, false, false)
 					continue
 				}
 			}
@@ -174,6 +462,10 @@ func (r *GioRenderer) drawTileMap(ops *op.Ops, offsetX, offsetY, screenW, screen
 				tileColor = color.NRGBA{80, 80, 80, 255}
 			case '~':
 				tileColor = color.NRGBA{50, 100, 200, 255}
+			case '%':
+				tileColor = color.NRGBA{140, 110, 90, 255}
+			case '^', '*':
+				tileColor = rgb(r.palette.Hazard, 255)
 			default:
 				tileColor = color.NRGBA{60, 60, 60, 255}
 			}
@@ -182,37 +474,72 @@ func (r *GioRenderer) drawTileMap(ops *op.Ops, offsetX, offsetY, screenW, screen
 	}
 }
 
+// region returns the atlas region drawn for a sprite. Each sprite's name is
+// only looked up the first time it is drawn after the atlas loads.
+func (r *GioRenderer) region(id game.SpriteID) (SpriteRegion, bool) {
+	if int(id) >= len(r.regions) {
+		r.regions = append(r.regions, make([]atlasRegion, int(id)+1-len(r.regions))...)
+	}
+	c := &r.regions[id]
+	if !c.resolved {
+		c.region, c.ok = r.atlas.GetRegion(atlasName(id))
+		c.resolved = true
+	}
+	return c.region, c.ok
+}
+
+// atlasRegion is a cached atlas lookup
+type atlasRegion struct {
+	resolved, ok bool
+	region       SpriteRegion
+}
+
+// atlasName maps a game sprite to the atlas sprite drawn for it
+func atlasName(id game.SpriteID) string {
+	switch id {
+	case game.SpriteSlime:
+		return "blob_1"
+	case game.SpriteBat:
+		return "bat_1"
+	case game.SpriteSpikySlime:
+		return "blob_jump_1"
+	case game.SpriteShieldSlime:
+		return "blob_2"
+	case game.SpriteDiveBat, game.SpriteDiveBatAlert:
+		return "bat_2"
+	case game.SpriteDiveBatDive:
+		return "bat_4"
+	case game.SpriteFistRight, game.SpriteFistLeft:
+		return "fist_1"
+	case game.SpritePlayer:
+		return "player_idle"
+	case game.SpritePlayerPound, game.SpritePlayerHang:
+		return "player_jump"
+	}
+	switch name := id.String(); name {
+	case "orb":
+		return "orb_1"
+	case "cage":
+		return "cage_closed"
+	default:
+		return name
+	}
+}
+
 func (r *GioRenderer) drawEntity(ops *op.Ops, entity game.Renderable, offsetX, offsetY float64) {
 	ts := float64(r.tileSize)
 	px := entity.X*ts + offsetX
 	py := entity.Y*ts + offsetY
+	flash := r.feedback != nil && entity.ID != 0 && r.feedback.Flashing(entity.ID)
 
 	// Try sprite atlas first
 	if r.useAtlas {
-		// Map game entity IDs to atlas sprite IDs
-		spriteID := entity.SpriteID
-		switch {
-		case spriteID == "slime":
-			spriteID = "blob_1"
-		case spriteID == "bat":
-			spriteID = "bat_1"
-		case spriteID == "fist_right" || spriteID == "fist_left":
-			spriteID = "fist_1"
-		case spriteID == "player":
-			spriteID = "player_idle"
-		case spriteID == "orb":
-			spriteID = "orb_1"
-		case spriteID == "health":
-			spriteID = "health"
-		case spriteID == "cage":
-			spriteID = "cage_closed"
-		}
-		if region, ok := r.atlas.GetRegion(spriteID); ok {
+		if region, ok := r.region(entity.SpriteID); ok {
 			// Calculate draw position using anchor
 			drawX := int(px) - region.AnchorX
 			drawY := int(py) - region.AnchorY
 
-			r.drawSprite(ops, drawX, drawY, region.W, region.H, region, entity.FlipX)
+			r.drawSprite(ops, drawX, drawY, region.W, region.H, region, entity.FlipX, flash)
 			return
 		}
 	}
@@ -221,25 +548,49 @@ func (r *GioRenderer) drawEntity(ops *op.Ops, entity game.Renderable, offsetX, o
 	w, h := int(ts*0.8), int(ts*0.9)
 
 	var entityColor color.NRGBA
-	switch {
-	case len(entity.SpriteID) >= 6 && entity.SpriteID[:6] == "player":
-		entityColor = color.NRGBA{0, 200, 0, 255}
-		if len(entity.SpriteID) > 13 && entity.SpriteID[7:13] == "charge" {
-			entityColor = color.NRGBA{255, 200, 0, 255}
-		}
-		if len(entity.SpriteID) >= 12 && entity.SpriteID[7:12] == "punch" {
-			entityColor = color.NRGBA{200, 255, 0, 255}
-		}
-	case entity.SpriteID == "fist_right" || entity.SpriteID == "fist_left":
+	switch id := entity.SpriteID; {
+	case id >= game.SpritePlayerChargeLeft1 && id <= game.SpritePlayerChargeRight3:
+		entityColor = color.NRGBA{255, 200, 0, 255}
+	case id == game.SpritePlayerPunchLeft || id == game.SpritePlayerPunchRight:
+		entityColor = color.NRGBA{200, 255, 0, 255}
+	case id == game.SpritePlayerPound:
+		entityColor = color.NRGBA{255, 120, 40, 255}
+	case id == game.SpritePlayer || id == game.SpritePlayerHang:
+		entityColor = rgb(entity.Color, 255)
+	case id == game.SpriteFistRight || id == game.SpriteFistLeft:
 		entityColor = color.NRGBA{255, 255, 0, 255}
 		w, h = int(ts*0.4), int(ts*0.4)
-	case entity.SpriteID == "slime":
+	case id == game.SpriteSlime:
 		entityColor = color.NRGBA{0, 180, 0, 255}
-	case entity.SpriteID == "bat":
+	case id == game.SpriteBat:
 		entityColor = color.NRGBA{150, 0, 150, 255}
+	case id == game.SpriteSpikySlime:
+		entityColor = color.NRGBA{60, 160, 60, 255}
+	case id == game.SpriteShieldSlime:
+		entityColor = color.NRGBA{0, 130, 100, 255}
+	case id == game.SpriteDiveBat || id == game.SpriteDiveBatDive:
+		entityColor = color.NRGBA{170, 0, 170, 255}
+	case id == game.SpriteDiveBatAlert:
+		entityColor = rgb(r.palette.Alert, 255)
+	case strings.HasPrefix(id.String(), "key_"):
+		entityColor = rgb(entity.Color, 255)
+		w, h = int(ts*0.4), int(ts*0.5)
+	case strings.HasPrefix(id.String(), "door_"):
+		entityColor = rgb(entity.Color, 255)
+		w, h = int(ts), int(ts)
+	case id.String() == game.SpriteTeleporter:
+		entityColor = rgb(entity.Color, 255)
+		w, h = int(ts), int(ts*0.2)
 	default:
 		entityColor = color.NRGBA{255, 0, 0, 255}
 	}
+	if entity.SpriteID != game.SpriteDiveBatAlert {
+		entityColor = r.colorMode.Remap(entityColor) // The palette's alert color is already adapted
+	}
+
+	if flash {
+		entityColor = color.NRGBA{255, 255, 255, 255}
+	}
 
 	// Center on position
 	drawX := int(px) - w/2
@@ -248,8 +599,207 @@ func (r *GioRenderer) drawEntity(ops *op.Ops, entity game.Renderable, offsetX, o
 	drawRect(ops, drawX, drawY, w, h, entityColor)
 }
 
-// drawSprite draws a sprite from the atlas
-func (r *GioRenderer) drawSprite(ops *op.Ops, x, y, w, h int, region SpriteRegion, flipX bool) {
+// drawFeedback draws health bars over recently hit enemies and the death
+// animations playing
+func (r *GioRenderer) drawFeedback(ops *op.Ops, offsetX, offsetY float64) {
+	ts := float64(r.tileSize)
+	for _, entity := range r.renderables {
+		if entity.PlayerID != 0 || entity.MaxHealth <= 0 || entity.ID == 0 || !r.feedback.ShowHealth(entity.ID) {
+			continue
+		}
+		w, h := int(ts*0.8), 4
+		x := int(entity.X*ts+offsetX) - w/2
+		y := int(entity.Y*ts+offsetY) - int(ts) - h - 2
+		fill := w * max(entity.Health, 0) / entity.MaxHealth
+		drawRect(ops, x, y, w, h, rgb(r.palette.HealthEmpty, 220))
+		drawRect(ops, x, y, fill, h, rgb(r.palette.HealthFill, 255))
+	}
+
+	for _, d := range r.feedback.Deaths() {
+		px, py := int(d.X*ts+offsetX), int(d.Y*ts+offsetY)
+		if r.useAtlas {
+			// Kinds without their own death frames puff into smoke
+			region, ok := r.atlas.GetRegion(d.SpriteID())
+			if !ok {
+				region, ok = r.atlas.GetRegion(fmt.Sprintf("smoke_%d", d.Frame()))
+			}
+			if ok {
+				r.drawSprite(ops, px-region.AnchorX, py-region.AnchorY, region.W, region.H, region, false, false)
+				continue
+			}
+		}
+		// Shrink and fade
+		left := 1 - float64(d.Age)/float64(DeathFrames*DeathFrameTicks)
+		w := int(ts * 0.8 * left)
+		drawRect(ops, px-w/2, py-w, w, w, color.NRGBA{220, 220, 220, uint8(200 * left)})
+	}
+}
+
+// drawDamageNumbers draws the floating damage numbers, fading as they rise
+func (r *GioRenderer) drawDamageNumbers(gtx layout.Context, offsetX, offsetY float64) {
+	ts := float64(r.tileSize)
+	const numberWidth = 48
+	for _, n := range r.feedback.Numbers() {
+		label := material.Body2(r.theme, strconv.Itoa(n.Amount))
+		label.Color = color.NRGBA{255, 230, 80, uint8(255 * n.Fade())}
+		label.Alignment = text.Middle
+
+		px := int(n.X*ts + offsetX)
+		py := int(n.Y*ts + offsetY)
+		stack := op.Offset(image.Pt(px-gtx.Dp(numberWidth)/2, py-int(ts*1.4))).Push(gtx.Ops)
+		gtx.Constraints = layout.Exact(image.Pt(gtx.Dp(numberWidth), gtx.Dp(20)))
+		label.Layout(gtx)
+		stack.Pop()
+	}
+}
+
+// whiten returns a copy of an image with every visible pixel white, keeping
+// its alpha, so sprites can be drawn as a white silhouette
+func whiten(img image.Image) image.Image {
+	bounds := img.Bounds()
+	out := image.NewNRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA); c.A > 0 {
+				out.SetNRGBA(x, y, color.NRGBA{255, 255, 255, c.A})
+			}
+		}
+	}
+	return out
+}
+
+// drawParticles draws each particle as a small square fading out with age
+func (r *GioRenderer) drawParticles(ops *op.Ops, offsetX, offsetY float64) {
+	ts := float64(r.tileSize)
+	size := r.tileSize / 8
+	for _, p := range r.particles.All() {
+		px := int(p.X*ts+offsetX) - size/2
+		py := int(p.Y*ts+offsetY) - size/2
+		drawRect(ops, px, py, size, size, color.NRGBA{200, 180, 150, uint8(220 * p.Fade())})
+	}
+}
+
+// drawWeather draws rain as streaks and snow as flakes
+func (r *GioRenderer) drawWeather(ops *op.Ops, offsetX, offsetY float64) {
+	ts := float64(r.tileSize)
+	snow := r.weather.Kind() == game.WeatherSnow
+	for _, p := range r.weather.All() {
+		px := int(p.X*ts + offsetX)
+		py := int(p.Y*ts + offsetY)
+		if snow {
+			size := max(int(ts/8), 2)
+			drawRect(ops, px, py, size, size, color.NRGBA{240, 245, 255, 220})
+			continue
+		}
+		drawRect(ops, px, py, max(int(ts/24), 1), int(ts*0.5), color.NRGBA{170, 190, 230, 150})
+	}
+}
+
+// fogBands is how many strips the fog gradient is drawn in
+const fogBands = 16
+
+// drawGrading is the color grading post pass: the tint over the whole
+// scene, then the fog in bands thickening toward the bottom. Cell
+// renderers get the same result from GradeRGB.
+func (r *GioRenderer) drawGrading(ops *op.Ops, size image.Point) {
+	amb := r.ambience
+	if amb.TintAlpha > 0 {
+		drawRect(ops, 0, 0, size.X, size.Y, rgb(uint32(amb.Tint), uint8(amb.TintAlpha*255)))
+	}
+	if amb.Fog <= 0 {
+		return
+	}
+	band := (size.Y + fogBands - 1) / fogBands
+	for i := range fogBands {
+		alpha := FogAlpha(amb, (float64(i)+0.5)/fogBands)
+		drawRect(ops, 0, i*band, size.X, band, rgb(amb.FogRGB(), uint8(alpha*255)))
+	}
+}
+
+// drawPlayerTag marks a player with its color: a bar under the feet, and
+// for remote players a name tag above the head. Spectators' tags are faded
+// like their sprites.
+func (r *GioRenderer) drawPlayerTag(gtx layout.Context, entity game.Renderable, offsetX, offsetY float64) {
+	ts := float64(r.tileSize)
+	px := int(entity.X*ts + offsetX)
+	py := int(entity.Y*ts + offsetY)
+	w := int(ts * 0.8)
+	alpha := uint8(255)
+	if entity.Spectate {
+		alpha = 100
+	}
+	tag := rgb(r.colorMode.RemapRGB(entity.Color), alpha)
+	drawRect(gtx.Ops, px-w/2, py+1, w, 3, tag)
+
+	if entity.PlayerID == r.localPlayer || entity.Name == "" {
+		return
+	}
+	label := material.Caption(r.theme, entity.Name)
+	label.Color = tag
+	label.Alignment = text.Middle
+
+	const tagWidth = 160
+	stack := op.Offset(image.Pt(px-gtx.Dp(tagWidth)/2, py-int(ts*1.6))).Push(gtx.Ops)
+	gtx.Constraints = layout.Exact(image.Pt(gtx.Dp(tagWidth), gtx.Dp(16)))
+	label.Layout(gtx)
+	stack.Pop()
+}
+
+// drawCollision draws the collision overlay: visible tiles tinted by their
+// flags, box outlines, each fist's remaining travel as a line labeled in
+// tiles, and the camera clamp rect
+func (r *GioRenderer) drawCollision(gtx layout.Context, offsetX, offsetY float64) {
+	ts := float64(r.tileSize)
+	o := r.collision
+	if tiles := o.Tiles; tiles != nil {
+		x0, y0 := max(int(r.view.X), 0), max(int(r.view.Y), 0)
+		x1 := min(int(r.view.X+r.view.W)+1, tiles.Width)
+		y1 := min(int(r.view.Y+r.view.H)+1, tiles.Height)
+		for y := y0; y < y1; y++ {
+			for x := x0; x < x1; x++ {
+				if c, ok := TileFlagColor(tiles.Get(x, y)); ok {
+					drawRect(gtx.Ops, int(float64(x)*ts+offsetX), int(float64(y)*ts+offsetY), int(ts), int(ts), rgb(c, 70))
+				}
+			}
+		}
+	}
+
+	outline := func(x, y, w, h float64, c color.NRGBA, thickness int) {
+		px, py := int(x*ts+offsetX), int(y*ts+offsetY)
+		pw, ph := int(w*ts), int(h*ts)
+		drawRect(gtx.Ops, px, py, pw, thickness, c)
+		drawRect(gtx.Ops, px, py+ph-thickness, pw, thickness, c)
+		drawRect(gtx.Ops, px, py, thickness, ph, c)
+		drawRect(gtx.Ops, px+pw-thickness, py, thickness, ph, c)
+	}
+	for _, b := range o.Boxes {
+		c := rgb(DebugBoxColor(b.Kind), 230)
+		outline(b.Box.X, b.Box.Y, b.Box.Width, b.Box.Height, c, 1)
+		if b.Kind != game.DebugFist {
+			continue
+		}
+		cx, cy := b.Box.Center()
+		px, py := int(min(cx, cx+b.Reach)*ts+offsetX), int(cy*ts+offsetY)
+		drawRect(gtx.Ops, px, py, int(math.Abs(b.Reach)*ts), 1, c)
+
+		label := material.Caption(r.theme, strconv.FormatFloat(math.Abs(b.Reach), 'f', 1, 64))
+		label.Color = c
+		stack := op.Offset(image.Pt(int((cx+b.Reach)*ts+offsetX), py-gtx.Dp(16))).Push(gtx.Ops)
+		label.Layout(gtx)
+		stack.Pop()
+	}
+	if cl := o.Clamp; cl.W > 0 && cl.H > 0 {
+		outline(cl.X, cl.Y, cl.W, cl.H, rgb(ClampColor, 230), 3)
+	}
+}
+
+// rgb converts a 0xRRGGBB color
+func rgb(c uint32, alpha uint8) color.NRGBA {
+	return color.NRGBA{R: uint8(c >> 16), G: uint8(c >> 8), B: uint8(c), A: alpha}
+}
+
+// drawSprite draws a sprite from the atlas, in solid white if white is set
+func (r *GioRenderer) drawSprite(ops *op.Ops, x, y, w, h int, region SpriteRegion, flipX, white bool) {
 	// Create transformation stack
 	defer op.Offset(image.Pt(x, y)).Push(ops).Pop()
 
@@ -276,7 +826,11 @@ func (r *GioRenderer) drawSprite(ops *op.Ops, x, y, w, h int, region SpriteRegio
 	op.Affine(f32.Affine2D{}.Offset(f32.Pt(float32(-region.X), float32(-region.Y)))).Add(ops)
 
 	// Draw the atlas image
-	r.atlasOp.Add(ops)
+	if white {
+		r.whiteOp.Add(ops)
+	} else {
+		r.atlasOp.Add(ops)
+	}
 	paint.PaintOp{}.Add(ops)
 }
 
@@ -287,6 +841,311 @@ func (r *GioRenderer) drawHUD(gtx layout.Context) {
 	label.Layout(gtx)
 }
 
+// drawKeys draws an icon per key held along the bottom left: the key's
+// atlas sprite if there is one, or a swatch of its color
+func (r *GioRenderer) drawKeys(gtx layout.Context) {
+	size, gap := gtx.Dp(20), gtx.Dp(6)
+	top := gtx.Constraints.Max.Y - size - gtx.Dp(16)
+	drawRect(gtx.Ops, gap, top-gap, len(r.keys)*(size+gap)+gap, size+2*gap, color.NRGBA{0, 0, 0, 180})
+	for i, key := range r.keys {
+		left := gap + i*(size+gap) + gap
+		if r.useAtlas {
+			if region, ok := r.region(game.InternSprite(game.KeySprite(key))); ok {
+				r.drawSprite(gtx.Ops, left, top, region.W, region.H, region, false, false)
+				continue
+			}
+		}
+		c := r.colorMode.Remap(rgb(game.KeyColors[key], 255))
+		drawRect(gtx.Ops, left+size/4, top, size/2, size/2, c)                // Bow
+		drawRect(gtx.Ops, left+size/2-size/12, top+size/2, size/6, size/2, c) // Shaft
+	}
+}
+
+// chargeColors are the charge meter's stage colors, brightening toward full
+var chargeColors = [game.ChargeLevels]color.NRGBA{
+	{255, 220, 80, 255},
+	{255, 150, 40, 255},
+	{255, 70, 40, 255},
+}
+
+// drawChargeMeter draws the charge as a segmented bar centered near the
+// bottom of the screen, one segment per charge stage, washed white while
+// the full-charge flash lasts
+func (r *GioRenderer) drawChargeMeter(gtx layout.Context) {
+	const gap = 4
+	segment := gtx.Dp(56)
+	height := gtx.Dp(10)
+	width := game.ChargeLevels*segment + (game.ChargeLevels-1)*gap
+	left := (gtx.Constraints.Max.X - width) / 2
+	top := gtx.Constraints.Max.Y - height - gtx.Dp(24)
+	drawRect(gtx.Ops, left-gap, top-gap, width+2*gap, height+2*gap, color.NRGBA{0, 0, 0, 180})
+
+	for i := range game.ChargeLevels {
+		x := left + i*(segment+gap)
+		drawRect(gtx.Ops, x, top, segment, height, color.NRGBA{60, 60, 60, 255})
+		drawRect(gtx.Ops, x, top, int(float64(segment)*r.charge.Fill(i+1)), height, chargeColors[i])
+	}
+	if flash := r.charge.Flash(); flash > 0 {
+		drawRect(gtx.Ops, left-gap, top-gap, width+2*gap, height+2*gap, color.NRGBA{255, 255, 255, uint8(200 * flash)})
+	}
+}
+
+// comboColors tint the combo count per tier of ComboTiers
+var comboColors = []color.NRGBA{
+	{255, 255, 255, 255},
+	{255, 220, 80, 255},
+	{255, 150, 40, 255},
+	{255, 70, 40, 255},
+}
+
+// drawComboMeter draws the combo count at the bottom right, larger and
+// hotter at each tier and swelling white on each kill, over a bar draining
+// until it runs out
+func (r *GioRenderer) drawComboMeter(gtx layout.Context) {
+	tier := r.combo.Tier()
+	tint := comboColors[min(tier, len(comboColors))-1]
+	c := tint
+	label := material.H6(r.theme, r.combo.Text())
+	label.TextSize = unit.Sp(20 + 6*float32(tier-1))
+	if pop := r.combo.Pop(); pop > 0 {
+		label.TextSize *= unit.Sp(1 + 0.3*float32(pop))
+		white := func(v uint8) uint8 { return uint8(float64(v) + float64(255-v)*pop) }
+		c = color.NRGBA{white(c.R), white(c.G), white(c.B), 255}
+	}
+	label.Color = c
+	label.Alignment = text.End
+
+	width, height := gtx.Dp(260), gtx.Dp(64)
+	barWidth, barHeight := gtx.Dp(120), gtx.Dp(4)
+	left := gtx.Constraints.Max.X - width - gtx.Dp(16)
+	top := gtx.Constraints.Max.Y - height - barHeight - gtx.Dp(24)
+	stack := op.Offset(image.Pt(left, top)).Push(gtx.Ops)
+	gtx.Constraints = layout.Exact(image.Pt(width, height))
+	label.Layout(gtx)
+	stack.Pop()
+
+	barLeft := left + width - barWidth
+	drawRect(gtx.Ops, barLeft, top+height+gtx.Dp(4), barWidth, barHeight, color.NRGBA{0, 0, 0, 180})
+	drawRect(gtx.Ops, barLeft, top+height+gtx.Dp(4), int(float64(barWidth)*r.combo.Left()), barHeight, tint)
+}
+
+// drawHintBox draws the tutorial hint on a framed panel centered below the
+// HUD, wrapping the text to the panel, faded by the hint's alpha
+func (r *GioRenderer) drawHintBox(gtx layout.Context) {
+	const border = 2
+	alpha := r.hints.Alpha()
+	width := min(gtx.Dp(520), gtx.Constraints.Max.X-gtx.Dp(32))
+	height := gtx.Dp(64)
+	left := (gtx.Constraints.Max.X - width) / 2
+	top := gtx.Dp(40)
+	drawRect(gtx.Ops, left-border, top-border, width+2*border, height+2*border, color.NRGBA{255, 220, 80, uint8(220 * alpha)})
+	drawRect(gtx.Ops, left, top, width, height, color.NRGBA{20, 20, 40, uint8(230 * alpha)})
+
+	pad := gtx.Dp(12)
+	stack := op.Offset(image.Pt(left+pad, top+pad)).Push(gtx.Ops)
+	gtx.Constraints = layout.Exact(image.Pt(width-2*pad, height-2*pad))
+	label := material.Body1(r.theme, r.hints.Text())
+	label.Color = color.NRGBA{255, 255, 255, uint8(255 * alpha)}
+	label.Alignment = text.Middle
+	label.Layout(gtx)
+	stack.Pop()
+}
+
+// drawDialogue draws the dialogue box along the bottom: the portrait at
+// the left if the atlas has it, the speaker's name, the text, the choices
+// with the selected one highlighted, and the prompt
+func (r *GioRenderer) drawDialogue(gtx layout.Context) {
+	const border = 2
+	const lineHeight = 22
+	b := r.dialogue
+	choices, selected := b.Choices()
+	width := min(gtx.Dp(640), gtx.Constraints.Max.X-gtx.Dp(32))
+	height := gtx.Dp(110) + len(choices)*lineHeight
+	left := (gtx.Constraints.Max.X - width) / 2
+	top := gtx.Constraints.Max.Y - height - gtx.Dp(24)
+	drawRect(gtx.Ops, left-border, top-border, width+2*border, height+2*border, color.NRGBA{200, 200, 220, 230})
+	drawRect(gtx.Ops, left, top, width, height, color.NRGBA{20, 20, 40, 240})
+
+	pad := gtx.Dp(12)
+	textLeft := left + pad
+	if name := b.Portrait(); name != "" && r.useAtlas {
+		if region, ok := r.region(game.InternSprite(name)); ok {
+			r.drawSprite(gtx.Ops, left+pad, top+pad, region.W, region.H, region, false, false)
+			textLeft += region.W + pad
+		}
+	}
+
+	line := func(y int, s string, c color.NRGBA) {
+		stack := op.Offset(image.Pt(textLeft, y)).Push(gtx.Ops)
+		lgtx := gtx
+		lgtx.Constraints = layout.Exact(image.Pt(left+width-pad-textLeft, lineHeight*3))
+		label := material.Body1(r.theme, s)
+		label.Color = c
+		label.Layout(lgtx)
+		stack.Pop()
+	}
+	y := top + pad
+	if speaker := b.Speaker(); speaker != "" {
+		line(y, speaker, color.NRGBA{255, 220, 80, 255})
+		y += lineHeight
+	}
+	line(y, b.Text(), color.NRGBA{255, 255, 255, 255})
+	y += 2 * lineHeight
+	for i, choice := range choices {
+		c := color.NRGBA{180, 180, 200, 255}
+		if i == selected {
+			choice = "> " + choice
+			c = color.NRGBA{255, 220, 80, 255}
+		}
+		line(y, choice, c)
+		y += lineHeight
+	}
+	line(top+height-pad-lineHeight, b.Prompt(), color.NRGBA{150, 150, 170, 255})
+}
+
+// drawPrompt draws the use prompt on a small panel centered above the
+// bottom edge, where the dialogue box opens
+func (r *GioRenderer) drawPrompt(gtx layout.Context) {
+	width, height := gtx.Dp(240), gtx.Dp(32)
+	left := (gtx.Constraints.Max.X - width) / 2
+	top := gtx.Constraints.Max.Y - height - gtx.Dp(48)
+	drawRect(gtx.Ops, left, top, width, height, color.NRGBA{0, 0, 0, 180})
+
+	stack := op.Offset(image.Pt(left, top+gtx.Dp(6))).Push(gtx.Ops)
+	pgtx := gtx
+	pgtx.Constraints = layout.Exact(image.Pt(width, height))
+	label := material.Body1(r.theme, r.prompt)
+	label.Color = color.NRGBA{255, 220, 80, 255}
+	label.Alignment = text.Middle
+	label.Layout(pgtx)
+	stack.Pop()
+}
+
+// drawVote draws the vote on a panel at the left edge, a third of the way
+// down, with a bar filling toward the yes votes needed
+func (r *GioRenderer) drawVote(gtx layout.Context) {
+	const lineHeight = 20
+	const barHeight = 6
+	lines := r.vote.Lines()
+	width := gtx.Dp(280)
+	top := gtx.Constraints.Max.Y / 3
+	height := len(lines)*lineHeight + barHeight + 16
+	drawRect(gtx.Ops, 0, top, width, height, color.NRGBA{0, 0, 0, 180})
+
+	for i, line := range lines {
+		stack := op.Offset(image.Pt(8, top+4+i*lineHeight)).Push(gtx.Ops)
+		label := material.Body2(r.theme, line)
+		label.Color = color.NRGBA{255, 255, 255, 255}
+		if i == 0 {
+			label.Color = color.NRGBA{255, 220, 80, 255}
+		}
+		label.Layout(gtx)
+		stack.Pop()
+	}
+	barTop := top + 8 + len(lines)*lineHeight
+	drawRect(gtx.Ops, 8, barTop, width-16, barHeight, color.NRGBA{60, 60, 60, 255})
+	drawRect(gtx.Ops, 8, barTop, int(float64(width-16)*r.vote.YesShare()), barHeight, rgb(r.palette.HealthFill, 255))
+}
+
+// drawDebugOverlay draws the debug lines on a dark panel below the HUD
+func (r *GioRenderer) drawDebugOverlay(gtx layout.Context) {
+	const lineHeight = 20
+	const top = 28
+	width := gtx.Dp(360)
+	drawRect(gtx.Ops, 0, top, width, len(r.debugLines)*lineHeight+8, color.NRGBA{0, 0, 0, 180})
+
+	for i, line := range r.debugLines {
+		stack := op.Offset(image.Pt(6, top+4+i*lineHeight)).Push(gtx.Ops)
+		label := material.Body2(r.theme, line)
+		label.Color = color.NRGBA{200, 255, 200, 255}
+		label.Layout(gtx)
+		stack.Pop()
+	}
+}
+
+// drawConsole draws the developer console on a dark band across the top
+func (r *GioRenderer) drawConsole(gtx layout.Context) {
+	const lineHeight = 20
+	lines := r.console.Lines()
+	drawRect(gtx.Ops, 0, 0, gtx.Constraints.Max.X, len(lines)*lineHeight+8, color.NRGBA{0, 0, 0, 220})
+
+	for i, line := range lines {
+		stack := op.Offset(image.Pt(8, 4+i*lineHeight)).Push(gtx.Ops)
+		label := material.Body2(r.theme, line)
+		label.Font.Typeface = "monospace"
+		label.Color = color.NRGBA{200, 255, 200, 255}
+		if i == 0 {
+			label.Color = color.NRGBA{255, 220, 80, 255}
+		}
+		label.Layout(gtx)
+		stack.Pop()
+	}
+}
+
+// drawNetGraph draws bytes in (green) and out (orange) per second as bars,
+// scaled to the busiest second shown, with the latest figures below
+func (r *GioRenderer) drawNetGraph(gtx layout.Context) {
+	const lineHeight = 20
+	const top = 28
+	const graphHeight = 60
+	width := gtx.Dp(240)
+	left := gtx.Constraints.Max.X - width
+	lines := r.netGraph.Lines()
+	drawRect(gtx.Ops, left, top, width, graphHeight+len(lines)*lineHeight+16, color.NRGBA{0, 0, 0, 180})
+
+	samples := r.netGraph.Samples()
+	peak := 1
+	for _, s := range samples {
+		peak = max(peak, s.BytesIn, s.BytesOut)
+	}
+	bar := max((width-12)/max(r.netGraph.Size(), 1), 2)
+	base := top + 4 + graphHeight
+	for i, s := range samples {
+		x := left + 6 + i*bar
+		in := s.BytesIn * graphHeight / peak
+		out := s.BytesOut * graphHeight / peak
+		drawRect(gtx.Ops, x, base-in, bar/2, in, color.NRGBA{100, 220, 100, 255})
+		drawRect(gtx.Ops, x+bar/2, base-out, bar-bar/2, out, color.NRGBA{240, 160, 60, 255})
+	}
+
+	for i, line := range lines {
+		stack := op.Offset(image.Pt(left+6, base+6+i*lineHeight)).Push(gtx.Ops)
+		label := material.Body2(r.theme, line)
+		label.Color = color.NRGBA{200, 255, 200, 255}
+		label.Layout(gtx)
+		stack.Pop()
+	}
+}
+
+// drawScoreboard draws the scoreboard lines on a dark panel in the middle of
+// the screen
+func (r *GioRenderer) drawScoreboard(gtx layout.Context) {
+	r.drawPanel(gtx, r.scoreboard.Lines(), r.scoreboard.Title != "", 560)
+}
+
+// drawPanel draws text lines on a dark panel in the middle of the screen,
+// the first line highlighted as a title if titled
+func (r *GioRenderer) drawPanel(gtx layout.Context, lines []string, titled bool, widthDp unit.Dp) {
+	const lineHeight = 24
+	width := gtx.Dp(widthDp)
+	height := len(lines)*lineHeight + 24
+	left := (gtx.Constraints.Max.X - width) / 2
+	top := (gtx.Constraints.Max.Y - height) / 2
+	drawRect(gtx.Ops, left, top, width, height, color.NRGBA{0, 0, 0, 200})
+
+	for i, line := range lines {
+		stack := op.Offset(image.Pt(left+16, top+12+i*lineHeight)).Push(gtx.Ops)
+		label := material.Body1(r.theme, line)
+		label.Font.Typeface = "monospace"
+		label.Color = color.NRGBA{255, 255, 255, 255}
+		if i == 0 && titled {
+			label.Color = color.NRGBA{255, 220, 80, 255}
+		}
+		label.Layout(gtx)
+		stack.Pop()
+	}
+}
+
 // drawRect draws a filled rectangle (fallback when no atlas)
 func drawRect(ops *op.Ops, x, y, w, h int, c color.NRGBA) {
 	defer clip.Rect{Min: image.Pt(x, y), Max: image.Pt(x+w, y+h)}.Push(ops).Pop()
@@ -0,0 +1,130 @@
+package render
+
+import (
+	"strings"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/i18n"
+)
+
+// Hint box timing, in ticks
+const (
+	HintMinTicks  = 180 // A hint stays up at least this long once shown
+	HintFadeTicks = 30  // It then fades out this long after the player leaves its area
+)
+
+// HintBox shows a level's tutorial hints: the first hint whose area the
+// local player enters is shown while they stay inside, and for at least
+// HintMinTicks, then fades out. A hint is only ever shown once per save
+// profile, so its ID is marked seen as soon as it appears. It is advanced
+// once per tick and drawn by the backends as a text box.
+type HintBox struct {
+	hints   []game.Hint
+	seen    map[string]bool
+	current *game.Hint
+	age     int // Ticks the current hint has been shown
+	fade    int // Ticks fading out, 0 while showing
+
+	// OnSeen, if set, is called with a hint's ID when it is first shown,
+	// for saving it to the profile
+	OnSeen func(id string)
+}
+
+// NewHintBox creates a hint box for a level's hints, skipping the IDs
+// already seen
+func NewHintBox(hints []game.Hint, seen []string) *HintBox {
+	b := &HintBox{hints: hints, seen: make(map[string]bool, len(seen))}
+	for _, id := range seen {
+		b.seen[id] = true
+	}
+	return b
+}
+
+// Update takes the local player's position for this tick; ok is false
+// while they have no body (dead or not spawned yet)
+func (b *HintBox) Update(x, y float64, ok bool) {
+	if ok {
+		for i := range b.hints {
+			h := &b.hints[i]
+			if b.seen[h.ID] || !h.Area.Contains(x, y) {
+				continue
+			}
+			b.seen[h.ID] = true
+			b.current, b.age, b.fade = h, 0, 0
+			if b.OnSeen != nil {
+				b.OnSeen(h.ID)
+			}
+			return
+		}
+	}
+	if b.current == nil {
+		return
+	}
+
+	b.age++
+	inside := ok && b.current.Area.Contains(x, y)
+	switch {
+	case inside || b.age < HintMinTicks:
+		b.fade = 0
+	case b.fade < HintFadeTicks:
+		b.fade++
+	default:
+		b.current = nil
+	}
+}
+
+// Clear hides the hint showing, for a restart. Seen hints stay seen.
+func (b *HintBox) Clear() {
+	b.current, b.age, b.fade = nil, 0, 0
+}
+
+// Visible reports whether a hint is showing
+func (b *HintBox) Visible() bool {
+	return b.current != nil
+}
+
+// Text returns the hint showing, or "" if none
+func (b *HintBox) Text() string {
+	if b.current == nil {
+		return ""
+	}
+	return b.current.Text
+}
+
+// Alpha returns how opaque the box is, from 1 while showing down to 0 as
+// it fades out
+func (b *HintBox) Alpha() float64 {
+	if b.current == nil {
+		return 0
+	}
+	return 1 - float64(b.fade)/HintFadeTicks
+}
+
+// Lines wraps the hint's text at word boundaries to at most width
+// cells per line, for backends that lay out text themselves (a cell
+// renderer's box). Wide characters count as two cells. Words longer than
+// width get a line of their own.
+func (b *HintBox) Lines(width int) []string {
+	return wrapText(b.Text(), width)
+}
+
+// wrapText wraps text at word boundaries to at most width cells per line
+func wrapText(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		switch {
+		case line == "":
+			line = word
+		case i18n.Width(line)+1+i18n.Width(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,66 @@
+package render
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+)
+
+// TestHintBox tests that a hint shows on entering its area, stays up for
+// HintMinTicks, fades once the player has left, and never shows again, nor
+// do hints the profile has already seen.
+func TestHintBox(t *testing.T) {
+	hints := []game.Hint{
+		{ID: "move", Area: game.Rect{X: 0, Y: 0, W: 5, H: 5}, Text: "Walk"},
+		{ID: "jump", Area: game.Rect{X: 10, Y: 0, W: 5, H: 5}, Text: "Jump"},
+		{ID: "glide", Area: game.Rect{X: 20, Y: 0, W: 5, H: 5}, Text: "Glide"},
+	}
+	var saved []string
+	b := NewHintBox(hints, []string{"jump"})
+	b.OnSeen = func(id string) { saved = append(saved, id) }
+
+	b.Update(2, 2, true)
+	if b.Text() != "Walk" || b.Alpha() != 1 {
+		t.Fatalf("Entering the area should show its hint, got %q at %v", b.Text(), b.Alpha())
+	}
+	for range HintMinTicks - 1 {
+		b.Update(7, 2, true)
+	}
+	if b.Text() != "Walk" || b.Alpha() != 1 {
+		t.Fatal("The hint should stay up for HintMinTicks after leaving")
+	}
+	for range HintFadeTicks {
+		b.Update(7, 2, true)
+	}
+	if !b.Visible() || b.Alpha() != 0 {
+		t.Fatalf("The hint should have faded out, alpha %v", b.Alpha())
+	}
+	b.Update(7, 2, true)
+	if b.Visible() {
+		t.Fatal("The hint should be gone after fading")
+	}
+
+	b.Update(2, 2, true)
+	b.Update(12, 2, true)
+	if b.Visible() {
+		t.Fatalf("Seen hints shouldn't show again, got %q", b.Text())
+	}
+	b.Update(22, 2, true)
+	if b.Text() != "Glide" {
+		t.Fatalf("Expected the glide hint, got %q", b.Text())
+	}
+	if !slices.Equal(saved, []string{"move", "glide"}) {
+		t.Errorf("Expected move and glide to be saved, got %v", saved)
+	}
+}
+
+// TestHintBoxLines tests wrapping hint text for cell renderers.
+func TestHintBoxLines(t *testing.T) {
+	b := NewHintBox([]game.Hint{{ID: "a", Area: game.Rect{W: 1, H: 1}, Text: "Hold J to wind up your fist"}}, nil)
+	b.Update(0, 0, true)
+	want := []string{"Hold J to", "wind up", "your fist"}
+	if got := b.Lines(10); !slices.Equal(got, want) {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+}
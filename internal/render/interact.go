@@ -0,0 +1,24 @@
+package render
+
+import (
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/i18n"
+)
+
+// InteractPrompt returns the HUD prompt for the object the local player
+// stands by (see game.World.InteractTarget): the use key and what it does
+func InteractPrompt(tr *i18n.Catalog, obj game.LevelObject) string {
+	switch obj.Type {
+	case game.ObjectSign:
+		return tr.T("interact.sign")
+	case game.ObjectLever:
+		return tr.T("interact.lever")
+	case game.ObjectNPC:
+		if obj.Name != "" {
+			return tr.T("interact.talk_to", obj.Name)
+		}
+		return tr.T("interact.talk")
+	default:
+		return tr.T("interact.use")
+	}
+}
@@ -0,0 +1,33 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/i18n"
+)
+
+// TestInteractPrompt tests the prompt for each object type, in English and
+// translated.
+func TestInteractPrompt(t *testing.T) {
+	nb, err := i18n.Load("nb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		obj    game.LevelObject
+		en, nb string
+	}{
+		{game.LevelObject{Type: game.ObjectSign}, "K: Read", "K: Les"},
+		{game.LevelObject{Type: game.ObjectLever}, "K: Pull the lever", "K: Dra i spaken"},
+		{game.LevelObject{Type: game.ObjectNPC}, "K: Talk", "K: Snakk"},
+		{game.LevelObject{Type: game.ObjectNPC, Name: "Ly"}, "K: Talk to Ly", "K: Snakk med Ly"},
+	} {
+		if got := InteractPrompt(nil, tt.obj); got != tt.en {
+			t.Errorf("%+v: %q, want %q", tt.obj, got, tt.en)
+		}
+		if got := InteractPrompt(nb, tt.obj); got != tt.nb {
+			t.Errorf("%+v in nb: %q, want %q", tt.obj, got, tt.nb)
+		}
+	}
+}
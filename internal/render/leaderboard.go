@@ -0,0 +1,120 @@
+package render
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/andersfylling/rayman-slides/internal/i18n"
+)
+
+// LeaderboardEntry is one row of a leaderboard
+type LeaderboardEntry struct {
+	Rank   int
+	Name   string
+	Ticks  uint64
+	Orbs   int
+	Grade  string // Best ranked run's, "" if unranked
+	Points int
+}
+
+// Leaderboard is the top of a leaderboard: the daily challenge's times,
+// shown instead of the results screen when a daily run finishes, or a
+// level's best runs in the leaderboard browser
+type Leaderboard struct {
+	Title     string
+	Entries   []LeaderboardEntry
+	Orbs      bool   // Show an orbs column
+	Grades    bool   // Show a grade column
+	Highlight string // Name marked with '>', the local player's
+	Rank      int    // Local player's rank, 0 if unknown
+	Total     int    // Players on the board
+	Status    string // e.g. "Submitting your time..." or an error
+	Footer    string
+	Lang      *i18n.Catalog // Headings; nil for English
+}
+
+// leaderboardColumns are the widths in cells of the rank, name and time
+// columns; the orbs and grade columns follow when shown
+var leaderboardColumns = []int{4, 16, 9}
+
+// Widths in cells of the optional columns
+const (
+	leaderboardOrbsWidth  = 6
+	leaderboardGradeWidth = 8
+)
+
+// Lines formats the leaderboard as fixed-width text rows, like
+// Scoreboard.Lines. The local player's rank is repeated below the list when
+// they didn't make it.
+func (lb *Leaderboard) Lines() []string {
+	tr := lb.Lang
+	lines := make([]string, 0, len(lb.Entries)+8)
+	if lb.Title != "" {
+		lines = append(lines, lb.Title, "")
+	}
+	header := []string{"#", tr.T("scoreboard.player"), tr.T("scoreboard.time")}
+	widths := slices.Clip(leaderboardColumns)
+	if lb.Orbs {
+		header = append(header, tr.T("scoreboard.orbs"))
+		widths = append(widths, leaderboardOrbsWidth)
+	}
+	if lb.Grades {
+		header = append(header, tr.T("scoreboard.grade"))
+		widths = append(widths, leaderboardGradeWidth)
+	}
+	lines = append(lines, leaderboardRow(' ', widths, header...))
+	listed := false
+	for _, e := range lb.Entries {
+		mark := ' '
+		if e.Name == lb.Highlight && lb.Highlight != "" {
+			mark, listed = '>', true
+		}
+		row := []string{fmt.Sprint(e.Rank), e.Name, FormatTicks(e.Ticks)}
+		if lb.Orbs {
+			row = append(row, fmt.Sprint(e.Orbs))
+		}
+		if lb.Grades {
+			grade := "-"
+			if e.Grade != "" {
+				grade = fmt.Sprintf("%s %d", e.Grade, e.Points)
+			}
+			row = append(row, grade)
+		}
+		lines = append(lines, leaderboardRow(mark, widths, row...))
+	}
+	if len(lb.Entries) == 0 && lb.Status == "" {
+		lines = append(lines, tr.T("leaderboard.empty"))
+	}
+	if lb.Rank > 0 && !listed {
+		lines = append(lines, "", tr.T("leaderboard.rank", lb.Rank, lb.Total))
+	}
+	if lb.Status != "" {
+		lines = append(lines, "", lb.Status)
+	}
+	if lb.Footer != "" {
+		lines = append(lines, "", lb.Footer)
+	}
+	return lines
+}
+
+// leaderboardRow lays out one row: a marker, the rank right-aligned, the
+// name left-aligned and the numbers right-aligned, in columns of the given
+// widths
+func leaderboardRow(mark rune, widths []int, cells ...string) string {
+	var b strings.Builder
+	b.WriteRune(mark)
+	for i, cell := range cells {
+		switch i {
+		case 0:
+			b.WriteString(i18n.PadLeft(cell, widths[i]))
+		case 1:
+			b.WriteString("  ")
+			b.WriteString(i18n.PadRight(cell, widths[i]))
+		default:
+			b.WriteByte(' ')
+			b.WriteString(i18n.PadLeft(cell, widths[i]))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,97 @@
+package render
+
+import (
+	"fmt"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/i18n"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// Menu is a titled list of options with their keys, such as the pause menu
+type Menu struct {
+	Title string
+	Items []MenuItem
+	Notes []string // Shown under the items, such as an update notice
+}
+
+// MenuItem is one menu option and the key that picks it
+type MenuItem struct {
+	Key   string
+	Label string
+}
+
+// PauseMenu returns the in-game menu in the catalog's language (nil for
+// English), showing the color mode C switches to next. In multiplayer the
+// game keeps running behind it, which the title says, and only the server
+// can restart a level.
+func PauseMenu(tr *i18n.Catalog, multiplayer bool, colors ColorMode) *Menu {
+	colorItem := MenuItem{Key: "C", Label: tr.T("menu.colors", tr.T("colors."+colors.String()))}
+	if multiplayer {
+		return &Menu{
+			Title: tr.T("menu.continues"),
+			Items: []MenuItem{{Key: "Esc", Label: tr.T("menu.resume")}, colorItem, {Key: "Q", Label: tr.T("menu.quit")}},
+		}
+	}
+	return &Menu{
+		Title: tr.T("menu.paused"),
+		Items: []MenuItem{{Key: "Esc", Label: tr.T("menu.resume")}, {Key: "R", Label: tr.T("menu.restart")}, colorItem, {Key: "Q", Label: tr.T("menu.quit")}},
+	}
+}
+
+// Lines formats the menu as text rows, title first
+func (m *Menu) Lines() []string {
+	lines := make([]string, 0, len(m.Items)+len(m.Notes)+3)
+	lines = append(lines, m.Title, "")
+	for _, item := range m.Items {
+		lines = append(lines, fmt.Sprintf("%-5s %s", item.Key, item.Label))
+	}
+	if len(m.Notes) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, m.Notes...)
+	}
+	return lines
+}
+
+// HostMenu returns the host's menu for the settings the server last sent:
+// difficulty, friendly fire, restart, and the players the host can kick,
+// the selected one marked. Kickable players are the ones other than the
+// host, in roster order.
+func HostMenu(tr *i18n.Catalog, settings protocol.HostSettings, players []protocol.PlayerInfo, selected int) *Menu {
+	fire := tr.T("host.off")
+	if settings.FriendlyFire {
+		fire = tr.T("host.on")
+	}
+	m := &Menu{
+		Title: tr.T("host.title"),
+		Items: []MenuItem{
+			{Key: "D", Label: tr.T("host.difficulty", tr.T("difficulty."+game.Difficulty(settings.Difficulty).String()))},
+			{Key: "F", Label: tr.T("host.friendly_fire", fire)},
+			{Key: "R", Label: tr.T("menu.restart")},
+		},
+		Notes: []string{tr.T("host.players")},
+	}
+	kickable := 0
+	for _, p := range players {
+		name := p.Name
+		if name == "" {
+			name = tr.T("scoreboard.unnamed", p.PlayerID)
+		}
+		if p.PlayerID == settings.Host {
+			m.Notes = append(m.Notes, "  "+tr.T("host.you", name))
+			continue
+		}
+		marker := "  "
+		if kickable == selected {
+			marker = "> "
+			m.Items = append(m.Items, MenuItem{Key: "K", Label: tr.T("host.kick", name)})
+		}
+		m.Notes = append(m.Notes, marker+name)
+		kickable++
+	}
+	if kickable == 0 {
+		m.Notes = append(m.Notes, tr.T("host.alone"))
+	}
+	m.Items = append(m.Items, MenuItem{Key: "Esc", Label: tr.T("menu.resume")})
+	return m
+}
@@ -0,0 +1,44 @@
+package render
+
+// SafeFlashGap is the fewest ticks between two flashes under reduced
+// motion: three flashes a second at 60 ticks per second, the limit
+// photosensitivity guidelines (WCAG 2.3.1) allow
+const SafeFlashGap = 20
+
+// Motion holds the reduced-motion and photosensitivity settings. Every
+// backend draws effects through CameraController, HitFeedback, Particles
+// and TimeWarp, so applying the settings to those covers them all.
+type Motion struct {
+	Shake       bool    `json:"shake"`         // Camera shake on ground pounds
+	MinFlashGap int     `json:"min_flash_gap"` // Fewest ticks between hit flashes on one entity, 0 for no cap
+	Particles   float64 `json:"particles"`     // Fraction of particles spawned, 0 to 1
+	SlowMotion  bool    `json:"slow_motion"`   // Slow motion on the final hit
+}
+
+// FullMotion is the default: every effect at full strength
+func FullMotion() Motion {
+	return Motion{Shake: true, Particles: 1, SlowMotion: true}
+}
+
+// ReducedMotion turns shake and slow motion off, caps hit flashes at
+// SafeFlashGap and spawns a quarter of the particles
+func ReducedMotion() Motion {
+	return Motion{Shake: false, MinFlashGap: SafeFlashGap, Particles: 0.25}
+}
+
+// Apply sets the motion settings on a client's effects; nil ones are
+// skipped
+func (m Motion) Apply(camera *CameraController, feedback *HitFeedback, particles *Particles, warp *TimeWarp) {
+	if camera != nil {
+		camera.NoShake = !m.Shake
+	}
+	if feedback != nil {
+		feedback.MinFlashGap = m.MinFlashGap
+	}
+	if particles != nil {
+		particles.Density = m.Particles
+	}
+	if warp != nil {
+		warp.NoSlowMotion = !m.SlowMotion
+	}
+}
@@ -0,0 +1,46 @@
+package render
+
+import "testing"
+
+// TestReducedMotion tests that reduced motion stops camera shake, keeps hit
+// flashes at least SafeFlashGap apart however often an entity is hit, thins
+// out particles and skips slow motion.
+func TestReducedMotion(t *testing.T) {
+	camera := NewCameraController(DefaultCameraConfig())
+	camera.SetBounds(100, 100)
+	feedback := NewHitFeedback()
+	particles := NewParticles()
+	warp := NewTimeWarp()
+	ReducedMotion().Apply(camera, feedback, particles, warp)
+
+	camera.Update(50, 50, true, 20, 10)
+	camera.Shake(0.3, 12)
+	if cam := camera.Update(50, 50, true, 20, 10); cam.X != 50 || cam.Y != 50 {
+		t.Errorf("The camera shouldn't shake, got (%v, %v)", cam.X, cam.Y)
+	}
+
+	flashes, flashing := 0, false
+	for tick := range 60 {
+		if tick%4 == 0 {
+			feedback.Hit(7, 0, 0, 1) // Hit 15 times a second
+		}
+		if feedback.Flashing(7) && !flashing {
+			flashes++
+		}
+		flashing = feedback.Flashing(7)
+		feedback.Update()
+	}
+	if want := 60 / SafeFlashGap; flashes != want {
+		t.Errorf("Expected %d flashes in a second, got %d", want, flashes)
+	}
+
+	particles.Burst(0, 0, 16)
+	if n := len(particles.All()); n != 4 {
+		t.Errorf("Expected a quarter of the particles, got %d", n)
+	}
+
+	warp.SlowMotion(SlowMotionTicks)
+	if warp.Scale() != 1 {
+		t.Error("Expected no slow motion")
+	}
+}
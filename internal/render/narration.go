@@ -0,0 +1,178 @@
+package render
+
+import (
+	"math"
+	"strings"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/i18n"
+)
+
+// Narration timing, in ticks, and how far it looks, in tiles
+const (
+	NarrationMinTicks = 60  // A changed description waits at least this long after the last
+	NarrationTicks    = 300 // An unchanged one is repeated this often
+	NarrationRange    = 8   // Enemies and the exit further away aren't mentioned
+	NarrationAhead    = 4   // Pits and hazards are looked for this far ahead
+)
+
+// Narrator describes the local player's surroundings in short sentences
+// for screen readers: "Health low. Pit 2 tiles right. Enemy 3 tiles left."
+// It is advanced once per tick and returns a sentence whenever one should
+// be spoken: as soon as the description changes, but no more often than
+// NarrationMinTicks, and again every NarrationTicks while nothing changes.
+// The client writes them wherever its screen reader listens.
+type Narrator struct {
+	Lang *i18n.Catalog // Sentences; nil for English
+
+	last        string
+	age         int
+	renderables []game.Renderable // Reused every tick
+	parts       []string
+}
+
+// NewNarrator creates a narrator speaking tr's language
+func NewNarrator(tr *i18n.Catalog) *Narrator {
+	return &Narrator{Lang: tr, age: NarrationTicks}
+}
+
+// Update looks around the player for this tick and returns the sentence to
+// speak, or "" to stay quiet
+func (n *Narrator) Update(w *game.World, playerID int) string {
+	n.age++
+	text := n.Describe(w, playerID)
+	if n.age < NarrationMinTicks || (text == n.last && n.age < NarrationTicks) || text == "" {
+		return ""
+	}
+	n.last, n.age = text, 0
+	return text
+}
+
+// Clear forgets the last sentence, so the next Update speaks at once, for
+// a restart
+func (n *Narrator) Clear() {
+	n.last, n.age = "", NarrationTicks
+}
+
+// Describe returns the player's surroundings as one sentence, most urgent
+// first: low health, a pit or hazard ahead, the nearest enemy, the exit,
+// an object to use. An open dialogue is read instead.
+func (n *Narrator) Describe(w *game.World, playerID int) string {
+	tr := n.Lang
+	if state, ok := w.Dialogue(playerID); ok {
+		return (&DialogueBox{State: state, Lang: tr}).Sentence()
+	}
+	n.renderables = w.AppendRenderables(n.renderables[:0])
+	var self *game.Renderable
+	for i := range n.renderables {
+		if n.renderables[i].PlayerID == playerID {
+			self = &n.renderables[i]
+		}
+	}
+	if self == nil {
+		return ""
+	}
+
+	n.parts = n.parts[:0]
+	if current, maxHealth, ok := w.PlayerHealth(playerID); ok && current*3 <= maxHealth {
+		n.parts = append(n.parts, tr.T("narrate.health_low"))
+	}
+
+	dir := 1
+	if self.FlipX {
+		dir = -1
+	}
+	if w.PlayerOnGround(playerID) {
+		if dx, pit := lookAhead(w, self.X, self.Y, dir); dx > 0 {
+			key := "narrate.hazard"
+			if pit {
+				key = "narrate.pit"
+			}
+			n.parts = append(n.parts, tr.T(key, n.offset(float64(dx*dir), 0)))
+		}
+	}
+
+	nearest, best := -1, float64(NarrationRange)
+	for i, r := range n.renderables {
+		if r.PlayerID != 0 || r.MaxHealth <= 0 {
+			continue
+		}
+		if d := math.Hypot(r.X-self.X, r.Y-self.Y); d <= best {
+			nearest, best = i, d
+		}
+	}
+	if nearest >= 0 {
+		e := n.renderables[nearest]
+		n.parts = append(n.parts, tr.T("narrate.enemy", n.offset(e.X-self.X, e.Y-self.Y)))
+	}
+
+	if level := w.Level(); level != nil && level.Exit != nil {
+		dx, dy := level.Exit.X-self.X, level.Exit.Y-self.Y
+		if math.Hypot(dx, dy) <= NarrationRange {
+			n.parts = append(n.parts, tr.T("narrate.exit", n.offset(dx, dy)))
+		}
+	}
+	if obj, ok := w.InteractTarget(playerID); ok {
+		n.parts = append(n.parts, InteractPrompt(tr, obj))
+	}
+	if len(n.parts) == 0 {
+		return ""
+	}
+	return strings.Join(n.parts, ". ") + "."
+}
+
+// offset describes where something is from the player, e.g. "3 tiles
+// right" or "2 tiles left, above"
+func (n *Narrator) offset(dx, dy float64) string {
+	tr := n.Lang
+	tiles := int(math.Round(math.Abs(dx)))
+	var s string
+	switch {
+	case tiles == 0:
+		s = tr.T("narrate.here")
+	case dx < 0:
+		s = tr.T(plural("narrate.left", tiles), tiles)
+	default:
+		s = tr.T(plural("narrate.right", tiles), tiles)
+	}
+	switch {
+	case dy <= -1.5:
+		s += ", " + tr.T("narrate.above")
+	case dy >= 1.5:
+		s += ", " + tr.T("narrate.below")
+	}
+	return s
+}
+
+// plural picks a key's "_one" form for a single tile
+func plural(key string, n int) string {
+	if n == 1 {
+		return key + "_one"
+	}
+	return key
+}
+
+// lookAhead scans up to NarrationAhead tiles in front of a standing
+// player's feet and returns the distance to the first pit (no ground down
+// to the bottom of the map) or hazard, 0 if there is none before a wall
+func lookAhead(w *game.World, x, y float64, dir int) (dx int, pit bool) {
+	tm := w.TileMap
+	feet, ground := int(y), int(y)+1
+	for dx = 1; dx <= NarrationAhead; dx++ {
+		col := int(x) + dx*dir
+		if col < 0 || col >= tm.Width || tm.IsSolid(col, feet) {
+			return 0, false
+		}
+		if tm.IsHazard(col, feet) || tm.IsHazard(col, ground) {
+			return dx, false
+		}
+		floor := false
+		for row := ground; row < tm.Height && !floor; row++ {
+			floor = tm.IsSolid(col, row) || tm.IsPlatform(col, row)
+		}
+		if !floor {
+			return dx, true
+		}
+	}
+	return 0, false
+}
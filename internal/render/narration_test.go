@@ -0,0 +1,51 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/collision"
+	"github.com/andersfylling/rayman-slides/internal/game"
+)
+
+// TestNarrator tests that the narrator reports a pit ahead and a nearby
+// enemy, speaks at once, and then waits before repeating itself.
+func TestNarrator(t *testing.T) {
+	tm := collision.NewTileMap(20, 10)
+	for x := range 10 {
+		tm.Set(x, 9, collision.TileSolid) // Ground ends at x 10
+	}
+	world := game.NewWorld()
+	world.SetTileMap(tm)
+	world.SpawnPlayer(1, "Test", 7.5, 8)
+	world.SpawnEnemy("slime", 3, 8)
+	for range 30 {
+		world.Update()
+	}
+
+	n := NewNarrator(nil)
+	text := n.Update(world, 1)
+	if !strings.Contains(text, "Pit 3 tiles right") || !strings.Contains(text, "Enemy") {
+		t.Fatalf("Expected a pit ahead and the slime, got %q", text)
+	}
+	if strings.Contains(text, "Health low") {
+		t.Errorf("A healthy player shouldn't hear about health, got %q", text)
+	}
+	if again := n.Update(world, 1); again != "" {
+		t.Errorf("Narration should wait before speaking again, got %q", again)
+	}
+
+	spoken := 0
+	for range NarrationTicks {
+		if n.Update(world, 1) != "" {
+			spoken++
+		}
+	}
+	if spoken == 0 || spoken > NarrationTicks/NarrationMinTicks {
+		t.Errorf("Expected a repeat at least every %d ticks and at most every %d, spoke %d times", NarrationTicks, NarrationMinTicks, spoken)
+	}
+
+	if text := n.Update(world, 2); text != "" {
+		t.Errorf("A player without a body has nothing to describe, got %q", text)
+	}
+}
@@ -0,0 +1,61 @@
+package render
+
+import "fmt"
+
+// NetSample is one second of a connection's traffic
+type NetSample struct {
+	BytesIn, BytesOut int
+	FullSnapshots     int
+	DeltaSnapshots    int
+	AvgSnapshotSize   float64
+}
+
+// NetGraph keeps the last samples of a connection's traffic for the net
+// graph overlay, oldest first
+type NetGraph struct {
+	samples []NetSample
+	size    int
+}
+
+// NewNetGraph creates a graph holding up to size samples
+func NewNetGraph(size int) *NetGraph {
+	return &NetGraph{size: size}
+}
+
+// Push adds a sample, dropping the oldest when full
+func (g *NetGraph) Push(s NetSample) {
+	if len(g.samples) == g.size {
+		copy(g.samples, g.samples[1:])
+		g.samples = g.samples[:g.size-1]
+	}
+	g.samples = append(g.samples, s)
+}
+
+// Samples returns the samples, oldest first
+func (g *NetGraph) Samples() []NetSample {
+	return g.samples
+}
+
+// Size returns the number of samples the graph holds when full
+func (g *NetGraph) Size() int {
+	return g.size
+}
+
+// Lines formats the latest sample as text rows
+func (g *NetGraph) Lines() []string {
+	var s NetSample
+	if len(g.samples) > 0 {
+		s = g.samples[len(g.samples)-1]
+	}
+	snaps := s.FullSnapshots + s.DeltaSnapshots
+	full := 0.0
+	if snaps > 0 {
+		full = 100 * float64(s.FullSnapshots) / float64(snaps)
+	}
+	return []string{
+		fmt.Sprintf("in  %6.1f KB/s", float64(s.BytesIn)/1024),
+		fmt.Sprintf("out %6.1f KB/s", float64(s.BytesOut)/1024),
+		fmt.Sprintf("snapshots %d/s, %.0f%% full", snaps, full),
+		fmt.Sprintf("avg snapshot %.0f B", s.AvgSnapshotSize),
+	}
+}
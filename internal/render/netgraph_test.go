@@ -0,0 +1,33 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestNetGraph tests that the graph keeps the newest samples and describes
+// the latest one.
+func TestNetGraph(t *testing.T) {
+	g := NewNetGraph(3)
+	if lines := g.Lines(); len(lines) != 4 || !strings.Contains(lines[2], "0/s, 0% full") {
+		t.Errorf("Empty graph lines %q, want zeros", lines)
+	}
+	for i := 1; i <= 5; i++ {
+		g.Push(NetSample{BytesIn: i * 1024})
+	}
+	samples := g.Samples()
+	if len(samples) != g.Size() || samples[0].BytesIn != 3*1024 || samples[2].BytesIn != 5*1024 {
+		t.Errorf("Samples %+v, want the last 3", samples)
+	}
+
+	g.Push(NetSample{BytesIn: 2560, BytesOut: 512, FullSnapshots: 1, DeltaSnapshots: 19, AvgSnapshotSize: 87.4})
+	want := []string{
+		"in     2.5 KB/s",
+		"out    0.5 KB/s",
+		"snapshots 20/s, 5% full",
+		"avg snapshot 87 B",
+	}
+	if got := g.Lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Lines = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,187 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+)
+
+// ColorMode adapts colors for color vision deficiencies. Outside
+// ColorNormal, sprites and color hints are daltonized (Remap): the contrast
+// a player with that deficiency can't see is shifted into channels they
+// can, so green slimes and red-tinted sprites stay apart. Colors that mean
+// something by hue alone, such as the health bar, come from the mode's
+// Palette instead. Cell renderers also mark hazards and threats with a
+// glyph (TileMark, EntityMark), since a two-color glyph carries less than a
+// sprite's shape.
+type ColorMode uint8
+
+const (
+	ColorNormal       ColorMode = iota
+	ColorDeuteranopia           // Green-blind, the most common
+	ColorProtanopia             // Red-blind
+	ColorTritanopia             // Blue-blind
+	colorModeCount
+)
+
+func (m ColorMode) String() string {
+	switch m {
+	case ColorNormal:
+		return "normal"
+	case ColorDeuteranopia:
+		return "deuteranopia"
+	case ColorProtanopia:
+		return "protanopia"
+	case ColorTritanopia:
+		return "tritanopia"
+	}
+	return fmt.Sprintf("ColorMode(%d)", uint8(m))
+}
+
+// ParseColorMode parses "normal", "deuteranopia", "protanopia" or
+// "tritanopia"
+func ParseColorMode(s string) (ColorMode, error) {
+	for m := ColorNormal; m < colorModeCount; m++ {
+		if m.String() == s {
+			return m, nil
+		}
+	}
+	return ColorNormal, fmt.Errorf("unknown color mode %q (want normal, deuteranopia, protanopia or tritanopia)", s)
+}
+
+// Next returns the mode after m, wrapping around, for cycling in a menu
+func (m ColorMode) Next() ColorMode {
+	return (m + 1) % colorModeCount
+}
+
+// Palette holds the colors that carry meaning by hue, as 0xRRGGBB. Each
+// mode picks its own from the Okabe-Ito set rather than daltonizing them,
+// which clips saturated colors and can bring pairs closer.
+type Palette struct {
+	HealthFill  uint32 // Enemy health bar, remaining
+	HealthEmpty uint32 // Enemy health bar, lost
+	Hazard      uint32 // Spikes and fire
+	Alert       uint32 // A dive bat about to dive
+}
+
+var palettes = [colorModeCount]Palette{
+	ColorNormal:       {HealthFill: 0x3CDC3C, HealthEmpty: 0x500000, Hazard: 0xDC3C1E, Alert: 0xFF5050},
+	ColorDeuteranopia: {HealthFill: 0x56B4E9, HealthEmpty: 0x402000, Hazard: 0xE69F00, Alert: 0xF0E442},
+	ColorProtanopia:   {HealthFill: 0x56B4E9, HealthEmpty: 0x402000, Hazard: 0xE69F00, Alert: 0xF0E442},
+	ColorTritanopia:   {HealthFill: 0x3CDCDC, HealthEmpty: 0x500000, Hazard: 0xDC3C1E, Alert: 0xFF5AB4},
+}
+
+// Palette returns the mode's colors for hue-coded UI
+func (m ColorMode) Palette() Palette {
+	if m >= colorModeCount {
+		return palettes[ColorNormal]
+	}
+	return palettes[m]
+}
+
+// colorMatrix maps linear combinations of R, G and B
+type colorMatrix [3][3]float64
+
+// Simulated vision per mode (Machado et al. 2009, full severity), and the
+// daltonize step moving the lost difference into the channels left
+var (
+	simulation = [colorModeCount]colorMatrix{
+		ColorDeuteranopia: {{0.367322, 0.860646, -0.227968}, {0.280085, 0.672501, 0.047413}, {-0.011820, 0.042940, 0.968881}},
+		ColorProtanopia:   {{0.152286, 1.052583, -0.204868}, {0.114503, 0.786281, 0.099216}, {-0.003882, -0.048116, 1.051998}},
+		ColorTritanopia:   {{1.255528, -0.076749, -0.178779}, {-0.078411, 0.930809, 0.147602}, {0.004733, 0.691367, 0.303900}},
+	}
+	redGreenShift = colorMatrix{{0, 0, 0}, {0.7, 1, 0}, {0.7, 0, 1}}
+	blueShift     = colorMatrix{{1, 0, 0.7}, {0, 1, 0.7}, {0, 0, 0}}
+)
+
+// Simulate returns roughly how a color looks with the mode's deficiency,
+// for checking that remapped colors stay apart
+func (m ColorMode) Simulate(c color.NRGBA) color.NRGBA {
+	if m == ColorNormal || m >= colorModeCount {
+		return c
+	}
+	r, g, b := simulation[m].apply(float64(c.R), float64(c.G), float64(c.B))
+	return color.NRGBA{R: clampByte(r), G: clampByte(g), B: clampByte(b), A: c.A}
+}
+
+// Remap adapts a color to the mode; ColorNormal returns it unchanged
+func (m ColorMode) Remap(c color.NRGBA) color.NRGBA {
+	if m == ColorNormal || m >= colorModeCount {
+		return c
+	}
+	r, g, b := float64(c.R), float64(c.G), float64(c.B)
+	sr, sg, sb := simulation[m].apply(r, g, b)
+	shift := redGreenShift
+	if m == ColorTritanopia {
+		shift = blueShift
+	}
+	er, eg, eb := shift.apply(r-sr, g-sg, b-sb)
+	return color.NRGBA{R: clampByte(r + er), G: clampByte(g + eg), B: clampByte(b + eb), A: c.A}
+}
+
+// RemapRGB adapts a 0xRRGGBB color, such as a sprite's color hint
+func (m ColorMode) RemapRGB(c uint32) uint32 {
+	n := m.Remap(color.NRGBA{R: uint8(c >> 16), G: uint8(c >> 8), B: uint8(c), A: 255})
+	return uint32(n.R)<<16 | uint32(n.G)<<8 | uint32(n.B)
+}
+
+// RemapImage returns a remapped copy of an image such as the sprite
+// atlas, or the image itself for ColorNormal
+func (m ColorMode) RemapImage(img image.Image) image.Image {
+	if m == ColorNormal || m >= colorModeCount {
+		return img
+	}
+	bounds := img.Bounds()
+	out := image.NewNRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA); c.A > 0 {
+				out.SetNRGBA(x, y, m.Remap(c))
+			}
+		}
+	}
+	return out
+}
+
+// TileMark returns the glyph a cell renderer overlays on a tile from
+// game.RenderTileMap so it reads without color: '!' on hazards. It is 0
+// for other tiles and in ColorNormal.
+func (m ColorMode) TileMark(tile rune) rune {
+	if m == ColorNormal {
+		return 0
+	}
+	switch tile {
+	case '^', '*':
+		return '!'
+	}
+	return 0
+}
+
+// EntityMark returns the glyph a cell renderer draws for an entity instead
+// of its usual one, so threats read without color: '!' on a dive bat about
+// to dive and '^' on a spiky slime, which can't be pounded. It is 0 for
+// other sprites and in ColorNormal.
+func (m ColorMode) EntityMark(id game.SpriteID) rune {
+	if m == ColorNormal {
+		return 0
+	}
+	switch id {
+	case game.SpriteDiveBatAlert:
+		return '!'
+	case game.SpriteSpikySlime:
+		return '^'
+	}
+	return 0
+}
+
+func (m colorMatrix) apply(r, g, b float64) (float64, float64, float64) {
+	return m[0][0]*r + m[0][1]*g + m[0][2]*b,
+		m[1][0]*r + m[1][1]*g + m[1][2]*b,
+		m[2][0]*r + m[2][1]*g + m[2][2]*b
+}
+
+func clampByte(v float64) uint8 {
+	return uint8(max(0, min(255, v+0.5)))
+}
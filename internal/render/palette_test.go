@@ -0,0 +1,66 @@
+package render
+
+import (
+	"image/color"
+	"testing"
+)
+
+// TestPalette tests that each mode's palette keeps hue-coded pairs further
+// apart, as that deficiency sees them, than the normal palette does.
+func TestPalette(t *testing.T) {
+	normal := ColorNormal.Palette()
+	for _, m := range []ColorMode{ColorDeuteranopia, ColorProtanopia, ColorTritanopia} {
+		p := m.Palette()
+		pairs := []struct {
+			name       string
+			a, b, c, d uint32 // Normal pair, then the mode's
+		}{
+			{"health fill/empty", normal.HealthFill, normal.HealthEmpty, p.HealthFill, p.HealthEmpty},
+			{"health/hazard", normal.HealthFill, normal.Hazard, p.HealthFill, p.Hazard},
+			{"alert/hazard", normal.Alert, normal.Hazard, p.Alert, p.Hazard},
+		}
+		for _, pair := range pairs {
+			before := distance(m.Simulate(nrgba(pair.a)), m.Simulate(nrgba(pair.b)))
+			after := distance(m.Simulate(nrgba(pair.c)), m.Simulate(nrgba(pair.d)))
+			if after <= before {
+				t.Errorf("%v: %s should look further apart than with the normal palette, %.0f -> %.0f", m, pair.name, before, after)
+			}
+		}
+	}
+}
+
+// TestColorModeRemap tests that remapping leaves normal vision and alpha
+// alone.
+func TestColorModeRemap(t *testing.T) {
+	if got := ColorNormal.RemapRGB(0x00B400); got != 0x00B400 {
+		t.Errorf("Normal vision should keep colors, got %06x", got)
+	}
+	c := color.NRGBA{R: 0, G: 180, B: 0, A: 90}
+	if got := ColorDeuteranopia.Remap(c); got.A != c.A || got == c {
+		t.Errorf("Expected a remapped color with the same alpha, got %v", got)
+	}
+}
+
+// TestParseColorMode tests that every mode parses back from its name.
+func TestParseColorMode(t *testing.T) {
+	for m := ColorNormal; m < colorModeCount; m++ {
+		if got, err := ParseColorMode(m.String()); err != nil || got != m {
+			t.Errorf("ParseColorMode(%q) = %v, %v", m.String(), got, err)
+		}
+	}
+	if _, err := ParseColorMode("sepia"); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+	if ColorTritanopia.Next() != ColorNormal {
+		t.Error("Next should wrap around to normal")
+	}
+}
+
+func nrgba(c uint32) color.NRGBA {
+	return color.NRGBA{R: uint8(c >> 16), G: uint8(c >> 8), B: uint8(c), A: 255}
+}
+
+func distance(a, b color.NRGBA) float64 {
+	dr, dg, db := float64(a.R)-float64(b.R), float64(a.G)-float64(b.G), float64(a.B)-float64(b.B)
+	return dr*dr + dg*dg + db*db
+}
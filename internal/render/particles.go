@@ -0,0 +1,76 @@
+package render
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// Particle is one speck of a cosmetic effect, in world units
+type Particle struct {
+	X, Y   float64
+	VX, VY float64
+	Age    int
+	Life   int // Ticks until it disappears
+}
+
+// Fade returns how much of the particle's life is left, from 1 down to 0
+func (p Particle) Fade() float64 {
+	return 1 - float64(p.Age)/float64(p.Life)
+}
+
+// Particles holds cosmetic effects such as landing dust. They live only on
+// the client and never affect the simulation.
+type Particles struct {
+	// Density scales every burst, from 1 for full effects down to 0 for
+	// none. See Motion.
+	Density float64
+
+	particles []Particle
+	rng       *rand.Rand
+}
+
+// NewParticles creates an empty particle set at full density
+func NewParticles() *Particles {
+	return &Particles{Density: 1, rng: rand.New(rand.NewPCG(1, 2))}
+}
+
+// Burst throws n dust particles, scaled by Density, up and out from (x, y)
+func (p *Particles) Burst(x, y float64, n int) {
+	n = int(math.Round(float64(n) * min(max(p.Density, 0), 1)))
+	for range n {
+		p.particles = append(p.particles, Particle{
+			X:    x + (p.rng.Float64()-0.5)*0.6,
+			Y:    y,
+			VX:   (p.rng.Float64() - 0.5) * 0.5,
+			VY:   -0.1 - p.rng.Float64()*0.25,
+			Life: 15 + p.rng.IntN(15),
+		})
+	}
+}
+
+// Update moves the particles one tick and drops the expired ones
+func (p *Particles) Update() {
+	live := p.particles[:0]
+	for _, pt := range p.particles {
+		pt.Age++
+		if pt.Age >= pt.Life {
+			continue
+		}
+		pt.X += pt.VX
+		pt.Y += pt.VY
+		pt.VX *= 0.9
+		pt.VY += 0.03 // Dust settles slowly
+		live = append(live, pt)
+	}
+	p.particles = live
+}
+
+// Clear removes every particle, e.g. after a level restart
+func (p *Particles) Clear() {
+	p.particles = p.particles[:0]
+}
+
+// All returns the live particles
+func (p *Particles) All() []Particle {
+	return p.particles
+}
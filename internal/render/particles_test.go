@@ -0,0 +1,22 @@
+package render
+
+import "testing"
+
+// TestParticlesAllocs tests that a steady stream of dust reuses the
+// particle slice instead of growing a new one every frame.
+func TestParticlesAllocs(t *testing.T) {
+	p := NewParticles()
+	step := func() {
+		p.Burst(10, 5, 4)
+		p.Update()
+	}
+	for range 60 { // Longer than a particle lives, so the slice stops growing
+		step()
+	}
+	if len(p.All()) == 0 {
+		t.Fatal("no particles alive")
+	}
+	if allocs := testing.AllocsPerRun(100, step); allocs != 0 {
+		t.Errorf("a burst and update allocated %v times per run, want 0", allocs)
+	}
+}
@@ -0,0 +1,61 @@
+package render
+
+import (
+	"fmt"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/i18n"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// LevelResults is the end-of-level screen: the local player's finished run,
+// stat by stat, and the rank it earned
+type LevelResults struct {
+	Title  string
+	Stats  protocol.PlayerStats
+	Goals  *game.RankGoals // Orb and cage totals; nil if the level sets none
+	Rank   game.RunRank
+	Notes  []string // Under the rank, e.g. stars earned or a new best
+	Footer string
+	Lang   *i18n.Catalog // nil for English
+}
+
+// resultsColumns are the widths in cells of the label and value columns
+var resultsColumns = [2]int{16, 10}
+
+// Lines formats the results as text rows, title first, with the stats
+// aligned in terminal cells like Scoreboard.Lines
+func (r *LevelResults) Lines() []string {
+	tr := r.Lang
+	var goals game.RankGoals
+	if r.Goals != nil {
+		goals = *r.Goals
+	}
+	lines := make([]string, 0, len(r.Notes)+12)
+	if r.Title != "" {
+		lines = append(lines, r.Title, "")
+	}
+	row := func(label, value string) {
+		lines = append(lines, i18n.PadRight(label, resultsColumns[0])+i18n.PadLeft(value, resultsColumns[1]))
+	}
+	row(tr.T("scoreboard.time"), FormatTicks(r.Stats.FinishTicks))
+	row(tr.T("scoreboard.orbs"), outOf(r.Stats.Orbs, goals.Orbs))
+	row(tr.T("scoreboard.cages"), outOf(r.Stats.Cages, goals.Cages))
+	row(tr.T("results.damage_taken"), fmt.Sprint(r.Stats.DamageTaken))
+	row(tr.T("scoreboard.deaths"), fmt.Sprint(r.Stats.Deaths))
+	row(tr.T("results.best_combo"), fmt.Sprint(r.Stats.BestCombo))
+	lines = append(lines, "", tr.T("results.rank", r.Rank.Grade, r.Rank.Score, game.RankMaxScore))
+	lines = append(lines, r.Notes...)
+	if r.Footer != "" {
+		lines = append(lines, "", r.Footer)
+	}
+	return lines
+}
+
+// outOf formats n of a total, or n alone when the total is unknown
+func outOf(n, total int) string {
+	if total <= 0 {
+		return fmt.Sprint(n)
+	}
+	return fmt.Sprintf("%d/%d", n, total)
+}
@@ -0,0 +1,71 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/andersfylling/rayman-slides/internal/i18n"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// Scoreboard is the player stats table, shown as the TAB overlay during play
+// and as the results screen at match end
+type Scoreboard struct {
+	Title  string
+	Stats  []protocol.PlayerStats
+	Footer string        // e.g. key hints on the results screen
+	Lang   *i18n.Catalog // Column headings; nil for English
+}
+
+// scoreboardColumns are the widths in cells of the player column and the
+// right-aligned stat columns after it
+var scoreboardColumns = [6]int{16, 6, 6, 7, 7, 9}
+
+// Lines formats the scoreboard as fixed-width text rows, header first, so
+// every backend lays it out the same way. Columns are measured in terminal
+// cells, so translated headings and player names with wide characters stay
+// aligned.
+func (sb *Scoreboard) Lines() []string {
+	lines := make([]string, 0, len(sb.Stats)+4)
+	if sb.Title != "" {
+		lines = append(lines, sb.Title, "")
+	}
+	tr := sb.Lang
+	lines = append(lines, scoreboardRow(tr.T("scoreboard.player"), tr.T("scoreboard.orbs"), tr.T("scoreboard.cages"),
+		tr.T("scoreboard.damage"), tr.T("scoreboard.deaths"), tr.T("scoreboard.time")))
+	for _, ps := range sb.Stats {
+		name := ps.Name
+		if name == "" {
+			name = tr.T("scoreboard.unnamed", ps.PlayerID)
+		}
+		lines = append(lines, scoreboardRow(name, fmt.Sprint(ps.Orbs), fmt.Sprint(ps.Cages),
+			fmt.Sprint(ps.Damage), fmt.Sprint(ps.Deaths), FormatTicks(ps.FinishTicks)))
+	}
+	if sb.Footer != "" {
+		lines = append(lines, "", sb.Footer)
+	}
+	return lines
+}
+
+// scoreboardRow lays out one row: the name left-aligned, the rest right
+func scoreboardRow(cells ...string) string {
+	var b strings.Builder
+	for i, cell := range cells {
+		if i == 0 {
+			b.WriteString(i18n.PadRight(cell, scoreboardColumns[i]))
+			continue
+		}
+		b.WriteByte(' ')
+		b.WriteString(i18n.PadLeft(cell, scoreboardColumns[i]))
+	}
+	return b.String()
+}
+
+// FormatTicks formats a 60 Hz tick count as m:ss.cc, or "-" for zero
+func FormatTicks(ticks uint64) string {
+	if ticks == 0 {
+		return "-"
+	}
+	cs := ticks * 100 / 60
+	return fmt.Sprintf("%d:%02d.%02d", cs/6000, cs/100%60, cs%100)
+}
@@ -0,0 +1,105 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/i18n"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// TestScoreboardLines tests that rows stay aligned in terminal cells with
+// translated headings and names in wide characters.
+func TestScoreboardLines(t *testing.T) {
+	de, err := i18n.Load("de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sb := &Scoreboard{
+		Lang: de,
+		Stats: []protocol.PlayerStats{
+			{PlayerID: 1, Name: "レイマン", Orbs: 12},
+			{PlayerID: 2, Orbs: 3, Deaths: 1},
+		},
+	}
+	lines := sb.Lines()
+	if len(lines) != 3 {
+		t.Fatalf("Expected a header and two rows, got %q", lines)
+	}
+	for _, line := range lines[1:] {
+		if i18n.Width(line) != i18n.Width(lines[0]) {
+			t.Errorf("Row %q is %d cells, header %d", line, i18n.Width(line), i18n.Width(lines[0]))
+		}
+	}
+	if want := "Spieler"; lines[0][:len(want)] != want {
+		t.Errorf("Expected the German header, got %q", lines[0])
+	}
+	if want := "Spieler 2"; lines[2][:len(want)] != want {
+		t.Errorf("Expected the unnamed player in German, got %q", lines[2])
+	}
+}
+
+// TestLeaderboardLines tests that rows stay aligned with the orbs column,
+// the local player is marked in the list and their rank repeated below it
+// when they didn't make the list.
+func TestLeaderboardLines(t *testing.T) {
+	lb := &Leaderboard{
+		Entries:   []LeaderboardEntry{{Rank: 1, Name: "ada", Ticks: 600, Orbs: 12}, {Rank: 2, Name: "bob", Ticks: 720}},
+		Orbs:      true,
+		Highlight: "bob",
+		Rank:      2,
+		Total:     5,
+	}
+	lines := lb.Lines()
+	if len(lines) != 3 || lines[2][0] != '>' {
+		t.Fatalf("Expected a header and two rows, bob's marked, got %q", lines)
+	}
+	for _, line := range lines[1:] {
+		if i18n.Width(line) != i18n.Width(lines[0]) {
+			t.Errorf("Row %q is %d cells, header %d", line, i18n.Width(line), i18n.Width(lines[0]))
+		}
+	}
+
+	lb.Orbs, lb.Grades = false, true
+	lb.Entries[0].Grade, lb.Entries[0].Points = "S", 100
+	for _, line := range lb.Lines()[1:] {
+		if i18n.Width(line) != i18n.Width(lines[0])+2 {
+			t.Errorf("Row %q with grades is %d cells, want %d", line, i18n.Width(line), i18n.Width(lines[0])+2)
+		}
+	}
+
+	lb.Highlight, lb.Rank = "eve", 4
+	lines = lb.Lines()
+	if got := lines[len(lines)-1]; got != "You are #4 of 5" {
+		t.Errorf("Expected the player's rank below the list, got %q", got)
+	}
+}
+
+// TestLevelResultsLines tests the end-of-level screen: totals shown only
+// when the level sets them, the rank line and notes under it.
+func TestLevelResultsLines(t *testing.T) {
+	res := &LevelResults{
+		Title: "Level complete!",
+		Stats: protocol.PlayerStats{Orbs: 12, Cages: 1, DamageTaken: 2, FinishTicks: 1872, BestCombo: 4},
+		Goals: &game.RankGoals{Orbs: 20},
+		Rank:  game.RunRank{Score: 84, Grade: "A"},
+		Notes: []string{"New best rank on this level!"},
+	}
+	want := []string{
+		"Level complete!",
+		"",
+		"Time               0:31.20",
+		"Orbs                 12/20",
+		"Cages                    1",
+		"Damage taken             2",
+		"Deaths                   0",
+		"Best combo               4",
+		"",
+		"Rank: A (84/100)",
+		"New best rank on this level!",
+	}
+	if got := res.Lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Lines =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
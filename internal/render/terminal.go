@@ -0,0 +1,50 @@
+package render
+
+// ANSIColor is one of the 16 standard terminal colors, by index
+type ANSIColor uint8
+
+const (
+	ANSIBlack ANSIColor = iota
+	ANSIRed
+	ANSIGreen
+	ANSIYellow
+	ANSIBlue
+	ANSIMagenta
+	ANSICyan
+	ANSIWhite
+	ANSIBrightBlack
+	ANSIBrightRed
+	ANSIBrightGreen
+	ANSIBrightYellow
+	ANSIBrightBlue
+	ANSIBrightMagenta
+	ANSIBrightCyan
+	ANSIBrightWhite
+)
+
+// ansiRGB are xterm's default values for the 16 colors
+var ansiRGB = [16]uint32{
+	0x000000, 0xCD0000, 0x00CD00, 0xCDCD00, 0x0000EE, 0xCD00CD, 0x00CDCD, 0xE5E5E5,
+	0x7F7F7F, 0xFF0000, 0x00FF00, 0xFFFF00, 0x5C5CFF, 0xFF00FF, 0x00FFFF, 0xFFFFFF,
+}
+
+// Cell is a glyph with its colors in a 16-color terminal
+type Cell struct {
+	Glyph  rune
+	Fg, Bg ANSIColor
+	Bold   bool
+}
+
+// Nearest16 maps a 0xRRGGBB color to the closest of the 16 ANSI colors
+func Nearest16(rgb uint32) ANSIColor {
+	best, bestDist := ANSIBlack, -1
+	for i, c := range ansiRGB {
+		dr := int(rgb>>16&0xFF) - int(c>>16&0xFF)
+		dg := int(rgb>>8&0xFF) - int(c>>8&0xFF)
+		db := int(rgb&0xFF) - int(c&0xFF)
+		if d := dr*dr + dg*dg + db*db; bestDist < 0 || d < bestDist {
+			best, bestDist = ANSIColor(i), d
+		}
+	}
+	return best
+}
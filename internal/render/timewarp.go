@@ -0,0 +1,191 @@
+package render
+
+import (
+	"math"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// Time warp timing
+const (
+	MaxHitstopTicks  = 3    // Frozen ticks on a fully charged hit
+	SlowMotionTicks  = 60   // Ticks of slow motion on the final hit
+	SlowMotionScale  = 0.25 // Speed of the world drawn in slow motion
+	WarpCatchUp      = 0.2  // Share of the lag closed per tick once time runs normally again
+	WarpMaxLag       = 2.0  // Furthest an entity is drawn from where the world has it
+	WarpSnapDistance = 4.0  // An entity moving further in one tick teleported, and is drawn there
+)
+
+// HitstopTicks returns the frozen ticks for a fist hit with the given
+// charge, from 0 to 1: none for a tap, 2 or MaxHitstopTicks for the charge
+// levels past it
+func HitstopTicks(charge float64) int {
+	switch {
+	case charge > 2.0/3:
+		return MaxHitstopTicks
+	case charge > 1.0/3:
+		return MaxHitstopTicks - 1
+	}
+	return 0
+}
+
+// TimeWarp slows down what the client draws without touching the
+// simulation: hitstop holds every entity still for a few ticks, slow motion
+// moves them at a fraction of their speed, and afterwards they catch up
+// with where the world has them. The world keeps ticking at its rate, so
+// prediction and the server's ticks are unaffected; only drawn positions
+// lag for a moment. It is advanced once per tick after the world; like
+// particles it only exists on the client.
+type TimeWarp struct {
+	// NoSlowMotion ignores SlowMotion. See Motion.
+	NoSlowMotion bool
+
+	freeze   int     // Frozen ticks left
+	slow     int     // Slow ticks left
+	accum    float64 // Fractional effect ticks owed in slow motion
+	tick     uint64  // Steps taken, to drop entities no longer seen
+	entities map[protocol.EntityID]*warped
+}
+
+// warped is an entity drawn away from its simulated position
+type warped struct {
+	x, y     float64 // Where it is drawn
+	liveX    float64 // Where the world had it last tick
+	liveY    float64
+	sprite   game.SpriteID // Held while frozen
+	flipX    bool
+	playerID int
+	seen     uint64
+}
+
+// NewTimeWarp creates a warp running at normal speed
+func NewTimeWarp() *TimeWarp {
+	return &TimeWarp{entities: make(map[protocol.EntityID]*warped)}
+}
+
+// Hitstop freezes what is drawn for ticks, unless already frozen longer
+func (t *TimeWarp) Hitstop(ticks int) {
+	t.freeze = max(t.freeze, ticks)
+}
+
+// SlowMotion draws the world at SlowMotionScale for ticks, after any
+// hitstop
+func (t *TimeWarp) SlowMotion(ticks int) {
+	if !t.NoSlowMotion {
+		t.slow = max(t.slow, ticks)
+	}
+}
+
+// Scale returns the speed the world is drawn at: 0 frozen, SlowMotionScale
+// in slow motion, 1 otherwise
+func (t *TimeWarp) Scale() float64 {
+	switch {
+	case t.freeze > 0:
+		return 0
+	case t.slow > 0:
+		return SlowMotionScale
+	}
+	return 1
+}
+
+// Active reports whether anything is drawn away from where the world has
+// it, or is about to be
+func (t *TimeWarp) Active() bool {
+	return t.freeze > 0 || t.slow > 0 || len(t.entities) > 0
+}
+
+// Step advances the warp one tick, given the world's renderables after it.
+// It returns how many ticks cosmetic effects (particles, hit feedback)
+// should advance: none while frozen, one every few ticks in slow motion.
+func (t *TimeWarp) Step(live []game.Renderable) int {
+	if !t.Active() {
+		return 1
+	}
+	scale := t.Scale()
+	t.tick++
+	settled := true
+	for _, r := range live {
+		if r.ID == 0 {
+			continue // Ghosts
+		}
+		w, ok := t.entities[r.ID]
+		switch {
+		case !ok:
+			w = &warped{x: r.X, y: r.Y, sprite: r.SpriteID, flipX: r.FlipX}
+			t.entities[r.ID] = w
+		case math.Hypot(r.X-w.liveX, r.Y-w.liveY) > WarpSnapDistance:
+			w.x, w.y = r.X, r.Y // Teleported or respawned: don't slide there
+		default:
+			w.x += (r.X - w.liveX) * scale
+			w.y += (r.Y - w.liveY) * scale
+			if scale == 1 {
+				w.x += (r.X - w.x) * WarpCatchUp
+				w.y += (r.Y - w.y) * WarpCatchUp
+			}
+			if scale > 0 {
+				w.sprite, w.flipX = r.SpriteID, r.FlipX
+			}
+		}
+		w.liveX, w.liveY, w.playerID, w.seen = r.X, r.Y, r.PlayerID, t.tick
+		if dist := math.Hypot(r.X-w.x, r.Y-w.y); dist > WarpMaxLag {
+			// Keep the player's view of the game within reach
+			w.x = r.X + (w.x-r.X)*WarpMaxLag/dist
+			w.y = r.Y + (w.y-r.Y)*WarpMaxLag/dist
+			settled = false
+		} else if dist > 0.01 {
+			settled = false
+		}
+	}
+	for id, w := range t.entities {
+		if w.seen != t.tick {
+			delete(t.entities, id)
+		}
+	}
+
+	effects := 1
+	switch {
+	case t.freeze > 0:
+		t.freeze--
+		effects = 0
+	case t.slow > 0:
+		t.slow--
+		t.accum += SlowMotionScale
+		effects = int(t.accum)
+		t.accum -= float64(effects)
+	case settled:
+		clear(t.entities) // Caught up: draw the world as it is again
+	}
+	return effects
+}
+
+// Apply moves renderables to where the warp draws them, keeping the
+// sprite an entity had when time froze
+func (t *TimeWarp) Apply(renderables []game.Renderable) {
+	if len(t.entities) == 0 {
+		return
+	}
+	for i := range renderables {
+		r := &renderables[i]
+		if w, ok := t.entities[r.ID]; ok && r.ID != 0 {
+			r.X, r.Y, r.SpriteID, r.FlipX = w.x, w.y, w.sprite, w.flipX
+		}
+	}
+}
+
+// PlayerPosition returns where a player at (x, y) in the world is drawn,
+// for the camera to follow
+func (t *TimeWarp) PlayerPosition(playerID int, x, y float64) (float64, float64) {
+	for _, w := range t.entities {
+		if w.playerID == playerID {
+			return w.x, w.y
+		}
+	}
+	return x, y
+}
+
+// Clear drops any warp, e.g. after a level restart
+func (t *TimeWarp) Clear() {
+	t.freeze, t.slow, t.accum = 0, 0, 0
+	clear(t.entities)
+}
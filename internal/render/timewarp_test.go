@@ -0,0 +1,110 @@
+package render
+
+import (
+	"math"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+)
+
+// TestHitstopTicks tests that only charged hits freeze time, longer the
+// more they were charged.
+func TestHitstopTicks(t *testing.T) {
+	for _, tc := range []struct {
+		charge float64
+		want   int
+	}{{0, 0}, {0.3, 0}, {0.5, 2}, {1, MaxHitstopTicks}} {
+		if got := HitstopTicks(tc.charge); got != tc.want {
+			t.Errorf("HitstopTicks(%v) = %d, want %d", tc.charge, got, tc.want)
+		}
+	}
+}
+
+// TestTimeWarpHitstop tests that hitstop holds entities where they were
+// hit while the world moves on, stops effects, and lets them catch up
+// afterwards without ever falling further behind than WarpMaxLag.
+func TestTimeWarpHitstop(t *testing.T) {
+	warp := NewTimeWarp()
+	live := []game.Renderable{{ID: 1, X: 10, Y: 5, PlayerID: 1}, {ID: 2, X: 20, Y: 5}}
+	if effects := warp.Step(live); effects != 1 || warp.Active() {
+		t.Fatalf("idle warp ran %d effect ticks, active %v", effects, warp.Active())
+	}
+
+	warp.Hitstop(3)
+	drawn := make([]game.Renderable, len(live))
+	for tick := range 3 {
+		if effects := warp.Step(live); effects != 0 {
+			t.Errorf("tick %d: effects advanced %d ticks while frozen", tick, effects)
+		}
+		copy(drawn, live)
+		warp.Apply(drawn)
+		if drawn[0].X != 10 {
+			t.Errorf("tick %d: player drawn at x %v, want 10 while frozen", tick, drawn[0].X)
+		}
+		if x, _ := warp.PlayerPosition(1, live[0].X, live[0].Y); x != 10 {
+			t.Errorf("tick %d: camera follows x %v, want 10", tick, x)
+		}
+		live[0].X += 0.5
+	}
+
+	for tick := range 60 {
+		warp.Step(live)
+		copy(drawn, live)
+		warp.Apply(drawn)
+		if lag := live[0].X - drawn[0].X; lag < 0 || lag > WarpMaxLag {
+			t.Fatalf("tick %d: player drawn %v behind", tick, lag)
+		}
+		if !warp.Active() {
+			break
+		}
+		live[0].X += 0.1
+	}
+	if warp.Active() {
+		t.Fatal("warp still active after catching up")
+	}
+	copy(drawn, live)
+	warp.Apply(drawn)
+	if drawn[0].X != live[0].X || drawn[1].X != 20 {
+		t.Errorf("drawn at %v and %v after catching up, want %v and 20", drawn[0].X, drawn[1].X, live[0].X)
+	}
+}
+
+// TestTimeWarpSlowMotion tests that slow motion moves entities at
+// SlowMotionScale, runs effects at the same rate, can be turned off, and
+// that a teleport during it is drawn at once.
+func TestTimeWarpSlowMotion(t *testing.T) {
+	warp := NewTimeWarp()
+	live := []game.Renderable{{ID: 1, X: 0, Y: 5}}
+	warp.SlowMotion(8)
+	warp.Step(live)
+	effects := 0
+	for range 7 {
+		live[0].X += 0.1
+		effects += warp.Step(live)
+	}
+	drawn := []game.Renderable{live[0]}
+	warp.Apply(drawn)
+	// The first step only starts tracking; the other seven move at the scale
+	if want := 0.7 * SlowMotionScale; math.Abs(drawn[0].X-want) > 1e-9 {
+		t.Errorf("drawn at x %v, want %v", drawn[0].X, want)
+	}
+	if want := int(8 * SlowMotionScale); effects != want {
+		t.Errorf("effects advanced %d ticks, want %d", effects, want)
+	}
+
+	warp.SlowMotion(8)
+	live[0].X = 50
+	warp.Step(live)
+	drawn[0] = live[0]
+	warp.Apply(drawn)
+	if drawn[0].X != 50 {
+		t.Errorf("teleported entity drawn at x %v, want 50", drawn[0].X)
+	}
+
+	warp.Clear()
+	warp.NoSlowMotion = true
+	warp.SlowMotion(8)
+	if warp.Active() || warp.Scale() != 1 {
+		t.Error("slow motion started while turned off")
+	}
+}
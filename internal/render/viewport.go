@@ -0,0 +1,20 @@
+package render
+
+import "fmt"
+
+// Smallest terminal, in cells, the game can be played in. Below this the
+// camera clamps degenerate and the HUD overlaps the playfield.
+const (
+	MinViewportCols = 60
+	MinViewportRows = 20
+)
+
+// ViewportTooSmall reports whether a terminal of the given size is below
+// the minimum, with the message to show instead of the game. Backends check
+// it on start and after every resize.
+func ViewportTooSmall(cols, rows int) (string, bool) {
+	if cols >= MinViewportCols && rows >= MinViewportRows {
+		return "", false
+	}
+	return fmt.Sprintf("terminal too small: %dx%d (min %dx%d)", cols, rows, MinViewportCols, MinViewportRows), true
+}
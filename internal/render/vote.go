@@ -0,0 +1,60 @@
+package render
+
+import (
+	"github.com/andersfylling/rayman-slides/internal/i18n"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// VotePanel is the voting overlay: what is voted on, by whom, the count and
+// how to vote, or the result once the vote is over
+type VotePanel struct {
+	Status protocol.VoteStatus
+	Caller string        // Name of the player who called it
+	Tick   uint64        // Current tick, for the time left
+	Lang   *i18n.Catalog // nil for English
+}
+
+// Visible reports whether there is a vote to show
+func (v *VotePanel) Visible() bool {
+	return v != nil && v.Status.Kind != protocol.VoteNone
+}
+
+// Lines formats the panel as text rows, title first
+func (v *VotePanel) Lines() []string {
+	tr, st := v.Lang, v.Status
+	var title string
+	switch st.Kind {
+	case protocol.VoteKick:
+		title = tr.T("vote.kick", st.Subject)
+	case protocol.VoteMap:
+		title = tr.T("vote.map", st.Subject)
+	default:
+		title = tr.T("vote.restart")
+	}
+	lines := []string{title, tr.T("vote.caller", v.Caller), tr.T("vote.count", st.Yes, st.No, st.Needed)}
+	switch st.Result {
+	case protocol.VotePassed:
+		lines = append(lines, tr.T("vote.passed"))
+	case protocol.VoteFailed:
+		lines = append(lines, tr.T("vote.failed"))
+	default:
+		lines = append(lines, tr.T("vote.keys", (v.Left()+59)/60))
+	}
+	return lines
+}
+
+// Left returns the ticks until the vote closes
+func (v *VotePanel) Left() uint64 {
+	if v.Tick >= v.Status.Ends {
+		return 0
+	}
+	return v.Status.Ends - v.Tick
+}
+
+// YesShare returns the yes votes as a share of those needed, for a bar
+func (v *VotePanel) YesShare() float64 {
+	if v.Status.Needed == 0 {
+		return 0
+	}
+	return min(float64(v.Status.Yes)/float64(v.Status.Needed), 1)
+}
@@ -0,0 +1,34 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// TestVotePanel tests the overlay's rows while a vote is open and after.
+func TestVotePanel(t *testing.T) {
+	v := &VotePanel{
+		Status: protocol.VoteStatus{Kind: protocol.VoteKick, Subject: "Carol", Yes: 1, Needed: 2, Ends: 1000},
+		Caller: "Alice",
+		Tick:   1000 - 5*60 - 1,
+	}
+	if !v.Visible() || (*VotePanel)(nil).Visible() || (&VotePanel{}).Visible() {
+		t.Fatal("Only a panel with a vote should be visible")
+	}
+	lines := v.Lines()
+	want := []string{"Vote: kick Carol", "Called by Alice", "Yes 1  No 0  (2 needed)", "F1: Yes | F2: No | 6 s left"}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Lines = %q, want %q", lines, want)
+	}
+	if v.YesShare() != 0.5 {
+		t.Errorf("YesShare = %v, want 0.5", v.YesShare())
+	}
+
+	v.Status.Result = protocol.VoteFailed
+	v.Tick = 2000
+	if lines := v.Lines(); lines[len(lines)-1] != "Vote failed" || v.Left() != 0 {
+		t.Errorf("A failed vote should say so, got %q", lines)
+	}
+}
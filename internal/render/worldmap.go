@@ -0,0 +1,77 @@
+package render
+
+import (
+	"strings"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/i18n"
+)
+
+// WorldMap is the campaign's level select screen: its levels in order with
+// the stars earned on each, locked ones with what unlocks them, one of them
+// selected
+type WorldMap struct {
+	Campaign *game.Campaign
+	Progress game.CampaignProgress
+	Selected int
+	Status   string        // Load errors
+	Lang     *i18n.Catalog // nil for English
+}
+
+// starOrder is the order StarMarks shows stars in
+var starOrder = [...]game.Stars{game.StarFinished, game.StarCages, game.StarOrbs, game.StarTime}
+
+// StarMarks formats a level's stars as one mark each, in the order
+// finished, all cages, all orbs, time goal: * earned, . not yet
+func StarMarks(s game.Stars) string {
+	var b strings.Builder
+	for _, star := range starOrder {
+		if s&star != 0 {
+			b.WriteByte('*')
+		} else {
+			b.WriteByte('.')
+		}
+	}
+	return b.String()
+}
+
+// Lines formats the world map as text rows, title first; the selected
+// level is marked with >
+func (m *WorldMap) Lines() []string {
+	tr, c := m.Lang, m.Campaign
+	lines := make([]string, 0, len(c.Levels)+8)
+	lines = append(lines, tr.T("worldmap.title", c.Name), tr.T("worldmap.stars", m.Progress.Total(), len(starOrder)*len(c.Levels)), "")
+	for i := range c.Levels {
+		l := &c.Levels[i]
+		marker := "  "
+		if i == m.Selected {
+			marker = "> "
+		}
+		state := StarMarks(m.Progress[l.ID])
+		if levels, stars := c.Missing(i, m.Progress); len(levels) > 0 {
+			titles := make([]string, len(levels))
+			for j, id := range levels {
+				titles[j] = m.title(id)
+			}
+			state = tr.T("worldmap.finish", strings.Join(titles, ", "))
+		} else if stars > 0 {
+			state = tr.T("worldmap.needs", stars)
+		}
+		lines = append(lines, marker+i18n.PadRight(l.Title, 24)+" "+state)
+	}
+	lines = append(lines, "", tr.T("worldmap.legend"))
+	if m.Status != "" {
+		lines = append(lines, m.Status)
+	}
+	return append(lines, tr.T("worldmap.footer"))
+}
+
+// title returns the title of the level with the given ID
+func (m *WorldMap) title(id string) string {
+	for _, l := range m.Campaign.Levels {
+		if l.ID == id {
+			return l.Title
+		}
+	}
+	return id
+}
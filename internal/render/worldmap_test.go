@@ -0,0 +1,40 @@
+package render
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+)
+
+// TestWorldMap tests the world map's rows: stars earned, the selection and
+// what locks each locked level.
+func TestWorldMap(t *testing.T) {
+	fsys := fstest.MapFS{"c.json": {Data: []byte(`{"name": "Forest", "levels": [
+		{"id": "glade", "title": "The Glade", "file": "glade.json"},
+		{"id": "vault", "title": "The Vault", "file": "vault.json"},
+		{"id": "bonus", "title": "Bonus", "file": "bonus.json", "requires": ["glade"], "stars": 8}]}`)}}
+	c, err := game.ReadCampaign(fsys, "c.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &WorldMap{Campaign: c, Progress: game.CampaignProgress{"glade": game.StarFinished | game.StarTime}, Selected: 1}
+	lines := m.Lines()
+	want := []string{
+		"World map: Forest",
+		"Stars: 2/12",
+		"",
+		"  The Glade                *..*",
+		"> The Vault                ....",
+		"  Bonus                    Locked: 6 more stars",
+	}
+	if got := strings.Join(lines[:len(want)], "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("Lines =\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+
+	m.Progress = game.CampaignProgress{}
+	if lines := m.Lines(); lines[4] != "> The Vault                Locked: finish The Glade" {
+		t.Errorf("Locked level row = %q", lines[4])
+	}
+}
@@ -0,0 +1,203 @@
+package scripting
+
+import (
+	"fmt"
+
+	"go.starlark.net/starlark"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// scriptKey is the thread-local holding the calling script
+const scriptKey = "script"
+
+// builtins returns the world API predeclared in every script:
+//
+//	spawn(type, x, y) -> id           spawn an enemy
+//	despawn(id) -> bool               remove an entity
+//	position(id) -> (x, y) | None     entity position
+//	velocity(id) -> (vx, vy) | None   entity velocity
+//	set_velocity(id, vx, vy) -> bool  set entity velocity
+//	set_gravity(id, scale) -> bool    set gravity scale (0 = floats)
+//	player_position(player=1)         player position, or None
+//	entities(sprite) -> [id]          entities using a sprite, by ID
+//	tick() -> int                     current tick
+//	subscribe(event, fn)              call fn(event) on world events
+//	dialogue(id, player=1) -> bool    open a level dialogue for a player
+//	flag(name) -> int                 script flag, 0 if unset
+//	set_flag(name, value=1)           set a script flag
+func (e *Engine) builtins() starlark.StringDict {
+	return starlark.StringDict{
+		"spawn":           starlark.NewBuiltin("spawn", e.spawn),
+		"despawn":         starlark.NewBuiltin("despawn", e.despawn),
+		"position":        starlark.NewBuiltin("position", e.position),
+		"velocity":        starlark.NewBuiltin("velocity", e.velocity),
+		"set_velocity":    starlark.NewBuiltin("set_velocity", e.setVelocity),
+		"set_gravity":     starlark.NewBuiltin("set_gravity", e.setGravity),
+		"player_position": starlark.NewBuiltin("player_position", e.playerPosition),
+		"entities":        starlark.NewBuiltin("entities", e.entities),
+		"tick":            starlark.NewBuiltin("tick", e.tick),
+		"subscribe":       starlark.NewBuiltin("subscribe", e.subscribe),
+		"dialogue":        starlark.NewBuiltin("dialogue", e.dialogue),
+		"flag":            starlark.NewBuiltin("flag", e.flag),
+		"set_flag":        starlark.NewBuiltin("set_flag", e.setFlag),
+	}
+}
+
+func (e *Engine) spawn(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var kind string
+	var x, y starlark.Value
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "type", &kind, "x", &x, "y", &y); err != nil {
+		return nil, err
+	}
+	fx, fy, err := coords(b, x, y)
+	if err != nil {
+		return nil, err
+	}
+	entity := e.world.SpawnEnemy(kind, fx, fy)
+	return starlark.MakeUint64(uint64(e.world.NetIDOf(entity))), nil
+}
+
+func (e *Engine) despawn(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var id uint64
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "id", &id); err != nil {
+		return nil, err
+	}
+	return starlark.Bool(e.world.Despawn(protocol.EntityID(id))), nil
+}
+
+func (e *Engine) position(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var id uint64
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "id", &id); err != nil {
+		return nil, err
+	}
+	x, y, ok := e.world.EntityPosition(protocol.EntityID(id))
+	if !ok {
+		return starlark.None, nil
+	}
+	return starlark.Tuple{starlark.Float(x), starlark.Float(y)}, nil
+}
+
+func (e *Engine) velocity(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var id uint64
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "id", &id); err != nil {
+		return nil, err
+	}
+	vx, vy, ok := e.world.EntityVelocity(protocol.EntityID(id))
+	if !ok {
+		return starlark.None, nil
+	}
+	return starlark.Tuple{starlark.Float(vx), starlark.Float(vy)}, nil
+}
+
+func (e *Engine) setGravity(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var id uint64
+	var scale starlark.Value
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "id", &id, "scale", &scale); err != nil {
+		return nil, err
+	}
+	f, ok := starlark.AsFloat(scale)
+	if !ok {
+		return nil, fmt.Errorf("%s: got %s, want number", b.Name(), scale.Type())
+	}
+	return starlark.Bool(e.world.SetEntityGravity(protocol.EntityID(id), f)), nil
+}
+
+func (e *Engine) setVelocity(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var id uint64
+	var vx, vy starlark.Value
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "id", &id, "vx", &vx, "vy", &vy); err != nil {
+		return nil, err
+	}
+	fx, fy, err := coords(b, vx, vy)
+	if err != nil {
+		return nil, err
+	}
+	return starlark.Bool(e.world.SetEntityVelocity(protocol.EntityID(id), fx, fy)), nil
+}
+
+func (e *Engine) playerPosition(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	player := 1
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "player?", &player); err != nil {
+		return nil, err
+	}
+	x, y, ok := e.world.PlayerPosition(player)
+	if !ok {
+		return starlark.None, nil
+	}
+	return starlark.Tuple{starlark.Float(x), starlark.Float(y)}, nil
+}
+
+func (e *Engine) entities(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var sprite string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "sprite", &sprite); err != nil {
+		return nil, err
+	}
+	ids := e.world.EntitiesWithSprite(sprite)
+	list := make([]starlark.Value, len(ids))
+	for i, id := range ids {
+		list[i] = starlark.MakeUint64(uint64(id))
+	}
+	return starlark.NewList(list), nil
+}
+
+func (e *Engine) tick(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs); err != nil {
+		return nil, err
+	}
+	return starlark.MakeUint64(e.world.Tick), nil
+}
+
+func (e *Engine) subscribe(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var event string
+	var fn starlark.Callable
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "event", &event, "fn", &fn); err != nil {
+		return nil, err
+	}
+	s, ok := thread.Local(scriptKey).(*script)
+	if !ok {
+		return nil, fmt.Errorf("%s: not called from a script", b.Name())
+	}
+	s.handlers[event] = append(s.handlers[event], fn)
+	return starlark.None, nil
+}
+
+func (e *Engine) dialogue(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var id string
+	player := 1
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "id", &id, "player?", &player); err != nil {
+		return nil, err
+	}
+	return starlark.Bool(e.world.StartDialogue(player, id)), nil
+}
+
+func (e *Engine) flag(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "name", &name); err != nil {
+		return nil, err
+	}
+	return starlark.MakeInt(e.world.Flag(name)), nil
+}
+
+func (e *Engine) setFlag(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name string
+	value := 1
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "name", &name, "value?", &value); err != nil {
+		return nil, err
+	}
+	e.world.SetFlag(name, value)
+	return starlark.None, nil
+}
+
+// coords converts two numbers (int or float) to float64
+func coords(b *starlark.Builtin, x, y starlark.Value) (float64, float64, error) {
+	fx, ok := starlark.AsFloat(x)
+	if !ok {
+		return 0, 0, fmt.Errorf("%s: got %s, want number", b.Name(), x.Type())
+	}
+	fy, ok := starlark.AsFloat(y)
+	if !ok {
+		return 0, 0, fmt.Errorf("%s: got %s, want number", b.Name(), y.Type())
+	}
+	return fx, fy, nil
+}
@@ -0,0 +1,169 @@
+// Package scripting runs Starlark level and entity scripts inside the tick.
+//
+// Starlark is deterministic by design: no clocks, no randomness, no I/O and
+// ordered dicts. Scripts get a small API over the game world and run as the
+// "script" system after collision, so the same inputs always produce the
+// same world on every machine.
+package scripting
+
+import (
+	"fmt"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+)
+
+// DefaultMaxSteps bounds the work one script call may do per tick
+const DefaultMaxSteps = 100_000
+
+// Engine loads scripts and runs their hooks against a world
+type Engine struct {
+	world   *game.World
+	scripts []*script
+
+	// MaxSteps is the execution step budget for each hook call
+	MaxSteps uint64
+
+	// OnError, if set, receives script errors. A script that fails is
+	// disabled until the next reload.
+	OnError func(err error)
+
+	// Print, if set, receives output of the script print builtin
+	Print func(script, msg string)
+}
+
+// script is one loaded file and the hooks it registered
+type script struct {
+	name     string
+	state    *starlark.Dict // Mutable per-script storage; globals freeze after load
+	onTick   starlark.Callable
+	handlers map[string][]starlark.Callable
+	err      error
+}
+
+// New creates an engine for the world and registers it as the world's
+// "script" system. Scripts of the world's level are reloaded on reset, and
+// their state is saved with the world's snapshots.
+func New(world *game.World) *Engine {
+	e := &Engine{world: world, MaxSteps: DefaultMaxSteps}
+	world.Systems().Add("script", e.runTick, "collision")
+	world.Subscribe(e.onEvent)
+	world.SetScriptState(e)
+	return e
+}
+
+// LoadLevel replaces all loaded scripts with the level's scripts
+func (e *Engine) LoadLevel(level *game.Level) error {
+	e.scripts = nil
+	for _, s := range level.Scripts {
+		if err := e.Load(s.Name, s.Source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load executes a script file. Its top level runs once; it may define
+// on_tick(tick) and call subscribe(event, fn). Globals are frozen after the
+// top level runs, so scripts keep mutable data in the predeclared state dict.
+func (e *Engine) Load(name string, src []byte) error {
+	s := &script{name: name, state: starlark.NewDict(0), handlers: make(map[string][]starlark.Callable)}
+	thread := e.thread(s)
+
+	predeclared := e.builtins()
+	predeclared["state"] = s.state
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, name, src, predeclared)
+	if err != nil {
+		return fmt.Errorf("script %s: %w", name, err)
+	}
+	if fn, ok := globals["on_tick"].(starlark.Callable); ok {
+		s.onTick = fn
+	}
+	e.scripts = append(e.scripts, s)
+	return nil
+}
+
+// thread creates a sandboxed thread for one call: no load, bounded steps
+func (e *Engine) thread(s *script) *starlark.Thread {
+	thread := &starlark.Thread{
+		Name: s.name,
+		Print: func(_ *starlark.Thread, msg string) {
+			if e.Print != nil {
+				e.Print(s.name, msg)
+			}
+		},
+	}
+	thread.SetMaxExecutionSteps(e.MaxSteps)
+	thread.SetLocal(scriptKey, s)
+	return thread
+}
+
+// call runs a hook, disabling the script on error
+func (e *Engine) call(s *script, fn starlark.Callable, args ...starlark.Value) {
+	if s.err != nil {
+		return
+	}
+	if _, err := starlark.Call(e.thread(s), fn, args, nil); err != nil {
+		e.fail(s, err)
+	}
+}
+
+// fail disables a script and reports why, once
+func (e *Engine) fail(s *script, err error) {
+	if s.err != nil {
+		return
+	}
+	s.err = fmt.Errorf("script %s: %w", s.name, err)
+	if e.OnError != nil {
+		e.OnError(s.err)
+	}
+}
+
+func (e *Engine) runTick() {
+	tick := starlark.MakeUint64(e.world.Tick)
+	for _, s := range e.scripts {
+		if s.onTick != nil {
+			e.call(s, s.onTick, tick)
+		}
+	}
+}
+
+func (e *Engine) onEvent(ev game.Event) {
+	if ev.Type == game.EventReset {
+		// Restart scripts with fresh state, like the rest of the level
+		if level := e.world.Level(); level != nil {
+			if err := e.LoadLevel(level); err != nil && e.OnError != nil {
+				e.OnError(err)
+			}
+		}
+	}
+
+	name := ev.Type.String()
+	value := starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"type":   starlark.String(name),
+		"tick":   starlark.MakeUint64(ev.Tick),
+		"level":  starlark.String(ev.Level),
+		"player": starlark.MakeInt(ev.Player),
+		"kind":   starlark.String(ev.Kind),
+		"amount": starlark.MakeInt(ev.Amount),
+	})
+	for _, s := range e.scripts {
+		for _, fn := range s.handlers[name] {
+			e.call(s, fn, value)
+		}
+	}
+}
+
+// Errors returns the errors of disabled scripts
+func (e *Engine) Errors() []error {
+	var errs []error
+	for _, s := range e.scripts {
+		if s.err != nil {
+			errs = append(errs, s.err)
+		}
+	}
+	return errs
+}
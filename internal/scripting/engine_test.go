@@ -0,0 +1,240 @@
+package scripting
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+)
+
+// testScript spawns a bat on load, counts ticks in state, respawns the bat
+// every 20 ticks and records resets in a flag
+const testScript = `
+bat = spawn("bat", 10, 5)
+set_gravity(bat, 0)
+state["ticks"] = 0
+state["bats"] = [bat]
+
+def on_tick(t):
+    state["ticks"] += 1
+    set_flag("ticks", state["ticks"])
+    if state["ticks"] % 20 == 0:
+        despawn(state["bats"][-1])
+        state["bats"].append(spawn("bat", 12, 5))
+
+def on_reset(event):
+    set_flag("resets", flag("resets") + 1)
+
+subscribe("reset", on_reset)
+`
+
+// scriptWorld returns a world playing a small level with src as its script
+func scriptWorld(t *testing.T, src string) (*game.World, *Engine, *[]error) {
+	t.Helper()
+	world := game.NewWorld()
+	engine := New(world)
+	var errs []error
+	engine.OnError = func(err error) { errs = append(errs, err) }
+	level := game.NewDemoLevel(40, 20)
+	level.Scripts = []game.Script{{Name: "test.star", Source: []byte(src)}}
+	world.LoadLevel(level)
+	if err := engine.LoadLevel(level); err != nil {
+		t.Fatal(err)
+	}
+	return world, engine, &errs
+}
+
+// TestEngineState tests that state persists across ticks and that a reset
+// reloads the script with fresh state before delivering the event.
+func TestEngineState(t *testing.T) {
+	world, _, errs := scriptWorld(t, testScript)
+	for range 30 {
+		world.Update()
+	}
+	if got := world.Flag("ticks"); got != 30 {
+		t.Errorf("ticks flag = %d after 30 ticks, want 30", got)
+	}
+
+	world.Reset(nil)
+	if got := world.Flag("resets"); got != 1 {
+		t.Errorf("resets flag = %d, want the reloaded handler to see the reset once", got)
+	}
+	world.Update()
+	if got := world.Flag("ticks"); got != 1 {
+		t.Errorf("ticks flag = %d one tick after the reset, want state to start over", got)
+	}
+	bats := 0
+	for _, r := range world.AppendRenderables(nil) {
+		if r.SpriteID == game.SpriteBat {
+			bats++
+		}
+	}
+	if bats != 1 {
+		t.Errorf("%d bats after the reset, want the reloaded script's one", bats)
+	}
+	if len(*errs) != 0 {
+		t.Errorf("Unexpected errors: %v", *errs)
+	}
+}
+
+// TestEngineDeterministic tests that two worlds running the same script,
+// spawning and despawning, end with the same network IDs and state.
+func TestEngineDeterministic(t *testing.T) {
+	a, _, _ := scriptWorld(t, testScript)
+	b, _, _ := scriptWorld(t, testScript)
+	for range 100 {
+		a.Update()
+		b.Update()
+	}
+	sa, sb := a.Snapshot(), b.Snapshot()
+	if sa.Checksum != sb.Checksum || len(sa.Entities) != len(sb.Entities) {
+		t.Fatalf("Worlds diverged: checksums %#x and %#x", sa.Checksum, sb.Checksum)
+	}
+	for i := range sa.Entities {
+		if sa.Entities[i].ID != sb.Entities[i].ID {
+			t.Errorf("Entity %d has ID %d and %d", i, sa.Entities[i].ID, sb.Entities[i].ID)
+		}
+	}
+	if string(sa.Scripts["test.star"]) != string(sb.Scripts["test.star"]) {
+		t.Errorf("Script state differs: %s and %s", sa.Scripts["test.star"], sb.Scripts["test.star"])
+	}
+}
+
+// TestEngineRollback tests that script state and flags are saved with
+// snapshots, so a restored world replays the same ticks the same way. The
+// replay stops short of the script's next spawn, as restored worlds never
+// reuse network IDs.
+func TestEngineRollback(t *testing.T) {
+	world, _, errs := scriptWorld(t, testScript)
+	for range 25 {
+		world.Update()
+	}
+	saved := world.Snapshot()
+	for range 10 {
+		world.Update()
+	}
+	want := world.Snapshot()
+
+	world.Restore(saved)
+	if got := world.Flag("ticks"); got != 25 {
+		t.Errorf("ticks flag = %d after restoring tick 25, want 25", got)
+	}
+	for range 10 {
+		world.Update()
+	}
+	got := world.Snapshot()
+	if got.Checksum != want.Checksum || string(got.Scripts["test.star"]) != string(want.Scripts["test.star"]) {
+		t.Errorf("Replay ended in %s, want %s", got.Scripts["test.star"], want.Scripts["test.star"])
+	}
+	if len(*errs) != 0 {
+		t.Errorf("Unexpected errors: %v", *errs)
+	}
+}
+
+// TestEngineStateValues tests that every kind of value a script may keep in
+// state survives saving, with dicts in insertion order, and that one that
+// can't be saved disables the script.
+func TestEngineStateValues(t *testing.T) {
+	world, engine, _ := scriptWorld(t, `
+state["z"] = None
+state["a"] = (1, 2.5, "x")
+state[7] = {"b": [True, False], 3: 1.0}
+state["big"] = 1 << 70
+state["order"] = []
+
+def on_tick(t):
+    state["order"] = list(state.keys())
+`)
+	before := engine.SaveScripts()
+	world.Update()
+	world.Restore(game.WorldState{Tick: world.Tick, Scripts: before})
+	after := engine.SaveScripts()
+	if string(after["test.star"]) != string(before["test.star"]) {
+		t.Errorf("State changed in a round trip: %s, want %s", after["test.star"], before["test.star"])
+	}
+	world.Update()
+	if s := string(engine.SaveScripts()["test.star"]); !strings.Contains(s, `["z","a",7,"big","order"]`) {
+		t.Errorf("Restored dict iterates as %s, want insertion order", s)
+	}
+
+	bad, engine, errs := scriptWorld(t, `
+def on_tick(t):
+    state["fn"] = on_tick
+`)
+	bad.Update()
+	if saved := engine.SaveScripts(); saved != nil || len(*errs) != 1 || len(engine.Errors()) != 1 {
+		t.Errorf("Saving a function: saved %s, errors %v, want the script disabled", saved, *errs)
+	}
+}
+
+// TestEngineSandbox tests that a hook running past DefaultMaxSteps or
+// failing is reported once and disabled, without stopping other scripts,
+// until the level is reloaded.
+func TestEngineSandbox(t *testing.T) {
+	world := game.NewWorld()
+	engine := New(world)
+	var errs []error
+	engine.OnError = func(err error) { errs = append(errs, err) }
+	level := game.NewDemoLevel(40, 20)
+	level.Scripts = []game.Script{
+		{Name: "spin.star", Source: []byte(`
+def on_tick(t):
+    for i in range(200000):
+        pass
+`)},
+		{Name: "fail.star", Source: []byte(`
+def on_reset(event):
+    return 1 // 0
+
+subscribe("reset", on_reset)
+`)},
+		{Name: "count.star", Source: []byte(`
+def on_tick(t):
+    set_flag("ticks", flag("ticks") + 1)
+`)},
+	}
+	world.LoadLevel(level)
+	if err := engine.LoadLevel(level); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 5 {
+		world.Update()
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "spin.star") || !strings.Contains(errs[0].Error(), "too many steps") {
+		t.Fatalf("Errors = %v, want spin.star stopped once for too many steps", errs)
+	}
+	if got := world.Flag("ticks"); got != 5 {
+		t.Errorf("Other scripts ran %d of 5 ticks", got)
+	}
+
+	// The reset reloads spin.star, which then fails again on its next tick
+	world.Reset(nil)
+	if len(errs) != 2 || !strings.Contains(errs[1].Error(), "fail.star") || !strings.Contains(errs[1].Error(), "division by zero") {
+		t.Fatalf("Errors = %v, want the reset handler's division by zero", errs)
+	}
+	world.Update()
+	if len(errs) != 3 || len(engine.Errors()) != 2 {
+		t.Errorf("Errors = %v, want spin.star to fail again after its reload", errs)
+	}
+}
+
+// TestEngineLoadErrors tests that scripts using load, while or recursion
+// are refused.
+func TestEngineLoadErrors(t *testing.T) {
+	tests := []struct {
+		name, src string
+	}{
+		{"load", `load("other.star", "x")`},
+		{"while", "def f():\n    while True:\n        pass\n"},
+		{"recursion", "def f(n):\n    return f(n - 1) if n else 0\nf(3)\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			engine := New(game.NewWorld())
+			if err := engine.Load("bad.star", []byte(tc.src)); err == nil {
+				t.Error("Script should be refused")
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package scripting
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"go.starlark.net/starlark"
+)
+
+// maxStateDepth bounds how deeply state values may nest, which also stops
+// a dict that contains itself
+const maxStateDepth = 32
+
+// SaveScripts returns each script's state dict as JSON, for
+// game.WorldState; scripts with an empty state are left out. A script whose
+// state holds a value that can't be saved, such as a function, is disabled
+// as if it had failed.
+func (e *Engine) SaveScripts() map[string]json.RawMessage {
+	var saved map[string]json.RawMessage
+	for _, s := range e.scripts {
+		if s.state.Len() == 0 {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := encodeValue(&buf, s.state, 0); err != nil {
+			e.fail(s, fmt.Errorf("saving state: %w", err))
+			continue
+		}
+		if saved == nil {
+			saved = make(map[string]json.RawMessage, len(e.scripts))
+		}
+		saved[s.name] = buf.Bytes()
+	}
+	return saved
+}
+
+// RestoreScripts puts back the state dicts saved by SaveScripts. A script
+// missing from saved gets an empty state.
+func (e *Engine) RestoreScripts(saved map[string]json.RawMessage) {
+	for _, s := range e.scripts {
+		if err := restoreState(s.state, saved[s.name]); err != nil {
+			e.fail(s, fmt.Errorf("restoring state: %w", err))
+		}
+	}
+}
+
+// restoreState replaces the contents of state, keeping the dict itself,
+// which the script's functions hold on to
+func restoreState(state *starlark.Dict, data json.RawMessage) error {
+	if err := state.Clear(); err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var raw any
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	v, err := decodeValue(raw)
+	if err != nil {
+		return err
+	}
+	saved, ok := v.(*starlark.Dict)
+	if !ok {
+		return fmt.Errorf("state is a %s, want dict", v.Type())
+	}
+	for _, item := range saved.Items() {
+		if err := state.SetKey(item[0], item[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeValue writes a state value as JSON. None, bools, ints, floats,
+// strings and lists map to JSON directly; tuples are {"tuple": [...]} and
+// dicts {"dict": [[key, value], ...]}, in insertion order, so a restored
+// dict iterates exactly as the saved one did.
+func encodeValue(buf *bytes.Buffer, v starlark.Value, depth int) error {
+	if depth > maxStateDepth {
+		return fmt.Errorf("state nests deeper than %d", maxStateDepth)
+	}
+	switch x := v.(type) {
+	case starlark.NoneType:
+		buf.WriteString("null")
+	case starlark.Bool:
+		buf.WriteString(strconv.FormatBool(bool(x)))
+	case starlark.Int:
+		buf.WriteString(x.String())
+	case starlark.Float:
+		if math.IsInf(float64(x), 0) || math.IsNaN(float64(x)) {
+			return fmt.Errorf("can't save %v", x)
+		}
+		buf.WriteString(x.String()) // Always has a '.' or an exponent
+	case starlark.String:
+		data, _ := json.Marshal(string(x))
+		buf.Write(data)
+	case *starlark.List:
+		return encodeItems(buf, x, depth)
+	case starlark.Tuple:
+		buf.WriteString(`{"tuple":`)
+		if err := encodeItems(buf, x, depth); err != nil {
+			return err
+		}
+		buf.WriteByte('}')
+	case *starlark.Dict:
+		buf.WriteString(`{"dict":[`)
+		for i, item := range x.Items() {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeItems(buf, item, depth); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("]}")
+	default:
+		return fmt.Errorf("can't save a %s", v.Type())
+	}
+	return nil
+}
+
+func encodeItems(buf *bytes.Buffer, items starlark.Indexable, depth int) error {
+	buf.WriteByte('[')
+	for i := range items.Len() {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := encodeValue(buf, items.Index(i), depth+1); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte(']')
+	return nil
+}
+
+// decodeValue turns JSON decoded with UseNumber back into the value
+// encodeValue wrote
+func decodeValue(raw any) (starlark.Value, error) {
+	switch x := raw.(type) {
+	case nil:
+		return starlark.None, nil
+	case bool:
+		return starlark.Bool(x), nil
+	case json.Number:
+		s := x.String()
+		if strings.ContainsAny(s, ".eE") {
+			f, err := strconv.ParseFloat(s, 64)
+			return starlark.Float(f), err
+		}
+		n, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return nil, fmt.Errorf("bad int %s", s)
+		}
+		return starlark.MakeBigInt(n), nil
+	case string:
+		return starlark.String(x), nil
+	case []any:
+		items, err := decodeItems(x)
+		if err != nil {
+			return nil, err
+		}
+		return starlark.NewList(items), nil
+	case map[string]any:
+		if tuple, ok := x["tuple"].([]any); ok && len(x) == 1 {
+			items, err := decodeItems(tuple)
+			return starlark.Tuple(items), err
+		}
+		pairs, ok := x["dict"].([]any)
+		if !ok || len(x) != 1 {
+			return nil, fmt.Errorf("unknown object in state")
+		}
+		d := starlark.NewDict(len(pairs))
+		for _, p := range pairs {
+			pair, ok := p.([]any)
+			if !ok || len(pair) != 2 {
+				return nil, fmt.Errorf("bad dict entry in state")
+			}
+			items, err := decodeItems(pair)
+			if err != nil {
+				return nil, err
+			}
+			if err := d.SetKey(items[0], items[1]); err != nil {
+				return nil, err
+			}
+		}
+		return d, nil
+	}
+	return nil, fmt.Errorf("unexpected %T in state", raw)
+}
+
+func decodeItems(raw []any) ([]starlark.Value, error) {
+	items := make([]starlark.Value, len(raw))
+	for i, r := range raw {
+		v, err := decodeValue(r)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = v
+	}
+	return items, nil
+}
@@ -0,0 +1,364 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+)
+
+// AdminCommand is a console command handler. It receives the words after the
+// command name and returns text to print.
+type AdminCommand struct {
+	Usage string
+	Help  string
+	Run   func(args []string) (string, error)
+}
+
+// Admin is the server's operator console. It parses one command per line;
+// the transport (stdin, a socket) is up to the caller.
+type Admin struct {
+	server   *Server
+	commands map[string]AdminCommand
+}
+
+// NewAdmin creates a console for the server with the built-in commands
+func NewAdmin(s *Server) *Admin {
+	a := &Admin{server: s, commands: make(map[string]AdminCommand)}
+
+	a.Register("help", AdminCommand{Help: "list commands", Run: a.help})
+	a.Register("status", AdminCommand{Help: "show tick, sessions and time scale", Run: a.status})
+	a.Register("pause", AdminCommand{Help: "pause the simulation", Run: a.pause})
+	a.Register("resume", AdminCommand{Help: "resume the simulation", Run: a.resume})
+	a.Register("step", AdminCommand{Usage: "[n]", Help: "pause and run n ticks (default 1)", Run: a.step})
+	a.Register("restart", AdminCommand{Help: "restart the current level", Run: a.restart})
+	a.Register("systems", AdminCommand{Usage: "[reset]", Help: "show per-system tick timings", Run: a.systems})
+	a.Register("mode", AdminCommand{Usage: "[coop|race|deathmatch]", Help: "show or start a game mode", Run: a.mode})
+	a.Register("scores", AdminCommand{Help: "show match scores", Run: a.scores})
+	a.Register("violations", AdminCommand{Help: "show anti-cheat violations and strikes", Run: a.violations})
+	a.Register("net", AdminCommand{Help: "show traffic per session", Run: a.net})
+	a.Register("allocs", AdminCommand{Usage: "[on|off|reset]", Help: "show or track allocations per subsystem", Run: a.allocs})
+	a.Register("speed", AdminCommand{Usage: "<scale>", Help: "set time scale, 0.25 to 4", Run: a.speed})
+	a.Register("afk", AdminCommand{Help: "list idle players", Run: a.afk})
+	a.Register("kick", AdminCommand{Usage: "<player>", Help: "disconnect a player, by ID or name", Run: a.kick})
+	a.Register("friendlyfire", AdminCommand{Usage: "[on|off]", Help: "show or set friendly fire", Run: a.friendlyFire})
+	a.Register("difficulty", AdminCommand{Usage: "[easy|normal|hard]", Help: "show or set the difficulty", Run: a.difficulty})
+	a.Register("vote", AdminCommand{Usage: "[cancel]", Help: "show or cancel the players' vote", Run: a.vote})
+	a.Register("map", AdminCommand{Usage: "[name]", Help: "list the votable maps or change to one", Run: a.changeMap})
+
+	return a
+}
+
+// Register adds or replaces a command
+func (a *Admin) Register(name string, cmd AdminCommand) {
+	a.commands[name] = cmd
+}
+
+// Exec runs one console line
+func (a *Admin) Exec(line string) (string, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	cmd, ok := a.commands[strings.ToLower(fields[0])]
+	if !ok {
+		return "", fmt.Errorf("unknown command %q (try help)", fields[0])
+	}
+	return cmd.Run(fields[1:])
+}
+
+func (a *Admin) help([]string) (string, error) {
+	names := make([]string, 0, len(a.commands))
+	for name := range a.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		cmd := a.commands[name]
+		fmt.Fprintf(&b, "%-20s %s\n", strings.TrimSpace(name+" "+cmd.Usage), cmd.Help)
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+func (a *Admin) status([]string) (string, error) {
+	s := a.server
+	s.mu.RLock()
+	sessions := len(s.sessions)
+	s.mu.RUnlock()
+	return fmt.Sprintf("tick %d, %d sessions, time %s", s.Tick(), sessions, s.time.String()), nil
+}
+
+func (a *Admin) pause([]string) (string, error) {
+	a.server.time.Pause()
+	return fmt.Sprintf("paused at tick %d", a.server.Tick()), nil
+}
+
+func (a *Admin) resume([]string) (string, error) {
+	a.server.time.Resume()
+	return "resumed", nil
+}
+
+func (a *Admin) step(args []string) (string, error) {
+	n := 1
+	if len(args) > 0 {
+		v, err := strconv.Atoi(args[0])
+		if err != nil || v < 1 {
+			return "", fmt.Errorf("step: invalid tick count %q", args[0])
+		}
+		n = v
+	}
+	a.server.time.Step(n)
+	return fmt.Sprintf("stepping %d tick(s) from %d", n, a.server.Tick()), nil
+}
+
+func (a *Admin) speed(args []string) (string, error) {
+	if len(args) == 0 {
+		return a.server.time.String(), nil
+	}
+	scale, err := strconv.ParseFloat(strings.TrimPrefix(args[0], "x"), 64)
+	if err != nil {
+		return "", fmt.Errorf("speed: invalid scale %q", args[0])
+	}
+	if err := a.server.time.SetScale(scale); err != nil {
+		return "", err
+	}
+	return a.server.time.String(), nil
+}
+
+func (a *Admin) restart([]string) (string, error) {
+	w := a.server.World()
+	if w == nil || w.Level() == nil {
+		return "", fmt.Errorf("restart: no level loaded")
+	}
+	a.server.ResetLevel(nil)
+	return fmt.Sprintf("restarted %s at tick %d", w.Level().Name, a.server.Tick()), nil
+}
+
+func (a *Admin) systems(args []string) (string, error) {
+	if len(args) > 0 && args[0] == "reset" {
+		a.server.mu.Lock()
+		a.server.world.Systems().ResetTimings()
+		a.server.mu.Unlock()
+		return "timings reset", nil
+	}
+
+	timings, total := a.server.SystemTimings()
+	budget := a.server.TickBudget()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-12s %10s %10s %10s\n", "system", "last", "avg", "max")
+	for _, t := range append(timings, total) {
+		fmt.Fprintf(&b, "%-12s %10s %10s %10s\n", t.Name, t.Last, t.Avg, t.Max)
+	}
+	fmt.Fprintf(&b, "budget %s, avg %.1f%% used", budget, 100*float64(total.Avg)/float64(budget))
+	return b.String(), nil
+}
+
+func (a *Admin) mode(args []string) (string, error) {
+	if len(args) == 0 {
+		status, ok := a.server.MatchStatus()
+		if !ok {
+			return "no game mode", nil
+		}
+		return status.Mode, nil
+	}
+	mode, err := NewGameMode(args[0])
+	if err != nil {
+		return "", err
+	}
+	a.server.SetGameMode(mode)
+	return fmt.Sprintf("started %s match", mode.Name()), nil
+}
+
+func (a *Admin) scores([]string) (string, error) {
+	status, ok := a.server.MatchStatus()
+	if !ok {
+		return "", fmt.Errorf("scores: no game mode")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s, %d ticks", status.Mode, status.Elapsed)
+	if status.Wave > 0 {
+		fmt.Fprintf(&b, ", wave %d", status.Wave)
+	}
+	b.WriteString("\n")
+	for _, ps := range status.Scores {
+		fmt.Fprintf(&b, "%3d %-16s score %d, kills %d, deaths %d", ps.PlayerID, ps.Name, ps.Score, ps.Kills, ps.Deaths)
+		if ps.Finished {
+			fmt.Fprintf(&b, ", finished in %d ticks", ps.Time)
+		}
+		b.WriteString("\n")
+	}
+	if status.Ended {
+		fmt.Fprintf(&b, "match over (%s), winners %v", status.Result.Reason, status.Result.Winners)
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+func (a *Admin) violations([]string) (string, error) {
+	var b strings.Builder
+	kinds, counts := a.server.ViolationCounts()
+	if len(kinds) == 0 {
+		b.WriteString("no violations\n")
+	}
+	for i, kind := range kinds {
+		fmt.Fprintf(&b, "%-12s %d\n", kind, counts[i])
+	}
+
+	s := a.server
+	s.mu.RLock()
+	for _, session := range s.sessions {
+		if session.strikes > 0 {
+			fmt.Fprintf(&b, "session %d (%s): %d strikes\n", session.ID, session.Name, session.strikes)
+		}
+	}
+	s.mu.RUnlock()
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+func (a *Admin) net([]string) (string, error) {
+	stats := a.server.SessionNetStats()
+	if len(stats) == 0 {
+		return "no sessions", nil
+	}
+	ids := make([]int, 0, len(stats))
+	for id := range stats {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%7s %10s %10s %8s %8s %6s %6s %9s\n", "session", "bytes in", "bytes out", "pkts in", "pkts out", "full", "delta", "avg snap")
+	for _, id := range ids {
+		n := stats[id]
+		fmt.Fprintf(&b, "%7d %10d %10d %8d %8d %6d %6d %9.0f\n",
+			id, n.BytesIn, n.BytesOut, n.PacketsIn, n.PacketsOut, n.FullSnapshots, n.DeltaSnapshots, n.AvgSnapshotSize())
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+func (a *Admin) allocs(args []string) (string, error) {
+	tracker := a.server.Allocs()
+	if len(args) > 0 {
+		switch strings.ToLower(args[0]) {
+		case "on":
+			tracker.SetEnabled(true)
+			return "allocation tracking on", nil
+		case "off":
+			tracker.SetEnabled(false)
+			return "allocation tracking off", nil
+		case "reset":
+			tracker.Reset()
+			return "allocation counts reset", nil
+		default:
+			return "", fmt.Errorf("allocs: want on, off or reset, not %q", args[0])
+		}
+	}
+
+	counters := tracker.AppendCounters(nil)
+	if len(counters) == 0 {
+		if !tracker.Enabled() {
+			return "allocation tracking off (allocs on)", nil
+		}
+		return "nothing measured yet", nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-12s %8s %12s %12s %8s %8s\n", "subsystem", "spans", "allocs/span", "bytes/span", "last", "max")
+	for _, c := range counters {
+		allocs, bytes := c.PerSpan()
+		fmt.Fprintf(&b, "%-12s %8d %12.1f %12.0f %8d %8d\n", c.Name, c.Spans, allocs, bytes, c.LastAllocs, c.MaxAllocs)
+	}
+	if !tracker.Enabled() {
+		b.WriteString("tracking off\n")
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+func (a *Admin) afk([]string) (string, error) {
+	var b strings.Builder
+	for _, p := range a.server.Roster() {
+		if p.AFK {
+			fmt.Fprintf(&b, "%3d %s\n", p.PlayerID, p.Name)
+		}
+	}
+	if b.Len() == 0 {
+		return "no idle players", nil
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+func (a *Admin) kick(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("kick: which player?")
+	}
+	for _, p := range a.server.Roster() {
+		if strconv.Itoa(p.PlayerID) != args[0] && !strings.EqualFold(p.Name, args[0]) {
+			continue
+		}
+		if session, ok := a.server.sessionOf(p.PlayerID); ok {
+			a.server.Kick(session, ReasonOperatorKicked)
+			return fmt.Sprintf("kicked %s (player %d)", p.Name, p.PlayerID), nil
+		}
+	}
+	return "", fmt.Errorf("kick: no player %q", args[0])
+}
+
+func (a *Admin) friendlyFire(args []string) (string, error) {
+	if len(args) > 0 {
+		switch strings.ToLower(args[0]) {
+		case "on":
+			a.server.SetFriendlyFire(true)
+		case "off":
+			a.server.SetFriendlyFire(false)
+		default:
+			return "", fmt.Errorf("friendlyfire: want on or off, not %q", args[0])
+		}
+	}
+	if a.server.Settings().FriendlyFire {
+		return "friendly fire on", nil
+	}
+	return "friendly fire off", nil
+}
+
+func (a *Admin) difficulty(args []string) (string, error) {
+	if len(args) > 0 {
+		d, err := game.ParseDifficulty(strings.ToLower(args[0]))
+		if err != nil {
+			return "", err
+		}
+		a.server.SetDifficulty(d)
+		return fmt.Sprintf("difficulty %s from the next restart", d), nil
+	}
+	return fmt.Sprintf("difficulty %s", game.Difficulty(a.server.Settings().Difficulty)), nil
+}
+
+func (a *Admin) vote(args []string) (string, error) {
+	if len(args) > 0 {
+		if args[0] != "cancel" {
+			return "", fmt.Errorf("vote: want cancel, not %q", args[0])
+		}
+		if !a.server.CancelVote() {
+			return "", fmt.Errorf("vote: no vote is open")
+		}
+		return "vote cancelled", nil
+	}
+	return a.server.voteSummary(), nil
+}
+
+func (a *Admin) changeMap(args []string) (string, error) {
+	if len(args) == 0 {
+		maps := a.server.Maps()
+		if len(maps) == 0 {
+			return "no maps to vote for (rayserver -maps)", nil
+		}
+		return strings.Join(maps, "\n"), nil
+	}
+	if err := a.server.ChangeMap(args[0]); err != nil {
+		return "", err
+	}
+	return "map changed to " + args[0], nil
+}
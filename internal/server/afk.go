@@ -0,0 +1,59 @@
+package server
+
+// ReasonAFK is the disconnect reason for a player removed for idling
+const ReasonAFK = "removed for being idle"
+
+// AFKConfig tunes idle player detection. A player who sends no intents for
+// After ticks while a match is on is flagged AFK in the roster; in co-op
+// (see ModeRules.IdleSpectate) they also spectate, invulnerable and ignored
+// by enemies, until they move again. After Kick more ticks they are removed
+// to free their slot, which public rooms want and private ones usually
+// don't. Zero turns either off.
+type AFKConfig struct {
+	After uint64 // Idle ticks before a player is flagged AFK
+	Kick  uint64 // Further ticks before an AFK player is removed
+}
+
+// DefaultAFKConfig flags players after a minute without input and never
+// removes them
+func DefaultAFKConfig() AFKConfig {
+	return AFKConfig{After: 60 * 60}
+}
+
+// tickAFK counts each session's idle ticks, flags and unflags AFK players,
+// and removes those idle past AFKConfig.Kick. Nothing counts while the
+// match is over, so reading the results isn't idling.
+func (s *Server) tickAFK() {
+	s.mu.Lock()
+	cfg := s.config.AFK
+	if cfg.After == 0 || s.world == nil {
+		s.mu.Unlock()
+		return
+	}
+	counting := s.match == nil || !s.match.Ended
+	spectate := s.match == nil || s.match.Mode.Rules().IdleSpectate
+
+	var kick []int
+	for id, session := range s.sessions {
+		switch {
+		case session.moved:
+			session.idle, session.moved = 0, false
+		case counting:
+			session.idle++
+		}
+		afk := session.idle >= cfg.After
+		if afk != session.afk {
+			session.afk = afk
+			s.rosterVersion++
+		}
+		s.world.SetSpectating(session.PlayerID, afk && spectate)
+		if afk && cfg.Kick > 0 && session.idle >= cfg.After+cfg.Kick {
+			kick = append(kick, id)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, id := range kick {
+		s.Kick(id, ReasonAFK)
+	}
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// TestAFK tests that an idle player is flagged in the roster, spectates in
+// co-op until they move and is removed once idle past the kick timeout.
+func TestAFK(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AFK = AFKConfig{After: 10, Kick: 20}
+	srv := New(cfg)
+	world := game.NewWorld()
+	world.LoadLevel(game.NewDemoLevel(40, 20))
+	srv.SetWorld(world)
+	srv.AddSession(1, 1, "Alice")
+	srv.AddSession(2, 2, "Bob")
+	srv.SetGameMode(&CoopMode{})
+
+	var kicked []protocol.Disconnect
+	srv.SetDisconnectCallback(func(sessionID int, msg protocol.Disconnect) {
+		if sessionID != 2 {
+			t.Errorf("Removed session %d, want 2", sessionID)
+		}
+		kicked = append(kicked, msg)
+	})
+	afk := func(playerID int) bool {
+		for _, p := range srv.Roster() {
+			if p.PlayerID == playerID {
+				return p.AFK
+			}
+		}
+		return false
+	}
+	// Alice moves every tick, Bob never does
+	step := func(n uint64) {
+		for range n {
+			srv.QueueInput(1, protocol.InputFrame{Tick: srv.Tick() + 1, Intents: protocol.IntentRight})
+			srv.Step()
+		}
+	}
+
+	step(cfg.AFK.After - 1)
+	if afk(2) {
+		t.Fatal("Bob was flagged before his time")
+	}
+	step(1)
+	if afk(1) || !afk(2) {
+		t.Fatalf("AFK flags: Alice %v, Bob %v; want only Bob", afk(1), afk(2))
+	}
+	if !world.Spectating(2) {
+		t.Error("An AFK player should spectate in co-op")
+	}
+
+	// Moving clears the flag
+	srv.QueueInput(2, protocol.InputFrame{Tick: srv.Tick() + 1, Intents: protocol.IntentJump})
+	step(1)
+	if afk(2) || world.Spectating(2) {
+		t.Error("Moving should clear the AFK flag")
+	}
+
+	// Outside co-op the flag stays but the player can still be hurt
+	srv.SetGameMode(&DeathmatchMode{})
+	step(cfg.AFK.After)
+	if !afk(2) || world.Spectating(2) {
+		t.Errorf("Deathmatch: AFK %v, spectating %v; want flagged, not spectating", afk(2), world.Spectating(2))
+	}
+
+	step(cfg.AFK.Kick)
+	if len(kicked) != 1 || kicked[0].Reason != ReasonAFK || srv.SessionCount() != 1 {
+		t.Fatalf("Bob should be removed after the kick timeout, removed %+v", kicked)
+	}
+}
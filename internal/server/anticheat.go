@@ -0,0 +1,154 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// AntiCheatConfig controls the input plausibility checks. The server is
+// authoritative on state, so a modified client can only lie through its
+// inputs; these catch inputs no honest client sends.
+type AntiCheatConfig struct {
+	MaxInputLead uint64  // Ticks ahead of the server an input may be for
+	MaxFrames    int     // Frames one InputMessage may carry
+	TeleportDist float64 // Player movement in one tick that is flagged
+	KickAfter    int     // Input violations before a session is kicked; 0 only reports
+}
+
+// DefaultAntiCheatConfig reports violations without kicking
+func DefaultAntiCheatConfig() AntiCheatConfig {
+	return AntiCheatConfig{
+		MaxInputLead: 120, // 2 seconds at 60 Hz, far beyond any prediction lead
+		MaxFrames:    32,
+		TeleportDist: 2, // Run and fall speeds are at most 1 tile per tick
+		KickAfter:    0,
+	}
+}
+
+// ViolationKind names a failed check
+type ViolationKind string
+
+// Violation kinds
+const (
+	ViolationIntents    ViolationKind = "intents"     // Unknown intent bits
+	ViolationInputAhead ViolationKind = "input_ahead" // Input for a tick too far ahead; dropped
+	ViolationInputFlood ViolationKind = "input_flood" // Too many frames in one message; truncated
+	ViolationTeleport   ViolationKind = "teleport"    // Player moved further in one tick than physics allows
+)
+
+// Violation is an implausible input or state change
+type Violation struct {
+	SessionID int
+	PlayerID  int
+	Tick      uint64
+	Kind      ViolationKind
+	Detail    string
+	Kicked    bool // The session was removed for it
+}
+
+func (v Violation) String() string {
+	s := fmt.Sprintf("tick %d: session %d (player %d) %s: %s", v.Tick, v.SessionID, v.PlayerID, v.Kind, v.Detail)
+	if v.Kicked {
+		s += " [kicked]"
+	}
+	return s
+}
+
+// validIntents are the intent bits the protocol defines
+const validIntents = protocol.IntentLeft | protocol.IntentRight | protocol.IntentJump |
+	protocol.IntentAttack | protocol.IntentUse | protocol.IntentDown | protocol.IntentGlide |
+	protocol.IntentSlide | protocol.IntentMenu | protocol.IntentPound
+
+// SetViolationCallback sets the handler for failed checks, e.g. to log them
+// or close a kicked session's connection
+func (s *Server) SetViolationCallback(cb func(v Violation)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onViolation = cb
+}
+
+// checkFrame sanitizes a frame in place and reports what was wrong with
+// it, if anything. Frames for ticks too far ahead must be dropped.
+func (s *Server) checkFrame(frame *protocol.InputFrame, tick uint64) (ViolationKind, string) {
+	if frame.Axis != nil {
+		frame.Axis.X = max(frame.Axis.X, -127)
+		frame.Axis.Y = max(frame.Axis.Y, -127)
+	}
+	if frame.Tick > tick+s.config.AntiCheat.MaxInputLead {
+		return ViolationInputAhead, fmt.Sprintf("input for tick %d", frame.Tick)
+	}
+	if bad := frame.Intents &^ validIntents; bad != 0 {
+		frame.Intents &= validIntents
+		return ViolationIntents, fmt.Sprintf("unknown intent bits %#x", uint16(bad))
+	}
+	return "", ""
+}
+
+// checkMovement flags players that moved further in the last tick than
+// physics allows. Called with s.mu held, after the world updated.
+func (s *Server) checkMovement() []Violation {
+	var flagged []Violation
+	for _, session := range s.sessions {
+		x, y, ok := s.world.PlayerPosition(session.PlayerID)
+		last, seen := s.lastPos[session.PlayerID]
+		if !ok {
+			delete(s.lastPos, session.PlayerID)
+			continue
+		}
+		s.lastPos[session.PlayerID] = [2]float64{x, y}
+		if dist := math.Hypot(x-last[0], y-last[1]); seen && dist > s.config.AntiCheat.TeleportDist {
+			flagged = append(flagged, Violation{
+				SessionID: session.ID,
+				PlayerID:  session.PlayerID,
+				Tick:      s.tick,
+				Kind:      ViolationTeleport,
+				Detail:    fmt.Sprintf("moved %.1f tiles from (%.1f, %.1f) to (%.1f, %.1f)", dist, last[0], last[1], x, y),
+			})
+		}
+	}
+	return flagged
+}
+
+// report counts a violation, kicks the session if it has too many input
+// violations, and passes it to the callback. Teleports are only flagged:
+// they show a state bug worth looking at, not which client caused it.
+func (s *Server) report(v Violation) {
+	s.mu.Lock()
+	s.violations[v.Kind]++
+	session, ok := s.sessions[v.SessionID]
+	if ok && v.Kind != ViolationTeleport {
+		session.strikes++
+		if kickAfter := s.config.AntiCheat.KickAfter; kickAfter > 0 && session.strikes >= kickAfter {
+			delete(s.sessions, v.SessionID)
+			s.rosterVersion++
+			s.electHost()
+			v.Kicked = true
+		}
+	}
+	cb := s.onViolation
+	s.mu.Unlock()
+
+	if cb != nil {
+		cb(v)
+	}
+}
+
+// ViolationCounts returns how many violations of each kind were seen, by
+// kind
+func (s *Server) ViolationCounts() ([]ViolationKind, []uint64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	kinds := make([]ViolationKind, 0, len(s.violations))
+	for kind := range s.violations {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+	counts := make([]uint64, len(kinds))
+	for i, kind := range kinds {
+		counts[i] = s.violations[kind]
+	}
+	return kinds, counts
+}
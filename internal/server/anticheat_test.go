@@ -0,0 +1,41 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// TestAntiCheat tests that implausible inputs are sanitized or dropped,
+// counted as strikes, and kick the session once KickAfter is reached.
+func TestAntiCheat(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AntiCheat.KickAfter = 2
+	srv := New(cfg)
+	world := game.NewWorld()
+	world.LoadLevel(game.NewDemoLevel(40, 20))
+	srv.SetWorld(world)
+	srv.AddSession(1, 1, "Cheater")
+	world.SpawnPlayer(1, "Cheater", 5, 10)
+
+	var got []Violation
+	srv.SetViolationCallback(func(v Violation) { got = append(got, v) })
+
+	srv.QueueInput(1, protocol.InputFrame{Tick: 1, Intents: protocol.IntentRight | 1<<15})
+	if len(got) != 1 || got[0].Kind != ViolationIntents || got[0].Kicked {
+		t.Fatalf("Expected an intents violation, got %+v", got)
+	}
+	srv.Step()
+	if x, _, _ := world.PlayerPosition(1); x <= 5 {
+		t.Error("Sanitized input should still apply its valid intents")
+	}
+
+	srv.QueueInput(1, protocol.InputFrame{Tick: 10000, Intents: protocol.IntentLeft})
+	if len(got) != 2 || got[1].Kind != ViolationInputAhead || !got[1].Kicked {
+		t.Fatalf("Expected the second violation to kick, got %+v", got)
+	}
+	if len(srv.Roster()) != 0 {
+		t.Error("Kicked session should be removed")
+	}
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// SetChatCallback sets the function that sends a chat line to a session
+func (s *Server) SetChatCallback(cb func(sessionID int, msg protocol.Chat)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onChat = cb
+}
+
+// Chat handles a line a session's player typed. Commands (a leading /) are
+// run and answered to the sender alone; anything else is relayed to every
+// session under the sender's player ID. Control characters are dropped and
+// the line is cut to protocol.MaxChatLength.
+func (s *Server) Chat(sessionID int, text string) {
+	text = cleanChat(text)
+	s.mu.RLock()
+	session, ok := s.sessions[sessionID]
+	s.mu.RUnlock()
+	if !ok || text == "" {
+		return
+	}
+	if strings.HasPrefix(text, "/") {
+		if reply := s.chatCommand(session, text); reply != "" {
+			s.sendChat(sessionID, protocol.Chat{Text: reply})
+		}
+		return
+	}
+	s.broadcastChat(protocol.Chat{PlayerID: session.PlayerID, Text: text})
+}
+
+// chatCommand runs a chat command and returns the reply to the sender
+func (s *Server) chatCommand(session *Session, text string) string {
+	args := strings.Fields(text)
+	name, args := strings.ToLower(args[0]), args[1:]
+	var err error
+	switch name {
+	case "/votekick":
+		if len(args) == 0 {
+			return "usage: /votekick <player>"
+		}
+		err = s.CallVote(session.ID, protocol.VoteKick, strings.Join(args, " "))
+	case "/votemap":
+		if len(args) == 0 {
+			return "maps: " + strings.Join(s.Maps(), ", ")
+		}
+		err = s.CallVote(session.ID, protocol.VoteMap, args[0])
+	case "/voterestart":
+		err = s.CallVote(session.ID, protocol.VoteRestart, "")
+	case "/yes", "/no":
+		err = s.CastVote(session.ID, name == "/yes")
+	case "/vote":
+		return s.voteSummary()
+	default:
+		return "unknown command " + name + "; try /votekick, /votemap, /voterestart, /yes, /no or /vote"
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// sendChat sends a chat line to one session
+func (s *Server) sendChat(sessionID int, msg protocol.Chat) {
+	s.mu.RLock()
+	send := s.onChat
+	s.mu.RUnlock()
+	if send != nil {
+		send(sessionID, msg)
+	}
+}
+
+// broadcastChat sends a chat line to every session
+func (s *Server) broadcastChat(msg protocol.Chat) {
+	s.mu.RLock()
+	send := s.onChat
+	ids := make([]int, 0, len(s.sessions))
+	for id := range s.sessions {
+		ids = append(ids, id)
+	}
+	s.mu.RUnlock()
+	if send == nil {
+		return
+	}
+	for _, id := range ids {
+		send(id, msg)
+	}
+}
+
+// cleanChat trims a chat line, drops control characters and cuts it to
+// protocol.MaxChatLength bytes without splitting a character
+func cleanChat(text string) string {
+	text = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, text))
+	if len(text) <= protocol.MaxChatLength {
+		return text
+	}
+	cut := protocol.MaxChatLength
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut]
+}
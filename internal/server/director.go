@@ -0,0 +1,124 @@
+package server
+
+import (
+	"math"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// WaveConfig tunes the enemy waves of a Director
+type WaveConfig struct {
+	FirstWave     int     // Enemies in wave 1
+	PerWave       int     // Extra enemies in each later wave
+	PerPlayer     float64 // Extra fraction of a wave per player beyond the first
+	MaxAlive      int     // Wave enemies alive at once; the rest wait
+	SpawnInterval uint64  // Ticks between spawns within a wave
+	WaveDelay     uint64  // Ticks of calm before each wave
+	ToughEvery    int     // Enemies take one more hit every this many waves; 0 = never
+}
+
+// DefaultWaveConfig returns the horde mode defaults
+func DefaultWaveConfig() WaveConfig {
+	return WaveConfig{
+		FirstWave:     4,
+		PerWave:       2,
+		PerPlayer:     0.5,
+		MaxAlive:      12,
+		SpawnInterval: 45,
+		WaveDelay:     5 * 60,
+		ToughEvery:    3,
+	}
+}
+
+// WaveState is where a Director is in its waves
+type WaveState struct {
+	Wave    int                 // Current wave; 0 before the first
+	Pending int                 // Enemies of the wave still to spawn
+	NextAt  uint64              // Tick of the next spawn, or of the next wave while Cleared
+	Cleared bool                // Wave over, waiting WaveDelay for the next
+	Alive   []protocol.EntityID // Wave enemies spawned and not yet killed
+	Spawned int                 // Enemies spawned so far, to rotate spawners and types
+}
+
+// Director spawns enemies in waves from the level's spawners. A wave starts
+// WaveDelay ticks after the previous one was cleared and grows with the
+// wave number and the player count; later waves are tougher. Enemies are
+// ordinary world entities, so clients get them in snapshots like any other.
+type Director struct {
+	Config WaveConfig
+	WaveState
+}
+
+// tick advances the waves by one tick of w
+func (d *Director) tick(w *game.World) {
+	alive := d.Alive[:0]
+	for _, id := range d.Alive {
+		if _, _, ok := w.EntityPosition(id); ok {
+			alive = append(alive, id)
+		}
+	}
+	d.Alive = alive
+
+	if d.Pending == 0 && len(d.Alive) == 0 {
+		if !d.Cleared {
+			d.Cleared = true
+			d.NextAt = w.Tick + d.Config.WaveDelay
+		}
+		if w.Tick < d.NextAt {
+			return
+		}
+		d.Wave++
+		d.Pending = d.waveSize(len(w.Players()))
+		d.Cleared = false
+		d.NextAt = w.Tick
+	}
+
+	if d.Pending > 0 && w.Tick >= d.NextAt && len(d.Alive) < d.Config.MaxAlive {
+		if id, ok := d.spawn(w); ok {
+			d.Alive = append(d.Alive, id)
+		}
+		d.Pending--
+		d.NextAt = w.Tick + d.Config.SpawnInterval
+	}
+}
+
+// waveSize returns how many enemies the current wave has for the players
+// alive when it starts
+func (d *Director) waveSize(players int) int {
+	n := float64(d.Config.FirstWave + d.Config.PerWave*(d.Wave-1))
+	n *= 1 + d.Config.PerPlayer*float64(max(players-1, 0))
+	return int(math.Round(n))
+}
+
+// spawn places the next enemy at the next spawner. Levels without spawners
+// use their enemy placements.
+func (d *Director) spawn(w *game.World) (protocol.EntityID, bool) {
+	level := w.Level()
+	if level == nil {
+		return 0, false
+	}
+	spawners := level.Spawners
+	if len(spawners) == 0 {
+		for _, e := range level.Enemies {
+			spawners = append(spawners, game.EnemySpawner{X: e.X, Y: e.Y, Types: []string{e.Type}})
+		}
+	}
+	if len(spawners) == 0 {
+		return 0, false
+	}
+
+	sp := spawners[d.Spawned%len(spawners)]
+	enemyType := "slime"
+	if len(sp.Types) > 0 {
+		enemyType = sp.Types[d.Spawned/len(spawners)%len(sp.Types)]
+	}
+	d.Spawned++
+
+	id := w.NetIDOf(w.SpawnEnemy(enemyType, sp.X, sp.Y))
+	if d.Config.ToughEvery > 0 {
+		hp := game.FistDamage * (1 + (d.Wave-1)/d.Config.ToughEvery)
+		w.SetEntityHealth(id, hp, hp)
+	}
+	return id, true
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// TestDirectorWaves tests that waves start after the delay, grow, respect
+// the alive cap and get tougher, and that the next wave waits for the
+// previous one to be cleared.
+func TestDirectorWaves(t *testing.T) {
+	srv := New(DefaultConfig())
+	world := game.NewWorld()
+	level := game.NewDemoLevel(60, 30)
+	level.Spawners = []game.EnemySpawner{{X: 20, Y: 5, Types: []string{"bat"}}, {X: 40, Y: 5}}
+	world.LoadLevel(level)
+	srv.SetWorld(world)
+	srv.SetGameMode(&HordeMode{Waves: WaveConfig{
+		FirstWave: 2, PerWave: 2, PerPlayer: 0.5, MaxAlive: 3,
+		SpawnInterval: 5, WaveDelay: 30, ToughEvery: 1,
+	}})
+	for id := 1; id <= 2; id++ {
+		spawn := level.PlayerSpawn(id - 1)
+		world.SpawnPlayer(id, "P", spawn.X, spawn.Y)
+	}
+	d := srv.match.director
+
+	steps := func(n int) {
+		for range n {
+			srv.Step()
+		}
+	}
+	steps(29)
+	if d.Wave != 0 {
+		t.Fatalf("wave %d started before the delay", d.Wave)
+	}
+	steps(1 + 5*4)
+	if d.Wave != 1 || d.Pending != 0 || len(d.Alive) != 3 {
+		// 2 enemies × 1.5 for the second player
+		t.Fatalf("wave %d: %d pending, %d alive; want wave 1, 0 pending, 3 alive", d.Wave, d.Pending, len(d.Alive))
+	}
+	if bats := len(world.EntitiesWithSprite("bat")); bats != 2 {
+		t.Errorf("%d bats, want 2 (spawners alternate)", bats)
+	}
+
+	steps(100)
+	if d.Wave != 1 {
+		t.Fatalf("wave %d started while wave 1 is alive", d.Wave)
+	}
+	for _, id := range d.Alive {
+		world.Despawn(id)
+	}
+	steps(1 + 30 + 5*4)
+	if d.Wave != 2 || len(d.Alive) != 3 || d.Pending != 3 {
+		// 4 enemies × 1.5 = 6, capped at 3 alive
+		t.Fatalf("wave %d: %d pending, %d alive; want wave 2, 3 pending, 3 alive", d.Wave, d.Pending, len(d.Alive))
+	}
+	wave2 := make(map[protocol.EntityID]bool)
+	for _, id := range d.Alive {
+		wave2[id] = true
+	}
+	for _, es := range world.Snapshot().Entities {
+		if wave2[es.ID] && es.Health.Max != 2 {
+			t.Errorf("wave 2 enemy %d has %d health, want 2", es.ID, es.Health.Max)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"sort"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// ReasonShutdown is the disconnect and join rejection reason while the
+// server shuts down
+const ReasonShutdown = "server is shutting down"
+
+// SetDisconnectCallback sets the function that sends a Disconnect to a
+// session's client; the network layer closes the connection after it
+func (s *Server) SetDisconnectCallback(cb func(sessionID int, msg protocol.Disconnect)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onDisconnect = cb
+}
+
+// Drain starts a graceful shutdown: the game keeps running for the players
+// already in it, but new joins are refused with ReasonShutdown
+func (s *Server) Drain() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.draining = true
+}
+
+// Draining reports whether Drain was called
+func (s *Server) Draining() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.draining
+}
+
+// Drained reports whether a draining server can stop without cutting a
+// game short: nobody is left, or the match is over
+func (s *Server) Drained() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.sessions) == 0 || (s.match != nil && s.match.Ended)
+}
+
+// DisconnectAll sends every session a Disconnect with the reason, in
+// session order, and removes them
+func (s *Server) DisconnectAll(reason string) {
+	s.mu.Lock()
+	ids := make([]int, 0, len(s.sessions))
+	for id := range s.sessions {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		delete(s.sessions, id)
+	}
+	if len(ids) > 0 {
+		s.rosterVersion++
+		s.electHost()
+	}
+	notify := s.onDisconnect
+	s.mu.Unlock()
+
+	if notify != nil {
+		for _, id := range ids {
+			notify(id, protocol.Disconnect{Reason: reason})
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// Disconnect reasons for kicked players
+const (
+	ReasonKicked         = "kicked by the host"
+	ReasonOperatorKicked = "kicked by the server operator"
+)
+
+// HostCommand applies a command from a session's client, which must be the
+// host's. Every field comes from the network, so everything is checked:
+// the server must have host controls, the sender must be the host, a kick
+// must name another connected player and a setting must be valid. The
+// result should be sent back to the client either way.
+func (s *Server) HostCommand(sessionID int, cmd protocol.HostCommand) protocol.HostResult {
+	result := protocol.HostResult{Action: cmd.Action}
+	if err := s.checkHost(sessionID); err != nil {
+		result.Reason = err.Error()
+		return result
+	}
+
+	var err error
+	switch cmd.Action {
+	case protocol.HostKick:
+		err = s.kickPlayer(sessionID, cmd.Target)
+	case protocol.HostFriendlyFire:
+		if cmd.Value > 1 {
+			err = fmt.Errorf("friendly fire: invalid value %d", cmd.Value)
+			break
+		}
+		s.SetFriendlyFire(cmd.Value == 1)
+	case protocol.HostRestart:
+		if w := s.World(); w == nil || w.Level() == nil {
+			err = fmt.Errorf("restart: no level loaded")
+			break
+		}
+		s.ResetLevel(nil)
+	case protocol.HostDifficulty:
+		d := game.Difficulty(cmd.Value)
+		if !d.Valid() {
+			err = fmt.Errorf("difficulty: invalid value %d", cmd.Value)
+			break
+		}
+		s.SetDifficulty(d)
+	default:
+		err = fmt.Errorf("unknown host command %d", cmd.Action)
+	}
+	if err != nil {
+		result.Reason = err.Error()
+		return result
+	}
+	result.OK = true
+	return result
+}
+
+// checkHost returns why a session may not give host commands, if it may not
+func (s *Server) checkHost(sessionID int) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	switch {
+	case !s.config.HostControls:
+		return fmt.Errorf("this server has no host; ask its operator")
+	case s.host != sessionID || s.host == 0:
+		return fmt.Errorf("only the host can do that")
+	}
+	return nil
+}
+
+// kickPlayer removes the player's session on the host's behalf and tells
+// their client why
+func (s *Server) kickPlayer(hostSession, playerID int) error {
+	target, ok := s.sessionOf(playerID)
+	switch {
+	case !ok:
+		return fmt.Errorf("kick: no player %d", playerID)
+	case target == hostSession:
+		return fmt.Errorf("kick: the host can't kick themselves")
+	}
+	s.Kick(target, ReasonKicked)
+	return nil
+}
+
+// sessionOf returns the ID of the player's session
+func (s *Server) sessionOf(playerID int) (int, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for id, session := range s.sessions {
+		if session.PlayerID == playerID {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// Kick sends a session a Disconnect with the reason and removes it
+func (s *Server) Kick(sessionID int, reason string) {
+	s.mu.RLock()
+	_, ok := s.sessions[sessionID]
+	notify := s.onDisconnect
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+	s.RemoveSession(sessionID)
+	if notify != nil {
+		notify(sessionID, protocol.Disconnect{Reason: reason})
+	}
+}
+
+// SetFriendlyFire turns friendly fire on or off. It overrides the game
+// mode's rule, also for later matches, until the server restarts.
+func (s *Server) SetFriendlyFire(on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.friendlyFire = &on
+	s.applySettings()
+}
+
+// SetDifficulty sets the world's difficulty. Players get its health when
+// they next spawn and enemies as they spawn, so it takes full effect from
+// the next restart.
+func (s *Server) SetDifficulty(d game.Difficulty) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.world == nil {
+		s.world = game.NewWorld()
+	}
+	s.world.Difficulty = d
+	s.settingsVersion++
+}
+
+// Settings returns the host and the settings the host controls
+func (s *Server) Settings() protocol.HostSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings()
+}
+
+func (s *Server) settings() protocol.HostSettings {
+	var settings protocol.HostSettings
+	if host, ok := s.sessions[s.host]; ok {
+		settings.Host = host.PlayerID
+	}
+	if s.world != nil {
+		settings.FriendlyFire = s.world.FriendlyFire
+		settings.Difficulty = uint8(s.world.Difficulty)
+	}
+	return settings
+}
+
+// applySettings reapplies the host's friendly fire choice over the game
+// mode's, after a match starts. Called with s.mu held.
+func (s *Server) applySettings() {
+	if s.friendlyFire != nil && s.world != nil {
+		s.world.FriendlyFire = *s.friendlyFire
+	}
+	s.settingsVersion++
+}
+
+// electHost makes the longest connected session (the lowest ID) the host
+// if there is none, as when the host leaves. Called with s.mu held.
+func (s *Server) electHost() {
+	if !s.config.HostControls {
+		return
+	}
+	if _, ok := s.sessions[s.host]; ok {
+		return
+	}
+	ids := make([]int, 0, len(s.sessions))
+	for id := range s.sessions {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	s.host = 0
+	if len(ids) > 0 {
+		s.host = ids[0]
+	}
+	s.settingsVersion++
+}
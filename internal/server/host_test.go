@@ -0,0 +1,104 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// TestHostCommand tests that only the host's commands are applied, that
+// invalid ones are refused, and that the host moves on when the host leaves.
+func TestHostCommand(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.HostControls = true
+	srv := New(cfg)
+	world := game.NewWorld()
+	world.LoadLevel(game.NewDemoLevel(40, 20))
+	srv.SetWorld(world)
+	srv.AddSession(1, 1, "Host")
+	srv.AddSession(2, 2, "Guest")
+	srv.AddSession(3, 3, "Other")
+
+	var kicked []int
+	srv.SetDisconnectCallback(func(sessionID int, msg protocol.Disconnect) {
+		if msg.Reason != ReasonKicked {
+			t.Errorf("Kick reason = %q, want %q", msg.Reason, ReasonKicked)
+		}
+		kicked = append(kicked, sessionID)
+	})
+
+	if host := srv.Settings().Host; host != 1 {
+		t.Fatalf("Host = %d, want the first player", host)
+	}
+
+	refused := []struct {
+		name    string
+		session int
+		cmd     protocol.HostCommand
+	}{
+		{"not the host", 2, protocol.HostCommand{Action: protocol.HostRestart}},
+		{"kick self", 1, protocol.HostCommand{Action: protocol.HostKick, Target: 1}},
+		{"kick unknown", 1, protocol.HostCommand{Action: protocol.HostKick, Target: 9}},
+		{"friendly fire value", 1, protocol.HostCommand{Action: protocol.HostFriendlyFire, Value: 2}},
+		{"difficulty value", 1, protocol.HostCommand{Action: protocol.HostDifficulty, Value: 9}},
+		{"unknown action", 1, protocol.HostCommand{Action: 99}},
+	}
+	for _, tc := range refused {
+		if result := srv.HostCommand(tc.session, tc.cmd); result.OK || result.Reason == "" {
+			t.Errorf("%s: got %+v, want a refusal with a reason", tc.name, result)
+		}
+	}
+	if len(kicked) != 0 || srv.SessionCount() != 3 {
+		t.Fatal("Refused commands should change nothing")
+	}
+
+	difficulty := protocol.HostCommand{Action: protocol.HostDifficulty, Value: uint8(game.DifficultyHard)}
+	if result := srv.HostCommand(1, difficulty); !result.OK {
+		t.Fatalf("Difficulty refused: %s", result.Reason)
+	}
+	friendlyFire := protocol.HostCommand{Action: protocol.HostFriendlyFire, Value: 1}
+	if result := srv.HostCommand(1, friendlyFire); !result.OK {
+		t.Fatalf("Friendly fire refused: %s", result.Reason)
+	}
+	if result := srv.HostCommand(1, protocol.HostCommand{Action: protocol.HostRestart}); !result.OK {
+		t.Fatalf("Restart refused: %s", result.Reason)
+	}
+	settings := srv.Settings()
+	if settings.Difficulty != uint8(game.DifficultyHard) || !settings.FriendlyFire {
+		t.Errorf("Settings = %+v, want hard with friendly fire", settings)
+	}
+	if !world.FriendlyFire {
+		t.Error("Friendly fire should survive the restart")
+	}
+
+	var snaps []protocol.StateSnapshot
+	srv.SetSnapshotCallback(func(sessionID int, snap protocol.StateSnapshot) {
+		if sessionID == 2 {
+			snaps = append(snaps, snap)
+		}
+	})
+	srv.Step()
+	if len(snaps) != 1 || snaps[0].Settings == nil || *snaps[0].Settings != settings {
+		t.Fatalf("Snapshot should carry the settings %+v, got %+v", settings, snaps)
+	}
+
+	if result := srv.HostCommand(1, protocol.HostCommand{Action: protocol.HostKick, Target: 2}); !result.OK {
+		t.Fatalf("Kick refused: %s", result.Reason)
+	}
+	if len(kicked) != 1 || kicked[0] != 2 || srv.SessionCount() != 2 {
+		t.Fatalf("Kick should remove session 2, kicked %v", kicked)
+	}
+
+	srv.RemoveSession(1)
+	if host := srv.Settings().Host; host != 3 {
+		t.Errorf("Host = %d after the host left, want 3", host)
+	}
+
+	srv = New(DefaultConfig())
+	srv.SetWorld(game.NewWorld())
+	srv.AddSession(1, 1, "Player")
+	if result := srv.HostCommand(1, difficulty); result.OK {
+		t.Error("A server without host controls should refuse host commands")
+	}
+}
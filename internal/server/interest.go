@@ -0,0 +1,108 @@
+package server
+
+import (
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+	statesync "github.com/andersfylling/rayman-slides/internal/sync"
+)
+
+// InterestArea is the region around a session's player that it receives
+// entity updates for, in world units. A zero Width or Height disables
+// filtering so the session sees every entity.
+type InterestArea struct {
+	Width, Height float64 // Camera region centered on the player
+	Margin        float64 // Extra border so entities appear before they are on screen
+}
+
+// DefaultInterestArea covers a large terminal or window with room to spare
+func DefaultInterestArea() InterestArea {
+	return InterestArea{Width: 80, Height: 40, Margin: 16}
+}
+
+// Contains reports whether (x, y) is inside the area centered on (cx, cy)
+func (a InterestArea) Contains(cx, cy, x, y float64) bool {
+	if a.Width <= 0 || a.Height <= 0 {
+		return true
+	}
+	halfW := a.Width/2 + a.Margin
+	halfH := a.Height/2 + a.Margin
+	return x >= cx-halfW && x <= cx+halfW && y >= cy-halfH && y <= cy+halfH
+}
+
+// sentHistory is how many sent snapshots a session keeps to diff against,
+// about three seconds at the default sync rate
+const sentHistory = 64
+
+// sentSnapshot is what a session was sent at a tick
+type sentSnapshot struct {
+	baseline *statesync.Baseline // Entities in the snapshot
+	sprites  int                 // Sprite table entries known after it
+}
+
+// ackedBaseline returns the sent snapshot the client last acknowledged, if
+// it is still kept
+func (s *Session) ackedBaseline() (sentSnapshot, bool) {
+	s.mu.Lock()
+	acked := s.LastAckTick
+	s.mu.Unlock()
+	for i := len(s.sent) - 1; i >= 0; i-- {
+		if s.sent[i].baseline.Tick() == acked {
+			return s.sent[i], true
+		}
+	}
+	return sentSnapshot{}, false
+}
+
+// snapshotFor builds a session's snapshot from the world state. Entities
+// outside the session's interest are omitted; entities that left interest
+// since the baseline are listed in Removed, same as despawned ones.
+//
+// Snapshots may be lost, so the delta is against the last snapshot the
+// client acknowledged rather than the last one sent. Without a kept acked
+// snapshot, or with full set, everything visible is sent as a full snapshot.
+// Must be called with s.mu held.
+func (s *Server) snapshotFor(session *Session, state *game.WorldState, full bool) protocol.StateSnapshot {
+	if full {
+		session.sent = session.sent[:0] // The client starts over from this one
+	}
+	base, ok := session.ackedBaseline()
+	if !ok {
+		base = sentSnapshot{baseline: statesync.NewBaseline()}
+	}
+
+	// Center on the session's player; without one, fall back to everything
+	cx, cy, hasCenter := 0.0, 0.0, false
+	for i := range state.Entities {
+		es := &state.Entities[i]
+		if es.HasPlayer && es.Player.ID == session.PlayerID {
+			cx, cy, hasCenter = es.Position.X, es.Position.Y, true
+			break
+		}
+	}
+
+	visible := make([]protocol.EntityState, 0, len(state.Entities))
+	for i := range state.Entities {
+		es := &state.Entities[i]
+		if hasCenter && !session.Interest.Contains(cx, cy, es.Position.X, es.Position.Y) {
+			continue
+		}
+		c := es.Components(s.sprites)
+		visible = append(visible, protocol.EntityState{
+			ID:         es.ID,
+			Components: protocol.AppendComponents(nil, &c),
+		})
+	}
+
+	snap := statesync.Diff(base.baseline, visible)
+	snap.Tick = state.Tick
+	snap.Full = !ok
+	snap.Sprites = s.sprites.Since(base.sprites)
+
+	sent := statesync.NewBaseline()
+	sent.Update(&protocol.StateSnapshot{Tick: state.Tick, Entities: visible})
+	if len(session.sent) == sentHistory {
+		session.sent = append(session.sent[:0], session.sent[1:]...)
+	}
+	session.sent = append(session.sent, sentSnapshot{baseline: sent, sprites: s.sprites.Len()})
+	return snap
+}
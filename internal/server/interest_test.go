@@ -0,0 +1,99 @@
+package server
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+func TestInterestAreaContains(t *testing.T) {
+	area := InterestArea{Width: 80, Height: 40, Margin: 10}
+	tests := []struct {
+		name string
+		area InterestArea
+		x, y float64
+		want bool
+	}{
+		{"center", area, 100, 50, true},
+		{"in the margin", area, 100 + 49, 50 - 29, true},
+		{"past the margin", area, 100 + 51, 50, false},
+		{"above", area, 100, 50 - 31, false},
+		{"disabled", InterestArea{}, 1e6, -1e6, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.area.Contains(100, 50, tc.x, tc.y); got != tc.want {
+				t.Errorf("Contains(%v, %v) = %v, want %v", tc.x, tc.y, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestSnapshotInterest tests that entities leaving a session's interest are
+// removed, and that a lost snapshot doesn't break the next delta: it is
+// taken against the snapshot the client last acknowledged.
+func TestSnapshotInterest(t *testing.T) {
+	srv := New(DefaultConfig())
+	world := game.NewWorld()
+	world.LoadLevel(game.NewDemoLevel(300, 40))
+	srv.SetWorld(world)
+	session := srv.AddSession(1, 1, "P")
+	world.SpawnPlayer(1, "P", 10, 10)
+	near := world.NetIDOf(world.SpawnEnemy(game.EnemyDummy, 20, 10))
+	far := world.NetIDOf(world.SpawnEnemy(game.EnemyDummy, 150, 10))
+	for range 60 {
+		world.Update() // Let everyone land
+	}
+
+	snapshot := func() protocol.StateSnapshot {
+		world.Update()
+		state := world.Snapshot()
+		return srv.snapshotFor(session, &state, false)
+	}
+	ids := func(es []protocol.EntityState) []protocol.EntityID {
+		var out []protocol.EntityID
+		for _, e := range es {
+			out = append(out, e.ID)
+		}
+		return out
+	}
+
+	first := snapshot()
+	if !first.Full || !slices.Contains(ids(first.Entities), near) || slices.Contains(ids(first.Entities), far) {
+		t.Fatalf("First snapshot: full %v with %v, want a full one with the near dummy only", first.Full, ids(first.Entities))
+	}
+	if again := snapshot(); !again.Full {
+		t.Error("Snapshot before any ack should be full")
+	}
+	session.AckSnapshot(first.Tick)
+
+	// This one is lost: the client never acks it
+	world.MovePlayer(1, 140, 10)
+	lost := snapshot()
+	if lost.Full || lost.Baseline != first.Tick || !slices.Contains(lost.Removed, near) || !slices.Contains(ids(lost.Entities), far) {
+		t.Fatalf("Moving away: baseline %d, entities %v, removed %v; want a delta from tick %d that swaps the dummies",
+			lost.Baseline, ids(lost.Entities), lost.Removed, first.Tick)
+	}
+
+	world.MovePlayer(1, 10, 10)
+	back := snapshot()
+	if back.Full || back.Baseline != first.Tick {
+		t.Fatalf("Snapshot after the lost one: full %v from tick %d, want a delta from tick %d", back.Full, back.Baseline, first.Tick)
+	}
+	if len(back.Removed) != 0 || slices.Contains(ids(back.Entities), near) || slices.Contains(ids(back.Entities), far) {
+		t.Errorf("Snapshot after the lost one has entities %v and removed %v, want neither dummy", ids(back.Entities), back.Removed)
+	}
+
+	session.AckSnapshot(back.Tick)
+	if next := snapshot(); next.Baseline != back.Tick {
+		t.Errorf("Next snapshot is from tick %d, want the acked %d", next.Baseline, back.Tick)
+	}
+	for range sentHistory {
+		snapshot()
+	}
+	if stale := snapshot(); !stale.Full {
+		t.Error("Snapshot with an ack older than the history should be full")
+	}
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/network"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// LevelHash returns the hash of the level the server runs, see
+// game.LevelHash
+func (s *Server) LevelHash() ([32]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, hash, err := s.encodedLevel()
+	return hash, err
+}
+
+// encodedLevel returns the current level encoded with game.EncodeLevel, and
+// its hash. The caller must hold mu.
+func (s *Server) encodedLevel() ([]byte, [32]byte, error) {
+	if s.world == nil || s.world.Level() == nil {
+		return nil, [32]byte{}, fmt.Errorf("no level loaded")
+	}
+	data, err := game.EncodeLevel(s.world.Level())
+	if err != nil {
+		return nil, [32]byte{}, err
+	}
+	return data, sha256.Sum256(data), nil
+}
+
+// checkLevel compares a joining client's level hash with the server's and
+// reports whether the client needs the level sent. Without
+// Config.SendLevel a mismatch is an error, for the handshake reply.
+func (s *Server) checkLevel(hash [32]byte) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.world == nil || s.world.Level() == nil {
+		return false, nil // Nothing to check against
+	}
+	_, own, err := s.encodedLevel()
+	if err != nil {
+		return false, err
+	}
+	if hash == own {
+		return false, nil
+	}
+	if !s.config.SendLevel {
+		name := s.world.Level().Name
+		if hash == ([32]byte{}) {
+			return false, fmt.Errorf("level %q required: install it to join this server", name)
+		}
+		return false, fmt.Errorf("level %q differs from the server's (%x, yours %x): install the server's version to join", name, own[:4], hash[:4])
+	}
+	return true, nil
+}
+
+// checkBuild compares a joining client's build with the server's. Builds
+// that speak the same protocol can still simulate differently, so mixed
+// versions are refused; clients before protocol version 11 send no build.
+func (s *Server) checkBuild(build string) error {
+	own := s.config.Build
+	switch {
+	case own == "" || build == own:
+		return nil
+	case build == "":
+		return fmt.Errorf("server runs version %s and your client is older: update to join", own)
+	default:
+		return fmt.Errorf("server runs version %s, you have %s: install the same version to join", own, build)
+	}
+}
+
+// SetPhysics sets the physics profile the server simulates with. Clients
+// must run the same profile to join.
+func (s *Server) SetPhysics(p game.PhysicsProfile) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.world.Physics = p
+}
+
+// SetAimAssist sets a player's aim assist in the world. Join sets it from
+// the handshake; modes with ModeRules.NoAimAssist override it for everyone.
+func (s *Server) SetAimAssist(playerID int, a game.AimAssist) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.world != nil {
+		s.world.SetAimAssist(playerID, a)
+	}
+}
+
+// checkPhysics compares a joining client's physics profile hash with the
+// server's; a zero hash stands for the default profile
+func (s *Server) checkPhysics(hash [32]byte) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if hash == ([32]byte{}) {
+		hash = game.DefaultPhysics().Hash()
+	}
+	if own := s.world.Physics.Hash(); hash != own {
+		return fmt.Errorf("physics profile differs from the server's (%x, yours %x): use the server's tunables to join", own[:4], hash[:4])
+	}
+	return nil
+}
+
+// JoinBundle returns what a client joining now needs to build the same
+// world before its first snapshot. withLevel includes the encoded level, for
+// clients that don't have a level with the same hash (Session.NeedsLevel)
+// and predate asset transfers (see JoinTransfers).
+func (s *Server) JoinBundle(withLevel bool) (protocol.JoinBundle, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, hash, err := s.encodedLevel()
+	if err != nil {
+		return protocol.JoinBundle{}, fmt.Errorf("join bundle: %w", err)
+	}
+
+	b := protocol.JoinBundle{
+		Level:      s.world.Level().Name,
+		LevelHash:  hash,
+		Tick:       s.world.Tick,
+		LevelStart: s.world.LevelStart(),
+		Elapsed:    s.world.Tick - s.world.LevelStart(),
+		Stats:      s.world.Stats(),
+	}
+	if withLevel {
+		b.LevelData = data
+	}
+	if m := s.match; m != nil {
+		b.Mode = m.Mode.Name()
+		b.Elapsed = m.Elapsed()
+		if m.Ended {
+			result := m.Result
+			b.Result = &result
+		}
+	}
+	return b, nil
+}
+
+// SetAtlas sets a sprite atlas definition to push to joining clients, for
+// custom sprites. nil sends none.
+func (s *Server) SetAtlas(data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.atlas = data
+}
+
+// JoinTransfers returns the asset chunks to push to a client that just
+// joined: the sprite atlas, if set, and the level if the session needs it.
+// Sessions before protocol version 8 can't receive them; they get the level
+// in the join bundle instead.
+func (s *Server) JoinTransfers(session *Session) ([]protocol.TransferChunk, error) {
+	if session.Version < 8 {
+		return nil, nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var chunks []protocol.TransferChunk
+	if s.atlas != nil {
+		chunks = network.SplitTransfer(protocol.TransferAtlas, s.atlas, 0, protocol.TransferChunkSize)
+	}
+	if session.NeedsLevel {
+		data, _, err := s.encodedLevel()
+		if err != nil {
+			return nil, fmt.Errorf("join transfers: %w", err)
+		}
+		chunks = append(chunks, network.SplitTransfer(protocol.TransferLevel, data, 0, protocol.TransferChunkSize)...)
+	}
+	return chunks, nil
+}
+
+// Transfer answers a client's TransferRequest with the rest of the asset,
+// from the requested offset
+func (s *Server) Transfer(req protocol.TransferRequest) ([]protocol.TransferChunk, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.atlas != nil && sha256.Sum256(s.atlas) == req.Hash {
+		return network.SplitTransfer(protocol.TransferAtlas, s.atlas, int(req.Offset), protocol.TransferChunkSize), nil
+	}
+	data, hash, err := s.encodedLevel()
+	if err == nil && hash == req.Hash {
+		return network.SplitTransfer(protocol.TransferLevel, data, int(req.Offset), protocol.TransferChunkSize), nil
+	}
+	return nil, fmt.Errorf("transfer: no asset %x", req.Hash[:4])
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// TestJoinLevelMismatch tests that clients with a different level get it in
+// the join bundle, or are rejected when the server doesn't send levels.
+func TestJoinLevelMismatch(t *testing.T) {
+	level := game.NewDemoLevel(80, 45)
+	hash, err := game.LevelHash(level)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := game.LevelHash(game.NewDemoLevel(60, 30))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		sendLevel  bool
+		hash       [32]byte
+		accepted   bool
+		needsLevel bool
+	}{
+		{"same level", false, hash, true, false},
+		{"no level, sent", true, [32]byte{}, true, true},
+		{"different level, sent", true, other, true, true},
+		{"no level, rejected", false, [32]byte{}, false, false},
+		{"different level, rejected", false, other, false, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.SendLevel = tc.sendLevel
+			srv := New(cfg)
+			w := game.NewWorld()
+			w.LoadLevel(level)
+			srv.SetWorld(w)
+
+			h := protocol.NewHandshake("Alice")
+			h.LevelHash = tc.hash
+			session, reply := srv.Join(1, 1, h)
+			if reply.Accepted != tc.accepted {
+				t.Fatalf("accepted = %v (%q), want %v", reply.Accepted, reply.Reason, tc.accepted)
+			}
+			if !tc.accepted {
+				if !strings.Contains(reply.Reason, level.Name) {
+					t.Errorf("reason %q should name the level", reply.Reason)
+				}
+				return
+			}
+			if session.NeedsLevel != tc.needsLevel {
+				t.Errorf("NeedsLevel = %v, want %v", session.NeedsLevel, tc.needsLevel)
+			}
+
+			b, err := srv.JoinBundle(session.NeedsLevel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b.LevelHash != hash || (len(b.LevelData) > 0) != tc.needsLevel {
+				t.Errorf("bundle has hash %x and %d bytes of level", b.LevelHash[:4], len(b.LevelData))
+			}
+		})
+	}
+}
+
+// TestJoinPhysicsMismatch tests that clients with a different physics
+// profile are rejected, and that a zero hash stands for the default one.
+func TestJoinPhysicsMismatch(t *testing.T) {
+	level := game.NewDemoLevel(80, 45)
+	hash, err := game.LevelHash(level)
+	if err != nil {
+		t.Fatal(err)
+	}
+	floaty := game.DefaultPhysics()
+	floaty.Gravity = 0.04
+
+	tests := []struct {
+		name     string
+		server   game.PhysicsProfile
+		physics  [32]byte
+		accepted bool
+	}{
+		{"default, unset", game.DefaultPhysics(), [32]byte{}, true},
+		{"default", game.DefaultPhysics(), game.DefaultPhysics().Hash(), true},
+		{"custom", floaty, floaty.Hash(), true},
+		{"client differs", game.DefaultPhysics(), floaty.Hash(), false},
+		{"server differs", floaty, [32]byte{}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := New(DefaultConfig())
+			w := game.NewWorld()
+			w.LoadLevel(level)
+			srv.SetWorld(w)
+			srv.SetPhysics(tc.server)
+
+			h := protocol.NewHandshake("Alice")
+			h.LevelHash = hash
+			h.PhysicsHash = tc.physics
+			_, reply := srv.Join(1, 1, h)
+			if reply.Accepted != tc.accepted {
+				t.Fatalf("accepted = %v (%q), want %v", reply.Accepted, reply.Reason, tc.accepted)
+			}
+			if !tc.accepted && !strings.Contains(reply.Reason, "physics") {
+				t.Errorf("reason %q should name the physics profile", reply.Reason)
+			}
+		})
+	}
+}
+
+// TestJoinBuild tests that a server with a build refuses clients of other
+// builds and clients too old to send one.
+func TestJoinBuild(t *testing.T) {
+	tests := []struct {
+		name     string
+		server   string
+		client   string
+		accepted bool
+	}{
+		{"same build", "1.4.0", "1.4.0", true},
+		{"server without build", "", "1.3.0", true},
+		{"older client", "1.4.0", "1.3.0", false},
+		{"newer client", "1.4.0", "1.5.0", false},
+		{"client before builds", "1.4.0", "", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Build = tc.server
+			srv := New(cfg)
+			srv.SetWorld(game.NewWorld())
+
+			h := protocol.NewHandshake("Alice")
+			h.Build = tc.client
+			_, reply := srv.Join(1, 1, h)
+			if reply.Accepted != tc.accepted {
+				t.Fatalf("accepted = %v (%q), want %v", reply.Accepted, reply.Reason, tc.accepted)
+			}
+			if !tc.accepted && !strings.Contains(reply.Reason, tc.server) {
+				t.Errorf("reason %q should name the server's version", reply.Reason)
+			}
+		})
+	}
+}
+
+// TestJoinAimAssist tests that a player's aim assist comes from the
+// handshake and that competitive modes turn it off.
+func TestJoinAimAssist(t *testing.T) {
+	srv := New(DefaultConfig())
+	w := game.NewWorld()
+	srv.SetWorld(w)
+
+	h := protocol.NewHandshake("Alice")
+	h.AimAssist = uint8(game.AimAssistHigh)
+	if _, reply := srv.Join(1, 1, h); !reply.Accepted {
+		t.Fatalf("join rejected: %s", reply.Reason)
+	}
+	if got := w.AimAssistFor(1); got != game.AimAssistHigh {
+		t.Fatalf("aim assist %v, want high", got)
+	}
+
+	srv.SetGameMode(&DeathmatchMode{})
+	if got := w.AimAssistFor(1); got != game.AimAssistOff {
+		t.Errorf("aim assist %v in deathmatch, want off", got)
+	}
+	srv.SetGameMode(&CoopMode{})
+	if got := w.AimAssistFor(1); got != game.AimAssistHigh {
+		t.Errorf("aim assist %v in coop, want high", got)
+	}
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+)
+
+// SystemTimings returns per-system tick timings and the whole-tick total
+func (s *Server) SystemTimings() ([]game.SystemTiming, game.SystemTiming) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.world == nil {
+		return nil, game.SystemTiming{}
+	}
+	return s.world.Systems().Timings(), s.world.Systems().Total()
+}
+
+// TickBudget is the real time available for one tick
+func (s *Server) TickBudget() time.Duration {
+	return time.Second / time.Duration(s.config.TickRate)
+}
+
+// WriteMetrics writes server metrics in the Prometheus text format
+func (s *Server) WriteMetrics(w io.Writer) {
+	s.mu.RLock()
+	sessions := len(s.sessions)
+	s.mu.RUnlock()
+	timings, total := s.SystemTimings()
+
+	fmt.Fprintln(w, "# HELP rayserver_tick Current simulation tick.")
+	fmt.Fprintln(w, "# TYPE rayserver_tick counter")
+	fmt.Fprintf(w, "rayserver_tick %d\n", s.Tick())
+
+	fmt.Fprintln(w, "# HELP rayserver_sessions Connected sessions.")
+	fmt.Fprintln(w, "# TYPE rayserver_sessions gauge")
+	fmt.Fprintf(w, "rayserver_sessions %d\n", sessions)
+
+	fmt.Fprintln(w, "# HELP rayserver_tick_budget_seconds Real time available per tick.")
+	fmt.Fprintln(w, "# TYPE rayserver_tick_budget_seconds gauge")
+	fmt.Fprintf(w, "rayserver_tick_budget_seconds %g\n", s.TickBudget().Seconds())
+
+	fmt.Fprintln(w, "# HELP rayserver_violations_total Anti-cheat violations by kind.")
+	fmt.Fprintln(w, "# TYPE rayserver_violations_total counter")
+	kinds, counts := s.ViolationCounts()
+	for i, kind := range kinds {
+		fmt.Fprintf(w, "rayserver_violations_total{kind=%q} %d\n", kind, counts[i])
+	}
+
+	net := s.SessionNetStats()
+	ids := make([]int, 0, len(net))
+	for id := range net {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	fmt.Fprintln(w, "# HELP rayserver_session_bytes_total Bytes exchanged with each session.")
+	fmt.Fprintln(w, "# TYPE rayserver_session_bytes_total counter")
+	for _, id := range ids {
+		fmt.Fprintf(w, "rayserver_session_bytes_total{session=\"%d\",direction=\"in\"} %d\n", id, net[id].BytesIn)
+		fmt.Fprintf(w, "rayserver_session_bytes_total{session=\"%d\",direction=\"out\"} %d\n", id, net[id].BytesOut)
+	}
+	fmt.Fprintln(w, "# HELP rayserver_session_packets_total Packets exchanged with each session.")
+	fmt.Fprintln(w, "# TYPE rayserver_session_packets_total counter")
+	for _, id := range ids {
+		fmt.Fprintf(w, "rayserver_session_packets_total{session=\"%d\",direction=\"in\"} %d\n", id, net[id].PacketsIn)
+		fmt.Fprintf(w, "rayserver_session_packets_total{session=\"%d\",direction=\"out\"} %d\n", id, net[id].PacketsOut)
+	}
+	fmt.Fprintln(w, "# HELP rayserver_session_snapshots_total Snapshots sent to each session.")
+	fmt.Fprintln(w, "# TYPE rayserver_session_snapshots_total counter")
+	for _, id := range ids {
+		fmt.Fprintf(w, "rayserver_session_snapshots_total{session=\"%d\",type=\"full\"} %d\n", id, net[id].FullSnapshots)
+		fmt.Fprintf(w, "rayserver_session_snapshots_total{session=\"%d\",type=\"delta\"} %d\n", id, net[id].DeltaSnapshots)
+	}
+	fmt.Fprintln(w, "# HELP rayserver_session_snapshot_bytes Mean encoded snapshot size per session.")
+	fmt.Fprintln(w, "# TYPE rayserver_session_snapshot_bytes gauge")
+	for _, id := range ids {
+		fmt.Fprintf(w, "rayserver_session_snapshot_bytes{session=\"%d\"} %g\n", id, net[id].AvgSnapshotSize())
+	}
+
+	counters := s.allocs.AppendCounters(nil)
+	fmt.Fprintln(w, "# HELP rayserver_allocs_total Heap objects allocated per subsystem while allocation tracking is on.")
+	fmt.Fprintln(w, "# TYPE rayserver_allocs_total counter")
+	for _, c := range counters {
+		fmt.Fprintf(w, "rayserver_allocs_total{subsystem=%q} %d\n", c.Name, c.Allocs)
+	}
+	fmt.Fprintln(w, "# HELP rayserver_alloc_bytes_total Heap bytes allocated per subsystem while allocation tracking is on.")
+	fmt.Fprintln(w, "# TYPE rayserver_alloc_bytes_total counter")
+	for _, c := range counters {
+		fmt.Fprintf(w, "rayserver_alloc_bytes_total{subsystem=%q} %d\n", c.Name, c.Bytes)
+	}
+	fmt.Fprintln(w, "# HELP rayserver_alloc_spans_total Spans of work measured per subsystem, to divide the allocations by.")
+	fmt.Fprintln(w, "# TYPE rayserver_alloc_spans_total counter")
+	for _, c := range counters {
+		fmt.Fprintf(w, "rayserver_alloc_spans_total{subsystem=%q} %d\n", c.Name, c.Spans)
+	}
+
+	fmt.Fprintln(w, "# HELP rayserver_system_seconds Time spent in each system per tick.")
+	fmt.Fprintln(w, "# TYPE rayserver_system_seconds gauge")
+	for _, t := range append(timings, total) {
+		fmt.Fprintf(w, "rayserver_system_seconds{system=%q,stat=\"last\"} %g\n", t.Name, t.Last.Seconds())
+		fmt.Fprintf(w, "rayserver_system_seconds{system=%q,stat=\"avg\"} %g\n", t.Name, t.Avg.Seconds())
+		fmt.Fprintf(w, "rayserver_system_seconds{system=%q,stat=\"max\"} %g\n", t.Name, t.Max.Seconds())
+	}
+}
+
+// ServeMetrics serves /metrics on addr until the listener fails
+func (s *Server) ServeMetrics(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		s.WriteMetrics(w)
+	})
+	return http.ListenAndServe(addr, mux)
+}
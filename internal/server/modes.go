@@ -0,0 +1,397 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// ModeRules are the world settings a game mode imposes
+type ModeRules struct {
+	FriendlyFire bool        // Fists hurt other players
+	RespawnDelay int         // Ticks before a dead player respawns; negative = never
+	Waves        *WaveConfig // Enemy waves, see Director; nil for none
+	NoAimAssist  bool        // Competitive: players' aim assist is ignored
+	IdleSpectate bool        // AFK players spectate: invulnerable, ignored by enemies
+}
+
+// GameMode defines scoring, the win condition and respawn policy of a match.
+// Modes are driven by the server's Match and must be deterministic.
+type GameMode interface {
+	Name() string
+	Rules() ModeRules
+
+	// OnEvent updates scores from a world event
+	OnEvent(m *Match, e game.Event)
+
+	// Result reports whether the match is over, checked after every tick
+	Result(m *Match) (protocol.MatchResult, bool)
+}
+
+// PlayerScore is one player's standing in a match
+type PlayerScore struct {
+	PlayerID int
+	Name     string
+	Score    int
+	Kills    int // Players killed
+	Deaths   int
+	Finished bool   // Reached the exit (race)
+	Time     uint64 // Ticks from match start to finish (race)
+}
+
+// Match tracks a running game mode: scores, timers and pending respawns
+type Match struct {
+	Mode      GameMode
+	StartTick uint64
+	Ended     bool
+	Result    protocol.MatchResult
+
+	world     *game.World
+	scores    map[int]*PlayerScore
+	respawns  map[int]uint64 // Player ID -> tick to respawn at
+	nextSpawn int            // Rotates through the level's spawn points
+	director  *Director      // Enemy waves, if the mode has them
+}
+
+// newMatch starts a match of the mode on the world's current players
+func newMatch(mode GameMode, w *game.World) *Match {
+	m := &Match{
+		Mode:      mode,
+		StartTick: w.Tick,
+		world:     w,
+		scores:    make(map[int]*PlayerScore),
+		respawns:  make(map[int]uint64),
+	}
+	w.FriendlyFire = mode.Rules().FriendlyFire
+	w.NoAimAssist = mode.Rules().NoAimAssist
+	if waves := mode.Rules().Waves; waves != nil {
+		m.director = &Director{Config: *waves}
+	}
+	for _, p := range w.Players() {
+		m.scores[p.ID] = &PlayerScore{PlayerID: p.ID, Name: p.Name}
+	}
+	return m
+}
+
+// Score returns a player's score entry, creating it for late joiners
+func (m *Match) Score(playerID int) *PlayerScore {
+	ps, ok := m.scores[playerID]
+	if !ok {
+		ps = &PlayerScore{PlayerID: playerID}
+		m.scores[playerID] = ps
+	}
+	return ps
+}
+
+// Scores returns all scores, best first
+func (m *Match) Scores() []PlayerScore {
+	scores := make([]PlayerScore, 0, len(m.scores))
+	for _, ps := range m.scores {
+		scores = append(scores, *ps)
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].PlayerID < scores[j].PlayerID
+	})
+	return scores
+}
+
+// Wave returns the current enemy wave, 0 if the mode has none or the first
+// hasn't started
+func (m *Match) Wave() int {
+	if m.director == nil {
+		return 0
+	}
+	return m.director.Wave
+}
+
+// Elapsed returns ticks since the match started
+func (m *Match) Elapsed() uint64 {
+	return m.world.Tick - m.StartTick
+}
+
+// onEvent handles deaths for the respawn policy, then lets the mode score
+func (m *Match) onEvent(e game.Event) {
+	if e.Type == game.EventReset {
+		// A restart begins a fresh match of the same mode
+		*m = *newMatch(m.Mode, m.world)
+		return
+	}
+	if m.Ended {
+		return
+	}
+	if e.Type == game.EventDeath && e.Player != 0 {
+		m.Score(e.Player).Deaths++
+		if delay := m.Mode.Rules().RespawnDelay; delay >= 0 {
+			m.respawns[e.Player] = e.Tick + uint64(delay)
+		}
+	}
+	m.Mode.OnEvent(m, e)
+}
+
+// tick runs respawns and checks the win condition
+func (m *Match) tick() {
+	if m.Ended {
+		return
+	}
+
+	due := make([]int, 0, len(m.respawns))
+	for id, at := range m.respawns {
+		if m.world.Tick >= at {
+			due = append(due, id)
+		}
+	}
+	sort.Ints(due) // Map order is random; spawn order must not be
+	for _, id := range due {
+		delete(m.respawns, id)
+		m.respawn(id)
+	}
+	if m.director != nil {
+		m.director.tick(m.world)
+	}
+
+	if result, done := m.Mode.Result(m); done {
+		result.Mode = m.Mode.Name()
+		m.Ended = true
+		m.Result = result
+	}
+}
+
+func (m *Match) respawn(playerID int) {
+	level := m.world.Level()
+	spawn := game.SpawnPoint{X: 1, Y: 1}
+	if level != nil {
+		spawn = level.PlayerSpawn(m.nextSpawn)
+		m.nextSpawn++
+	}
+	m.world.SpawnPlayer(playerID, m.Score(playerID).Name, spawn.X, spawn.Y)
+}
+
+// NewGameMode returns a mode with default settings by name
+func NewGameMode(name string) (GameMode, error) {
+	switch name {
+	case "coop", "co-op":
+		return &CoopMode{}, nil
+	case "race":
+		return &RaceMode{TimeLimit: 60 * 60 * 5}, nil
+	case "deathmatch", "dm":
+		return &DeathmatchMode{FragLimit: 10, TimeLimit: 60 * 60 * 5}, nil
+	case "horde":
+		return &HordeMode{Waves: DefaultWaveConfig()}, nil
+	default:
+		return nil, fmt.Errorf("unknown game mode %q (coop, race, deathmatch, horde)", name)
+	}
+}
+
+// CoopMode is the default: players fight enemies together, nobody hurts
+// each other, and the match never ends on its own. Each enemy killed is a
+// point for the player who landed the blow.
+type CoopMode struct{}
+
+func (*CoopMode) Name() string { return "coop" }
+
+func (*CoopMode) Rules() ModeRules {
+	return ModeRules{FriendlyFire: false, RespawnDelay: 120, IdleSpectate: true}
+}
+
+func (*CoopMode) OnEvent(m *Match, e game.Event) {
+	if e.Type == game.EventDeath && e.Player == 0 && e.Attacker != 0 {
+		m.Score(e.Attacker).Score++
+	}
+}
+
+func (*CoopMode) Result(*Match) (protocol.MatchResult, bool) {
+	return protocol.MatchResult{}, false
+}
+
+// RaceMode is a race to the level exit with per-player timers. Dead players
+// respawn at once. The match ends when everyone has finished or the time
+// limit runs out; the fastest finisher wins.
+type RaceMode struct {
+	TimeLimit uint64 // Ticks; 0 = none
+}
+
+func (*RaceMode) Name() string { return "race" }
+
+func (*RaceMode) Rules() ModeRules {
+	return ModeRules{FriendlyFire: false, RespawnDelay: 0, NoAimAssist: true}
+}
+
+func (r *RaceMode) OnEvent(m *Match, e game.Event) {
+	if e.Type != game.EventFinish {
+		return
+	}
+	ps := m.Score(e.Player)
+	ps.Finished = true
+	ps.Time = e.Tick - m.StartTick
+
+	// Earlier finishers score more
+	finished := 0
+	for _, s := range m.scores {
+		if s.Finished {
+			finished++
+		}
+	}
+	ps.Score = len(m.scores) - finished + 1
+}
+
+func (r *RaceMode) Result(m *Match) (protocol.MatchResult, bool) {
+	if len(m.scores) == 0 {
+		return protocol.MatchResult{}, false
+	}
+	var first *PlayerScore
+	all := true
+	for _, ps := range m.scores {
+		if !ps.Finished {
+			all = false
+			continue
+		}
+		if first == nil || ps.Time < first.Time || (ps.Time == first.Time && ps.PlayerID < first.PlayerID) {
+			first = ps
+		}
+	}
+
+	timeUp := r.TimeLimit > 0 && m.Elapsed() >= r.TimeLimit
+	if !all && !timeUp {
+		return protocol.MatchResult{}, false
+	}
+	result := protocol.MatchResult{Reason: "all finished"}
+	if timeUp && !all {
+		result.Reason = "time limit"
+	}
+	if first != nil {
+		result.Winners = []int{first.PlayerID}
+	}
+	return result, true
+}
+
+// DeathmatchMode is an arena fight: fists hurt other players, each kill is a
+// point, and the first to FragLimit (or the leader at the time limit) wins.
+type DeathmatchMode struct {
+	FragLimit int    // 0 = none
+	TimeLimit uint64 // Ticks; 0 = none
+}
+
+func (*DeathmatchMode) Name() string { return "deathmatch" }
+
+func (*DeathmatchMode) Rules() ModeRules {
+	return ModeRules{FriendlyFire: true, RespawnDelay: 90, NoAimAssist: true}
+}
+
+func (*DeathmatchMode) OnEvent(m *Match, e game.Event) {
+	if e.Type == game.EventDeath && e.Player != 0 && e.Attacker != 0 && e.Attacker != e.Player {
+		killer := m.Score(e.Attacker)
+		killer.Kills++
+		killer.Score++
+	}
+}
+
+func (d *DeathmatchMode) Result(m *Match) (protocol.MatchResult, bool) {
+	best := 0
+	var leaders []int
+	for _, ps := range m.Scores() {
+		if ps.Score > best {
+			best = ps.Score
+			leaders = []int{ps.PlayerID}
+		} else if ps.Score == best && best > 0 {
+			leaders = append(leaders, ps.PlayerID)
+		}
+	}
+
+	switch {
+	case d.FragLimit > 0 && best >= d.FragLimit:
+		return protocol.MatchResult{Winners: leaders, Reason: "frag limit"}, true
+	case d.TimeLimit > 0 && m.Elapsed() >= d.TimeLimit:
+		return protocol.MatchResult{Winners: leaders, Reason: "time limit"}, true
+	}
+	return protocol.MatchResult{}, false
+}
+
+// HordeMode is survival against endless enemy waves (see Director). Each
+// enemy killed is a point; dead players respawn after a while, and the
+// match ends when every player is down at once. The top scorer wins.
+type HordeMode struct {
+	Waves WaveConfig
+}
+
+func (*HordeMode) Name() string { return "horde" }
+
+func (h *HordeMode) Rules() ModeRules {
+	return ModeRules{FriendlyFire: false, RespawnDelay: 10 * 60, Waves: &h.Waves}
+}
+
+func (*HordeMode) OnEvent(m *Match, e game.Event) {
+	if e.Type == game.EventDeath && e.Player == 0 && e.Attacker != 0 {
+		ps := m.Score(e.Attacker)
+		ps.Kills++
+		ps.Score++
+	}
+}
+
+func (*HordeMode) Result(m *Match) (protocol.MatchResult, bool) {
+	if len(m.scores) == 0 || m.Wave() == 0 || len(m.world.Players()) > 0 {
+		return protocol.MatchResult{}, false
+	}
+	result := protocol.MatchResult{Reason: fmt.Sprintf("overrun on wave %d", m.Wave())}
+	scores := m.Scores()
+	for _, ps := range scores {
+		if ps.Score == scores[0].Score && ps.Score > 0 {
+			result.Winners = append(result.Winners, ps.PlayerID)
+		}
+	}
+	return result, true
+}
+
+// MatchStatus is a copy of a match's state, safe to use outside the server
+type MatchStatus struct {
+	Mode        string
+	Competitive bool   // The mode's rules are competitive, see ModeRules.NoAimAssist
+	Elapsed     uint64 // Ticks
+	Wave        int    // Enemy wave, for modes with waves
+	Scores      []PlayerScore
+	Ended       bool
+	Result      protocol.MatchResult
+}
+
+// SetGameMode starts a new match of the mode on the server's world
+func (s *Server) SetGameMode(mode GameMode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.world == nil {
+		s.world = game.NewWorld()
+	}
+	if !s.subscribed {
+		// Events fire inside world.Update or ResetLevel, with s.mu held
+		s.world.Subscribe(func(e game.Event) {
+			if s.match != nil {
+				s.match.onEvent(e)
+			}
+		})
+		s.subscribed = true
+	}
+	s.match = newMatch(mode, s.world)
+	s.applySettings()
+}
+
+// MatchStatus returns the current match, if a game mode is set
+func (s *Server) MatchStatus() (MatchStatus, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.match == nil {
+		return MatchStatus{}, false
+	}
+	return MatchStatus{
+		Mode:        s.match.Mode.Name(),
+		Competitive: s.match.Mode.Rules().NoAimAssist,
+		Elapsed:     s.match.Elapsed(),
+		Wave:        s.match.Wave(),
+		Scores:      s.match.Scores(),
+		Ended:       s.match.Ended,
+		Result:      s.match.Result,
+	}, true
+}
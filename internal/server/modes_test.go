@@ -0,0 +1,341 @@
+package server
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// modeWorld returns a world with players 1 to n on the test level, and
+// their network IDs
+func modeWorld(n int) (*game.World, []protocol.EntityID) {
+	world := game.NewWorld()
+	world.LoadLevel(&game.Level{
+		Name:         "test",
+		TileMap:      game.DemoLevel(),
+		PlayerSpawns: []game.SpawnPoint{{X: 5, Y: 10}, {X: 20, Y: 10}, {X: 30, Y: 10}},
+	})
+	var ids []protocol.EntityID
+	for id := 1; id <= n; id++ {
+		spawn := world.Level().PlayerSpawn(id - 1)
+		ids = append(ids, world.NetIDOf(world.SpawnPlayer(id, "P", spawn.X, spawn.Y)))
+	}
+	return world, ids
+}
+
+// TestModeScoring tests how each mode scores kills, deaths and finishes
+func TestModeScoring(t *testing.T) {
+	enemyKill := func(attacker int) game.Event {
+		return game.Event{Type: game.EventDeath, Attacker: attacker}
+	}
+	playerKill := func(player, attacker int) game.Event {
+		return game.Event{Type: game.EventDeath, Player: player, Attacker: attacker}
+	}
+	finish := func(player int, tick uint64) game.Event {
+		return game.Event{Type: game.EventFinish, Player: player, Tick: tick}
+	}
+
+	tests := []struct {
+		name   string
+		mode   GameMode
+		events []game.Event
+		want   []PlayerScore // Score, Kills, Deaths, Finished and Time by player, best first
+	}{
+		{
+			name:   "coop scores enemies only",
+			mode:   &CoopMode{},
+			events: []game.Event{enemyKill(1), enemyKill(1), enemyKill(2), playerKill(2, 1), enemyKill(0)},
+			want: []PlayerScore{
+				{PlayerID: 1, Score: 2},
+				{PlayerID: 2, Score: 1, Deaths: 1},
+				{PlayerID: 3},
+			},
+		},
+		{
+			name:   "deathmatch scores players only",
+			mode:   &DeathmatchMode{},
+			events: []game.Event{playerKill(2, 1), playerKill(3, 1), playerKill(1, 3), playerKill(3, 3), playerKill(2, 0), enemyKill(2)},
+			want: []PlayerScore{
+				{PlayerID: 1, Score: 2, Kills: 2, Deaths: 1},
+				{PlayerID: 3, Score: 1, Kills: 1, Deaths: 2},
+				{PlayerID: 2, Deaths: 2},
+			},
+		},
+		{
+			name:   "horde scores enemies only",
+			mode:   &HordeMode{Waves: DefaultWaveConfig()},
+			events: []game.Event{enemyKill(3), enemyKill(3), playerKill(1, 3), enemyKill(1)},
+			want: []PlayerScore{
+				{PlayerID: 3, Score: 2, Kills: 2},
+				{PlayerID: 1, Score: 1, Kills: 1, Deaths: 1},
+				{PlayerID: 2},
+			},
+		},
+		{
+			name:   "race scores earlier finishers higher",
+			mode:   &RaceMode{},
+			events: []game.Event{finish(2, 100), playerKill(3, 0), finish(1, 250)},
+			want: []PlayerScore{
+				{PlayerID: 2, Score: 3, Finished: true, Time: 100},
+				{PlayerID: 1, Score: 2, Finished: true, Time: 250},
+				{PlayerID: 3, Deaths: 1},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			world, _ := modeWorld(3)
+			m := newMatch(tc.mode, world)
+			for _, e := range tc.events {
+				m.onEvent(e)
+			}
+			got := m.Scores()
+			for i := range got {
+				got[i].Name = ""
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("Scores = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestModeResult tests each mode's win conditions
+func TestModeResult(t *testing.T) {
+	finish := func(m *Match, player int, tick uint64) {
+		m.onEvent(game.Event{Type: game.EventFinish, Player: player, Tick: tick})
+	}
+	kills := func(m *Match, attacker, n int) {
+		for range n {
+			m.onEvent(game.Event{Type: game.EventDeath, Player: 3, Attacker: attacker})
+		}
+	}
+
+	tests := []struct {
+		name    string
+		mode    GameMode
+		setup   func(m *Match)
+		down    bool // Every player is dead
+		elapsed uint64
+		done    bool
+		winners []int
+		reason  string
+	}{
+		{
+			name:    "coop never ends",
+			mode:    &CoopMode{},
+			setup:   func(m *Match) { kills(m, 1, 100) },
+			elapsed: 1 << 20,
+		},
+		{
+			name:    "race waits for everyone",
+			mode:    &RaceMode{TimeLimit: 1000},
+			setup:   func(m *Match) { finish(m, 1, 100) },
+			elapsed: 999,
+		},
+		{
+			name: "race ends when all finished",
+			mode: &RaceMode{TimeLimit: 1000},
+			setup: func(m *Match) {
+				finish(m, 3, 300)
+				finish(m, 1, 200)
+				finish(m, 2, 200)
+			},
+			elapsed: 300,
+			done:    true,
+			winners: []int{1}, // Ties go to the lower ID
+			reason:  "all finished",
+		},
+		{
+			name:    "race time limit",
+			mode:    &RaceMode{TimeLimit: 1000},
+			setup:   func(m *Match) { finish(m, 2, 500) },
+			elapsed: 1000,
+			done:    true,
+			winners: []int{2},
+			reason:  "time limit",
+		},
+		{
+			name:    "race time limit without finishers",
+			mode:    &RaceMode{TimeLimit: 1000},
+			elapsed: 1000,
+			done:    true,
+			reason:  "time limit",
+		},
+		{
+			name:    "race without a time limit",
+			mode:    &RaceMode{},
+			elapsed: 1 << 20,
+		},
+		{
+			name:    "deathmatch below the frag limit",
+			mode:    &DeathmatchMode{FragLimit: 3, TimeLimit: 1000},
+			setup:   func(m *Match) { kills(m, 1, 2) },
+			elapsed: 999,
+		},
+		{
+			name:    "deathmatch frag limit",
+			mode:    &DeathmatchMode{FragLimit: 3, TimeLimit: 1000},
+			setup:   func(m *Match) { kills(m, 2, 1); kills(m, 1, 3) },
+			elapsed: 10,
+			done:    true,
+			winners: []int{1},
+			reason:  "frag limit",
+		},
+		{
+			name:    "deathmatch time limit shares a tie",
+			mode:    &DeathmatchMode{FragLimit: 3, TimeLimit: 1000},
+			setup:   func(m *Match) { kills(m, 2, 2); kills(m, 1, 2) },
+			elapsed: 1000,
+			done:    true,
+			winners: []int{1, 2},
+			reason:  "time limit",
+		},
+		{
+			name:    "deathmatch time limit without kills",
+			mode:    &DeathmatchMode{TimeLimit: 1000},
+			elapsed: 1000,
+			done:    true,
+			reason:  "time limit",
+		},
+		{
+			name:    "horde before the first wave",
+			mode:    &HordeMode{Waves: DefaultWaveConfig()},
+			down:    true,
+			elapsed: 10,
+		},
+		{
+			name: "horde with players standing",
+			mode: &HordeMode{Waves: DefaultWaveConfig()},
+			setup: func(m *Match) {
+				m.director.Wave = 2
+			},
+			elapsed: 10,
+		},
+		{
+			name: "horde overrun",
+			mode: &HordeMode{Waves: DefaultWaveConfig()},
+			setup: func(m *Match) {
+				m.director.Wave = 2
+				m.onEvent(game.Event{Type: game.EventDeath, Attacker: 2})
+				m.onEvent(game.Event{Type: game.EventDeath, Attacker: 3})
+			},
+			down:    true,
+			elapsed: 10,
+			done:    true,
+			winners: []int{2, 3},
+			reason:  "overrun on wave 2",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			world, players := modeWorld(3)
+			m := newMatch(tc.mode, world)
+			if tc.setup != nil {
+				tc.setup(m)
+			}
+			if tc.down {
+				despawn(world, players)
+			}
+			world.Tick = m.StartTick + tc.elapsed
+			result, done := tc.mode.Result(m)
+			if done != tc.done {
+				t.Fatalf("Result done = %v, want %v", done, tc.done)
+			}
+			if !slices.Equal(result.Winners, tc.winners) || result.Reason != tc.reason {
+				t.Errorf("Result = %+v, want winners %v for %q", result, tc.winners, tc.reason)
+			}
+		})
+	}
+}
+
+// despawn removes the entities from the world, as if they had died
+func despawn(world *game.World, ids []protocol.EntityID) {
+	for _, id := range ids {
+		world.Despawn(id)
+	}
+}
+
+// TestMatchRespawn tests that dead players come back after the mode's
+// delay at the level's spawn points, and that a reset starts a fresh match.
+func TestMatchRespawn(t *testing.T) {
+	tests := []struct {
+		mode  GameMode
+		delay uint64 // Ticks from death to respawn
+	}{
+		{&CoopMode{}, 120},
+		{&RaceMode{}, 1}, // At once: on the next tick
+		{&DeathmatchMode{}, 90},
+		{&HordeMode{Waves: DefaultWaveConfig()}, 600},
+	}
+	for _, tc := range tests {
+		t.Run(tc.mode.Name(), func(t *testing.T) {
+			world, players := modeWorld(2)
+			m := newMatch(tc.mode, world)
+			despawn(world, players[1:])
+			died := world.Tick
+			m.onEvent(game.Event{Type: game.EventDeath, Tick: died, Player: 2})
+
+			for world.Tick-died < tc.delay {
+				if _, _, ok := world.PlayerPosition(2); ok {
+					t.Fatalf("Player respawned after %d ticks, want %d", world.Tick-died, tc.delay)
+				}
+				world.Update()
+				m.tick()
+			}
+			if _, _, ok := world.PlayerPosition(2); !ok {
+				t.Fatalf("Player not respawned after %d ticks", tc.delay)
+			}
+			if m.Score(2).Deaths != 1 || m.Score(2).Name != "P" {
+				t.Errorf("Score after respawning = %+v, want 1 death and the name kept", *m.Score(2))
+			}
+
+			m.onEvent(game.Event{Type: game.EventReset})
+			if m.Score(2).Deaths != 0 || m.StartTick != world.Tick {
+				t.Errorf("Reset kept the old match: %+v from tick %d", *m.Score(2), m.StartTick)
+			}
+		})
+	}
+}
+
+// TestModeFriendlyFire tests that a fist hurts other players only in modes
+// with friendly fire, and that the kill is scored
+func TestModeFriendlyFire(t *testing.T) {
+	tests := []struct {
+		mode  GameMode
+		kills int
+	}{
+		{&CoopMode{}, 0},
+		{&RaceMode{}, 0},
+		{&DeathmatchMode{}, 1},
+		{&HordeMode{Waves: DefaultWaveConfig()}, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.mode.Name(), func(t *testing.T) {
+			srv := New(DefaultConfig())
+			world, _ := modeWorld(0)
+			srv.SetWorld(world)
+			srv.SetGameMode(tc.mode)
+			world.SpawnPlayer(1, "One", 5, 10)
+			victim := world.SpawnPlayer(2, "Two", 20, 10)
+			world.SetEntityHealth(world.NetIDOf(victim), 1, 3)
+
+			x, y, _ := world.PlayerPosition(2)
+			world.SpawnFist(x-game.FistSpeed, y, true, game.MaxFistDistance, 1)
+			srv.Step()
+
+			_, _, alive := world.PlayerPosition(2)
+			if alive != (tc.kills == 0) {
+				t.Errorf("Victim alive = %v after the hit, want %v", alive, tc.kills == 0)
+			}
+			status, _ := srv.MatchStatus()
+			for _, ps := range status.Scores {
+				if ps.PlayerID == 1 && ps.Kills != tc.kills {
+					t.Errorf("Attacker has %d kills, want %d", ps.Kills, tc.kills)
+				}
+			}
+		})
+	}
+}
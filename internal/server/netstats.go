@@ -0,0 +1,87 @@
+package server
+
+import (
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// NetStats are traffic counters for one connection. The network layer
+// reports bytes and packets (Session.RecordSent, RecordReceived); the server
+// counts the snapshots it builds and their encoded size.
+type NetStats struct {
+	BytesIn, BytesOut     uint64
+	PacketsIn, PacketsOut uint64
+
+	FullSnapshots  uint64
+	DeltaSnapshots uint64
+	SnapshotBytes  uint64 // Encoded size of all snapshots
+}
+
+// Snapshots returns the number of snapshots sent
+func (n NetStats) Snapshots() uint64 {
+	return n.FullSnapshots + n.DeltaSnapshots
+}
+
+// AvgSnapshotSize returns the mean encoded snapshot size in bytes
+func (n NetStats) AvgSnapshotSize() float64 {
+	if n.Snapshots() == 0 {
+		return 0
+	}
+	return float64(n.SnapshotBytes) / float64(n.Snapshots())
+}
+
+// Sub returns the traffic since an earlier reading, e.g. for per-second rates
+func (n NetStats) Sub(earlier NetStats) NetStats {
+	return NetStats{
+		BytesIn:        n.BytesIn - earlier.BytesIn,
+		BytesOut:       n.BytesOut - earlier.BytesOut,
+		PacketsIn:      n.PacketsIn - earlier.PacketsIn,
+		PacketsOut:     n.PacketsOut - earlier.PacketsOut,
+		FullSnapshots:  n.FullSnapshots - earlier.FullSnapshots,
+		DeltaSnapshots: n.DeltaSnapshots - earlier.DeltaSnapshots,
+		SnapshotBytes:  n.SnapshotBytes - earlier.SnapshotBytes,
+	}
+}
+
+// AddSnapshot counts a snapshot
+func (n *NetStats) AddSnapshot(snap *protocol.StateSnapshot) {
+	if snap.Full {
+		n.FullSnapshots++
+	} else {
+		n.DeltaSnapshots++
+	}
+	n.SnapshotBytes += uint64(snap.Size())
+}
+
+// RecordReceived counts a packet of the given size from the client
+func (s *Session) RecordReceived(bytes int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.net.PacketsIn++
+	s.net.BytesIn += uint64(bytes)
+}
+
+// RecordSent counts a packet of the given size to the client
+func (s *Session) RecordSent(bytes int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.net.PacketsOut++
+	s.net.BytesOut += uint64(bytes)
+}
+
+// NetStats returns the session's traffic counters
+func (s *Session) NetStats() NetStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.net
+}
+
+// SessionNetStats returns every session's traffic counters, by session ID
+func (s *Server) SessionNetStats() map[int]NetStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stats := make(map[int]NetStats, len(s.sessions))
+	for id, session := range s.sessions {
+		stats[id] = session.NetStats()
+	}
+	return stats
+}
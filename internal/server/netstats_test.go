@@ -0,0 +1,55 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// TestSessionNetStats tests that the server counts each session's full and
+// delta snapshots and their size, next to the traffic the network layer
+// reports, and that Sub gives the traffic between two readings.
+func TestSessionNetStats(t *testing.T) {
+	srv := New(DefaultConfig())
+	world := game.NewWorld()
+	world.LoadLevel(game.NewDemoLevel(80, 40))
+	srv.SetWorld(world)
+	session := srv.AddSession(1, 1, "P")
+	world.SpawnPlayer(1, "P", 10, 10)
+
+	var sent uint64
+	srv.SetSnapshotCallback(func(id int, snap protocol.StateSnapshot) {
+		sent += uint64(snap.Size())
+		session.AckSnapshot(snap.Tick)
+	})
+	for range 30 {
+		srv.Step()
+	}
+	session.RecordReceived(40)
+	session.RecordSent(100)
+	session.RecordSent(60)
+
+	stats := srv.SessionNetStats()[1]
+	if stats.FullSnapshots != 1 || stats.DeltaSnapshots != 9 {
+		t.Errorf("%d full and %d delta snapshots, want 1 and 9 (20/s at 60 ticks)", stats.FullSnapshots, stats.DeltaSnapshots)
+	}
+	if stats.SnapshotBytes != sent || stats.AvgSnapshotSize() != float64(sent)/10 {
+		t.Errorf("Snapshot bytes %d (avg %v), want %d", stats.SnapshotBytes, stats.AvgSnapshotSize(), sent)
+	}
+	if stats.PacketsIn != 1 || stats.BytesIn != 40 || stats.PacketsOut != 2 || stats.BytesOut != 160 {
+		t.Errorf("Traffic %+v, want 1 packet in of 40 bytes and 2 out of 160", stats)
+	}
+
+	for range 3 {
+		srv.Step()
+	}
+	session.RecordSent(10)
+	diff := session.NetStats().Sub(stats)
+	if diff.Snapshots() != 1 || diff.FullSnapshots != 0 || diff.PacketsOut != 1 || diff.BytesOut != 10 || diff.BytesIn != 0 {
+		t.Errorf("Traffic since the reading %+v, want one delta snapshot and one 10 byte packet out", diff)
+	}
+	if avg := (NetStats{}).AvgSnapshotSize(); avg != 0 {
+		t.Errorf("AvgSnapshotSize without snapshots = %v, want 0", avg)
+	}
+}
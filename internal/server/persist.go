@@ -0,0 +1,198 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// Checkpoint is the server's state saved to disk so a match survives a
+// crash: the world, the match and who was playing. Connections are not
+// saved; players rejoin and Join gives them their old player back by name.
+type Checkpoint struct {
+	SavedAt    time.Time
+	Map        string // Config.MapPath, empty for the demo level
+	Tick       uint64
+	World      game.WorldState
+	LastNetID  protocol.EntityID
+	LevelStart uint64
+	Match      *MatchCheckpoint      // nil without a game mode
+	Players    []protocol.PlayerInfo // Connected players, by player ID
+
+	Difficulty   game.Difficulty `json:",omitempty"`
+	FriendlyFire *bool           `json:",omitempty"` // Host's or operator's choice over the mode's
+}
+
+// MatchCheckpoint is the saved state of a Match
+type MatchCheckpoint struct {
+	Mode      string
+	StartTick uint64
+	Ended     bool
+	Result    protocol.MatchResult
+	Scores    []PlayerScore
+	Respawns  map[int]uint64 // Player ID -> tick to respawn at
+	NextSpawn int
+	Waves     *WaveState // Enemy waves, for modes with them
+}
+
+// Checkpoint captures the server's state between ticks
+func (s *Server) Checkpoint() Checkpoint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cp := Checkpoint{
+		SavedAt: time.Now(),
+		Map:     s.config.MapPath,
+		Tick:    s.tick,
+		Players: s.roster(),
+
+		FriendlyFire: s.friendlyFire,
+	}
+	if s.world != nil {
+		cp.Difficulty = s.world.Difficulty
+		cp.World = s.world.Snapshot()
+		cp.LastNetID = s.world.LastNetID()
+		cp.LevelStart = s.world.LevelStart()
+	}
+	if m := s.match; m != nil {
+		cp.Match = &MatchCheckpoint{
+			Mode:      m.Mode.Name(),
+			StartTick: m.StartTick,
+			Ended:     m.Ended,
+			Result:    m.Result,
+			Scores:    m.Scores(),
+			Respawns:  make(map[int]uint64, len(m.respawns)),
+			NextSpawn: m.nextSpawn,
+		}
+		for id, at := range m.respawns {
+			cp.Match.Respawns[id] = at
+		}
+		if m.director != nil {
+			waves := m.director.WaveState
+			waves.Alive = append([]protocol.EntityID(nil), waves.Alive...)
+			cp.Match.Waves = &waves
+		}
+	}
+	return cp
+}
+
+// Resume continues from a checkpoint. The server's world must have the
+// same level freshly loaded and its game mode must match the checkpoint's.
+// Fists in flight are not part of it and are lost.
+func (s *Server) Resume(cp Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.world == nil {
+		return fmt.Errorf("resume: no world")
+	}
+	if cp.Map != s.config.MapPath {
+		return fmt.Errorf("resume: checkpoint is for map %q, server runs %q", cp.Map, s.config.MapPath)
+	}
+	switch {
+	case cp.Match == nil && s.match != nil:
+		return fmt.Errorf("resume: checkpoint has no game mode, server runs %s", s.match.Mode.Name())
+	case cp.Match != nil && (s.match == nil || s.match.Mode.Name() != cp.Match.Mode):
+		return fmt.Errorf("resume: checkpoint is a %s match", cp.Match.Mode)
+	}
+
+	for _, p := range cp.Players {
+		s.world.SetPlayerColor(p.PlayerID, p.Color)
+	}
+	s.world.Restore(cp.World)
+	s.world.ReserveNetIDs(cp.LastNetID)
+	s.world.SetLevelStart(cp.LevelStart)
+	s.world.Difficulty = cp.Difficulty
+	s.friendlyFire = cp.FriendlyFire
+	s.applySettings()
+	s.tick = cp.Tick
+	clear(s.lastPos)
+
+	if mc := cp.Match; mc != nil {
+		m := s.match
+		m.StartTick = mc.StartTick
+		m.Ended = mc.Ended
+		m.Result = mc.Result
+		clear(m.scores)
+		for _, ps := range mc.Scores {
+			m.scores[ps.PlayerID] = &ps
+		}
+		clear(m.respawns)
+		for id, at := range mc.Respawns {
+			m.respawns[id] = at
+		}
+		m.nextSpawn = mc.NextSpawn
+		if m.director != nil && mc.Waves != nil {
+			m.director.WaveState = *mc.Waves
+		}
+	}
+
+	s.resumed = make(map[string]resumedPlayer, len(cp.Players))
+	for _, p := range cp.Players {
+		slot := -1
+		for i, c := range protocol.PlayerColors {
+			if c == p.Color {
+				slot = i
+			}
+		}
+		s.resumed[p.Name] = resumedPlayer{id: p.PlayerID, colorSlot: slot}
+	}
+	return nil
+}
+
+// resumedPlayer is a player from a resumed checkpoint who hasn't rejoined
+type resumedPlayer struct {
+	id        int
+	colorSlot int // -1 if the color isn't in the palette
+}
+
+// claimResumed returns the player a resumed checkpoint had under name, once
+func (s *Server) claimResumed(name string) (resumedPlayer, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.resumed[name]
+	delete(s.resumed, name)
+	return id, ok
+}
+
+// WriteCheckpoint saves a checkpoint as JSON. It writes a temporary file
+// and renames it over path, so a crash while saving leaves the last one.
+func WriteCheckpoint(path string, cp Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op after the rename
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ReadCheckpoint loads a checkpoint written by WriteCheckpoint
+func ReadCheckpoint(path string) (Checkpoint, error) {
+	var cp Checkpoint
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cp, err
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, fmt.Errorf("checkpoint %s: %w", path, err)
+	}
+	return cp, nil
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// TestCheckpointResume tests that a server resumed from a saved checkpoint
+// has the same world, scores and tick, hands a rejoining player their old
+// player, and then simulates on exactly like the original.
+func TestCheckpointResume(t *testing.T) {
+	newServer := func() *Server {
+		srv := New(DefaultConfig())
+		world := game.NewWorld()
+		world.LoadLevel(game.NewDemoLevel(60, 30))
+		srv.SetWorld(world)
+		srv.SetGameMode(&CoopMode{})
+		return srv
+	}
+
+	original := newServer()
+	for id, name := range map[int]string{1: "Alice", 2: "Bob"} {
+		spawn := original.World().Level().PlayerSpawn(id - 1)
+		original.World().SpawnPlayer(id, name, spawn.X, spawn.Y)
+		original.AddSession(id, id, name)
+	}
+	for tick := 1; tick <= 300; tick++ {
+		intents := protocol.IntentRight
+		if tick%40 < 10 {
+			intents |= protocol.IntentJump
+		}
+		original.QueueInput(1, protocol.InputFrame{Tick: uint64(tick), Intents: intents})
+		original.QueueInput(2, protocol.InputFrame{Tick: uint64(tick), Intents: protocol.IntentLeft})
+		original.Step()
+	}
+
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	if err := WriteCheckpoint(path, original.Checkpoint()); err != nil {
+		t.Fatal(err)
+	}
+	cp, err := ReadCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resumed := newServer()
+	if err := resumed.Resume(cp); err != nil {
+		t.Fatal(err)
+	}
+	want, got := original.World().Snapshot(), resumed.World().Snapshot()
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("Resumed world differs:\nwant %+v\ngot  %+v", want.Entities, got.Entities)
+	}
+	if resumed.Tick() != original.Tick() {
+		t.Errorf("Resumed at tick %d, want %d", resumed.Tick(), original.Tick())
+	}
+	wantMatch, _ := original.MatchStatus()
+	gotMatch, _ := resumed.MatchStatus()
+	if !reflect.DeepEqual(wantMatch, gotMatch) {
+		t.Errorf("Resumed match %+v, want %+v", gotMatch, wantMatch)
+	}
+
+	// Bob rejoins on a new connection and gets player 2 back
+	_, reply := resumed.Join(7, 7, protocol.NewHandshake("Bob"))
+	if !reply.Accepted || reply.PlayerID != 2 {
+		t.Fatalf("Rejoin should get player 2, got %+v", reply)
+	}
+	resumed.AddSession(8, 1, "Alice")
+
+	for tick := 301; tick <= 420; tick++ {
+		original.QueueInput(1, protocol.InputFrame{Tick: uint64(tick), Intents: protocol.IntentLeft})
+		original.QueueInput(2, protocol.InputFrame{Tick: uint64(tick), Intents: protocol.IntentJump})
+		resumed.QueueInput(8, protocol.InputFrame{Tick: uint64(tick), Intents: protocol.IntentLeft})
+		resumed.QueueInput(7, protocol.InputFrame{Tick: uint64(tick), Intents: protocol.IntentJump})
+		original.Step()
+		resumed.Step()
+	}
+	if a, b := original.World().Snapshot(), resumed.World().Snapshot(); !reflect.DeepEqual(a.Entities, b.Entities) {
+		t.Errorf("Worlds diverged after resuming:\noriginal %+v\nresumed  %+v", a.Entities, b.Entities)
+	}
+}
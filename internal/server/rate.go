@@ -0,0 +1,146 @@
+package server
+
+import (
+	"time"
+)
+
+// RateConfig controls per-session send-rate adaptation. A congested client
+// gets snapshots less often (and only deltas) instead of a growing queue;
+// a healthy one is brought back up to the configured SyncRate.
+type RateConfig struct {
+	MinSyncRate  int           // Lowest snapshot rate per second under congestion
+	BacklogHigh  int           // Queued outgoing messages that count as congested
+	RTTHigh      time.Duration // Round trip that counts as congested
+	RTTLow       time.Duration // Round trip considered healthy again
+	RecoverAfter int           // Healthy sends before the rate is raised one step
+	ResyncTicks  uint64        // Unacked ticks after which a full snapshot is sent
+}
+
+// DefaultRateConfig returns conservative adaptation settings
+func DefaultRateConfig() RateConfig {
+	return RateConfig{
+		MinSyncRate:  2,
+		BacklogHigh:  8,
+		RTTHigh:      250 * time.Millisecond,
+		RTTLow:       120 * time.Millisecond,
+		RecoverAfter: 10,
+		ResyncTicks:  120,
+	}
+}
+
+// sendRate is a session's adaptive snapshot schedule, in ticks
+type sendRate struct {
+	interval    int // Ticks between snapshots
+	minInterval int // From Config.SyncRate
+	maxInterval int // From RateConfig.MinSyncRate
+	sinceSend   int
+	healthy     int    // Consecutive healthy sends
+	lastFull    uint64 // Tick of the last forced full snapshot
+
+	rtt     time.Duration
+	backlog int
+}
+
+func newSendRate(cfg Config) sendRate {
+	minInterval := intervalFor(cfg.TickRate, cfg.SyncRate)
+	maxInterval := intervalFor(cfg.TickRate, cfg.Rate.MinSyncRate)
+	if maxInterval < minInterval {
+		maxInterval = minInterval
+	}
+	return sendRate{interval: minInterval, minInterval: minInterval, maxInterval: maxInterval}
+}
+
+// intervalFor converts a per-second rate into ticks between sends
+func intervalFor(tickRate, rate int) int {
+	if rate <= 0 || tickRate <= 0 {
+		return 1
+	}
+	interval := tickRate / rate
+	if interval < 1 {
+		interval = 1
+	}
+	return interval
+}
+
+// RecordRTT updates the session's smoothed round-trip time from a ping
+func (s *Session) RecordRTT(rtt time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.rate.rtt == 0 {
+		s.rate.rtt = rtt
+		return
+	}
+	// Exponential moving average, 1/8 weight like TCP's SRTT
+	s.rate.rtt += (rtt - s.rate.rtt) / 8
+}
+
+// SetBacklog records how many messages are waiting in the session's send queue
+func (s *Session) SetBacklog(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rate.backlog = n
+}
+
+// AckSnapshot records the latest snapshot tick the client confirmed
+func (s *Session) AckSnapshot(tick uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tick > s.LastAckTick {
+		s.LastAckTick = tick
+	}
+}
+
+// SendInterval returns the current number of ticks between snapshots
+func (s *Session) SendInterval() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rate.interval
+}
+
+// RTT returns the smoothed round-trip time
+func (s *Session) RTT() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rate.rtt
+}
+
+// snapshotDue advances the session's schedule by one tick and reports whether
+// a snapshot should be sent now, and whether it must be a full snapshot.
+func (s *Session) snapshotDue(tick uint64, cfg RateConfig) (send, full bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := &s.rate
+	r.sinceSend++
+	if r.sinceSend < r.interval {
+		return false, false
+	}
+	r.sinceSend = 0
+
+	congested := r.backlog >= cfg.BacklogHigh || (cfg.RTTHigh > 0 && r.rtt >= cfg.RTTHigh)
+	switch {
+	case congested:
+		// Back off quickly
+		r.healthy = 0
+		r.interval = min(r.interval*2, r.maxInterval)
+	case r.backlog == 0 && r.rtt <= cfg.RTTLow:
+		// Recover slowly
+		r.healthy++
+		if r.healthy >= cfg.RecoverAfter {
+			r.healthy = 0
+			r.interval = max(r.interval-1, r.minInterval)
+		}
+	default:
+		r.healthy = 0
+	}
+
+	// A client that stopped acking has likely lost our deltas. Resync with a
+	// full snapshot, unless the link is congested and a full one would only
+	// make it worse.
+	full = !congested && cfg.ResyncTicks > 0 && s.LastAckTick > 0 &&
+		tick-s.LastAckTick > cfg.ResyncTicks && tick-r.lastFull > cfg.ResyncTicks
+	if full {
+		r.lastFull = tick
+	}
+	return true, full
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestIntervalFor(t *testing.T) {
+	tests := []struct {
+		tickRate, rate, want int
+	}{
+		{60, 20, 3},
+		{60, 2, 30},
+		{60, 120, 1},
+		{60, 0, 1},
+		{0, 20, 1},
+	}
+	for _, tc := range tests {
+		if got := intervalFor(tc.tickRate, tc.rate); got != tc.want {
+			t.Errorf("intervalFor(%d, %d) = %d, want %d", tc.tickRate, tc.rate, got, tc.want)
+		}
+	}
+}
+
+// TestSendRate tests that congestion doubles the interval up to the
+// minimum rate, and that a healthy link brings it back one step at a time.
+func TestSendRate(t *testing.T) {
+	cfg := DefaultConfig()
+	s := &Session{rate: newSendRate(cfg)}
+	tick := uint64(0)
+	// send runs the schedule until the next snapshot and returns the ticks
+	// it took
+	send := func() int {
+		for n := 1; ; n++ {
+			tick++
+			if due, _ := s.snapshotDue(tick, cfg.Rate); due {
+				return n
+			}
+		}
+	}
+
+	if n := send(); n != 3 {
+		t.Fatalf("Healthy session sent after %d ticks, want 3 (20/s at 60 ticks)", n)
+	}
+
+	s.SetBacklog(cfg.Rate.BacklogHigh)
+	var intervals []int
+	for range 6 {
+		send()
+		intervals = append(intervals, s.SendInterval())
+	}
+	if want := []int{6, 12, 24, 30, 30, 30}; !slices.Equal(intervals, want) {
+		t.Errorf("Congested intervals %v, want %v", intervals, want)
+	}
+
+	s.SetBacklog(0)
+	s.RecordRTT(200 * time.Millisecond) // Neither congested nor healthy
+	for range 3 * cfg.Rate.RecoverAfter {
+		send()
+	}
+	if got := s.SendInterval(); got != 30 {
+		t.Errorf("Interval %d with a middling RTT, want it kept at 30", got)
+	}
+
+	s.RecordRTT(250 * time.Millisecond)
+	if got := s.RTT(); got != 200*time.Millisecond+50*time.Millisecond/8 {
+		t.Errorf("Smoothed RTT = %v, want an eighth of the way to the sample", got)
+	}
+	send()
+	if got := s.SendInterval(); got != 30 {
+		t.Errorf("Interval %d after an RTT spike, want the maximum 30", got)
+	}
+
+	s.rate.rtt = 50 * time.Millisecond
+	for range cfg.Rate.RecoverAfter {
+		send()
+	}
+	if got := s.SendInterval(); got != 29 {
+		t.Errorf("Interval %d after %d healthy sends, want one step down to 29", got, cfg.Rate.RecoverAfter)
+	}
+	for range 40 * cfg.Rate.RecoverAfter {
+		send()
+	}
+	if got := s.SendInterval(); got != 3 {
+		t.Errorf("Interval %d after recovering, want back at 3", got)
+	}
+}
+
+// TestSendRateResync tests that a session that stopped acking is sent a
+// full snapshot once, then again only after another resync period, and
+// never while congested.
+func TestSendRateResync(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Rate.ResyncTicks = 30
+	s := &Session{rate: newSendRate(cfg)}
+
+	fulls := func(from, to uint64) []uint64 {
+		var at []uint64
+		for tick := from; tick <= to; tick++ {
+			if due, full := s.snapshotDue(tick, cfg.Rate); due && full {
+				at = append(at, tick)
+			}
+		}
+		return at
+	}
+
+	if got := fulls(1, 100); len(got) != 0 {
+		t.Errorf("Full snapshots at %v before any ack, want none", got)
+	}
+	s.AckSnapshot(100)
+	s.AckSnapshot(90) // Late ack, ignored
+	if got := fulls(101, 200); !slices.Equal(got, []uint64{132, 165, 198}) {
+		t.Errorf("Full snapshots at %v, want one per resync period past tick 130", got)
+	}
+
+	s.SetBacklog(cfg.Rate.BacklogHigh)
+	if got := fulls(201, 400); len(got) != 0 {
+		t.Errorf("Full snapshots at %v while congested, want none", got)
+	}
+}
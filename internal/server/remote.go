@@ -0,0 +1,215 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/andersfylling/rayman-slides/internal/network"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// Remotes serves clients over network connections. Every message starts
+// with its protocol.MsgType, followed by the message's encoding:
+//
+//	type u8 | payload
+//
+// A connection runs the room key handshake (network.SecureServer) if the
+// room has a key, then the protocol handshake; an accepted client gets its
+// join bundle, followed by the JoinTransfers chunks from protocol version 8,
+// and may then send inputs, chat, transfer requests and a disconnect. Chat,
+// kicks and map changes from the server are sent to the client's connection.
+// Snapshots have no codec yet, so remote clients don't see the game.
+type Remotes struct {
+	srv *Server
+	psk []byte
+
+	mu     sync.Mutex
+	conns  map[int]*remoteConn // By session ID
+	nextID int
+}
+
+// remoteConn is a client's connection; sends come from the connection's
+// own goroutine and from the server's callbacks, so they are serialized
+type remoteConn struct {
+	mu      sync.Mutex
+	conn    network.Connection
+	version int         // Negotiated protocol version
+	closed  atomic.Bool // Closed by the server, e.g. after a kick
+}
+
+// NewRemotes serves srv's remote clients. psk is the room key's pre-shared
+// key (lobby.KeyPSK), or nil for an open room. It takes over the server's
+// chat, disconnect and map change callbacks.
+func NewRemotes(srv *Server, psk []byte) *Remotes {
+	r := &Remotes{srv: srv, psk: psk, conns: make(map[int]*remoteConn)}
+	srv.SetChatCallback(func(sessionID int, msg protocol.Chat) {
+		r.send(sessionID, protocol.MsgChat, protocol.AppendChat(nil, msg))
+	})
+	srv.SetDisconnectCallback(func(sessionID int, msg protocol.Disconnect) {
+		r.send(sessionID, protocol.MsgDisconnect, protocol.AppendDisconnect(nil, msg))
+		r.mu.Lock()
+		rc := r.conns[sessionID]
+		r.mu.Unlock()
+		if rc != nil {
+			rc.close()
+		}
+	})
+	srv.SetMapChangeCallback(func(sessionID int, b protocol.JoinBundle) {
+		r.mu.Lock()
+		rc := r.conns[sessionID]
+		r.mu.Unlock()
+		if rc != nil {
+			rc.send(protocol.MsgJoinBundle, protocol.AppendJoinBundle(nil, b, rc.version))
+		}
+	})
+	return r
+}
+
+// Serve runs one client's connection until the client leaves or the
+// connection fails, and closes it. Each connection gets the next session
+// and player ID.
+func (r *Remotes) Serve(conn network.Connection) error {
+	defer conn.Close()
+	if r.psk != nil {
+		sc, err := network.SecureServer(conn, r.psk)
+		if err != nil {
+			return err
+		}
+		conn = sc
+	}
+
+	msg, err := conn.Recv()
+	if err != nil {
+		return err
+	}
+	if len(msg) == 0 || protocol.MsgType(msg[0]) != protocol.MsgHandshake {
+		return errors.New("server: connection didn't start with a handshake")
+	}
+	h, _, err := protocol.DecodeHandshake(msg[1:])
+	if err != nil {
+		return fmt.Errorf("server: handshake: %w", err)
+	}
+
+	r.mu.Lock()
+	r.nextID++
+	id := r.nextID
+	r.mu.Unlock()
+	session, reply := r.srv.Join(id, id, h)
+	rc := &remoteConn{conn: conn, version: reply.Version}
+	if err := rc.send(protocol.MsgHandshakeReply, protocol.AppendHandshakeReply(nil, reply)); err != nil || !reply.Accepted {
+		if session != nil {
+			r.srv.RemoveSession(id)
+		}
+		return err
+	}
+
+	r.mu.Lock()
+	r.conns[id] = rc
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.conns, id)
+		r.mu.Unlock()
+		r.srv.RemoveSession(id)
+	}()
+
+	bundle, err := r.srv.JoinBundle(session.NeedsLevel && session.Version < 8)
+	if err != nil {
+		return err
+	}
+	if err := rc.send(protocol.MsgJoinBundle, protocol.AppendJoinBundle(nil, bundle, rc.version)); err != nil {
+		return err
+	}
+	chunks, err := r.srv.JoinTransfers(session)
+	if err != nil {
+		return err
+	}
+	if err := rc.sendChunks(chunks); err != nil {
+		return err
+	}
+
+	for {
+		msg, err := conn.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) || rc.closed.Load() {
+				return nil
+			}
+			return err
+		}
+		if len(msg) == 0 {
+			continue
+		}
+		switch protocol.MsgType(msg[0]) {
+		case protocol.MsgInput:
+			m, _, err := protocol.DecodeInputMessage(msg[1:])
+			if err != nil {
+				return fmt.Errorf("server: input: %w", err)
+			}
+			r.srv.QueueInputs(id, m)
+			if ack, ok := r.srv.InputAck(id); ok {
+				if err := rc.send(protocol.MsgInputAck, protocol.AppendInputAck(nil, ack)); err != nil {
+					return err
+				}
+			}
+		case protocol.MsgChat:
+			c, _, err := protocol.DecodeChat(msg[1:])
+			if err != nil {
+				return fmt.Errorf("server: chat: %w", err)
+			}
+			r.srv.Chat(id, c.Text)
+		case protocol.MsgTransferRequest:
+			req, _, err := protocol.DecodeTransferRequest(msg[1:])
+			if err != nil {
+				return fmt.Errorf("server: transfer request: %w", err)
+			}
+			chunks, err := r.srv.Transfer(req)
+			if err != nil {
+				continue // Gone with a map change; the client gets the new one
+			}
+			if err := rc.sendChunks(chunks); err != nil {
+				return err
+			}
+		case protocol.MsgDisconnect:
+			return nil
+		}
+	}
+}
+
+// send sends a message to a session's connection, if it has one
+func (r *Remotes) send(sessionID int, t protocol.MsgType, payload []byte) {
+	r.mu.Lock()
+	rc := r.conns[sessionID]
+	r.mu.Unlock()
+	if rc != nil {
+		rc.send(t, payload)
+	}
+}
+
+func (rc *remoteConn) send(t protocol.MsgType, payload []byte) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.closed.Load() {
+		return net.ErrClosed
+	}
+	return rc.conn.Send(append([]byte{byte(t)}, payload...))
+}
+
+func (rc *remoteConn) sendChunks(chunks []protocol.TransferChunk) error {
+	for _, c := range chunks {
+		if err := rc.send(protocol.MsgTransferChunk, protocol.AppendTransferChunk(nil, c)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close closes the connection without waiting for a send in progress, so
+// a stalled client can't hold up a kick
+func (rc *remoteConn) close() {
+	rc.closed.Store(true)
+	rc.conn.Close()
+}
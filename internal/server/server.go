@@ -3,9 +3,13 @@
 package server
 
 import (
+	"fmt"
+	"runtime/debug"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/andersfylling/rayman-slides/internal/allocs"
 	"github.com/andersfylling/rayman-slides/internal/game"
 	"github.com/andersfylling/rayman-slides/internal/protocol"
 )
@@ -14,9 +18,30 @@ import (
 type Config struct {
 	Port       int
 	MaxPlayers int
-	TickRate   int           // Game ticks per second
-	SyncRate   int           // State broadcasts per second (can be lower than tick rate)
+	TickRate   int // Game ticks per second
+	SyncRate   int // State broadcasts per second (can be lower than tick rate)
 	MapPath    string
+	Interest   InterestArea // Default area of interest for new sessions
+	Rate       RateConfig   // Per-session send-rate adaptation
+	AntiCheat  AntiCheatConfig
+	SendLevel  bool // Send the level to clients without it, instead of rejecting them
+
+	// Build is the server's release; clients of any other build are
+	// rejected in the handshake. Empty accepts every build.
+	Build string
+
+	// HostControls makes the first player to join the host, who may kick
+	// players, restart the level and change friendly fire and difficulty
+	// (see HostCommand). Off for dedicated servers, which their operator
+	// runs from the console.
+	HostControls bool
+
+	// Votes lets players of a server without a host vote to kick, change
+	// the map or restart (see CallVote)
+	Votes VoteConfig
+
+	// AFK flags idle players, and in co-op makes them spectate
+	AFK AFKConfig
 }
 
 // DefaultConfig returns sensible defaults
@@ -27,6 +52,12 @@ func DefaultConfig() Config {
 		TickRate:   60,
 		SyncRate:   20, // Broadcast state 20 times per second
 		MapPath:    "",
+		Interest:   DefaultInterestArea(),
+		Rate:       DefaultRateConfig(),
+		AntiCheat:  DefaultAntiCheatConfig(),
+		SendLevel:  true,
+		Votes:      DefaultVoteConfig(),
+		AFK:        DefaultAFKConfig(),
 	}
 }
 
@@ -35,16 +66,76 @@ type Session struct {
 	ID          int
 	PlayerID    int
 	Name        string
+	Version     int                   // Negotiated protocol version
 	InputQueue  []protocol.InputFrame // Pending inputs to process
 	LastAckTick uint64                // Last tick acknowledged by client
-	mu          sync.Mutex
+	Interest    InterestArea          // Region around the player this session receives
+	Color       uint32                // Player color, from protocol.PlayerColors
+	NeedsLevel  bool                  // Client's level differs; send it in the join bundle
+
+	sent         []sentSnapshot // Recent snapshots, oldest first, see snapshotFor
+	rate         sendRate       // Adaptive snapshot schedule
+	reset        bool           // Next snapshot is a full one flagged Reset
+	colorSlot    int            // Index into protocol.PlayerColors
+	rosterSent   uint64         // Roster version last sent
+	settingsSent uint64         // Host settings version last sent
+	voteSent     uint64         // Vote status version last sent
+	statsSent    uint64         // World stats version last sent
+	levelSent    uint64         // World level state version last sent
+	resultSent   bool           // Match result already sent
+	strikes      int            // Input violations, see AntiCheatConfig.KickAfter
+	idle         uint64         // Ticks without intents, see AFKConfig
+	moved        bool           // Intents applied since the last AFK check
+	afk          bool           // Flagged AFK in the roster
+	net          NetStats       // Traffic counters, guarded by mu
+
+	lastQueuedTick    uint64 // Highest input tick received, for dedupe
+	lastProcessedTick uint64 // Highest input tick applied to the world
+	missedInputs      uint32 // Ticks simulated without a fresh input
+	mu                sync.Mutex
 }
 
-// QueueInput adds an input frame to the session's queue
+// QueueInput adds an input frame to the session's queue.
+// Frames older than the newest received tick are redundant copies and are
+// dropped; a frame for the newest tick replaces the queued one.
 func (s *Session) QueueInput(frame protocol.InputFrame) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.queueLocked(frame)
+}
+
+// QueueInputs adds all new frames from a redundant input message
+func (s *Session) QueueInputs(msg protocol.InputMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, frame := range msg.Frames {
+		s.queueLocked(frame)
+	}
+}
+
+func (s *Session) queueLocked(frame protocol.InputFrame) {
+	if s.lastProcessedTick > 0 && frame.Tick <= s.lastProcessedTick {
+		return // Already simulated
+	}
+	if frame.Tick < s.lastQueuedTick {
+		return // Redundant copy of a queued frame
+	}
+	if n := len(s.InputQueue); n > 0 && s.InputQueue[n-1].Tick == frame.Tick {
+		s.InputQueue[n-1] = frame
+		return
+	}
 	s.InputQueue = append(s.InputQueue, frame)
+	s.lastQueuedTick = frame.Tick
+}
+
+// Ack returns the input acknowledgement to send to the client
+func (s *Session) Ack() protocol.InputAck {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return protocol.InputAck{
+		LastProcessedTick: s.lastProcessedTick,
+		Missed:            s.missedInputs,
+	}
 }
 
 // DrainInputs returns and clears all pending inputs up to the given tick
@@ -64,25 +155,90 @@ func (s *Session) DrainInputs(upToTick uint64) []protocol.InputFrame {
 	}
 
 	s.InputQueue = remaining
+	if len(result) > 0 {
+		s.lastProcessedTick = result[len(result)-1].Tick
+	} else if s.lastQueuedTick > 0 {
+		// The client is sending, but nothing arrived for this tick
+		s.missedInputs++
+	}
 	return result
 }
 
 // Server is the authoritative game server
 type Server struct {
-	config   Config
-	tick     uint64
-	running  bool
-	mu       sync.RWMutex
+	config  Config
+	tick    uint64
+	running bool
+	mu      sync.RWMutex
 
 	world    *game.World
 	sessions map[int]*Session // sessionID -> session
 
 	// Channels
-	quitCh   chan struct{}
-	doneCh   chan struct{}
+	quitCh chan struct{}
+	doneCh chan struct{}
 
 	// Callbacks for embedded mode (when server runs in same process as client)
 	onStateUpdate func(state game.WorldState)
+
+	// Per-session snapshots for network mode, filtered by area of interest
+	onSnapshot func(sessionID int, snap protocol.StateSnapshot)
+	sprites    *protocol.SpriteTable
+
+	// Debug pacing of the simulation (pause, step, time scale)
+	time TimeControl
+
+	// Player roster, bumped on every join and leave
+	rosterVersion uint64
+
+	// Host controls: the host's session (0 for none), the host's friendly
+	// fire choice over the mode's (nil for none) and a version bumped on
+	// every change, for snapshots
+	host            int
+	friendlyFire    *bool
+	settingsVersion uint64
+
+	// Votes, without a host: the vote open or just ended (nil for none)
+	// and a version bumped on every change, the tick each player last
+	// called one, and the maps to vote for by name
+	vote        *vote
+	voteVersion uint64
+	voteCalled  map[int]uint64
+	maps        map[string]string
+	onMapChange func(sessionID int, b protocol.JoinBundle)
+
+	// Chat lines out, to one session
+	onChat func(sessionID int, msg protocol.Chat)
+
+	// Game mode; nil runs the world without scoring
+	match      *Match
+	subscribed bool // Match event handler registered on the world
+
+	// Anti-cheat checks
+	onViolation func(v Violation)
+	violations  map[ViolationKind]uint64
+	lastPos     map[int][2]float64 // Player positions after the last tick
+
+	// Shutdown: joins are refused while draining
+	draining     bool
+	onDisconnect func(sessionID int, msg protocol.Disconnect)
+
+	// Player IDs by name from a resumed checkpoint, until they rejoin
+	resumed map[string]resumedPlayer
+
+	// Sprite atlas definition pushed to joining clients; nil for none
+	atlas []byte
+
+	// Crash reporting: applied inputs are shown to onInput, and a panic in
+	// the tick loop is handed to onPanic instead of killing the process
+	onInput func(tick uint64, playerID int, intents protocol.Intent)
+	onPanic func(p any, stack []byte)
+
+	// Load testing: every tick is shown to onTick with how long it took
+	onTick func(tick uint64, took time.Duration)
+
+	// Allocations by subsystem, while enabled
+	allocs *allocs.Tracker
 }
 
 // New creates a new server with the given config
@@ -92,6 +248,11 @@ func New(cfg Config) *Server {
 		sessions: make(map[int]*Session),
 		quitCh:   make(chan struct{}),
 		doneCh:   make(chan struct{}),
+		sprites:  protocol.NewSpriteTable(),
+
+		violations: make(map[ViolationKind]uint64),
+		lastPos:    make(map[int][2]float64),
+		allocs:     allocs.NewTracker(),
 	}
 }
 
@@ -100,6 +261,9 @@ func (s *Server) SetWorld(w *game.World) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.world = w
+	for _, session := range s.sessions {
+		w.SetPlayerColor(session.PlayerID, session.Color)
+	}
 }
 
 // World returns the server's game world
@@ -109,6 +273,13 @@ func (s *Server) World() *game.World {
 	return s.world
 }
 
+// Allocs returns the tracker counting the server's allocations in the
+// simulation and network subsystems. It is disabled until enabled; an
+// embedded client shares it for its own.
+func (s *Server) Allocs() *allocs.Tracker {
+	return s.allocs
+}
+
 // SetStateUpdateCallback sets a callback for state updates (embedded mode)
 func (s *Server) SetStateUpdateCallback(cb func(state game.WorldState)) {
 	s.mu.Lock()
@@ -116,37 +287,237 @@ func (s *Server) SetStateUpdateCallback(cb func(state game.WorldState)) {
 	s.onStateUpdate = cb
 }
 
+// SetSnapshotCallback sets the sender for per-session network snapshots
+func (s *Server) SetSnapshotCallback(cb func(sessionID int, snap protocol.StateSnapshot)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onSnapshot = cb
+}
+
+// SetInputObserver sets a callback shown every input as it is applied,
+// with the tick it runs on, for crash reports. It runs under the server
+// lock and must not call back into the server.
+func (s *Server) SetInputObserver(cb func(tick uint64, playerID int, intents protocol.Intent)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onInput = cb
+}
+
+// SetTickObserver sets a callback shown every tick once it has run, with
+// how long it took, for load tests. It runs on the tick loop's goroutine
+// before the tick's snapshots are sent, so it must return quickly.
+func (s *Server) SetTickObserver(cb func(tick uint64, took time.Duration)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onTick = cb
+}
+
+// SetPanicHandler sets a callback for a panic in the tick loop started by
+// Start, with the panicking goroutine's stack; it is expected not to
+// return. The server lock is released first, so the handler can still read
+// the world. Without a handler the panic crashes the process as usual.
+func (s *Server) SetPanicHandler(cb func(p any, stack []byte)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onPanic = cb
+}
+
 // AddSession adds a new session for a connected client
 func (s *Server) AddSession(sessionID int, playerID int, name string) *Session {
+	return s.addSession(sessionID, playerID, name, -1)
+}
+
+// addSession adds a session with the given color slot if it is free, else
+// the lowest free one
+func (s *Server) addSession(sessionID int, playerID int, name string, slot int) *Session {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if slot < 0 || s.colorSlotUsed(slot) {
+		slot = s.freeColorSlot()
+	}
 	session := &Session{
 		ID:         sessionID,
 		PlayerID:   playerID,
 		Name:       name,
 		InputQueue: make([]protocol.InputFrame, 0, 16),
+		Interest:   s.config.Interest,
+		Color:      protocol.PlayerColor(slot),
+		colorSlot:  slot,
+		rate:       newSendRate(s.config),
 	}
 	s.sessions[sessionID] = session
+	s.rosterVersion++
+	s.electHost()
+	if s.world != nil {
+		s.world.SetPlayerColor(playerID, session.Color)
+	}
 	return session
 }
 
+// colorSlotUsed reports whether a session has the palette slot
+func (s *Server) colorSlotUsed(slot int) bool {
+	for _, session := range s.sessions {
+		if session.colorSlot == slot {
+			return true
+		}
+	}
+	return false
+}
+
+// freeColorSlot returns the lowest palette slot no session is using
+func (s *Server) freeColorSlot() int {
+	used := make(map[int]bool, len(s.sessions))
+	for _, session := range s.sessions {
+		used[session.colorSlot] = true
+	}
+	slot := 0
+	for used[slot] {
+		slot++
+	}
+	return slot
+}
+
+// Join negotiates the protocol version from a client handshake and adds a
+// session on success. The reply should be sent to the client either way.
+// After Resume, a player rejoining under a saved name gets their old player
+// ID, whose entity is already in the world, instead of playerID, and their
+// old color if it is free. A client whose level hash differs from the
+// server's gets NeedsLevel set, or is rejected if Config.SendLevel is off.
+// A client with a different physics profile, or another build than
+// Config.Build, is rejected. The player's aim assist is taken from the
+// handshake.
+func (s *Server) Join(sessionID int, playerID int, h protocol.Handshake) (*Session, protocol.HandshakeReply) {
+	if s.Draining() {
+		return nil, protocol.HandshakeReply{
+			Version: protocol.ProtocolVersion,
+			Reason:  ReasonShutdown,
+		}
+	}
+	version, err := protocol.Negotiate(h)
+	if err != nil {
+		return nil, protocol.HandshakeReply{
+			Version: protocol.ProtocolVersion,
+			Reason:  err.Error(),
+		}
+	}
+
+	if err := s.checkBuild(h.Build); err != nil {
+		return nil, protocol.HandshakeReply{
+			Version: protocol.ProtocolVersion,
+			Reason:  err.Error(),
+		}
+	}
+
+	needsLevel, err := s.checkLevel(h.LevelHash)
+	if err == nil {
+		err = s.checkPhysics(h.PhysicsHash)
+	}
+	if err != nil {
+		return nil, protocol.HandshakeReply{
+			Version: protocol.ProtocolVersion,
+			Reason:  err.Error(),
+		}
+	}
+
+	slot := -1
+	if p, ok := s.claimResumed(h.PlayerName); ok {
+		playerID, slot = p.id, p.colorSlot // Already in the world
+	}
+	session := s.addSession(sessionID, playerID, h.PlayerName, slot)
+	s.SetAimAssist(playerID, game.AimAssist(h.AimAssist))
+	session.Version = version
+	session.NeedsLevel = needsLevel
+	return session, protocol.HandshakeReply{
+		Accepted: true,
+		Version:  version,
+		PlayerID: playerID,
+		Color:    session.Color,
+	}
+}
+
 // RemoveSession removes a session
 func (s *Server) RemoveSession(sessionID int) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if session, ok := s.sessions[sessionID]; ok && s.world != nil {
+		s.world.SetSpectating(session.PlayerID, false)
+	}
 	delete(s.sessions, sessionID)
+	s.rosterVersion++
+	s.electHost()
+}
+
+// Roster returns the connected players with their names and colors, by
+// player ID
+func (s *Server) Roster() []protocol.PlayerInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.roster()
 }
 
-// QueueInput adds an input to a session's queue
+func (s *Server) roster() []protocol.PlayerInfo {
+	roster := make([]protocol.PlayerInfo, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		roster = append(roster, protocol.PlayerInfo{PlayerID: session.PlayerID, Name: session.Name, Color: session.Color, AFK: session.afk})
+	}
+	sort.Slice(roster, func(i, j int) bool { return roster[i].PlayerID < roster[j].PlayerID })
+	return roster
+}
+
+// QueueInput adds an input to a session's queue, after the anti-cheat
+// checks
 func (s *Server) QueueInput(sessionID int, frame protocol.InputFrame) {
+	s.QueueInputs(sessionID, protocol.InputMessage{Frames: []protocol.InputFrame{frame}})
+}
+
+// QueueInputs adds a redundant input message to a session's queue, after
+// the anti-cheat checks
+func (s *Server) QueueInputs(sessionID int, msg protocol.InputMessage) {
+	s.mu.RLock()
+	session, ok := s.sessions[sessionID]
+	tick := s.tick
+	s.mu.RUnlock()
+
+	if !ok {
+		return
+	}
+
+	var flagged []Violation
+	flag := func(kind ViolationKind, detail string) {
+		flagged = append(flagged, Violation{SessionID: sessionID, PlayerID: session.PlayerID, Tick: tick, Kind: kind, Detail: detail})
+	}
+	if n := len(msg.Frames); n > s.config.AntiCheat.MaxFrames {
+		flag(ViolationInputFlood, fmt.Sprintf("%d frames in one message", n))
+		msg.Frames = msg.Frames[n-s.config.AntiCheat.MaxFrames:]
+	}
+	frames := make([]protocol.InputFrame, 0, len(msg.Frames))
+	for _, frame := range msg.Frames {
+		kind, detail := s.checkFrame(&frame, tick)
+		if kind != "" {
+			flag(kind, detail)
+		}
+		if kind != ViolationInputAhead {
+			frames = append(frames, frame)
+		}
+	}
+	session.QueueInputs(protocol.InputMessage{Frames: frames})
+
+	for _, v := range flagged {
+		s.report(v)
+	}
+}
+
+// InputAck returns the acknowledgement for a session's inputs
+func (s *Server) InputAck(sessionID int) (protocol.InputAck, bool) {
 	s.mu.RLock()
 	session, ok := s.sessions[sessionID]
 	s.mu.RUnlock()
 
-	if ok {
-		session.QueueInput(frame)
+	if !ok {
+		return protocol.InputAck{}, false
 	}
+	return session.Ack(), true
 }
 
 // Start begins the server tick loop
@@ -178,16 +549,13 @@ func (s *Server) StartBlocking() error {
 
 func (s *Server) runTickLoop() {
 	defer close(s.doneCh)
+	defer s.recoverPanic()
 
 	tickDuration := time.Second / time.Duration(s.config.TickRate)
 	ticker := time.NewTicker(tickDuration)
 	defer ticker.Stop()
 
-	// Sync rate for state broadcasts
-	syncInterval := s.config.TickRate / s.config.SyncRate
-	if syncInterval < 1 {
-		syncInterval = 1
-	}
+	syncInterval := s.syncInterval()
 	ticksSinceSync := 0
 
 	for {
@@ -195,19 +563,79 @@ func (s *Server) runTickLoop() {
 		case <-s.quitCh:
 			return
 		case <-ticker.C:
-			s.processTick()
-
-			// Broadcast state at sync rate
-			ticksSinceSync++
-			if ticksSinceSync >= syncInterval {
-				ticksSinceSync = 0
-				s.broadcastState()
+			// Paused or slowed time runs fewer simulation ticks than real
+			// ones. Tick numbers still advance one at a time, so clients see
+			// the same contiguous sequence at any speed.
+			for range s.time.Advance() {
+				s.processTick()
+
+				// Broadcast state at sync rate
+				ticksSinceSync++
+				if ticksSinceSync >= syncInterval {
+					ticksSinceSync = 0
+					s.broadcastState()
+				}
 			}
+
+			// Network sessions each run on their own adaptive schedule
+			s.sendSnapshots()
 		}
 	}
 }
 
+// recoverPanic hands a panic in the tick loop to the panic handler, if set
+func (s *Server) recoverPanic() {
+	s.mu.RLock()
+	handler := s.onPanic
+	s.mu.RUnlock()
+	if handler == nil {
+		return
+	}
+	if p := recover(); p != nil {
+		handler(p, debug.Stack())
+	}
+}
+
+// syncInterval returns how many ticks apart state broadcasts are
+func (s *Server) syncInterval() int {
+	return max(s.config.TickRate/max(s.config.SyncRate, 1), 1)
+}
+
+// Step runs one simulation tick on the caller's goroutine, broadcasts
+// state at the sync rate and sends snapshots to the sessions due one. It is for an embedded server driven by the
+// client's own fixed-timestep loop instead of Start: pacing, and honoring
+// TimeControl, are then up to the caller.
+func (s *Server) Step() {
+	s.processTick()
+	if s.Tick()%uint64(s.syncInterval()) == 0 {
+		s.broadcastState()
+	}
+	s.sendSnapshots()
+}
+
 func (s *Server) processTick() {
+	start := time.Now()
+	span := s.allocs.Begin(allocs.Simulation)
+	violations := s.simulate()
+	span.End()
+	for _, v := range violations {
+		s.report(v)
+	}
+	s.tickVote()
+	s.tickAFK()
+
+	s.mu.RLock()
+	observe, tick := s.onTick, s.tick
+	s.mu.RUnlock()
+	if observe != nil {
+		observe(tick, time.Since(start))
+	}
+}
+
+// simulate applies inputs and runs one tick under the lock, returning the
+// anti-cheat violations to report. The deferred unlock also runs on a
+// panic, so a panic handler isn't left waiting on the lock.
+func (s *Server) simulate() []Violation {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -217,26 +645,122 @@ func (s *Server) processTick() {
 		for _, input := range inputs {
 			// Apply input to player entity
 			s.world.SetPlayerIntent(session.PlayerID, input.Intents)
+			session.moved = session.moved || input.Intents != 0
+			if s.onInput != nil {
+				s.onInput(s.tick+1, session.PlayerID, input.Intents)
+			}
 		}
 	}
 
 	// Run game simulation
 	s.world.Update()
 	s.tick = s.world.Tick
+
+	if s.match != nil {
+		s.match.tick()
+	}
+	return s.checkMovement()
 }
 
 func (s *Server) broadcastState() {
 	s.mu.RLock()
-	state := s.world.Snapshot()
 	callback := s.onStateUpdate
+	if callback == nil {
+		s.mu.RUnlock()
+		return
+	}
+	span := s.allocs.Begin(allocs.Network)
+	state := s.world.Snapshot()
+	span.End()
 	s.mu.RUnlock()
 
 	// For embedded mode, call the callback directly
-	if callback != nil {
-		callback(state)
+	callback(state)
+}
+
+// sendSnapshots sends network snapshots to every session that is due one.
+// Each session is filtered to its area of interest and paced by its own
+// send rate, so a slow client gets fewer snapshots instead of a backlog.
+func (s *Server) sendSnapshots() {
+	s.mu.Lock()
+	send := s.onSnapshot
+	if send == nil || len(s.sessions) == 0 {
+		s.mu.Unlock()
+		return
+	}
+	span := s.allocs.Begin(allocs.Network)
+
+	var state *game.WorldState
+	snaps := make(map[int]protocol.StateSnapshot)
+	for id, session := range s.sessions {
+		due, full := session.snapshotDue(s.tick, s.config.Rate)
+		if session.reset {
+			due, full = true, true
+		}
+		// A vote overlay follows the vote right away: waiting for the
+		// next scheduled snapshot would leave a cleared vote showing
+		if s.voteVersion != session.voteSent {
+			due = true
+		}
+		if !due {
+			continue
+		}
+		if state == nil {
+			ws := s.world.Snapshot()
+			state = &ws
+		}
+		snap := s.snapshotFor(session, state, full)
+		snap.Reset, session.reset = session.reset, false
+		s.addPlayerInfo(session, &snap, state)
+		session.mu.Lock()
+		session.net.AddSnapshot(&snap)
+		session.mu.Unlock()
+		snaps[id] = snap
 	}
+	s.mu.Unlock()
+	span.End() // Not the sender's work
 
-	// TODO: For network mode, serialize and send to all sessions
+	for id, snap := range snaps {
+		send(id, snap)
+	}
+}
+
+// addPlayerInfo attaches the roster, player stats, host settings, vote and
+// level state when they changed since the session's last snapshot, and the
+// match result once the match is over
+func (s *Server) addPlayerInfo(session *Session, snap *protocol.StateSnapshot, state *game.WorldState) {
+	if snap.Full || s.rosterVersion != session.rosterSent {
+		snap.Players = s.roster()
+		session.rosterSent = s.rosterVersion
+	}
+	if snap.Full || state.StatsVersion != session.statsSent {
+		snap.Stats = state.Stats
+		session.statsSent = state.StatsVersion
+	}
+	if snap.Full || s.settingsVersion != session.settingsSent {
+		settings := s.settings()
+		snap.Settings = &settings
+		session.settingsSent = s.settingsVersion
+	}
+	if (snap.Full && s.vote != nil) || s.voteVersion != session.voteSent {
+		status := s.voteStatus()
+		snap.Vote = &status
+		session.voteSent = s.voteVersion
+	}
+	if snap.Full || state.LevelVersion != session.levelSent {
+		snap.Level = &protocol.LevelState{Broken: state.Broken, Gates: state.Gates, Keys: state.Keys}
+		session.levelSent = state.LevelVersion
+	}
+
+	if s.match == nil || !s.match.Ended {
+		session.resultSent = false
+		return
+	}
+	if snap.Full || !session.resultSent {
+		result := s.match.Result
+		snap.Result = &result
+		session.resultSent = true
+	}
 }
 
 // Stop gracefully shuts down the server
@@ -253,6 +777,13 @@ func (s *Server) Stop() {
 	<-s.doneCh
 }
 
+// SessionCount returns the number of connected sessions
+func (s *Server) SessionCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.sessions)
+}
+
 // Tick returns the current tick number
 func (s *Server) Tick() uint64 {
 	s.mu.RLock()
@@ -260,6 +791,26 @@ func (s *Server) Tick() uint64 {
 	return s.tick
 }
 
+// ResetLevel restarts the world on the given level, or the current one if
+// nil. Players keep their IDs and sessions; every session's next snapshot is
+// a full one flagged Reset so clients drop their prediction buffers.
+func (s *Server) ResetLevel(level *game.Level) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.world.Reset(level)
+	s.applySettings() // The reset started a new match with the mode's rules
+	clear(s.lastPos)  // Players are back at the spawn points
+	for _, session := range s.sessions {
+		session.reset = true
+	}
+}
+
+// TimeControl returns the simulation's pause/step/speed controls
+func (s *Server) TimeControl() *TimeControl {
+	return &s.time
+}
+
 // IsRunning returns whether the server is running
 func (s *Server) IsRunning() bool {
 	s.mu.RLock()
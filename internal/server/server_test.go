@@ -0,0 +1,66 @@
+package server
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// TestSessionInputs tests that redundant and late input frames are dropped,
+// the newest copy of a frame wins, and the ack reports ticks simulated
+// without input.
+func TestSessionInputs(t *testing.T) {
+	frames := func(ticks ...uint64) protocol.InputMessage {
+		var msg protocol.InputMessage
+		for _, tick := range ticks {
+			msg.Frames = append(msg.Frames, protocol.InputFrame{Tick: tick, Intents: protocol.IntentRight})
+		}
+		return msg
+	}
+	ticks := func(in []protocol.InputFrame) []uint64 {
+		var out []uint64
+		for _, f := range in {
+			out = append(out, f.Tick)
+		}
+		return out
+	}
+
+	s := &Session{}
+	s.DrainInputs(1)
+	if ack := s.Ack(); ack.Missed != 0 {
+		t.Errorf("Missed = %d before any input arrived, want 0", ack.Missed)
+	}
+
+	s.QueueInputs(frames(1, 2, 3))
+	s.QueueInputs(frames(2, 3, 4))
+	s.QueueInput(protocol.InputFrame{Tick: 4, Intents: protocol.IntentJump})
+	if got := ticks(s.InputQueue); !slices.Equal(got, []uint64{1, 2, 3, 4}) {
+		t.Fatalf("Queued ticks %v, want each once", got)
+	}
+	if s.InputQueue[3].Intents != protocol.IntentJump {
+		t.Errorf("Tick 4 has intents %v, want the newest copy", s.InputQueue[3].Intents)
+	}
+
+	if got := ticks(s.DrainInputs(3)); !slices.Equal(got, []uint64{1, 2, 3}) {
+		t.Errorf("Drained ticks %v, want 1 to 3", got)
+	}
+	s.QueueInputs(frames(2, 3))
+	if got := ticks(s.DrainInputs(4)); !slices.Equal(got, []uint64{4}) {
+		t.Errorf("Drained ticks %v, want 4 without the simulated ones", got)
+	}
+
+	// Nothing arrives for ticks 5 and 6
+	s.DrainInputs(5)
+	s.DrainInputs(6)
+	if ack := s.Ack(); ack != (protocol.InputAck{LastProcessedTick: 4, Missed: 2}) {
+		t.Errorf("Ack = %+v, want tick 4 with 2 missed", ack)
+	}
+	s.QueueInputs(frames(5, 6, 7))
+	if got := ticks(s.DrainInputs(7)); !slices.Equal(got, []uint64{5, 6, 7}) {
+		t.Errorf("Drained ticks %v, want the late frames too", got)
+	}
+	if ack := s.Ack(); ack.LastProcessedTick != 7 {
+		t.Errorf("Ack = %+v, want tick 7", ack)
+	}
+}
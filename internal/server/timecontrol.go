@@ -0,0 +1,148 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Time scale limits
+const (
+	MinTimeScale = 0.25
+	MaxTimeScale = 4.0
+)
+
+// TimeControl paces the simulation relative to the real tick clock. It can
+// pause, single-step and scale time. The simulation always advances in whole
+// ticks, so tick numbers stay contiguous for clients at any speed.
+//
+// The zero value runs at normal speed.
+type TimeControl struct {
+	mu     sync.Mutex
+	paused bool
+	scale  float64 // 0 means 1.0
+	steps  int     // Ticks to run while paused
+	accum  float64 // Fractional ticks owed
+}
+
+// Advance is called once per real tick and returns how many simulation
+// ticks to run now
+func (t *TimeControl) Advance() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.paused {
+		if t.steps > 0 {
+			t.steps--
+			return 1
+		}
+		return 0
+	}
+
+	t.accum += t.scaleLocked()
+	n := int(t.accum)
+	t.accum -= float64(n)
+	return n
+}
+
+// Pause stops the simulation
+func (t *TimeControl) Pause() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.paused = true
+	t.accum = 0
+}
+
+// Resume continues the simulation and drops pending steps
+func (t *TimeControl) Resume() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.paused = false
+	t.steps = 0
+}
+
+// TogglePause pauses or resumes and returns the new paused state
+func (t *TimeControl) TogglePause() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.paused = !t.paused
+	t.steps = 0
+	t.accum = 0
+	return t.paused
+}
+
+// Paused reports whether the simulation is paused
+func (t *TimeControl) Paused() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.paused
+}
+
+// Idle reports whether Advance will run no ticks until the simulation is
+// resumed or stepped
+func (t *TimeControl) Idle() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.paused && t.steps == 0
+}
+
+// Step pauses the simulation and queues n ticks, run one per real tick
+func (t *TimeControl) Step(n int) {
+	if n < 1 {
+		n = 1
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.paused = true
+	t.steps += n
+}
+
+// SetScale sets the speed multiplier, within [MinTimeScale, MaxTimeScale]
+func (t *TimeControl) SetScale(scale float64) error {
+	if scale < MinTimeScale || scale > MaxTimeScale {
+		return fmt.Errorf("time scale %g out of range [%g, %g]", scale, MinTimeScale, MaxTimeScale)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.scale = scale
+	return nil
+}
+
+// Scale returns the speed multiplier
+func (t *TimeControl) Scale() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.scaleLocked()
+}
+
+func (t *TimeControl) scaleLocked() float64 {
+	if t.scale == 0 {
+		return 1
+	}
+	return t.scale
+}
+
+// Faster doubles the speed, up to MaxTimeScale, and returns the new scale
+func (t *TimeControl) Faster() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.scale = min(t.scaleLocked()*2, MaxTimeScale)
+	return t.scale
+}
+
+// Slower halves the speed, down to MinTimeScale, and returns the new scale
+func (t *TimeControl) Slower() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.scale = max(t.scaleLocked()/2, MinTimeScale)
+	return t.scale
+}
+
+// String describes the state for HUDs and the admin console
+func (t *TimeControl) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.paused {
+		return "paused"
+	}
+	return fmt.Sprintf("x%g", t.scaleLocked())
+}
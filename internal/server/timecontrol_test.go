@@ -0,0 +1,89 @@
+package server
+
+import "testing"
+
+// TestTimeControlScale tests that scaled time runs whole ticks at the
+// average rate of the scale.
+func TestTimeControlScale(t *testing.T) {
+	tests := []struct {
+		scale float64
+		want  int // Ticks over 100 real ticks
+	}{
+		{1, 100},
+		{0.25, 25},
+		{0.5, 50},
+		{1.5, 150},
+		{4, 400},
+	}
+	for _, tc := range tests {
+		var ctl TimeControl
+		if err := ctl.SetScale(tc.scale); err != nil {
+			t.Fatal(err)
+		}
+		total := 0
+		for range 100 {
+			n := ctl.Advance()
+			if n > int(tc.scale)+1 {
+				t.Fatalf("x%g ran %d ticks at once", tc.scale, n)
+			}
+			total += n
+		}
+		if total != tc.want {
+			t.Errorf("x%g ran %d ticks in 100, want %d", tc.scale, total, tc.want)
+		}
+	}
+
+	var ctl TimeControl
+	for _, bad := range []float64{0, 0.1, 5} {
+		if err := ctl.SetScale(bad); err == nil {
+			t.Errorf("SetScale(%g) should fail", bad)
+		}
+	}
+	if got := ctl.Scale(); got != 1 {
+		t.Errorf("Scale = %g after bad values, want 1", got)
+	}
+	for _, want := range []float64{2, 4, 4} {
+		if got := ctl.Faster(); got != want {
+			t.Errorf("Faster = %g, want %g", got, want)
+		}
+	}
+	for _, want := range []float64{2, 1, 0.5, 0.25, 0.25} {
+		if got := ctl.Slower(); got != want {
+			t.Errorf("Slower = %g, want %g", got, want)
+		}
+	}
+}
+
+// TestTimeControlPause tests pausing, single-stepping and resuming.
+func TestTimeControlPause(t *testing.T) {
+	var ctl TimeControl
+	ctl.SetScale(0.5)
+	ctl.Advance() // Half a tick owed
+
+	ctl.Pause()
+	if ctl.Advance() != 0 || !ctl.Idle() || ctl.String() != "paused" {
+		t.Fatalf("Paused control ran ticks or isn't idle (%s)", &ctl)
+	}
+
+	ctl.Step(2)
+	var ran []int
+	for range 4 {
+		ran = append(ran, ctl.Advance())
+	}
+	if ran[0] != 1 || ran[1] != 1 || ran[2] != 0 || ran[3] != 0 || !ctl.Paused() {
+		t.Errorf("Stepping 2 ran %v, want one tick per real tick, then paused", ran)
+	}
+
+	ctl.Step(0) // At least one
+	ctl.Resume()
+	if ctl.Paused() || ctl.Advance() != 0 || ctl.Advance() != 1 {
+		t.Errorf("Resumed control should drop pending steps and the owed half tick")
+	}
+	if ctl.String() != "x0.5" {
+		t.Errorf("String = %q, want x0.5", &ctl)
+	}
+
+	if !ctl.TogglePause() || ctl.TogglePause() {
+		t.Error("TogglePause should pause, then resume")
+	}
+}
@@ -0,0 +1,385 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// ReasonVoteKicked is the disconnect reason for a player voted out
+const ReasonVoteKicked = "kicked by vote"
+
+// VoteShowTicks is how long a finished vote stays on the overlay
+const VoteShowTicks = 3 * 60
+
+// VoteConfig tunes voting, which servers without a host use instead of host
+// controls. A vote passes once more than the required share of the players
+// vote yes, and fails once it can't or when time runs out.
+type VoteConfig struct {
+	Duration       uint64  // Ticks a vote stays open; 0 turns voting off
+	Cooldown       uint64  // Ticks before the same player may call another vote
+	Pass           float64 // Share of the players that must vote yes, more than
+	KickPass       float64 // The same for kicks; the player voted on doesn't vote
+	KickMinPlayers int     // Players connected, counting the one voted on, to vote a kick
+}
+
+// DefaultVoteConfig returns 30 second votes passed by a majority, kicks by
+// more than 60% of at least three players, and a minute between a player's
+// votes
+func DefaultVoteConfig() VoteConfig {
+	return VoteConfig{
+		Duration:       30 * 60,
+		Cooldown:       60 * 60,
+		Pass:           0.5,
+		KickPass:       0.6,
+		KickMinPlayers: 3,
+	}
+}
+
+// vote is the vote in progress, or the one that just ended
+type vote struct {
+	status  protocol.VoteStatus
+	target  int          // Player ID, for a kick
+	ballots map[int]bool // Yes or no by player ID
+	clearAt uint64       // Tick the ended vote leaves the overlay
+}
+
+// SetMaps sets the map files players may vote to change to, by name: the
+// file name without .json
+func (s *Server) SetMaps(paths []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maps = make(map[string]string, len(paths))
+	for _, path := range paths {
+		s.maps[strings.TrimSuffix(filepath.Base(path), ".json")] = path
+	}
+}
+
+// Maps returns the names of the maps players may vote for, sorted
+func (s *Server) Maps() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.maps))
+	for name := range s.maps {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// SetMapChangeCallback sets the function that sends a session the join
+// bundle of a new map, for its client to build the new world from
+func (s *Server) SetMapChangeCallback(cb func(sessionID int, b protocol.JoinBundle)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onMapChange = cb
+}
+
+// ChangeMap loads one of the maps from SetMaps and restarts on it. Players
+// keep their sessions; each gets a join bundle with the new level (or only
+// its hash without Config.SendLevel) through SetMapChangeCallback.
+func (s *Server) ChangeMap(name string) error {
+	s.mu.RLock()
+	path, ok := s.maps[name]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("no map %q", name)
+	}
+	level, err := game.ReadLevelFile(os.DirFS(filepath.Dir(path)), filepath.Base(path))
+	if err != nil {
+		return fmt.Errorf("map %s: %w", name, err)
+	}
+	s.ResetLevel(level)
+
+	s.mu.Lock()
+	s.config.MapPath = path
+	send := s.onMapChange
+	ids := make([]int, 0, len(s.sessions))
+	for id := range s.sessions {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+	if send == nil {
+		return nil
+	}
+	b, err := s.JoinBundle(s.config.SendLevel)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		send(id, b)
+	}
+	return nil
+}
+
+// CallVote starts a vote called by a session's player, who votes yes. The
+// subject is the player to kick (name or ID) or the map. Only one vote runs
+// at a time, and a player must wait VoteConfig.Cooldown between votes.
+func (s *Server) CallVote(sessionID int, kind protocol.VoteKind, subject string) error {
+	s.mu.Lock()
+	caller, err := s.newVote(sessionID, kind, subject)
+	var summary string
+	if err == nil {
+		summary = s.describeVote()
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	s.broadcastChat(protocol.Chat{Text: fmt.Sprintf("%s called a vote to %s (/yes or /no)", caller, summary)})
+	s.decideVote()
+	return nil
+}
+
+// newVote checks and opens a vote, returning the caller's name. Called with
+// s.mu held.
+func (s *Server) newVote(sessionID int, kind protocol.VoteKind, subject string) (string, error) {
+	cfg := s.config.Votes
+	session, ok := s.sessions[sessionID]
+	switch {
+	case s.config.HostControls:
+		return "", fmt.Errorf("this game has a host, who decides")
+	case cfg.Duration == 0:
+		return "", fmt.Errorf("voting is off on this server")
+	case !ok:
+		return "", fmt.Errorf("not connected")
+	case s.vote != nil && s.vote.status.Result == protocol.VoteOpen:
+		return "", fmt.Errorf("a vote is already open: %s", s.describeVote())
+	}
+	if last, ok := s.voteCalled[session.PlayerID]; ok && s.tick < last+cfg.Cooldown {
+		return "", fmt.Errorf("wait %d s to call another vote", (last+cfg.Cooldown-s.tick+59)/60)
+	}
+
+	v := &vote{
+		status:  protocol.VoteStatus{Kind: kind, Caller: session.PlayerID, Ends: s.tick + cfg.Duration},
+		ballots: map[int]bool{session.PlayerID: true},
+	}
+	switch kind {
+	case protocol.VoteKick:
+		target, ok := s.findPlayer(subject)
+		switch {
+		case !ok:
+			return "", fmt.Errorf("no player %q", subject)
+		case target.PlayerID == session.PlayerID:
+			return "", fmt.Errorf("you can't vote to kick yourself")
+		case len(s.sessions) < cfg.KickMinPlayers:
+			return "", fmt.Errorf("a kick vote needs %d players", cfg.KickMinPlayers)
+		}
+		v.target = target.PlayerID
+		v.status.Subject = target.Name
+	case protocol.VoteMap:
+		if _, ok := s.maps[subject]; !ok {
+			return "", fmt.Errorf("no map %q", subject)
+		}
+		v.status.Subject = subject
+	case protocol.VoteRestart:
+		if s.world == nil || s.world.Level() == nil {
+			return "", fmt.Errorf("no level loaded")
+		}
+	default:
+		return "", fmt.Errorf("unknown vote %s", kind)
+	}
+	if s.voteCalled == nil {
+		s.voteCalled = make(map[int]uint64)
+	}
+	s.voteCalled[session.PlayerID] = s.tick
+	s.vote = v
+	s.voteVersion++
+	s.tally()
+	return session.Name, nil
+}
+
+// findPlayer finds a connected player by ID or name. Called with s.mu held.
+func (s *Server) findPlayer(nameOrID string) (*Session, bool) {
+	id, err := strconv.Atoi(nameOrID)
+	for _, session := range s.sessions {
+		if (err == nil && session.PlayerID == id) || strings.EqualFold(session.Name, nameOrID) {
+			return session, true
+		}
+	}
+	return nil, false
+}
+
+// CastVote records a session's player's yes or no on the open vote; a
+// player may change their mind until it closes
+func (s *Server) CastVote(sessionID int, yes bool) error {
+	s.mu.Lock()
+	session, ok := s.sessions[sessionID]
+	switch {
+	case !ok:
+		s.mu.Unlock()
+		return fmt.Errorf("not connected")
+	case s.vote == nil || s.vote.status.Result != protocol.VoteOpen:
+		s.mu.Unlock()
+		return fmt.Errorf("no vote is open")
+	case session.PlayerID == s.vote.target:
+		s.mu.Unlock()
+		return fmt.Errorf("you can't vote on your own kick")
+	}
+	s.vote.ballots[session.PlayerID] = yes
+	s.mu.Unlock()
+	s.decideVote()
+	return nil
+}
+
+// decideVote counts the open vote and, once it is decided, announces the
+// result and carries it out. It runs after every ballot and every tick, so
+// players who leave stop counting.
+func (s *Server) decideVote() {
+	s.mu.Lock()
+	v := s.vote
+	if v == nil || v.status.Result != protocol.VoteOpen {
+		s.mu.Unlock()
+		return
+	}
+	voters, targetHere := s.tally()
+	status := v.status
+	yes, no, needed := int(status.Yes), int(status.No), int(status.Needed)
+	reason := ""
+	switch {
+	case status.Kind == protocol.VoteKick && !targetHere:
+		status.Result, reason = protocol.VoteFailed, status.Subject+" left"
+	case voters > 0 && yes >= needed:
+		status.Result = protocol.VotePassed
+	case voters == 0 || no > voters-needed:
+		status.Result, reason = protocol.VoteFailed, "not enough yes votes"
+	case s.tick >= status.Ends:
+		status.Result, reason = protocol.VoteFailed, "time ran out"
+	}
+	if status.Result == protocol.VoteOpen {
+		s.mu.Unlock()
+		return
+	}
+	v.status = status
+	v.clearAt = s.tick + VoteShowTicks
+	s.voteVersion++
+	summary := s.describeVote()
+	target := v.target
+	s.mu.Unlock()
+
+	if status.Result == protocol.VoteFailed {
+		s.broadcastChat(protocol.Chat{Text: fmt.Sprintf("Vote failed (%s): %s", reason, summary)})
+		return
+	}
+	s.broadcastChat(protocol.Chat{Text: "Vote passed: " + summary})
+	var err error
+	switch status.Kind {
+	case protocol.VoteKick:
+		if session, ok := s.sessionOf(target); ok {
+			s.Kick(session, ReasonVoteKicked)
+		}
+	case protocol.VoteMap:
+		err = s.ChangeMap(status.Subject)
+	case protocol.VoteRestart:
+		s.ResetLevel(nil)
+	}
+	if err != nil {
+		s.broadcastChat(protocol.Chat{Text: "Map change failed: " + err.Error()})
+	}
+}
+
+// tally counts the open vote's ballots from the players still connected,
+// returning how many may vote and whether the player voted on is still
+// here. Called with s.mu held.
+func (s *Server) tally() (voters int, targetHere bool) {
+	v := s.vote
+	var yes, no int
+	for _, session := range s.sessions {
+		if v.status.Kind == protocol.VoteKick && session.PlayerID == v.target {
+			targetHere = true
+			continue
+		}
+		voters++
+		if ballot, ok := v.ballots[session.PlayerID]; ok {
+			if ballot {
+				yes++
+			} else {
+				no++
+			}
+		}
+	}
+	share := s.config.Votes.Pass
+	if v.status.Kind == protocol.VoteKick {
+		share = s.config.Votes.KickPass
+	}
+	needed := min(int(share*float64(voters))+1, voters)
+
+	status := v.status
+	status.Yes, status.No, status.Needed = uint8(min(yes, 255)), uint8(min(no, 255)), uint8(min(needed, 255))
+	if status != v.status {
+		v.status = status
+		s.voteVersion++
+	}
+	return voters, targetHere
+}
+
+// tickVote counts the open vote against the clock, and takes a finished
+// one off the overlay after VoteShowTicks
+func (s *Server) tickVote() {
+	s.mu.Lock()
+	v := s.vote
+	if v != nil && v.status.Result != protocol.VoteOpen && s.tick >= v.clearAt {
+		s.vote = nil
+		s.voteVersion++
+	}
+	s.mu.Unlock()
+	if v != nil && v.status.Result == protocol.VoteOpen {
+		s.decideVote()
+	}
+}
+
+// CancelVote ends the open vote without a result, for the operator
+func (s *Server) CancelVote() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.vote == nil || s.vote.status.Result != protocol.VoteOpen {
+		return false
+	}
+	s.vote = nil
+	s.voteVersion++
+	return true
+}
+
+// VoteStatus returns the vote in progress or just ended; Kind is
+// protocol.VoteNone if there is none
+func (s *Server) VoteStatus() protocol.VoteStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.voteStatus()
+}
+
+func (s *Server) voteStatus() protocol.VoteStatus {
+	if s.vote == nil {
+		return protocol.VoteStatus{}
+	}
+	return s.vote.status
+}
+
+// voteSummary describes the vote with its count, for chat and the console
+func (s *Server) voteSummary() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.vote == nil {
+		return "no vote"
+	}
+	return s.describeVote()
+}
+
+// describeVote describes the current vote. Called with s.mu held.
+func (s *Server) describeVote() string {
+	st := s.vote.status
+	what := "restart the level"
+	switch st.Kind {
+	case protocol.VoteKick:
+		what = "kick " + st.Subject
+	case protocol.VoteMap:
+		what = "change the map to " + st.Subject
+	}
+	return fmt.Sprintf("%s, %d yes and %d no of %d needed", what, st.Yes, st.No, st.Needed)
+}
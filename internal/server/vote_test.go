@@ -0,0 +1,118 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+)
+
+// TestVotes tests that votes pass by their thresholds, fail when time runs
+// out, respect the cooldown and reach clients through chat and snapshots.
+func TestVotes(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Votes.Duration = 10
+	srv := New(cfg)
+	world := game.NewWorld()
+	world.LoadLevel(game.NewDemoLevel(40, 20))
+	srv.SetWorld(world)
+	srv.AddSession(1, 1, "Alice")
+	srv.AddSession(2, 2, "Bob")
+	srv.AddSession(3, 3, "Carol")
+
+	chat := make(map[int][]protocol.Chat)
+	srv.SetChatCallback(func(sessionID int, msg protocol.Chat) {
+		chat[sessionID] = append(chat[sessionID], msg)
+	})
+	var kicked []protocol.Disconnect
+	srv.SetDisconnectCallback(func(sessionID int, msg protocol.Disconnect) {
+		if sessionID != 3 {
+			t.Errorf("Kicked session %d, want 3", sessionID)
+		}
+		kicked = append(kicked, msg)
+	})
+
+	srv.Chat(1, "hello\x07 ")
+	if got := chat[3]; len(got) != 1 || got[0] != (protocol.Chat{PlayerID: 1, Text: "hello"}) {
+		t.Fatalf("Relayed chat = %+v", got)
+	}
+
+	// A restart needs 2 of 3: the caller and one more
+	srv.Chat(1, "/voterestart")
+	if st := srv.VoteStatus(); st.Kind != protocol.VoteRestart || st.Yes != 1 || st.Needed != 2 {
+		t.Fatalf("Vote = %+v, want a restart with 1 of 2 yes", st)
+	}
+	if err := srv.CastVote(2, true); err != nil {
+		t.Fatal(err)
+	}
+	if st := srv.VoteStatus(); st.Result != protocol.VotePassed {
+		t.Fatalf("Vote = %+v, want passed", st)
+	}
+	if last := chat[2][len(chat[2])-1]; last.PlayerID != 0 || !strings.HasPrefix(last.Text, "Vote passed") {
+		t.Errorf("Last chat = %+v, want the server announcing the result", last)
+	}
+
+	if err := srv.CallVote(1, protocol.VoteRestart, ""); err == nil || !strings.Contains(err.Error(), "wait") {
+		t.Errorf("Calling again within the cooldown: %v", err)
+	}
+	if err := srv.CallVote(2, protocol.VoteMap, "nowhere"); err == nil {
+		t.Error("A map that isn't votable should be refused")
+	}
+	if err := srv.CallVote(2, protocol.VoteKick, "Bob"); err == nil {
+		t.Error("Voting to kick yourself should be refused")
+	}
+
+	// A vote nobody answers fails when its time is up, and leaves the
+	// overlay VoteShowTicks later
+	var snaps []protocol.StateSnapshot
+	srv.SetSnapshotCallback(func(sessionID int, snap protocol.StateSnapshot) {
+		if sessionID == 3 && snap.Vote != nil {
+			snaps = append(snaps, snap)
+		}
+	})
+	if err := srv.CallVote(2, protocol.VoteKick, "carol"); err != nil {
+		t.Fatal(err)
+	}
+	for range cfg.Votes.Duration {
+		srv.Step()
+	}
+	if st := srv.VoteStatus(); st.Result != protocol.VoteFailed {
+		t.Fatalf("Vote = %+v, want failed after its duration", st)
+	}
+	for range VoteShowTicks {
+		srv.Step()
+	}
+	if st := srv.VoteStatus(); st.Kind != protocol.VoteNone {
+		t.Errorf("Vote = %+v, want cleared", st)
+	}
+	if len(snaps) == 0 || snaps[len(snaps)-1].Vote.Kind != protocol.VoteNone {
+		t.Error("Snapshots should carry the vote and then clear it")
+	}
+
+	// A kick needs more than 60% of the two who may vote
+	if err := srv.CallVote(1, protocol.VoteKick, "3"); err == nil {
+		t.Error("Alice's cooldown should still run")
+	}
+	srv.voteCalled = nil
+	if err := srv.CallVote(1, protocol.VoteKick, "3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.CastVote(3, false); err == nil {
+		t.Error("The player voted on shouldn't vote")
+	}
+	if err := srv.CastVote(2, true); err != nil {
+		t.Fatal(err)
+	}
+	if len(kicked) != 1 || kicked[0].Reason != ReasonVoteKicked || srv.SessionCount() != 2 {
+		t.Fatalf("Kick vote should remove Carol, kicked %+v", kicked)
+	}
+
+	cfg.HostControls = true
+	hosted := New(cfg)
+	hosted.SetWorld(world)
+	hosted.AddSession(1, 1, "Host")
+	if err := hosted.CallVote(1, protocol.VoteRestart, ""); err == nil {
+		t.Error("A game with a host shouldn't vote")
+	}
+}
@@ -0,0 +1,401 @@
+// Package stress loads a server with a swarm of headless clients playing
+// scripted inputs in real time, and measures how it holds up: how steady
+// its ticks are, how long snapshots take to reach the clients and how much
+// bandwidth they use.
+package stress
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/andersfylling/rayman-slides/internal/client"
+	"github.com/andersfylling/rayman-slides/internal/game"
+	"github.com/andersfylling/rayman-slides/internal/protocol"
+	"github.com/andersfylling/rayman-slides/internal/server"
+)
+
+// Config describes a stress run
+type Config struct {
+	Clients    int
+	Duration   time.Duration
+	Script     string // Name of a script in Scripts
+	Lead       int    // Ticks clients send their input ahead of the newest snapshot
+	Redundancy int    // Input frames repeated per message
+	Queue      int    // Snapshots a client may have waiting before more are dropped
+	Seed       uint64
+
+	Server server.Config
+	Level  *game.Level     // nil plays the demo level
+	Mode   server.GameMode // nil runs the world without a game mode
+}
+
+// DefaultConfig returns a 30 second run of 16 patrolling clients on a
+// default server
+func DefaultConfig() Config {
+	return Config{
+		Clients:    16,
+		Duration:   30 * time.Second,
+		Script:     "patrol",
+		Lead:       2,
+		Redundancy: protocol.DefaultInputRedundancy,
+		Queue:      64,
+		Seed:       1,
+		Server:     server.DefaultConfig(),
+	}
+}
+
+// Script returns a client's intents on each of its ticks, counted from 0
+type Script func(tick int) protocol.Intent
+
+// Scripts build each client's script by name, from its index and a seed
+var Scripts = map[string]func(client int, seed uint64) Script{
+	"patrol": patrol,
+	"random": random,
+	"idle":   func(int, uint64) Script { return func(int) protocol.Intent { return protocol.IntentNone } },
+}
+
+// patrol runs right and back every four seconds, jumping and punching on
+// the way; clients start at different points of the loop
+func patrol(client int, _ uint64) Script {
+	return func(tick int) protocol.Intent {
+		phase := (tick + client*37) % 240
+		intents := protocol.IntentRight
+		if phase >= 120 {
+			intents = protocol.IntentLeft
+		}
+		if phase%40 < 6 {
+			intents |= protocol.IntentJump
+		}
+		if phase%60 >= 20 && phase%60 < 23 {
+			intents |= protocol.IntentAttack
+		}
+		return intents
+	}
+}
+
+// random holds random movement, jumps and attacks for up to half a second
+// each, as netsim's clients do
+func random(client int, seed uint64) Script {
+	rng := rand.New(rand.NewPCG(seed, uint64(client)))
+	choices := []protocol.Intent{
+		protocol.IntentNone,
+		protocol.IntentLeft,
+		protocol.IntentRight,
+		protocol.IntentJump,
+		protocol.IntentLeft | protocol.IntentJump,
+		protocol.IntentRight | protocol.IntentJump,
+		protocol.IntentAttack,
+	}
+	var intents protocol.Intent
+	hold := 0
+	return func(int) protocol.Intent {
+		if hold == 0 {
+			intents = choices[rng.IntN(len(choices))]
+			hold = 1 + rng.IntN(30)
+		}
+		hold--
+		return intents
+	}
+}
+
+// Percentiles summarize a distribution of durations
+type Percentiles struct {
+	P50, P95, P99, Max time.Duration
+}
+
+// percentiles sorts samples and summarizes them; none gives zeros
+func percentiles(samples []time.Duration) Percentiles {
+	if len(samples) == 0 {
+		return Percentiles{}
+	}
+	slices.Sort(samples)
+	at := func(p float64) time.Duration {
+		return samples[min(int(p*float64(len(samples))), len(samples)-1)]
+	}
+	return Percentiles{P50: at(0.50), P95: at(0.95), P99: at(0.99), Max: samples[len(samples)-1]}
+}
+
+func (p Percentiles) String() string {
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	return fmt.Sprintf("p50 %.2f ms, p95 %.2f ms, p99 %.2f ms, max %.2f ms", ms(p.P50), ms(p.P95), ms(p.P99), ms(p.Max))
+}
+
+// Report is the outcome of a stress run
+type Report struct {
+	Clients  int
+	Duration time.Duration
+	Rejected []string // Reasons clients were refused in the handshake
+
+	Ticks        uint64      // Ticks the server ran
+	MissedTicks  uint64      // Ticks it fell short of its tick rate over the run
+	TickInterval Percentiles // Real time between the ends of consecutive ticks
+	TickTime     Percentiles // Real time spent running a tick
+	Budget       time.Duration
+
+	Snapshots uint64      // Snapshots the clients handled
+	Dropped   uint64      // Snapshots dropped on a full client queue
+	Latency   Percentiles // From the end of a tick to a client handling its snapshot
+
+	BytesDown    float64 // Mean snapshot bytes per second per client
+	BytesUp      float64 // Mean input bytes per second per client
+	MaxBytesDown float64 // Snapshot bytes per second of the busiest client
+}
+
+// Thresholds are the limits a run must stay within; zero skips a check
+type Thresholds struct {
+	TickInterval time.Duration // Largest p99 tick interval
+	MissedTicks  float64       // Largest share of ticks missed
+	Latency      time.Duration // Largest p99 snapshot latency
+	Bandwidth    float64       // Most snapshot bytes per second for any client
+}
+
+// DefaultThresholds allow a tick interval half again as long as the budget
+// at 60 ticks per second, one missed tick in a hundred, 50 ms snapshot
+// latency and 16 KiB/s to a client
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		TickInterval: 25 * time.Millisecond,
+		MissedTicks:  0.01,
+		Latency:      50 * time.Millisecond,
+		Bandwidth:    16 << 10,
+	}
+}
+
+// Check returns a line for every threshold the run exceeded, and for
+// clients that could not join; none means it passed
+func (r *Report) Check(t Thresholds) []string {
+	var failed []string
+	for _, reason := range r.Rejected {
+		failed = append(failed, "client rejected: "+reason)
+	}
+	if t.TickInterval > 0 && r.TickInterval.P99 > t.TickInterval {
+		failed = append(failed, fmt.Sprintf("p99 tick interval %v over %v", r.TickInterval.P99, t.TickInterval))
+	}
+	expected := r.Ticks + r.MissedTicks
+	if t.MissedTicks > 0 && expected > 0 && float64(r.MissedTicks)/float64(expected) > t.MissedTicks {
+		failed = append(failed, fmt.Sprintf("missed %d of %d ticks, over %.1f%%", r.MissedTicks, expected, 100*t.MissedTicks))
+	}
+	if t.Latency > 0 && r.Latency.P99 > t.Latency {
+		failed = append(failed, fmt.Sprintf("p99 snapshot latency %v over %v", r.Latency.P99, t.Latency))
+	}
+	if t.Bandwidth > 0 && r.MaxBytesDown > t.Bandwidth {
+		failed = append(failed, fmt.Sprintf("busiest client gets %.0f B/s, over %.0f B/s", r.MaxBytesDown, t.Bandwidth))
+	}
+	return failed
+}
+
+// tickLog keeps when recent ticks ended, for snapshot latency. It is
+// written by the tick loop and read by every client.
+type tickLog struct {
+	mu    sync.Mutex
+	ended [1024]struct {
+		tick uint64
+		at   time.Time
+	}
+	intervals   []time.Duration
+	times       []time.Duration
+	first, last time.Time
+}
+
+func (l *tickLog) observe(tick uint64, took time.Duration) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e := &l.ended[tick%uint64(len(l.ended))]
+	e.tick, e.at = tick, now
+	if l.first.IsZero() {
+		l.first = now
+	} else {
+		l.intervals = append(l.intervals, now.Sub(l.last))
+	}
+	l.last = now
+	l.times = append(l.times, took)
+}
+
+// endOf returns when a tick ended, if it is recent enough to be kept
+func (l *tickLog) endOf(tick uint64) (time.Time, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e := l.ended[tick%uint64(len(l.ended))]
+	return e.at, e.tick == tick && !e.at.IsZero()
+}
+
+// swarmClient is one headless client. Its goroutine plays its script at
+// the tick rate and handles the snapshots the server sends it, the way the
+// network layer would pass them on.
+type swarmClient struct {
+	session *server.Session
+	sender  *client.InputSender
+	script  Script
+	snaps   chan protocol.StateSnapshot
+
+	roster     client.Roster
+	scoreboard client.Scoreboard
+	latest     uint64 // Newest snapshot tick
+	since      int    // Ticks played since it arrived
+	latency    []time.Duration
+	handled    uint64
+	dropped    uint64
+	encodeTo   []byte
+}
+
+// Run starts a server on the level with one player per client, joins the
+// clients through the handshake and lets them play for the duration
+func Run(cfg Config) (Report, error) {
+	newScript, ok := Scripts[cfg.Script]
+	if !ok {
+		return Report{}, fmt.Errorf("stress: unknown script %q", cfg.Script)
+	}
+	level := cfg.Level
+	if level == nil {
+		level = game.NewDemoLevel(80, 45)
+	}
+	world := game.NewWorld()
+	world.LoadLevel(level)
+	for i := range cfg.Clients {
+		spawn := level.PlayerSpawn(i)
+		world.SpawnPlayer(i+1, fmt.Sprintf("bot%d", i+1), spawn.X, spawn.Y)
+	}
+
+	srvCfg := cfg.Server
+	srvCfg.MaxPlayers = max(srvCfg.MaxPlayers, cfg.Clients)
+	srv := server.New(srvCfg)
+	srv.SetWorld(world)
+	if cfg.Mode != nil {
+		srv.SetGameMode(cfg.Mode)
+	}
+
+	report := Report{Clients: cfg.Clients, Budget: srv.TickBudget()}
+	clients := make(map[int]*swarmClient, cfg.Clients)
+	for i := range cfg.Clients {
+		id := i + 1
+		h, err := client.NewHandshake(fmt.Sprintf("bot%d", id), srvCfg.Build, level, game.DefaultPhysics())
+		if err != nil {
+			return Report{}, err
+		}
+		session, reply := srv.Join(id, id, h)
+		if !reply.Accepted {
+			report.Rejected = append(report.Rejected, reply.Reason)
+			continue
+		}
+		c := &swarmClient{
+			session: session,
+			sender:  client.NewInputSender(cfg.Redundancy),
+			script:  newScript(i, cfg.Seed),
+			snaps:   make(chan protocol.StateSnapshot, max(cfg.Queue, 1)),
+		}
+		c.roster.Accept(reply)
+		clients[id] = c
+	}
+
+	var ticks tickLog
+	srv.SetTickObserver(ticks.observe)
+	srv.SetSnapshotCallback(func(sessionID int, snap protocol.StateSnapshot) {
+		c, ok := clients[sessionID]
+		if !ok {
+			return
+		}
+		// The queue stands in for the socket's send buffer
+		c.session.SetBacklog(len(c.snaps))
+		select {
+		case c.snaps <- snap:
+			c.session.RecordSent(snap.Size())
+		default:
+			c.dropped++
+		}
+	})
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	start := time.Now()
+	if err := srv.Start(); err != nil {
+		return Report{}, err
+	}
+	for id, c := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.play(srv, id, cfg.Lead, &ticks, stop)
+		}()
+	}
+	time.Sleep(cfg.Duration)
+	srv.Stop()
+	elapsed := time.Since(start)
+	close(stop)
+	wg.Wait()
+
+	report.Duration = elapsed
+	report.Ticks = srv.Tick()
+	// The ticker drops the ticks a slow loop can't keep up with
+	if ran := uint64(len(ticks.times)); ran > 0 {
+		expected := uint64(ticks.last.Sub(ticks.first)/report.Budget) + 1
+		report.MissedTicks = expected - min(ran, expected)
+	}
+	report.TickInterval = percentiles(ticks.intervals)
+	report.TickTime = percentiles(ticks.times)
+
+	var latency []time.Duration
+	seconds := elapsed.Seconds()
+	for _, c := range clients {
+		latency = append(latency, c.latency...)
+		report.Snapshots += c.handled
+		report.Dropped += c.dropped
+		stats := c.session.NetStats()
+		down, up := float64(stats.BytesOut)/seconds, float64(stats.BytesIn)/seconds
+		report.BytesDown += down / float64(len(clients))
+		report.BytesUp += up / float64(len(clients))
+		report.MaxBytesDown = max(report.MaxBytesDown, down)
+	}
+	report.Latency = percentiles(latency)
+	return report, nil
+}
+
+// play runs the client until stop is closed: every tick it sends the
+// script's input, and it handles snapshots as they arrive
+func (c *swarmClient) play(srv *server.Server, id, lead int, ticks *tickLog, stop <-chan struct{}) {
+	ticker := time.NewTicker(srv.TickBudget())
+	defer ticker.Stop()
+	for tick := 0; ; {
+		select {
+		case <-stop:
+			return
+		case snap := <-c.snaps:
+			c.handle(snap, ticks)
+		case <-ticker.C:
+			frame := protocol.InputFrame{
+				Tick:    c.latest + uint64(c.since+lead),
+				Intents: c.script(tick),
+			}
+			tick++
+			c.since++
+			c.encodeTo = protocol.AppendInputMessage(c.encodeTo[:0], c.sender.Next(frame))
+			c.session.RecordReceived(len(c.encodeTo))
+			msg, _, err := protocol.DecodeInputMessage(c.encodeTo)
+			if err != nil {
+				continue
+			}
+			srv.QueueInputs(id, msg)
+			if ack, ok := srv.InputAck(id); ok {
+				c.sender.OnAck(ack)
+			}
+		}
+	}
+}
+
+// handle takes a snapshot as a client would: roster and scoreboard, then
+// the ack, and times it from the end of its tick
+func (c *swarmClient) handle(snap protocol.StateSnapshot, ticks *tickLog) {
+	c.roster.Apply(&snap)
+	c.scoreboard.Apply(&snap)
+	c.session.AckSnapshot(snap.Tick)
+	if snap.Tick > c.latest {
+		c.latest, c.since = snap.Tick, 0
+	}
+	if ended, ok := ticks.endOf(snap.Tick); ok {
+		c.latency = append(c.latency, time.Since(ended))
+	}
+	c.handled++
+}
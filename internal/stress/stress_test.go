@@ -0,0 +1,61 @@
+package stress
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRun runs four clients against a server for a second and tests that
+// they all join, handle snapshots with a measured latency and use
+// bandwidth both ways, and that Check reports an exceeded threshold.
+func TestRun(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Clients = 4
+	cfg.Duration = time.Second
+	report, err := Run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%d ticks, %d missed, tick interval %v, snapshot latency %v, %.0f B/s down, %.0f B/s up",
+		report.Ticks, report.MissedTicks, report.TickInterval, report.Latency, report.BytesDown, report.BytesUp)
+
+	if len(report.Rejected) > 0 {
+		t.Fatalf("Clients rejected: %v", report.Rejected)
+	}
+	if report.Ticks < 30 || report.TickInterval.P50 == 0 {
+		t.Errorf("%d ticks with median interval %v, want about a second's worth", report.Ticks, report.TickInterval.P50)
+	}
+	if report.Snapshots == 0 || report.Latency.Max == 0 {
+		t.Errorf("%d snapshots handled, max latency %v, want some of each", report.Snapshots, report.Latency.Max)
+	}
+	if report.BytesDown == 0 || report.BytesUp == 0 || report.MaxBytesDown < report.BytesDown {
+		t.Errorf("Bandwidth %.0f down (max %.0f), %.0f up, want both ways and the max at least the mean",
+			report.BytesDown, report.MaxBytesDown, report.BytesUp)
+	}
+	if failed := report.Check(Thresholds{Latency: time.Nanosecond}); len(failed) != 1 {
+		t.Errorf("Check with a 1 ns latency limit = %q, want one failure", failed)
+	}
+	if failed := report.Check(Thresholds{}); len(failed) != 0 {
+		t.Errorf("Check without thresholds = %q, want none", failed)
+	}
+
+	cfg.Script = "dance"
+	if _, err := Run(cfg); err == nil {
+		t.Error("Run with an unknown script should fail")
+	}
+}
+
+// TestPercentiles tests the summary of a distribution.
+func TestPercentiles(t *testing.T) {
+	var samples []time.Duration
+	for i := 100; i >= 1; i-- {
+		samples = append(samples, time.Duration(i)*time.Millisecond)
+	}
+	p := percentiles(samples)
+	if p.P50 != 51*time.Millisecond || p.P99 != 100*time.Millisecond || p.Max != 100*time.Millisecond {
+		t.Errorf("Percentiles of 1..100 ms = %+v", p)
+	}
+	if (percentiles(nil) != Percentiles{}) {
+		t.Error("No samples should give zeros")
+	}
+}
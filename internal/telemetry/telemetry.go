@@ -0,0 +1,230 @@
+// Package telemetry batches anonymous gameplay events to an HTTP endpoint,
+// for balancing levels and the charge attack on data rather than hunches.
+// It is opt-in: nothing is recorded or sent until a Sender is enabled.
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event kinds
+const (
+	KindDeath  = "death"  // The player died in Segment
+	KindCharge = "charge" // The player released an attack charged for Ticks
+	KindFinish = "finish" // The player finished the level in Ticks
+)
+
+// SegmentWidth is how many tiles wide a level segment is, so deaths group
+// by stretch of level rather than exact position
+const SegmentWidth = 16
+
+// Queue limits
+const (
+	BatchSize     = 100              // Events sent in one request
+	MaxQueue      = 2000             // Events kept unsent; the oldest go first
+	FlushInterval = time.Minute      // How often Run sends what is queued
+	MinBackoff    = 30 * time.Second // Wait after the first failed send
+	MaxBackoff    = 30 * time.Minute // Longest wait, doubling up to it
+)
+
+// ErrBackoff is returned by Flush while waiting to retry after a failed send
+var ErrBackoff = errors.New("telemetry: waiting to retry")
+
+// Event is one anonymous gameplay event. It names no player: the level is
+// its hash, as on the leaderboards, and the rest is what happened there.
+type Event struct {
+	Kind    string `json:"kind"`
+	Level   string `json:"level"`             // Level hash, hex
+	Segment int    `json:"segment,omitempty"` // Death position, see Segment
+	Ticks   int    `json:"ticks,omitempty"`   // Charge or completion time, at 60 TPS
+}
+
+// Segment returns the level segment a tile X position is in
+func Segment(x float64) int {
+	return int(math.Floor(x / SegmentWidth))
+}
+
+// Batch is the body POSTed to the endpoint
+type Batch struct {
+	Version string  `json:"version"` // Game release
+	Events  []Event `json:"events"`
+}
+
+// Sender queues events while enabled and sends them in batches. Events are
+// kept in memory until sent, and across runs through MarshalQueue and
+// UnmarshalQueue; a failed send keeps them and waits MinBackoff, doubling
+// up to MaxBackoff, before the next try. Its methods may be called from
+// any goroutine.
+type Sender struct {
+	URL     string // Endpoint batches are POSTed to
+	Version string
+	HTTP    *http.Client
+
+	mu      sync.Mutex
+	enabled bool
+	queue   []Event // Oldest first
+	backoff time.Duration
+	retryAt time.Time // No sends before this, after a failure
+	sending sync.Mutex
+	now     func() time.Time
+}
+
+// NewSender creates a disabled sender for the endpoint at url
+func NewSender(url, version string) *Sender {
+	return &Sender{
+		URL:     url,
+		Version: version,
+		HTTP:    &http.Client{Timeout: 10 * time.Second},
+		now:     time.Now,
+	}
+}
+
+// SetEnabled opts in or out. Opting out drops the events not yet sent.
+func (s *Sender) SetEnabled(on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enabled = on
+	if !on {
+		s.queue = nil
+	}
+}
+
+// Enabled reports whether events are recorded
+func (s *Sender) Enabled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enabled
+}
+
+// Record queues an event, if enabled, dropping the oldest past MaxQueue
+func (s *Sender) Record(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.enabled {
+		return
+	}
+	s.queue = append(s.queue, e)
+	s.trim()
+}
+
+// Pending returns how many events wait to be sent
+func (s *Sender) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queue)
+}
+
+// trim drops the oldest events past MaxQueue; s.mu must be held
+func (s *Sender) trim() {
+	if n := len(s.queue) - MaxQueue; n > 0 {
+		s.queue = append(s.queue[:0], s.queue[n:]...)
+	}
+}
+
+// Flush sends the queue in batches of up to BatchSize. After a failure it
+// puts the batch back and returns ErrBackoff until the wait is over.
+func (s *Sender) Flush() error {
+	s.sending.Lock()
+	defer s.sending.Unlock()
+	for {
+		s.mu.Lock()
+		if !s.enabled || len(s.queue) == 0 {
+			s.mu.Unlock()
+			return nil
+		}
+		if s.now().Before(s.retryAt) {
+			s.mu.Unlock()
+			return ErrBackoff
+		}
+		n := min(len(s.queue), BatchSize)
+		batch := Batch{Version: s.Version, Events: append([]Event(nil), s.queue[:n]...)}
+		s.queue = s.queue[n:]
+		s.mu.Unlock()
+
+		err := s.send(batch)
+
+		s.mu.Lock()
+		if err != nil {
+			// Back at the front, unless opted out meanwhile
+			if s.enabled {
+				s.queue = append(batch.Events, s.queue...)
+				s.trim()
+			}
+			s.backoff = min(max(2*s.backoff, MinBackoff), MaxBackoff)
+			s.retryAt = s.now().Add(s.backoff)
+			s.mu.Unlock()
+			return err
+		}
+		s.backoff, s.retryAt = 0, time.Time{}
+		s.mu.Unlock()
+	}
+}
+
+// Run flushes every FlushInterval until stop is closed. Failures are left
+// to the backoff, and quitting doesn't wait for a send: a new run sends
+// what MarshalQueue kept.
+func (s *Sender) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Flush()
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (s *Sender) send(batch Batch) error {
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	resp, err := s.HTTP.Post(s.URL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("telemetry: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("telemetry: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// MarshalQueue returns the unsent events as JSON, to keep until the next
+// run; nil if there are none
+func (s *Sender) MarshalQueue() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(s.queue)
+}
+
+// UnmarshalQueue queues events kept by MarshalQueue ahead of any recorded
+// since, if enabled
+func (s *Sender) UnmarshalQueue(data []byte) error {
+	var events []Event
+	if err := json.Unmarshal(data, &events); err != nil {
+		return fmt.Errorf("telemetry queue: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.enabled {
+		s.queue = append(events, s.queue...)
+		s.trim()
+	}
+	return nil
+}
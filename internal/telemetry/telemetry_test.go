@@ -0,0 +1,109 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestSender tests that nothing is recorded until opted in, that events
+// go out in batches, and that a failed send keeps them and backs off,
+// doubling, until the endpoint recovers.
+func TestSender(t *testing.T) {
+	var batches []Batch
+	failing := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		var b Batch
+		if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+			t.Error(err)
+		}
+		batches = append(batches, b)
+	}))
+	defer srv.Close()
+
+	now := time.Unix(1000, 0)
+	s := NewSender(srv.URL, "v1.2.3")
+	s.now = func() time.Time { return now }
+
+	s.Record(Event{Kind: KindDeath, Level: "abc", Segment: 2})
+	if s.Pending() != 0 {
+		t.Fatal("Recorded an event before opting in")
+	}
+	s.SetEnabled(true)
+	for i := range BatchSize + 5 {
+		s.Record(Event{Kind: KindCharge, Level: "abc", Ticks: i})
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 2 || len(batches[0].Events) != BatchSize || batches[1].Version != "v1.2.3" || s.Pending() != 0 {
+		t.Fatalf("Sent %d batches, %d pending, want 2 and none", len(batches), s.Pending())
+	}
+
+	failing = true
+	s.Record(Event{Kind: KindFinish, Level: "abc", Ticks: 3600})
+	if err := s.Flush(); err == nil || s.Pending() != 1 {
+		t.Fatalf("Failed send: %v, %d pending, want an error and the event kept", err, s.Pending())
+	}
+	failing = false
+	if err := s.Flush(); !errors.Is(err, ErrBackoff) {
+		t.Fatalf("Flush during backoff = %v, want ErrBackoff", err)
+	}
+	now = now.Add(MinBackoff)
+	failing = true
+	s.Flush()
+	if now = now.Add(MinBackoff); !errors.Is(s.Flush(), ErrBackoff) {
+		t.Error("The second failure should wait twice as long")
+	}
+	failing = false
+	now = now.Add(MinBackoff)
+	if err := s.Flush(); err != nil || len(batches) != 3 || batches[2].Events[0].Ticks != 3600 {
+		t.Errorf("Flush after backoff = %v, %d batches, want the kept event sent", err, len(batches))
+	}
+
+	s.Record(Event{Kind: KindDeath, Level: "abc"})
+	s.SetEnabled(false)
+	if s.Pending() != 0 {
+		t.Error("Opting out should drop unsent events")
+	}
+}
+
+// TestSenderQueue tests that unsent events carry over to the next run and
+// that the queue keeps the newest MaxQueue events.
+func TestSenderQueue(t *testing.T) {
+	s := NewSender("http://127.0.0.1:0", "dev")
+	s.SetEnabled(true)
+	for i := range MaxQueue + 10 {
+		s.Record(Event{Kind: KindCharge, Ticks: i})
+	}
+	data, err := s.MarshalQueue()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := NewSender("http://127.0.0.1:0", "dev")
+	next.SetEnabled(true)
+	next.Record(Event{Kind: KindDeath, Segment: 1})
+	if err := next.UnmarshalQueue(data); err != nil {
+		t.Fatal(err)
+	}
+	if next.Pending() != MaxQueue {
+		t.Fatalf("%d pending, want %d", next.Pending(), MaxQueue)
+	}
+	if last := next.queue[len(next.queue)-1]; last.Kind != KindDeath {
+		t.Errorf("Newest event %+v, want the one recorded this run", last)
+	}
+	if first := next.queue[0]; first.Ticks != 11 {
+		t.Errorf("Oldest event has ticks %d, want 11 after dropping the oldest", first.Ticks)
+	}
+	if Segment(15.9) != 0 || Segment(16) != 1 || Segment(-0.5) != -1 {
+		t.Error("Segment should floor by SegmentWidth")
+	}
+}